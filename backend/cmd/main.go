@@ -8,8 +8,14 @@ import (
 	"os"
 	"procurement-system/internal/handlers"
 	"procurement-system/internal/middleware"
+	"procurement-system/internal/models"
 	"procurement-system/internal/repository"
 	"procurement-system/internal/services"
+	"procurement-system/pkg/blobstore"
+	"procurement-system/pkg/mailer"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
@@ -51,25 +57,150 @@ func main() {
 	requisitionRepo := repository.NewPostgresRequisitionRepository(db)
 	poRepo := repository.NewPostgresPurchaseOrderRepository(db)
 	activityLogRepo := repository.NewPostgresActivityLogRepository(db)
+	loginThrottleRepo := repository.NewPostgresLoginThrottleRepository(db)
+	twoFactorRepo := repository.NewPostgresTwoFactorRepository(db)
+	invitationRepo := repository.NewPostgresInvitationRepository(db)
+	roleRepo := repository.NewPostgresRoleRepository(db)
+	navigationRepo := repository.NewPostgresNavigationRepository(db)
+	departmentRepo := repository.NewPostgresDepartmentRepository(db)
+	delegationRepo := repository.NewPostgresDelegationRepository(db)
+	sodRepo := repository.NewPostgresSoDRepository(db)
+	commentRepo := repository.NewPostgresCommentRepository(db)
+	notificationRepo := repository.NewPostgresNotificationRepository(db)
+	attachmentRepo := repository.NewPostgresAttachmentRepository(db)
+	statusHistoryRepo := repository.NewPostgresStatusHistoryRepository(db)
+	idempotencyRepo := repository.NewPostgresIdempotencyRepository(db)
+	changeOrderRepo := repository.NewPostgresChangeOrderRepository(db)
+	poDispatchRepo := repository.NewPostgresPODispatchRepository(db)
+	companySettingsRepo := repository.NewPostgresCompanySettingsRepository(db)
+	poDocumentRepo := repository.NewPostgresPODocumentRepository(db)
+
+	// Initialize blob storage for attachments
+	attachmentDir := os.Getenv("ATTACHMENT_STORAGE_DIR")
+	if attachmentDir == "" {
+		attachmentDir = "data/attachments"
+	}
+	attachmentStore, err := blobstore.NewLocalStore(attachmentDir)
+	if err != nil {
+		log.Fatalf("Could not open attachment storage: %v", err)
+	}
+
+	// Initialize blob storage for archived purchase order PDFs
+	poDocumentDir := os.Getenv("PO_DOCUMENT_STORAGE_DIR")
+	if poDocumentDir == "" {
+		poDocumentDir = "data/po-documents"
+	}
+	poDocumentStore, err := blobstore.NewLocalStore(poDocumentDir)
+	if err != nil {
+		log.Fatalf("Could not open purchase order document storage: %v", err)
+	}
+
+	// Initialize the mail sender: SMTP when configured, otherwise .eml files
+	var mailSender mailer.Sender
+	if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+		mailSender = mailer.NewSMTPSender(smtpHost, getEnvInt("SMTP_PORT", 25), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+	} else {
+		outboxDir := os.Getenv("MAIL_OUTBOX_DIR")
+		if outboxDir == "" {
+			outboxDir = "data/outbox"
+		}
+		fileSender, err := mailer.NewFileSender(outboxDir)
+		if err != nil {
+			log.Fatalf("Could not open mail outbox: %v", err)
+		}
+		mailSender = fileSender
+		log.Printf("SMTP_HOST not set, writing outgoing mail to %s", outboxDir)
+	}
 
 	// Initialize services
 	logService := services.NewActivityLogService(activityLogRepo)
-	authService := services.NewAuthService(userRepo, logService)
+	throttlePolicy := services.DefaultLoginThrottlePolicy()
+	throttlePolicy.MaxAccountFailures = getEnvInt("LOGIN_MAX_ACCOUNT_FAILURES", throttlePolicy.MaxAccountFailures)
+	throttlePolicy.MaxIPFailures = getEnvInt("LOGIN_MAX_IP_FAILURES", throttlePolicy.MaxIPFailures)
+	throttlePolicy.LockoutDuration = time.Duration(getEnvInt("LOGIN_LOCKOUT_MINUTES", int(throttlePolicy.LockoutDuration/time.Minute))) * time.Minute
+	loginThrottleService := services.NewLoginThrottleService(loginThrottleRepo, logService, throttlePolicy)
+	twoFactorIssuer := os.Getenv("TWO_FACTOR_ISSUER")
+	if twoFactorIssuer == "" {
+		twoFactorIssuer = "Procurement System"
+	}
+	twoFactorService := services.NewTwoFactorService(twoFactorRepo, userRepo, roleRepo, logService, twoFactorIssuer, getEnvList("TWO_FACTOR_REQUIRED_ROLES"))
+	registrationMode := services.ParseRegistrationMode(os.Getenv("SELF_REGISTRATION"))
+	authService := services.NewAuthService(userRepo, logService, loginThrottleService, twoFactorService, registrationMode)
+	invitationService := services.NewInvitationService(invitationRepo, logService)
 	vendorService := services.NewVendorService(vendorRepo, logService)
-	pdfService := services.NewPDFService()
-	poService := services.NewPurchaseOrderService(poRepo, vendorRepo, pdfService) // PO service doesn't log directly yet
-	requisitionService := services.NewRequisitionService(requisitionRepo, poService, logService)
-	navigationService := services.NewNavigationService()
+	companySettingsService := services.NewCompanySettingsService(companySettingsRepo, logService)
+	pdfService := services.NewPDFService(companySettingsService)
+	poDocumentService := services.NewPODocumentService(poDocumentRepo, poRepo, pdfService, poDocumentStore, logService)
+	poIssueMode := services.ParsePOIssueMode(os.Getenv("PO_ISSUE_MODE"))
+	poService := services.NewPurchaseOrderService(poRepo, vendorRepo, pdfService, companySettingsService, poDocumentService, logService, poIssueMode)
 	userService := services.NewUserService(userRepo, logService)
+	roleService := services.NewRoleService(roleRepo, logService)
+	delegationService := services.NewDelegationService(delegationRepo, userRepo, roleService, logService)
+	sodService := services.NewSoDService(sodRepo, logService)
+	requisitionService := services.NewRequisitionService(requisitionRepo, poService, logService, roleService, delegationService, sodService, vendorRepo)
+	navigationService := services.NewNavigationService(navigationRepo, roleService, logService)
+	organisationService := services.NewOrganisationService(departmentRepo, userRepo, logService)
+	entityAccessService := services.NewEntityAccessService(userRepo, roleService)
+	services.RegisterDefaultEntityPolicies(entityAccessService, requisitionRepo, poRepo, vendorRepo)
+	notificationService := services.NewNotificationService(notificationRepo)
+	changeOrderThreshold := float64(getEnvInt("PO_CHANGE_APPROVAL_THRESHOLD", 0))
+	changeOrderService := services.NewChangeOrderService(changeOrderRepo, poRepo, vendorRepo, userRepo, entityAccessService, sodService, notificationService, poDocumentService, logService, changeOrderThreshold)
+	blanketService := services.NewBlanketService(poRepo, vendorRepo, sodService, companySettingsService, poDocumentService, logService, float64(getEnvInt("BLANKET_WARNING_PERCENT", services.DefaultBlanketWarningPercent)))
+	commentEditWindow := time.Duration(getEnvInt("COMMENT_EDIT_WINDOW_MINUTES", int(services.DefaultCommentEditWindow/time.Minute))) * time.Minute
+	commentService := services.NewCommentService(commentRepo, userRepo, entityAccessService, roleService, notificationService, logService, commentEditWindow)
+	attachmentPolicy := services.DefaultAttachmentPolicy()
+	attachmentPolicy.MaxSize = int64(getEnvInt("ATTACHMENT_MAX_SIZE_MB", int(attachmentPolicy.MaxSize>>20))) << 20
+	if types := getEnvList("ATTACHMENT_ALLOWED_TYPES"); len(types) > 0 {
+		attachmentPolicy.AllowedTypes = types
+	}
+	attachmentService := services.NewAttachmentService(attachmentRepo, attachmentStore, entityAccessService, roleService, logService, attachmentPolicy)
+	timelineService := services.NewTimelineService(entityAccessService)
+	services.RegisterDefaultTimelineSources(timelineService, logService, commentRepo, attachmentRepo)
+	statusHistoryService := services.NewStatusHistoryService(statusHistoryRepo, entityAccessService)
+	idempotencyTTL := time.Duration(getEnvInt("IDEMPOTENCY_TTL_HOURS", int(services.DefaultIdempotencyTTL/time.Hour))) * time.Hour
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo, idempotencyTTL)
+	dispatchPolicy := services.DefaultDispatchPolicy()
+	if from := os.Getenv("MAIL_FROM"); from != "" {
+		dispatchPolicy.From = from
+	}
+	dispatchPolicy.MaxAttempts = getEnvInt("PO_DISPATCH_MAX_ATTEMPTS", dispatchPolicy.MaxAttempts)
+	dispatchPolicy.RetryDelay = time.Duration(getEnvInt("PO_DISPATCH_RETRY_MINUTES", int(dispatchPolicy.RetryDelay/time.Minute))) * time.Minute
+	poDispatchService := services.NewPODispatchService(poDispatchRepo, poService, poDocumentService, vendorRepo, mailSender, logService, dispatchPolicy)
+	services.RegisterDefaultBadgeProviders(navigationService, requisitionService, requisitionRepo, poRepo, changeOrderRepo, poDispatchRepo)
+
+	// Retry failed purchase order dispatches in the background
+	go func() {
+		for range time.Tick(time.Minute) {
+			if _, err := poDispatchService.RetryDue(); err != nil {
+				log.Printf("Failed to retry purchase order dispatches: %v", err)
+			}
+		}
+	}()
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService)
 	vendorHandler := handlers.NewVendorHandler(vendorService)
 	requisitionHandler := handlers.NewRequisitionHandler(requisitionService)
 	poHandler := handlers.NewPurchaseOrderHandler(poService)
+	changeOrderHandler := handlers.NewChangeOrderHandler(changeOrderService)
+	blanketHandler := handlers.NewBlanketHandler(blanketService)
+	poDispatchHandler := handlers.NewPODispatchHandler(poDispatchService)
+	poDocumentHandler := handlers.NewPODocumentHandler(poDocumentService, poService)
+	companySettingsHandler := handlers.NewCompanySettingsHandler(companySettingsService)
 	navigationHandler := handlers.NewNavigationHandler(navigationService)
 	userHandler := handlers.NewUserHandler(userService)
 	profileHandler := handlers.NewProfileHandler(userService)
+	loginLockHandler := handlers.NewLoginLockHandler(loginThrottleService)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService)
+	invitationHandler := handlers.NewInvitationHandler(invitationService)
+	roleHandler := handlers.NewRoleHandler(roleService)
+	organisationHandler := handlers.NewOrganisationHandler(organisationService)
+	delegationHandler := handlers.NewDelegationHandler(delegationService)
+	sodHandler := handlers.NewSoDHandler(sodService)
+	commentHandler := handlers.NewCommentHandler(commentService, timelineService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService, attachmentPolicy.MaxSize)
+	statusHistoryHandler := handlers.NewStatusHistoryHandler(statusHistoryService)
 
 	// Create router
 	r := mux.NewRouter()
@@ -79,72 +210,221 @@ func main() {
 
 	// Auth routes
 	api.HandleFunc("/register", authHandler.Register).Methods("POST")
+	api.HandleFunc("/register/invitation", invitationHandler.AcceptInvitation).Methods("POST")
 	api.HandleFunc("/login", authHandler.Login).Methods("POST")
+	api.HandleFunc("/login/2fa", authHandler.VerifyTwoFactor).Methods("POST")
+	api.HandleFunc("/login/2fa/enrol", authHandler.BeginTwoFactorEnrolment).Methods("POST")
+	api.HandleFunc("/login/2fa/enrol/confirm", authHandler.ConfirmTwoFactorEnrolment).Methods("POST")
+
+	// require wraps a handler so it is only reachable with one of the given permissions.
+	require := func(h http.HandlerFunc, permissions ...string) http.Handler {
+		return middleware.RequirePermission(roleService, permissions...)(h)
+	}
+
+	// idempotent lets authenticated POSTs be retried safely with an
+	// Idempotency-Key. Bodies may be as large as the largest attachment plus
+	// room for the multipart envelope.
+	idempotent := middleware.Idempotency(idempotencyService, attachmentPolicy.MaxSize+1<<20)
 
 	// Profile routes
 	profileRoutes := api.PathPrefix("/profile").Subrouter()
-	profileRoutes.Use(middleware.AuthMiddleware)
+	profileRoutes.Use(middleware.AuthMiddleware, idempotent)
 	profileRoutes.HandleFunc("/me", profileHandler.GetMyProfile).Methods("GET")
 	profileRoutes.HandleFunc("/me", profileHandler.UpdateMyProfile).Methods("PUT")
 	profileRoutes.HandleFunc("/password", profileHandler.ChangeMyPassword).Methods("PUT")
+	profileRoutes.HandleFunc("/permissions", roleHandler.GetMyPermissions).Methods("GET")
+	profileRoutes.HandleFunc("/organisation", organisationHandler.GetMyOrganisation).Methods("GET")
+	profileRoutes.HandleFunc("/2fa", twoFactorHandler.GetMyStatus).Methods("GET")
+	profileRoutes.HandleFunc("/2fa", twoFactorHandler.Disable).Methods("DELETE")
+	profileRoutes.HandleFunc("/2fa/enrol", twoFactorHandler.BeginEnrolment).Methods("POST")
+	profileRoutes.HandleFunc("/2fa/confirm", twoFactorHandler.ConfirmEnrolment).Methods("POST")
+	profileRoutes.HandleFunc("/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes).Methods("POST")
 
-	// User Management routes (Admin only)
+	// User Management routes
 	userRoutes := api.PathPrefix("/users").Subrouter()
-	userRoutes.Use(middleware.AuthMiddleware, middleware.RoleMiddleware("Admin"))
-	userRoutes.HandleFunc("", userHandler.GetAllUsers).Methods("GET")
-	userRoutes.HandleFunc("/{id:[0-9]+}", userHandler.GetUserByID).Methods("GET")
-	userRoutes.HandleFunc("/{id:[0-9]+}", userHandler.UpdateUser).Methods("PUT")
-	userRoutes.HandleFunc("/{id:[0-9]+}", userHandler.DeleteUser).Methods("DELETE")
+	userRoutes.Use(middleware.AuthMiddleware, idempotent)
+	userRoutes.Handle("", require(userHandler.CreateUser, models.PermUserWrite)).Methods("POST")
+	userRoutes.Handle("", require(userHandler.GetAllUsers, models.PermUserRead)).Methods("GET")
+	userRoutes.Handle("/pending", require(userHandler.GetPendingUsers, models.PermUserRead)).Methods("GET")
+	userRoutes.Handle("/{id:[0-9]+}/approve", require(userHandler.ApproveUser, models.PermUserWrite)).Methods("POST")
+	userRoutes.Handle("/{id:[0-9]+}/reject", require(userHandler.RejectUser, models.PermUserWrite)).Methods("POST")
+	userRoutes.Handle("/{id:[0-9]+}", require(userHandler.GetUserByID, models.PermUserRead)).Methods("GET")
+	userRoutes.Handle("/{id:[0-9]+}", require(userHandler.UpdateUser, models.PermUserWrite)).Methods("PUT")
+	userRoutes.Handle("/{id:[0-9]+}", require(userHandler.DeleteUser, models.PermUserWrite)).Methods("DELETE")
+	userRoutes.Handle("/{id:[0-9]+}/vendor", require(userHandler.SetUserVendor, models.PermUserWrite)).Methods("PUT")
+	userRoutes.Handle("/{id:[0-9]+}/roles", require(roleHandler.GetUserRoles, models.PermUserRead, models.PermRoleManage)).Methods("GET")
+	userRoutes.Handle("/{id:[0-9]+}/roles", require(roleHandler.SetUserRoles, models.PermRoleManage)).Methods("PUT")
+	userRoutes.Handle("/{id:[0-9]+}/2fa", require(twoFactorHandler.ResetUserTwoFactor, models.PermSecurityManage)).Methods("DELETE")
+	userRoutes.Handle("/{id:[0-9]+}/organisation", require(organisationHandler.GetUserOrganisation, models.PermOrgRead, models.PermOrgManage)).Methods("GET")
+	userRoutes.Handle("/{id:[0-9]+}/organisation", require(organisationHandler.SetUserOrganisation, models.PermOrgManage)).Methods("PUT")
+
+	// Organisation chart routes
+	departmentRoutes := api.PathPrefix("/departments").Subrouter()
+	departmentRoutes.Use(middleware.AuthMiddleware, idempotent)
+	departmentRoutes.Handle("", require(organisationHandler.GetAllDepartments, models.PermOrgRead, models.PermOrgManage)).Methods("GET")
+	departmentRoutes.Handle("", require(organisationHandler.CreateDepartment, models.PermOrgManage)).Methods("POST")
+	departmentRoutes.Handle("/{id:[0-9]+}", require(organisationHandler.UpdateDepartment, models.PermOrgManage)).Methods("PUT")
+	departmentRoutes.Handle("/{id:[0-9]+}", require(organisationHandler.DeleteDepartment, models.PermOrgManage)).Methods("DELETE")
+	api.Handle("/organisation/import", middleware.AuthMiddleware(idempotent(require(organisationHandler.ImportOrgChart, models.PermOrgManage)))).Methods("POST")
+
+	// Role and permission definition routes
+	roleRoutes := api.PathPrefix("/roles").Subrouter()
+	roleRoutes.Use(middleware.AuthMiddleware, middleware.RequirePermission(roleService, models.PermRoleManage), idempotent)
+	roleRoutes.HandleFunc("", roleHandler.GetAllRoles).Methods("GET")
+	roleRoutes.HandleFunc("", roleHandler.CreateRole).Methods("POST")
+	roleRoutes.HandleFunc("/{id:[0-9]+}", roleHandler.UpdateRole).Methods("PUT")
+	roleRoutes.HandleFunc("/{id:[0-9]+}", roleHandler.DeleteRole).Methods("DELETE")
+	api.Handle("/permissions", middleware.AuthMiddleware(require(roleHandler.GetAllPermissions, models.PermRoleManage))).Methods("GET")
+
+	// Login lock routes
+	loginLockRoutes := api.PathPrefix("/login-locks").Subrouter()
+	loginLockRoutes.Use(middleware.AuthMiddleware, middleware.RequirePermission(roleService, models.PermSecurityManage), idempotent)
+	loginLockRoutes.HandleFunc("", loginLockHandler.GetActiveLocks).Methods("GET")
+	loginLockRoutes.HandleFunc("/{id:[0-9]+}", loginLockHandler.ClearLock).Methods("DELETE")
+
+	// Invitation routes
+	invitationRoutes := api.PathPrefix("/invitations").Subrouter()
+	invitationRoutes.Use(middleware.AuthMiddleware, middleware.RequirePermission(roleService, models.PermInvitationManage), idempotent)
+	invitationRoutes.HandleFunc("", invitationHandler.CreateInvitation).Methods("POST")
+	invitationRoutes.HandleFunc("", invitationHandler.GetAllInvitations).Methods("GET")
+	invitationRoutes.HandleFunc("/{id:[0-9]+}", invitationHandler.RevokeInvitation).Methods("DELETE")
 
 	// Navigation routes
 	navRoutes := api.PathPrefix("/navigation").Subrouter()
-	navRoutes.Use(middleware.AuthMiddleware)
+	navRoutes.Use(middleware.AuthMiddleware, idempotent)
 	navRoutes.HandleFunc("/menu", navigationHandler.GetMenu).Methods("GET")
 	navRoutes.HandleFunc("/breadcrumbs", navigationHandler.GetBreadcrumbs).Methods("GET")
+	navRoutes.Handle("/items", require(navigationHandler.GetAllMenuItems, models.PermNavigationManage)).Methods("GET")
+	navRoutes.Handle("/items", require(navigationHandler.CreateMenuItem, models.PermNavigationManage)).Methods("POST")
+	navRoutes.Handle("/items/{id:[0-9]+}", require(navigationHandler.UpdateMenuItem, models.PermNavigationManage)).Methods("PUT")
+	navRoutes.Handle("/items/{id:[0-9]+}", require(navigationHandler.DeleteMenuItem, models.PermNavigationManage)).Methods("DELETE")
 
-	// Vendor routes (Admin only)
+	// Vendor routes
 	vendorRoutes := api.PathPrefix("/vendors").Subrouter()
-	vendorRoutes.Use(middleware.AuthMiddleware, middleware.RoleMiddleware("Admin"))
-	vendorRoutes.HandleFunc("", vendorHandler.CreateVendor).Methods("POST")
-	vendorRoutes.HandleFunc("", vendorHandler.GetAllVendors).Methods("GET")
-	vendorRoutes.HandleFunc("/{id:[0-9]+}", vendorHandler.GetVendorByID).Methods("GET")
-	vendorRoutes.HandleFunc("/{id:[0-9]+}", vendorHandler.UpdateVendor).Methods("PUT")
-	vendorRoutes.HandleFunc("/{id:[0-9]+}", vendorHandler.DeleteVendor).Methods("DELETE")
+	vendorRoutes.Use(middleware.AuthMiddleware, idempotent)
+	vendorRoutes.Handle("", require(vendorHandler.CreateVendor, models.PermVendorWrite)).Methods("POST")
+	vendorRoutes.Handle("", require(vendorHandler.GetAllVendors, models.PermVendorRead)).Methods("GET")
+	vendorRoutes.Handle("/{id:[0-9]+}", require(vendorHandler.GetVendorByID, models.PermVendorRead)).Methods("GET")
+	vendorRoutes.Handle("/{id:[0-9]+}", require(vendorHandler.UpdateVendor, models.PermVendorWrite)).Methods("PUT")
+	vendorRoutes.Handle("/{id:[0-9]+}", require(vendorHandler.DeleteVendor, models.PermVendorWrite)).Methods("DELETE")
 
 	// Requisition routes
 	reqRoutes := api.PathPrefix("/requisitions").Subrouter()
-	reqRoutes.Use(middleware.AuthMiddleware) // All requisition routes require authentication
-	reqRoutes.HandleFunc("", requisitionHandler.CreateRequisition).Methods("POST")
-	reqRoutes.HandleFunc("/my", requisitionHandler.GetMyRequisitions).Methods("GET")
-	reqRoutes.HandleFunc("/{id:[0-9]+}", requisitionHandler.UpdateRequisition).Methods("PUT")
-	reqRoutes.HandleFunc("/{id:[0-9]+}", requisitionHandler.DeleteRequisition).Methods("DELETE")
-
-	// Admin-only requisition routes
-	adminReqRoutes := reqRoutes.PathPrefix("").Subrouter()
-	adminReqRoutes.Use(middleware.RoleMiddleware("Admin"))
-	adminReqRoutes.HandleFunc("/pending", requisitionHandler.GetPendingRequisitions).Methods("GET")
-	adminReqRoutes.HandleFunc("/all", requisitionHandler.GetAllRequisitions).Methods("GET")
-	adminReqRoutes.HandleFunc("/{id:[0-9]+}/approve", requisitionHandler.ApproveRequisition).Methods("POST")
-	adminReqRoutes.HandleFunc("/{id:[0-9]+}/reject", requisitionHandler.RejectRequisition).Methods("POST")
-	adminReqRoutes.HandleFunc("/{id:[0-9]+}", requisitionHandler.AdminUpdateRequisition).Methods("PUT")
-	adminReqRoutes.HandleFunc("/{id:[0-9]+}", requisitionHandler.AdminDeleteRequisition).Methods("DELETE")
+	reqRoutes.Use(middleware.AuthMiddleware, idempotent) // All requisition routes require authentication
+	reqRoutes.Handle("", require(requisitionHandler.CreateRequisition, models.PermRequisitionCreate)).Methods("POST")
+	reqRoutes.Handle("/my", require(requisitionHandler.GetMyRequisitions, models.PermRequisitionCreate)).Methods("GET")
+	reqRoutes.Handle("/pending", require(requisitionHandler.GetPendingRequisitions, models.PermRequisitionReadAll)).Methods("GET")
+	reqRoutes.Handle("/all", require(requisitionHandler.GetAllRequisitions, models.PermRequisitionReadAll)).Methods("GET")
+	reqRoutes.Handle("/{id:[0-9]+}/submit", require(requisitionHandler.SubmitRequisition, models.PermRequisitionCreate)).Methods("POST")
+	reqRoutes.Handle("/{id:[0-9]+}/withdraw", require(requisitionHandler.WithdrawRequisition, models.PermRequisitionCreate)).Methods("POST")
+	reqRoutes.Handle("/{id:[0-9]+}/reassign", require(requisitionHandler.ReassignApprover, models.PermRequisitionManage)).Methods("POST")
+
+	// Deciding is checked per requisition in the service, since an active
+	// delegation lets users without requisition:approve act for an approver.
+	reqRoutes.HandleFunc("/approvals", requisitionHandler.GetApprovalQueue).Methods("GET")
+	reqRoutes.HandleFunc("/{id:[0-9]+}/approve", requisitionHandler.ApproveRequisition).Methods("POST")
+	reqRoutes.HandleFunc("/{id:[0-9]+}/reject", requisitionHandler.RejectRequisition).Methods("POST")
+	reqRoutes.HandleFunc("/{id:[0-9]+}/return", requisitionHandler.ReturnRequisition).Methods("POST")
+	reqRoutes.HandleFunc("/bulk-approve", requisitionHandler.BulkApproveRequisitions).Methods("POST")
+	reqRoutes.HandleFunc("/bulk-reject", requisitionHandler.BulkRejectRequisitions).Methods("POST")
+	reqRoutes.HandleFunc("/{id:[0-9]+}", requisitionHandler.GetRequisition).Methods("GET")
+	reqRoutes.HandleFunc("/{id:[0-9]+}/history", requisitionHandler.GetRequisitionHistory).Methods("GET")
+
+	// Editing and deleting go through one route each; holders of
+	// requisition:manage may change any requisition, everyone else only their own.
+	reqRoutes.Handle("/{id:[0-9]+}", require(requisitionHandler.UpdateRequisition, models.PermRequisitionCreate, models.PermRequisitionManage)).Methods("PUT")
+	reqRoutes.Handle("/{id:[0-9]+}", require(requisitionHandler.DeleteRequisition, models.PermRequisitionCreate, models.PermRequisitionManage)).Methods("DELETE")
+
+	// Approval delegation routes
+	delegationRoutes := api.PathPrefix("/delegations").Subrouter()
+	delegationRoutes.Use(middleware.AuthMiddleware, idempotent)
+	delegationRoutes.HandleFunc("", delegationHandler.GetMyDelegations).Methods("GET")
+	delegationRoutes.Handle("", require(delegationHandler.CreateDelegation, models.PermRequisitionApprove, models.PermRequisitionManage)).Methods("POST")
+	delegationRoutes.Handle("/all", require(delegationHandler.GetAllDelegations, models.PermRequisitionManage)).Methods("GET")
+	delegationRoutes.Handle("/{id:[0-9]+}", require(delegationHandler.DeleteDelegation, models.PermRequisitionApprove, models.PermRequisitionManage)).Methods("DELETE")
+
+	// Segregation of duties routes
+	sodRoutes := api.PathPrefix("/sod").Subrouter()
+	sodRoutes.Use(middleware.AuthMiddleware, middleware.RequirePermission(roleService, models.PermSoDManage), idempotent)
+	sodRoutes.HandleFunc("/rules", sodHandler.GetAllRules).Methods("GET")
+	sodRoutes.HandleFunc("/rules", sodHandler.CreateRule).Methods("POST")
+	sodRoutes.HandleFunc("/rules/{id:[0-9]+}", sodHandler.UpdateRule).Methods("PUT")
+	sodRoutes.HandleFunc("/rules/{id:[0-9]+}", sodHandler.DeleteRule).Methods("DELETE")
+	sodRoutes.HandleFunc("/exceptions", sodHandler.GetViolations).Methods("GET")
 
 	// Purchase Order routes
 	poRoutes := api.PathPrefix("/purchase-orders").Subrouter()
-	poRoutes.Use(middleware.AuthMiddleware)
-	poRoutes.HandleFunc("/{id:[0-9]+}", poHandler.GetPurchaseOrderByID).Methods("GET")
-	poRoutes.HandleFunc("/{id:[0-9]+}/pdf", poHandler.GetPurchaseOrderPDF).Methods("GET")
+	poRoutes.Use(middleware.AuthMiddleware, idempotent)
+	poRoutes.Handle("/all", require(poHandler.GetAllPurchaseOrders, models.PermPOReadAll)).Methods("GET")
+	poRoutes.Handle("/buy-queue", require(poHandler.GetBuyQueue, models.PermPOWrite)).Methods("GET")
+	poRoutes.Handle("/consolidate", require(poHandler.ConsolidateRequisitions, models.PermPOWrite)).Methods("POST")
+	poRoutes.Handle("/blankets", require(blanketHandler.CreateBlanket, models.PermPOWrite)).Methods("POST")
+	poRoutes.Handle("/blankets/{id:[0-9]+}/call-offs", require(blanketHandler.CreateCallOff, models.PermPOWrite)).Methods("POST")
+	poRoutes.Handle("/{id:[0-9]+}", require(poHandler.GetPurchaseOrderByID, models.PermPORead, models.PermPOReadAll)).Methods("GET")
+	poRoutes.Handle("/{id:[0-9]+}/pdf", require(poDocumentHandler.GetPurchaseOrderPDF, models.PermPORead, models.PermPOReadAll)).Methods("GET")
+	poRoutes.Handle("/{id:[0-9]+}/documents", require(poDocumentHandler.GetDocuments, models.PermPORead, models.PermPOReadAll)).Methods("GET")
+	poRoutes.Handle("/documents/verify", require(poDocumentHandler.VerifyDocument, models.PermPORead, models.PermPOReadAll)).Methods("POST")
+	poRoutes.Handle("/{id:[0-9]+}/send", require(poDispatchHandler.SendToVendor, models.PermPOWrite)).Methods("POST")
+	poRoutes.Handle("/{id:[0-9]+}/dispatches", require(poDispatchHandler.GetDispatches, models.PermPOWrite, models.PermPOReadAll)).Methods("GET")
+	poRoutes.Handle("/{id:[0-9]+}/cancel", require(poHandler.CancelPurchaseOrder, models.PermPOWrite)).Methods("POST")
+	poRoutes.Handle("/{id:[0-9]+}/close", require(poHandler.ClosePurchaseOrder, models.PermPOWrite)).Methods("POST")
+	poRoutes.Handle("/{id:[0-9]+}/change-orders", require(changeOrderHandler.GetChangeOrders, models.PermPORead, models.PermPOReadAll)).Methods("GET")
+	poRoutes.Handle("/{id:[0-9]+}/change-orders", require(changeOrderHandler.RequestChangeOrder, models.PermPOWrite)).Methods("POST")
+	poRoutes.Handle("/change-orders/pending", require(changeOrderHandler.GetPendingChangeOrders, models.PermPOApprove)).Methods("GET")
+	poRoutes.Handle("/change-orders/{id:[0-9]+}/approve", require(changeOrderHandler.ApproveChangeOrder, models.PermPOApprove)).Methods("POST")
+	poRoutes.Handle("/change-orders/{id:[0-9]+}/reject", require(changeOrderHandler.RejectChangeOrder, models.PermPOApprove)).Methods("POST")
+
+	// Comment, attachment and timeline routes. Who may see what is kept
+	// against an entity is decided per entity by the access policies
+	// registered above.
+	entityRoutes := api.PathPrefix("/entities/{type}/{id:[0-9]+}").Subrouter()
+	entityRoutes.Use(middleware.AuthMiddleware, idempotent)
+	entityRoutes.HandleFunc("/comments", commentHandler.GetComments).Methods("GET")
+	entityRoutes.HandleFunc("/comments", commentHandler.CreateComment).Methods("POST")
+	entityRoutes.HandleFunc("/attachments", attachmentHandler.GetAttachments).Methods("GET")
+	entityRoutes.HandleFunc("/attachments", attachmentHandler.UploadAttachment).Methods("POST")
+	entityRoutes.HandleFunc("/timeline", commentHandler.GetTimeline).Methods("GET")
+	entityRoutes.HandleFunc("/status-history", statusHistoryHandler.GetStatusHistory).Methods("GET")
+	commentRoutes := api.PathPrefix("/comments").Subrouter()
+	commentRoutes.Use(middleware.AuthMiddleware, idempotent)
+	commentRoutes.HandleFunc("/{id:[0-9]+}", commentHandler.UpdateComment).Methods("PUT")
+	commentRoutes.HandleFunc("/{id:[0-9]+}", commentHandler.DeleteComment).Methods("DELETE")
+	attachmentRoutes := api.PathPrefix("/attachments").Subrouter()
+	attachmentRoutes.Use(middleware.AuthMiddleware, idempotent)
+	attachmentRoutes.HandleFunc("/{id:[0-9]+}", attachmentHandler.DownloadAttachment).Methods("GET")
+	attachmentRoutes.HandleFunc("/{id:[0-9]+}", attachmentHandler.DeleteAttachment).Methods("DELETE")
+
+	// Report routes
+	reportRoutes := api.PathPrefix("/reports").Subrouter()
+	reportRoutes.Use(middleware.AuthMiddleware, middleware.RequirePermission(roleService, models.PermReportView), idempotent)
+	reportRoutes.HandleFunc("/cycle-times", statusHistoryHandler.GetCycleTimeSummary).Methods("GET")
+	reportRoutes.HandleFunc("/blanket-consumption", blanketHandler.GetBlanketConsumption).Methods("GET")
 
-	// Admin-only PO routes
-	adminPoRoutes := poRoutes.PathPrefix("").Subrouter()
-	adminPoRoutes.Use(middleware.RoleMiddleware("Admin"))
-	adminPoRoutes.HandleFunc("/all", poHandler.GetAllPurchaseOrders).Methods("GET")
+	// Company settings routes
+	settingsRoutes := api.PathPrefix("/settings/company").Subrouter()
+	settingsRoutes.Use(middleware.AuthMiddleware, middleware.RequirePermission(roleService, models.PermSettingsManage), idempotent)
+	settingsRoutes.HandleFunc("", companySettingsHandler.GetCompanySettings).Methods("GET")
+	settingsRoutes.HandleFunc("", companySettingsHandler.UpdateCompanySettings).Methods("PUT")
+	settingsRoutes.HandleFunc("/history", companySettingsHandler.GetSettingsHistory).Methods("GET")
+	settingsRoutes.HandleFunc("/pdf-layouts", companySettingsHandler.GetPDFLayouts).Methods("GET")
+	settingsRoutes.HandleFunc("/logo", companySettingsHandler.GetLogo).Methods("GET")
+	settingsRoutes.HandleFunc("/logo", companySettingsHandler.SetLogo).Methods("PUT")
+	settingsRoutes.HandleFunc("/logo", companySettingsHandler.RemoveLogo).Methods("DELETE")
+
+	// Notification routes
+	notificationRoutes := api.PathPrefix("/notifications").Subrouter()
+	notificationRoutes.Use(middleware.AuthMiddleware, idempotent)
+	notificationRoutes.HandleFunc("", notificationHandler.GetMyNotifications).Methods("GET")
+	notificationRoutes.HandleFunc("/unread-count", notificationHandler.GetUnreadCount).Methods("GET")
+	notificationRoutes.HandleFunc("/read-all", notificationHandler.MarkAllRead).Methods("POST")
+	notificationRoutes.HandleFunc("/{id:[0-9]+}/read", notificationHandler.MarkRead).Methods("POST")
 
 	// Configure CORS
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"}, // Allow all origins for development
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "If-Match", middleware.IdempotencyKeyHeader},
+		ExposedHeaders:   []string{"ETag", "Idempotent-Replayed"},
 		AllowCredentials: true,
 	})
 	handler := c.Handler(r)
@@ -159,3 +439,28 @@ func main() {
 		log.Fatalf("Could not start server: %s\n", err)
 	}
 }
+
+// getEnvInt reads an integer environment variable, falling back to def when unset or invalid.
+func getEnvInt(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid value for %s, using default %d: %v", key, def, err)
+		return def
+	}
+	return n
+}
+
+// getEnvList reads a comma-separated environment variable, ignoring empty entries.
+func getEnvList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
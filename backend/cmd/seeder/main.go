@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"procurement-system/internal/models"
 	"procurement-system/internal/repository"
 	"procurement-system/internal/services"
+	"procurement-system/pkg/blobstore"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -61,9 +63,22 @@ func main() {
 	// Initialize services (we need this for the approval logic)
 	activityLogRepo := repository.NewPostgresActivityLogRepository(db)
 	logService := services.NewActivityLogService(activityLogRepo)
-	pdfService := services.NewPDFService()
-	poService := services.NewPurchaseOrderService(poRepo, vendorRepo, pdfService)
-	requisitionService = services.NewRequisitionService(requisitionRepo, poService, logService)
+	companySettingsService := services.NewCompanySettingsService(repository.NewPostgresCompanySettingsRepository(db), logService)
+	pdfService := services.NewPDFService(companySettingsService)
+	poDocumentDir := os.Getenv("PO_DOCUMENT_STORAGE_DIR")
+	if poDocumentDir == "" {
+		poDocumentDir = "data/po-documents"
+	}
+	poDocumentStore, err := blobstore.NewLocalStore(poDocumentDir)
+	if err != nil {
+		log.Fatalf("Could not open purchase order document storage: %v", err)
+	}
+	poDocumentService := services.NewPODocumentService(repository.NewPostgresPODocumentRepository(db), poRepo, pdfService, poDocumentStore, logService)
+	poService := services.NewPurchaseOrderService(poRepo, vendorRepo, pdfService, companySettingsService, poDocumentService, logService, services.POIssueImmediate)
+	roleService := services.NewRoleService(repository.NewPostgresRoleRepository(db), logService)
+	delegationService := services.NewDelegationService(repository.NewPostgresDelegationRepository(db), userRepo, roleService, logService)
+	sodService := services.NewSoDService(repository.NewPostgresSoDRepository(db), logService)
+	requisitionService = services.NewRequisitionService(requisitionRepo, poService, logService, roleService, delegationService, sodService, vendorRepo)
 
 	fmt.Println("Starting database seeding...")
 
@@ -80,6 +95,13 @@ func main() {
 
 func cleanData() {
 	fmt.Println("Cleaning existing data...")
+	// These reference their entity by type and id without a foreign key, so
+	// nothing cascades to them when the entities below are deleted.
+	for _, table := range []string{"status_transitions", "comments", "attachments", "sod_violations", "notifications"} {
+		if _, err := db.Exec("DELETE FROM " + table + ";"); err != nil {
+			log.Printf("Warn: could not delete from %s: %v", table, err)
+		}
+	}
 	// Order is important due to foreign key constraints
 	if _, err := db.Exec("DELETE FROM purchase_orders;"); err != nil {
 		log.Printf("Warn: could not delete from purchase_orders: %v", err)
@@ -100,6 +122,9 @@ func cleanData() {
 	db.Exec("ALTER SEQUENCE vendors_id_seq RESTART WITH 1;")
 	db.Exec("ALTER SEQUENCE requisitions_id_seq RESTART WITH 1;")
 	db.Exec("ALTER SEQUENCE purchase_orders_id_seq RESTART WITH 1;")
+	db.Exec("ALTER SEQUENCE status_transitions_id_seq RESTART WITH 1;")
+	db.Exec("ALTER SEQUENCE comments_id_seq RESTART WITH 1;")
+	db.Exec("ALTER SEQUENCE attachments_id_seq RESTART WITH 1;")
 	fmt.Println("Data cleaned.")
 }
 
@@ -161,14 +186,17 @@ func seedRequisitions(users []models.User, vendors []models.Vendor) {
 	vendor1 := vendors[0]
 	vendor2 := vendors[1]
 
+	submittedAt := time.Now()
 	requisitionsToCreate := []models.Requisition{
-		{RequesterID: employee1.ID, VendorID: &vendor1.ID, ItemDescription: "10x New Dell Laptops", Quantity: 10, EstimatedPrice: 1200.00, TotalPrice: 12000.00, Justification: "New hire setup", Status: "Pending"},
-		{RequesterID: employee2.ID, VendorID: &vendor2.ID, ItemDescription: "5x Ergonomic Office Chairs", Quantity: 5, EstimatedPrice: 350.00, TotalPrice: 1750.00, Justification: "Replace old chairs", Status: "Pending"},
-		{RequesterID: employee1.ID, VendorID: &vendor2.ID, ItemDescription: "20x Standing Desks", Quantity: 20, EstimatedPrice: 500.00, TotalPrice: 10000.00, Justification: "Office wellness initiative", Status: "Pending"},
-		{RequesterID: employee2.ID, VendorID: &vendor1.ID, ItemDescription: "1x VR Headset", Quantity: 1, EstimatedPrice: 800.00, TotalPrice: 800.00, Justification: "Research and development", Status: "Rejected"},
+		{RequesterID: employee1.ID, VendorID: &vendor1.ID, ItemDescription: "10x New Dell Laptops", Quantity: 10, EstimatedPrice: 1200.00, TotalPrice: 12000.00, Justification: "New hire setup", Status: "Pending", Round: 1, SubmittedAt: &submittedAt},
+		{RequesterID: employee2.ID, VendorID: &vendor2.ID, ItemDescription: "5x Ergonomic Office Chairs", Quantity: 5, EstimatedPrice: 350.00, TotalPrice: 1750.00, Justification: "Replace old chairs", Status: "Pending", Round: 1, SubmittedAt: &submittedAt},
+		{RequesterID: employee1.ID, VendorID: &vendor2.ID, ItemDescription: "20x Standing Desks", Quantity: 20, EstimatedPrice: 500.00, TotalPrice: 10000.00, Justification: "Office wellness initiative", Status: "Pending", Round: 1, SubmittedAt: &submittedAt},
+		{RequesterID: employee2.ID, VendorID: &vendor1.ID, ItemDescription: "1x VR Headset", Quantity: 1, EstimatedPrice: 800.00, TotalPrice: 800.00, Justification: "Research and development", Status: "Rejected", Round: 1, SubmittedAt: &submittedAt},
+		{RequesterID: employee1.ID, ItemDescription: "Team offsite catering", Justification: "Quarterly planning day", Status: models.RequisitionStatusDraft},
 	}
 
 	for i, req := range requisitionsToCreate {
+		req.Currency = models.DefaultCurrency
 		createdReq, err := requisitionRepo.CreateRequisition(&req)
 		if err != nil {
 			log.Fatalf("Error creating requisition: %v", err)
@@ -180,11 +208,27 @@ func seedRequisitions(users []models.User, vendors []models.Vendor) {
 			fmt.Printf("Approving requisition ID %d to generate a Purchase Order...\n", createdReq.ID)
 			// We'll use the Admin User (ID: 1) to approve this in the seeder
 			adminID := 1
-			err := requisitionService.ApproveRequisition(createdReq.ID, adminID)
+			err := requisitionService.ApproveRequisition(createdReq.ID, adminID, nil)
 			if err != nil {
 				log.Fatalf("Error approving requisition: %v", err)
 			}
 			fmt.Println("Requisition approved and PO created.")
 		}
+
+		// Give the rejected requisition a reason the requester can see
+		if createdReq.Status == models.RequisitionStatusRejected {
+			adminID := 1
+			round := &models.RequisitionRound{
+				RequisitionID: createdReq.ID,
+				Round:         createdReq.Round,
+				SubmittedAt:   createdReq.SubmittedAt,
+				Outcome:       models.RequisitionStatusRejected,
+				Reason:        "Not in this year's R&D budget",
+				DecidedBy:     &adminID,
+			}
+			if err := requisitionRepo.CreateRequisitionRound(round); err != nil {
+				log.Fatalf("Error recording requisition round: %v", err)
+			}
+		}
 	}
 }
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"procurement-system/internal/middleware"
+	"procurement-system/internal/repository"
+	"procurement-system/internal/services"
+	"procurement-system/pkg/blobstore"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// multipartOverhead allows for the multipart framing and form fields around
+// an uploaded file when capping the request body.
+const multipartOverhead = 1 << 20
+
+type AttachmentHandler struct {
+	service       services.AttachmentService
+	maxUploadSize int64
+}
+
+func NewAttachmentHandler(service services.AttachmentService, maxUploadSize int64) *AttachmentHandler {
+	return &AttachmentHandler{service: service, maxUploadSize: maxUploadSize}
+}
+
+// GetAttachments handles the request to list an entity's attachments.
+func (h *AttachmentHandler) GetAttachments(w http.ResponseWriter, r *http.Request) {
+	entityType, entityID, err := entityFromRequest(r)
+	if err != nil {
+		http.Error(w, "Invalid entity ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	attachments, err := h.service.GetAttachments(userID, entityType, entityID)
+	if err != nil {
+		writeAttachmentError(w, err, "Failed to retrieve attachments")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attachments)
+}
+
+// UploadAttachment handles a multipart/form-data upload with the file in the
+// "file" field and an optional "visibility" field.
+func (h *AttachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	entityType, entityID, err := entityFromRequest(r)
+	if err != nil {
+		http.Error(w, "Invalid entity ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize+multipartOverhead)
+	if err := r.ParseMultipartForm(multipartOverhead); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, services.ErrAttachmentTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	visibility := r.FormValue("visibility")
+	if visibility != "" && visibility != "internal" && visibility != "vendor" {
+		http.Error(w, "visibility must be internal or vendor", http.StatusBadRequest)
+		return
+	}
+
+	attachment, err := h.service.UploadAttachment(userID, entityType, entityID, header.Filename, visibility, file)
+	if err != nil {
+		writeAttachmentError(w, err, "Failed to upload attachment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(attachment)
+}
+
+// DownloadAttachment handles the request to download an attachment's content.
+func (h *AttachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	attachment, content, err := h.service.OpenAttachment(userID, id)
+	if err != nil {
+		writeAttachmentError(w, err, "Failed to retrieve attachment")
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.SizeBytes, 10))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Content-SHA256", attachment.SHA256)
+	if _, err := io.Copy(w, content); err != nil {
+		log.Printf("Failed to send attachment %d: %v", id, err)
+	}
+}
+
+// DeleteAttachment handles the request to delete an attachment.
+func (h *AttachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.service.DeleteAttachment(userID, id); err != nil {
+		writeAttachmentError(w, err, "Failed to delete attachment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeAttachmentError maps attachment and entity access errors to HTTP responses.
+func writeAttachmentError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrUnknownEntityType), errors.Is(err, services.ErrEntityNotFound),
+		errors.Is(err, repository.ErrAttachmentNotFound), errors.Is(err, blobstore.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, services.ErrAttachmentTypeNotAllowed):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, services.ErrAttachmentEmpty), errors.Is(err, services.ErrInvalidAttachmentVisibility):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
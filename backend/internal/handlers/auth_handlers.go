@@ -3,10 +3,15 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"math"
+	"net"
 	"net/http"
+	"os"
 	"procurement-system/internal/models"
 	"procurement-system/internal/repository"
 	"procurement-system/internal/services"
+	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -41,6 +46,10 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Email already exists", http.StatusConflict)
 			return
 		}
+		if errors.Is(err, services.ErrRegistrationDisabled) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, "Failed to register user", http.StatusInternalServerError)
 		return
 	}
@@ -61,16 +70,147 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.authService.Login(payload)
+	resp, err := h.authService.Login(payload, clientIP(r))
 	if err != nil {
 		if errors.Is(err, services.ErrInvalidCredentials) {
 			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 			return
 		}
+		if errors.Is(err, services.ErrAccountPendingApproval) || errors.Is(err, services.ErrAccountRejected) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if writeThrottled(w, err) {
+			return
+		}
 		http.Error(w, "Failed to login", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(models.LoginResponse{Token: token})
+	json.NewEncoder(w).Encode(resp)
+}
+
+// VerifyTwoFactor handles the second login step for users with 2FA enabled.
+func (h *AuthHandler) VerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var payload models.TwoFactorChallengePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.authService.VerifyTwoFactor(payload, clientIP(r))
+	if err != nil {
+		if writeTwoFactorError(w, err) {
+			return
+		}
+		http.Error(w, "Failed to verify two-factor code", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// BeginTwoFactorEnrolment handles enrolment for users whose role requires 2FA
+// and who were given an enrolment challenge at login.
+func (h *AuthHandler) BeginTwoFactorEnrolment(w http.ResponseWriter, r *http.Request) {
+	var payload models.TwoFactorEnrolmentPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	enrolment, err := h.authService.BeginTwoFactorEnrolment(payload)
+	if err != nil {
+		if writeTwoFactorError(w, err) {
+			return
+		}
+		http.Error(w, "Failed to start two-factor enrolment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrolment)
+}
+
+// ConfirmTwoFactorEnrolment activates 2FA and completes the login.
+func (h *AuthHandler) ConfirmTwoFactorEnrolment(w http.ResponseWriter, r *http.Request) {
+	var payload models.TwoFactorChallengePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.authService.ConfirmTwoFactorEnrolment(payload, clientIP(r))
+	if err != nil {
+		if writeTwoFactorError(w, err) {
+			return
+		}
+		http.Error(w, "Failed to confirm two-factor enrolment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// writeThrottled writes a 429 with Retry-After if err is a throttling error.
+func writeThrottled(w http.ResponseWriter, err error) bool {
+	var throttled *services.ThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	http.Error(w, err.Error(), http.StatusTooManyRequests)
+	return true
+}
+
+// writeTwoFactorError maps two-factor service errors to HTTP responses.
+// It returns false if err is not a known two-factor error.
+func writeTwoFactorError(w http.ResponseWriter, err error) bool {
+	if writeThrottled(w, err) {
+		return true
+	}
+	switch {
+	case errors.Is(err, services.ErrInvalidChallenge), errors.Is(err, services.ErrInvalidTwoFactorCode):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, services.ErrTwoFactorNotEnrolled), errors.Is(err, services.ErrTwoFactorAlreadyEnabled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrTwoFactorRequiredForRole):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		return false
+	}
+	return true
+}
+
+// clientIP returns the address of the caller. X-Forwarded-For is only honoured
+// when TRUST_PROXY_HEADERS is "true", since clients can set it freely.
+func clientIP(r *http.Request) string {
+	if os.Getenv("TRUST_PROXY_HEADERS") == "true" {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
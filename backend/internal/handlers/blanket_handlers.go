@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"procurement-system/internal/middleware"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"procurement-system/internal/services"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type BlanketHandler struct {
+	service  services.BlanketService
+	validate *validator.Validate
+}
+
+func NewBlanketHandler(service services.BlanketService) *BlanketHandler {
+	return &BlanketHandler{service: service, validate: validator.New()}
+}
+
+// CreateBlanket issues a blanket purchase order with its price list.
+func (h *BlanketHandler) CreateBlanket(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.CreateBlanketPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	po, err := h.service.CreateBlanket(actorID, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidBlanket):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrSoDViolation):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, "Failed to create blanket purchase order", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, po.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(po)
+}
+
+// CreateCallOff releases a call-off against a blanket purchase order. The
+// response includes the blanket's consumption and any ceiling warning.
+func (h *BlanketHandler) CreateCallOff(w http.ResponseWriter, r *http.Request) {
+	blanketID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid purchase order ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.CallOffPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.CreateCallOff(actorID, blanketID, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownPriceItem), errors.Is(err, services.ErrInvalidBlanket):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrBlanketNotOpen), errors.Is(err, repository.ErrBlanketCeilingExceeded):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, services.ErrSoDViolation):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, repository.ErrBlanketNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to create call-off", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, result.PurchaseOrder.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetBlanketConsumption reports how much of each blanket order's ceiling has
+// been consumed by call-offs.
+func (h *BlanketHandler) GetBlanketConsumption(w http.ResponseWriter, r *http.Request) {
+	report, err := h.service.GetBlanketConsumption()
+	if err != nil {
+		http.Error(w, "Failed to retrieve blanket consumption", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
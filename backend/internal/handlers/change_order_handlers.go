@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"procurement-system/internal/middleware"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"procurement-system/internal/services"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type ChangeOrderHandler struct {
+	service  services.ChangeOrderService
+	validate *validator.Validate
+}
+
+func NewChangeOrderHandler(service services.ChangeOrderService) *ChangeOrderHandler {
+	return &ChangeOrderHandler{service: service, validate: validator.New()}
+}
+
+// RequestChangeOrder changes the lines of an issued purchase order. The change
+// is applied as the next revision (200) or waits for approval (202). It honours
+// If-Match against the purchase order's ETag.
+func (h *ChangeOrderHandler) RequestChangeOrder(w http.ResponseWriter, r *http.Request) {
+	poID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid purchase order ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.ChangeOrderPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	co, err := h.service.RequestChangeOrder(actorID, poID, payload, expectedVersion)
+	if err != nil {
+		status, message := changeOrderErrorStatus(err, "Failed to change purchase order")
+		http.Error(w, message, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if co.Status == models.ChangeOrderStatusPending {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	json.NewEncoder(w).Encode(co)
+}
+
+// GetChangeOrders lists a purchase order's change orders, oldest first.
+func (h *ChangeOrderHandler) GetChangeOrders(w http.ResponseWriter, r *http.Request) {
+	poID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid purchase order ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	changeOrders, err := h.service.GetChangeOrders(userID, poID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrEntityNotFound):
+			http.Error(w, "Purchase order not found", http.StatusNotFound)
+		case errors.Is(err, services.ErrForbidden):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, "Failed to retrieve change orders", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changeOrders)
+}
+
+// GetPendingChangeOrders lists the change orders waiting for approval.
+func (h *ChangeOrderHandler) GetPendingChangeOrders(w http.ResponseWriter, r *http.Request) {
+	changeOrders, err := h.service.GetPendingChangeOrders()
+	if err != nil {
+		http.Error(w, "Failed to retrieve change orders", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changeOrders)
+}
+
+// ApproveChangeOrder applies a pending change order. A reason is optional.
+func (h *ChangeOrderHandler) ApproveChangeOrder(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.service.ApproveChangeOrder, false, "Failed to approve change order")
+}
+
+// RejectChangeOrder turns down a pending change order. A reason is required.
+func (h *ChangeOrderHandler) RejectChangeOrder(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.service.RejectChangeOrder, true, "Failed to reject change order")
+}
+
+func (h *ChangeOrderHandler) decide(w http.ResponseWriter, r *http.Request, decide func(actorID int, id int, reason string) (*models.ChangeOrder, error), reasonRequired bool, fallback string) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid change order ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.DecisionReasonPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !(errors.Is(err, io.EOF) && !reasonRequired) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	co, err := decide(actorID, id, payload.Reason)
+	if err != nil {
+		status, message := changeOrderErrorStatus(err, fallback)
+		http.Error(w, message, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(co)
+}
+
+// changeOrderErrorStatus maps a change order error to an HTTP status and the
+// message to show, using fallback for unexpected errors.
+func changeOrderErrorStatus(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, services.ErrSoDViolation):
+		return http.StatusForbidden, err.Error()
+	case errors.Is(err, services.ErrNoChanges), errors.Is(err, services.ErrUnknownPOLine),
+		errors.Is(err, services.ErrDuplicatePOLine), errors.Is(err, services.ErrReasonRequired):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, services.ErrPurchaseOrderNotChangeable), errors.Is(err, services.ErrChangeOrderNotPending),
+		errors.Is(err, services.ErrChangeOrderStale), errors.Is(err, repository.ErrChangeOrderPending), errors.Is(err, repository.ErrStatusConflict),
+		errors.Is(err, repository.ErrBlanketCeilingExceeded):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, repository.ErrVersionConflict):
+		return http.StatusPreconditionFailed, err.Error()
+	case errors.Is(err, repository.ErrPurchaseOrderNotFound):
+		return http.StatusNotFound, "Purchase order not found"
+	case errors.Is(err, repository.ErrChangeOrderNotFound):
+		return http.StatusNotFound, err.Error()
+	default:
+		return http.StatusInternalServerError, fallback
+	}
+}
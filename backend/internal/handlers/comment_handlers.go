@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"procurement-system/internal/middleware"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"procurement-system/internal/services"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type CommentHandler struct {
+	service  services.CommentService
+	timeline services.TimelineService
+	validate *validator.Validate
+}
+
+func NewCommentHandler(service services.CommentService, timeline services.TimelineService) *CommentHandler {
+	return &CommentHandler{service: service, timeline: timeline, validate: validator.New()}
+}
+
+// entityFromRequest reads the {type} and {id} route variables naming the
+// entity a thread belongs to.
+func entityFromRequest(r *http.Request) (string, int, error) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return "", 0, err
+	}
+	return vars["type"], id, nil
+}
+
+// GetComments handles the request to list an entity's comments.
+func (h *CommentHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	entityType, entityID, err := entityFromRequest(r)
+	if err != nil {
+		http.Error(w, "Invalid entity ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	comments, err := h.service.GetComments(userID, entityType, entityID)
+	if err != nil {
+		writeCommentError(w, err, "Failed to retrieve comments")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comments)
+}
+
+// CreateComment handles the request to comment on an entity.
+func (h *CommentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	entityType, entityID, err := entityFromRequest(r)
+	if err != nil {
+		http.Error(w, "Invalid entity ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.CreateCommentPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.service.CreateComment(userID, entityType, entityID, payload)
+	if err != nil {
+		writeCommentError(w, err, "Failed to create comment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// UpdateComment handles the request to edit a comment.
+func (h *CommentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.UpdateCommentPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.service.UpdateComment(userID, id, payload)
+	if err != nil {
+		writeCommentError(w, err, "Failed to update comment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comment)
+}
+
+// DeleteComment handles the request to delete a comment.
+func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.service.DeleteComment(userID, id); err != nil {
+		writeCommentError(w, err, "Failed to delete comment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTimeline handles the request for an entity's timeline of activity and comments.
+func (h *CommentHandler) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	entityType, entityID, err := entityFromRequest(r)
+	if err != nil {
+		http.Error(w, "Invalid entity ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	timeline, err := h.timeline.GetTimeline(userID, entityType, entityID)
+	if err != nil {
+		writeCommentError(w, err, "Failed to retrieve timeline")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(timeline)
+}
+
+// writeCommentError maps comment, timeline and entity access errors to HTTP responses.
+func writeCommentError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrUnknownEntityType), errors.Is(err, services.ErrEntityNotFound),
+		errors.Is(err, repository.ErrCommentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrForbidden), errors.Is(err, services.ErrCommentEditWindowClosed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrInvalidCommentVisibility), errors.Is(err, services.ErrInvalidCommentParent):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"procurement-system/internal/middleware"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"procurement-system/internal/services"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type CompanySettingsHandler struct {
+	service  services.CompanySettingsService
+	validate *validator.Validate
+}
+
+func NewCompanySettingsHandler(service services.CompanySettingsService) *CompanySettingsHandler {
+	return &CompanySettingsHandler{service: service, validate: validator.New()}
+}
+
+// GetCompanySettings returns the settings in use, with their version as ETag.
+func (h *CompanySettingsHandler) GetCompanySettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.service.GetCompanySettings()
+	if err != nil {
+		writeCompanySettingsError(w, err, "Failed to retrieve company settings")
+		return
+	}
+
+	setETag(w, settings.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// GetSettingsHistory lists every version of the settings, newest first.
+func (h *CompanySettingsHandler) GetSettingsHistory(w http.ResponseWriter, r *http.Request) {
+	history, err := h.service.GetSettingsHistory()
+	if err != nil {
+		http.Error(w, "Failed to retrieve company settings history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// GetPDFLayouts lists the layouts purchase order PDFs can be drawn with.
+func (h *CompanySettingsHandler) GetPDFLayouts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services.PDFLayouts())
+}
+
+// UpdateCompanySettings saves a new version of the company profile. It
+// honours If-Match.
+func (h *CompanySettingsHandler) UpdateCompanySettings(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.CompanySettingsPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.service.UpdateCompanySettings(actorID, payload, expectedVersion)
+	if err != nil {
+		writeCompanySettingsError(w, err, "Failed to update company settings")
+		return
+	}
+
+	setETag(w, settings.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// GetLogo returns the logo image in use.
+func (h *CompanySettingsHandler) GetLogo(w http.ResponseWriter, r *http.Request) {
+	logo, err := h.service.GetLogo()
+	if err != nil {
+		writeCompanySettingsError(w, err, "Failed to retrieve logo")
+		return
+	}
+
+	w.Header().Set("Content-Type", logo.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(logo.Data)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(logo.Data)
+}
+
+// SetLogo handles a multipart/form-data upload of a new logo in the "file"
+// field. It honours If-Match.
+func (h *CompanySettingsHandler) SetLogo(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxLogoSize+multipartOverhead)
+	if err := r.ParseMultipartForm(multipartOverhead); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, services.ErrLogoTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	settings, err := h.service.SetLogo(actorID, file, expectedVersion)
+	if err != nil {
+		writeCompanySettingsError(w, err, "Failed to upload logo")
+		return
+	}
+
+	setETag(w, settings.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// RemoveLogo saves a new version of the settings without a logo. It honours
+// If-Match.
+func (h *CompanySettingsHandler) RemoveLogo(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.service.RemoveLogo(actorID, expectedVersion)
+	if err != nil {
+		writeCompanySettingsError(w, err, "Failed to remove logo")
+		return
+	}
+
+	setETag(w, settings.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+func writeCompanySettingsError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrVersionConflict):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, services.ErrLogoTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, services.ErrLogoTypeNotAllowed):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, services.ErrUnknownPDFLayout):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrNoLogo), errors.Is(err, repository.ErrCompanySettingsNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
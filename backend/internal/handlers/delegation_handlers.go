@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"procurement-system/internal/middleware"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"procurement-system/internal/services"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type DelegationHandler struct {
+	service  services.DelegationService
+	validate *validator.Validate
+}
+
+func NewDelegationHandler(service services.DelegationService) *DelegationHandler {
+	return &DelegationHandler{service: service, validate: validator.New()}
+}
+
+// GetMyDelegations handles the request to list the current user's delegations.
+func (h *DelegationHandler) GetMyDelegations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	delegations, err := h.service.GetMyDelegations(userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve delegations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(delegations)
+}
+
+// GetAllDelegations handles the request to list every current and upcoming delegation.
+func (h *DelegationHandler) GetAllDelegations(w http.ResponseWriter, r *http.Request) {
+	delegations, err := h.service.GetAllDelegations()
+	if err != nil {
+		http.Error(w, "Failed to retrieve delegations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(delegations)
+}
+
+// CreateDelegation handles the request to register a delegation. Holders of
+// requisition:manage may delegate on behalf of another approver.
+func (h *DelegationHandler) CreateDelegation(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.CreateDelegationPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var delegation *models.Delegation
+	var err error
+	if middleware.HasPermission(r.Context(), models.PermRequisitionManage) {
+		delegation, err = h.service.AdminCreateDelegation(actorID, payload)
+	} else {
+		delegation, err = h.service.CreateDelegation(actorID, payload)
+	}
+	if err != nil {
+		writeDelegationError(w, err, "Failed to create delegation")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(delegation)
+}
+
+// DeleteDelegation handles the request to withdraw a delegation. Delegators
+// may withdraw their own; holders of requisition:manage may withdraw any.
+func (h *DelegationHandler) DeleteDelegation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid delegation ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	if middleware.HasPermission(r.Context(), models.PermRequisitionManage) {
+		err = h.service.AdminDeleteDelegation(actorID, id)
+	} else {
+		err = h.service.DeleteDelegation(actorID, id)
+	}
+	if err != nil {
+		writeDelegationError(w, err, "Failed to delete delegation")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeDelegationError maps delegation service errors to HTTP responses.
+func writeDelegationError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrDelegationNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrInvalidDelegation), errors.Is(err, services.ErrNotAnApprover),
+		errors.Is(err, services.ErrUnknownUser):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
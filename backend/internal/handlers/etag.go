@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errInvalidIfMatch = errors.New(`If-Match must be a single ETag such as "3", or *`)
+
+// setETag publishes a record's version as its ETag.
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(version)+`"`)
+}
+
+// ifMatchVersion reads the version a client last saw from the If-Match header.
+// It returns nil when the header is absent or *, meaning any version will do.
+func ifMatchVersion(r *http.Request) (*int, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return nil, nil
+	}
+
+	value = strings.TrimPrefix(value, "W/")
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return nil, errInvalidIfMatch
+	}
+	version, err := strconv.Atoi(value[1 : len(value)-1])
+	if err != nil {
+		return nil, errInvalidIfMatch
+	}
+	return &version, nil
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"procurement-system/internal/middleware"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"procurement-system/internal/services"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+// InvitationHandler handles HTTP requests for registration invitations.
+type InvitationHandler struct {
+	service  services.InvitationService
+	validate *validator.Validate
+}
+
+// NewInvitationHandler creates a new instance of InvitationHandler.
+func NewInvitationHandler(service services.InvitationService) *InvitationHandler {
+	return &InvitationHandler{
+		service:  service,
+		validate: validator.New(),
+	}
+}
+
+// CreateInvitation handles the admin request to invite someone with a given role.
+func (h *InvitationHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	var payload models.CreateInvitationPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := h.service.CreateInvitation(actorID, payload)
+	if err != nil {
+		if errors.Is(err, repository.ErrRoleNotFound) {
+			http.Error(w, "Unknown role", http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetAllInvitations handles the admin request to list invitations.
+func (h *InvitationHandler) GetAllInvitations(w http.ResponseWriter, r *http.Request) {
+	invitations, err := h.service.GetAllInvitations()
+	if err != nil {
+		http.Error(w, "Failed to retrieve invitations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+// RevokeInvitation handles the admin request to revoke an unused invitation.
+func (h *InvitationHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.service.RevokeInvitation(actorID, id); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrInvitationNotFound):
+			http.Error(w, "Invitation not found", http.StatusNotFound)
+		case errors.Is(err, repository.ErrInvitationUsed):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to revoke invitation", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AcceptInvitation handles registration with an invitation token. It is unauthenticated.
+func (h *InvitationHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var payload models.AcceptInvitationPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.service.AcceptInvitation(payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvitationInvalid):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, repository.ErrEmailExists):
+			http.Error(w, "Email already exists", http.StatusConflict)
+		default:
+			http.Error(w, "Failed to register user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
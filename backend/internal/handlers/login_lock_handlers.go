@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"procurement-system/internal/middleware"
+	"procurement-system/internal/repository"
+	"procurement-system/internal/services"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// LoginLockHandler handles HTTP requests for viewing and clearing login lockouts.
+type LoginLockHandler struct {
+	service services.LoginThrottleService
+}
+
+// NewLoginLockHandler creates a new instance of LoginLockHandler.
+func NewLoginLockHandler(service services.LoginThrottleService) *LoginLockHandler {
+	return &LoginLockHandler{service: service}
+}
+
+// GetActiveLocks handles the request to list accounts and IPs that are currently locked.
+func (h *LoginLockHandler) GetActiveLocks(w http.ResponseWriter, r *http.Request) {
+	locks, err := h.service.GetActiveLocks()
+	if err != nil {
+		http.Error(w, "Failed to retrieve login locks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(locks)
+}
+
+// ClearLock handles the request to clear a login lock.
+func (h *LoginLockHandler) ClearLock(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid lock ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.service.ClearLock(actorID, id); err != nil {
+		if errors.Is(err, repository.ErrLoginThrottleNotFound) {
+			http.Error(w, "Login lock not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to clear login lock", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,36 +2,48 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"procurement-system/internal/middleware"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
 	"procurement-system/internal/services"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
 )
 
 type NavigationHandler struct {
-	service services.NavigationService
+	service  services.NavigationService
+	validate *validator.Validate
 }
 
 func NewNavigationHandler(service services.NavigationService) *NavigationHandler {
-	return &NavigationHandler{service: service}
+	return &NavigationHandler{service: service, validate: validator.New()}
 }
 
 func (h *NavigationHandler) GetMenu(w http.ResponseWriter, r *http.Request) {
-	role, ok := r.Context().Value(middleware.UserRoleKey).(string)
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
 	if !ok {
-		http.Error(w, "Could not get user role from context", http.StatusInternalServerError)
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
 		return
 	}
 
-	menu := h.service.GetMenuForRole(role)
+	menu, err := h.service.GetMenu(userID)
+	if err != nil {
+		http.Error(w, "Failed to build menu", http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(menu)
 }
 
 func (h *NavigationHandler) GetBreadcrumbs(w http.ResponseWriter, r *http.Request) {
-	role, ok := r.Context().Value(middleware.UserRoleKey).(string)
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
 	if !ok {
-		http.Error(w, "Could not get user role from context", http.StatusInternalServerError)
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
 		return
 	}
 
@@ -41,8 +53,124 @@ func (h *NavigationHandler) GetBreadcrumbs(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	breadcrumbs := h.service.GetBreadcrumbsForPath(role, path)
+	breadcrumbs, err := h.service.GetBreadcrumbsForPath(userID, path)
+	if err != nil {
+		http.Error(w, "Failed to build breadcrumbs", http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(breadcrumbs)
 }
+
+// GetAllMenuItems handles the request to list every stored menu item.
+func (h *NavigationHandler) GetAllMenuItems(w http.ResponseWriter, r *http.Request) {
+	items, err := h.service.GetAllMenuItems()
+	if err != nil {
+		http.Error(w, "Failed to retrieve menu items", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// CreateMenuItem handles the request to add a menu item.
+func (h *NavigationHandler) CreateMenuItem(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.MenuItemPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.service.CreateMenuItem(actorID, payload)
+	if err != nil {
+		writeMenuItemError(w, err, "Failed to create menu item")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(item)
+}
+
+// UpdateMenuItem handles the request to change a menu item.
+func (h *NavigationHandler) UpdateMenuItem(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid menu item ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.MenuItemPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.service.UpdateMenuItem(actorID, id, payload)
+	if err != nil {
+		writeMenuItemError(w, err, "Failed to update menu item")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// DeleteMenuItem handles the request to remove a menu item and its children.
+func (h *NavigationHandler) DeleteMenuItem(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid menu item ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.service.DeleteMenuItem(actorID, id); err != nil {
+		writeMenuItemError(w, err, "Failed to delete menu item")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeMenuItemError maps navigation service errors to HTTP responses.
+func writeMenuItemError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrMenuItemNotFound):
+		http.Error(w, "Menu item not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidMenuParent), errors.Is(err, services.ErrUnknownPermission),
+		errors.Is(err, services.ErrUnknownBadgeProvider):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
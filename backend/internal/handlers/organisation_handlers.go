@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"procurement-system/internal/middleware"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"procurement-system/internal/services"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+// maxOrgChartSize caps the size of an uploaded org chart CSV.
+const maxOrgChartSize = 5 << 20
+
+type OrganisationHandler struct {
+	service  services.OrganisationService
+	validate *validator.Validate
+}
+
+func NewOrganisationHandler(service services.OrganisationService) *OrganisationHandler {
+	return &OrganisationHandler{service: service, validate: validator.New()}
+}
+
+// GetAllDepartments handles the request to list every department.
+func (h *OrganisationHandler) GetAllDepartments(w http.ResponseWriter, r *http.Request) {
+	departments, err := h.service.GetAllDepartments()
+	if err != nil {
+		http.Error(w, "Failed to retrieve departments", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(departments)
+}
+
+// CreateDepartment handles the request to add a department.
+func (h *OrganisationHandler) CreateDepartment(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.DepartmentPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dept, err := h.service.CreateDepartment(actorID, payload)
+	if err != nil {
+		writeOrganisationError(w, err, "Failed to create department")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dept)
+}
+
+// UpdateDepartment handles the request to rename, move or change the head of a department.
+func (h *OrganisationHandler) UpdateDepartment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid department ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.DepartmentPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dept, err := h.service.UpdateDepartment(actorID, id, payload)
+	if err != nil {
+		writeOrganisationError(w, err, "Failed to update department")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dept)
+}
+
+// DeleteDepartment handles the request to remove an empty department.
+func (h *OrganisationHandler) DeleteDepartment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid department ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.service.DeleteDepartment(actorID, id); err != nil {
+		writeOrganisationError(w, err, "Failed to delete department")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUserOrganisation handles the request to show a user's department and managers.
+func (h *OrganisationHandler) GetUserOrganisation(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	h.writeUserOrganisation(w, userID)
+}
+
+// GetMyOrganisation handles the request to show the current user's department and managers.
+func (h *OrganisationHandler) GetMyOrganisation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeUserOrganisation(w, userID)
+}
+
+func (h *OrganisationHandler) writeUserOrganisation(w http.ResponseWriter, userID int) {
+	org, err := h.service.GetUserOrganisation(userID)
+	if err != nil {
+		writeOrganisationError(w, err, "Failed to retrieve organisation")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(org)
+}
+
+// SetUserOrganisation handles the request to set a user's department and line manager.
+func (h *OrganisationHandler) SetUserOrganisation(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.UserOrganisationPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.service.SetUserOrganisation(actorID, userID, payload)
+	if err != nil {
+		writeOrganisationError(w, err, "Failed to update organisation")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// ImportOrgChart handles an org chart CSV upload sent as the request body.
+func (h *OrganisationHandler) ImportOrgChart(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.service.ImportOrgChart(actorID, http.MaxBytesReader(w, r.Body, maxOrgChartSize))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidOrgChart) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(result)
+			return
+		}
+		http.Error(w, "Failed to import org chart", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// writeOrganisationError maps organisation service errors to HTTP responses.
+func writeOrganisationError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrDepartmentNotFound), errors.Is(err, repository.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repository.ErrDepartmentExists), errors.Is(err, repository.ErrDepartmentNotEmpty):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrUnknownUser), errors.Is(err, services.ErrUnknownDepartment),
+		errors.Is(err, services.ErrInvalidDepartmentParent), errors.Is(err, services.ErrInvalidManager):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"procurement-system/internal/middleware"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"procurement-system/internal/services"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type PODispatchHandler struct {
+	service  services.PODispatchService
+	validate *validator.Validate
+}
+
+func NewPODispatchHandler(service services.PODispatchService) *PODispatchHandler {
+	return &PODispatchHandler{service: service, validate: validator.New()}
+}
+
+// SendToVendor emails a purchase order's PDF to its vendor. It returns 201
+// once sent, or 202 if the first attempt failed and the send will be retried.
+func (h *PODispatchHandler) SendToVendor(w http.ResponseWriter, r *http.Request) {
+	poID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid purchase order ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.SendToVendorPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dispatch, err := h.service.SendToVendor(actorID, poID, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrPurchaseOrderNotFound):
+			http.Error(w, "Purchase order not found", http.StatusNotFound)
+		case errors.Is(err, services.ErrVendorNoEmail):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to send purchase order", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if dispatch.Status == models.DispatchStatusSent {
+		w.WriteHeader(http.StatusCreated)
+	} else {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	json.NewEncoder(w).Encode(dispatch)
+}
+
+// GetDispatches lists the times a purchase order was sent to its vendor,
+// newest first, with the outcome of each.
+func (h *PODispatchHandler) GetDispatches(w http.ResponseWriter, r *http.Request) {
+	poID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid purchase order ID", http.StatusBadRequest)
+		return
+	}
+
+	dispatches, err := h.service.GetDispatches(poID)
+	if err != nil {
+		if errors.Is(err, repository.ErrPurchaseOrderNotFound) {
+			http.Error(w, "Purchase order not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve dispatches", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dispatches)
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"procurement-system/internal/repository"
+	"procurement-system/internal/services"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// maxVerifiedDocumentSize is the largest PDF accepted for verification.
+const maxVerifiedDocumentSize = 20 << 20
+
+type PODocumentHandler struct {
+	service   services.PODocumentService
+	poService services.PurchaseOrderService
+}
+
+func NewPODocumentHandler(service services.PODocumentService, poService services.PurchaseOrderService) *PODocumentHandler {
+	return &PODocumentHandler{service: service, poService: poService}
+}
+
+// GetPurchaseOrderPDF sends the archived PDF of a purchase order: the latest
+// one, or the latest of ?revision=N. With ?render=current the PDF is drawn
+// afresh from the order's current data instead, and not archived.
+func (h *PODocumentHandler) GetPurchaseOrderPDF(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid purchase order ID", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"PO-%d.pdf\"", id))
+
+	if r.URL.Query().Get("render") == "current" {
+		pdfBuffer, err := h.poService.GeneratePurchaseOrderPDF(id)
+		if err != nil {
+			writePODocumentError(w, err, fmt.Sprintf("Failed to generate PDF: %v", err))
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(pdfBuffer.Len()))
+		if _, err := w.Write(pdfBuffer.Bytes()); err != nil {
+			log.Printf("Failed to send purchase order %d PDF: %v", id, err)
+		}
+		return
+	}
+
+	var revision *int
+	if value := r.URL.Query().Get("revision"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid revision", http.StatusBadRequest)
+			return
+		}
+		revision = &n
+	}
+
+	doc, content, err := h.service.OpenDocument(id, revision)
+	if err != nil {
+		writePODocumentError(w, err, "Failed to retrieve PDF")
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Length", strconv.FormatInt(doc.SizeBytes, 10))
+	w.Header().Set("X-Content-SHA256", doc.SHA256)
+	w.Header().Set("X-PO-Revision", strconv.Itoa(doc.Revision))
+	w.Header().Set("X-PO-Document-Original", strconv.FormatBool(doc.Original()))
+	if _, err := io.Copy(w, content); err != nil {
+		log.Printf("Failed to send purchase order %d document %d: %v", id, doc.ID, err)
+	}
+}
+
+// GetDocuments lists a purchase order's archived documents, newest first.
+func (h *PODocumentHandler) GetDocuments(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid purchase order ID", http.StatusBadRequest)
+		return
+	}
+
+	documents, err := h.service.GetDocuments(id)
+	if err != nil {
+		http.Error(w, "Failed to retrieve documents", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(documents)
+}
+
+// VerifyDocument checks a PDF uploaded as multipart/form-data in the "file"
+// field against the archive.
+func (h *PODocumentHandler) VerifyDocument(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxVerifiedDocumentSize+multipartOverhead)
+	if err := r.ParseMultipartForm(multipartOverhead); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Document is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	result, err := h.service.VerifyDocument(file)
+	if err != nil {
+		http.Error(w, "Failed to verify document", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func writePODocumentError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrPurchaseOrderNotFound):
+		http.Error(w, "Purchase order not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrPODocumentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
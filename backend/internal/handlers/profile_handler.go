@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"procurement-system/internal/middleware"
 	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
 	"procurement-system/internal/services"
 
 	"github.com/go-playground/validator/v10"
@@ -63,6 +65,10 @@ func (h *ProfileHandler) UpdateMyProfile(w http.ResponseWriter, r *http.Request)
 
 	user, err := h.userService.UpdateMyProfile(userID, payload)
 	if err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
 		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
 		return
 	}
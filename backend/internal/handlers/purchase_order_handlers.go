@@ -3,21 +3,24 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"net/http"
+	"procurement-system/internal/middleware"
+	"procurement-system/internal/models"
 	"procurement-system/internal/repository"
 	"procurement-system/internal/services"
 	"strconv"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 )
 
 type PurchaseOrderHandler struct {
-	service services.PurchaseOrderService
+	service  services.PurchaseOrderService
+	validate *validator.Validate
 }
 
 func NewPurchaseOrderHandler(service services.PurchaseOrderService) *PurchaseOrderHandler {
-	return &PurchaseOrderHandler{service: service}
+	return &PurchaseOrderHandler{service: service, validate: validator.New()}
 }
 
 func (h *PurchaseOrderHandler) GetPurchaseOrderByID(w http.ResponseWriter, r *http.Request) {
@@ -38,6 +41,7 @@ func (h *PurchaseOrderHandler) GetPurchaseOrderByID(w http.ResponseWriter, r *ht
 		return
 	}
 
+	setETag(w, po.Version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(po)
 }
@@ -53,33 +57,125 @@ func (h *PurchaseOrderHandler) GetAllPurchaseOrders(w http.ResponseWriter, r *ht
 	json.NewEncoder(w).Encode(pos)
 }
 
-func (h *PurchaseOrderHandler) GetPurchaseOrderPDF(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+// GetBuyQueue lists the approved requisitions waiting for a purchase order,
+// grouped by vendor, currency and ship-to.
+func (h *PurchaseOrderHandler) GetBuyQueue(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.service.GetBuyQueue()
 	if err != nil {
-		http.Error(w, "Invalid purchase order ID", http.StatusBadRequest)
+		http.Error(w, "Failed to retrieve buy queue", http.StatusInternalServerError)
 		return
 	}
 
-	pdfBuffer, err := h.service.GeneratePurchaseOrderPDF(id)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+// ConsolidateRequisitions issues one purchase order for several requisitions
+// from the buy queue.
+func (h *PurchaseOrderHandler) ConsolidateRequisitions(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.ConsolidatePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	po, err := h.service.ConsolidateRequisitions(actorID, payload.RequisitionIDs)
 	if err != nil {
-		if errors.Is(err, repository.ErrPurchaseOrderNotFound) {
-			http.Error(w, "Purchase order not found", http.StatusNotFound)
-			return
+		switch {
+		case errors.Is(err, services.ErrIncompatibleRequisitions):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrNotInBuyQueue), errors.Is(err, repository.ErrRequisitionAlreadyOrdered):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to consolidate requisitions", http.StatusInternalServerError)
 		}
-		http.Error(w, fmt.Sprintf("Failed to generate PDF: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	// Set headers to prompt download
-	w.Header().Set("Content-Type", "application/pdf")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"PO-%d.pdf\"", id))
-	w.Header().Set("Content-Length", strconv.Itoa(pdfBuffer.Len()))
+	setETag(w, po.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(po)
+}
+
+// CancelPurchaseOrder cancels an issued purchase order before anything has
+// been received. It honours If-Match.
+func (h *PurchaseOrderHandler) CancelPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	var payload models.CancelPurchaseOrderPayload
+	h.end(w, r, &payload, func(actorID int, id int, expectedVersion *int) (*models.PurchaseOrder, error) {
+		return h.service.CancelPurchaseOrder(actorID, id, payload, expectedVersion)
+	}, "Failed to cancel purchase order")
+}
+
+// ClosePurchaseOrder closes an issued purchase order out short. It honours
+// If-Match.
+func (h *PurchaseOrderHandler) ClosePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	var payload models.ClosePurchaseOrderPayload
+	h.end(w, r, &payload, func(actorID int, id int, expectedVersion *int) (*models.PurchaseOrder, error) {
+		return h.service.ClosePurchaseOrder(actorID, id, payload, expectedVersion)
+	}, "Failed to close purchase order")
+}
 
-	// Write the PDF bytes to the response
-	_, err = w.Write(pdfBuffer.Bytes())
+// end decodes and validates payload, then ends the order with endOrder.
+func (h *PurchaseOrderHandler) end(w http.ResponseWriter, r *http.Request, payload interface{}, endOrder func(actorID int, id int, expectedVersion *int) (*models.PurchaseOrder, error), fallback string) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		// Log the error, but the response has likely already been partially sent.
-		http.Error(w, "Failed to write PDF to response", http.StatusInternalServerError)
+		http.Error(w, "Invalid purchase order ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	po, err := endOrder(actorID, id, expectedVersion)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrReasonRequired):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrPurchaseOrderNotOpen), errors.Is(err, repository.ErrPurchaseOrderReceived),
+			errors.Is(err, repository.ErrStatusConflict):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, repository.ErrVersionConflict):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		case errors.Is(err, repository.ErrPurchaseOrderNotFound):
+			http.Error(w, "Purchase order not found", http.StatusNotFound)
+		default:
+			http.Error(w, fallback, http.StatusInternalServerError)
+		}
+		return
 	}
+
+	setETag(w, po.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(po)
 }
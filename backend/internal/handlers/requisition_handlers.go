@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"procurement-system/internal/middleware"
 	"procurement-system/internal/models"
@@ -70,6 +71,53 @@ func (h *RequisitionHandler) GetMyRequisitions(w http.ResponseWriter, r *http.Re
 	json.NewEncoder(w).Encode(requisitions)
 }
 
+// SubmitRequisition sends one of the current user's drafts for approval.
+func (h *RequisitionHandler) SubmitRequisition(w http.ResponseWriter, r *http.Request) {
+	h.changeOwnRequisition(w, r, h.service.SubmitRequisition, "Failed to submit requisition")
+}
+
+// WithdrawRequisition pulls one of the current user's pending requisitions
+// back to draft.
+func (h *RequisitionHandler) WithdrawRequisition(w http.ResponseWriter, r *http.Request) {
+	h.changeOwnRequisition(w, r, h.service.WithdrawRequisition, "Failed to withdraw requisition")
+}
+
+func (h *RequisitionHandler) changeOwnRequisition(w http.ResponseWriter, r *http.Request, change func(requisitionID int, requesterID int) (*models.Requisition, error), fallback string) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid requisition ID", http.StatusBadRequest)
+		return
+	}
+
+	requesterID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	requisition, err := change(id, requesterID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrForbidden):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, services.ErrCannotModify), errors.Is(err, services.ErrIncompleteRequisition):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, repository.ErrStatusConflict):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, repository.ErrVersionConflict):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		case errors.Is(err, repository.ErrRequisitionNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, fallback, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requisition)
+}
+
 func (h *RequisitionHandler) GetAllRequisitions(w http.ResponseWriter, r *http.Request) {
 	requisitions, err := h.service.GetAllRequisitions()
 	if err != nil {
@@ -106,8 +154,14 @@ func (h *RequisitionHandler) ApproveRequisition(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	if err := h.service.ApproveRequisition(id, adminID); err != nil {
-		http.Error(w, "Failed to approve requisition", http.StatusInternalServerError)
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ApproveRequisition(id, adminID, expectedVersion); err != nil {
+		writeDecisionError(w, err, "Failed to approve requisition")
 		return
 	}
 
@@ -115,9 +169,19 @@ func (h *RequisitionHandler) ApproveRequisition(w http.ResponseWriter, r *http.R
 	json.NewEncoder(w).Encode(map[string]string{"message": "Requisition approved successfully"})
 }
 
+// RejectRequisition rejects a pending requisition. A reason is required.
 func (h *RequisitionHandler) RejectRequisition(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+	h.decideWithReason(w, r, h.service.RejectRequisition, "Failed to reject requisition", "Requisition rejected successfully")
+}
+
+// ReturnRequisition sends a pending requisition back to its requester for
+// changes. A reason is required.
+func (h *RequisitionHandler) ReturnRequisition(w http.ResponseWriter, r *http.Request) {
+	h.decideWithReason(w, r, h.service.ReturnRequisition, "Failed to return requisition", "Requisition returned for changes")
+}
+
+func (h *RequisitionHandler) decideWithReason(w http.ResponseWriter, r *http.Request, decide func(requisitionID int, adminID int, reason string, expectedVersion *int) error, fallback string, message string) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
 		http.Error(w, "Invalid requisition ID", http.StatusBadRequest)
 		return
@@ -129,16 +193,249 @@ func (h *RequisitionHandler) RejectRequisition(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	if err := h.service.RejectRequisition(id, adminID); err != nil {
-		http.Error(w, "Failed to reject requisition", http.StatusInternalServerError)
+	var payload models.DecisionReasonPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := decide(id, adminID, payload.Reason, expectedVersion); err != nil {
+		writeDecisionError(w, err, fallback)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Requisition rejected successfully"})
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// GetRequisition returns a single requisition with its version as the ETag.
+func (h *RequisitionHandler) GetRequisition(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid requisition ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	requisition, err := h.service.GetRequisition(id, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrForbidden):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, repository.ErrRequisitionNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to retrieve requisition", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, requisition.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requisition)
+}
+
+// GetRequisitionHistory returns the review rounds of a requisition with the
+// outcome and reason of each.
+func (h *RequisitionHandler) GetRequisitionHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid requisition ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	rounds, err := h.service.GetRequisitionHistory(id, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrForbidden):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, repository.ErrRequisitionNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to retrieve requisition history", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rounds)
+}
+
+// GetApprovalQueue returns the pending requisitions the current user may
+// decide, including those covered by delegations to them.
+func (h *RequisitionHandler) GetApprovalQueue(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	requisitions, err := h.service.GetApprovalQueue(userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve approval queue", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requisitions)
+}
+
+// ReassignApprover moves a stuck pending requisition to another approver.
+func (h *RequisitionHandler) ReassignApprover(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid requisition ID", http.StatusBadRequest)
+		return
+	}
+
+	adminID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.ReassignApproverPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	requisition, err := h.service.ReassignApprover(id, adminID, payload)
+	if err != nil {
+		switch err {
+		case services.ErrCannotModify, services.ErrNotAnApprover:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case repository.ErrStatusConflict:
+			http.Error(w, err.Error(), http.StatusConflict)
+		case repository.ErrVersionConflict:
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		case repository.ErrRequisitionNotFound:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "Failed to reassign requisition", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requisition)
+}
+
+// writeDecisionError maps approve, reject and return failures to HTTP responses.
+func writeDecisionError(w http.ResponseWriter, err error, fallback string) {
+	status, message := decisionErrorStatus(err, fallback)
+	http.Error(w, message, status)
+}
+
+// decisionErrorStatus maps an error from approving, rejecting or returning a
+// requisition to an HTTP status and message.
+func decisionErrorStatus(err error, fallback string) (int, string) {
+	switch {
+	case errors.Is(err, services.ErrForbidden), errors.Is(err, services.ErrDelegationLimitExceeded),
+		errors.Is(err, services.ErrSoDViolation):
+		return http.StatusForbidden, err.Error()
+	case errors.Is(err, services.ErrCannotModify), errors.Is(err, services.ErrReasonRequired),
+		errors.Is(err, services.ErrDuplicateBulkItem):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, repository.ErrStatusConflict):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, repository.ErrVersionConflict):
+		return http.StatusPreconditionFailed, err.Error()
+	case errors.Is(err, repository.ErrRequisitionNotFound):
+		return http.StatusNotFound, err.Error()
+	default:
+		return http.StatusInternalServerError, fallback
+	}
+}
+
+// BulkApproveRequisitions approves several pending requisitions, reporting the
+// outcome for each.
+func (h *RequisitionHandler) BulkApproveRequisitions(w http.ResponseWriter, r *http.Request) {
+	h.decideBulk(w, r, h.service.BulkApproveRequisitions, "Failed to approve requisition")
+}
+
+// BulkRejectRequisitions rejects several pending requisitions with one reason,
+// reporting the outcome for each.
+func (h *RequisitionHandler) BulkRejectRequisitions(w http.ResponseWriter, r *http.Request) {
+	h.decideBulk(w, r, h.service.BulkRejectRequisitions, "Failed to reject requisition")
+}
+
+func (h *RequisitionHandler) decideBulk(w http.ResponseWriter, r *http.Request, decide func(requisitionIDs []int, adminID int, reason string) (*services.BulkDecision, error), fallback string) {
+	adminID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.BulkDecisionPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	decision, err := decide(payload.RequisitionIDs, adminID, payload.Reason)
+	if err != nil {
+		writeDecisionError(w, err, fallback+"s")
+		return
+	}
+
+	response := models.BulkDecisionResponse{BatchID: decision.BatchID, Results: make([]models.BulkDecisionResult, len(decision.Outcomes))}
+	for i, outcome := range decision.Outcomes {
+		result := models.BulkDecisionResult{RequisitionID: outcome.RequisitionID, Success: true, Status: http.StatusOK}
+		if outcome.Err != nil {
+			result.Success = false
+			result.Status, result.Error = decisionErrorStatus(outcome.Err, fallback)
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+		response.Results[i] = result
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
+// UpdateRequisition lets requesters edit their own drafts.
+// Holders of requisition:manage are handed to AdminUpdateRequisition.
 func (h *RequisitionHandler) UpdateRequisition(w http.ResponseWriter, r *http.Request) {
+	if middleware.HasPermission(r.Context(), models.PermRequisitionManage) {
+		h.AdminUpdateRequisition(w, r)
+		return
+	}
+
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
@@ -163,13 +460,21 @@ func (h *RequisitionHandler) UpdateRequisition(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	requisition, err := h.service.UpdateRequisition(id, requesterID, payload)
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	requisition, err := h.service.UpdateRequisition(id, requesterID, payload, expectedVersion)
 	if err != nil {
 		switch err {
 		case services.ErrForbidden:
 			http.Error(w, err.Error(), http.StatusForbidden)
 		case services.ErrCannotModify:
 			http.Error(w, err.Error(), http.StatusBadRequest)
+		case repository.ErrVersionConflict:
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
 		case repository.ErrRequisitionNotFound:
 			http.Error(w, err.Error(), http.StatusNotFound)
 		default:
@@ -178,11 +483,19 @@ func (h *RequisitionHandler) UpdateRequisition(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	setETag(w, requisition.Version)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(requisition)
 }
 
+// DeleteRequisition lets requesters delete their own drafts.
+// Holders of requisition:manage are handed to AdminDeleteRequisition.
 func (h *RequisitionHandler) DeleteRequisition(w http.ResponseWriter, r *http.Request) {
+	if middleware.HasPermission(r.Context(), models.PermRequisitionManage) {
+		h.AdminDeleteRequisition(w, r)
+		return
+	}
+
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
@@ -196,13 +509,21 @@ func (h *RequisitionHandler) DeleteRequisition(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	err = h.service.DeleteRequisition(id, requesterID)
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.DeleteRequisition(id, requesterID, expectedVersion)
 	if err != nil {
 		switch err {
 		case services.ErrForbidden:
 			http.Error(w, err.Error(), http.StatusForbidden)
 		case services.ErrCannotModify:
 			http.Error(w, err.Error(), http.StatusBadRequest)
+		case repository.ErrVersionConflict:
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
 		case repository.ErrRequisitionNotFound:
 			http.Error(w, err.Error(), http.StatusNotFound)
 		default:
@@ -239,16 +560,27 @@ func (h *RequisitionHandler) AdminUpdateRequisition(w http.ResponseWriter, r *ht
 		return
 	}
 
-	requisition, err := h.service.AdminUpdateRequisition(id, adminID, payload)
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	requisition, err := h.service.AdminUpdateRequisition(id, adminID, payload, expectedVersion)
 	if err != nil {
 		if err == repository.ErrRequisitionNotFound {
 			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if errors.Is(err, services.ErrIncompleteRequisition) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else if err == repository.ErrVersionConflict {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
 		} else {
 			http.Error(w, "Failed to update requisition", http.StatusInternalServerError)
 		}
 		return
 	}
 
+	setETag(w, requisition.Version)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(requisition)
 }
@@ -267,10 +599,20 @@ func (h *RequisitionHandler) AdminDeleteRequisition(w http.ResponseWriter, r *ht
 		return
 	}
 
-	err = h.service.AdminDeleteRequisition(id, adminID)
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.AdminDeleteRequisition(id, adminID, expectedVersion)
 	if err != nil {
 		if err == repository.ErrRequisitionNotFound {
 			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if err == repository.ErrVersionConflict {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+		} else if err == repository.ErrRequisitionOrdered {
+			http.Error(w, err.Error(), http.StatusConflict)
 		} else {
 			http.Error(w, "Failed to delete requisition", http.StatusInternalServerError)
 		}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"procurement-system/internal/middleware"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"procurement-system/internal/services"
+	"sort"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+// RoleHandler handles HTTP requests for role definitions and user role assignments.
+type RoleHandler struct {
+	service  services.RoleService
+	validate *validator.Validate
+}
+
+// NewRoleHandler creates a new instance of RoleHandler.
+func NewRoleHandler(service services.RoleService) *RoleHandler {
+	return &RoleHandler{
+		service:  service,
+		validate: validator.New(),
+	}
+}
+
+// GetAllPermissions handles the request to list the permission catalogue.
+func (h *RoleHandler) GetAllPermissions(w http.ResponseWriter, r *http.Request) {
+	permissions, err := h.service.GetAllPermissions()
+	if err != nil {
+		http.Error(w, "Failed to retrieve permissions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(permissions)
+}
+
+// GetMyPermissions handles the request for the current user's effective permissions.
+func (h *RoleHandler) GetMyPermissions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	granted, err := h.service.GetUserPermissions(userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve permissions", http.StatusInternalServerError)
+		return
+	}
+
+	permissions := make([]string, 0, len(granted))
+	for p := range granted {
+		permissions = append(permissions, p)
+	}
+	sort.Strings(permissions)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(permissions)
+}
+
+// GetAllRoles handles the request to list roles and their permissions.
+func (h *RoleHandler) GetAllRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.service.GetAllRoles()
+	if err != nil {
+		http.Error(w, "Failed to retrieve roles", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(roles)
+}
+
+// CreateRole handles the request to define a new role.
+func (h *RoleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.CreateRolePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	role, err := h.service.CreateRole(actorID, payload)
+	if err != nil {
+		if errors.Is(err, repository.ErrRoleExists) {
+			http.Error(w, "Role already exists", http.StatusConflict)
+			return
+		}
+		writeRoleError(w, err, "Failed to create role")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(role)
+}
+
+// UpdateRole handles the request to change a role's description and permissions.
+func (h *RoleHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid role ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.UpdateRolePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	role, err := h.service.UpdateRole(actorID, id, payload)
+	if err != nil {
+		writeRoleError(w, err, "Failed to update role")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(role)
+}
+
+// DeleteRole handles the request to delete a custom role.
+func (h *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid role ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.service.DeleteRole(actorID, id); err != nil {
+		if errors.Is(err, repository.ErrRoleInUse) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		writeRoleError(w, err, "Failed to delete role")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUserRoles handles the request to view a user's roles.
+func (h *RoleHandler) GetUserRoles(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	roles, err := h.service.GetUserRoles(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to retrieve user roles", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(roles)
+}
+
+// SetUserRoles handles the request to replace a user's additional roles.
+func (h *RoleHandler) SetUserRoles(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.SetUserRolesPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	roles, err := h.service.SetUserRoles(actorID, userID, payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrUserNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, repository.ErrRoleNotFound):
+			http.Error(w, "Unknown role", http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to update user roles", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(roles)
+}
+
+// writeRoleError maps role service errors to HTTP responses.
+func writeRoleError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrRoleNotFound):
+		http.Error(w, "Role not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrUnknownPermission):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrSystemRoleProtected):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"procurement-system/internal/middleware"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"procurement-system/internal/services"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type SoDHandler struct {
+	service  services.SoDService
+	validate *validator.Validate
+}
+
+func NewSoDHandler(service services.SoDService) *SoDHandler {
+	return &SoDHandler{service: service, validate: validator.New()}
+}
+
+// GetAllRules handles the request to list segregation of duties rules.
+func (h *SoDHandler) GetAllRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.service.GetAllRules()
+	if err != nil {
+		http.Error(w, "Failed to retrieve rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// CreateRule handles the request to add a segregation of duties rule.
+func (h *SoDHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.SoDRulePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.service.CreateRule(actorID, payload)
+	if err != nil {
+		writeSoDError(w, err, "Failed to create rule")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// UpdateRule handles the request to change or disable a segregation of duties rule.
+func (h *SoDHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.SoDRulePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.service.UpdateRule(actorID, id, payload)
+	if err != nil {
+		writeSoDError(w, err, "Failed to update rule")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// DeleteRule handles the request to remove a segregation of duties rule.
+func (h *SoDHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.service.DeleteRule(actorID, id); err != nil {
+		writeSoDError(w, err, "Failed to delete rule")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetViolations handles the request to list blocked segregation of duties attempts.
+func (h *SoDHandler) GetViolations(w http.ResponseWriter, r *http.Request) {
+	violations, err := h.service.GetViolations()
+	if err != nil {
+		http.Error(w, "Failed to retrieve violations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(violations)
+}
+
+// writeSoDError maps segregation of duties service errors to HTTP responses.
+func writeSoDError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrSoDRuleNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repository.ErrSoDRuleExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrUnknownSoDDuty):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
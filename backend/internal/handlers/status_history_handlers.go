@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"procurement-system/internal/middleware"
+	"procurement-system/internal/services"
+	"time"
+)
+
+type StatusHistoryHandler struct {
+	service services.StatusHistoryService
+}
+
+func NewStatusHistoryHandler(service services.StatusHistoryService) *StatusHistoryHandler {
+	return &StatusHistoryHandler{service: service}
+}
+
+// GetStatusHistory handles the request for an entity's status transitions and
+// cycle times.
+func (h *StatusHistoryHandler) GetStatusHistory(w http.ResponseWriter, r *http.Request) {
+	entityType, entityID, err := entityFromRequest(r)
+	if err != nil {
+		http.Error(w, "Invalid entity ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	history, err := h.service.GetStatusHistory(userID, entityType, entityID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownEntityType), errors.Is(err, services.ErrEntityNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, services.ErrForbidden):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, "Failed to retrieve status history", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// GetCycleTimeSummary handles the request for cycle times across requisitions
+// approved between the optional from and to query parameters. Both take a
+// date (YYYY-MM-DD, to being inclusive) or an RFC 3339 timestamp.
+func (h *StatusHistoryHandler) GetCycleTimeSummary(w http.ResponseWriter, r *http.Request) {
+	from, err := parsePeriodBound(r.URL.Query().Get("from"), false)
+	if err != nil {
+		http.Error(w, "Invalid from date", http.StatusBadRequest)
+		return
+	}
+	to, err := parsePeriodBound(r.URL.Query().Get("to"), true)
+	if err != nil {
+		http.Error(w, "Invalid to date", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.service.GetCycleTimeSummary(from, to)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPeriod) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to retrieve cycle times", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// parsePeriodBound parses one end of a reporting period. An empty value gives
+// the zero time; a plain date used as the end of a period covers that whole day.
+func parsePeriodBound(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"procurement-system/internal/middleware"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"procurement-system/internal/services"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+// TwoFactorHandler handles HTTP requests for managing two-factor authentication.
+type TwoFactorHandler struct {
+	service  services.TwoFactorService
+	validate *validator.Validate
+}
+
+// NewTwoFactorHandler creates a new instance of TwoFactorHandler.
+func NewTwoFactorHandler(service services.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		service:  service,
+		validate: validator.New(),
+	}
+}
+
+// GetMyStatus handles the request for a user to see whether 2FA is enabled and required for them.
+func (h *TwoFactorHandler) GetMyStatus(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+	enabled, err := h.service.IsEnabled(userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve two-factor status", http.StatusInternalServerError)
+		return
+	}
+	required, err := h.service.IsRequiredForUser(userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve two-factor status", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{
+		"enabled":  enabled,
+		"required": required,
+	})
+}
+
+// BeginEnrolment handles the request for a logged-in user to start enrolment.
+func (h *TwoFactorHandler) BeginEnrolment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	enrolment, err := h.service.BeginEnrolment(userID)
+	if err != nil {
+		if writeTwoFactorError(w, err) {
+			return
+		}
+		http.Error(w, "Failed to start two-factor enrolment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrolment)
+}
+
+// ConfirmEnrolment handles the request to activate 2FA with a first code.
+func (h *TwoFactorHandler) ConfirmEnrolment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.TwoFactorCodePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.service.ConfirmEnrolment(userID, payload.Code)
+	if err != nil {
+		if writeTwoFactorError(w, err) {
+			return
+		}
+		http.Error(w, "Failed to confirm two-factor enrolment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes handles the request to replace the user's recovery codes.
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.TwoFactorCodePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(userID, payload.Code)
+	if err != nil {
+		if writeTwoFactorError(w, err) {
+			return
+		}
+		http.Error(w, "Failed to regenerate recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable handles the request for a user to turn off their own 2FA.
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.TwoFactorCodePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Disable(userID, payload.Code); err != nil {
+		if writeTwoFactorError(w, err) {
+			return
+		}
+		http.Error(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResetUserTwoFactor handles the admin request to clear another user's 2FA.
+func (h *TwoFactorHandler) ResetUserTwoFactor(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	targetUserID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.service.Reset(actorID, targetUserID); err != nil {
+		if errors.Is(err, repository.ErrTwoFactorNotFound) {
+			http.Error(w, "Two-factor authentication is not set up for this user", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to reset two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
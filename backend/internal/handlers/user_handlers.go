@@ -28,6 +28,43 @@ func NewUserHandler(userService services.UserService) *UserHandler {
 	}
 }
 
+// CreateUser handles the admin request to create an account with any role.
+func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.CreateUserPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(payload); err != nil {
+		http.Error(w, "Validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userService.CreateUser(actorID, payload)
+	if err != nil {
+		if err == repository.ErrEmailExists {
+			http.Error(w, "Email already exists", http.StatusConflict)
+		} else if err == repository.ErrRoleNotFound {
+			http.Error(w, "Unknown role", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Failed to create user: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		http.Error(w, "Failed to encode user: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
 // GetAllUsers handles the request to retrieve all users.
 func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	users, err := h.userService.GetAllUsers()
@@ -61,6 +98,7 @@ func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, user.Version)
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(user); err != nil {
 		http.Error(w, "Failed to encode user: "+err.Error(), http.StatusInternalServerError)
@@ -93,16 +131,27 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.UpdateUser(actorID, targetUserID, payload)
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userService.UpdateUser(actorID, targetUserID, payload, expectedVersion)
 	if err != nil {
 		if err == repository.ErrUserNotFound {
 			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if err == repository.ErrRoleNotFound {
+			http.Error(w, "Unknown role", http.StatusBadRequest)
+		} else if err == repository.ErrVersionConflict {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
 		} else {
 			http.Error(w, "Failed to update user: "+err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
 
+	setETag(w, user.Version)
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(user); err != nil {
 		http.Error(w, "Failed to encode updated user: "+err.Error(), http.StatusInternalServerError)
@@ -124,9 +173,17 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.userService.DeleteUser(actorID, targetUserID); err != nil {
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userService.DeleteUser(actorID, targetUserID, expectedVersion); err != nil {
 		if err == repository.ErrUserNotFound {
 			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if err == repository.ErrVersionConflict {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
 		} else {
 			http.Error(w, "Failed to delete user: "+err.Error(), http.StatusInternalServerError)
 		}
@@ -135,3 +192,98 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// SetUserVendor handles the request to link a vendor portal user to their vendor.
+func (h *UserHandler) SetUserVendor(w http.ResponseWriter, r *http.Request) {
+	targetUserID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	var payload models.SetUserVendorPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userService.SetUserVendor(actorID, targetUserID, payload)
+	if err != nil {
+		if err == repository.ErrUserNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if err == repository.ErrInvalidVendor {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Failed to update user: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		http.Error(w, "Failed to encode updated user: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// GetPendingUsers handles the request to list self-registered users awaiting approval.
+func (h *UserHandler) GetPendingUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.userService.GetPendingUsers()
+	if err != nil {
+		http.Error(w, "Failed to retrieve pending users: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(users); err != nil {
+		http.Error(w, "Failed to encode users: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ApproveUser handles the request to activate a pending self-registered account.
+func (h *UserHandler) ApproveUser(w http.ResponseWriter, r *http.Request) {
+	h.decidePendingUser(w, r, h.userService.ApproveUser)
+}
+
+// RejectUser handles the request to refuse a pending self-registered account.
+func (h *UserHandler) RejectUser(w http.ResponseWriter, r *http.Request) {
+	h.decidePendingUser(w, r, h.userService.RejectUser)
+}
+
+func (h *UserHandler) decidePendingUser(w http.ResponseWriter, r *http.Request, decide func(actorID int, targetUserID int) (*models.User, error)) {
+	vars := mux.Vars(r)
+	targetUserID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := decide(actorID, targetUserID)
+	if err != nil {
+		switch err {
+		case repository.ErrUserNotFound:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case services.ErrUserNotPending:
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to update user status: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		http.Error(w, "Failed to encode user: "+err.Error(), http.StatusInternalServerError)
+	}
+}
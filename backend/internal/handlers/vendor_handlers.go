@@ -45,7 +45,7 @@ func (h *VendorHandler) CreateVendor(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.service.CreateVendor(actorID, &vendor); err != nil {
-		http.Error(w, "Failed to create vendor", http.StatusInternalServerError)
+		writeVendorError(w, err, "Failed to create vendor")
 		return
 	}
 
@@ -82,6 +82,7 @@ func (h *VendorHandler) GetVendorByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, vendor.Version)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(vendor)
 }
@@ -107,21 +108,24 @@ func (h *VendorHandler) UpdateVendor(w http.ResponseWriter, r *http.Request) {
 
 	vendor.ID = id
 
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
 	if !ok {
 		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
 		return
 	}
 
-	if err := h.service.UpdateVendor(actorID, &vendor); err != nil {
-		if errors.Is(err, repository.ErrVendorNotFound) {
-			http.Error(w, "Vendor not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to update vendor", http.StatusInternalServerError)
+	if err := h.service.UpdateVendor(actorID, &vendor, expectedVersion); err != nil {
+		writeVendorError(w, err, "Failed to update vendor")
 		return
 	}
 
+	setETag(w, vendor.Version)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(vendor)
 }
@@ -134,20 +138,36 @@ func (h *VendorHandler) DeleteVendor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	expectedVersion, err := ifMatchVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	actorID, ok := r.Context().Value(middleware.UserIDKey).(int)
 	if !ok {
 		http.Error(w, "Could not get user ID from context", http.StatusInternalServerError)
 		return
 	}
 
-	if err := h.service.DeleteVendor(actorID, id); err != nil {
-		if errors.Is(err, repository.ErrVendorNotFound) {
-			http.Error(w, "Vendor not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Failed to delete vendor", http.StatusInternalServerError)
+	if err := h.service.DeleteVendor(actorID, id, expectedVersion); err != nil {
+		writeVendorError(w, err, "Failed to delete vendor")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// writeVendorError maps vendor create, update and delete failures to HTTP responses.
+func writeVendorError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrVendorNotFound):
+		http.Error(w, "Vendor not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrVersionConflict):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, services.ErrUnknownPDFLayout):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
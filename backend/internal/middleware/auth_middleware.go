@@ -13,7 +13,7 @@ import (
 type contextKey string
 
 const (
-	UserIDKey contextKey = "user_id"
+	UserIDKey   contextKey = "user_id"
 	UserRoleKey contextKey = "user_role"
 )
 
@@ -55,8 +55,20 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		userID := int(claims["user_id"].(float64))
-		role := claims["role"].(string)
+		// Challenge tokens issued during two-factor login carry a purpose
+		// and must not be accepted as session tokens.
+		if _, isChallenge := claims["purpose"]; isChallenge {
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		userIDClaim, ok := claims["user_id"].(float64)
+		role, roleOK := claims["role"].(string)
+		if !ok || !roleOK {
+			http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+			return
+		}
+		userID := int(userIDClaim)
 
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
 		ctx = context.WithValue(ctx, UserRoleKey, role)
@@ -64,16 +76,3 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
-
-func RoleMiddleware(requiredRole string) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			role, ok := r.Context().Value(UserRoleKey).(string)
-			if !ok || role != requiredRole {
-				http.Error(w, "Forbidden: Insufficient permissions", http.StatusForbidden)
-				return
-			}
-			next.ServeHTTP(w, r)
-		})
-	}
-}
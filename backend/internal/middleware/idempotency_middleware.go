@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"procurement-system/internal/models"
+)
+
+// IdempotencyKeyHeader names the header clients use to make a POST safe to retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength is the longest key accepted.
+const maxIdempotencyKeyLength = 255
+
+// replayedHeaders are the response headers stored and sent again on replay.
+// Others, such as CORS headers, belong to the request being answered.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// IdempotencyStore remembers requests sent with an Idempotency-Key and their
+// responses.
+type IdempotencyStore interface {
+	Reserve(userID int, key string, fingerprint string) (*models.IdempotencyRecord, error)
+	Complete(userID int, key string, statusCode int, header http.Header, body []byte) error
+	Release(userID int, key string) error
+}
+
+// Idempotency makes POST requests that carry an Idempotency-Key header safe to
+// retry. The first request with a key is handled normally and its response
+// stored; retries with the same key and body get that response again, marked
+// with Idempotent-Replayed: true, without the action being repeated. Reusing a
+// key for a different request is rejected with 422, and a retry that arrives
+// while the first request is still running gets 409. Server errors are not
+// stored, so the request can be retried. Bodies over maxBodyBytes are
+// rejected with 413. It must run after AuthMiddleware, as keys are per user.
+func Idempotency(store IdempotencyStore, maxBodyBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				http.Error(w, "Idempotency-Key must be at most 255 characters", http.StatusBadRequest)
+				return
+			}
+
+			userID, ok := r.Context().Value(UserIDKey).(int)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				http.Error(w, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			fingerprint := requestFingerprint(r, body)
+			existing, err := store.Reserve(userID, key, fingerprint)
+			if err != nil {
+				http.Error(w, "Failed to check Idempotency-Key", http.StatusInternalServerError)
+				return
+			}
+			if existing != nil {
+				switch {
+				case existing.Fingerprint != fingerprint:
+					http.Error(w, "Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity)
+				case !existing.Completed():
+					http.Error(w, "A request with this Idempotency-Key is still in progress", http.StatusConflict)
+				default:
+					replay(w, existing)
+				}
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w}
+			completed := false
+			defer func() {
+				// Free the key if the handler panicked, so the request can be retried.
+				if !completed {
+					if err := store.Release(userID, key); err != nil {
+						log.Printf("Failed to release idempotency key for user %d: %v", userID, err)
+					}
+				}
+			}()
+			next.ServeHTTP(recorder, r)
+
+			status := recorder.statusCode()
+			if status >= http.StatusInternalServerError {
+				return
+			}
+			header := make(http.Header)
+			for _, name := range replayedHeaders {
+				if value := recorder.Header().Get(name); value != "" {
+					header.Set(name, value)
+				}
+			}
+			if err := store.Complete(userID, key, status, header, recorder.body.Bytes()); err != nil {
+				log.Printf("Failed to store response for idempotency key of user %d: %v", userID, err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// requestFingerprint identifies a request by its method, path and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// replay writes a stored response.
+func replay(w http.ResponseWriter, record *models.IdempotencyRecord) {
+	for name, values := range record.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write(record.Body)
+}
+
+// responseRecorder passes a response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
+
+// statusCode returns the status sent, which is 200 if the handler wrote
+// nothing at all.
+func (r *responseRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"procurement-system/internal/models"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeIdempotencyStore keeps idempotency records in memory, as the database
+// backed store does.
+type fakeIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*models.IdempotencyRecord
+}
+
+func newFakeIdempotencyStore() *fakeIdempotencyStore {
+	return &fakeIdempotencyStore{records: make(map[string]*models.IdempotencyRecord)}
+}
+
+func (s *fakeIdempotencyStore) id(userID int, key string) string {
+	return fmt.Sprintf("%d/%s", userID, key)
+}
+
+func (s *fakeIdempotencyStore) Reserve(userID int, key string, fingerprint string) (*models.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.records[s.id(userID, key)]; ok {
+		copied := *existing
+		return &copied, nil
+	}
+	s.records[s.id(userID, key)] = &models.IdempotencyRecord{UserID: userID, Key: key, Fingerprint: fingerprint}
+	return nil, nil
+}
+
+func (s *fakeIdempotencyStore) Complete(userID int, key string, statusCode int, header http.Header, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.records[s.id(userID, key)]
+	record.StatusCode = statusCode
+	record.Header = header
+	record.Body = body
+	return nil
+}
+
+func (s *fakeIdempotencyStore) Release(userID int, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, s.id(userID, key))
+	return nil
+}
+
+func (s *fakeIdempotencyStore) has(userID int, key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.records[s.id(userID, key)]
+	return ok
+}
+
+func TestIdempotency(t *testing.T) {
+	const userID = 7
+
+	newRequest := func(key string, body string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/api/requisitions", strings.NewReader(body))
+		if key != "" {
+			r.Header.Set(IdempotencyKeyHeader, key)
+		}
+		return r.WithContext(context.WithValue(r.Context(), UserIDKey, userID))
+	}
+
+	// counting returns a handler that creates something, and the number of
+	// times it has run.
+	counting := func() (http.Handler, *int) {
+		calls := 0
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"id": %d}`, calls)
+		}), &calls
+	}
+
+	t.Run("Replays The Stored Response", func(t *testing.T) {
+		store := newFakeIdempotencyStore()
+		handler, calls := counting()
+		h := Idempotency(store, 1024)(handler)
+
+		first := httptest.NewRecorder()
+		h.ServeHTTP(first, newRequest("abc", `{"item": "Laptop"}`))
+		assert.Equal(t, http.StatusCreated, first.Code)
+		assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+		retry := httptest.NewRecorder()
+		h.ServeHTTP(retry, newRequest("abc", `{"item": "Laptop"}`))
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, `{"id": 1}`, retry.Body.String())
+		assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+		// Only the listed headers are stored; CORS headers belong to the retry.
+		assert.Empty(t, retry.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Without A Key Every Request Runs", func(t *testing.T) {
+		store := newFakeIdempotencyStore()
+		handler, calls := counting()
+		h := Idempotency(store, 1024)(handler)
+
+		h.ServeHTTP(httptest.NewRecorder(), newRequest("", `{}`))
+		h.ServeHTTP(httptest.NewRecorder(), newRequest("", `{}`))
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("Key Reused For A Different Request", func(t *testing.T) {
+		store := newFakeIdempotencyStore()
+		handler, calls := counting()
+		h := Idempotency(store, 1024)(handler)
+
+		h.ServeHTTP(httptest.NewRecorder(), newRequest("abc", `{"item": "Laptop"}`))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, newRequest("abc", `{"item": "Monitor"}`))
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("Retry While The First Request Runs", func(t *testing.T) {
+		store := newFakeIdempotencyStore()
+		started := make(chan struct{})
+		finish := make(chan struct{})
+		h := Idempotency(store, 1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-finish
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		done := make(chan struct{})
+		first := httptest.NewRecorder()
+		go func() {
+			h.ServeHTTP(first, newRequest("abc", `{}`))
+			close(done)
+		}()
+		<-started
+
+		retry := httptest.NewRecorder()
+		h.ServeHTTP(retry, newRequest("abc", `{}`))
+		assert.Equal(t, http.StatusConflict, retry.Code)
+
+		close(finish)
+		<-done
+		assert.Equal(t, http.StatusCreated, first.Code)
+	})
+
+	t.Run("Server Error Releases The Key", func(t *testing.T) {
+		store := newFakeIdempotencyStore()
+		calls := 0
+		h := Idempotency(store, 1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				http.Error(w, "database unavailable", http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, newRequest("abc", `{}`))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.False(t, store.has(userID, "abc"))
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, newRequest("abc", `{}`))
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Panic Releases The Key", func(t *testing.T) {
+		store := newFakeIdempotencyStore()
+		h := Idempotency(store, 1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("handler bug")
+		}))
+
+		assert.Panics(t, func() {
+			h.ServeHTTP(httptest.NewRecorder(), newRequest("abc", `{}`))
+		})
+		assert.False(t, store.has(userID, "abc"))
+	})
+
+	t.Run("Body Too Large", func(t *testing.T) {
+		store := newFakeIdempotencyStore()
+		handler, calls := counting()
+		h := Idempotency(store, 8)(handler)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, newRequest("abc", `{"item": "Laptop"}`))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.Equal(t, 0, *calls)
+		assert.False(t, store.has(userID, "abc"))
+	})
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+// PermissionsKey holds the set of permissions granted to the current user.
+const PermissionsKey contextKey = "permissions"
+
+// PermissionResolver looks up the permissions granted to a user by all of
+// their roles.
+type PermissionResolver interface {
+	GetUserPermissions(userID int) (map[string]bool, error)
+}
+
+// RequirePermission allows the request through if the authenticated user holds
+// at least one of the given permissions. It must run after AuthMiddleware.
+// Permissions are resolved on every request, so changes to role definitions
+// take effect without the user logging in again.
+func RequirePermission(resolver PermissionResolver, permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			granted, ok := r.Context().Value(PermissionsKey).(map[string]bool)
+			if !ok {
+				userID, ok := r.Context().Value(UserIDKey).(int)
+				if !ok {
+					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+					return
+				}
+				var err error
+				granted, err = resolver.GetUserPermissions(userID)
+				if err != nil {
+					http.Error(w, "Failed to resolve permissions", http.StatusInternalServerError)
+					return
+				}
+				r = r.WithContext(context.WithValue(r.Context(), PermissionsKey, granted))
+			}
+
+			for _, p := range permissions {
+				if granted[p] {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			http.Error(w, "Forbidden: Insufficient permissions", http.StatusForbidden)
+		})
+	}
+}
+
+// HasPermission reports whether the permissions resolved for this request
+// include the given permission.
+func HasPermission(ctx context.Context, permission string) bool {
+	granted, _ := ctx.Value(PermissionsKey).(map[string]bool)
+	return granted[permission]
+}
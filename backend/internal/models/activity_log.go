@@ -11,5 +11,6 @@ type ActivityLog struct {
 	TargetID    *int      `json:"target_id,omitempty"`   // e.g., the ID of the affected user or vendor
 	Status      string    `json:"status"`                // e.g., "SUCCESS", "FAILED"
 	Details     *string   `json:"details,omitempty"`     // e.g., error message on failure
+	BatchID     *string   `json:"batch_id,omitempty"`    // links the entries of one bulk operation
 	CreatedAt   time.Time `json:"created_at"`
 }
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Attachment is a file attached to an entity. Its content is kept in the
+// blob store under StorageKey.
+type Attachment struct {
+	ID          int       `json:"id"`
+	EntityType  string    `json:"entity_type"`
+	EntityID    int       `json:"entity_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	SHA256      string    `json:"sha256"`
+	StorageKey  string    `json:"-"`
+	Visibility  string    `json:"visibility"`
+	UploadedBy  *int      `json:"uploaded_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Purchase order types.
+const (
+	POTypeStandard = "standard"
+	POTypeBlanket  = "blanket"  // An agreement up to a ceiling, with no lines of its own
+	POTypeCallOff  = "call_off" // Released against a blanket, priced from its price list
+)
+
+// BlanketPriceItem is an agreed price on a blanket order's price list.
+type BlanketPriceItem struct {
+	ID          int     `json:"id"`
+	BlanketID   int     `json:"blanket_id"`
+	LineNo      int     `json:"line_no"`
+	Description string  `json:"description"`
+	UnitPrice   float64 `json:"unit_price"`
+}
+
+// BlanketConsumption reports how much of a blanket order's ceiling its
+// call-offs have used. A call-off consumes what it commits: its total while
+// open, what was received once closed, and nothing once cancelled.
+type BlanketConsumption struct {
+	BlanketID       int       `json:"blanket_id"`
+	PONumber        string    `json:"po_number"`
+	VendorID        int       `json:"vendor_id"`
+	VendorName      string    `json:"vendor_name"`
+	Currency        string    `json:"currency"`
+	Status          string    `json:"status"`
+	ValidFrom       time.Time `json:"valid_from"`
+	ValidTo         time.Time `json:"valid_to"`
+	CeilingAmount   float64   `json:"ceiling_amount"`
+	ConsumedAmount  float64   `json:"consumed_amount"`
+	RemainingAmount float64   `json:"remaining_amount"`
+	PercentUsed     float64   `json:"percent_used"`
+	CallOffs        int       `json:"call_offs"`
+}
+
+// CreateBlanketPayload defines a blanket order. Currency defaults to MYR.
+type CreateBlanketPayload struct {
+	VendorID      int                       `json:"vendor_id" validate:"required,gt=0"`
+	Currency      string                    `json:"currency" validate:"omitempty,len=3,alpha"`
+	ShipTo        string                    `json:"ship_to" validate:"max=1000"`
+	ValidFrom     string                    `json:"valid_from" validate:"required,datetime=2006-01-02"`
+	ValidTo       string                    `json:"valid_to" validate:"required,datetime=2006-01-02"`
+	CeilingAmount float64                   `json:"ceiling_amount" validate:"required,gt=0"`
+	PriceList     []BlanketPriceItemPayload `json:"price_list" validate:"required,min=1,max=500,dive"`
+}
+
+// BlanketPriceItemPayload is one agreed price of a new blanket order.
+type BlanketPriceItemPayload struct {
+	Description string  `json:"description" validate:"required,max=500"`
+	UnitPrice   float64 `json:"unit_price" validate:"gt=0"`
+}
+
+// CallOffPayload releases a call-off against a blanket order. Ship-to
+// defaults to the blanket's.
+type CallOffPayload struct {
+	ShipTo string               `json:"ship_to" validate:"max=1000"`
+	Lines  []CallOffLinePayload `json:"lines" validate:"required,min=1,max=100,dive"`
+}
+
+// CallOffLinePayload orders a quantity of an item on the blanket's price list.
+type CallOffLinePayload struct {
+	PriceItemID  int    `json:"price_item_id" validate:"required,gt=0"`
+	Quantity     int    `json:"quantity" validate:"required,gt=0"`
+	DeliveryDate string `json:"delivery_date" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// CallOffResult is a new call-off with the blanket's consumption after it.
+// Warning is set once the blanket is near its ceiling.
+type CallOffResult struct {
+	PurchaseOrder *PurchaseOrder      `json:"purchase_order"`
+	Blanket       *BlanketConsumption `json:"blanket"`
+	Warning       string              `json:"warning,omitempty"`
+}
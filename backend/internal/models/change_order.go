@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Change order statuses. A change order that raises an order's value by more
+// than the approval threshold waits as Pending; others are applied at once.
+const (
+	ChangeOrderStatusPending  = "Pending"
+	ChangeOrderStatusApplied  = "Applied"
+	ChangeOrderStatusRejected = "Rejected"
+)
+
+// ChangeOrder changes the quantities, prices or delivery dates of a purchase
+// order's lines. Applying it creates the order's next revision; Revision is
+// only set once it has been applied.
+type ChangeOrder struct {
+	ID              int          `json:"id"`
+	PurchaseOrderID int          `json:"purchase_order_id"`
+	Revision        *int         `json:"revision,omitempty"`
+	BaseRevision    int          `json:"base_revision"` // Revision the changes were made against
+	Status          string       `json:"status"`
+	Reason          string       `json:"reason"`
+	Changes         []LineChange `json:"changes"`
+	PreviousTotal   float64      `json:"previous_total"`
+	NewTotal        float64      `json:"new_total"`
+	RequestedBy     *int         `json:"requested_by,omitempty"`
+	RequestedAt     time.Time    `json:"requested_at"`
+	DecidedBy       *int         `json:"decided_by,omitempty"`
+	DecidedAt       *time.Time   `json:"decided_at,omitempty"`
+	DecisionReason  string       `json:"decision_reason,omitempty"`
+}
+
+// LineChange is the difference a change order makes to one line, against the
+// revision it was made against.
+type LineChange struct {
+	LineNo          int        `json:"line_no"`
+	Description     string     `json:"description"`
+	OldQuantity     int        `json:"old_quantity"`
+	NewQuantity     int        `json:"new_quantity"`
+	OldUnitPrice    float64    `json:"old_unit_price"`
+	NewUnitPrice    float64    `json:"new_unit_price"`
+	OldDeliveryDate *time.Time `json:"old_delivery_date,omitempty"`
+	NewDeliveryDate *time.Time `json:"new_delivery_date,omitempty"`
+	OldTotal        float64    `json:"old_total"`
+	NewTotal        float64    `json:"new_total"`
+}
+
+// ChangeOrderPayload lists the lines to change. Fields left out of a line
+// keep their current value; an empty delivery date clears it.
+type ChangeOrderPayload struct {
+	Reason string                   `json:"reason" validate:"required"`
+	Lines  []ChangeOrderLinePayload `json:"lines" validate:"required,min=1,dive"`
+}
+
+// ChangeOrderLinePayload changes one line of a purchase order.
+type ChangeOrderLinePayload struct {
+	LineNo       int      `json:"line_no" validate:"required,gt=0"`
+	Quantity     *int     `json:"quantity" validate:"omitempty,gt=0"`
+	UnitPrice    *float64 `json:"unit_price" validate:"omitempty,gte=0"`
+	DeliveryDate *string  `json:"delivery_date" validate:"omitempty,datetime=2006-01-02"`
+}
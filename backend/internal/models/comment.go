@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Entity types that comments, and other records kept against an entity,
+// may attach to. They match the target types used in the activity log.
+const (
+	EntityRequisition   = "requisition"
+	EntityPurchaseOrder = "purchase_order"
+	EntityVendor        = "vendor"
+	EntityInvoice       = "invoice"
+)
+
+// Comment and attachment visibilities. Vendor-visible ones are only allowed
+// on purchase orders, where the vendor's portal users can read and write them.
+const (
+	CommentVisibilityInternal = "internal"
+	CommentVisibilityVendor   = "vendor"
+)
+
+// Comment is one message in an entity's thread.
+type Comment struct {
+	ID         int        `json:"id"`
+	EntityType string     `json:"entity_type"`
+	EntityID   int        `json:"entity_id"`
+	ParentID   *int       `json:"parent_id,omitempty"`
+	AuthorID   *int       `json:"author_id,omitempty"`
+	AuthorName string     `json:"author_name,omitempty"`
+	Body       string     `json:"body"`
+	Visibility string     `json:"visibility"`
+	Mentions   []int      `json:"mentions"`
+	CreatedAt  time.Time  `json:"created_at"`
+	EditedAt   *time.Time `json:"edited_at,omitempty"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+}
+
+// CreateCommentPayload defines the structure for posting a comment. Users are
+// mentioned by writing @ followed by their email address.
+type CreateCommentPayload struct {
+	Body       string `json:"body" validate:"required,max=10000"`
+	Visibility string `json:"visibility" validate:"omitempty,oneof=internal vendor"`
+	ParentID   *int   `json:"parent_id"`
+}
+
+// UpdateCommentPayload defines the structure for editing a comment.
+type UpdateCommentPayload struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
+
+// TimelineEntry is one event in an entity's timeline. Exactly one of
+// Activity, Comment and Attachment is set, matching Kind.
+type TimelineEntry struct {
+	Kind       string       `json:"kind"`
+	At         time.Time    `json:"at"`
+	Activity   *ActivityLog `json:"activity,omitempty"`
+	Comment    *Comment     `json:"comment,omitempty"`
+	Attachment *Attachment  `json:"attachment,omitempty"`
+}
+
+// Timeline entry kinds.
+const (
+	TimelineActivity   = "activity"
+	TimelineComment    = "comment"
+	TimelineAttachment = "attachment"
+)
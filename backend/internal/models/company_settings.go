@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// CompanySettings is the company profile and branding printed on purchase
+// orders. Each change creates a new Version; the latest is the one in use.
+type CompanySettings struct {
+	Version      int       `json:"version"`
+	CompanyName  string    `json:"company_name"`
+	RegNo        string    `json:"reg_no"`
+	TinNo        string    `json:"tin_no"`
+	MsicCode     string    `json:"msic_code"`
+	Address      string    `json:"address"`
+	Phones       string    `json:"phones"`
+	Email        string    `json:"email"`
+	BankName     string    `json:"bank_name"`
+	BankAccount  string    `json:"bank_account"`
+	PaymentTerms string    `json:"payment_terms"` // Default payment terms, e.g. "NET 30"
+	PDFFooter    string    `json:"pdf_footer"`
+	PDFLayout    string    `json:"pdf_layout"` // Default layout; vendors may override it
+	TaxLabel     string    `json:"tax_label"`  // e.g. "SST"
+	TaxRate      float64   `json:"tax_rate"`   // Percent added to the PDF's subtotal
+	RoundingStep float64   `json:"rounding_increment"`
+	LogoID       *int      `json:"logo_id,omitempty"`
+	ChangedBy    *int      `json:"changed_by,omitempty"`
+	ChangedAt    time.Time `json:"changed_at"`
+}
+
+// CompanyLogo is a logo image.
+type CompanyLogo struct {
+	ID          int    `json:"id"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"-"`
+	SHA256      string `json:"sha256"`
+}
+
+// CompanySettingsPayload replaces the company profile. The logo is kept; it is
+// changed through its own endpoint.
+type CompanySettingsPayload struct {
+	CompanyName  string  `json:"company_name" validate:"required,max=255"`
+	RegNo        string  `json:"reg_no" validate:"max=50"`
+	TinNo        string  `json:"tin_no" validate:"max=50"`
+	MsicCode     string  `json:"msic_code" validate:"max=20"`
+	Address      string  `json:"address" validate:"max=1000"`
+	Phones       string  `json:"phones" validate:"max=255"`
+	Email        string  `json:"email" validate:"omitempty,email,max=255"`
+	BankName     string  `json:"bank_name" validate:"max=255"`
+	BankAccount  string  `json:"bank_account" validate:"max=100"`
+	PaymentTerms string  `json:"payment_terms" validate:"max=255"`
+	PDFFooter    string  `json:"pdf_footer" validate:"max=2000"`
+	PDFLayout    string  `json:"pdf_layout" validate:"max=50"`
+	TaxLabel     string  `json:"tax_label" validate:"max=50"`
+	TaxRate      float64 `json:"tax_rate" validate:"gte=0,lte=100"`
+	RoundingStep float64 `json:"rounding_increment" validate:"gte=0,lte=1"`
+}
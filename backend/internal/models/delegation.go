@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Delegation lets DelegateID approve and reject requisitions on behalf of
+// DelegatorID between StartsAt and EndsAt.
+type Delegation struct {
+	ID            int       `json:"id"`
+	DelegatorID   int       `json:"delegator_id"`
+	DelegatorName string    `json:"delegator_name"`
+	DelegateID    int       `json:"delegate_id"`
+	DelegateName  string    `json:"delegate_name"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+	MaxAmount     *float64  `json:"max_amount,omitempty"`
+	Reason        string    `json:"reason"`
+	CreatedBy     *int      `json:"created_by,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ActiveAt reports whether the delegation is in force at t.
+func (d *Delegation) ActiveAt(t time.Time) bool {
+	return !t.Before(d.StartsAt) && t.Before(d.EndsAt)
+}
+
+// Covers reports whether the delegation allows deciding a requisition of the given amount.
+func (d *Delegation) Covers(amount float64) bool {
+	return d.MaxAmount == nil || amount <= *d.MaxAmount
+}
+
+// CreateDelegationPayload defines the structure for registering a delegation.
+// DelegatorID is only honoured for callers holding requisition:manage; other
+// users always delegate their own authority.
+type CreateDelegationPayload struct {
+	DelegatorID *int      `json:"delegator_id" validate:"omitempty,gt=0"`
+	DelegateID  int       `json:"delegate_id" validate:"required,gt=0"`
+	StartsAt    time.Time `json:"starts_at" validate:"required"`
+	EndsAt      time.Time `json:"ends_at" validate:"required,gtfield=StartsAt"`
+	MaxAmount   *float64  `json:"max_amount" validate:"omitempty,gt=0"`
+	Reason      string    `json:"reason" validate:"max=500"`
+}
+
+// ReassignApproverPayload assigns a pending requisition to another approver.
+// A null approver_id returns it to the shared approval queue.
+type ReassignApproverPayload struct {
+	ApproverID *int   `json:"approver_id" validate:"omitempty,gt=0"`
+	Reason     string `json:"reason" validate:"max=500"`
+}
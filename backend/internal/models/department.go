@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Department is a node in the organisation chart.
+type Department struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	ParentID   *int      `json:"parent_id,omitempty"`
+	HeadUserID *int      `json:"head_user_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DepartmentPayload defines the structure for creating or updating a department.
+type DepartmentPayload struct {
+	Name       string `json:"name" validate:"required,max=255"`
+	ParentID   *int   `json:"parent_id" validate:"omitempty,gt=0"`
+	HeadUserID *int   `json:"head_user_id" validate:"omitempty,gt=0"`
+}
+
+// UserOrganisationPayload sets a user's department and line manager.
+// Null values clear the assignment.
+type UserOrganisationPayload struct {
+	DepartmentID *int `json:"department_id" validate:"omitempty,gt=0"`
+	ManagerID    *int `json:"manager_id" validate:"omitempty,gt=0"`
+}
+
+// UserOrganisation describes where a user sits in the organisation chart.
+type UserOrganisation struct {
+	UserID         int         `json:"user_id"`
+	Department     *Department `json:"department,omitempty"`
+	LineManager    *User       `json:"line_manager,omitempty"`
+	DepartmentHead *User       `json:"department_head,omitempty"`
+}
+
+// OrgChartAssignment is one resolved line of an org chart import.
+// DepartmentPath lists department names from the root, such as
+// ["Operations", "Procurement"]; missing departments are created.
+type OrgChartAssignment struct {
+	UserID         int
+	DepartmentPath []string
+	ManagerID      *int
+	DepartmentHead bool
+}
+
+// OrgChartImportError reports a problem with one line of an import.
+type OrgChartImportError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// OrgChartImportResult summarises an org chart import.
+type OrgChartImportResult struct {
+	DepartmentsCreated int                   `json:"departments_created"`
+	UsersUpdated       int                   `json:"users_updated"`
+	Errors             []OrgChartImportError `json:"errors,omitempty"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"net/http"
+	"time"
+)
+
+// IdempotencyRecord is a request sent with an Idempotency-Key and, once it has
+// been handled, the response to replay when the request is retried.
+type IdempotencyRecord struct {
+	UserID      int
+	Key         string
+	Fingerprint string      // SHA-256 of the method, path and body
+	StatusCode  int         // 0 while the original request is still in progress
+	Header      http.Header // response headers worth replaying
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// Completed reports whether the original request has finished.
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
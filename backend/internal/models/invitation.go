@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Invitation is an admin-issued invitation to register with a given email and role.
+type Invitation struct {
+	ID             int        `json:"id"`
+	Email          string     `json:"email"`
+	Role           string     `json:"role"`
+	TokenHash      string     `json:"-"`
+	InvitedBy      *int       `json:"invited_by,omitempty"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
+	AcceptedUserID *int       `json:"accepted_user_id,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// CreateInvitationPayload defines the structure for creating an invitation.
+type CreateInvitationPayload struct {
+	Email          string `json:"email" validate:"required,email"`
+	Role           string `json:"role" validate:"required,max=50"`
+	ExpiresInHours int    `json:"expires_in_hours" validate:"omitempty,gt=0,lte=720"`
+}
+
+// CreateInvitationResponse returns the invitation together with its token.
+// The token is only available at creation time.
+type CreateInvitationResponse struct {
+	Invitation Invitation `json:"invitation"`
+	Token      string     `json:"token"`
+}
+
+// AcceptInvitationPayload defines the structure for registering with an invitation.
+// The email and role come from the invitation, not the request.
+type AcceptInvitationPayload struct {
+	Token    string `json:"token" validate:"required"`
+	Name     string `json:"name" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
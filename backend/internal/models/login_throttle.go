@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Login throttle scopes.
+const (
+	ThrottleScopeAccount = "account"
+	ThrottleScopeIP      = "ip"
+)
+
+// LoginThrottle tracks consecutive failed logins for an account or a client IP.
+type LoginThrottle struct {
+	ID            int        `json:"id"`
+	Scope         string     `json:"scope"` // "account" or "ip"
+	Key           string     `json:"key"`   // normalised email or IP address
+	FailureCount  int        `json:"failure_count"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
@@ -4,15 +4,51 @@ type NavigationItem struct {
 	Title    string              `json:"title"`
 	Path     string              `json:"path"`
 	Icon     string              `json:"icon"`
+	Badge    *NavigationBadge    `json:"badge,omitempty"`
 	SubItems []NavigationSubItem `json:"subItems,omitempty"`
 }
 
 type NavigationSubItem struct {
-	Title string `json:"title"`
-	Path  string `json:"path"`
+	Title string           `json:"title"`
+	Path  string           `json:"path"`
+	Badge *NavigationBadge `json:"badge,omitempty"`
+}
+
+// NavigationBadge is a live count shown next to a menu item, e.g. "(2 rejected)".
+type NavigationBadge struct {
+	Count int    `json:"count"`
+	Label string `json:"label,omitempty"`
 }
 
 type BreadcrumbItem struct {
 	Title string `json:"title"`
 	Path  string `json:"path"`
 }
+
+// MenuItem is a stored navigation entry. Top-level items and their direct
+// children make up the menu; deeper or hidden items are only used to build
+// breadcrumbs. Path segments starting with ':' match any value.
+type MenuItem struct {
+	ID         int     `json:"id"`
+	ParentID   *int    `json:"parent_id,omitempty"`
+	Title      string  `json:"title"`
+	Path       string  `json:"path"`
+	Icon       string  `json:"icon"`
+	Permission *string `json:"permission,omitempty"`
+	Badge      *string `json:"badge,omitempty"`
+	SortOrder  int     `json:"sort_order"`
+	ShowInMenu bool    `json:"show_in_menu"`
+}
+
+// MenuItemPayload defines the structure for creating or updating a menu item.
+// An empty permission makes the item visible to every authenticated user.
+type MenuItemPayload struct {
+	ParentID   *int    `json:"parent_id" validate:"omitempty,gt=0"`
+	Title      string  `json:"title" validate:"required,max=100"`
+	Path       string  `json:"path" validate:"required,startswith=/,max=255"`
+	Icon       string  `json:"icon" validate:"max=50"`
+	Permission *string `json:"permission" validate:"omitempty,max=100"`
+	Badge      *string `json:"badge" validate:"omitempty,max=50"`
+	SortOrder  int     `json:"sort_order"`
+	ShowInMenu *bool   `json:"show_in_menu"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Notification kinds.
+const (
+	NotificationMention    = "mention"
+	NotificationPORevision = "po_revision"
+)
+
+// Notification is an in-app message to a user, optionally about an entity.
+type Notification struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Kind       string     `json:"kind"`
+	Message    string     `json:"message"`
+	EntityType *string    `json:"entity_type,omitempty"`
+	EntityID   *int       `json:"entity_id,omitempty"`
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Purchase order dispatch statuses.
+const (
+	DispatchStatusPending = "Pending" // Not sent yet; retried at NextAttemptAt
+	DispatchStatusSent    = "Sent"
+	DispatchStatusFailed  = "Failed" // Gave up after the last attempt
+)
+
+// PODispatch records a purchase order PDF being emailed to its vendor.
+// Revision is the revision of the order when the send was requested; retries
+// attach the order's PDF as it is at the time.
+type PODispatch struct {
+	ID              int        `json:"id"`
+	PurchaseOrderID int        `json:"purchase_order_id"`
+	Revision        int        `json:"revision"`
+	To              string     `json:"to"`
+	Cc              []string   `json:"cc"`
+	Subject         string     `json:"subject"`
+	Body            string     `json:"body"`
+	Status          string     `json:"status"`
+	Attempts        int        `json:"attempts"`
+	LastError       *string    `json:"last_error,omitempty"`
+	NextAttemptAt   *time.Time `json:"next_attempt_at,omitempty"`
+	SentAt          *time.Time `json:"sent_at,omitempty"`
+	RequestedBy     *int       `json:"requested_by,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// SendToVendorPayload emails a purchase order to its vendor. Message is added
+// to the email's body.
+type SendToVendorPayload struct {
+	Cc      []string `json:"cc" validate:"max=10,dive,email"`
+	Message string   `json:"message" validate:"max=5000"`
+}
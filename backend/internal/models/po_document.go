@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Reasons a purchase order document was archived.
+const (
+	PODocumentIssued     = "issued"
+	PODocumentRevised    = "revised"
+	PODocumentCancelled  = "cancelled"
+	PODocumentBackfilled = "backfilled" // Order issued before documents were archived
+)
+
+// PODocument is the PDF of a purchase order as it stood at Revision, kept
+// unchanged in the blob store. The latest one is what the order's PDF shows.
+type PODocument struct {
+	ID              int       `json:"id"`
+	PurchaseOrderID int       `json:"purchase_order_id"`
+	Revision        int       `json:"revision"`
+	Reason          string    `json:"reason"`
+	SizeBytes       int64     `json:"size_bytes"`
+	SHA256          string    `json:"sha256"`
+	StorageKey      string    `json:"-"`
+	CreatedBy       *int      `json:"created_by,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Original reports whether the document was archived when the order changed.
+// A backfilled document was drawn later from the data of the day, so it may
+// differ from what was sent to the vendor.
+func (d *PODocument) Original() bool {
+	return d.Reason != PODocumentBackfilled
+}
+
+// PODocumentVerification is the outcome of checking a PDF against the
+// archive. Genuine documents name the order and document they match; Latest
+// is false when the order has been revised or cancelled since, and Original
+// is false when the document was backfilled rather than archived at the time.
+type PODocumentVerification struct {
+	Genuine  bool        `json:"genuine"`
+	SHA256   string      `json:"sha256"`
+	PONumber string      `json:"po_number,omitempty"`
+	Latest   bool        `json:"latest"`
+	Original bool        `json:"original"`
+	Document *PODocument `json:"document,omitempty"`
+}
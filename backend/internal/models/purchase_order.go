@@ -1,12 +1,154 @@
 package models
 
-import "time"
+import (
+	"math"
+	"time"
+)
 
+// PurchaseOrder is an order issued to a vendor. It is raised from one
+// approved requisition, or consolidated from several for the same vendor,
+// currency and ship-to; RequisitionID is only set in the first case, and
+// each line records the requisition it came from. CommittedAmount is the
+// total while the order is Issued, 0 once Cancelled and the value received
+// once Closed. Blanket orders and their call-offs are described in
+// blanket_order.go. The tax and rounding in force when the order is issued
+// are kept with it, so that TotalAmount, the commitment, the PDF and the
+// email to the vendor all quote the same grand total; see Totals.
 type PurchaseOrder struct {
-	ID            int       `json:"id"`
-	PONumber      string    `json:"po_number"`
-	RequisitionID int       `json:"requisition_id"`
-	VendorID      int       `json:"vendor_id"`
-	OrderDate     time.Time `json:"order_date"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID              int                 `json:"id"`
+	PONumber        string              `json:"po_number"`
+	RequisitionID   *int                `json:"requisition_id"`
+	VendorID        int                 `json:"vendor_id"`
+	OrderDate       time.Time           `json:"order_date"`
+	Status          string              `json:"status"`
+	Currency        string              `json:"currency"`
+	ShipTo          string              `json:"ship_to"`
+	TotalAmount     float64             `json:"total_amount"` // Grand total, with tax and rounding
+	TaxLabel        string              `json:"tax_label"`
+	TaxRate         float64             `json:"tax_rate"`               // Percent
+	RoundingStep    float64             `json:"rounding_increment"`     // Grand total is rounded to a multiple of this; 0 for none
+	Revision        int                 `json:"revision"`               // 0 until the first change order is applied
+	CommittedAmount float64             `json:"committed_amount"`       // Value still committed against budget
+	ClosureCode     *string             `json:"closure_code,omitempty"` // Reason code given to cancel or close the order
+	ClosureNote     string              `json:"closure_note,omitempty"`
+	POType          string              `json:"po_type"`
+	BlanketID       *int                `json:"blanket_id,omitempty"` // Blanket a call-off is released against
+	ValidFrom       *time.Time          `json:"valid_from,omitempty"` // Blanket only
+	ValidTo         *time.Time          `json:"valid_to,omitempty"`   // Blanket only
+	CeilingAmount   *float64            `json:"ceiling_amount,omitempty"`
+	PriceList       []BlanketPriceItem  `json:"price_list,omitempty"`
+	Lines           []PurchaseOrderLine `json:"lines,omitempty"`
+	DocumentSHA256  *string             `json:"document_sha256,omitempty"` // Digest of the latest archived PDF
+	CreatedAt       time.Time           `json:"created_at"`
+	Version         int                 `json:"version"`
+}
+
+// PurchaseOrderLine is one item of a purchase order.
+type PurchaseOrderLine struct {
+	ID                  int        `json:"id"`
+	PurchaseOrderID     int        `json:"purchase_order_id"`
+	LineNo              int        `json:"line_no"`
+	RequisitionID       *int       `json:"requisition_id"` // Source requisition, for traceability
+	Description         string     `json:"description"`
+	Quantity            int        `json:"quantity"`
+	UnitPrice           float64    `json:"unit_price"`
+	TotalPrice          float64    `json:"total_price"`
+	DeliveryDate        *time.Time `json:"delivery_date,omitempty"`
+	ReceivedQuantity    int        `json:"received_quantity"`       // Kept by goods receipt
+	ShortClosedQuantity int        `json:"short_closed_quantity"`   // Given up when the order was closed
+	PriceItemID         *int       `json:"price_item_id,omitempty"` // Blanket price list item a call-off line is priced from
+}
+
+// OrderTotals is what an order comes to: its lines, the tax on them, the
+// adjustment that rounds the sum, and the grand total.
+type OrderTotals struct {
+	Subtotal float64
+	Tax      float64
+	Rounding float64
+	Grand    float64
+}
+
+// ComputeOrderTotals adds tax at taxRate percent to subtotal and rounds the
+// result to a multiple of roundingStep, or to the cent when it is 0. Every
+// total quoted for an order is worked out here.
+func ComputeOrderTotals(subtotal float64, taxRate float64, roundingStep float64) OrderTotals {
+	totals := OrderTotals{Subtotal: roundCents(subtotal)}
+	totals.Tax = roundCents(totals.Subtotal * taxRate / 100)
+	exact := roundCents(totals.Subtotal + totals.Tax)
+	totals.Grand = exact
+	if roundingStep > 0 {
+		totals.Grand = roundCents(math.Round(exact/roundingStep) * roundingStep)
+		totals.Rounding = roundCents(totals.Grand - exact)
+	}
+	return totals
+}
+
+// Totals works out the order's totals from its lines at its tax rate and
+// rounding step.
+func (po *PurchaseOrder) Totals() OrderTotals {
+	return ComputeOrderTotals(LinesSubtotal(po.Lines, func(line PurchaseOrderLine) int { return line.Quantity }), po.TaxRate, po.RoundingStep)
+}
+
+// LinesSubtotal adds up the value of quantity(line) of each line, each
+// rounded to the cent as it is printed.
+func LinesSubtotal(lines []PurchaseOrderLine, quantity func(line PurchaseOrderLine) int) float64 {
+	var subtotal float64
+	for _, line := range lines {
+		subtotal += roundCents(float64(quantity(line)) * line.UnitPrice)
+	}
+	return roundCents(subtotal)
+}
+
+// roundCents rounds a money amount to cents.
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// BuyQueueGroup is a set of approved requisitions waiting for a purchase
+// order that can be consolidated into one: they share a vendor, currency and
+// ship-to.
+type BuyQueueGroup struct {
+	VendorID     int           `json:"vendor_id"`
+	Currency     string        `json:"currency"`
+	ShipTo       string        `json:"ship_to"`
+	TotalAmount  float64       `json:"total_amount"`
+	Requisitions []Requisition `json:"requisitions"`
+}
+
+// ConsolidatePayload lists the queued requisitions to order on one purchase order.
+type ConsolidatePayload struct {
+	RequisitionIDs []int `json:"requisition_ids" validate:"required,min=1,max=200,dive,gt=0"`
+}
+
+// Reason codes for cancelling a purchase order.
+const (
+	POCancelNoLongerRequired = "no_longer_required"
+	POCancelVendorUnable     = "vendor_unable"
+	POCancelDuplicate        = "duplicate"
+	POCancelPriceDispute     = "price_dispute"
+)
+
+// Reason codes for closing out a purchase order short.
+const (
+	POCloseVendorShortShipped = "vendor_short_shipped"
+	POCloseNoLongerRequired   = "no_longer_required"
+	POCloseSubstituted        = "substituted"
+)
+
+// POReasonOther may be given to cancel or close a purchase order for any
+// other reason, with a note saying what it is.
+const POReasonOther = "other"
+
+// CancelPurchaseOrderPayload gives the reason for cancelling a purchase
+// order. A note is required with the "other" code.
+type CancelPurchaseOrderPayload struct {
+	ReasonCode string `json:"reason_code" validate:"required,oneof=no_longer_required vendor_unable duplicate price_dispute other"`
+	Note       string `json:"note" validate:"max=1000"`
+}
+
+// ClosePurchaseOrderPayload gives the reason for closing out a purchase
+// order short. A note is required with the "other" code.
+type ClosePurchaseOrderPayload struct {
+	ReasonCode string `json:"reason_code" validate:"required,oneof=vendor_short_shipped no_longer_required substituted other"`
+	Note       string `json:"note" validate:"max=1000"`
 }
@@ -1,7 +1,10 @@
 package models
 
 // PDFData holds all the data needed to generate a purchase order PDF.
-// This structure is based on the cash-bill-template-golang library.
+// This structure is based on the cash-bill-template-golang library. The
+// company fields, bank details, payment terms, footer and logo come from the
+// company settings; tax and rounding come from the order; the totals and
+// AmountInWords are worked out when the PDF is generated.
 type PDFData struct {
 	CompanyName    string      `json:"company_name"`
 	RegNo          string      `json:"reg_no"`
@@ -14,6 +17,8 @@ type PDFData struct {
 	CustomerPhone  string      `json:"customer_phone"`     // Vendor's phone
 	ReceiptNo      string      `json:"receipt_no"`         // This will be the PO Number
 	ReceiptDate    string      `json:"receipt_date"`       // PO Date
+	Revision       int         `json:"revision"`           // PO revision; 0 as issued
+	Status         string      `json:"status"`             // PO status; Cancelled orders are watermarked
 	PaymentMethod  string      `json:"payment_method"`     // e.g., "30-day term"
 	Items          []PDFItem   `json:"items"`
 	RoundingAdj    float64     `json:"rounding_adj"`
@@ -22,13 +27,25 @@ type PDFData struct {
 	BankAccount    string      `json:"bank_account"`
 	CustomerAddress string     `json:"customer_address"`   // Vendor's address
 	CustomerEmail  string      `json:"customer_email"`     // Vendor's email
+	Footer         string      `json:"footer"`             // Printed at the foot of every page
+	Currency       string      `json:"currency"`
+	Layout         string      `json:"layout"`             // Vendor's layout; empty for the company default
+	TaxLabel       string      `json:"tax_label"`
+	TaxRate        float64     `json:"tax_rate"`           // Percent
+	RoundingStep   float64     `json:"rounding_step"`      // Grand total is rounded to a multiple of this; 0 for none
+	Subtotal       float64     `json:"subtotal"`
+	TaxAmount      float64     `json:"tax_amount"`
+	GrandTotal     float64     `json:"grand_total"`
+	Logo           []byte      `json:"-"`                  // PNG, JPEG or GIF image
+	LogoType       string      `json:"-"`                  // MIME type of Logo
 }
 
 // PDFItem represents a single item in the purchase order.
 type PDFItem struct {
-	Desc     string  `json:"desc"`
-	Qty      float64 `json:"qty"`
-	Uom      string  `json:"uom"`
-	UPrice   float64 `json:"u_price"`
-	Discount float64 `json:"discount"`
+	Desc         string  `json:"desc"`
+	Qty          float64 `json:"qty"`
+	Uom          string  `json:"uom"`
+	UPrice       float64 `json:"u_price"`
+	DeliveryDate string  `json:"delivery_date"`
+	Changed      bool    `json:"changed"` // Changed in the current revision
 }
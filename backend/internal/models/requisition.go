@@ -2,23 +2,104 @@ package models
 
 import "time"
 
+// Requisition statuses. A requisition is a Draft until its requester submits
+// it, and only Pending requisitions appear in approval queues. A Returned
+// requisition is back with its requester for changes.
+const (
+	RequisitionStatusDraft    = "Draft"
+	RequisitionStatusPending  = "Pending"
+	RequisitionStatusReturned = "Returned"
+	RequisitionStatusApproved = "Approved"
+	RequisitionStatusRejected = "Rejected"
+)
+
+// DefaultCurrency is the ISO 4217 code requisitions are priced in when none
+// is given.
+const DefaultCurrency = "MYR"
+
+// RoundOutcomeWithdrawn ends a round the requester pulled back before a
+// decision. Other rounds end with the status the requisition moved to.
+const RoundOutcomeWithdrawn = "Withdrawn"
+
 type Requisition struct {
-	ID              int       `json:"id"`
-	RequesterID     int       `json:"requester_id"`
-	VendorID        *int      `json:"vendor_id"` // Pointer to allow null
-	ItemDescription string    `json:"item_description" validate:"required"`
-	Quantity        int       `json:"quantity" validate:"required,gt=0"`
-	EstimatedPrice  float64   `json:"estimated_price" validate:"required,gt=0"`
-	TotalPrice      float64   `json:"total_price"`
-	Justification   string    `json:"justification"`
-	Status          string    `json:"status"`
-	CreatedAt       time.Time `json:"created_at"`
+	ID              int        `json:"id"`
+	RequesterID     int        `json:"requester_id"`
+	VendorID        *int       `json:"vendor_id"` // Pointer to allow null
+	ItemDescription string     `json:"item_description" validate:"required"`
+	Quantity        int        `json:"quantity" validate:"required,gt=0"`
+	EstimatedPrice  float64    `json:"estimated_price" validate:"required,gt=0"`
+	TotalPrice      float64    `json:"total_price"`
+	Justification   string     `json:"justification"`
+	Currency        string     `json:"currency"`
+	ShipTo          string     `json:"ship_to"` // Delivery address; empty means the company's own
+	Status          string     `json:"status"`
+	ApproverID      *int       `json:"approver_id,omitempty"` // Assigned approver; nil means any approver
+	Round           int        `json:"round"`                 // Number of times submitted
+	SubmittedAt     *time.Time `json:"submitted_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	Version         int        `json:"version"`
+}
+
+// EditableByRequester reports whether the requester may still change the
+// requisition: while it is a draft or has been returned to them.
+func (r *Requisition) EditableByRequester() bool {
+	return r.Status == RequisitionStatusDraft || r.Status == RequisitionStatusReturned
+}
+
+// RequisitionRound records how one submission of a requisition ended.
+type RequisitionRound struct {
+	ID            int        `json:"id"`
+	RequisitionID int        `json:"requisition_id"`
+	Round         int        `json:"round"`
+	SubmittedAt   *time.Time `json:"submitted_at,omitempty"`
+	Outcome       string     `json:"outcome"`
+	Reason        string     `json:"reason,omitempty"`
+	DecidedBy     *int       `json:"decided_by,omitempty"`
+	DecidedByName string     `json:"decided_by_name,omitempty"`
+	OnBehalfOfID  *int       `json:"on_behalf_of_id,omitempty"`
+	DecidedAt     time.Time  `json:"decided_at"`
+}
+
+// DecisionReasonPayload carries the explanation required to reject or return
+// a requisition.
+type DecisionReasonPayload struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+// BulkDecisionPayload lists the requisitions to approve or reject at once.
+// The reason is optional when approving and required when rejecting.
+type BulkDecisionPayload struct {
+	RequisitionIDs []int  `json:"requisition_ids" validate:"required,min=1,max=100,dive,gt=0"`
+	Reason         string `json:"reason"`
+}
+
+// BulkDecisionResponse reports the outcome of a bulk decision for each
+// requisition, in request order. BatchID links the activity log entries of
+// the operation.
+type BulkDecisionResponse struct {
+	BatchID   string               `json:"batch_id"`
+	Succeeded int                  `json:"succeeded"`
+	Failed    int                  `json:"failed"`
+	Results   []BulkDecisionResult `json:"results"`
+}
+
+// BulkDecisionResult is the outcome for one requisition. Status is the HTTP
+// status the single approve or reject call would have returned.
+type BulkDecisionResult struct {
+	RequisitionID int    `json:"requisition_id"`
+	Success       bool   `json:"success"`
+	Status        int    `json:"status"`
+	Error         string `json:"error,omitempty"`
 }
 
+// CreateRequisitionPayload holds the editable fields of a requisition. Drafts
+// may be incomplete; the required fields are checked on submit.
 type CreateRequisitionPayload struct {
 	VendorID        *int    `json:"vendor_id"`
-	ItemDescription string  `json:"item_description" validate:"required"`
-	Quantity        int     `json:"quantity" validate:"required,gt=0"`
-	EstimatedPrice  float64 `json:"estimated_price" validate:"required,gt=0"`
+	ItemDescription string  `json:"item_description"`
+	Quantity        int     `json:"quantity" validate:"gte=0"`
+	EstimatedPrice  float64 `json:"estimated_price" validate:"gte=0"`
 	Justification   string  `json:"justification"`
+	Currency        string  `json:"currency" validate:"omitempty,len=3,alpha"`
+	ShipTo          string  `json:"ship_to" validate:"max=1000"`
 }
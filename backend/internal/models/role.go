@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Permission codes checked by the API. They must match the rows seeded in
+// the permissions table.
+const (
+	PermUserRead           = "user:read"
+	PermUserWrite          = "user:write"
+	PermRoleManage         = "role:manage"
+	PermInvitationManage   = "invitation:manage"
+	PermSecurityManage     = "security:manage"
+	PermVendorRead         = "vendor:read"
+	PermVendorWrite        = "vendor:write"
+	PermRequisitionCreate  = "requisition:create"
+	PermRequisitionReadAll = "requisition:read:all"
+	PermRequisitionApprove = "requisition:approve"
+	PermRequisitionManage  = "requisition:manage"
+	PermPORead             = "po:read"
+	PermPOReadAll          = "po:read:all"
+	PermPOWrite            = "po:write"
+	PermPOApprove          = "po:approve"
+	PermNavigationManage   = "navigation:manage"
+	PermReportView         = "report:view"
+	PermSettingsManage     = "settings:manage"
+	PermVendorPortal       = "vendor_portal:access"
+	PermOrgRead            = "org:read"
+	PermOrgManage          = "org:manage"
+	PermSoDManage          = "sod:manage"
+	PermCommentModerate    = "comment:moderate"
+	PermAttachmentManage   = "attachment:manage"
+)
+
+// AdminRole is the built-in role that must always be able to manage roles.
+const AdminRole = "Admin"
+
+// Permission describes a single permission in the catalogue.
+type Permission struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// Role is a named set of permissions.
+type Role struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	IsSystem    bool      `json:"is_system"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateRolePayload defines the structure for creating a role.
+type CreateRolePayload struct {
+	Name        string   `json:"name" validate:"required,max=50"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" validate:"dive,required"`
+}
+
+// UpdateRolePayload defines the structure for updating a role.
+// Role names are fixed once created.
+type UpdateRolePayload struct {
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" validate:"dive,required"`
+}
+
+// UserRoles lists a user's primary role and any additional roles they hold.
+type UserRoles struct {
+	UserID          int      `json:"user_id"`
+	Role            string   `json:"role"`
+	AdditionalRoles []string `json:"additional_roles"`
+}
+
+// SetUserRolesPayload replaces a user's additional roles.
+type SetUserRolesPayload struct {
+	AdditionalRoles []string `json:"additional_roles" validate:"dive,required"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// Duties checked by segregation of duties rules. A rule pairs two of them.
+const (
+	DutyRequisitionCreate  = "requisition.create"
+	DutyRequisitionApprove = "requisition.approve"
+	DutyVendorCreate       = "vendor.create"
+	DutyPOApprove          = "po.approve"
+	DutyPOChange           = "po.change"
+	DutyGoodsReceive       = "goods.receive"
+	DutyInvoiceApprove     = "invoice.approve"
+)
+
+// Duties lists every duty a rule may refer to.
+var Duties = []string{
+	DutyRequisitionCreate,
+	DutyRequisitionApprove,
+	DutyVendorCreate,
+	DutyPOApprove,
+	DutyPOChange,
+	DutyGoodsReceive,
+	DutyInvoiceApprove,
+}
+
+// SoDRule forbids one person from performing both duties on the same transaction.
+type SoDRule struct {
+	ID          int       `json:"id"`
+	Code        string    `json:"code"`
+	Description string    `json:"description"`
+	FirstDuty   string    `json:"first_duty"`
+	SecondDuty  string    `json:"second_duty"`
+	Enabled     bool      `json:"enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// SoDRulePayload defines the structure for creating or updating a rule.
+type SoDRulePayload struct {
+	Code        string `json:"code" validate:"required,max=100"`
+	Description string `json:"description" validate:"required"`
+	FirstDuty   string `json:"first_duty" validate:"required"`
+	SecondDuty  string `json:"second_duty" validate:"required,nefield=FirstDuty"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+// SoDViolation records an attempt that a rule blocked.
+type SoDViolation struct {
+	ID           int       `json:"id"`
+	RuleID       *int      `json:"rule_id,omitempty"`
+	RuleCode     string    `json:"rule_code"`
+	UserID       *int      `json:"user_id,omitempty"`
+	OnBehalfOfID *int      `json:"on_behalf_of_id,omitempty"`
+	Duty         string    `json:"duty"`
+	EntityType   string    `json:"entity_type"`
+	EntityID     int       `json:"entity_id"`
+	Details      string    `json:"details"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SoDCheck describes a duty about to be performed. Performers maps the other
+// duties already carried out on the same transaction to the users who did
+// them; duties with no known performer are left out.
+type SoDCheck struct {
+	Duties       []string
+	ActorID      int
+	OnBehalfOfID *int
+	EntityType   string
+	EntityID     int
+	Performers   map[string]int
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Purchase order statuses.
+const (
+	PurchaseOrderStatusIssued    = "Issued"
+	PurchaseOrderStatusCancelled = "Cancelled"
+	PurchaseOrderStatusClosed    = "Closed"
+)
+
+// StatusTransition records one status change of a requisition or purchase
+// order. FromStatus is nil for the status the entity was created with.
+type StatusTransition struct {
+	ID         int64     `json:"id"`
+	EntityType string    `json:"entity_type"`
+	EntityID   int       `json:"entity_id"`
+	FromStatus *string   `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ActorID    *int      `json:"actor_id,omitempty"`
+	ActorName  string    `json:"actor_name,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// StatusChange describes a status change to make. From and Version are the
+// status and version the entity must still be at, so a change made on a stale
+// read fails instead of acting on a later state.
+type StatusChange struct {
+	From    string
+	To      string
+	Version int
+	ActorID *int
+	Reason  string
+}
+
+// CycleTimes are the durations computed from one entity's status history,
+// in seconds. Metrics that do not apply yet are omitted.
+type CycleTimes struct {
+	TimeInStatus         map[string]float64 `json:"time_in_status_seconds"`
+	SubmitToApprove      *float64           `json:"submit_to_approve_seconds,omitempty"`
+	FirstSubmitToApprove *float64           `json:"first_submit_to_approve_seconds,omitempty"`
+	ApproveToPOIssued    *float64           `json:"approve_to_po_issued_seconds,omitempty"`
+}
+
+// StatusHistory is an entity's status transitions, oldest first, with the
+// cycle times computed from them.
+type StatusHistory struct {
+	Transitions []StatusTransition `json:"transitions"`
+	CycleTimes  CycleTimes         `json:"cycle_times"`
+}
+
+// CycleTimeMetric summarises one cycle time across many requisitions.
+type CycleTimeMetric struct {
+	Count          int      `json:"count"`
+	AverageSeconds *float64 `json:"average_seconds"`
+	MedianSeconds  *float64 `json:"median_seconds"`
+}
+
+// CycleTimeSummary summarises the cycle times of requisitions approved in a
+// period.
+type CycleTimeSummary struct {
+	From                 time.Time       `json:"from"`
+	To                   time.Time       `json:"to"`
+	SubmitToApprove      CycleTimeMetric `json:"submit_to_approve"`
+	FirstSubmitToApprove CycleTimeMetric `json:"first_submit_to_approve"`
+	ApproveToPOIssued    CycleTimeMetric `json:"approve_to_po_issued"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// UserTwoFactor holds a user's TOTP enrolment.
+type UserTwoFactor struct {
+	UserID       int        `json:"user_id"`
+	Secret       string     `json:"-"` // Never expose the shared secret after enrolment
+	Enabled      bool       `json:"enabled"`
+	LastUsedStep int64      `json:"-"`
+	EnabledAt    *time.Time `json:"enabled_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// RecoveryCode is a single-use fallback for a lost authenticator.
+type RecoveryCode struct {
+	ID       int        `json:"id"`
+	UserID   int        `json:"user_id"`
+	CodeHash string     `json:"-"`
+	UsedAt   *time.Time `json:"used_at,omitempty"`
+}
+
+// TwoFactorEnrolment is returned when a user starts enrolment.
+// The provisioning URI is meant to be rendered as a QR code by the client.
+type TwoFactorEnrolment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// TwoFactorCodePayload defines the structure for requests that carry a TOTP or recovery code.
+type TwoFactorCodePayload struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// TwoFactorChallengePayload defines the structure for the second login step.
+type TwoFactorChallengePayload struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+// TwoFactorEnrolmentPayload defines the structure for starting enrolment during login.
+type TwoFactorEnrolmentPayload struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+}
+
+// RecoveryCodesResponse defines the structure for returning freshly generated recovery codes.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -1,19 +1,32 @@
 package models
 
+// Account statuses. Self-registered users start as pending_approval when the
+// organisation requires approval; everyone else is active.
+const (
+	UserStatusActive          = "active"
+	UserStatusPendingApproval = "pending_approval"
+	UserStatusRejected        = "rejected"
+)
+
 type User struct {
 	ID             int    `json:"id"`
 	Name           string `json:"name"`
 	Email          string `json:"email"`
 	HashedPassword string `json:"-"` // Do not expose password hash
 	Role           string `json:"role"`
+	Status         string `json:"status"`
+	DepartmentID   *int   `json:"department_id,omitempty"`
+	ManagerID      *int   `json:"manager_id,omitempty"`
+	VendorID       *int   `json:"vendor_id,omitempty"` // Set for vendor portal users
+	Version        int    `json:"version"`
 }
 
-// RegistrationPayload defines the structure for user registration request
+// RegistrationPayload defines the structure for user registration request.
+// Self-registration always creates an Employee; other roles require an invitation.
 type RegistrationPayload struct {
 	Name     string `json:"name" validate:"required"`
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=8"`
-	Role     string `json:"role" validate:"required,oneof=Employee Admin 'Procurement Officer' Approver Vendor"`
 }
 
 // LoginPayload defines the structure for user login request
@@ -22,16 +35,36 @@ type LoginPayload struct {
 	Password string `json:"password" validate:"required"`
 }
 
-// LoginResponse defines the structure for a successful login response
+// LoginResponse defines the structure for a login response.
+// Token is only set once login is complete; when a second factor is needed
+// ChallengeToken is returned instead and must be exchanged via /login/2fa.
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token                      string   `json:"token,omitempty"`
+	TwoFactorRequired          bool     `json:"two_factor_required,omitempty"`
+	TwoFactorEnrolmentRequired bool     `json:"two_factor_enrolment_required,omitempty"`
+	ChallengeToken             string   `json:"challenge_token,omitempty"`
+	RecoveryCodes              []string `json:"recovery_codes,omitempty"`
+}
+
+// CreateUserPayload defines the structure for an admin creating an account directly.
+type CreateUserPayload struct {
+	Name     string `json:"name" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=8"`
+	Role     string `json:"role" validate:"required,max=50"`
 }
 
 // UpdateUserPayload defines the structure for updating a user's details.
 // Admins can update a user's name and role. Email is not updatable for simplicity.
 type UpdateUserPayload struct {
 	Name string `json:"name" validate:"required"`
-	Role string `json:"role" validate:"required,oneof=Employee Admin 'Procurement Officer' Approver Vendor"`
+	Role string `json:"role" validate:"required,max=50"`
+}
+
+// SetUserVendorPayload links a vendor portal user to the vendor they work
+// for. A null vendor_id removes the link.
+type SetUserVendorPayload struct {
+	VendorID *int `json:"vendor_id"`
 }
 
 // UpdateProfilePayload defines the structure for updating a user's own name.
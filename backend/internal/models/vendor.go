@@ -7,4 +7,7 @@ type Vendor struct {
 	Email         *string `json:"email,omitempty" validate:"omitempty,email"`
 	Phone         *string `json:"phone,omitempty"`
 	Address       *string `json:"address,omitempty"`
+	PDFLayout     *string `json:"pdf_layout,omitempty" validate:"omitempty,max=50"` // Overrides the company's PDF layout
+	CreatedBy     *int    `json:"created_by,omitempty"`
+	Version       int     `json:"version"`
 }
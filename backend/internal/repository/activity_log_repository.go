@@ -9,6 +9,7 @@ import (
 type ActivityLogRepository interface {
 	Log(activity *models.ActivityLog) error
 	GetAll() ([]models.ActivityLog, error)
+	GetByTarget(targetType string, targetID int) ([]models.ActivityLog, error)
 }
 
 type postgresActivityLogRepository struct {
@@ -23,8 +24,8 @@ func NewPostgresActivityLogRepository(db *sql.DB) ActivityLogRepository {
 // Log creates a new activity log entry in the database.
 func (r *postgresActivityLogRepository) Log(activity *models.ActivityLog) error {
 	query := `
-		INSERT INTO activity_logs (user_id, action, target_type, target_id, status, details)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO activity_logs (user_id, action, target_type, target_id, status, details, batch_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
 	_, err := r.db.Exec(
 		query,
@@ -34,6 +35,7 @@ func (r *postgresActivityLogRepository) Log(activity *models.ActivityLog) error
 		activity.TargetID,
 		activity.Status,
 		activity.Details,
+		activity.BatchID,
 	)
 	return err
 }
@@ -41,7 +43,7 @@ func (r *postgresActivityLogRepository) Log(activity *models.ActivityLog) error
 // GetAll retrieves all activity logs from the database, ordered by creation date.
 func (r *postgresActivityLogRepository) GetAll() ([]models.ActivityLog, error) {
 	query := `
-		SELECT id, user_id, action, target_type, target_id, status, details, created_at
+		SELECT id, user_id, action, target_type, target_id, status, details, batch_id, created_at
 		FROM activity_logs
 		ORDER BY created_at DESC
 		LIMIT 100 -- Limit to the last 100 activities for performance
@@ -63,6 +65,42 @@ func (r *postgresActivityLogRepository) GetAll() ([]models.ActivityLog, error) {
 			&log.TargetID,
 			&log.Status,
 			&log.Details,
+			&log.BatchID,
+			&log.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		logs = append(logs, log)
+	}
+	return logs, nil
+}
+
+// GetByTarget retrieves every activity recorded against one entity, oldest first.
+func (r *postgresActivityLogRepository) GetByTarget(targetType string, targetID int) ([]models.ActivityLog, error) {
+	query := `
+		SELECT id, user_id, action, target_type, target_id, status, details, batch_id, created_at
+		FROM activity_logs
+		WHERE target_type = $1 AND target_id = $2
+		ORDER BY created_at ASC
+	`
+	rows, err := r.db.Query(query, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := []models.ActivityLog{}
+	for rows.Next() {
+		var log models.ActivityLog
+		if err := rows.Scan(
+			&log.ID,
+			&log.UserID,
+			&log.Action,
+			&log.TargetType,
+			&log.TargetID,
+			&log.Status,
+			&log.Details,
+			&log.BatchID,
 			&log.CreatedAt,
 		); err != nil {
 			return nil, err
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"procurement-system/internal/models"
+)
+
+var (
+	ErrAttachmentNotFound = errors.New("attachment not found")
+)
+
+// AttachmentRepository defines the interface for attachment metadata. The
+// content itself is kept in a blob store.
+type AttachmentRepository interface {
+	CreateAttachment(attachment *models.Attachment) error
+	GetAttachmentByID(id int) (*models.Attachment, error)
+	GetAttachments(entityType string, entityID int, vendorVisibleOnly bool) ([]models.Attachment, error)
+	DeleteAttachment(id int) error
+}
+
+type postgresAttachmentRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresAttachmentRepository creates a new instance of AttachmentRepository.
+func NewPostgresAttachmentRepository(db *sql.DB) AttachmentRepository {
+	return &postgresAttachmentRepository{db: db}
+}
+
+const attachmentColumns = `
+	id, entity_type, entity_id, filename, content_type, size_bytes, sha256, storage_key, visibility, uploaded_by, created_at`
+
+func scanAttachment(row interface{ Scan(...interface{}) error }, a *models.Attachment) error {
+	return row.Scan(
+		&a.ID, &a.EntityType, &a.EntityID, &a.Filename, &a.ContentType, &a.SizeBytes, &a.SHA256,
+		&a.StorageKey, &a.Visibility, &a.UploadedBy, &a.CreatedAt,
+	)
+}
+
+// CreateAttachment records an attachment whose content is already stored.
+func (r *postgresAttachmentRepository) CreateAttachment(a *models.Attachment) error {
+	query := `
+		INSERT INTO attachments (entity_type, entity_id, filename, content_type, size_bytes, sha256, storage_key, visibility, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(query, a.EntityType, a.EntityID, a.Filename, a.ContentType, a.SizeBytes, a.SHA256,
+		a.StorageKey, a.Visibility, a.UploadedBy).Scan(&a.ID, &a.CreatedAt)
+}
+
+// GetAttachmentByID returns a single attachment.
+func (r *postgresAttachmentRepository) GetAttachmentByID(id int) (*models.Attachment, error) {
+	a := &models.Attachment{}
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = $1`
+	if err := scanAttachment(r.db.QueryRow(query, id), a); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// GetAttachments returns an entity's attachments, oldest first, optionally
+// only those visible to the vendor.
+func (r *postgresAttachmentRepository) GetAttachments(entityType string, entityID int, vendorVisibleOnly bool) ([]models.Attachment, error) {
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM attachments
+		WHERE entity_type = $1 AND entity_id = $2 AND (NOT $3 OR visibility = 'vendor')
+		ORDER BY created_at ASC, id ASC
+	`
+	rows, err := r.db.Query(query, entityType, entityID, vendorVisibleOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		var a models.Attachment
+		if err := scanAttachment(rows, &a); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+// DeleteAttachment removes an attachment's record.
+func (r *postgresAttachmentRepository) DeleteAttachment(id int) error {
+	result, err := r.db.Exec(`DELETE FROM attachments WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrAttachmentNotFound
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"procurement-system/internal/models"
+)
+
+var (
+	ErrChangeOrderNotFound = errors.New("change order not found")
+	ErrChangeOrderPending  = errors.New("purchase order already has a change order waiting for approval")
+)
+
+// ChangeOrderRepository stores purchase order change orders and applies them
+// to the orders they change.
+type ChangeOrderRepository interface {
+	CreateChangeOrder(co *models.ChangeOrder) error
+	ApplyChangeOrder(co *models.ChangeOrder, poVersion int) error
+	RejectChangeOrder(co *models.ChangeOrder) error
+	GetChangeOrderByID(id int) (*models.ChangeOrder, error)
+	GetChangeOrdersByPurchaseOrder(poID int) ([]models.ChangeOrder, error)
+	GetPendingChangeOrders() ([]models.ChangeOrder, error)
+	GetAppliedChangeOrder(poID int, revision int) (*models.ChangeOrder, error)
+}
+
+type postgresChangeOrderRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresChangeOrderRepository(db *sql.DB) ChangeOrderRepository {
+	return &postgresChangeOrderRepository{db: db}
+}
+
+const changeOrderColumns = `id, purchase_order_id, revision, base_revision, status, reason, changes, previous_total, new_total, requested_by, requested_at, decided_by, decided_at, decision_reason`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanChangeOrder(row rowScanner) (*models.ChangeOrder, error) {
+	co := &models.ChangeOrder{}
+	var changes []byte
+	if err := row.Scan(
+		&co.ID, &co.PurchaseOrderID, &co.Revision, &co.BaseRevision, &co.Status, &co.Reason, &changes,
+		&co.PreviousTotal, &co.NewTotal, &co.RequestedBy, &co.RequestedAt, &co.DecidedBy, &co.DecidedAt, &co.DecisionReason,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(changes, &co.Changes); err != nil {
+		return nil, err
+	}
+	return co, nil
+}
+
+// CreateChangeOrder stores a change order waiting for approval. It fails with
+// ErrChangeOrderPending if the order already has one.
+func (r *postgresChangeOrderRepository) CreateChangeOrder(co *models.ChangeOrder) error {
+	changes, err := json.Marshal(co.Changes)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO purchase_order_change_orders (purchase_order_id, base_revision, status, reason, changes, previous_total, new_total, requested_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, requested_at
+	`
+	err = r.db.QueryRow(
+		query,
+		co.PurchaseOrderID, co.BaseRevision, co.Status, co.Reason, changes, co.PreviousTotal, co.NewTotal, co.RequestedBy,
+	).Scan(&co.ID, &co.RequestedAt)
+	if isUniqueViolation(err) {
+		return ErrChangeOrderPending
+	}
+	return err
+}
+
+// ApplyChangeOrder makes a change order the purchase order's next revision:
+// the changed lines and the order total are updated, and the change order is
+// stored as Applied with its revision number. A change order with no ID is
+// stored for the first time; otherwise it must still be Pending, or
+// ErrStatusConflict is returned. poVersion is the version of the order the
+// change was worked out against. A call-off may not be changed to take its
+// blanket over the ceiling.
+func (r *postgresChangeOrderRepository) ApplyChangeOrder(co *models.ChangeOrder, poVersion int) error {
+	changes, err := json.Marshal(co.Changes)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var revision int
+	var blanketID *int
+	err = tx.QueryRow(`
+		UPDATE purchase_orders
+		SET total_amount = $1, committed_amount = $1, revision = revision + 1, version = version + 1
+		WHERE id = $2 AND version = $3
+		RETURNING revision, blanket_id
+	`, co.NewTotal, co.PurchaseOrderID, poVersion).Scan(&revision, &blanketID)
+	if err == sql.ErrNoRows {
+		return versionMismatch(tx, "purchase_orders", co.PurchaseOrderID, ErrPurchaseOrderNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	if blanketID != nil {
+		if err := checkBlanketCeiling(tx, *blanketID); err != nil {
+			return err
+		}
+	}
+
+	for _, change := range co.Changes {
+		_, err := tx.Exec(`
+			UPDATE purchase_order_lines
+			SET quantity = $1, unit_price = $2, total_price = $3, delivery_date = $4
+			WHERE purchase_order_id = $5 AND line_no = $6
+		`, change.NewQuantity, change.NewUnitPrice, change.NewTotal, change.NewDeliveryDate, co.PurchaseOrderID, change.LineNo)
+		if err != nil {
+			return err
+		}
+	}
+
+	if co.ID == 0 {
+		err = tx.QueryRow(`
+			INSERT INTO purchase_order_change_orders (purchase_order_id, revision, base_revision, status, reason, changes, previous_total, new_total, requested_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id, requested_at
+		`, co.PurchaseOrderID, revision, co.BaseRevision, models.ChangeOrderStatusApplied, co.Reason, changes, co.PreviousTotal, co.NewTotal, co.RequestedBy,
+		).Scan(&co.ID, &co.RequestedAt)
+	} else {
+		err = tx.QueryRow(`
+			UPDATE purchase_order_change_orders
+			SET status = $1, revision = $2, decided_by = $3, decided_at = CURRENT_TIMESTAMP, decision_reason = $4
+			WHERE id = $5 AND status = $6
+			RETURNING decided_at
+		`, models.ChangeOrderStatusApplied, revision, co.DecidedBy, co.DecisionReason, co.ID, models.ChangeOrderStatusPending,
+		).Scan(&co.DecidedAt)
+		if err == sql.ErrNoRows {
+			return ErrStatusConflict
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	co.Status = models.ChangeOrderStatusApplied
+	co.Revision = &revision
+	return nil
+}
+
+// RejectChangeOrder turns down a change order waiting for approval. It
+// returns ErrStatusConflict if the change order is no longer Pending.
+func (r *postgresChangeOrderRepository) RejectChangeOrder(co *models.ChangeOrder) error {
+	err := r.db.QueryRow(`
+		UPDATE purchase_order_change_orders
+		SET status = $1, decided_by = $2, decided_at = CURRENT_TIMESTAMP, decision_reason = $3
+		WHERE id = $4 AND status = $5
+		RETURNING decided_at
+	`, models.ChangeOrderStatusRejected, co.DecidedBy, co.DecisionReason, co.ID, models.ChangeOrderStatusPending).Scan(&co.DecidedAt)
+	if err == sql.ErrNoRows {
+		return ErrStatusConflict
+	}
+	if err != nil {
+		return err
+	}
+	co.Status = models.ChangeOrderStatusRejected
+	return nil
+}
+
+func (r *postgresChangeOrderRepository) GetChangeOrderByID(id int) (*models.ChangeOrder, error) {
+	row := r.db.QueryRow(`SELECT `+changeOrderColumns+` FROM purchase_order_change_orders WHERE id = $1`, id)
+	co, err := scanChangeOrder(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrChangeOrderNotFound
+	}
+	return co, err
+}
+
+// GetChangeOrdersByPurchaseOrder returns a purchase order's change orders,
+// oldest first.
+func (r *postgresChangeOrderRepository) GetChangeOrdersByPurchaseOrder(poID int) ([]models.ChangeOrder, error) {
+	rows, err := r.db.Query(`
+		SELECT `+changeOrderColumns+`
+		FROM purchase_order_change_orders
+		WHERE purchase_order_id = $1
+		ORDER BY requested_at ASC, id ASC
+	`, poID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanChangeOrders(rows)
+}
+
+// GetPendingChangeOrders returns every change order waiting for approval,
+// oldest first.
+func (r *postgresChangeOrderRepository) GetPendingChangeOrders() ([]models.ChangeOrder, error) {
+	rows, err := r.db.Query(`
+		SELECT `+changeOrderColumns+`
+		FROM purchase_order_change_orders
+		WHERE status = $1
+		ORDER BY requested_at ASC, id ASC
+	`, models.ChangeOrderStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanChangeOrders(rows)
+}
+
+func scanChangeOrders(rows *sql.Rows) ([]models.ChangeOrder, error) {
+	changeOrders := []models.ChangeOrder{}
+	for rows.Next() {
+		co, err := scanChangeOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		changeOrders = append(changeOrders, *co)
+	}
+	return changeOrders, rows.Err()
+}
+
+// GetAppliedChangeOrder returns the change order that created a revision of a
+// purchase order.
+func (r *postgresChangeOrderRepository) GetAppliedChangeOrder(poID int, revision int) (*models.ChangeOrder, error) {
+	row := r.db.QueryRow(`
+		SELECT `+changeOrderColumns+`
+		FROM purchase_order_change_orders
+		WHERE purchase_order_id = $1 AND revision = $2
+	`, poID, revision)
+	co, err := scanChangeOrder(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrChangeOrderNotFound
+	}
+	return co, err
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"procurement-system/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrCommentNotFound = errors.New("comment not found")
+)
+
+// CommentRepository defines the interface for comment database operations.
+type CommentRepository interface {
+	CreateComment(comment *models.Comment) error
+	GetCommentByID(id int) (*models.Comment, error)
+	GetComments(entityType string, entityID int, vendorVisibleOnly bool) ([]models.Comment, error)
+	UpdateComment(comment *models.Comment) error
+	DeleteComment(id int, at time.Time) error
+}
+
+type postgresCommentRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresCommentRepository creates a new instance of CommentRepository.
+func NewPostgresCommentRepository(db *sql.DB) CommentRepository {
+	return &postgresCommentRepository{db: db}
+}
+
+const commentColumns = `
+	c.id, c.entity_type, c.entity_id, c.parent_id, c.author_id, COALESCE(u.name, ''), c.body, c.visibility,
+	ARRAY(SELECT m.user_id FROM comment_mentions m WHERE m.comment_id = c.id ORDER BY m.user_id),
+	c.created_at, c.edited_at, c.deleted_at`
+
+func scanComment(row interface{ Scan(...interface{}) error }, c *models.Comment) error {
+	var mentions pq.Int64Array
+	if err := row.Scan(
+		&c.ID, &c.EntityType, &c.EntityID, &c.ParentID, &c.AuthorID, &c.AuthorName, &c.Body, &c.Visibility,
+		&mentions, &c.CreatedAt, &c.EditedAt, &c.DeletedAt,
+	); err != nil {
+		return err
+	}
+	c.Mentions = make([]int, len(mentions))
+	for i, id := range mentions {
+		c.Mentions[i] = int(id)
+	}
+	return nil
+}
+
+// CreateComment stores a comment together with the users it mentions.
+func (r *postgresCommentRepository) CreateComment(c *models.Comment) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO comments (entity_type, entity_id, parent_id, author_id, body, visibility)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	err = tx.QueryRow(query, c.EntityType, c.EntityID, c.ParentID, c.AuthorID, c.Body, c.Visibility).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := insertMentions(tx, c.ID, c.Mentions); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetCommentByID returns a single comment.
+func (r *postgresCommentRepository) GetCommentByID(id int) (*models.Comment, error) {
+	c := &models.Comment{}
+	query := `SELECT ` + commentColumns + ` FROM comments c LEFT JOIN users u ON u.id = c.author_id WHERE c.id = $1`
+	if err := scanComment(r.db.QueryRow(query, id), c); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetComments returns an entity's comments, oldest first, optionally only
+// those visible to the vendor.
+func (r *postgresCommentRepository) GetComments(entityType string, entityID int, vendorVisibleOnly bool) ([]models.Comment, error) {
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments c
+		LEFT JOIN users u ON u.id = c.author_id
+		WHERE c.entity_type = $1 AND c.entity_id = $2 AND (NOT $3 OR c.visibility = 'vendor')
+		ORDER BY c.created_at ASC, c.id ASC
+	`
+	rows, err := r.db.Query(query, entityType, entityID, vendorVisibleOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		var c models.Comment
+		if err := scanComment(rows, &c); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// UpdateComment replaces a comment's body and mentions and stamps it as edited.
+func (r *postgresCommentRepository) UpdateComment(c *models.Comment) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE comments SET body = $1, edited_at = $2 WHERE id = $3 AND deleted_at IS NULL`, c.Body, c.EditedAt, c.ID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrCommentNotFound
+	}
+
+	if _, err := tx.Exec(`DELETE FROM comment_mentions WHERE comment_id = $1`, c.ID); err != nil {
+		return err
+	}
+	if err := insertMentions(tx, c.ID, c.Mentions); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteComment removes a comment's body and mentions but keeps its place in
+// the thread, so replies still make sense.
+func (r *postgresCommentRepository) DeleteComment(id int, at time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE comments SET body = '', deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`, at, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrCommentNotFound
+	}
+
+	if _, err := tx.Exec(`DELETE FROM comment_mentions WHERE comment_id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertMentions(tx *sql.Tx, commentID int, userIDs []int) error {
+	for _, userID := range userIDs {
+		if _, err := tx.Exec(`INSERT INTO comment_mentions (comment_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, commentID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"procurement-system/internal/models"
+)
+
+var ErrCompanySettingsNotFound = errors.New("company settings have not been set up")
+
+// CompanySettingsRepository stores the versioned company settings and logos.
+type CompanySettingsRepository interface {
+	GetCurrentSettings() (*models.CompanySettings, error)
+	GetSettingsHistory() ([]models.CompanySettings, error)
+	CreateSettingsVersion(settings *models.CompanySettings) error
+	SaveLogo(logo *models.CompanyLogo) error
+	GetLogo(id int) (*models.CompanyLogo, error)
+}
+
+type postgresCompanySettingsRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresCompanySettingsRepository(db *sql.DB) CompanySettingsRepository {
+	return &postgresCompanySettingsRepository{db: db}
+}
+
+const companySettingsColumns = `version, company_name, reg_no, tin_no, msic_code, address, phones, email, bank_name, bank_account, payment_terms, pdf_footer, pdf_layout, tax_label, tax_rate, rounding_increment, logo_id, changed_by, changed_at`
+
+func scanCompanySettings(row rowScanner) (*models.CompanySettings, error) {
+	s := &models.CompanySettings{}
+	err := row.Scan(
+		&s.Version, &s.CompanyName, &s.RegNo, &s.TinNo, &s.MsicCode, &s.Address, &s.Phones, &s.Email,
+		&s.BankName, &s.BankAccount, &s.PaymentTerms, &s.PDFFooter, &s.PDFLayout, &s.TaxLabel, &s.TaxRate, &s.RoundingStep, &s.LogoID, &s.ChangedBy, &s.ChangedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetCurrentSettings returns the latest version of the settings.
+func (r *postgresCompanySettingsRepository) GetCurrentSettings() (*models.CompanySettings, error) {
+	s, err := scanCompanySettings(r.db.QueryRow(`SELECT ` + companySettingsColumns + ` FROM company_settings ORDER BY version DESC LIMIT 1`))
+	if err == sql.ErrNoRows {
+		return nil, ErrCompanySettingsNotFound
+	}
+	return s, err
+}
+
+// GetSettingsHistory returns every version of the settings, newest first.
+func (r *postgresCompanySettingsRepository) GetSettingsHistory() ([]models.CompanySettings, error) {
+	rows, err := r.db.Query(`SELECT ` + companySettingsColumns + ` FROM company_settings ORDER BY version DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.CompanySettings{}
+	for rows.Next() {
+		s, err := scanCompanySettings(rows)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, *s)
+	}
+	return history, rows.Err()
+}
+
+// CreateSettingsVersion stores settings as the version given. It fails with
+// ErrVersionConflict if that version already exists, meaning someone else
+// changed the settings first.
+func (r *postgresCompanySettingsRepository) CreateSettingsVersion(s *models.CompanySettings) error {
+	err := r.db.QueryRow(`
+		INSERT INTO company_settings (version, company_name, reg_no, tin_no, msic_code, address, phones, email, bank_name, bank_account, payment_terms, pdf_footer, pdf_layout, tax_label, tax_rate, rounding_increment, logo_id, changed_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		RETURNING changed_at
+	`, s.Version, s.CompanyName, s.RegNo, s.TinNo, s.MsicCode, s.Address, s.Phones, s.Email,
+		s.BankName, s.BankAccount, s.PaymentTerms, s.PDFFooter, s.PDFLayout, s.TaxLabel, s.TaxRate, s.RoundingStep, s.LogoID, s.ChangedBy,
+	).Scan(&s.ChangedAt)
+	if isUniqueViolation(err) {
+		return ErrVersionConflict
+	}
+	return err
+}
+
+// SaveLogo stores a logo, or finds the one already stored with the same
+// content, and sets logo.ID.
+func (r *postgresCompanySettingsRepository) SaveLogo(logo *models.CompanyLogo) error {
+	return r.db.QueryRow(`
+		INSERT INTO company_logos (content_type, data, sha256)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (sha256) DO UPDATE SET content_type = EXCLUDED.content_type
+		RETURNING id
+	`, logo.ContentType, logo.Data, logo.SHA256).Scan(&logo.ID)
+}
+
+func (r *postgresCompanySettingsRepository) GetLogo(id int) (*models.CompanyLogo, error) {
+	logo := &models.CompanyLogo{}
+	err := r.db.QueryRow(`SELECT id, content_type, data, sha256 FROM company_logos WHERE id = $1`, id).Scan(&logo.ID, &logo.ContentType, &logo.Data, &logo.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	return logo, nil
+}
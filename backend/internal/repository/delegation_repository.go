@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"procurement-system/internal/models"
+	"time"
+)
+
+var (
+	ErrDelegationNotFound = errors.New("delegation not found")
+)
+
+// DelegationRepository defines the interface for approval delegation database operations.
+type DelegationRepository interface {
+	CreateDelegation(d *models.Delegation) error
+	GetDelegationByID(id int) (*models.Delegation, error)
+	GetDelegationsForUser(userID int, since time.Time) ([]models.Delegation, error)
+	GetAllDelegations(since time.Time) ([]models.Delegation, error)
+	GetActiveDelegationsTo(delegateID int, at time.Time) ([]models.Delegation, error)
+	DeleteDelegation(id int) error
+}
+
+type postgresDelegationRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresDelegationRepository creates a new instance of DelegationRepository.
+func NewPostgresDelegationRepository(db *sql.DB) DelegationRepository {
+	return &postgresDelegationRepository{db: db}
+}
+
+const delegationSelect = `
+	SELECT d.id, d.delegator_id, dr.name, d.delegate_id, de.name, d.starts_at, d.ends_at,
+		d.max_amount, d.reason, d.created_by, d.created_at
+	FROM approval_delegations d
+	JOIN users dr ON dr.id = d.delegator_id
+	JOIN users de ON de.id = d.delegate_id
+`
+
+func scanDelegation(row interface{ Scan(...interface{}) error }, d *models.Delegation) error {
+	return row.Scan(&d.ID, &d.DelegatorID, &d.DelegatorName, &d.DelegateID, &d.DelegateName, &d.StartsAt, &d.EndsAt,
+		&d.MaxAmount, &d.Reason, &d.CreatedBy, &d.CreatedAt)
+}
+
+func (r *postgresDelegationRepository) queryDelegations(query string, args ...interface{}) ([]models.Delegation, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var delegations []models.Delegation
+	for rows.Next() {
+		var d models.Delegation
+		if err := scanDelegation(rows, &d); err != nil {
+			return nil, err
+		}
+		delegations = append(delegations, d)
+	}
+	return delegations, nil
+}
+
+// CreateDelegation inserts a new delegation.
+func (r *postgresDelegationRepository) CreateDelegation(d *models.Delegation) error {
+	query := `
+		INSERT INTO approval_delegations (delegator_id, delegate_id, starts_at, ends_at, max_amount, reason, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRow(query, d.DelegatorID, d.DelegateID, d.StartsAt, d.EndsAt, d.MaxAmount, d.Reason, d.CreatedBy).Scan(&d.ID, &d.CreatedAt)
+	if isForeignKeyViolation(err) {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+// GetDelegationByID returns a single delegation.
+func (r *postgresDelegationRepository) GetDelegationByID(id int) (*models.Delegation, error) {
+	d := &models.Delegation{}
+	if err := scanDelegation(r.db.QueryRow(delegationSelect+` WHERE d.id = $1`, id), d); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrDelegationNotFound
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+// GetDelegationsForUser returns delegations given or received by a user that
+// end after since, soonest first.
+func (r *postgresDelegationRepository) GetDelegationsForUser(userID int, since time.Time) ([]models.Delegation, error) {
+	return r.queryDelegations(delegationSelect+`
+		WHERE (d.delegator_id = $1 OR d.delegate_id = $1) AND d.ends_at > $2
+		ORDER BY d.starts_at ASC
+	`, userID, since)
+}
+
+// GetAllDelegations returns every delegation that ends after since, soonest first.
+func (r *postgresDelegationRepository) GetAllDelegations(since time.Time) ([]models.Delegation, error) {
+	return r.queryDelegations(delegationSelect+`
+		WHERE d.ends_at > $1
+		ORDER BY d.starts_at ASC
+	`, since)
+}
+
+// GetActiveDelegationsTo returns the delegations in force at the given time
+// for which the user is the delegate.
+func (r *postgresDelegationRepository) GetActiveDelegationsTo(delegateID int, at time.Time) ([]models.Delegation, error) {
+	return r.queryDelegations(delegationSelect+`
+		WHERE d.delegate_id = $1 AND d.starts_at <= $2 AND d.ends_at > $2
+		ORDER BY d.starts_at ASC
+	`, delegateID, at)
+}
+
+// DeleteDelegation removes a delegation.
+func (r *postgresDelegationRepository) DeleteDelegation(id int) error {
+	result, err := r.db.Exec(`DELETE FROM approval_delegations WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrDelegationNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"procurement-system/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDepartmentNotFound = errors.New("department not found")
+	ErrDepartmentExists   = errors.New("a department with this name already exists under the same parent")
+	ErrDepartmentNotEmpty = errors.New("department still has sub-departments")
+)
+
+// DepartmentRepository defines the interface for department and reporting line database operations.
+type DepartmentRepository interface {
+	GetAllDepartments() ([]models.Department, error)
+	GetDepartmentByID(id int) (*models.Department, error)
+	CreateDepartment(dept *models.Department) error
+	UpdateDepartment(dept *models.Department) error
+	DeleteDepartment(id int) error
+	SetUserOrganisation(userID int, departmentID, managerID *int) error
+	ImportOrgChart(assignments []models.OrgChartAssignment) (int, error)
+}
+
+type postgresDepartmentRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresDepartmentRepository creates a new instance of DepartmentRepository.
+func NewPostgresDepartmentRepository(db *sql.DB) DepartmentRepository {
+	return &postgresDepartmentRepository{db: db}
+}
+
+const departmentColumns = `id, name, parent_id, head_user_id, created_at`
+
+func scanDepartment(row interface{ Scan(...interface{}) error }, dept *models.Department) error {
+	return row.Scan(&dept.ID, &dept.Name, &dept.ParentID, &dept.HeadUserID, &dept.CreatedAt)
+}
+
+// GetAllDepartments returns every department ordered by name.
+func (r *postgresDepartmentRepository) GetAllDepartments() ([]models.Department, error) {
+	rows, err := r.db.Query(`SELECT ` + departmentColumns + ` FROM departments ORDER BY name, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var departments []models.Department
+	for rows.Next() {
+		var dept models.Department
+		if err := scanDepartment(rows, &dept); err != nil {
+			return nil, err
+		}
+		departments = append(departments, dept)
+	}
+	return departments, nil
+}
+
+// GetDepartmentByID returns a single department.
+func (r *postgresDepartmentRepository) GetDepartmentByID(id int) (*models.Department, error) {
+	dept := &models.Department{}
+	if err := scanDepartment(r.db.QueryRow(`SELECT `+departmentColumns+` FROM departments WHERE id = $1`, id), dept); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrDepartmentNotFound
+		}
+		return nil, err
+	}
+	return dept, nil
+}
+
+// CreateDepartment inserts a new department.
+func (r *postgresDepartmentRepository) CreateDepartment(dept *models.Department) error {
+	query := `
+		INSERT INTO departments (name, parent_id, head_user_id)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRow(query, dept.Name, dept.ParentID, dept.HeadUserID).Scan(&dept.ID, &dept.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrDepartmentExists
+	}
+	return err
+}
+
+// UpdateDepartment replaces a department's name, parent and head.
+func (r *postgresDepartmentRepository) UpdateDepartment(dept *models.Department) error {
+	query := `
+		UPDATE departments
+		SET name = $1, parent_id = $2, head_user_id = $3
+		WHERE id = $4
+	`
+	result, err := r.db.Exec(query, dept.Name, dept.ParentID, dept.HeadUserID, dept.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDepartmentExists
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrDepartmentNotFound
+	}
+
+	return nil
+}
+
+// DeleteDepartment removes a department. Members are left without a
+// department; departments that still have children cannot be deleted.
+func (r *postgresDepartmentRepository) DeleteDepartment(id int) error {
+	result, err := r.db.Exec(`DELETE FROM departments WHERE id = $1`, id)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrDepartmentNotEmpty
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrDepartmentNotFound
+	}
+
+	return nil
+}
+
+// SetUserOrganisation sets a user's department and line manager.
+func (r *postgresDepartmentRepository) SetUserOrganisation(userID int, departmentID, managerID *int) error {
+	result, err := r.db.Exec(`UPDATE users SET department_id = $1, manager_id = $2, version = version + 1 WHERE id = $3`, departmentID, managerID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// ImportOrgChart applies a set of org chart assignments in a single
+// transaction, creating any departments on the way. It returns the number of
+// departments created.
+func (r *postgresDepartmentRepository) ImportOrgChart(assignments []models.OrgChartAssignment) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	created := 0
+	resolved := make(map[string]int)
+	for _, a := range assignments {
+		var departmentID *int
+		if len(a.DepartmentPath) > 0 {
+			id, n, err := ensureDepartmentPath(tx, a.DepartmentPath, resolved)
+			if err != nil {
+				return 0, err
+			}
+			created += n
+			departmentID = &id
+		}
+
+		if _, err := tx.Exec(`UPDATE users SET department_id = $1, manager_id = $2, version = version + 1 WHERE id = $3`, departmentID, a.ManagerID, a.UserID); err != nil {
+			return 0, err
+		}
+
+		if a.DepartmentHead && departmentID != nil {
+			if _, err := tx.Exec(`UPDATE departments SET head_user_id = $1 WHERE id = $2`, a.UserID, *departmentID); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+// ensureDepartmentPath walks a department path from the root, creating any
+// missing departments. Resolved paths are cached in resolved. It returns the
+// ID of the last department and how many were created.
+func ensureDepartmentPath(tx *sql.Tx, path []string, resolved map[string]int) (int, int, error) {
+	created := 0
+	var parentID *int
+	key := ""
+	for _, name := range path {
+		key += "/" + name
+		if id, ok := resolved[key]; ok {
+			parentID = &id
+			continue
+		}
+
+		var id int
+		err := tx.QueryRow(`SELECT id FROM departments WHERE parent_id IS NOT DISTINCT FROM $1 AND name = $2`, parentID, name).Scan(&id)
+		if err == sql.ErrNoRows {
+			err = tx.QueryRow(`INSERT INTO departments (name, parent_id) VALUES ($1, $2) RETURNING id`, name, parentID).Scan(&id)
+			created++
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+
+		resolved[key] = id
+		parentID = &id
+	}
+	return *parentID, created, nil
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique constraint violation.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
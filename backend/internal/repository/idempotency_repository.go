@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"procurement-system/internal/models"
+	"time"
+)
+
+// IdempotencyRepository defines the interface for remembered idempotent requests.
+type IdempotencyRepository interface {
+	Reserve(record *models.IdempotencyRecord, abandonedBefore time.Time) (*models.IdempotencyRecord, error)
+	Complete(userID int, key string, statusCode int, header http.Header, body []byte) error
+	Release(userID int, key string) error
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+type postgresIdempotencyRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresIdempotencyRepository creates a new instance of IdempotencyRepository.
+func NewPostgresIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return &postgresIdempotencyRepository{db: db}
+}
+
+// Reserve claims a key for a new request. It returns nil if the key was free,
+// and otherwise the record already holding it. A key is free again once its
+// record has expired, or if the request holding it started before
+// abandonedBefore and never finished.
+func (r *postgresIdempotencyRepository) Reserve(record *models.IdempotencyRecord, abandonedBefore time.Time) (*models.IdempotencyRecord, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2
+		  AND (expires_at <= $3 OR (status_code IS NULL AND created_at < $4))
+	`, record.UserID, record.Key, record.CreatedAt, abandonedBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO idempotency_keys (user_id, idempotency_key, fingerprint, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, idempotency_key) DO NOTHING
+	`, record.UserID, record.Key, record.Fingerprint, record.CreatedAt, record.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if inserted == 1 {
+		return nil, tx.Commit()
+	}
+
+	existing := &models.IdempotencyRecord{}
+	var statusCode sql.NullInt64
+	var header []byte
+	err = tx.QueryRow(`
+		SELECT user_id, idempotency_key, fingerprint, status_code, response_headers, response_body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2
+	`, record.UserID, record.Key).Scan(
+		&existing.UserID, &existing.Key, &existing.Fingerprint, &statusCode, &header, &existing.Body, &existing.CreatedAt, &existing.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	existing.StatusCode = int(statusCode.Int64)
+	if err := json.Unmarshal(header, &existing.Header); err != nil {
+		return nil, err
+	}
+	return existing, tx.Commit()
+}
+
+// Complete stores the response to a reserved request.
+func (r *postgresIdempotencyRepository) Complete(userID int, key string, statusCode int, header http.Header, body []byte) error {
+	encoded, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		UPDATE idempotency_keys
+		SET status_code = $1, response_headers = $2, response_body = $3
+		WHERE user_id = $4 AND idempotency_key = $5
+	`, statusCode, encoded, body, userID, key)
+	return err
+}
+
+// Release frees a reserved key, so the request can be retried from scratch.
+func (r *postgresIdempotencyRepository) Release(userID int, key string) error {
+	_, err := r.db.Exec(`DELETE FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2`, userID, key)
+	return err
+}
+
+// DeleteExpired removes every record that expired by now.
+func (r *postgresIdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM idempotency_keys WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"procurement-system/internal/models"
+)
+
+var (
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrInvitationUsed     = errors.New("invitation has already been used or revoked")
+)
+
+// InvitationRepository defines the interface for invitation database operations.
+type InvitationRepository interface {
+	CreateInvitation(inv *models.Invitation) error
+	GetAllInvitations() ([]models.Invitation, error)
+	GetInvitationByTokenHash(tokenHash string) (*models.Invitation, error)
+	RevokeInvitation(id int) error
+	AcceptInvitation(id int, user *models.User) error
+}
+
+type postgresInvitationRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresInvitationRepository creates a new instance of InvitationRepository.
+func NewPostgresInvitationRepository(db *sql.DB) InvitationRepository {
+	return &postgresInvitationRepository{db: db}
+}
+
+const invitationColumns = `id, email, role, token_hash, invited_by, expires_at, accepted_at, accepted_user_id, revoked_at, created_at`
+
+func scanInvitation(row interface{ Scan(...interface{}) error }, inv *models.Invitation) error {
+	return row.Scan(
+		&inv.ID, &inv.Email, &inv.Role, &inv.TokenHash, &inv.InvitedBy, &inv.ExpiresAt,
+		&inv.AcceptedAt, &inv.AcceptedUserID, &inv.RevokedAt, &inv.CreatedAt,
+	)
+}
+
+// CreateInvitation stores a new invitation.
+func (r *postgresInvitationRepository) CreateInvitation(inv *models.Invitation) error {
+	query := `
+		INSERT INTO invitations (email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRow(query, inv.Email, inv.Role, inv.TokenHash, inv.InvitedBy, inv.ExpiresAt).Scan(&inv.ID, &inv.CreatedAt)
+	if isForeignKeyViolation(err) {
+		return ErrRoleNotFound
+	}
+	return err
+}
+
+// GetAllInvitations returns all invitations, newest first.
+func (r *postgresInvitationRepository) GetAllInvitations() ([]models.Invitation, error) {
+	rows, err := r.db.Query(`SELECT ` + invitationColumns + ` FROM invitations ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []models.Invitation
+	for rows.Next() {
+		var inv models.Invitation
+		if err := scanInvitation(rows, &inv); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, nil
+}
+
+// GetInvitationByTokenHash looks up an invitation by the hash of its token.
+func (r *postgresInvitationRepository) GetInvitationByTokenHash(tokenHash string) (*models.Invitation, error) {
+	inv := &models.Invitation{}
+	err := scanInvitation(r.db.QueryRow(`SELECT `+invitationColumns+` FROM invitations WHERE token_hash = $1`, tokenHash), inv)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	return inv, nil
+}
+
+// RevokeInvitation marks an unused invitation as revoked.
+func (r *postgresInvitationRepository) RevokeInvitation(id int) error {
+	query := `UPDATE invitations SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		var exists bool
+		if err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM invitations WHERE id = $1)`, id).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrInvitationNotFound
+		}
+		return ErrInvitationUsed
+	}
+
+	return nil
+}
+
+// AcceptInvitation creates the invited user and marks the invitation as used
+// in a single transaction, so an invitation can never produce two accounts.
+func (r *postgresInvitationRepository) AcceptInvitation(id int, user *models.User) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email=$1)", user.Email).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrEmailExists
+	}
+
+	query := `
+		INSERT INTO users (name, email, hashed_password, role, status)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, version
+	`
+	if err := tx.QueryRow(query, user.Name, user.Email, user.HashedPassword, user.Role, user.Status).Scan(&user.ID, &user.Version); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE invitations SET accepted_at = CURRENT_TIMESTAMP, accepted_user_id = $1
+		WHERE id = $2 AND accepted_at IS NULL AND revoked_at IS NULL
+	`, user.ID, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrInvitationUsed
+	}
+
+	return tx.Commit()
+}
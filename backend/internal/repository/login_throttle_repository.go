@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"database/sql"
+	"procurement-system/internal/models"
+	"time"
+)
+
+var (
+	ErrLoginThrottleNotFound = sql.ErrNoRows
+)
+
+// LoginThrottleRepository defines the interface for login throttle database operations.
+type LoginThrottleRepository interface {
+	GetThrottle(scope, key string) (*models.LoginThrottle, error)
+	RecordFailure(scope, key string, resetBefore time.Time) (*models.LoginThrottle, error)
+	SetLockedUntil(id int, lockedUntil time.Time) error
+	ResetThrottle(scope, key string) error
+	GetActiveLocks() ([]models.LoginThrottle, error)
+	DeleteThrottle(id int) error
+}
+
+type postgresLoginThrottleRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresLoginThrottleRepository creates a new instance of LoginThrottleRepository.
+func NewPostgresLoginThrottleRepository(db *sql.DB) LoginThrottleRepository {
+	return &postgresLoginThrottleRepository{db: db}
+}
+
+// GetThrottle returns the throttle row for a scope/key pair, or ErrLoginThrottleNotFound.
+func (r *postgresLoginThrottleRepository) GetThrottle(scope, key string) (*models.LoginThrottle, error) {
+	t := &models.LoginThrottle{}
+	query := `
+		SELECT id, scope, key, failure_count, last_failure_at, locked_until, created_at
+		FROM login_throttles
+		WHERE scope = $1 AND key = $2
+	`
+	err := r.db.QueryRow(query, scope, key).Scan(
+		&t.ID, &t.Scope, &t.Key, &t.FailureCount, &t.LastFailureAt, &t.LockedUntil, &t.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// RecordFailure atomically increments the failure counter for a scope/key pair.
+// If the previous failure happened before resetBefore, the counter restarts at 1.
+func (r *postgresLoginThrottleRepository) RecordFailure(scope, key string, resetBefore time.Time) (*models.LoginThrottle, error) {
+	t := &models.LoginThrottle{}
+	query := `
+		INSERT INTO login_throttles (scope, key, failure_count, last_failure_at)
+		VALUES ($1, $2, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (scope, key) DO UPDATE
+		SET failure_count = CASE
+				WHEN login_throttles.last_failure_at < $3 THEN 1
+				ELSE login_throttles.failure_count + 1
+			END,
+			last_failure_at = CURRENT_TIMESTAMP
+		RETURNING id, scope, key, failure_count, last_failure_at, locked_until, created_at
+	`
+	err := r.db.QueryRow(query, scope, key, resetBefore).Scan(
+		&t.ID, &t.Scope, &t.Key, &t.FailureCount, &t.LastFailureAt, &t.LockedUntil, &t.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// SetLockedUntil blocks further attempts for the throttle until the given time.
+func (r *postgresLoginThrottleRepository) SetLockedUntil(id int, lockedUntil time.Time) error {
+	query := `UPDATE login_throttles SET locked_until = $1 WHERE id = $2`
+	result, err := r.db.Exec(query, lockedUntil, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrLoginThrottleNotFound
+	}
+
+	return nil
+}
+
+// ResetThrottle clears any failures recorded for a scope/key pair.
+func (r *postgresLoginThrottleRepository) ResetThrottle(scope, key string) error {
+	_, err := r.db.Exec(`DELETE FROM login_throttles WHERE scope = $1 AND key = $2`, scope, key)
+	return err
+}
+
+// GetActiveLocks returns all throttles that are currently blocking logins.
+func (r *postgresLoginThrottleRepository) GetActiveLocks() ([]models.LoginThrottle, error) {
+	query := `
+		SELECT id, scope, key, failure_count, last_failure_at, locked_until, created_at
+		FROM login_throttles
+		WHERE locked_until > CURRENT_TIMESTAMP
+		ORDER BY locked_until DESC
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var throttles []models.LoginThrottle
+	for rows.Next() {
+		var t models.LoginThrottle
+		if err := rows.Scan(
+			&t.ID, &t.Scope, &t.Key, &t.FailureCount, &t.LastFailureAt, &t.LockedUntil, &t.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		throttles = append(throttles, t)
+	}
+	return throttles, nil
+}
+
+// DeleteThrottle removes a throttle by ID, clearing any lock it holds.
+func (r *postgresLoginThrottleRepository) DeleteThrottle(id int) error {
+	result, err := r.db.Exec(`DELETE FROM login_throttles WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrLoginThrottleNotFound
+	}
+
+	return nil
+}
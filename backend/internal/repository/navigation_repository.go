@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"procurement-system/internal/models"
+)
+
+var (
+	ErrMenuItemNotFound = errors.New("menu item not found")
+)
+
+// NavigationRepository defines the interface for menu item database operations.
+type NavigationRepository interface {
+	GetAllMenuItems() ([]models.MenuItem, error)
+	GetMenuItemByID(id int) (*models.MenuItem, error)
+	CreateMenuItem(item *models.MenuItem) error
+	UpdateMenuItem(item *models.MenuItem) error
+	DeleteMenuItem(id int) error
+}
+
+type postgresNavigationRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresNavigationRepository creates a new instance of NavigationRepository.
+func NewPostgresNavigationRepository(db *sql.DB) NavigationRepository {
+	return &postgresNavigationRepository{db: db}
+}
+
+const menuItemColumns = `id, parent_id, title, path, icon, permission, badge, sort_order, show_in_menu`
+
+func scanMenuItem(row interface{ Scan(...interface{}) error }, item *models.MenuItem) error {
+	return row.Scan(&item.ID, &item.ParentID, &item.Title, &item.Path, &item.Icon, &item.Permission, &item.Badge, &item.SortOrder, &item.ShowInMenu)
+}
+
+// GetAllMenuItems returns every menu item in display order.
+func (r *postgresNavigationRepository) GetAllMenuItems() ([]models.MenuItem, error) {
+	rows, err := r.db.Query(`SELECT ` + menuItemColumns + ` FROM menu_items ORDER BY sort_order, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.MenuItem
+	for rows.Next() {
+		var item models.MenuItem
+		if err := scanMenuItem(rows, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// GetMenuItemByID returns a single menu item.
+func (r *postgresNavigationRepository) GetMenuItemByID(id int) (*models.MenuItem, error) {
+	item := &models.MenuItem{}
+	if err := scanMenuItem(r.db.QueryRow(`SELECT `+menuItemColumns+` FROM menu_items WHERE id = $1`, id), item); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMenuItemNotFound
+		}
+		return nil, err
+	}
+	return item, nil
+}
+
+// CreateMenuItem inserts a new menu item.
+func (r *postgresNavigationRepository) CreateMenuItem(item *models.MenuItem) error {
+	query := `
+		INSERT INTO menu_items (parent_id, title, path, icon, permission, badge, sort_order, show_in_menu)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+	return r.db.QueryRow(query, item.ParentID, item.Title, item.Path, item.Icon, item.Permission, item.Badge, item.SortOrder, item.ShowInMenu).Scan(&item.ID)
+}
+
+// UpdateMenuItem replaces a menu item's fields.
+func (r *postgresNavigationRepository) UpdateMenuItem(item *models.MenuItem) error {
+	query := `
+		UPDATE menu_items
+		SET parent_id = $1, title = $2, path = $3, icon = $4, permission = $5, badge = $6, sort_order = $7, show_in_menu = $8
+		WHERE id = $9
+	`
+	result, err := r.db.Exec(query, item.ParentID, item.Title, item.Path, item.Icon, item.Permission, item.Badge, item.SortOrder, item.ShowInMenu, item.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrMenuItemNotFound
+	}
+
+	return nil
+}
+
+// DeleteMenuItem removes a menu item and, through the foreign key, its children.
+func (r *postgresNavigationRepository) DeleteMenuItem(id int) error {
+	result, err := r.db.Exec(`DELETE FROM menu_items WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrMenuItemNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"procurement-system/internal/models"
+)
+
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+)
+
+// NotificationRepository defines the interface for notification database operations.
+type NotificationRepository interface {
+	CreateNotification(n *models.Notification) error
+	GetNotifications(userID int, unreadOnly bool, limit int) ([]models.Notification, error)
+	CountUnread(userID int) (int, error)
+	MarkRead(userID int, id int) error
+	MarkAllRead(userID int) error
+}
+
+type postgresNotificationRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresNotificationRepository creates a new instance of NotificationRepository.
+func NewPostgresNotificationRepository(db *sql.DB) NotificationRepository {
+	return &postgresNotificationRepository{db: db}
+}
+
+// CreateNotification stores a notification for a user.
+func (r *postgresNotificationRepository) CreateNotification(n *models.Notification) error {
+	query := `
+		INSERT INTO notifications (user_id, kind, message, entity_type, entity_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(query, n.UserID, n.Kind, n.Message, n.EntityType, n.EntityID).Scan(&n.ID, &n.CreatedAt)
+}
+
+// GetNotifications returns a user's most recent notifications, newest first.
+func (r *postgresNotificationRepository) GetNotifications(userID int, unreadOnly bool, limit int) ([]models.Notification, error) {
+	query := `
+		SELECT id, user_id, kind, message, entity_type, entity_id, read_at, created_at
+		FROM notifications
+		WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3
+	`
+	rows, err := r.db.Query(query, userID, unreadOnly, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var n models.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Kind, &n.Message, &n.EntityType, &n.EntityID, &n.ReadAt, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// CountUnread counts a user's unread notifications.
+func (r *postgresNotificationRepository) CountUnread(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
+
+// MarkRead marks one of the user's notifications as read.
+func (r *postgresNotificationRepository) MarkRead(userID int, id int) error {
+	result, err := r.db.Exec(`UPDATE notifications SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP) WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotificationNotFound
+	}
+
+	return nil
+}
+
+// MarkAllRead marks every unread notification of the user as read.
+func (r *postgresNotificationRepository) MarkAllRead(userID int) error {
+	_, err := r.db.Exec(`UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND read_at IS NULL`, userID)
+	return err
+}
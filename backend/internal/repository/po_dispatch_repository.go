@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"database/sql"
+	"procurement-system/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PODispatchRepository stores the log of purchase orders emailed to vendors.
+type PODispatchRepository interface {
+	CreateDispatch(d *models.PODispatch) error
+	UpdateDispatch(d *models.PODispatch) error
+	ClaimDueDispatches(now time.Time, lease time.Duration, limit int) ([]models.PODispatch, error)
+	GetDispatchesByPurchaseOrder(poID int) ([]models.PODispatch, error)
+	CountFailedPurchaseOrders() (int, error)
+}
+
+type postgresPODispatchRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresPODispatchRepository(db *sql.DB) PODispatchRepository {
+	return &postgresPODispatchRepository{db: db}
+}
+
+const poDispatchColumns = `id, purchase_order_id, revision, to_address, cc_addresses, subject, body, status, attempts, last_error, next_attempt_at, sent_at, requested_by, created_at`
+
+func scanPODispatch(row rowScanner) (*models.PODispatch, error) {
+	d := &models.PODispatch{}
+	err := row.Scan(
+		&d.ID, &d.PurchaseOrderID, &d.Revision, &d.To, pq.Array(&d.Cc), &d.Subject, &d.Body, &d.Status,
+		&d.Attempts, &d.LastError, &d.NextAttemptAt, &d.SentAt, &d.RequestedBy, &d.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if d.Cc == nil {
+		d.Cc = []string{}
+	}
+	return d, nil
+}
+
+// CreateDispatch stores a new dispatch before its first attempt.
+func (r *postgresPODispatchRepository) CreateDispatch(d *models.PODispatch) error {
+	if d.Cc == nil {
+		d.Cc = []string{}
+	}
+	return r.db.QueryRow(`
+		INSERT INTO purchase_order_dispatches (purchase_order_id, revision, to_address, cc_addresses, subject, body, status, next_attempt_at, requested_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`, d.PurchaseOrderID, d.Revision, d.To, pq.Array(d.Cc), d.Subject, d.Body, d.Status, d.NextAttemptAt, d.RequestedBy,
+	).Scan(&d.ID, &d.CreatedAt)
+}
+
+// UpdateDispatch records the outcome of an attempt.
+func (r *postgresPODispatchRepository) UpdateDispatch(d *models.PODispatch) error {
+	_, err := r.db.Exec(`
+		UPDATE purchase_order_dispatches
+		SET status = $1, attempts = $2, last_error = $3, next_attempt_at = $4, sent_at = $5
+		WHERE id = $6
+	`, d.Status, d.Attempts, d.LastError, d.NextAttemptAt, d.SentAt, d.ID)
+	return err
+}
+
+// ClaimDueDispatches returns up to limit Pending dispatches due by now, oldest
+// first, and pushes their next attempt lease into the future so that no other
+// worker picks them up while they are being sent.
+func (r *postgresPODispatchRepository) ClaimDueDispatches(now time.Time, lease time.Duration, limit int) ([]models.PODispatch, error) {
+	rows, err := r.db.Query(`
+		UPDATE purchase_order_dispatches
+		SET next_attempt_at = $1
+		WHERE id IN (
+			SELECT id FROM purchase_order_dispatches
+			WHERE status = $2 AND next_attempt_at <= $3
+			ORDER BY next_attempt_at ASC, id ASC
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+poDispatchColumns,
+		now.Add(lease), models.DispatchStatusPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanPODispatches(rows)
+}
+
+// GetDispatchesByPurchaseOrder returns a purchase order's dispatches, newest
+// first.
+func (r *postgresPODispatchRepository) GetDispatchesByPurchaseOrder(poID int) ([]models.PODispatch, error) {
+	rows, err := r.db.Query(`
+		SELECT `+poDispatchColumns+`
+		FROM purchase_order_dispatches
+		WHERE purchase_order_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, poID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanPODispatches(rows)
+}
+
+// CountFailedPurchaseOrders counts the purchase orders whose latest dispatch
+// gave up, so a later successful resend clears the order.
+func (r *postgresPODispatchRepository) CountFailedPurchaseOrders() (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*)
+		FROM (
+			SELECT DISTINCT ON (purchase_order_id) status
+			FROM purchase_order_dispatches
+			ORDER BY purchase_order_id, created_at DESC, id DESC
+		) latest
+		WHERE status = $1
+	`, models.DispatchStatusFailed).Scan(&count)
+	return count, err
+}
+
+func scanPODispatches(rows *sql.Rows) ([]models.PODispatch, error) {
+	dispatches := []models.PODispatch{}
+	for rows.Next() {
+		d, err := scanPODispatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		dispatches = append(dispatches, *d)
+	}
+	return dispatches, rows.Err()
+}
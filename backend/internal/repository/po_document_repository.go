@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"procurement-system/internal/models"
+)
+
+var ErrPODocumentNotFound = errors.New("purchase order document not found")
+
+// PODocumentRepository stores the archive of purchase order PDFs. Documents
+// are only ever added.
+type PODocumentRepository interface {
+	CreateDocument(doc *models.PODocument) error
+	GetDocuments(poID int) ([]models.PODocument, error)
+	GetLatestDocument(poID int, revision *int) (*models.PODocument, error)
+	GetDocumentBySHA256(sha256 string) (*models.PODocument, error)
+}
+
+type postgresPODocumentRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresPODocumentRepository(db *sql.DB) PODocumentRepository {
+	return &postgresPODocumentRepository{db: db}
+}
+
+const poDocumentColumns = `id, purchase_order_id, revision, reason, size_bytes, sha256, storage_key, created_by, created_at`
+
+func scanPODocument(row rowScanner) (*models.PODocument, error) {
+	d := &models.PODocument{}
+	err := row.Scan(&d.ID, &d.PurchaseOrderID, &d.Revision, &d.Reason, &d.SizeBytes, &d.SHA256, &d.StorageKey, &d.CreatedBy, &d.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrPODocumentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// CreateDocument stores a newly archived document and records its digest on
+// the purchase order. The digest is not a change to the order, so the
+// order's version stays as it is.
+func (r *postgresPODocumentRepository) CreateDocument(doc *models.PODocument) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO purchase_order_documents (purchase_order_id, revision, reason, size_bytes, sha256, storage_key, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, doc.PurchaseOrderID, doc.Revision, doc.Reason, doc.SizeBytes, doc.SHA256, doc.StorageKey, doc.CreatedBy,
+	).Scan(&doc.ID, &doc.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`UPDATE purchase_orders SET document_sha256 = $1 WHERE id = $2`, doc.SHA256, doc.PurchaseOrderID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrPurchaseOrderNotFound
+	}
+
+	return tx.Commit()
+}
+
+// GetDocuments returns a purchase order's archived documents, newest first.
+func (r *postgresPODocumentRepository) GetDocuments(poID int) ([]models.PODocument, error) {
+	rows, err := r.db.Query(`
+		SELECT `+poDocumentColumns+`
+		FROM purchase_order_documents
+		WHERE purchase_order_id = $1
+		ORDER BY id DESC
+	`, poID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	documents := []models.PODocument{}
+	for rows.Next() {
+		d, err := scanPODocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, *d)
+	}
+	return documents, rows.Err()
+}
+
+// GetLatestDocument returns the last document archived for a purchase order,
+// or for one of its revisions if revision is set.
+func (r *postgresPODocumentRepository) GetLatestDocument(poID int, revision *int) (*models.PODocument, error) {
+	return scanPODocument(r.db.QueryRow(`
+		SELECT `+poDocumentColumns+`
+		FROM purchase_order_documents
+		WHERE purchase_order_id = $1 AND ($2::INTEGER IS NULL OR revision = $2)
+		ORDER BY id DESC
+		LIMIT 1
+	`, poID, revision))
+}
+
+// GetDocumentBySHA256 returns the first document archived with the digest.
+func (r *postgresPODocumentRepository) GetDocumentBySHA256(sha256 string) (*models.PODocument, error) {
+	return scanPODocument(r.db.QueryRow(`
+		SELECT `+poDocumentColumns+`
+		FROM purchase_order_documents
+		WHERE sha256 = $1
+		ORDER BY id ASC
+		LIMIT 1
+	`, sha256))
+}
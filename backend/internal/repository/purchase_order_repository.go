@@ -2,21 +2,39 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"procurement-system/internal/models"
+	"strings"
 	"time"
 )
 
 var (
-	ErrPurchaseOrderNotFound = sql.ErrNoRows
+	ErrPurchaseOrderNotFound     = sql.ErrNoRows
+	ErrRequisitionAlreadyOrdered = errors.New("requisition is already on a purchase order")
+	ErrPurchaseOrderReceived     = errors.New("goods have been received against this purchase order; close it out instead")
+	ErrBlanketNotFound           = errors.New("blanket purchase order not found")
+	ErrBlanketCeilingExceeded    = errors.New("call-offs would exceed the blanket purchase order's ceiling")
 )
 
+// rowsQuerier is satisfied by both *sql.DB and *sql.Tx.
+type rowsQuerier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
 type PurchaseOrderRepository interface {
-	CreatePurchaseOrder(po *models.PurchaseOrder) error
+	CreatePurchaseOrder(po *models.PurchaseOrder, actorID *int) error
 	GetPurchaseOrderByID(id int) (*models.PurchaseOrder, error)
 	GetAllPurchaseOrders() ([]models.PurchaseOrder, error)
 	GetNextPONumber() (string, error)
 	GetPDFData(poID int) (*models.PDFData, error)
+	GetBuyQueue() ([]models.Requisition, error)
+	CancelPurchaseOrder(id int, change models.StatusChange, reasonCode string, note string) error
+	ClosePurchaseOrder(id int, change models.StatusChange, reasonCode string, note string) error
+	GetBlanketConsumption() ([]models.BlanketConsumption, error)
+	GetBlanketConsumptionByID(blanketID int) (*models.BlanketConsumption, error)
 }
 
 type postgresPurchaseOrderRepository struct {
@@ -27,28 +45,114 @@ func NewPostgresPurchaseOrderRepository(db *sql.DB) PurchaseOrderRepository {
 	return &postgresPurchaseOrderRepository{db: db}
 }
 
-func (r *postgresPurchaseOrderRepository) CreatePurchaseOrder(po *models.PurchaseOrder) error {
+// CreatePurchaseOrder stores a purchase order with its lines, or a blanket
+// order with its price list, and records its initial status. It fails with
+// ErrRequisitionAlreadyOrdered if a line's requisition is already on another
+// order, and with ErrBlanketCeilingExceeded if a call-off would take its
+// blanket over the ceiling.
+func (r *postgresPurchaseOrderRepository) CreatePurchaseOrder(po *models.PurchaseOrder, actorID *int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertPurchaseOrder(tx, po, actorID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertPurchaseOrder does the work of CreatePurchaseOrder inside tx.
+func insertPurchaseOrder(tx *sql.Tx, po *models.PurchaseOrder, actorID *int) error {
 	query := `
-		INSERT INTO purchase_orders (po_number, requisition_id, vendor_id, order_date)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at
+		INSERT INTO purchase_orders (po_number, requisition_id, vendor_id, order_date, status, currency, ship_to, total_amount, committed_amount, tax_label, tax_rate, rounding_increment, po_type, blanket_id, valid_from, valid_to, ceiling_amount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING id, committed_amount, created_at, version
 	`
-	err := r.db.QueryRow(
+	err := tx.QueryRow(
 		query,
-		po.PONumber, po.RequisitionID, po.VendorID, po.OrderDate,
-	).Scan(&po.ID, &po.CreatedAt)
-	return err
+		po.PONumber, po.RequisitionID, po.VendorID, po.OrderDate, po.Status, po.Currency, po.ShipTo, po.TotalAmount,
+		po.TaxLabel, po.TaxRate, po.RoundingStep, po.POType, po.BlanketID, po.ValidFrom, po.ValidTo, po.CeilingAmount,
+	).Scan(&po.ID, &po.CommittedAmount, &po.CreatedAt, &po.Version)
+	if err != nil {
+		return err
+	}
+
+	for i := range po.PriceList {
+		item := &po.PriceList[i]
+		item.BlanketID = po.ID
+		err := tx.QueryRow(`
+			INSERT INTO blanket_price_items (blanket_id, line_no, description, unit_price)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id
+		`, po.ID, item.LineNo, item.Description, item.UnitPrice).Scan(&item.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range po.Lines {
+		line := &po.Lines[i]
+		line.PurchaseOrderID = po.ID
+		err := tx.QueryRow(`
+			INSERT INTO purchase_order_lines (purchase_order_id, line_no, requisition_id, description, quantity, unit_price, total_price, delivery_date, price_item_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id
+		`, po.ID, line.LineNo, line.RequisitionID, line.Description, line.Quantity, line.UnitPrice, line.TotalPrice, line.DeliveryDate, line.PriceItemID).Scan(&line.ID)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrRequisitionAlreadyOrdered
+			}
+			return err
+		}
+	}
+
+	if po.BlanketID != nil {
+		if err := checkBlanketCeiling(tx, *po.BlanketID); err != nil {
+			return err
+		}
+	}
+
+	return insertTransition(tx, models.EntityPurchaseOrder, po.ID, nil, po.Status, actorID, "")
 }
 
-func (r *postgresPurchaseOrderRepository) GetPurchaseOrderByID(id int) (*models.PurchaseOrder, error) {
+// checkBlanketCeiling locks a blanket order and returns
+// ErrBlanketCeilingExceeded if its call-offs together commit more than its
+// ceiling. It is called after a call-off's commitment has been written, so
+// the lock serialises call-offs against the same blanket.
+func checkBlanketCeiling(tx *sql.Tx, blanketID int) error {
+	var ceiling float64
+	err := tx.QueryRow(`
+		SELECT ceiling_amount FROM purchase_orders
+		WHERE id = $1 AND po_type = $2
+		FOR UPDATE
+	`, blanketID, models.POTypeBlanket).Scan(&ceiling)
+	if err == sql.ErrNoRows {
+		return ErrBlanketNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var consumed float64
+	err = tx.QueryRow(`SELECT COALESCE(SUM(committed_amount), 0) FROM purchase_orders WHERE blanket_id = $1`, blanketID).Scan(&consumed)
+	if err != nil {
+		return err
+	}
+	if consumed > ceiling+0.005 {
+		return ErrBlanketCeilingExceeded
+	}
+	return nil
+}
+
+const purchaseOrderColumns = `id, po_number, requisition_id, vendor_id, order_date, status, currency, ship_to, total_amount, tax_label, tax_rate, rounding_increment, revision, committed_amount, closure_code, closure_note, po_type, blanket_id, valid_from, valid_to, ceiling_amount, document_sha256, created_at, version`
+
+func scanPurchaseOrder(row rowScanner) (*models.PurchaseOrder, error) {
 	po := &models.PurchaseOrder{}
-	query := `
-		SELECT po.id, po.po_number, po.requisition_id, po.vendor_id, po.order_date, po.created_at
-		FROM purchase_orders po
-		WHERE po.id = $1
-	`
-	err := r.db.QueryRow(query, id).Scan(
-		&po.ID, &po.PONumber, &po.RequisitionID, &po.VendorID, &po.OrderDate, &po.CreatedAt,
+	err := row.Scan(
+		&po.ID, &po.PONumber, &po.RequisitionID, &po.VendorID, &po.OrderDate, &po.Status, &po.Currency, &po.ShipTo, &po.TotalAmount, &po.TaxLabel, &po.TaxRate, &po.RoundingStep, &po.Revision, &po.CommittedAmount, &po.ClosureCode, &po.ClosureNote,
+		&po.POType, &po.BlanketID, &po.ValidFrom, &po.ValidTo, &po.CeilingAmount, &po.DocumentSHA256, &po.CreatedAt, &po.Version,
 	)
 	if err != nil {
 		return nil, err
@@ -56,9 +160,31 @@ func (r *postgresPurchaseOrderRepository) GetPurchaseOrderByID(id int) (*models.
 	return po, nil
 }
 
+// GetPurchaseOrderByID returns a purchase order with its lines, or a blanket
+// order with its price list.
+func (r *postgresPurchaseOrderRepository) GetPurchaseOrderByID(id int) (*models.PurchaseOrder, error) {
+	po, err := scanPurchaseOrder(r.db.QueryRow(`SELECT `+purchaseOrderColumns+` FROM purchase_orders WHERE id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+
+	po.Lines, err = getPurchaseOrderLines(r.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if po.POType == models.POTypeBlanket {
+		po.PriceList, err = getBlanketPriceItems(r.db, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return po, nil
+}
+
+// GetAllPurchaseOrders returns every purchase order, newest first, without lines.
 func (r *postgresPurchaseOrderRepository) GetAllPurchaseOrders() ([]models.PurchaseOrder, error) {
 	query := `
-		SELECT id, po_number, requisition_id, vendor_id, order_date, created_at
+		SELECT ` + purchaseOrderColumns + `
 		FROM purchase_orders
 		ORDER BY order_date DESC
 	`
@@ -70,15 +196,227 @@ func (r *postgresPurchaseOrderRepository) GetAllPurchaseOrders() ([]models.Purch
 
 	var purchaseOrders []models.PurchaseOrder
 	for rows.Next() {
-		var po models.PurchaseOrder
+		po, err := scanPurchaseOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		purchaseOrders = append(purchaseOrders, *po)
+	}
+	return purchaseOrders, nil
+}
+
+// GetBuyQueue returns the approved requisitions not yet on a purchase order,
+// ordered so that those that can be consolidated are next to each other.
+func (r *postgresPurchaseOrderRepository) GetBuyQueue() ([]models.Requisition, error) {
+	query := `
+		SELECT id, requester_id, vendor_id, item_description, quantity, estimated_price, total_price, justification, currency, ship_to, status, approver_id, round, submitted_at, created_at, version
+		FROM requisitions r
+		WHERE r.status = 'Approved'
+		  AND NOT EXISTS (SELECT 1 FROM purchase_order_lines l WHERE l.requisition_id = r.id)
+		ORDER BY r.vendor_id, r.currency, r.ship_to, r.id
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRequisitions(rows)
+}
+
+// CancelPurchaseOrder cancels an order before anything has been received,
+// releasing its whole commitment. It returns ErrPurchaseOrderReceived if goods
+// have been received, and ErrStatusConflict if the order is no longer in
+// change.From. Pending change orders are rejected.
+func (r *postgresPurchaseOrderRepository) CancelPurchaseOrder(id int, change models.StatusChange, reasonCode string, note string) error {
+	return r.endPurchaseOrder(id, change, reasonCode, note, func(tx *sql.Tx) error {
+		var received bool
+		err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM purchase_order_lines WHERE purchase_order_id = $1 AND received_quantity > 0)`, id).Scan(&received)
+		if err != nil {
+			return err
+		}
+		if received {
+			return ErrPurchaseOrderReceived
+		}
+		return nil
+	})
+}
+
+// ClosePurchaseOrder closes an order out short: whatever has not been
+// received on each line is given up, and the commitment drops to the value
+// received. It returns ErrStatusConflict if the order is no longer in
+// change.From. Pending change orders are rejected.
+func (r *postgresPurchaseOrderRepository) ClosePurchaseOrder(id int, change models.StatusChange, reasonCode string, note string) error {
+	return r.endPurchaseOrder(id, change, reasonCode, note, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE purchase_order_lines
+			SET short_closed_quantity = quantity - received_quantity
+			WHERE purchase_order_id = $1
+		`, id)
+		return err
+	})
+}
+
+// endPurchaseOrder makes the status change shared by cancelling and closing
+// an order, running prepare inside the same transaction first. The order's
+// commitment is recalculated from what its lines have received, with the
+// order's tax and rounding.
+func (r *postgresPurchaseOrderRepository) endPurchaseOrder(id int, change models.StatusChange, reasonCode string, note string, prepare func(tx *sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := changeStatus(tx, "purchase_orders", models.EntityPurchaseOrder, id, change, ErrPurchaseOrderNotFound); err != nil {
+		return err
+	}
+	if err := prepare(tx); err != nil {
+		return err
+	}
+
+	var taxRate, roundingStep float64
+	if err := tx.QueryRow(`SELECT tax_rate, rounding_increment FROM purchase_orders WHERE id = $1`, id).Scan(&taxRate, &roundingStep); err != nil {
+		return err
+	}
+	lines, err := getPurchaseOrderLines(tx, id)
+	if err != nil {
+		return err
+	}
+	received := models.LinesSubtotal(lines, func(line models.PurchaseOrderLine) int { return line.ReceivedQuantity })
+	committed := models.ComputeOrderTotals(received, taxRate, roundingStep).Grand
+
+	_, err = tx.Exec(`
+		UPDATE purchase_orders
+		SET closure_code = $1, closure_note = $2, committed_amount = $3
+		WHERE id = $4
+	`, reasonCode, note, committed, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE purchase_order_change_orders
+		SET status = $1, decided_by = $2, decided_at = CURRENT_TIMESTAMP, decision_reason = $3
+		WHERE purchase_order_id = $4 AND status = $5
+	`, models.ChangeOrderStatusRejected, change.ActorID, "Purchase order "+strings.ToLower(change.To), id, models.ChangeOrderStatusPending)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func getPurchaseOrderLines(q rowsQuerier, poID int) ([]models.PurchaseOrderLine, error) {
+	rows, err := q.Query(`
+		SELECT id, purchase_order_id, line_no, requisition_id, description, quantity, unit_price, total_price, delivery_date, received_quantity, short_closed_quantity, price_item_id
+		FROM purchase_order_lines
+		WHERE purchase_order_id = $1
+		ORDER BY line_no
+	`, poID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []models.PurchaseOrderLine{}
+	for rows.Next() {
+		var line models.PurchaseOrderLine
 		if err := rows.Scan(
-			&po.ID, &po.PONumber, &po.RequisitionID, &po.VendorID, &po.OrderDate, &po.CreatedAt,
+			&line.ID, &line.PurchaseOrderID, &line.LineNo, &line.RequisitionID, &line.Description, &line.Quantity, &line.UnitPrice, &line.TotalPrice, &line.DeliveryDate, &line.ReceivedQuantity, &line.ShortClosedQuantity, &line.PriceItemID,
 		); err != nil {
 			return nil, err
 		}
-		purchaseOrders = append(purchaseOrders, po)
+		lines = append(lines, line)
 	}
-	return purchaseOrders, nil
+	return lines, rows.Err()
+}
+
+func getBlanketPriceItems(q rowsQuerier, blanketID int) ([]models.BlanketPriceItem, error) {
+	rows, err := q.Query(`
+		SELECT id, blanket_id, line_no, description, unit_price
+		FROM blanket_price_items
+		WHERE blanket_id = $1
+		ORDER BY line_no
+	`, blanketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.BlanketPriceItem{}
+	for rows.Next() {
+		var item models.BlanketPriceItem
+		if err := rows.Scan(&item.ID, &item.BlanketID, &item.LineNo, &item.Description, &item.UnitPrice); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// blanketConsumptionQuery sums what each blanket order's call-offs commit.
+// Cancelled call-offs commit nothing and are not counted.
+const blanketConsumptionQuery = `
+	SELECT b.id, b.po_number, b.vendor_id, v.name, b.currency, b.status, b.valid_from, b.valid_to, b.ceiling_amount,
+		COALESCE(SUM(c.committed_amount), 0),
+		COUNT(c.id) FILTER (WHERE c.status <> 'Cancelled')
+	FROM purchase_orders b
+	JOIN vendors v ON v.id = b.vendor_id
+	LEFT JOIN purchase_orders c ON c.blanket_id = b.id
+	WHERE b.po_type = 'blanket'
+`
+
+func scanBlanketConsumption(row rowScanner) (*models.BlanketConsumption, error) {
+	c := &models.BlanketConsumption{}
+	err := row.Scan(
+		&c.BlanketID, &c.PONumber, &c.VendorID, &c.VendorName, &c.Currency, &c.Status, &c.ValidFrom, &c.ValidTo, &c.CeilingAmount,
+		&c.ConsumedAmount, &c.CallOffs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	c.RemainingAmount = c.CeilingAmount - c.ConsumedAmount
+	if c.CeilingAmount > 0 {
+		c.PercentUsed = math.Round(c.ConsumedAmount/c.CeilingAmount*10000) / 100
+	}
+	return c, nil
+}
+
+// GetBlanketConsumption reports the consumption of every blanket order,
+// those ending soonest first.
+func (r *postgresPurchaseOrderRepository) GetBlanketConsumption() ([]models.BlanketConsumption, error) {
+	rows, err := r.db.Query(blanketConsumptionQuery + `
+		GROUP BY b.id, v.name
+		ORDER BY b.valid_to ASC, b.id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := []models.BlanketConsumption{}
+	for rows.Next() {
+		c, err := scanBlanketConsumption(rows)
+		if err != nil {
+			return nil, err
+		}
+		report = append(report, *c)
+	}
+	return report, rows.Err()
+}
+
+// GetBlanketConsumptionByID reports the consumption of one blanket order. It
+// returns ErrBlanketNotFound if blanketID is not a blanket order.
+func (r *postgresPurchaseOrderRepository) GetBlanketConsumptionByID(blanketID int) (*models.BlanketConsumption, error) {
+	c, err := scanBlanketConsumption(r.db.QueryRow(blanketConsumptionQuery+`
+		AND b.id = $1
+		GROUP BY b.id, v.name
+	`, blanketID))
+	if err == sql.ErrNoRows {
+		return nil, ErrBlanketNotFound
+	}
+	return c, err
 }
 
 func (r *postgresPurchaseOrderRepository) GetNextPONumber() (string, error) {
@@ -94,40 +432,43 @@ func (r *postgresPurchaseOrderRepository) GetNextPONumber() (string, error) {
 
 func (r *postgresPurchaseOrderRepository) GetPDFData(poID int) (*models.PDFData, error) {
 	pdfData := &models.PDFData{}
-	item := models.PDFItem{}
 
 	query := `
 		SELECT
 			po.po_number,
 			po.order_date,
+			po.revision,
+			po.status,
+			po.currency,
+			po.tax_label,
+			po.tax_rate,
+			po.rounding_increment,
 			v.name,
 			v.address,
 			v.phone,
 			v.email,
-			r.item_description,
-			r.quantity,
-			r.estimated_price,
-			r.total_price
+			COALESCE(v.pdf_layout, '')
 		FROM purchase_orders po
 		JOIN vendors v ON po.vendor_id = v.id
-		JOIN requisitions r ON po.requisition_id = r.id
 		WHERE po.id = $1
 	`
 
 	var orderDate time.Time
-	var totalPrice float64
 
 	err := r.db.QueryRow(query, poID).Scan(
 		&pdfData.ReceiptNo,
 		&orderDate,
+		&pdfData.Revision,
+		&pdfData.Status,
+		&pdfData.Currency,
+		&pdfData.TaxLabel,
+		&pdfData.TaxRate,
+		&pdfData.RoundingStep,
 		&pdfData.CustomerName,
 		&pdfData.CustomerAddress,
 		&pdfData.CustomerPhone,
 		&pdfData.CustomerEmail,
-		&item.Desc,
-		&item.Qty,
-		&item.UPrice,
-		&totalPrice,
+		&pdfData.Layout,
 	)
 
 	if err != nil {
@@ -137,21 +478,41 @@ func (r *postgresPurchaseOrderRepository) GetPDFData(poID int) (*models.PDFData,
 		return nil, err
 	}
 
-	pdfData.ReceiptDate = orderDate.Format("02/01/2006")
-	pdfData.Items = []models.PDFItem{item}
-
-	// These fields can be populated from a config or company profile in a real app
-	pdfData.CompanyName = "Procurement Corp"
-	pdfData.CompanyAddress = "123 Tech Avenue, Silicon Valley, CA 94043"
-	pdfData.CompanyEmail = "contact@procurementcorp.com"
-	pdfData.CompanyPhones = "1-800-555-PROC"
-	pdfData.RegNo = "202301000001"
-	pdfData.TinNo = "TIN12345678"
-	pdfData.MsicCode = "62010"
-	pdfData.BankName = "Global Tech Bank"
-	pdfData.BankAccount = "123-456-7890"
-	pdfData.PaymentMethod = "NET 30"
+	lines, err := getPurchaseOrderLines(r.db, poID)
+	if err != nil {
+		return nil, err
+	}
 
+	// Lines changed by the change order that created this revision are highlighted.
+	changed := make(map[int]bool)
+	if pdfData.Revision > 0 {
+		var changes []byte
+		err := r.db.QueryRow(
+			`SELECT changes FROM purchase_order_change_orders WHERE purchase_order_id = $1 AND revision = $2`,
+			poID, pdfData.Revision,
+		).Scan(&changes)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if err == nil {
+			var lineChanges []models.LineChange
+			if err := json.Unmarshal(changes, &lineChanges); err != nil {
+				return nil, err
+			}
+			for _, change := range lineChanges {
+				changed[change.LineNo] = true
+			}
+		}
+	}
+
+	pdfData.ReceiptDate = orderDate.Format("02/01/2006")
+	pdfData.Items = make([]models.PDFItem, len(lines))
+	for i, line := range lines {
+		pdfData.Items[i] = models.PDFItem{Desc: line.Description, Qty: float64(line.Quantity), UPrice: line.UnitPrice, Changed: changed[line.LineNo]}
+		if line.DeliveryDate != nil {
+			pdfData.Items[i].DeliveryDate = line.DeliveryDate.Format("02/01/2006")
+		}
+	}
 
 	return pdfData, nil
 }
@@ -2,11 +2,13 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"procurement-system/internal/models"
 )
 
 var (
 	ErrRequisitionNotFound = sql.ErrNoRows
+	ErrRequisitionOrdered  = errors.New("requisition is on a purchase order; cancel the order before deleting it")
 )
 
 type RequisitionRepository interface {
@@ -15,9 +17,14 @@ type RequisitionRepository interface {
 	GetPendingRequisitions() ([]models.Requisition, error)
 	GetAllRequisitions() ([]models.Requisition, error)
 	GetRequisitionByID(id int) (*models.Requisition, error)
-	UpdateRequisitionStatus(id int, status string) error
 	UpdateRequisition(req *models.Requisition) error
-	DeleteRequisition(id int) error
+	DeleteRequisition(id int, version int) error
+	CountRequisitions(requesterID *int, status string) (int, error)
+	UpdateRequisitionApprover(id int, approverID *int, version int) error
+	SubmitRequisition(id int, actorID int) (*models.Requisition, error)
+	CreateRequisitionRound(round *models.RequisitionRound) error
+	CloseRequisitionRound(change models.StatusChange, round *models.RequisitionRound, po *models.PurchaseOrder) error
+	GetRequisitionRounds(requisitionID int) ([]models.RequisitionRound, error)
 }
 
 type postgresRequisitionRepository struct {
@@ -28,26 +35,41 @@ func NewPostgresRequisitionRepository(db *sql.DB) RequisitionRepository {
 	return &postgresRequisitionRepository{db: db}
 }
 
+// CreateRequisition stores a requisition and records its initial status.
 func (r *postgresRequisitionRepository) CreateRequisition(req *models.Requisition) (*models.Requisition, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO requisitions (requester_id, vendor_id, item_description, quantity, estimated_price, total_price, justification, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, created_at
+		INSERT INTO requisitions (requester_id, vendor_id, item_description, quantity, estimated_price, total_price, justification, currency, ship_to, status, round, submitted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, version
 	`
-	err := r.db.QueryRow(
+	err = tx.QueryRow(
 		query,
 		req.RequesterID, req.VendorID, req.ItemDescription, req.Quantity,
-		req.EstimatedPrice, req.TotalPrice, req.Justification, req.Status,
-	).Scan(&req.ID, &req.CreatedAt)
+		req.EstimatedPrice, req.TotalPrice, req.Justification, req.Currency, req.ShipTo, req.Status,
+		req.Round, req.SubmittedAt,
+	).Scan(&req.ID, &req.CreatedAt, &req.Version)
 	if err != nil {
 		return nil, err
 	}
+
+	if err := insertTransition(tx, models.EntityRequisition, req.ID, nil, req.Status, &req.RequesterID, ""); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return req, nil
 }
 
 func (r *postgresRequisitionRepository) GetRequisitionsByRequesterID(requesterID int) ([]models.Requisition, error) {
 	query := `
-		SELECT id, requester_id, vendor_id, item_description, quantity, estimated_price, total_price, justification, status, created_at
+		SELECT id, requester_id, vendor_id, item_description, quantity, estimated_price, total_price, justification, currency, ship_to, status, approver_id, round, submitted_at, created_at, version
 		FROM requisitions
 		WHERE requester_id = $1
 		ORDER BY created_at DESC
@@ -63,7 +85,7 @@ func (r *postgresRequisitionRepository) GetRequisitionsByRequesterID(requesterID
 
 func (r *postgresRequisitionRepository) GetPendingRequisitions() ([]models.Requisition, error) {
 	query := `
-		SELECT id, requester_id, vendor_id, item_description, quantity, estimated_price, total_price, justification, status, created_at
+		SELECT id, requester_id, vendor_id, item_description, quantity, estimated_price, total_price, justification, currency, ship_to, status, approver_id, round, submitted_at, created_at, version
 		FROM requisitions
 		WHERE status = 'Pending'
 		ORDER BY created_at ASC
@@ -79,7 +101,7 @@ func (r *postgresRequisitionRepository) GetPendingRequisitions() ([]models.Requi
 
 func (r *postgresRequisitionRepository) GetAllRequisitions() ([]models.Requisition, error) {
 	query := `
-		SELECT id, requester_id, vendor_id, item_description, quantity, estimated_price, total_price, justification, status, created_at
+		SELECT id, requester_id, vendor_id, item_description, quantity, estimated_price, total_price, justification, currency, ship_to, status, approver_id, round, submitted_at, created_at, version
 		FROM requisitions
 		ORDER BY created_at DESC
 	`
@@ -95,13 +117,13 @@ func (r *postgresRequisitionRepository) GetAllRequisitions() ([]models.Requisiti
 func (r *postgresRequisitionRepository) GetRequisitionByID(id int) (*models.Requisition, error) {
 	req := &models.Requisition{}
 	query := `
-		SELECT id, requester_id, vendor_id, item_description, quantity, estimated_price, total_price, justification, status, created_at
+		SELECT id, requester_id, vendor_id, item_description, quantity, estimated_price, total_price, justification, currency, ship_to, status, approver_id, round, submitted_at, created_at, version
 		FROM requisitions
 		WHERE id = $1
 	`
 	err := r.db.QueryRow(query, id).Scan(
 		&req.ID, &req.RequesterID, &req.VendorID, &req.ItemDescription, &req.Quantity,
-		&req.EstimatedPrice, &req.TotalPrice, &req.Justification, &req.Status, &req.CreatedAt,
+		&req.EstimatedPrice, &req.TotalPrice, &req.Justification, &req.Currency, &req.ShipTo, &req.Status, &req.ApproverID, &req.Round, &req.SubmittedAt, &req.CreatedAt, &req.Version,
 	)
 	if err != nil {
 		return nil, err
@@ -109,9 +131,61 @@ func (r *postgresRequisitionRepository) GetRequisitionByID(id int) (*models.Requ
 	return req, nil
 }
 
-func (r *postgresRequisitionRepository) UpdateRequisitionStatus(id int, status string) error {
-	query := `UPDATE requisitions SET status = $1 WHERE id = $2`
-	result, err := r.db.Exec(query, status, id)
+// UpdateRequisition saves a requisition's details if it is still at
+// req.Version, and moves req.Version on to the new version.
+func (r *postgresRequisitionRepository) UpdateRequisition(req *models.Requisition) error {
+	query := `
+		UPDATE requisitions
+		SET vendor_id = $1, item_description = $2, quantity = $3, estimated_price = $4, total_price = $5, justification = $6,
+		    currency = $7, ship_to = $8, version = version + 1
+		WHERE id = $9 AND version = $10
+		RETURNING version
+	`
+	err := r.db.QueryRow(
+		query,
+		req.VendorID, req.ItemDescription, req.Quantity,
+		req.EstimatedPrice, req.TotalPrice, req.Justification,
+		req.Currency, req.ShipTo,
+		req.ID, req.Version,
+	).Scan(&req.Version)
+	if err == sql.ErrNoRows {
+		return versionMismatch(r.db, "requisitions", req.ID, ErrRequisitionNotFound)
+	}
+	return err
+}
+
+// DeleteRequisition deletes a requisition if it is still at version. It
+// returns ErrRequisitionOrdered if the requisition is on a purchase order that
+// has not been cancelled.
+func (r *postgresRequisitionRepository) DeleteRequisition(id int, version int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Locking the requisition keeps it from being put on an order meanwhile.
+	if _, err := tx.Exec(`SELECT 1 FROM requisitions WHERE id = $1 FOR UPDATE`, id); err != nil {
+		return err
+	}
+	var ordered bool
+	err = tx.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM purchase_order_lines l
+			JOIN purchase_orders po ON po.id = l.purchase_order_id
+			WHERE l.requisition_id = $1 AND po.status <> $2
+		)
+	`, id, models.PurchaseOrderStatusCancelled).Scan(&ordered)
+	if err != nil {
+		return err
+	}
+	if ordered {
+		return ErrRequisitionOrdered
+	}
+
+	query := "DELETE FROM requisitions WHERE id = $1 AND version = $2"
+	result, err := tx.Exec(query, id, version)
 	if err != nil {
 		return err
 	}
@@ -122,25 +196,31 @@ func (r *postgresRequisitionRepository) UpdateRequisitionStatus(id int, status s
 	}
 
 	if rowsAffected == 0 {
-		return ErrRequisitionNotFound
+		return versionMismatch(tx, "requisitions", id, ErrRequisitionNotFound)
 	}
 
-	return nil
+	return tx.Commit()
 }
 
-
-func (r *postgresRequisitionRepository) UpdateRequisition(req *models.Requisition) error {
+// CountRequisitions counts requisitions with the given status, optionally
+// limited to one requester.
+func (r *postgresRequisitionRepository) CountRequisitions(requesterID *int, status string) (int, error) {
 	query := `
-		UPDATE requisitions
-		SET vendor_id = $1, item_description = $2, quantity = $3, estimated_price = $4, total_price = $5, justification = $6
-		WHERE id = $7
+		SELECT COUNT(*)
+		FROM requisitions
+		WHERE status = $1 AND ($2::int IS NULL OR requester_id = $2)
 	`
-	result, err := r.db.Exec(
-		query,
-		req.VendorID, req.ItemDescription, req.Quantity,
-		req.EstimatedPrice, req.TotalPrice, req.Justification,
-		req.ID,
-	)
+	var count int
+	err := r.db.QueryRow(query, status, requesterID).Scan(&count)
+	return count, err
+}
+
+// UpdateRequisitionApprover assigns a pending requisition to an approver, or
+// clears the assignment when approverID is nil. The requisition must still be
+// pending and at version.
+func (r *postgresRequisitionRepository) UpdateRequisitionApprover(id int, approverID *int, version int) error {
+	result, err := r.db.Exec(`UPDATE requisitions SET approver_id = $1, version = version + 1 WHERE id = $2 AND status = $3 AND version = $4`,
+		approverID, id, models.RequisitionStatusPending, version)
 	if err != nil {
 		return err
 	}
@@ -151,29 +231,126 @@ func (r *postgresRequisitionRepository) UpdateRequisition(req *models.Requisitio
 	}
 
 	if rowsAffected == 0 {
-		return ErrRequisitionNotFound
+		return statusMismatch(r.db, "requisitions", id, models.RequisitionStatusPending, ErrRequisitionNotFound)
 	}
 
 	return nil
 }
 
-func (r *postgresRequisitionRepository) DeleteRequisition(id int) error {
-	query := "DELETE FROM requisitions WHERE id = $1"
-	result, err := r.db.Exec(query, id)
+// SubmitRequisition sends a draft or returned requisition for approval,
+// starting a new round, and records the transition.
+func (r *postgresRequisitionRepository) SubmitRequisition(id int, actorID int) (*models.Requisition, error) {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	var from string
+	if err := tx.QueryRow(`SELECT status FROM requisitions WHERE id = $1 FOR UPDATE`, id).Scan(&from); err != nil {
+		return nil, err
+	}
+	if from != models.RequisitionStatusDraft && from != models.RequisitionStatusReturned {
+		return nil, ErrStatusConflict
+	}
+
+	req := &models.Requisition{}
+	query := `
+		UPDATE requisitions
+		SET status = 'Pending', round = round + 1, submitted_at = CURRENT_TIMESTAMP, version = version + 1
+		WHERE id = $1
+		RETURNING id, requester_id, vendor_id, item_description, quantity, estimated_price, total_price, justification, currency, ship_to, status, approver_id, round, submitted_at, created_at, version
+	`
+	err = tx.QueryRow(query, id).Scan(
+		&req.ID, &req.RequesterID, &req.VendorID, &req.ItemDescription, &req.Quantity,
+		&req.EstimatedPrice, &req.TotalPrice, &req.Justification, &req.Currency, &req.ShipTo, &req.Status, &req.ApproverID, &req.Round, &req.SubmittedAt, &req.CreatedAt, &req.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := insertTransition(tx, models.EntityRequisition, id, &from, req.Status, &actorID, ""); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// CreateRequisitionRound records how a review round ended.
+func (r *postgresRequisitionRepository) CreateRequisitionRound(round *models.RequisitionRound) error {
+	return insertRequisitionRound(r.db, round)
+}
+
+// CloseRequisitionRound moves a requisition out of review and records how
+// the round ended, and stores po if the decision issued one, all in one
+// transaction, so that a decision is never left without its round or its
+// order. It fails with ErrStatusConflict if the requisition is no longer in
+// change.From, with ErrVersionConflict if it has been changed since
+// change.Version, and as CreatePurchaseOrder does if po cannot be stored.
+func (r *postgresRequisitionRepository) CloseRequisitionRound(change models.StatusChange, round *models.RequisitionRound, po *models.PurchaseOrder) error {
+	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	if rowsAffected == 0 {
-		return ErrRequisitionNotFound
+	if err := changeStatus(tx, "requisitions", models.EntityRequisition, round.RequisitionID, change, ErrRequisitionNotFound); err != nil {
+		return err
 	}
+	if err := insertRequisitionRound(tx, round); err != nil {
+		return err
+	}
+	if po != nil {
+		if err := insertPurchaseOrder(tx, po, change.ActorID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
 
-	return nil
+// insertRequisitionRound appends a finished review round.
+func insertRequisitionRound(q rowQuerier, round *models.RequisitionRound) error {
+	query := `
+		INSERT INTO requisition_rounds (requisition_id, round, submitted_at, outcome, reason, decided_by, on_behalf_of_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, decided_at
+	`
+	return q.QueryRow(
+		query,
+		round.RequisitionID, round.Round, round.SubmittedAt, round.Outcome, round.Reason, round.DecidedBy, round.OnBehalfOfID,
+	).Scan(&round.ID, &round.DecidedAt)
+}
+
+// GetRequisitionRounds returns the finished review rounds of a requisition, oldest first.
+func (r *postgresRequisitionRepository) GetRequisitionRounds(requisitionID int) ([]models.RequisitionRound, error) {
+	query := `
+		SELECT rr.id, rr.requisition_id, rr.round, rr.submitted_at, rr.outcome, rr.reason,
+		       rr.decided_by, COALESCE(u.name, ''), rr.on_behalf_of_id, rr.decided_at
+		FROM requisition_rounds rr
+		LEFT JOIN users u ON u.id = rr.decided_by
+		WHERE rr.requisition_id = $1
+		ORDER BY rr.round ASC
+	`
+	rows, err := r.db.Query(query, requisitionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rounds := []models.RequisitionRound{}
+	for rows.Next() {
+		var rr models.RequisitionRound
+		if err := rows.Scan(
+			&rr.ID, &rr.RequisitionID, &rr.Round, &rr.SubmittedAt, &rr.Outcome, &rr.Reason,
+			&rr.DecidedBy, &rr.DecidedByName, &rr.OnBehalfOfID, &rr.DecidedAt,
+		); err != nil {
+			return nil, err
+		}
+		rounds = append(rounds, rr)
+	}
+	return rounds, rows.Err()
 }
 
 func scanRequisitions(rows *sql.Rows) ([]models.Requisition, error) {
@@ -182,7 +359,7 @@ func scanRequisitions(rows *sql.Rows) ([]models.Requisition, error) {
 		var req models.Requisition
 		if err := rows.Scan(
 			&req.ID, &req.RequesterID, &req.VendorID, &req.ItemDescription, &req.Quantity,
-			&req.EstimatedPrice, &req.TotalPrice, &req.Justification, &req.Status, &req.CreatedAt,
+			&req.EstimatedPrice, &req.TotalPrice, &req.Justification, &req.Currency, &req.ShipTo, &req.Status, &req.ApproverID, &req.Round, &req.SubmittedAt, &req.CreatedAt, &req.Version,
 		); err != nil {
 			return nil, err
 		}
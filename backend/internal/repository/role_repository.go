@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"procurement-system/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrRoleNotFound = errors.New("role not found")
+	ErrRoleExists   = errors.New("role already exists")
+	ErrRoleInUse    = errors.New("role is still assigned to users or invitations")
+)
+
+// RoleRepository defines the interface for role and permission database operations.
+type RoleRepository interface {
+	GetAllPermissions() ([]models.Permission, error)
+	GetAllRoles() ([]models.Role, error)
+	GetRoleByID(id int) (*models.Role, error)
+	CreateRole(role *models.Role) error
+	UpdateRole(role *models.Role) error
+	DeleteRole(id int) error
+	GetUserRoles(userID int) (*models.UserRoles, error)
+	SetUserAdditionalRoles(userID int, roleNames []string) error
+	GetUserPermissions(userID int) ([]string, error)
+}
+
+type postgresRoleRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRoleRepository creates a new instance of RoleRepository.
+func NewPostgresRoleRepository(db *sql.DB) RoleRepository {
+	return &postgresRoleRepository{db: db}
+}
+
+const roleSelect = `
+	SELECT r.id, r.name, r.description, r.is_system, r.created_at,
+		COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+	FROM roles r
+	LEFT JOIN role_permissions rp ON rp.role_id = r.id
+`
+
+func scanRole(row interface{ Scan(...interface{}) error }, role *models.Role) error {
+	return row.Scan(&role.ID, &role.Name, &role.Description, &role.IsSystem, &role.CreatedAt, pq.Array(&role.Permissions))
+}
+
+// GetAllPermissions returns the permission catalogue.
+func (r *postgresRoleRepository) GetAllPermissions() ([]models.Permission, error) {
+	rows, err := r.db.Query(`SELECT code, description FROM permissions ORDER BY code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []models.Permission
+	for rows.Next() {
+		var p models.Permission
+		if err := rows.Scan(&p.Code, &p.Description); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, nil
+}
+
+// GetAllRoles returns every role with its permissions.
+func (r *postgresRoleRepository) GetAllRoles() ([]models.Role, error) {
+	rows, err := r.db.Query(roleSelect + ` GROUP BY r.id ORDER BY r.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		var role models.Role
+		if err := scanRole(rows, &role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// GetRoleByID returns a single role with its permissions.
+func (r *postgresRoleRepository) GetRoleByID(id int) (*models.Role, error) {
+	role := &models.Role{}
+	if err := scanRole(r.db.QueryRow(roleSelect+` WHERE r.id = $1 GROUP BY r.id`, id), role); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+// CreateRole inserts a role and its permissions.
+func (r *postgresRoleRepository) CreateRole(role *models.Role) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM roles WHERE name = $1)`, role.Name).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrRoleExists
+	}
+
+	query := `
+		INSERT INTO roles (name, description)
+		VALUES ($1, $2)
+		RETURNING id, is_system, created_at
+	`
+	if err := tx.QueryRow(query, role.Name, role.Description).Scan(&role.ID, &role.IsSystem, &role.CreatedAt); err != nil {
+		return err
+	}
+
+	if err := insertRolePermissions(tx, role.ID, role.Permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateRole replaces a role's description and permissions.
+func (r *postgresRoleRepository) UpdateRole(role *models.Role) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE roles SET description = $1 WHERE id = $2`, role.Description, role.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRoleNotFound
+	}
+
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role_id = $1`, role.ID); err != nil {
+		return err
+	}
+	if err := insertRolePermissions(tx, role.ID, role.Permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteRole removes a role that is no longer assigned to anyone.
+func (r *postgresRoleRepository) DeleteRole(id int) error {
+	var inUse bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM user_roles WHERE role_id = $1)
+			OR EXISTS(SELECT 1 FROM users u JOIN roles r ON r.name = u.role WHERE r.id = $1)
+			OR EXISTS(SELECT 1 FROM invitations i JOIN roles r ON r.name = i.role WHERE r.id = $1)
+	`, id).Scan(&inUse)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrRoleInUse
+	}
+
+	result, err := r.db.Exec(`DELETE FROM roles WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRoleNotFound
+	}
+
+	return nil
+}
+
+// GetUserRoles returns the user's primary role and additional roles.
+func (r *postgresRoleRepository) GetUserRoles(userID int) (*models.UserRoles, error) {
+	roles := &models.UserRoles{UserID: userID}
+	query := `
+		SELECT u.role,
+			COALESCE(array_agg(r.name ORDER BY r.name) FILTER (WHERE r.name IS NOT NULL), '{}')
+		FROM users u
+		LEFT JOIN user_roles ur ON ur.user_id = u.id
+		LEFT JOIN roles r ON r.id = ur.role_id
+		WHERE u.id = $1
+		GROUP BY u.id
+	`
+	if err := r.db.QueryRow(query, userID).Scan(&roles.Role, pq.Array(&roles.AdditionalRoles)); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return roles, nil
+}
+
+// SetUserAdditionalRoles replaces the user's additional roles.
+func (r *postgresRoleRepository) SetUserAdditionalRoles(userID int, roleNames []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`, userID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	if _, err := tx.Exec(`DELETE FROM user_roles WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, name := range roleNames {
+		result, err := tx.Exec(`
+			INSERT INTO user_roles (user_id, role_id)
+			SELECT $1, id FROM roles WHERE name = $2
+			ON CONFLICT DO NOTHING
+		`, userID, name)
+		if err != nil {
+			return err
+		}
+		// A duplicate name in the list inserts nothing but is not an error,
+		// so only check that the role itself exists.
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			var found bool
+			if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM roles WHERE name = $1)`, name).Scan(&found); err != nil {
+				return err
+			}
+			if !found {
+				return ErrRoleNotFound
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUserPermissions returns the union of the permissions granted by the
+// user's primary role and additional roles.
+func (r *postgresRoleRepository) GetUserPermissions(userID int) ([]string, error) {
+	query := `
+		SELECT DISTINCT rp.permission
+		FROM role_permissions rp
+		JOIN roles r ON r.id = rp.role_id
+		WHERE r.name = (SELECT role FROM users WHERE id = $1)
+			OR r.id IN (SELECT role_id FROM user_roles WHERE user_id = $1)
+	`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, nil
+}
+
+func insertRolePermissions(tx *sql.Tx, roleID int, permissions []string) error {
+	for _, p := range permissions {
+		if _, err := tx.Exec(`INSERT INTO role_permissions (role_id, permission) VALUES ($1, $2) ON CONFLICT DO NOTHING`, roleID, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isForeignKeyViolation reports whether err is a PostgreSQL foreign key violation.
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"procurement-system/internal/models"
+)
+
+var (
+	ErrSoDRuleNotFound = errors.New("segregation of duties rule not found")
+	ErrSoDRuleExists   = errors.New("a segregation of duties rule with this code already exists")
+)
+
+// SoDRepository defines the interface for segregation of duties rule and violation database operations.
+type SoDRepository interface {
+	GetAllRules() ([]models.SoDRule, error)
+	GetEnabledRules() ([]models.SoDRule, error)
+	GetRuleByID(id int) (*models.SoDRule, error)
+	CreateRule(rule *models.SoDRule) error
+	UpdateRule(rule *models.SoDRule) error
+	DeleteRule(id int) error
+	CreateViolation(v *models.SoDViolation) error
+	GetViolations(limit int) ([]models.SoDViolation, error)
+}
+
+type postgresSoDRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresSoDRepository creates a new instance of SoDRepository.
+func NewPostgresSoDRepository(db *sql.DB) SoDRepository {
+	return &postgresSoDRepository{db: db}
+}
+
+const sodRuleColumns = `id, code, description, first_duty, second_duty, enabled, created_at`
+
+func scanSoDRule(row interface{ Scan(...interface{}) error }, rule *models.SoDRule) error {
+	return row.Scan(&rule.ID, &rule.Code, &rule.Description, &rule.FirstDuty, &rule.SecondDuty, &rule.Enabled, &rule.CreatedAt)
+}
+
+func (r *postgresSoDRepository) queryRules(query string, args ...interface{}) ([]models.SoDRule, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.SoDRule
+	for rows.Next() {
+		var rule models.SoDRule
+		if err := scanSoDRule(rows, &rule); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// GetAllRules returns every rule.
+func (r *postgresSoDRepository) GetAllRules() ([]models.SoDRule, error) {
+	return r.queryRules(`SELECT ` + sodRuleColumns + ` FROM sod_rules ORDER BY code`)
+}
+
+// GetEnabledRules returns the rules currently enforced.
+func (r *postgresSoDRepository) GetEnabledRules() ([]models.SoDRule, error) {
+	return r.queryRules(`SELECT ` + sodRuleColumns + ` FROM sod_rules WHERE enabled ORDER BY code`)
+}
+
+// GetRuleByID returns a single rule.
+func (r *postgresSoDRepository) GetRuleByID(id int) (*models.SoDRule, error) {
+	rule := &models.SoDRule{}
+	if err := scanSoDRule(r.db.QueryRow(`SELECT `+sodRuleColumns+` FROM sod_rules WHERE id = $1`, id), rule); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSoDRuleNotFound
+		}
+		return nil, err
+	}
+	return rule, nil
+}
+
+// CreateRule inserts a new rule.
+func (r *postgresSoDRepository) CreateRule(rule *models.SoDRule) error {
+	query := `
+		INSERT INTO sod_rules (code, description, first_duty, second_duty, enabled)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRow(query, rule.Code, rule.Description, rule.FirstDuty, rule.SecondDuty, rule.Enabled).Scan(&rule.ID, &rule.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrSoDRuleExists
+	}
+	return err
+}
+
+// UpdateRule replaces a rule's fields.
+func (r *postgresSoDRepository) UpdateRule(rule *models.SoDRule) error {
+	query := `
+		UPDATE sod_rules
+		SET code = $1, description = $2, first_duty = $3, second_duty = $4, enabled = $5
+		WHERE id = $6
+	`
+	result, err := r.db.Exec(query, rule.Code, rule.Description, rule.FirstDuty, rule.SecondDuty, rule.Enabled, rule.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrSoDRuleExists
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrSoDRuleNotFound
+	}
+
+	return nil
+}
+
+// DeleteRule removes a rule. Recorded violations keep its code.
+func (r *postgresSoDRepository) DeleteRule(id int) error {
+	result, err := r.db.Exec(`DELETE FROM sod_rules WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrSoDRuleNotFound
+	}
+
+	return nil
+}
+
+// CreateViolation records a blocked attempt.
+func (r *postgresSoDRepository) CreateViolation(v *models.SoDViolation) error {
+	query := `
+		INSERT INTO sod_violations (rule_id, rule_code, user_id, on_behalf_of_id, duty, entity_type, entity_id, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(query, v.RuleID, v.RuleCode, v.UserID, v.OnBehalfOfID, v.Duty, v.EntityType, v.EntityID, v.Details).Scan(&v.ID, &v.CreatedAt)
+}
+
+// GetViolations returns the most recent violations, newest first.
+func (r *postgresSoDRepository) GetViolations(limit int) ([]models.SoDViolation, error) {
+	query := `
+		SELECT id, rule_id, rule_code, user_id, on_behalf_of_id, duty, entity_type, entity_id, details, created_at
+		FROM sod_violations
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1
+	`
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var violations []models.SoDViolation
+	for rows.Next() {
+		var v models.SoDViolation
+		if err := rows.Scan(&v.ID, &v.RuleID, &v.RuleCode, &v.UserID, &v.OnBehalfOfID, &v.Duty, &v.EntityType, &v.EntityID, &v.Details, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		violations = append(violations, v)
+	}
+	return violations, nil
+}
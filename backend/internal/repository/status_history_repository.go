@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"procurement-system/internal/models"
+	"time"
+)
+
+var (
+	ErrStatusConflict = errors.New("status was changed by someone else")
+)
+
+// StatusHistoryRepository defines the interface for reading status
+// transitions. Transitions are written by the repositories that change
+// statuses, in the same transaction as the change.
+type StatusHistoryRepository interface {
+	GetTransitions(entityType string, entityID int) ([]models.StatusTransition, error)
+	GetPOIssuedAt(requisitionID int) (*time.Time, error)
+	GetCycleTimeSummary(from, to time.Time) (*models.CycleTimeSummary, error)
+}
+
+type postgresStatusHistoryRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresStatusHistoryRepository creates a new instance of StatusHistoryRepository.
+func NewPostgresStatusHistoryRepository(db *sql.DB) StatusHistoryRepository {
+	return &postgresStatusHistoryRepository{db: db}
+}
+
+// insertTransition appends a status transition inside tx.
+func insertTransition(tx *sql.Tx, entityType string, entityID int, from *string, to string, actorID *int, reason string) error {
+	query := `
+		INSERT INTO status_transitions (entity_type, entity_id, from_status, to_status, actor_id, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := tx.Exec(query, entityType, entityID, from, to, actorID, reason)
+	return err
+}
+
+// changeStatus moves a row of table from change.From to change.To, moving it
+// on to a new version, and records the transition, inside tx. It fails as
+// statusMismatch describes unless the row is still at change.Version.
+func changeStatus(tx *sql.Tx, table string, entityType string, id int, change models.StatusChange, notFound error) error {
+	result, err := tx.Exec(`UPDATE `+table+` SET status = $1, version = version + 1 WHERE id = $2 AND status = $3 AND version = $4`, change.To, id, change.From, change.Version)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return statusMismatch(tx, table, id, change.From, notFound)
+	}
+
+	return insertTransition(tx, entityType, id, &change.From, change.To, change.ActorID, change.Reason)
+}
+
+// statusMismatch explains why an UPDATE of a row of table guarded by status
+// and version matched nothing: notFound if the row does not exist,
+// ErrStatusConflict if it is no longer in status and ErrVersionConflict if it
+// is but has been changed since it was read.
+func statusMismatch(q rowQuerier, table string, id int, status string, notFound error) error {
+	var current string
+	err := q.QueryRow(`SELECT status FROM `+table+` WHERE id = $1`, id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return notFound
+	}
+	if err != nil {
+		return err
+	}
+	if current != status {
+		return ErrStatusConflict
+	}
+	return ErrVersionConflict
+}
+
+// GetTransitions returns an entity's status transitions, oldest first.
+func (r *postgresStatusHistoryRepository) GetTransitions(entityType string, entityID int) ([]models.StatusTransition, error) {
+	query := `
+		SELECT t.id, t.entity_type, t.entity_id, t.from_status, t.to_status, t.actor_id, COALESCE(u.name, ''), t.reason, t.created_at
+		FROM status_transitions t
+		LEFT JOIN users u ON u.id = t.actor_id
+		WHERE t.entity_type = $1 AND t.entity_id = $2
+		ORDER BY t.created_at ASC, t.id ASC
+	`
+	rows, err := r.db.Query(query, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transitions := []models.StatusTransition{}
+	for rows.Next() {
+		var t models.StatusTransition
+		if err := rows.Scan(
+			&t.ID, &t.EntityType, &t.EntityID, &t.FromStatus, &t.ToStatus, &t.ActorID, &t.ActorName, &t.Reason, &t.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, t)
+	}
+	return transitions, rows.Err()
+}
+
+// GetPOIssuedAt returns when the purchase order a requisition is on was
+// issued, or nil if it has none yet.
+func (r *postgresStatusHistoryRepository) GetPOIssuedAt(requisitionID int) (*time.Time, error) {
+	query := `
+		SELECT MIN(t.created_at)
+		FROM status_transitions t
+		JOIN purchase_order_lines l ON l.purchase_order_id = t.entity_id
+		WHERE t.entity_type = 'purchase_order' AND t.to_status = 'Issued' AND l.requisition_id = $1
+	`
+	var issuedAt sql.NullTime
+	if err := r.db.QueryRow(query, requisitionID).Scan(&issuedAt); err != nil {
+		return nil, err
+	}
+	if !issuedAt.Valid {
+		return nil, nil
+	}
+	return &issuedAt.Time, nil
+}
+
+// GetCycleTimeSummary summarises the cycle times of requisitions approved in
+// [from, to). Submit to approve is measured from the submission that was
+// approved; first submit to approve also counts time spent on returns.
+func (r *postgresStatusHistoryRepository) GetCycleTimeSummary(from, to time.Time) (*models.CycleTimeSummary, error) {
+	query := `
+		WITH approvals AS (
+			SELECT t.entity_id AS requisition_id, MIN(t.created_at) AS approved_at
+			FROM status_transitions t
+			WHERE t.entity_type = 'requisition' AND t.to_status = 'Approved'
+			GROUP BY t.entity_id
+			HAVING MIN(t.created_at) >= $1 AND MIN(t.created_at) < $2
+		), durations AS (
+			SELECT
+				EXTRACT(EPOCH FROM a.approved_at - (
+					SELECT MAX(s.created_at) FROM status_transitions s
+					WHERE s.entity_type = 'requisition' AND s.entity_id = a.requisition_id
+					  AND s.to_status = 'Pending' AND s.created_at <= a.approved_at)) AS submit_to_approve,
+				EXTRACT(EPOCH FROM a.approved_at - (
+					SELECT MIN(s.created_at) FROM status_transitions s
+					WHERE s.entity_type = 'requisition' AND s.entity_id = a.requisition_id
+					  AND s.to_status = 'Pending')) AS first_submit_to_approve,
+				EXTRACT(EPOCH FROM (
+					SELECT MIN(p.created_at) FROM status_transitions p
+					JOIN purchase_order_lines l ON l.purchase_order_id = p.entity_id
+					WHERE p.entity_type = 'purchase_order' AND p.to_status = 'Issued'
+					  AND l.requisition_id = a.requisition_id) - a.approved_at) AS approve_to_po_issued
+			FROM approvals a
+		)
+		SELECT
+			COUNT(submit_to_approve), AVG(submit_to_approve),
+			PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY submit_to_approve),
+			COUNT(first_submit_to_approve), AVG(first_submit_to_approve),
+			PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY first_submit_to_approve),
+			COUNT(approve_to_po_issued), AVG(approve_to_po_issued),
+			PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY approve_to_po_issued)
+		FROM durations
+	`
+	summary := &models.CycleTimeSummary{From: from, To: to}
+	err := r.db.QueryRow(query, from, to).Scan(
+		&summary.SubmitToApprove.Count, &summary.SubmitToApprove.AverageSeconds, &summary.SubmitToApprove.MedianSeconds,
+		&summary.FirstSubmitToApprove.Count, &summary.FirstSubmitToApprove.AverageSeconds, &summary.FirstSubmitToApprove.MedianSeconds,
+		&summary.ApproveToPOIssued.Count, &summary.ApproveToPOIssued.AverageSeconds, &summary.ApproveToPOIssued.MedianSeconds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
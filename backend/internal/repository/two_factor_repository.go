@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"procurement-system/internal/models"
+)
+
+var (
+	ErrTwoFactorNotFound    = errors.New("two-factor enrolment not found")
+	ErrRecoveryCodeConsumed = errors.New("recovery code already used")
+	ErrTOTPStepReplayed     = errors.New("TOTP code already used")
+)
+
+// TwoFactorRepository defines the interface for two-factor authentication database operations.
+type TwoFactorRepository interface {
+	GetByUserID(userID int) (*models.UserTwoFactor, error)
+	SaveSecret(userID int, secret string) error
+	Enable(userID int, step int64) error
+	UseStep(userID int, step int64) error
+	Delete(userID int) error
+	ReplaceRecoveryCodes(userID int, codeHashes []string) error
+	GetUnusedRecoveryCodes(userID int) ([]models.RecoveryCode, error)
+	UseRecoveryCode(id int) error
+}
+
+type postgresTwoFactorRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresTwoFactorRepository creates a new instance of TwoFactorRepository.
+func NewPostgresTwoFactorRepository(db *sql.DB) TwoFactorRepository {
+	return &postgresTwoFactorRepository{db: db}
+}
+
+// GetByUserID returns the user's enrolment, or ErrTwoFactorNotFound if they never started one.
+func (r *postgresTwoFactorRepository) GetByUserID(userID int) (*models.UserTwoFactor, error) {
+	tf := &models.UserTwoFactor{}
+	query := `
+		SELECT user_id, secret, enabled, last_used_step, enabled_at, created_at
+		FROM user_two_factor
+		WHERE user_id = $1
+	`
+	err := r.db.QueryRow(query, userID).Scan(
+		&tf.UserID, &tf.Secret, &tf.Enabled, &tf.LastUsedStep, &tf.EnabledAt, &tf.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTwoFactorNotFound
+		}
+		return nil, err
+	}
+	return tf, nil
+}
+
+// SaveSecret stores a new, not yet confirmed, secret for the user.
+func (r *postgresTwoFactorRepository) SaveSecret(userID int, secret string) error {
+	query := `
+		INSERT INTO user_two_factor (user_id, secret, enabled, last_used_step, enabled_at)
+		VALUES ($1, $2, FALSE, 0, NULL)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, enabled = FALSE, last_used_step = 0, enabled_at = NULL, created_at = CURRENT_TIMESTAMP
+	`
+	_, err := r.db.Exec(query, userID, secret)
+	return err
+}
+
+// Enable marks the enrolment as confirmed, consuming the step used to confirm it.
+func (r *postgresTwoFactorRepository) Enable(userID int, step int64) error {
+	query := `
+		UPDATE user_two_factor
+		SET enabled = TRUE, enabled_at = CURRENT_TIMESTAMP, last_used_step = $2
+		WHERE user_id = $1
+	`
+	result, err := r.db.Exec(query, userID, step)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrTwoFactorNotFound
+	}
+
+	return nil
+}
+
+// UseStep records a TOTP step as used. It fails with ErrTOTPStepReplayed if
+// that step (or a later one) has already been accepted.
+func (r *postgresTwoFactorRepository) UseStep(userID int, step int64) error {
+	query := `UPDATE user_two_factor SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`
+	result, err := r.db.Exec(query, userID, step)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrTOTPStepReplayed
+	}
+
+	return nil
+}
+
+// Delete removes the user's enrolment and all of their recovery codes.
+func (r *postgresTwoFactorRepository) Delete(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	result, err := tx.Exec(`DELETE FROM user_two_factor WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrTwoFactorNotFound
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes discards any existing recovery codes and stores the new hashes.
+func (r *postgresTwoFactorRepository) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(`INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUnusedRecoveryCodes returns the recovery codes the user can still redeem.
+func (r *postgresTwoFactorRepository) GetUnusedRecoveryCodes(userID int) ([]models.RecoveryCode, error) {
+	query := `
+		SELECT id, user_id, code_hash, used_at
+		FROM user_recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []models.RecoveryCode
+	for rows.Next() {
+		var c models.RecoveryCode
+		if err := rows.Scan(&c.ID, &c.UserID, &c.CodeHash, &c.UsedAt); err != nil {
+			return nil, err
+		}
+		codes = append(codes, c)
+	}
+	return codes, nil
+}
+
+// UseRecoveryCode marks a recovery code as used. It fails with
+// ErrRecoveryCodeConsumed if the code was redeemed concurrently.
+func (r *postgresTwoFactorRepository) UseRecoveryCode(id int) error {
+	query := `UPDATE user_recovery_codes SET used_at = CURRENT_TIMESTAMP WHERE id = $1 AND used_at IS NULL`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecoveryCodeConsumed
+	}
+
+	return nil
+}
@@ -9,8 +9,9 @@ import (
 )
 
 var (
-	ErrUserNotFound = errors.New("user not found")
-	ErrEmailExists  = errors.New("email already exists")
+	ErrUserNotFound  = errors.New("user not found")
+	ErrEmailExists   = errors.New("email already exists")
+	ErrInvalidVendor = errors.New("vendor does not exist")
 )
 
 type UserRepository interface {
@@ -19,8 +20,12 @@ type UserRepository interface {
 	GetUserByID(id int) (*models.User, error)
 	GetAllUsers() ([]models.User, error)
 	UpdateUser(user *models.User) error
-	DeleteUser(id int) error
+	DeleteUser(id int, version int) error
 	UpdatePassword(userID int, newHashedPassword string) error
+	GetUsersByStatus(status string) ([]models.User, error)
+	UpdateUserStatus(id int, status string) error
+	SetUserVendor(id int, vendorID *int) error
+	GetUsersByVendor(vendorID int) ([]models.User, error)
 }
 
 type postgresUserRepository struct {
@@ -42,13 +47,20 @@ func (r *postgresUserRepository) CreateUser(user *models.User) (*models.User, er
 		return nil, ErrEmailExists
 	}
 
+	if user.Status == "" {
+		user.Status = models.UserStatusActive
+	}
+
 	query := `
-		INSERT INTO users (name, email, hashed_password, role)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id
+		INSERT INTO users (name, email, hashed_password, role, status)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, version
 	`
-	err = r.db.QueryRow(query, user.Name, user.Email, user.HashedPassword, user.Role).Scan(&user.ID)
+	err = r.db.QueryRow(query, user.Name, user.Email, user.HashedPassword, user.Role, user.Status).Scan(&user.ID, &user.Version)
 	if err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, ErrRoleNotFound
+		}
 		return nil, err
 	}
 
@@ -77,11 +89,11 @@ func (r *postgresUserRepository) UpdatePassword(userID int, newHashedPassword st
 func (r *postgresUserRepository) GetUserByID(id int) (*models.User, error) {
 	user := &models.User{}
 	query := `
-		SELECT id, name, email, hashed_password, role
+		SELECT id, name, email, hashed_password, role, status, department_id, manager_id, vendor_id, version
 		FROM users
 		WHERE id = $1
 	`
-	err := r.db.QueryRow(query, id).Scan(&user.ID, &user.Name, &user.Email, &user.HashedPassword, &user.Role)
+	err := r.db.QueryRow(query, id).Scan(&user.ID, &user.Name, &user.Email, &user.HashedPassword, &user.Role, &user.Status, &user.DepartmentID, &user.ManagerID, &user.VendorID, &user.Version)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrUserNotFound
@@ -93,7 +105,7 @@ func (r *postgresUserRepository) GetUserByID(id int) (*models.User, error) {
 
 func (r *postgresUserRepository) GetAllUsers() ([]models.User, error) {
 	query := `
-		SELECT id, name, email, role
+		SELECT id, name, email, role, status, department_id, manager_id, vendor_id, version
 		FROM users
 		ORDER BY name ASC
 	`
@@ -103,26 +115,93 @@ func (r *postgresUserRepository) GetAllUsers() ([]models.User, error) {
 	}
 	defer rows.Close()
 
-	var users []models.User
-	for rows.Next() {
-		var user models.User
-		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Role); err != nil {
-			return nil, err
-		}
-		users = append(users, user)
+	return scanUsers(rows)
+}
+
+// GetUsersByStatus returns users with the given account status, oldest first.
+func (r *postgresUserRepository) GetUsersByStatus(status string) ([]models.User, error) {
+	query := `
+		SELECT id, name, email, role, status, department_id, manager_id, vendor_id, version
+		FROM users
+		WHERE status = $1
+		ORDER BY id ASC
+	`
+	rows, err := r.db.Query(query, status)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return users, nil
+	return scanUsers(rows)
+}
+
+// GetUsersByVendor returns the active users linked to a vendor.
+func (r *postgresUserRepository) GetUsersByVendor(vendorID int) ([]models.User, error) {
+	query := `
+		SELECT id, name, email, role, status, department_id, manager_id, vendor_id, version
+		FROM users
+		WHERE vendor_id = $1 AND status = $2
+		ORDER BY id ASC
+	`
+	rows, err := r.db.Query(query, vendorID, models.UserStatusActive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanUsers(rows)
+}
+
+// UpdateUserStatus sets a user's account status.
+func (r *postgresUserRepository) UpdateUserStatus(id int, status string) error {
+	query := `UPDATE users SET status = $1, version = version + 1 WHERE id = $2`
+	result, err := r.db.Exec(query, status, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
 }
 
+// UpdateUser saves a user's details if the user is still at user.Version, and
+// moves user.Version on to the new version.
 func (r *postgresUserRepository) UpdateUser(user *models.User) error {
 	query := `
 		UPDATE users
-		SET name = $1, email = $2, role = $3
-		WHERE id = $4
+		SET name = $1, email = $2, role = $3, version = version + 1
+		WHERE id = $4 AND version = $5
+		RETURNING version
 	`
-	result, err := r.db.Exec(query, user.Name, user.Email, user.Role, user.ID)
+	err := r.db.QueryRow(query, user.Name, user.Email, user.Role, user.ID, user.Version).Scan(&user.Version)
 	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrRoleNotFound
+		}
+		if err == sql.ErrNoRows {
+			return versionMismatch(r.db, "users", user.ID, ErrUserNotFound)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// SetUserVendor links a user to a vendor, or removes the link when vendorID is nil.
+func (r *postgresUserRepository) SetUserVendor(id int, vendorID *int) error {
+	result, err := r.db.Exec(`UPDATE users SET vendor_id = $1, version = version + 1 WHERE id = $2`, vendorID, id)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrInvalidVendor
+		}
 		return err
 	}
 
@@ -138,9 +217,10 @@ func (r *postgresUserRepository) UpdateUser(user *models.User) error {
 	return nil
 }
 
-func (r *postgresUserRepository) DeleteUser(id int) error {
-	query := "DELETE FROM users WHERE id = $1"
-	result, err := r.db.Exec(query, id)
+// DeleteUser deletes a user if they are still at version.
+func (r *postgresUserRepository) DeleteUser(id int, version int) error {
+	query := "DELETE FROM users WHERE id = $1 AND version = $2"
+	result, err := r.db.Exec(query, id, version)
 	if err != nil {
 		return err
 	}
@@ -151,7 +231,7 @@ func (r *postgresUserRepository) DeleteUser(id int) error {
 	}
 
 	if rowsAffected == 0 {
-		return ErrUserNotFound
+		return versionMismatch(r.db, "users", id, ErrUserNotFound)
 	}
 
 	return nil
@@ -160,11 +240,11 @@ func (r *postgresUserRepository) DeleteUser(id int) error {
 func (r *postgresUserRepository) GetUserByEmail(email string) (*models.User, error) {
 	user := &models.User{}
 	query := `
-		SELECT id, name, email, hashed_password, role
+		SELECT id, name, email, hashed_password, role, status, department_id, manager_id, vendor_id, version
 		FROM users
 		WHERE email = $1
 	`
-	err := r.db.QueryRow(query, email).Scan(&user.ID, &user.Name, &user.Email, &user.HashedPassword, &user.Role)
+	err := r.db.QueryRow(query, email).Scan(&user.ID, &user.Name, &user.Email, &user.HashedPassword, &user.Role, &user.Status, &user.DepartmentID, &user.ManagerID, &user.VendorID, &user.Version)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrUserNotFound
@@ -173,3 +253,16 @@ func (r *postgresUserRepository) GetUserByEmail(email string) (*models.User, err
 	}
 	return user, nil
 }
+
+// scanUsers scans rows of (id, name, email, role, status, department_id, manager_id, vendor_id, version) into users.
+func scanUsers(rows *sql.Rows) ([]models.User, error) {
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.Status, &user.DepartmentID, &user.ManagerID, &user.VendorID, &user.Version); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
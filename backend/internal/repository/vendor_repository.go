@@ -14,7 +14,7 @@ type VendorRepository interface {
 	GetAllVendors() ([]models.Vendor, error)
 	GetVendorByID(id int) (*models.Vendor, error)
 	UpdateVendor(vendor *models.Vendor) error
-	DeleteVendor(id int) error
+	DeleteVendor(id int, version int) error
 }
 
 type postgresVendorRepository struct {
@@ -27,16 +27,16 @@ func NewPostgresVendorRepository(db *sql.DB) VendorRepository {
 
 func (r *postgresVendorRepository) CreateVendor(vendor *models.Vendor) error {
 	query := `
-		INSERT INTO vendors (name, contact_person, email, phone, address)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id
+		INSERT INTO vendors (name, contact_person, email, phone, address, pdf_layout, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, version
 	`
-	err := r.db.QueryRow(query, vendor.Name, vendor.ContactPerson, vendor.Email, vendor.Phone, vendor.Address).Scan(&vendor.ID)
+	err := r.db.QueryRow(query, vendor.Name, vendor.ContactPerson, vendor.Email, vendor.Phone, vendor.Address, vendor.PDFLayout, vendor.CreatedBy).Scan(&vendor.ID, &vendor.Version)
 	return err
 }
 
 func (r *postgresVendorRepository) GetAllVendors() ([]models.Vendor, error) {
-	query := `SELECT id, name, contact_person, email, phone, address FROM vendors ORDER BY name ASC`
+	query := `SELECT id, name, contact_person, email, phone, address, pdf_layout, created_by, version FROM vendors ORDER BY name ASC`
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -46,7 +46,7 @@ func (r *postgresVendorRepository) GetAllVendors() ([]models.Vendor, error) {
 	var vendors []models.Vendor
 	for rows.Next() {
 		var v models.Vendor
-		if err := rows.Scan(&v.ID, &v.Name, &v.ContactPerson, &v.Email, &v.Phone, &v.Address); err != nil {
+		if err := rows.Scan(&v.ID, &v.Name, &v.ContactPerson, &v.Email, &v.Phone, &v.Address, &v.PDFLayout, &v.CreatedBy, &v.Version); err != nil {
 			return nil, err
 		}
 		vendors = append(vendors, v)
@@ -56,40 +56,34 @@ func (r *postgresVendorRepository) GetAllVendors() ([]models.Vendor, error) {
 
 func (r *postgresVendorRepository) GetVendorByID(id int) (*models.Vendor, error) {
 	vendor := &models.Vendor{}
-	query := `SELECT id, name, contact_person, email, phone, address FROM vendors WHERE id = $1`
-	err := r.db.QueryRow(query, id).Scan(&vendor.ID, &vendor.Name, &vendor.ContactPerson, &vendor.Email, &vendor.Phone, &vendor.Address)
+	query := `SELECT id, name, contact_person, email, phone, address, pdf_layout, created_by, version FROM vendors WHERE id = $1`
+	err := r.db.QueryRow(query, id).Scan(&vendor.ID, &vendor.Name, &vendor.ContactPerson, &vendor.Email, &vendor.Phone, &vendor.Address, &vendor.PDFLayout, &vendor.CreatedBy, &vendor.Version)
 	if err != nil {
 		return nil, err // This will be sql.ErrNoRows if not found
 	}
 	return vendor, nil
 }
 
+// UpdateVendor saves a vendor if it is still at vendor.Version, and moves
+// vendor.Version on to the new version.
 func (r *postgresVendorRepository) UpdateVendor(vendor *models.Vendor) error {
 	query := `
 		UPDATE vendors
-		SET name = $1, contact_person = $2, email = $3, phone = $4, address = $5
-		WHERE id = $6
+		SET name = $1, contact_person = $2, email = $3, phone = $4, address = $5, pdf_layout = $6, version = version + 1
+		WHERE id = $7 AND version = $8
+		RETURNING version
 	`
-	result, err := r.db.Exec(query, vendor.Name, vendor.ContactPerson, vendor.Email, vendor.Phone, vendor.Address, vendor.ID)
-	if err != nil {
-		return err
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
+	err := r.db.QueryRow(query, vendor.Name, vendor.ContactPerson, vendor.Email, vendor.Phone, vendor.Address, vendor.PDFLayout, vendor.ID, vendor.Version).Scan(&vendor.Version)
+	if err == sql.ErrNoRows {
+		return versionMismatch(r.db, "vendors", vendor.ID, ErrVendorNotFound)
 	}
-
-	if rowsAffected == 0 {
-		return ErrVendorNotFound
-	}
-
-	return nil
+	return err
 }
 
-func (r *postgresVendorRepository) DeleteVendor(id int) error {
-	query := `DELETE FROM vendors WHERE id = $1`
-	result, err := r.db.Exec(query, id)
+// DeleteVendor deletes a vendor if it is still at version.
+func (r *postgresVendorRepository) DeleteVendor(id int, version int) error {
+	query := `DELETE FROM vendors WHERE id = $1 AND version = $2`
+	result, err := r.db.Exec(query, id, version)
 	if err != nil {
 		return err
 	}
@@ -100,7 +94,7 @@ func (r *postgresVendorRepository) DeleteVendor(id int) error {
 	}
 
 	if rowsAffected == 0 {
-		return ErrVendorNotFound
+		return versionMismatch(r.db, "vendors", id, ErrVendorNotFound)
 	}
 
 	return nil
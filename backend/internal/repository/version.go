@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var (
+	ErrVersionConflict = errors.New("the record was changed by someone else; reload it and try again")
+)
+
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx.
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// versionMismatch explains why a versioned UPDATE or DELETE of a row of table
+// matched nothing: notFound if the row does not exist, ErrVersionConflict if
+// it has moved on to another version.
+func versionMismatch(q rowQuerier, table string, id int, notFound error) error {
+	var exists bool
+	if err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM `+table+` WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return notFound
+	}
+	return ErrVersionConflict
+}
@@ -9,7 +9,9 @@ import (
 // ActivityLogService defines the interface for activity logging operations.
 type ActivityLogService interface {
 	Log(userID *int, action string, targetType *string, targetID *int, status string, details *string)
+	LogBatch(batchID string, userID *int, action string, targetType *string, targetID *int, status string, details *string)
 	GetAll() ([]models.ActivityLog, error)
+	GetByTarget(targetType string, targetID int) ([]models.ActivityLog, error)
 }
 
 type activityLogService struct {
@@ -23,15 +25,32 @@ func NewActivityLogService(repo repository.ActivityLogRepository) ActivityLogSer
 
 // Log logs an activity. It runs in a separate goroutine so it doesn't block the main request flow.
 func (s *activityLogService) Log(userID *int, action string, targetType *string, targetID *int, status string, details *string) {
+	s.record(&models.ActivityLog{
+		UserID:     userID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Status:     status,
+		Details:    details,
+	})
+}
+
+// LogBatch logs an activity that is part of a bulk operation, linking it to the
+// other entries logged under the same batch ID.
+func (s *activityLogService) LogBatch(batchID string, userID *int, action string, targetType *string, targetID *int, status string, details *string) {
+	s.record(&models.ActivityLog{
+		UserID:     userID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Status:     status,
+		Details:    details,
+		BatchID:    &batchID,
+	})
+}
+
+func (s *activityLogService) record(activity *models.ActivityLog) {
 	go func() {
-		activity := &models.ActivityLog{
-			UserID:     userID,
-			Action:     action,
-			TargetType: targetType,
-			TargetID:   targetID,
-			Status:     status,
-			Details:    details,
-		}
 		// We log the error here for observability but don't block the main thread.
 		if err := s.repo.Log(activity); err != nil {
 			log.Printf("Failed to log activity: %v", err)
@@ -43,3 +62,8 @@ func (s *activityLogService) Log(userID *int, action string, targetType *string,
 func (s *activityLogService) GetAll() ([]models.ActivityLog, error) {
 	return s.repo.GetAll()
 }
+
+// GetByTarget retrieves the activity recorded against one entity, oldest first.
+func (s *activityLogService) GetByTarget(targetType string, targetID int) ([]models.ActivityLog, error) {
+	return s.repo.GetByTarget(targetType, targetID)
+}
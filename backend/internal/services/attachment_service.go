@@ -0,0 +1,275 @@
+package services
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"procurement-system/pkg/blobstore"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrAttachmentTooLarge          = errors.New("attachment exceeds the maximum size")
+	ErrAttachmentEmpty             = errors.New("attachment is empty")
+	ErrAttachmentTypeNotAllowed    = errors.New("attachment type is not allowed")
+	ErrInvalidAttachmentVisibility = errors.New("vendor-visible attachments are only allowed on purchase orders")
+)
+
+// AttachmentPolicy limits what may be uploaded.
+type AttachmentPolicy struct {
+	MaxSize      int64    // largest accepted file, in bytes
+	AllowedTypes []string // accepted MIME types, as detected from the content
+}
+
+// DefaultAttachmentPolicy returns the policy used when nothing is configured:
+// up to 10 MB of PDFs, images, plain text and office documents.
+func DefaultAttachmentPolicy() AttachmentPolicy {
+	return AttachmentPolicy{
+		MaxSize: 10 << 20,
+		AllowedTypes: []string{
+			"application/pdf",
+			"image/png",
+			"image/jpeg",
+			"image/gif",
+			"image/webp",
+			"text/plain",
+			"text/csv",
+			"application/zip",
+			"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		},
+	}
+}
+
+// refinedTypes narrows a sniffed container type by file extension. Content
+// sniffing sees office documents as zip archives and CSV as plain text; the
+// extension is only trusted to pick between formats of the same container.
+var refinedTypes = map[string]map[string]string{
+	"application/zip": {
+		".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	},
+	"text/plain": {
+		".csv": "text/csv",
+	},
+}
+
+// AttachmentService defines the interface for files attached to entities.
+// Access to an attachment follows access to the entity it is attached to.
+type AttachmentService interface {
+	GetAttachments(userID int, entityType string, entityID int) ([]models.Attachment, error)
+	UploadAttachment(userID int, entityType string, entityID int, filename string, visibility string, content io.Reader) (*models.Attachment, error)
+	OpenAttachment(userID int, id int) (*models.Attachment, io.ReadCloser, error)
+	DeleteAttachment(userID int, id int) error
+}
+
+type attachmentService struct {
+	repo        repository.AttachmentRepository
+	store       blobstore.Store
+	access      EntityAccessService
+	roleService RoleService
+	logService  ActivityLogService
+	policy      AttachmentPolicy
+}
+
+// NewAttachmentService creates a new instance of AttachmentService that keeps
+// content in store.
+func NewAttachmentService(repo repository.AttachmentRepository, store blobstore.Store, access EntityAccessService, roleService RoleService, logService ActivityLogService, policy AttachmentPolicy) AttachmentService {
+	return &attachmentService{repo: repo, store: store, access: access, roleService: roleService, logService: logService, policy: policy}
+}
+
+// GetAttachments lists an entity's attachments as the user may see them.
+// External users only see vendor-visible attachments.
+func (s *attachmentService) GetAttachments(userID int, entityType string, entityID int) ([]models.Attachment, error) {
+	access, err := s.access.Access(userID, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetAttachments(entityType, entityID, access.External)
+}
+
+// UploadAttachment stores a file against an entity. The type is detected from
+// the content rather than trusted from the client, and the content is hashed
+// with SHA-256 as it is stored.
+func (s *attachmentService) UploadAttachment(userID int, entityType string, entityID int, filename string, visibility string, content io.Reader) (*models.Attachment, error) {
+	access, err := s.access.Access(userID, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	visibility, err = resolveVisibility(visibility, entityType, access, ErrInvalidAttachmentVisibility)
+	if err != nil {
+		return nil, err
+	}
+
+	filename = cleanFilename(filename)
+	reader := bufio.NewReaderSize(content, 512)
+	head, err := reader.Peek(512)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(head) == 0 {
+		return nil, ErrAttachmentEmpty
+	}
+	contentType := detectContentType(head, filename)
+	if !contains(s.policy.AllowedTypes, contentType) {
+		return nil, fmt.Errorf("%w: %s", ErrAttachmentTypeNotAllowed, contentType)
+	}
+
+	key, err := blobstore.NewKey()
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	var size byteCounter
+	// Read one byte past the limit so an oversized file can be told apart
+	// from one of exactly the maximum size.
+	limited := io.TeeReader(io.LimitReader(reader, s.policy.MaxSize+1), io.MultiWriter(hash, &size))
+	if err := s.store.Put(key, limited); err != nil {
+		return nil, err
+	}
+	if int64(size) > s.policy.MaxSize {
+		s.deleteBlob(key)
+		return nil, ErrAttachmentTooLarge
+	}
+
+	a := &models.Attachment{
+		EntityType:  entityType,
+		EntityID:    entityID,
+		Filename:    filename,
+		ContentType: contentType,
+		SizeBytes:   int64(size),
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:  key,
+		Visibility:  visibility,
+		UploadedBy:  &userID,
+	}
+	if err := s.repo.CreateAttachment(a); err != nil {
+		s.deleteBlob(key)
+		details := err.Error()
+		s.logService.Log(&userID, "UPLOAD_ATTACHMENT_FAILED", Ptr("attachment"), nil, "FAILED", &details)
+		return nil, err
+	}
+
+	details := fmt.Sprintf("Attached %s (%d bytes, sha256 %s) to %s %d", a.Filename, a.SizeBytes, a.SHA256, entityType, entityID)
+	s.logService.Log(&userID, "UPLOAD_ATTACHMENT_SUCCESS", Ptr("attachment"), &a.ID, "SUCCESS", &details)
+	return a, nil
+}
+
+// OpenAttachment returns an attachment and its content if the user may see
+// the entity it is attached to. The caller must close the content.
+func (s *attachmentService) OpenAttachment(userID int, id int) (*models.Attachment, io.ReadCloser, error) {
+	a, err := s.repo.GetAttachmentByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	access, err := s.access.Access(userID, a.EntityType, a.EntityID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if access.External && a.Visibility != models.CommentVisibilityVendor {
+		return nil, nil, ErrForbidden
+	}
+
+	content, err := s.store.Get(a.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, content, nil
+}
+
+// DeleteAttachment removes an attachment. Uploaders may delete their own
+// while they can still see the entity; holders of attachment:manage may
+// delete any.
+func (s *attachmentService) DeleteAttachment(userID int, id int) error {
+	a, err := s.repo.GetAttachmentByID(id)
+	if err != nil {
+		return err
+	}
+
+	permissions, err := s.roleService.GetUserPermissions(userID)
+	if err != nil {
+		return err
+	}
+	if !permissions[models.PermAttachmentManage] {
+		if a.UploadedBy == nil || *a.UploadedBy != userID {
+			return ErrForbidden
+		}
+		if _, err := s.access.Access(userID, a.EntityType, a.EntityID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.DeleteAttachment(id); err != nil {
+		details := err.Error()
+		s.logService.Log(&userID, "DELETE_ATTACHMENT_FAILED", Ptr("attachment"), &id, "FAILED", &details)
+		return err
+	}
+	// The record is gone, so a blob left behind is only wasted space.
+	s.deleteBlob(a.StorageKey)
+
+	details := fmt.Sprintf("Removed %s from %s %d", a.Filename, a.EntityType, a.EntityID)
+	s.logService.Log(&userID, "DELETE_ATTACHMENT_SUCCESS", Ptr("attachment"), &id, "SUCCESS", &details)
+	return nil
+}
+
+func (s *attachmentService) deleteBlob(key string) {
+	if err := s.store.Delete(key); err != nil {
+		log.Printf("Failed to delete attachment blob %s: %v", key, err)
+	}
+}
+
+// detectContentType sniffs a file's MIME type from its first bytes, refined by
+// extension where the content alone cannot tell formats apart.
+func detectContentType(head []byte, filename string) string {
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	if refined, ok := refinedTypes[contentType][strings.ToLower(filepath.Ext(filename))]; ok {
+		return refined
+	}
+	return contentType
+}
+
+// cleanFilename keeps the base name of an uploaded file, without control
+// characters, so it is safe to echo back in a Content-Disposition header.
+func cleanFilename(filename string) string {
+	filename = filepath.Base(strings.ReplaceAll(filename, "\\", "/"))
+	filename = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, filename))
+	if filename == "" || filename == "." || filename == "/" {
+		return "attachment"
+	}
+	if len(filename) > 255 {
+		ext := filepath.Ext(filename)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		filename = strings.ToValidUTF8(filename[:255-len(ext)], "") + ext
+	}
+	return filename
+}
+
+// byteCounter counts the bytes written to it.
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
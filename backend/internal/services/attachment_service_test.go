@@ -0,0 +1,174 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"procurement-system/internal/models"
+	"procurement-system/pkg/blobstore"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAttachmentRepository is a mock type for the AttachmentRepository
+type MockAttachmentRepository struct {
+	mock.Mock
+}
+
+func (m *MockAttachmentRepository) CreateAttachment(a *models.Attachment) error {
+	args := m.Called(a)
+	return args.Error(0)
+}
+func (m *MockAttachmentRepository) GetAttachmentByID(id int) (*models.Attachment, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Attachment), args.Error(1)
+}
+func (m *MockAttachmentRepository) GetAttachments(entityType string, entityID int, vendorVisibleOnly bool) ([]models.Attachment, error) {
+	args := m.Called(entityType, entityID, vendorVisibleOnly)
+	return args.Get(0).([]models.Attachment), args.Error(1)
+}
+func (m *MockAttachmentRepository) DeleteAttachment(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func TestAttachmentService(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+
+	type fixture struct {
+		service AttachmentService
+		repo    *MockAttachmentRepository
+		store   *blobstore.LocalStore
+		dir     string
+		access  *MockEntityAccessService
+		roles   *MockRoleService
+	}
+	newService := func(policy AttachmentPolicy) fixture {
+		f := fixture{repo: new(MockAttachmentRepository), dir: t.TempDir(), access: new(MockEntityAccessService), roles: new(MockRoleService)}
+		f.store, _ = blobstore.NewLocalStore(f.dir)
+		mockLog := new(MockActivityLogService)
+		mockLog.On("Log", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+		f.service = NewAttachmentService(f.repo, f.store, f.access, f.roles, mockLog, policy)
+		return f
+	}
+	// storedBlobs counts the files in the store, ignoring the prefix directories.
+	storedBlobs := func(t *testing.T, dir string) int {
+		count := 0
+		prefixes, _ := os.ReadDir(dir)
+		for _, prefix := range prefixes {
+			blobs, _ := os.ReadDir(dir + "/" + prefix.Name())
+			count += len(blobs)
+		}
+		return count
+	}
+
+	t.Run("UploadAttachment - Sniffs Type And Hashes Content", func(t *testing.T) {
+		f := newService(DefaultAttachmentPolicy())
+		f.access.On("Access", 1, models.EntityRequisition, 10).Return(&EntityAccess{}, nil)
+		f.repo.On("CreateAttachment", mock.AnythingOfType("*models.Attachment")).Return(nil)
+
+		content := "%PDF-1.4\nquote for 3 laptops"
+		// The client's name and type are not trusted: the content is a PDF.
+		a, err := f.service.UploadAttachment(1, models.EntityRequisition, 10, `C:\quotes\quote.exe`, "", strings.NewReader(content))
+		assert.NoError(t, err)
+		sum := sha256.Sum256([]byte(content))
+		assert.Equal(t, "application/pdf", a.ContentType)
+		assert.Equal(t, hex.EncodeToString(sum[:]), a.SHA256)
+		assert.Equal(t, int64(len(content)), a.SizeBytes)
+		assert.Equal(t, "quote.exe", a.Filename)
+		assert.Equal(t, models.CommentVisibilityInternal, a.Visibility)
+
+		blob, err := f.store.Get(a.StorageKey)
+		assert.NoError(t, err)
+		stored, _ := io.ReadAll(blob)
+		blob.Close()
+		assert.Equal(t, content, string(stored))
+	})
+
+	t.Run("UploadAttachment - Office Documents Refined By Extension", func(t *testing.T) {
+		f := newService(DefaultAttachmentPolicy())
+		f.access.On("Access", 1, models.EntityVendor, 3).Return(&EntityAccess{}, nil)
+		f.repo.On("CreateAttachment", mock.AnythingOfType("*models.Attachment")).Return(nil)
+
+		a, err := f.service.UploadAttachment(1, models.EntityVendor, 3, "Price List.XLSX", "", strings.NewReader("PK\x03\x04rest of the archive"))
+		assert.NoError(t, err)
+		assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", a.ContentType)
+	})
+
+	t.Run("UploadAttachment - Too Large", func(t *testing.T) {
+		policy := DefaultAttachmentPolicy()
+		policy.MaxSize = 10
+		f := newService(policy)
+		f.access.On("Access", 1, models.EntityRequisition, 10).Return(&EntityAccess{}, nil)
+
+		_, err := f.service.UploadAttachment(1, models.EntityRequisition, 10, "notes.txt", "", strings.NewReader("eleven char"))
+		assert.Equal(t, ErrAttachmentTooLarge, err)
+		assert.Equal(t, 0, storedBlobs(t, f.dir))
+		f.repo.AssertNotCalled(t, "CreateAttachment", mock.Anything)
+	})
+
+	t.Run("UploadAttachment - Type Not Allowed", func(t *testing.T) {
+		f := newService(DefaultAttachmentPolicy())
+		f.access.On("Access", 1, models.EntityRequisition, 10).Return(&EntityAccess{}, nil)
+
+		_, err := f.service.UploadAttachment(1, models.EntityRequisition, 10, "quote.pdf", "", strings.NewReader("MZ\x90\x00\x03\x00\x00\x00"))
+		assert.ErrorIs(t, err, ErrAttachmentTypeNotAllowed)
+		assert.Equal(t, 0, storedBlobs(t, f.dir))
+	})
+
+	t.Run("UploadAttachment - Vendor Visibility Only On Purchase Orders", func(t *testing.T) {
+		f := newService(DefaultAttachmentPolicy())
+		f.access.On("Access", 1, models.EntityRequisition, 10).Return(&EntityAccess{}, nil)
+
+		_, err := f.service.UploadAttachment(1, models.EntityRequisition, 10, "notes.txt", models.CommentVisibilityVendor, strings.NewReader("hello"))
+		assert.Equal(t, ErrInvalidAttachmentVisibility, err)
+	})
+
+	t.Run("OpenAttachment - External Users Cannot Download Internal Files", func(t *testing.T) {
+		f := newService(DefaultAttachmentPolicy())
+		f.repo.On("GetAttachmentByID", 4).Return(&models.Attachment{ID: 4, EntityType: models.EntityPurchaseOrder, EntityID: 5, Visibility: models.CommentVisibilityInternal}, nil)
+		f.access.On("Access", 7, models.EntityPurchaseOrder, 5).Return(&EntityAccess{External: true}, nil)
+
+		_, _, err := f.service.OpenAttachment(7, 4)
+		assert.Equal(t, ErrForbidden, err)
+	})
+
+	t.Run("OpenAttachment - Checked Against The Parent Entity", func(t *testing.T) {
+		f := newService(DefaultAttachmentPolicy())
+		f.repo.On("GetAttachmentByID", 4).Return(&models.Attachment{ID: 4, EntityType: models.EntityRequisition, EntityID: 10}, nil)
+		f.access.On("Access", 2, models.EntityRequisition, 10).Return(nil, ErrForbidden)
+
+		_, _, err := f.service.OpenAttachment(2, 4)
+		assert.Equal(t, ErrForbidden, err)
+	})
+
+	t.Run("DeleteAttachment - Removes Record And Blob", func(t *testing.T) {
+		f := newService(DefaultAttachmentPolicy())
+		key, _ := blobstore.NewKey()
+		assert.NoError(t, f.store.Put(key, strings.NewReader("spec")))
+		f.repo.On("GetAttachmentByID", 4).Return(&models.Attachment{ID: 4, EntityType: models.EntityRequisition, EntityID: 10, StorageKey: key, UploadedBy: intPtr(1)}, nil)
+		f.roles.On("GetUserPermissions", 1).Return(map[string]bool{}, nil)
+		f.access.On("Access", 1, models.EntityRequisition, 10).Return(&EntityAccess{}, nil)
+		f.repo.On("DeleteAttachment", 4).Return(nil)
+
+		assert.NoError(t, f.service.DeleteAttachment(1, 4))
+		_, err := f.store.Get(key)
+		assert.Equal(t, blobstore.ErrNotFound, err)
+	})
+
+	t.Run("DeleteAttachment - Not The Uploader", func(t *testing.T) {
+		f := newService(DefaultAttachmentPolicy())
+		f.repo.On("GetAttachmentByID", 4).Return(&models.Attachment{ID: 4, UploadedBy: intPtr(1)}, nil)
+		f.roles.On("GetUserPermissions", 2).Return(map[string]bool{}, nil)
+
+		assert.Equal(t, ErrForbidden, f.service.DeleteAttachment(2, 4))
+		f.repo.AssertNotCalled(t, "DeleteAttachment", mock.Anything)
+	})
+}
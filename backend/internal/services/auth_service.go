@@ -5,6 +5,7 @@ import (
 	"os"
 	"procurement-system/internal/models"
 	"procurement-system/internal/repository"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -12,24 +13,95 @@ import (
 )
 
 var (
-	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrInvalidCredentials     = errors.New("invalid email or password")
+	ErrInvalidChallenge       = errors.New("invalid or expired two-factor challenge")
+	ErrRegistrationDisabled   = errors.New("self-registration is disabled")
+	ErrAccountPendingApproval = errors.New("account is awaiting administrator approval")
+	ErrAccountRejected        = errors.New("account registration was rejected")
+)
+
+// RegistrationMode controls what POST /register does.
+type RegistrationMode string
+
+const (
+	RegistrationOpen     RegistrationMode = "open"     // Employees can register and log in immediately
+	RegistrationApproval RegistrationMode = "approval" // Employees can register but need admin approval
+	RegistrationDisabled RegistrationMode = "disabled" // Only invitations can create accounts
+)
+
+// ParseRegistrationMode converts a config value into a RegistrationMode,
+// defaulting to RegistrationOpen for empty or unknown values.
+func ParseRegistrationMode(value string) RegistrationMode {
+	switch RegistrationMode(value) {
+	case RegistrationApproval, RegistrationDisabled:
+		return RegistrationMode(value)
+	default:
+		return RegistrationOpen
+	}
+}
+
+// Purposes for short-lived challenge tokens issued between the password and
+// second-factor steps of login. AuthMiddleware rejects any token with a purpose.
+const (
+	challengePurposeVerify = "2fa_verify"
+	challengePurposeEnrol  = "2fa_enrol"
+	challengeTokenTTL      = 10 * time.Minute
 )
 
 type AuthService interface {
 	Register(payload models.RegistrationPayload) (*models.User, error)
-	Login(payload models.LoginPayload) (string, error)
+	Login(payload models.LoginPayload, ipAddress string) (*models.LoginResponse, error)
+	VerifyTwoFactor(payload models.TwoFactorChallengePayload, ipAddress string) (*models.LoginResponse, error)
+	BeginTwoFactorEnrolment(payload models.TwoFactorEnrolmentPayload) (*models.TwoFactorEnrolment, error)
+	ConfirmTwoFactorEnrolment(payload models.TwoFactorChallengePayload, ipAddress string) (*models.LoginResponse, error)
 }
 
 type authService struct {
-	userRepo    repository.UserRepository
-	logService  ActivityLogService
+	userRepo         repository.UserRepository
+	logService       ActivityLogService
+	throttleService  LoginThrottleService
+	twoFactorService TwoFactorService
+	registration     RegistrationMode
 }
 
-func NewAuthService(userRepo repository.UserRepository, logService ActivityLogService) AuthService {
-	return &authService{userRepo: userRepo, logService: logService}
+func NewAuthService(userRepo repository.UserRepository, logService ActivityLogService, throttleService LoginThrottleService, twoFactorService TwoFactorService, registration RegistrationMode) AuthService {
+	return &authService{
+		userRepo:         userRepo,
+		logService:       logService,
+		throttleService:  throttleService,
+		twoFactorService: twoFactorService,
+		registration:     registration,
+	}
 }
 
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// getDummyHash returns a bcrypt hash that is compared against when the email
+// is unknown, so a missing user takes as long to reject as a wrong password.
+func getDummyHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password-for-timing"), bcrypt.DefaultCost)
+	})
+	return dummyHash
+}
+
+// Register creates an Employee account. Depending on the registration mode the
+// account is active immediately, waits for admin approval, or is refused.
 func (s *authService) Register(payload models.RegistrationPayload) (*models.User, error) {
+	if s.registration == RegistrationDisabled {
+		details := "Self-registration attempted while disabled for email: " + payload.Email
+		s.logService.Log(nil, "REGISTER_USER_FAILED", nil, nil, "FAILED", &details)
+		return nil, ErrRegistrationDisabled
+	}
+
+	status := models.UserStatusActive
+	if s.registration == RegistrationApproval {
+		status = models.UserStatusPendingApproval
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(payload.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, err
@@ -39,7 +111,8 @@ func (s *authService) Register(payload models.RegistrationPayload) (*models.User
 		Name:           payload.Name,
 		Email:          payload.Email,
 		HashedPassword: string(hashedPassword),
-		Role:           payload.Role,
+		Role:           "Employee",
+		Status:         status,
 	}
 
 	createdUser, err := s.userRepo.CreateUser(user)
@@ -57,30 +130,170 @@ func (s *authService) Register(payload models.RegistrationPayload) (*models.User
 	return createdUser, nil
 }
 
-func (s *authService) Login(payload models.LoginPayload) (string, error) {
+func (s *authService) Login(payload models.LoginPayload, ipAddress string) (*models.LoginResponse, error) {
+	if err := s.checkThrottle(payload.Email, ipAddress); err != nil {
+		return nil, err
+	}
+
 	user, err := s.userRepo.GetUserByEmail(payload.Email)
-	if err != nil {
-		var details string
-		if errors.Is(err, repository.ErrUserNotFound) {
-			details = "User not found for email: " + payload.Email
-			s.logService.Log(nil, "LOGIN_FAILED", Ptr("user"), nil, "FAILED", &details)
-			return "", ErrInvalidCredentials
-		}
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
 		// Generic database error
-		details = err.Error()
+		details := err.Error()
 		s.logService.Log(nil, "LOGIN_FAILED_DB_ERROR", nil, nil, "FAILED", &details)
-		return "", err
+		return nil, err
+	}
+
+	// Always run a bcrypt comparison so unknown emails and wrong passwords
+	// are indistinguishable, both in the response and in timing.
+	hashedPassword := getDummyHash()
+	if user != nil {
+		hashedPassword = []byte(user.HashedPassword)
+	}
+	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(payload.Password))
+	if user == nil || err != nil {
+		// Details deliberately don't say whether the email exists.
+		details := "Invalid credentials for email: " + payload.Email
+		s.logService.Log(nil, "LOGIN_FAILED", Ptr("user"), nil, "FAILED", &details)
+		s.throttleService.RegisterFailure(payload.Email, ipAddress)
+		return nil, ErrInvalidCredentials
+	}
+
+	// Status is only revealed once the password has been verified.
+	switch user.Status {
+	case models.UserStatusPendingApproval:
+		s.logService.Log(&user.ID, "LOGIN_BLOCKED_PENDING_APPROVAL", Ptr("user"), &user.ID, "FAILED", nil)
+		return nil, ErrAccountPendingApproval
+	case models.UserStatusRejected:
+		s.logService.Log(&user.ID, "LOGIN_BLOCKED_REJECTED", Ptr("user"), &user.ID, "FAILED", nil)
+		return nil, ErrAccountRejected
+	}
+
+	// The account throttle is only reset once login fully completes, so a
+	// known password can't be used to keep resetting second-factor guesses.
+	enabled, err := s.twoFactorService.IsEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return s.issueChallenge(user, challengePurposeVerify)
+	}
+	required, err := s.twoFactorService.IsRequiredForUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if required {
+		return s.issueChallenge(user, challengePurposeEnrol)
+	}
+
+	return s.completeLogin(user)
+}
+
+// VerifyTwoFactor completes a login that was answered with a challenge token.
+func (s *authService) VerifyTwoFactor(payload models.TwoFactorChallengePayload, ipAddress string) (*models.LoginResponse, error) {
+	user, err := s.userFromChallenge(payload.ChallengeToken, challengePurposeVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkThrottle(user.Email, ipAddress); err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorService.VerifyCode(user.ID, payload.Code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			details := "Invalid two-factor code"
+			s.logService.Log(&user.ID, "LOGIN_2FA_FAILED", Ptr("user"), &user.ID, "FAILED", &details)
+			s.throttleService.RegisterFailure(user.Email, ipAddress)
+		}
+		return nil, err
+	}
+
+	return s.completeLogin(user)
+}
+
+// BeginTwoFactorEnrolment starts enrolment for a user whose role requires 2FA
+// but who has not set it up yet. It can only be called with an enrolment challenge.
+func (s *authService) BeginTwoFactorEnrolment(payload models.TwoFactorEnrolmentPayload) (*models.TwoFactorEnrolment, error) {
+	user, err := s.userFromChallenge(payload.ChallengeToken, challengePurposeEnrol)
+	if err != nil {
+		return nil, err
+	}
+	return s.twoFactorService.BeginEnrolment(user.ID)
+}
+
+// ConfirmTwoFactorEnrolment activates 2FA and completes the login in one step,
+// returning the JWT together with the user's recovery codes.
+func (s *authService) ConfirmTwoFactorEnrolment(payload models.TwoFactorChallengePayload, ipAddress string) (*models.LoginResponse, error) {
+	user, err := s.userFromChallenge(payload.ChallengeToken, challengePurposeEnrol)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkThrottle(user.Email, ipAddress); err != nil {
+		return nil, err
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.HashedPassword), []byte(payload.Password))
+	codes, err := s.twoFactorService.ConfirmEnrolment(user.ID, payload.Code)
 	if err != nil {
-		details := "Invalid password for user: " + payload.Email
-		s.logService.Log(&user.ID, "LOGIN_FAILED", Ptr("user"), &user.ID, "FAILED", &details)
-		return "", ErrInvalidCredentials
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			s.throttleService.RegisterFailure(user.Email, ipAddress)
+		}
+		return nil, err
+	}
+
+	resp, err := s.completeLogin(user)
+	if err != nil {
+		return nil, err
+	}
+	resp.RecoveryCodes = codes
+	return resp, nil
+}
+
+func (s *authService) checkThrottle(email, ipAddress string) error {
+	err := s.throttleService.Check(email, ipAddress)
+	if err != nil && errors.Is(err, ErrTooManyLoginAttempts) {
+		details := "Login attempt blocked by throttling from IP: " + ipAddress
+		s.logService.Log(nil, "LOGIN_THROTTLED", Ptr("user"), nil, "FAILED", &details)
 	}
+	return err
+}
 
+func (s *authService) completeLogin(user *models.User) (*models.LoginResponse, error) {
+	s.throttleService.RegisterSuccess(user.Email)
 	s.logService.Log(&user.ID, "LOGIN_SUCCESS", Ptr("user"), &user.ID, "SUCCESS", nil)
-	return s.generateJWT(user)
+	token, err := s.generateJWT(user)
+	if err != nil {
+		return nil, err
+	}
+	return &models.LoginResponse{Token: token}, nil
+}
+
+func (s *authService) issueChallenge(user *models.User, purpose string) (*models.LoginResponse, error) {
+	token, err := s.generateChallengeToken(user, purpose)
+	if err != nil {
+		return nil, err
+	}
+	s.logService.Log(&user.ID, "LOGIN_2FA_CHALLENGE_ISSUED", Ptr("user"), &user.ID, "SUCCESS", &purpose)
+	return &models.LoginResponse{
+		ChallengeToken:             token,
+		TwoFactorRequired:          purpose == challengePurposeVerify,
+		TwoFactorEnrolmentRequired: purpose == challengePurposeEnrol,
+	}, nil
+}
+
+func (s *authService) userFromChallenge(challengeToken, purpose string) (*models.User, error) {
+	userID, err := s.parseChallengeToken(challengeToken, purpose)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+	return user, nil
 }
 
 // Ptr is a helper function to get a pointer to a string.
@@ -103,3 +316,46 @@ func (s *authService) generateJWT(user *models.User) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(jwtSecret))
 }
+
+func (s *authService) generateChallengeToken(user *models.User, purpose string) (string, error) {
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		return "", errors.New("JWT_SECRET environment variable not set")
+	}
+
+	claims := jwt.MapClaims{
+		"user_id": user.ID,
+		"purpose": purpose,
+		"exp":     time.Now().Add(challengeTokenTTL).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(jwtSecret))
+}
+
+func (s *authService) parseChallengeToken(tokenString, purpose string) (int, error) {
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		return 0, errors.New("JWT_SECRET environment variable not set")
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(jwtSecret), nil
+	})
+	if err != nil || !token.Valid {
+		return 0, ErrInvalidChallenge
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != purpose {
+		return 0, ErrInvalidChallenge
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, ErrInvalidChallenge
+	}
+	return int(userID), nil
+}
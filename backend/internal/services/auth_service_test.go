@@ -6,6 +6,7 @@ import (
 	"procurement-system/internal/models"
 	"procurement-system/internal/repository"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -33,13 +34,39 @@ func (m *MockUserRepository) GetUserByEmail(email string) (*models.User, error)
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) GetUserByID(id int) (*models.User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
 // These methods were added to the interface but are not used in this test file.
 // We add them here to satisfy the interface.
-func (m *MockUserRepository) GetUserByID(id int) (*models.User, error) { return nil, nil }
-func (m *MockUserRepository) GetAllUsers() ([]models.User, error)    { return nil, nil }
-func (m *MockUserRepository) UpdateUser(user *models.User) error      { return nil }
-func (m *MockUserRepository) DeleteUser(id int) error                 { return nil }
+func (m *MockUserRepository) GetAllUsers() ([]models.User, error) {
+	args := m.Called()
+	return args.Get(0).([]models.User), args.Error(1)
+}
+func (m *MockUserRepository) UpdateUser(user *models.User) error                        { return nil }
+func (m *MockUserRepository) DeleteUser(id int, version int) error                      { return nil }
 func (m *MockUserRepository) UpdatePassword(userID int, newHashedPassword string) error { return nil }
+func (m *MockUserRepository) GetUsersByStatus(status string) ([]models.User, error) {
+	args := m.Called(status)
+	return args.Get(0).([]models.User), args.Error(1)
+}
+func (m *MockUserRepository) UpdateUserStatus(id int, status string) error {
+	args := m.Called(id, status)
+	return args.Error(0)
+}
+func (m *MockUserRepository) SetUserVendor(id int, vendorID *int) error {
+	args := m.Called(id, vendorID)
+	return args.Error(0)
+}
+func (m *MockUserRepository) GetUsersByVendor(vendorID int) ([]models.User, error) {
+	args := m.Called(vendorID)
+	return args.Get(0).([]models.User), args.Error(1)
+}
 
 // MockActivityLogService is a mock type for the ActivityLogService
 type MockActivityLogService struct {
@@ -49,25 +76,105 @@ type MockActivityLogService struct {
 func (m *MockActivityLogService) Log(userID *int, action string, targetType *string, targetID *int, status string, details *string) {
 	m.Called(userID, action, targetType, targetID, status, details)
 }
+func (m *MockActivityLogService) LogBatch(batchID string, userID *int, action string, targetType *string, targetID *int, status string, details *string) {
+	m.Called(batchID, userID, action, targetType, targetID, status, details)
+}
 func (m *MockActivityLogService) GetAll() ([]models.ActivityLog, error) {
 	args := m.Called()
 	return args.Get(0).([]models.ActivityLog), args.Error(1)
 }
+func (m *MockActivityLogService) GetByTarget(targetType string, targetID int) ([]models.ActivityLog, error) {
+	args := m.Called(targetType, targetID)
+	return args.Get(0).([]models.ActivityLog), args.Error(1)
+}
+
+// MockLoginThrottleService is a mock type for the LoginThrottleService
+type MockLoginThrottleService struct {
+	mock.Mock
+}
+
+func (m *MockLoginThrottleService) Check(email, ipAddress string) error {
+	args := m.Called(email, ipAddress)
+	return args.Error(0)
+}
+func (m *MockLoginThrottleService) RegisterFailure(email, ipAddress string) {
+	m.Called(email, ipAddress)
+}
+func (m *MockLoginThrottleService) RegisterSuccess(email string) {
+	m.Called(email)
+}
+func (m *MockLoginThrottleService) GetActiveLocks() ([]models.LoginThrottle, error) {
+	args := m.Called()
+	return args.Get(0).([]models.LoginThrottle), args.Error(1)
+}
+func (m *MockLoginThrottleService) ClearLock(actorID int, lockID int) error {
+	args := m.Called(actorID, lockID)
+	return args.Error(0)
+}
 
+// MockTwoFactorService is a mock type for the TwoFactorService
+type MockTwoFactorService struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorService) IsRequiredForUser(userID int) (bool, error) {
+	args := m.Called(userID)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockTwoFactorService) IsEnabled(userID int) (bool, error) {
+	args := m.Called(userID)
+	return args.Bool(0), args.Error(1)
+}
+func (m *MockTwoFactorService) BeginEnrolment(userID int) (*models.TwoFactorEnrolment, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TwoFactorEnrolment), args.Error(1)
+}
+func (m *MockTwoFactorService) ConfirmEnrolment(userID int, code string) ([]string, error) {
+	args := m.Called(userID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+func (m *MockTwoFactorService) VerifyCode(userID int, code string) error {
+	args := m.Called(userID, code)
+	return args.Error(0)
+}
+func (m *MockTwoFactorService) RegenerateRecoveryCodes(userID int, code string) ([]string, error) {
+	args := m.Called(userID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+func (m *MockTwoFactorService) Disable(userID int, code string) error {
+	args := m.Called(userID, code)
+	return args.Error(0)
+}
+func (m *MockTwoFactorService) Reset(actorID int, targetUserID int) error {
+	args := m.Called(actorID, targetUserID)
+	return args.Error(0)
+}
 
 func TestAuthService_Register(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	mockLogService := new(MockActivityLogService)
-	authService := NewAuthService(mockRepo, mockLogService)
+	mockThrottle := new(MockLoginThrottleService)
+	mockTwoFactor := new(MockTwoFactorService)
+	authService := NewAuthService(mockRepo, mockLogService, mockThrottle, mockTwoFactor, RegistrationOpen)
 
 	payload := models.RegistrationPayload{
 		Name:     "Test User",
 		Email:    "test@example.com",
 		Password: "password123",
-		Role:     "Employee",
 	}
 
-	mockRepo.On("CreateUser", mock.AnythingOfType("*models.User")).Return(&models.User{ID: 1}, nil)
+	mockRepo.On("CreateUser", mock.MatchedBy(func(u *models.User) bool {
+		return u.Role == "Employee" && u.Status == models.UserStatusActive
+	})).Return(&models.User{ID: 1}, nil)
 	mockLogService.On("Log", mock.Anything, "REGISTER_USER_SUCCESS", mock.Anything, mock.Anything, "SUCCESS", mock.Anything).Return()
 
 	user, err := authService.Register(payload)
@@ -78,10 +185,61 @@ func TestAuthService_Register(t *testing.T) {
 	mockLogService.AssertExpectations(t)
 }
 
+func TestAuthService_Register_Modes(t *testing.T) {
+	payload := models.RegistrationPayload{Name: "Test User", Email: "test@example.com", Password: "password123"}
+
+	t.Run("Approval", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockLogService := new(MockActivityLogService)
+		authService := NewAuthService(mockRepo, mockLogService, nil, nil, RegistrationApproval)
+
+		mockRepo.On("CreateUser", mock.MatchedBy(func(u *models.User) bool {
+			return u.Role == "Employee" && u.Status == models.UserStatusPendingApproval
+		})).Return(&models.User{ID: 1, Status: models.UserStatusPendingApproval}, nil)
+		mockLogService.On("Log", mock.Anything, "REGISTER_USER_SUCCESS", mock.Anything, mock.Anything, "SUCCESS", mock.Anything).Return()
+
+		user, err := authService.Register(payload)
+		assert.NoError(t, err)
+		assert.Equal(t, models.UserStatusPendingApproval, user.Status)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockLogService := new(MockActivityLogService)
+		authService := NewAuthService(mockRepo, mockLogService, nil, nil, RegistrationDisabled)
+		mockLogService.On("Log", mock.Anything, "REGISTER_USER_FAILED", mock.Anything, mock.Anything, "FAILED", mock.Anything).Return()
+
+		_, err := authService.Register(payload)
+		assert.Equal(t, ErrRegistrationDisabled, err)
+		mockRepo.AssertNotCalled(t, "CreateUser", mock.Anything)
+	})
+}
+
+func TestAuthService_Login_PendingApproval(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockLogService := new(MockActivityLogService)
+	mockThrottle := new(MockLoginThrottleService)
+	mockTwoFactor := new(MockTwoFactorService)
+	authService := NewAuthService(mockRepo, mockLogService, mockThrottle, mockTwoFactor, RegistrationApproval)
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	mockUser := &models.User{ID: 1, Email: "new@example.com", HashedPassword: string(hashedPassword), Role: "Employee", Status: models.UserStatusPendingApproval}
+
+	mockThrottle.On("Check", "new@example.com", "").Return(nil)
+	mockRepo.On("GetUserByEmail", "new@example.com").Return(mockUser, nil)
+	mockLogService.On("Log", &mockUser.ID, "LOGIN_BLOCKED_PENDING_APPROVAL", mock.Anything, &mockUser.ID, "FAILED", mock.Anything).Return()
+
+	_, err := authService.Login(models.LoginPayload{Email: "new@example.com", Password: "password123"}, "")
+	assert.Equal(t, ErrAccountPendingApproval, err)
+	mockTwoFactor.AssertNotCalled(t, "IsEnabled", mock.Anything)
+}
+
 func TestAuthService_Register_EmailExists(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	mockLogService := new(MockActivityLogService)
-	authService := NewAuthService(mockRepo, mockLogService)
+	mockThrottle := new(MockLoginThrottleService)
+	mockTwoFactor := new(MockTwoFactorService)
+	authService := NewAuthService(mockRepo, mockLogService, mockThrottle, mockTwoFactor, RegistrationOpen)
 	payload := models.RegistrationPayload{Email: "exists@example.com"}
 
 	mockRepo.On("CreateUser", mock.AnythingOfType("*models.User")).Return(nil, repository.ErrEmailExists)
@@ -99,56 +257,172 @@ func TestAuthService_Register_EmailExists(t *testing.T) {
 func TestAuthService_Login(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	mockLogService := new(MockActivityLogService)
-	authService := NewAuthService(mockRepo, mockLogService)
+	mockThrottle := new(MockLoginThrottleService)
+	mockTwoFactor := new(MockTwoFactorService)
+	authService := NewAuthService(mockRepo, mockLogService, mockThrottle, mockTwoFactor, RegistrationOpen)
 	os.Setenv("JWT_SECRET", "test-secret")
 	defer os.Unsetenv("JWT_SECRET")
 	password := "password123"
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	mockUser := &models.User{ID: 1, Email: "test@example.com", HashedPassword: string(hashedPassword)}
+	mockUser := &models.User{ID: 1, Email: "test@example.com", HashedPassword: string(hashedPassword), Role: "Employee"}
 
+	mockThrottle.On("Check", "test@example.com", "10.0.0.1").Return(nil)
 	mockRepo.On("GetUserByEmail", "test@example.com").Return(mockUser, nil)
+	mockTwoFactor.On("IsEnabled", 1).Return(false, nil)
+	mockTwoFactor.On("IsRequiredForUser", 1).Return(false, nil)
+	mockThrottle.On("RegisterSuccess", "test@example.com").Return()
 	mockLogService.On("Log", &mockUser.ID, "LOGIN_SUCCESS", mock.Anything, &mockUser.ID, "SUCCESS", mock.Anything).Return()
 
-	token, err := authService.Login(models.LoginPayload{Email: "test@example.com", Password: "password123"})
+	resp, err := authService.Login(models.LoginPayload{Email: "test@example.com", Password: "password123"}, "10.0.0.1")
 
 	assert.NoError(t, err)
-	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, resp.Token)
+	assert.Empty(t, resp.ChallengeToken)
 	mockRepo.AssertExpectations(t)
 	mockLogService.AssertExpectations(t)
+	mockThrottle.AssertExpectations(t)
+}
+
+func TestAuthService_Login_TwoFactor(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test-secret")
+	defer os.Unsetenv("JWT_SECRET")
+	password := "password123"
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	mockUser := &models.User{ID: 1, Email: "admin@example.com", HashedPassword: string(hashedPassword), Role: "Admin"}
+
+	t.Run("Enabled - Challenge Then Verify", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockLogService := new(MockActivityLogService)
+		mockThrottle := new(MockLoginThrottleService)
+		mockTwoFactor := new(MockTwoFactorService)
+		authService := NewAuthService(mockRepo, mockLogService, mockThrottle, mockTwoFactor, RegistrationOpen)
+
+		mockThrottle.On("Check", "admin@example.com", "10.0.0.1").Return(nil)
+		mockRepo.On("GetUserByEmail", "admin@example.com").Return(mockUser, nil)
+		mockRepo.On("GetUserByID", 1).Return(mockUser, nil)
+		mockTwoFactor.On("IsEnabled", 1).Return(true, nil)
+		mockLogService.On("Log", &mockUser.ID, "LOGIN_2FA_CHALLENGE_ISSUED", mock.Anything, &mockUser.ID, "SUCCESS", mock.Anything).Return()
+
+		resp, err := authService.Login(models.LoginPayload{Email: "admin@example.com", Password: password}, "10.0.0.1")
+		assert.NoError(t, err)
+		assert.True(t, resp.TwoFactorRequired)
+		assert.Empty(t, resp.Token)
+		assert.NotEmpty(t, resp.ChallengeToken)
+		mockThrottle.AssertNotCalled(t, "RegisterSuccess", mock.Anything)
+
+		mockTwoFactor.On("VerifyCode", 1, "123456").Return(nil).Once()
+		mockThrottle.On("RegisterSuccess", "admin@example.com").Return()
+		mockLogService.On("Log", &mockUser.ID, "LOGIN_SUCCESS", mock.Anything, &mockUser.ID, "SUCCESS", mock.Anything).Return()
+
+		resp, err = authService.VerifyTwoFactor(models.TwoFactorChallengePayload{ChallengeToken: resp.ChallengeToken, Code: "123456"}, "10.0.0.1")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resp.Token)
+		mockTwoFactor.AssertExpectations(t)
+		mockThrottle.AssertExpectations(t)
+	})
+
+	t.Run("Required By Role - Enrolment Challenge", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockLogService := new(MockActivityLogService)
+		mockThrottle := new(MockLoginThrottleService)
+		mockTwoFactor := new(MockTwoFactorService)
+		authService := NewAuthService(mockRepo, mockLogService, mockThrottle, mockTwoFactor, RegistrationOpen)
+
+		mockThrottle.On("Check", "admin@example.com", "10.0.0.1").Return(nil)
+		mockRepo.On("GetUserByEmail", "admin@example.com").Return(mockUser, nil)
+		mockTwoFactor.On("IsEnabled", 1).Return(false, nil)
+		mockTwoFactor.On("IsRequiredForUser", 1).Return(true, nil)
+		mockLogService.On("Log", &mockUser.ID, "LOGIN_2FA_CHALLENGE_ISSUED", mock.Anything, &mockUser.ID, "SUCCESS", mock.Anything).Return()
+
+		resp, err := authService.Login(models.LoginPayload{Email: "admin@example.com", Password: password}, "10.0.0.1")
+		assert.NoError(t, err)
+		assert.True(t, resp.TwoFactorEnrolmentRequired)
+		assert.Empty(t, resp.Token)
+
+		// An enrolment challenge can't be used to skip the second factor.
+		_, err = authService.VerifyTwoFactor(models.TwoFactorChallengePayload{ChallengeToken: resp.ChallengeToken, Code: "123456"}, "10.0.0.1")
+		assert.Equal(t, ErrInvalidChallenge, err)
+	})
+
+	t.Run("Invalid Code Counts As Failure", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockLogService := new(MockActivityLogService)
+		mockThrottle := new(MockLoginThrottleService)
+		mockTwoFactor := new(MockTwoFactorService)
+		authService := NewAuthService(mockRepo, mockLogService, mockThrottle, mockTwoFactor, RegistrationOpen).(*authService)
+
+		challenge, _ := authService.generateChallengeToken(mockUser, challengePurposeVerify)
+		mockRepo.On("GetUserByID", 1).Return(mockUser, nil)
+		mockThrottle.On("Check", "admin@example.com", "10.0.0.1").Return(nil)
+		mockTwoFactor.On("VerifyCode", 1, "000000").Return(ErrInvalidTwoFactorCode)
+		mockLogService.On("Log", &mockUser.ID, "LOGIN_2FA_FAILED", mock.Anything, &mockUser.ID, "FAILED", mock.Anything).Return()
+		mockThrottle.On("RegisterFailure", "admin@example.com", "10.0.0.1").Return().Once()
+
+		_, err := authService.VerifyTwoFactor(models.TwoFactorChallengePayload{ChallengeToken: challenge, Code: "000000"}, "10.0.0.1")
+		assert.Equal(t, ErrInvalidTwoFactorCode, err)
+		mockThrottle.AssertExpectations(t)
+	})
 }
 
 func TestAuthService_Login_InvalidCredentials(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	mockLogService := new(MockActivityLogService)
-	authService := NewAuthService(mockRepo, mockLogService)
+	mockThrottle := new(MockLoginThrottleService)
+	mockTwoFactor := new(MockTwoFactorService)
+	authService := NewAuthService(mockRepo, mockLogService, mockThrottle, mockTwoFactor, RegistrationOpen)
+
+	mockThrottle.On("Check", mock.Anything, "10.0.0.1").Return(nil)
 
 	// Test case 1: User not found
 	mockRepo.On("GetUserByEmail", "notfound@example.com").Return(nil, repository.ErrUserNotFound)
-	mockLogService.On("Log", mock.Anything, "LOGIN_FAILED", mock.Anything, mock.Anything, "FAILED", mock.Anything).Return().Once()
-	_, err := authService.Login(models.LoginPayload{Email: "notfound@example.com", Password: "password"})
+	mockLogService.On("Log", (*int)(nil), "LOGIN_FAILED", mock.Anything, (*int)(nil), "FAILED", mock.Anything).Return().Twice()
+	mockThrottle.On("RegisterFailure", "notfound@example.com", "10.0.0.1").Return().Once()
+	_, err := authService.Login(models.LoginPayload{Email: "notfound@example.com", Password: "password"}, "10.0.0.1")
 	assert.Equal(t, ErrInvalidCredentials, err)
 
-	// Test case 2: Wrong password
+	// Test case 2: Wrong password is logged identically to an unknown email
 	password := "password123"
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	mockUser := &models.User{ID: 1, Email: "test@example.com", HashedPassword: string(hashedPassword)}
 	mockRepo.On("GetUserByEmail", "test@example.com").Return(mockUser, nil)
-	mockLogService.On("Log", &mockUser.ID, "LOGIN_FAILED", mock.Anything, &mockUser.ID, "FAILED", mock.Anything).Once()
-	_, err = authService.Login(models.LoginPayload{Email: "test@example.com", Password: "wrongpassword"})
+	mockThrottle.On("RegisterFailure", "test@example.com", "10.0.0.1").Return().Once()
+	_, err = authService.Login(models.LoginPayload{Email: "test@example.com", Password: "wrongpassword"}, "10.0.0.1")
 	assert.Equal(t, ErrInvalidCredentials, err)
 
 	mockRepo.AssertExpectations(t)
+	mockLogService.AssertExpectations(t)
+	mockThrottle.AssertExpectations(t)
+}
+
+func TestAuthService_Login_Throttled(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockLogService := new(MockActivityLogService)
+	mockThrottle := new(MockLoginThrottleService)
+	mockTwoFactor := new(MockTwoFactorService)
+	authService := NewAuthService(mockRepo, mockLogService, mockThrottle, mockTwoFactor, RegistrationOpen)
+
+	mockThrottle.On("Check", "locked@example.com", "10.0.0.1").Return(&ThrottledError{RetryAfter: time.Minute})
+	mockLogService.On("Log", mock.Anything, "LOGIN_THROTTLED", mock.Anything, mock.Anything, "FAILED", mock.Anything).Return()
+
+	_, err := authService.Login(models.LoginPayload{Email: "locked@example.com", Password: "password"}, "10.0.0.1")
+
+	assert.ErrorIs(t, err, ErrTooManyLoginAttempts)
+	mockRepo.AssertNotCalled(t, "GetUserByEmail", mock.Anything)
+	mockLogService.AssertExpectations(t)
 }
 
 func TestAuthService_Login_RepoError(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	mockLogService := new(MockActivityLogService)
-	authService := NewAuthService(mockRepo, mockLogService)
+	mockThrottle := new(MockLoginThrottleService)
+	mockTwoFactor := new(MockTwoFactorService)
+	authService := NewAuthService(mockRepo, mockLogService, mockThrottle, mockTwoFactor, RegistrationOpen)
 	expectedErr := errors.New("database error")
+	mockThrottle.On("Check", "any@example.com", "").Return(nil)
 	mockRepo.On("GetUserByEmail", "any@example.com").Return(nil, expectedErr)
 	mockLogService.On("Log", mock.Anything, "LOGIN_FAILED_DB_ERROR", mock.Anything, mock.Anything, "FAILED", mock.Anything).Return()
 
-	_, err := authService.Login(models.LoginPayload{Email: "any@example.com", Password: "password"})
+	_, err := authService.Login(models.LoginPayload{Email: "any@example.com", Password: "password"}, "")
 
 	assert.Error(t, err)
 	assert.Equal(t, expectedErr, err)
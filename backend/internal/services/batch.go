@@ -0,0 +1,29 @@
+package services
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// newBatchID returns a random UUID identifying one bulk operation.
+func newBatchID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// batchLog is an ActivityLogService that records every entry under one batch
+// ID, so code shared with single operations links its entries to the batch
+// without knowing about it.
+type batchLog struct {
+	ActivityLogService
+	batchID string
+}
+
+func (l batchLog) Log(userID *int, action string, targetType *string, targetID *int, status string, details *string) {
+	l.LogBatch(l.batchID, userID, action, targetType, targetID, status, details)
+}
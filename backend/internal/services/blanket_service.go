@@ -0,0 +1,262 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidBlanket   = errors.New("invalid blanket purchase order")
+	ErrBlanketNotOpen   = errors.New("blanket purchase order is not open for call-offs")
+	ErrUnknownPriceItem = errors.New("blanket price list has no such item")
+)
+
+// DefaultBlanketWarningPercent is how much of a blanket order's ceiling may be
+// consumed before call-offs against it come back with a warning.
+const DefaultBlanketWarningPercent = 80
+
+// BlanketService manages blanket purchase orders: agreements with a vendor
+// for a period, up to a ceiling value, at agreed prices. Call-offs released
+// against a blanket are ordinary purchase orders priced from its price list,
+// and together may not commit more than its ceiling.
+type BlanketService interface {
+	CreateBlanket(actorID int, payload models.CreateBlanketPayload) (*models.PurchaseOrder, error)
+	CreateCallOff(actorID int, blanketID int, payload models.CallOffPayload) (*models.CallOffResult, error)
+	GetBlanketConsumption() ([]models.BlanketConsumption, error)
+}
+
+type blanketService struct {
+	poRepo      repository.PurchaseOrderRepository
+	vendorRepo  repository.VendorRepository
+	sod         SoDService
+	settings    CompanySettingsService
+	documents   PODocumentService
+	logService  ActivityLogService
+	warnPercent float64
+	now         func() time.Time
+}
+
+// NewBlanketService creates a new instance of BlanketService. Call-offs are
+// issued with the tax and rounding in settings at the time, and warn once a
+// blanket is warnPercent consumed.
+func NewBlanketService(poRepo repository.PurchaseOrderRepository, vendorRepo repository.VendorRepository, sod SoDService, settings CompanySettingsService, documents PODocumentService, logService ActivityLogService, warnPercent float64) BlanketService {
+	return &blanketService{poRepo: poRepo, vendorRepo: vendorRepo, sod: sod, settings: settings, documents: documents, logService: logService, warnPercent: warnPercent, now: time.Now}
+}
+
+// CreateBlanket issues a blanket order. It commits nothing itself; its
+// call-offs do.
+func (s *blanketService) CreateBlanket(actorID int, payload models.CreateBlanketPayload) (*models.PurchaseOrder, error) {
+	po, err := s.createBlanket(actorID, payload)
+	if err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "CREATE_BLANKET_PO_FAILED", Ptr("purchase_order"), nil, "FAILED", &details)
+		return nil, err
+	}
+
+	details := fmt.Sprintf("Issued blanket %s, ceiling %.2f %s, valid %s to %s", po.PONumber, *po.CeilingAmount, po.Currency, payload.ValidFrom, payload.ValidTo)
+	s.logService.Log(&actorID, "CREATE_BLANKET_PO_SUCCESS", Ptr("purchase_order"), &po.ID, "SUCCESS", &details)
+	archivePODocument(s.documents, actorID, po.ID, models.PODocumentIssued)
+	return po, nil
+}
+
+func (s *blanketService) createBlanket(actorID int, payload models.CreateBlanketPayload) (*models.PurchaseOrder, error) {
+	validFrom, err := time.Parse("2006-01-02", payload.ValidFrom)
+	if err != nil {
+		return nil, fmt.Errorf("%w: valid_from is not a date", ErrInvalidBlanket)
+	}
+	validTo, err := time.Parse("2006-01-02", payload.ValidTo)
+	if err != nil {
+		return nil, fmt.Errorf("%w: valid_to is not a date", ErrInvalidBlanket)
+	}
+	if validTo.Before(validFrom) {
+		return nil, fmt.Errorf("%w: valid_to is before valid_from", ErrInvalidBlanket)
+	}
+	vendor, err := s.vendorRepo.GetVendorByID(payload.VendorID)
+	if err != nil {
+		if errors.Is(err, repository.ErrVendorNotFound) {
+			return nil, fmt.Errorf("%w: vendor does not exist", ErrInvalidBlanket)
+		}
+		return nil, err
+	}
+	// The blanket does not exist yet, so a violation is recorded against the
+	// vendor.
+	if err := s.checkIssueDuties(actorID, vendor, models.EntityVendor, vendor.ID); err != nil {
+		return nil, err
+	}
+
+	poNumber, err := s.poRepo.GetNextPONumber()
+	if err != nil {
+		return nil, err
+	}
+
+	ceiling := roundAmount(payload.CeilingAmount)
+	po := &models.PurchaseOrder{
+		PONumber:      poNumber,
+		VendorID:      payload.VendorID,
+		OrderDate:     s.now(),
+		Status:        models.PurchaseOrderStatusIssued,
+		POType:        models.POTypeBlanket,
+		Currency:      currencyOrDefault(payload.Currency),
+		ShipTo:        strings.TrimSpace(payload.ShipTo),
+		ValidFrom:     &validFrom,
+		ValidTo:       &validTo,
+		CeilingAmount: &ceiling,
+		PriceList:     make([]models.BlanketPriceItem, len(payload.PriceList)),
+	}
+	for i, item := range payload.PriceList {
+		po.PriceList[i] = models.BlanketPriceItem{
+			LineNo:      i + 1,
+			Description: strings.TrimSpace(item.Description),
+			UnitPrice:   item.UnitPrice,
+		}
+	}
+
+	if err := s.poRepo.CreatePurchaseOrder(po, &actorID); err != nil {
+		return nil, err
+	}
+	return po, nil
+}
+
+// CreateCallOff releases a call-off against an issued blanket order within
+// its validity. Lines are priced from the blanket's price list. The result
+// carries a warning once the blanket is near its ceiling; a call-off that
+// would go over it fails with repository.ErrBlanketCeilingExceeded.
+func (s *blanketService) CreateCallOff(actorID int, blanketID int, payload models.CallOffPayload) (*models.CallOffResult, error) {
+	result, err := s.createCallOff(actorID, blanketID, payload)
+	if err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "CREATE_CALL_OFF_FAILED", Ptr("purchase_order"), &blanketID, "FAILED", &details)
+		return nil, err
+	}
+
+	po := result.PurchaseOrder
+	details := fmt.Sprintf("Issued call-off %s against %s for %.2f %s", po.PONumber, result.Blanket.PONumber, po.TotalAmount, po.Currency)
+	if result.Warning != "" {
+		details += "; " + result.Warning
+	}
+	s.logService.Log(&actorID, "CREATE_CALL_OFF_SUCCESS", Ptr("purchase_order"), &po.ID, "SUCCESS", &details)
+	archivePODocument(s.documents, actorID, po.ID, models.PODocumentIssued)
+	return result, nil
+}
+
+func (s *blanketService) createCallOff(actorID int, blanketID int, payload models.CallOffPayload) (*models.CallOffResult, error) {
+	blanket, err := s.poRepo.GetPurchaseOrderByID(blanketID)
+	if errors.Is(err, repository.ErrPurchaseOrderNotFound) || (err == nil && blanket.POType != models.POTypeBlanket) {
+		return nil, repository.ErrBlanketNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	today := s.now().Format("2006-01-02")
+	if blanket.Status != models.PurchaseOrderStatusIssued {
+		return nil, fmt.Errorf("%w: it is %s", ErrBlanketNotOpen, blanket.Status)
+	}
+	if today < blanket.ValidFrom.Format("2006-01-02") || today > blanket.ValidTo.Format("2006-01-02") {
+		return nil, fmt.Errorf("%w: it is valid from %s to %s", ErrBlanketNotOpen, blanket.ValidFrom.Format("2006-01-02"), blanket.ValidTo.Format("2006-01-02"))
+	}
+	vendor, err := s.vendorRepo.GetVendorByID(blanket.VendorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkIssueDuties(actorID, vendor, models.EntityPurchaseOrder, blanket.ID); err != nil {
+		return nil, err
+	}
+
+	prices := make(map[int]models.BlanketPriceItem, len(blanket.PriceList))
+	for _, item := range blanket.PriceList {
+		prices[item.ID] = item
+	}
+
+	shipTo := strings.TrimSpace(payload.ShipTo)
+	if shipTo == "" {
+		shipTo = blanket.ShipTo
+	}
+	po := &models.PurchaseOrder{
+		VendorID:  blanket.VendorID,
+		OrderDate: s.now(),
+		Status:    models.PurchaseOrderStatusIssued,
+		POType:    models.POTypeCallOff,
+		BlanketID: &blanket.ID,
+		Currency:  blanket.Currency,
+		ShipTo:    shipTo,
+		Lines:     make([]models.PurchaseOrderLine, len(payload.Lines)),
+	}
+	for i, line := range payload.Lines {
+		item, ok := prices[line.PriceItemID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownPriceItem, line.PriceItemID)
+		}
+		po.Lines[i] = models.PurchaseOrderLine{
+			LineNo:      i + 1,
+			Description: item.Description,
+			Quantity:    line.Quantity,
+			UnitPrice:   item.UnitPrice,
+			TotalPrice:  roundAmount(float64(line.Quantity) * item.UnitPrice),
+			PriceItemID: &item.ID,
+		}
+		if line.DeliveryDate != "" {
+			date, err := time.Parse("2006-01-02", line.DeliveryDate)
+			if err != nil {
+				return nil, fmt.Errorf("%w: delivery_date is not a date", ErrInvalidBlanket)
+			}
+			po.Lines[i].DeliveryDate = &date
+		}
+	}
+	if err := priceOrder(s.settings, po); err != nil {
+		return nil, err
+	}
+
+	// Checked here for a clear error; the repository enforces the ceiling
+	// against call-offs released at the same time.
+	before, err := s.poRepo.GetBlanketConsumptionByID(blanket.ID)
+	if err != nil {
+		return nil, err
+	}
+	if po.TotalAmount > before.RemainingAmount+0.005 {
+		return nil, fmt.Errorf("%w: %.2f %s remaining", repository.ErrBlanketCeilingExceeded, before.RemainingAmount, blanket.Currency)
+	}
+
+	po.PONumber, err = s.poRepo.GetNextPONumber()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.poRepo.CreatePurchaseOrder(po, &actorID); err != nil {
+		return nil, err
+	}
+
+	result := &models.CallOffResult{PurchaseOrder: po}
+	result.Blanket, err = s.poRepo.GetBlanketConsumptionByID(blanket.ID)
+	if err != nil {
+		return nil, err
+	}
+	if result.Blanket.PercentUsed >= s.warnPercent {
+		result.Warning = fmt.Sprintf("blanket %s is %.0f%% consumed; %.2f %s remaining", result.Blanket.PONumber, result.Blanket.PercentUsed, result.Blanket.RemainingAmount, result.Blanket.Currency)
+	}
+	return result, nil
+}
+
+// checkIssueDuties runs the segregation of duties rules for issuing a blanket
+// order or call-off to a vendor, which counts as approving a purchase order.
+func (s *blanketService) checkIssueDuties(actorID int, vendor *models.Vendor, entityType string, entityID int) error {
+	check := models.SoDCheck{
+		Duties:     []string{models.DutyPOApprove},
+		ActorID:    actorID,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Performers: map[string]int{},
+	}
+	if vendor.CreatedBy != nil {
+		check.Performers[models.DutyVendorCreate] = *vendor.CreatedBy
+	}
+	return s.sod.Check(check)
+}
+
+// GetBlanketConsumption reports how much of each blanket order's ceiling its
+// call-offs have used.
+func (s *blanketService) GetBlanketConsumption() ([]models.BlanketConsumption, error) {
+	return s.poRepo.GetBlanketConsumption()
+}
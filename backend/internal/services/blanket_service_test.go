@@ -0,0 +1,192 @@
+package services
+
+import (
+	"errors"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBlanketService(t *testing.T) {
+	const actorID = 7
+	today := time.Date(2026, 6, 15, 10, 0, 0, 0, time.UTC)
+	date := func(s string) *time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return &d
+	}
+	ceiling := 1000.0
+	newBlanket := func() *models.PurchaseOrder {
+		return &models.PurchaseOrder{
+			ID: 5, PONumber: "PO-2026-0005", VendorID: 3, Status: models.PurchaseOrderStatusIssued, POType: models.POTypeBlanket,
+			Currency: "MYR", ShipTo: "Warehouse A", ValidFrom: date("2026-01-01"), ValidTo: date("2026-12-31"), CeilingAmount: &ceiling,
+			PriceList: []models.BlanketPriceItem{
+				{ID: 11, BlanketID: 5, LineNo: 1, Description: "Toner", UnitPrice: 45},
+				{ID: 12, BlanketID: 5, LineNo: 2, Description: "Paper", UnitPrice: 12.5},
+			},
+		}
+	}
+	consumption := func(consumed float64) *models.BlanketConsumption {
+		return &models.BlanketConsumption{
+			BlanketID: 5, PONumber: "PO-2026-0005", Currency: "MYR", CeilingAmount: ceiling,
+			ConsumedAmount: consumed, RemainingAmount: ceiling - consumed, PercentUsed: consumed / ceiling * 100,
+		}
+	}
+
+	vendorCreatorID := 2
+	newServiceWithSoD := func(sod SoDService) (BlanketService, *MockPurchaseOrderRepository, *MockVendorRepository) {
+		poRepo := new(MockPurchaseOrderRepository)
+		vendorRepo := new(MockVendorRepository)
+		vendorRepo.On("GetVendorByID", 3).Return(&models.Vendor{ID: 3, CreatedBy: &vendorCreatorID}, nil)
+		mockLog := new(MockActivityLogService)
+		mockLog.On("Log", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+		mockDocuments := new(MockPODocumentService)
+		mockDocuments.On("ArchiveDocument", mock.Anything, mock.Anything, models.PODocumentIssued).Return(&models.PODocument{}, nil)
+		s := NewBlanketService(poRepo, vendorRepo, sod, newStubCompanySettings(&models.CompanySettings{TaxLabel: "Tax"}), mockDocuments, mockLog, DefaultBlanketWarningPercent)
+		s.(*blanketService).now = func() time.Time { return today }
+		return s, poRepo, vendorRepo
+	}
+	newService := func() (BlanketService, *MockPurchaseOrderRepository, *MockVendorRepository) {
+		return newServiceWithSoD(newPassingSoD())
+	}
+
+	t.Run("CreateBlanket - Stores Price List And Ceiling", func(t *testing.T) {
+		service, poRepo, _ := newService()
+		poRepo.On("GetNextPONumber").Return("PO-2026-0005", nil)
+		poRepo.On("CreatePurchaseOrder", mock.AnythingOfType("*models.PurchaseOrder"), mock.Anything).Return(nil).Once()
+
+		po, err := service.CreateBlanket(actorID, models.CreateBlanketPayload{
+			VendorID: 3, ValidFrom: "2026-01-01", ValidTo: "2026-12-31", CeilingAmount: 1000, Currency: " usd ",
+			PriceList: []models.BlanketPriceItemPayload{{Description: " Toner ", UnitPrice: 45}, {Description: "Paper", UnitPrice: 12.5}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, models.POTypeBlanket, po.POType)
+		assert.Equal(t, "USD", po.Currency)
+		assert.Equal(t, 0.0, po.TotalAmount)
+		assert.Equal(t, 1000.0, *po.CeilingAmount)
+		if assert.Len(t, po.PriceList, 2) {
+			assert.Equal(t, 1, po.PriceList[0].LineNo)
+			assert.Equal(t, "Toner", po.PriceList[0].Description)
+		}
+	})
+
+	t.Run("CreateBlanket - Rejects Bad Validity And Unknown Vendor", func(t *testing.T) {
+		service, poRepo, vendorRepo := newService()
+		vendorRepo.On("GetVendorByID", 9).Return(nil, repository.ErrVendorNotFound)
+
+		_, err := service.CreateBlanket(actorID, models.CreateBlanketPayload{VendorID: 3, ValidFrom: "2026-12-31", ValidTo: "2026-01-01", CeilingAmount: 10})
+		assert.True(t, errors.Is(err, ErrInvalidBlanket))
+		_, err = service.CreateBlanket(actorID, models.CreateBlanketPayload{VendorID: 9, ValidFrom: "2026-01-01", ValidTo: "2026-12-31", CeilingAmount: 10})
+		assert.True(t, errors.Is(err, ErrInvalidBlanket))
+		poRepo.AssertNotCalled(t, "CreatePurchaseOrder", mock.Anything, mock.Anything)
+	})
+
+	t.Run("CreateCallOff - Prices Lines From Price List", func(t *testing.T) {
+		service, poRepo, _ := newService()
+		poRepo.On("GetPurchaseOrderByID", 5).Return(newBlanket(), nil)
+		poRepo.On("GetBlanketConsumptionByID", 5).Return(consumption(100), nil).Once()
+		poRepo.On("GetBlanketConsumptionByID", 5).Return(consumption(290), nil).Once()
+		poRepo.On("GetNextPONumber").Return("PO-2026-0009", nil)
+		poRepo.On("CreatePurchaseOrder", mock.AnythingOfType("*models.PurchaseOrder"), mock.Anything).Return(nil).Once()
+
+		result, err := service.CreateCallOff(actorID, 5, models.CallOffPayload{Lines: []models.CallOffLinePayload{
+			{PriceItemID: 11, Quantity: 2},
+			{PriceItemID: 12, Quantity: 8, DeliveryDate: "2026-07-01"},
+		}})
+		assert.NoError(t, err)
+		po := result.PurchaseOrder
+		assert.Equal(t, models.POTypeCallOff, po.POType)
+		assert.Equal(t, 5, *po.BlanketID)
+		assert.Equal(t, 3, po.VendorID)
+		assert.Equal(t, "Warehouse A", po.ShipTo)
+		assert.Equal(t, 190.0, po.TotalAmount)
+		if assert.Len(t, po.Lines, 2) {
+			assert.Equal(t, "Paper", po.Lines[1].Description)
+			assert.Equal(t, 12, *po.Lines[1].PriceItemID)
+			assert.Equal(t, 100.0, po.Lines[1].TotalPrice)
+		}
+		assert.Empty(t, result.Warning)
+	})
+
+	t.Run("CreateCallOff - Warns Near Ceiling", func(t *testing.T) {
+		service, poRepo, _ := newService()
+		poRepo.On("GetPurchaseOrderByID", 5).Return(newBlanket(), nil)
+		poRepo.On("GetBlanketConsumptionByID", 5).Return(consumption(700), nil).Once()
+		poRepo.On("GetBlanketConsumptionByID", 5).Return(consumption(880), nil).Once()
+		poRepo.On("GetNextPONumber").Return("PO-2026-0009", nil)
+		poRepo.On("CreatePurchaseOrder", mock.AnythingOfType("*models.PurchaseOrder"), mock.Anything).Return(nil).Once()
+
+		result, err := service.CreateCallOff(actorID, 5, models.CallOffPayload{Lines: []models.CallOffLinePayload{{PriceItemID: 11, Quantity: 4}}})
+		assert.NoError(t, err)
+		assert.Contains(t, result.Warning, "88% consumed")
+		assert.Contains(t, result.Warning, "120.00 MYR remaining")
+	})
+
+	t.Run("CreateCallOff - Refuses To Exceed Ceiling", func(t *testing.T) {
+		service, poRepo, _ := newService()
+		poRepo.On("GetPurchaseOrderByID", 5).Return(newBlanket(), nil)
+		poRepo.On("GetBlanketConsumptionByID", 5).Return(consumption(950), nil)
+
+		_, err := service.CreateCallOff(actorID, 5, models.CallOffPayload{Lines: []models.CallOffLinePayload{{PriceItemID: 11, Quantity: 2}}})
+		assert.True(t, errors.Is(err, repository.ErrBlanketCeilingExceeded))
+		poRepo.AssertNotCalled(t, "CreatePurchaseOrder", mock.Anything, mock.Anything)
+	})
+
+	t.Run("CreateCallOff - Rejects Closed, Expired And Non-Blanket Orders", func(t *testing.T) {
+		service, poRepo, _ := newService()
+		closed := newBlanket()
+		closed.ID, closed.Status = 6, models.PurchaseOrderStatusClosed
+		expired := newBlanket()
+		expired.ID, expired.ValidTo = 7, date("2026-06-14")
+		standard := &models.PurchaseOrder{ID: 8, POType: models.POTypeStandard, Status: models.PurchaseOrderStatusIssued}
+		poRepo.On("GetPurchaseOrderByID", 6).Return(closed, nil)
+		poRepo.On("GetPurchaseOrderByID", 7).Return(expired, nil)
+		poRepo.On("GetPurchaseOrderByID", 8).Return(standard, nil)
+		payload := models.CallOffPayload{Lines: []models.CallOffLinePayload{{PriceItemID: 11, Quantity: 1}}}
+
+		_, err := service.CreateCallOff(actorID, 6, payload)
+		assert.True(t, errors.Is(err, ErrBlanketNotOpen))
+		_, err = service.CreateCallOff(actorID, 7, payload)
+		assert.True(t, errors.Is(err, ErrBlanketNotOpen))
+		_, err = service.CreateCallOff(actorID, 8, payload)
+		assert.True(t, errors.Is(err, repository.ErrBlanketNotFound))
+	})
+
+	t.Run("CreateCallOff - Rejects Items Not On Price List", func(t *testing.T) {
+		service, poRepo, _ := newService()
+		poRepo.On("GetPurchaseOrderByID", 5).Return(newBlanket(), nil)
+
+		_, err := service.CreateCallOff(actorID, 5, models.CallOffPayload{Lines: []models.CallOffLinePayload{{PriceItemID: 99, Quantity: 1}}})
+		assert.True(t, errors.Is(err, ErrUnknownPriceItem))
+		poRepo.AssertNotCalled(t, "CreatePurchaseOrder", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Vendor Creator Cannot Issue Blanket Or Call-Offs", func(t *testing.T) {
+		sodRepo := new(MockSoDRepository)
+		sodRepo.On("GetEnabledRules").Return([]models.SoDRule{
+			{ID: 2, Code: "vendor_creator_not_po_approver", Description: "The creator of a vendor cannot approve purchase orders to that vendor", FirstDuty: models.DutyVendorCreate, SecondDuty: models.DutyPOApprove},
+		}, nil)
+		// A blanket does not exist until it is issued, so its violation is
+		// recorded against the vendor; a call-off's against its blanket.
+		sodRepo.On("CreateViolation", mock.MatchedBy(func(v *models.SoDViolation) bool {
+			return v.Duty == models.DutyPOApprove && v.EntityType == models.EntityVendor && v.EntityID == 3
+		})).Return(nil).Once()
+		sodRepo.On("CreateViolation", mock.MatchedBy(func(v *models.SoDViolation) bool {
+			return v.Duty == models.DutyPOApprove && v.EntityType == models.EntityPurchaseOrder && v.EntityID == 5
+		})).Return(nil).Once()
+		mockLog := new(MockActivityLogService)
+		mockLog.On("Log", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+		service, poRepo, _ := newServiceWithSoD(NewSoDService(sodRepo, mockLog))
+		poRepo.On("GetPurchaseOrderByID", 5).Return(newBlanket(), nil)
+
+		_, err := service.CreateBlanket(vendorCreatorID, models.CreateBlanketPayload{VendorID: 3, ValidFrom: "2026-01-01", ValidTo: "2026-12-31", CeilingAmount: 1000})
+		assert.ErrorIs(t, err, ErrSoDViolation)
+		_, err = service.CreateCallOff(vendorCreatorID, 5, models.CallOffPayload{Lines: []models.CallOffLinePayload{{PriceItemID: 11, Quantity: 1}}})
+		assert.ErrorIs(t, err, ErrSoDViolation)
+		poRepo.AssertNotCalled(t, "CreatePurchaseOrder", mock.Anything, mock.Anything)
+		sodRepo.AssertExpectations(t)
+	})
+}
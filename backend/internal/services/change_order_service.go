@@ -0,0 +1,372 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPurchaseOrderNotChangeable = errors.New("only issued purchase orders can be changed")
+	ErrNoChanges                  = errors.New("change order does not change anything")
+	ErrUnknownPOLine              = errors.New("purchase order has no such line")
+	ErrDuplicatePOLine            = errors.New("a line may only be changed once per change order")
+	ErrChangeOrderNotPending      = errors.New("change order is not waiting for approval")
+	ErrChangeOrderStale           = errors.New("purchase order has been revised since the change was requested")
+)
+
+// ChangeOrderService changes issued purchase orders. Each applied change
+// order creates the next revision of the order. Changes that raise the
+// order's value by more than the approval threshold wait for someone with
+// po:approve; the rest are applied at once.
+type ChangeOrderService interface {
+	RequestChangeOrder(actorID int, poID int, payload models.ChangeOrderPayload, expectedVersion *int) (*models.ChangeOrder, error)
+	ApproveChangeOrder(actorID int, id int, reason string) (*models.ChangeOrder, error)
+	RejectChangeOrder(actorID int, id int, reason string) (*models.ChangeOrder, error)
+	GetChangeOrders(userID int, poID int) ([]models.ChangeOrder, error)
+	GetPendingChangeOrders() ([]models.ChangeOrder, error)
+}
+
+type changeOrderService struct {
+	repo          repository.ChangeOrderRepository
+	poRepo        repository.PurchaseOrderRepository
+	vendorRepo    repository.VendorRepository
+	userRepo      repository.UserRepository
+	access        EntityAccessService
+	sod           SoDService
+	notifications NotificationService
+	documents     PODocumentService
+	logService    ActivityLogService
+	threshold     float64
+}
+
+// NewChangeOrderService creates a new instance of ChangeOrderService. Change
+// orders raising an order's value by more than threshold need approval.
+func NewChangeOrderService(repo repository.ChangeOrderRepository, poRepo repository.PurchaseOrderRepository, vendorRepo repository.VendorRepository, userRepo repository.UserRepository, access EntityAccessService, sod SoDService, notifications NotificationService, documents PODocumentService, logService ActivityLogService, threshold float64) ChangeOrderService {
+	return &changeOrderService{
+		repo:          repo,
+		poRepo:        poRepo,
+		vendorRepo:    vendorRepo,
+		userRepo:      userRepo,
+		access:        access,
+		sod:           sod,
+		notifications: notifications,
+		documents:     documents,
+		logService:    logService,
+		threshold:     threshold,
+	}
+}
+
+// RequestChangeOrder works out the difference a change makes to an issued
+// purchase order. It is applied as the order's next revision, unless it
+// raises the order's value by more than the threshold, when it is kept as
+// Pending for approval. Only one change order per order may be pending.
+func (s *changeOrderService) RequestChangeOrder(actorID int, poID int, payload models.ChangeOrderPayload, expectedVersion *int) (*models.ChangeOrder, error) {
+	co, po, err := s.requestChangeOrder(actorID, poID, payload, expectedVersion)
+	if err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "REQUEST_CHANGE_ORDER_FAILED", Ptr("purchase_order"), &poID, "FAILED", &details)
+		return nil, err
+	}
+
+	details := fmt.Sprintf("Change order %d: total %.2f -> %.2f", co.ID, co.PreviousTotal, co.NewTotal)
+	if co.Status == models.ChangeOrderStatusPending {
+		details += ", awaiting approval"
+	} else {
+		details += fmt.Sprintf(", applied as revision %d", *co.Revision)
+		archivePODocument(s.documents, actorID, poID, models.PODocumentRevised)
+		s.notifyVendor(po, co)
+	}
+	s.logService.Log(&actorID, "REQUEST_CHANGE_ORDER_SUCCESS", Ptr("purchase_order"), &poID, "SUCCESS", &details)
+	return co, nil
+}
+
+func (s *changeOrderService) requestChangeOrder(actorID int, poID int, payload models.ChangeOrderPayload, expectedVersion *int) (*models.ChangeOrder, *models.PurchaseOrder, error) {
+	po, err := s.poRepo.GetPurchaseOrderByID(poID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkVersion(expectedVersion, po.Version); err != nil {
+		return nil, nil, err
+	}
+	if po.Status != models.PurchaseOrderStatusIssued {
+		return nil, nil, ErrPurchaseOrderNotChangeable
+	}
+
+	// A pending change order was worked out against the current revision;
+	// applying another first would make it stale.
+	existing, err := s.repo.GetChangeOrdersByPurchaseOrder(poID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, other := range existing {
+		if other.Status == models.ChangeOrderStatusPending {
+			return nil, nil, repository.ErrChangeOrderPending
+		}
+	}
+
+	changes, newTotal, err := diffLines(po, payload.Lines)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	co := &models.ChangeOrder{
+		PurchaseOrderID: po.ID,
+		BaseRevision:    po.Revision,
+		Reason:          strings.TrimSpace(payload.Reason),
+		Changes:         changes,
+		PreviousTotal:   po.TotalAmount,
+		NewTotal:        newTotal,
+		RequestedBy:     &actorID,
+	}
+	if newTotal-po.TotalAmount > s.threshold {
+		co.Status = models.ChangeOrderStatusPending
+		if err := s.repo.CreateChangeOrder(co); err != nil {
+			return nil, nil, err
+		}
+		return co, po, nil
+	}
+
+	if err := s.repo.ApplyChangeOrder(co, po.Version); err != nil {
+		return nil, nil, err
+	}
+	return co, po, nil
+}
+
+// ApproveChangeOrder applies a pending change order as the purchase order's
+// next revision. The user who requested the change may not approve it.
+func (s *changeOrderService) ApproveChangeOrder(actorID int, id int, reason string) (*models.ChangeOrder, error) {
+	co, po, err := s.approveChangeOrder(actorID, id, reason)
+	if err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "APPROVE_CHANGE_ORDER_FAILED", Ptr("change_order"), &id, "FAILED", &details)
+		return nil, err
+	}
+
+	details := fmt.Sprintf("Change order %d applied as revision %d: total %.2f -> %.2f", co.ID, *co.Revision, co.PreviousTotal, co.NewTotal)
+	s.logService.Log(&actorID, "APPROVE_CHANGE_ORDER_SUCCESS", Ptr("purchase_order"), &po.ID, "SUCCESS", &details)
+	archivePODocument(s.documents, actorID, po.ID, models.PODocumentRevised)
+	s.notifyVendor(po, co)
+	return co, nil
+}
+
+func (s *changeOrderService) approveChangeOrder(actorID int, id int, reason string) (*models.ChangeOrder, *models.PurchaseOrder, error) {
+	co, po, err := s.loadPending(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if po.Status != models.PurchaseOrderStatusIssued {
+		return nil, nil, ErrPurchaseOrderNotChangeable
+	}
+	if po.Revision != co.BaseRevision {
+		return nil, nil, ErrChangeOrderStale
+	}
+	if err := s.checkApprovalDuties(co, po, actorID); err != nil {
+		return nil, nil, err
+	}
+
+	co.DecidedBy = &actorID
+	co.DecisionReason = strings.TrimSpace(reason)
+	if err := s.repo.ApplyChangeOrder(co, po.Version); err != nil {
+		return nil, nil, err
+	}
+	return co, po, nil
+}
+
+// RejectChangeOrder turns down a pending change order, leaving the purchase
+// order as it is. A reason is required.
+func (s *changeOrderService) RejectChangeOrder(actorID int, id int, reason string) (*models.ChangeOrder, error) {
+	reason = strings.TrimSpace(reason)
+	co, _, err := s.loadPending(id)
+	if err == nil && reason == "" {
+		err = ErrReasonRequired
+	}
+	if err == nil {
+		co.DecidedBy = &actorID
+		co.DecisionReason = reason
+		err = s.repo.RejectChangeOrder(co)
+	}
+	if err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "REJECT_CHANGE_ORDER_FAILED", Ptr("change_order"), &id, "FAILED", &details)
+		return nil, err
+	}
+
+	details := fmt.Sprintf("Change order %d rejected: %s", co.ID, reason)
+	s.logService.Log(&actorID, "REJECT_CHANGE_ORDER_SUCCESS", Ptr("purchase_order"), &co.PurchaseOrderID, "SUCCESS", &details)
+	return co, nil
+}
+
+// GetChangeOrders returns a purchase order's change orders, oldest first, if
+// the user may see the order. Vendor portal users only see applied ones.
+func (s *changeOrderService) GetChangeOrders(userID int, poID int) ([]models.ChangeOrder, error) {
+	access, err := s.access.Access(userID, models.EntityPurchaseOrder, poID)
+	if err != nil {
+		return nil, err
+	}
+
+	changeOrders, err := s.repo.GetChangeOrdersByPurchaseOrder(poID)
+	if err != nil {
+		return nil, err
+	}
+	if !access.External {
+		return changeOrders, nil
+	}
+
+	applied := []models.ChangeOrder{}
+	for _, co := range changeOrders {
+		if co.Status == models.ChangeOrderStatusApplied {
+			applied = append(applied, co)
+		}
+	}
+	return applied, nil
+}
+
+// GetPendingChangeOrders returns every change order waiting for approval.
+func (s *changeOrderService) GetPendingChangeOrders() ([]models.ChangeOrder, error) {
+	return s.repo.GetPendingChangeOrders()
+}
+
+// loadPending returns a change order that is waiting for approval, with the
+// purchase order it changes.
+func (s *changeOrderService) loadPending(id int) (*models.ChangeOrder, *models.PurchaseOrder, error) {
+	co, err := s.repo.GetChangeOrderByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if co.Status != models.ChangeOrderStatusPending {
+		return nil, nil, ErrChangeOrderNotPending
+	}
+	po, err := s.poRepo.GetPurchaseOrderByID(co.PurchaseOrderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return co, po, nil
+}
+
+// checkApprovalDuties runs the segregation of duties rules for approving a
+// change order, which counts as approving the purchase order.
+func (s *changeOrderService) checkApprovalDuties(co *models.ChangeOrder, po *models.PurchaseOrder, approverID int) error {
+	check := models.SoDCheck{
+		Duties:     []string{models.DutyPOApprove},
+		ActorID:    approverID,
+		EntityType: models.EntityPurchaseOrder,
+		EntityID:   po.ID,
+		Performers: map[string]int{},
+	}
+	if co.RequestedBy != nil {
+		check.Performers[models.DutyPOChange] = *co.RequestedBy
+	}
+
+	vendor, err := s.vendorRepo.GetVendorByID(po.VendorID)
+	if err != nil {
+		return err
+	}
+	if vendor.CreatedBy != nil {
+		check.Performers[models.DutyVendorCreate] = *vendor.CreatedBy
+	}
+
+	return s.sod.Check(check)
+}
+
+// notifyVendor tells the vendor's portal users about a new revision. The
+// revision is already applied, so failures are only logged.
+func (s *changeOrderService) notifyVendor(po *models.PurchaseOrder, co *models.ChangeOrder) {
+	users, err := s.userRepo.GetUsersByVendor(po.VendorID)
+	if err != nil {
+		log.Printf("Failed to find users of vendor %d to notify of purchase order %d revision: %v", po.VendorID, po.ID, err)
+		return
+	}
+
+	entityType := models.EntityPurchaseOrder
+	message := fmt.Sprintf("Purchase order %s has been revised (revision %d)", po.PONumber, *co.Revision)
+	for _, user := range users {
+		if err := s.notifications.Notify(user.ID, models.NotificationPORevision, message, &entityType, &po.ID); err != nil {
+			log.Printf("Failed to notify user %d of purchase order %d revision: %v", user.ID, po.ID, err)
+		}
+	}
+}
+
+// diffLines applies the requested line changes to a copy of a purchase order
+// and returns the lines that actually change, with the order's new total at
+// its tax rate and rounding step.
+func diffLines(po *models.PurchaseOrder, requested []models.ChangeOrderLinePayload) ([]models.LineChange, float64, error) {
+	revised := *po
+	revised.Lines = append([]models.PurchaseOrderLine(nil), po.Lines...)
+	lines := make(map[int]*models.PurchaseOrderLine, len(revised.Lines))
+	for i := range revised.Lines {
+		lines[revised.Lines[i].LineNo] = &revised.Lines[i]
+	}
+
+	changes := []models.LineChange{}
+	seen := make(map[int]bool, len(requested))
+	for _, req := range requested {
+		line, ok := lines[req.LineNo]
+		if !ok {
+			return nil, 0, fmt.Errorf("%w: %d", ErrUnknownPOLine, req.LineNo)
+		}
+		if seen[req.LineNo] {
+			return nil, 0, fmt.Errorf("%w: line %d", ErrDuplicatePOLine, req.LineNo)
+		}
+		seen[req.LineNo] = true
+
+		change := models.LineChange{
+			LineNo:          line.LineNo,
+			Description:     line.Description,
+			OldQuantity:     line.Quantity,
+			NewQuantity:     line.Quantity,
+			OldUnitPrice:    line.UnitPrice,
+			NewUnitPrice:    line.UnitPrice,
+			OldDeliveryDate: line.DeliveryDate,
+			NewDeliveryDate: line.DeliveryDate,
+			OldTotal:        line.TotalPrice,
+		}
+		if req.Quantity != nil {
+			change.NewQuantity = *req.Quantity
+		}
+		if req.UnitPrice != nil {
+			change.NewUnitPrice = *req.UnitPrice
+		}
+		if req.DeliveryDate != nil {
+			change.NewDeliveryDate = nil
+			if *req.DeliveryDate != "" {
+				date, err := time.Parse("2006-01-02", *req.DeliveryDate)
+				if err != nil {
+					return nil, 0, err
+				}
+				change.NewDeliveryDate = &date
+			}
+		}
+		change.NewTotal = roundAmount(float64(change.NewQuantity) * change.NewUnitPrice)
+
+		if change.NewQuantity == change.OldQuantity && change.NewUnitPrice == change.OldUnitPrice &&
+			sameDate(change.NewDeliveryDate, change.OldDeliveryDate) {
+			continue
+		}
+		line.Quantity, line.UnitPrice = change.NewQuantity, change.NewUnitPrice
+		changes = append(changes, change)
+	}
+
+	if len(changes) == 0 {
+		return nil, 0, ErrNoChanges
+	}
+	return changes, revised.Totals().Grand, nil
+}
+
+// roundAmount rounds a money amount to cents.
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// sameDate reports whether two optional dates fall on the same day.
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"errors"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockChangeOrderRepository is a mock type for the ChangeOrderRepository
+type MockChangeOrderRepository struct {
+	mock.Mock
+}
+
+func (m *MockChangeOrderRepository) CreateChangeOrder(co *models.ChangeOrder) error {
+	args := m.Called(co)
+	return args.Error(0)
+}
+func (m *MockChangeOrderRepository) ApplyChangeOrder(co *models.ChangeOrder, poVersion int) error {
+	args := m.Called(co, poVersion)
+	if args.Error(0) == nil {
+		revision := co.BaseRevision + 1
+		co.Status = models.ChangeOrderStatusApplied
+		co.Revision = &revision
+	}
+	return args.Error(0)
+}
+func (m *MockChangeOrderRepository) RejectChangeOrder(co *models.ChangeOrder) error {
+	args := m.Called(co)
+	return args.Error(0)
+}
+func (m *MockChangeOrderRepository) GetChangeOrderByID(id int) (*models.ChangeOrder, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChangeOrder), args.Error(1)
+}
+func (m *MockChangeOrderRepository) GetChangeOrdersByPurchaseOrder(poID int) ([]models.ChangeOrder, error) {
+	args := m.Called(poID)
+	return args.Get(0).([]models.ChangeOrder), args.Error(1)
+}
+func (m *MockChangeOrderRepository) GetPendingChangeOrders() ([]models.ChangeOrder, error) {
+	args := m.Called()
+	return args.Get(0).([]models.ChangeOrder), args.Error(1)
+}
+func (m *MockChangeOrderRepository) GetAppliedChangeOrder(poID int, revision int) (*models.ChangeOrder, error) {
+	return nil, nil
+}
+
+func TestChangeOrderService(t *testing.T) {
+	requesterID, approverID, vendorCreatorID := 5, 7, 9
+
+	// newPO returns an issued order with two lines totalling 500.
+	newPO := func() *models.PurchaseOrder {
+		return &models.PurchaseOrder{
+			ID: 1, PONumber: "PO-2026-0001", VendorID: 3, Status: models.PurchaseOrderStatusIssued,
+			TotalAmount: 500, Revision: 0, Version: 4,
+			Lines: []models.PurchaseOrderLine{
+				{LineNo: 1, Description: "Pens", Quantity: 100, UnitPrice: 2, TotalPrice: 200},
+				{LineNo: 2, Description: "Paper", Quantity: 20, UnitPrice: 15, TotalPrice: 300},
+			},
+		}
+	}
+
+	type mocks struct {
+		repo          *MockChangeOrderRepository
+		poRepo        *MockPurchaseOrderRepository
+		vendorRepo    *MockVendorRepository
+		userRepo      *MockUserRepository
+		sod           *MockSoDService
+		notifications *MockNotificationService
+		documents     *MockPODocumentService
+		log           *MockActivityLogService
+	}
+	newService := func(threshold float64) (ChangeOrderService, *mocks) {
+		m := &mocks{
+			repo:          new(MockChangeOrderRepository),
+			poRepo:        new(MockPurchaseOrderRepository),
+			vendorRepo:    new(MockVendorRepository),
+			userRepo:      new(MockUserRepository),
+			sod:           new(MockSoDService),
+			notifications: new(MockNotificationService),
+			documents:     new(MockPODocumentService),
+			log:           new(MockActivityLogService),
+		}
+		m.log.On("Log", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+		m.userRepo.On("GetUsersByVendor", 3).Return([]models.User{{ID: 20}}, nil)
+		m.notifications.On("Notify", 20, models.NotificationPORevision, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		m.documents.On("ArchiveDocument", mock.Anything, 1, models.PODocumentRevised).Return(&models.PODocument{}, nil)
+		service := NewChangeOrderService(m.repo, m.poRepo, m.vendorRepo, m.userRepo, nil, m.sod, m.notifications, m.documents, m.log, threshold)
+		return service, m
+	}
+	intPtr := func(i int) *int { return &i }
+	strPtr := func(s string) *string { return &s }
+
+	t.Run("RequestChangeOrder - Within Threshold Applies Next Revision", func(t *testing.T) {
+		service, m := newService(100)
+		m.poRepo.On("GetPurchaseOrderByID", 1).Return(newPO(), nil)
+		m.repo.On("GetChangeOrdersByPurchaseOrder", 1).Return([]models.ChangeOrder{}, nil)
+		m.repo.On("ApplyChangeOrder", mock.AnythingOfType("*models.ChangeOrder"), 4).Return(nil).Once()
+
+		payload := models.ChangeOrderPayload{Reason: "More pens", Lines: []models.ChangeOrderLinePayload{
+			{LineNo: 1, Quantity: intPtr(150)},
+			{LineNo: 2, DeliveryDate: strPtr("2026-11-30")},
+		}}
+		co, err := service.RequestChangeOrder(requesterID, 1, payload, intPtr(4))
+		assert.NoError(t, err)
+		assert.Equal(t, models.ChangeOrderStatusApplied, co.Status)
+		assert.Equal(t, 1, *co.Revision)
+		assert.Equal(t, 600.0, co.NewTotal)
+		if assert.Len(t, co.Changes, 2) {
+			assert.Equal(t, 100, co.Changes[0].OldQuantity)
+			assert.Equal(t, 150, co.Changes[0].NewQuantity)
+			assert.Equal(t, 300.0, co.Changes[0].NewTotal)
+			assert.Nil(t, co.Changes[1].OldDeliveryDate)
+			assert.Equal(t, "2026-11-30", co.Changes[1].NewDeliveryDate.Format("2006-01-02"))
+		}
+		m.repo.AssertNotCalled(t, "CreateChangeOrder", mock.Anything)
+		m.notifications.AssertNumberOfCalls(t, "Notify", 1)
+	})
+
+	t.Run("RequestChangeOrder - Increase Beyond Threshold Waits For Approval", func(t *testing.T) {
+		service, m := newService(100)
+		m.poRepo.On("GetPurchaseOrderByID", 1).Return(newPO(), nil)
+		m.repo.On("GetChangeOrdersByPurchaseOrder", 1).Return([]models.ChangeOrder{}, nil)
+		m.repo.On("CreateChangeOrder", mock.AnythingOfType("*models.ChangeOrder")).Return(nil).Once()
+
+		payload := models.ChangeOrderPayload{Reason: "Price rise", Lines: []models.ChangeOrderLinePayload{
+			{LineNo: 2, UnitPrice: func() *float64 { f := 25.0; return &f }()},
+		}}
+		co, err := service.RequestChangeOrder(requesterID, 1, payload, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, models.ChangeOrderStatusPending, co.Status)
+		assert.Nil(t, co.Revision)
+		assert.Equal(t, 700.0, co.NewTotal)
+		m.repo.AssertNotCalled(t, "ApplyChangeOrder", mock.Anything, mock.Anything)
+		m.notifications.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("RequestChangeOrder - Rejects No-Op And Unknown Lines", func(t *testing.T) {
+		service, m := newService(100)
+		m.poRepo.On("GetPurchaseOrderByID", 1).Return(newPO(), nil)
+		m.repo.On("GetChangeOrdersByPurchaseOrder", 1).Return([]models.ChangeOrder{}, nil)
+
+		_, err := service.RequestChangeOrder(requesterID, 1, models.ChangeOrderPayload{Reason: "x", Lines: []models.ChangeOrderLinePayload{
+			{LineNo: 1, Quantity: intPtr(100)},
+		}}, nil)
+		assert.Equal(t, ErrNoChanges, err)
+
+		_, err = service.RequestChangeOrder(requesterID, 1, models.ChangeOrderPayload{Reason: "x", Lines: []models.ChangeOrderLinePayload{
+			{LineNo: 3, Quantity: intPtr(1)},
+		}}, nil)
+		assert.True(t, errors.Is(err, ErrUnknownPOLine))
+		m.log.AssertCalled(t, "Log", &requesterID, "REQUEST_CHANGE_ORDER_FAILED", mock.Anything, mock.Anything, "FAILED", mock.Anything)
+	})
+
+	t.Run("RequestChangeOrder - Stale If-Match", func(t *testing.T) {
+		service, m := newService(100)
+		m.poRepo.On("GetPurchaseOrderByID", 1).Return(newPO(), nil)
+
+		_, err := service.RequestChangeOrder(requesterID, 1, models.ChangeOrderPayload{}, intPtr(3))
+		assert.Equal(t, repository.ErrVersionConflict, err)
+	})
+
+	t.Run("RequestChangeOrder - Another Change Pending", func(t *testing.T) {
+		service, m := newService(100)
+		m.poRepo.On("GetPurchaseOrderByID", 1).Return(newPO(), nil)
+		m.repo.On("GetChangeOrdersByPurchaseOrder", 1).Return([]models.ChangeOrder{{ID: 8, Status: models.ChangeOrderStatusPending}}, nil)
+
+		_, err := service.RequestChangeOrder(requesterID, 1, models.ChangeOrderPayload{Reason: "x", Lines: []models.ChangeOrderLinePayload{
+			{LineNo: 1, Quantity: intPtr(101)},
+		}}, nil)
+		assert.Equal(t, repository.ErrChangeOrderPending, err)
+	})
+
+	pending := func() *models.ChangeOrder {
+		return &models.ChangeOrder{
+			ID: 8, PurchaseOrderID: 1, BaseRevision: 0, Status: models.ChangeOrderStatusPending, RequestedBy: &requesterID,
+			PreviousTotal: 500, NewTotal: 700,
+			Changes: []models.LineChange{{LineNo: 2, OldQuantity: 20, NewQuantity: 20, OldUnitPrice: 15, NewUnitPrice: 25, OldTotal: 300, NewTotal: 500}},
+		}
+	}
+
+	t.Run("ApproveChangeOrder - Applies And Notifies Vendor", func(t *testing.T) {
+		service, m := newService(100)
+		m.repo.On("GetChangeOrderByID", 8).Return(pending(), nil)
+		m.poRepo.On("GetPurchaseOrderByID", 1).Return(newPO(), nil)
+		m.vendorRepo.On("GetVendorByID", 3).Return(&models.Vendor{ID: 3, CreatedBy: &vendorCreatorID}, nil)
+		m.sod.On("Check", models.SoDCheck{
+			Duties:     []string{models.DutyPOApprove},
+			ActorID:    approverID,
+			EntityType: models.EntityPurchaseOrder,
+			EntityID:   1,
+			Performers: map[string]int{models.DutyPOChange: requesterID, models.DutyVendorCreate: vendorCreatorID},
+		}).Return(nil)
+		m.repo.On("ApplyChangeOrder", mock.AnythingOfType("*models.ChangeOrder"), 4).Return(nil).Once()
+
+		co, err := service.ApproveChangeOrder(approverID, 8, "")
+		assert.NoError(t, err)
+		assert.Equal(t, models.ChangeOrderStatusApplied, co.Status)
+		assert.Equal(t, approverID, *co.DecidedBy)
+		m.sod.AssertExpectations(t)
+		m.notifications.AssertCalled(t, "Notify", 20, models.NotificationPORevision, "Purchase order PO-2026-0001 has been revised (revision 1)", mock.Anything, mock.Anything)
+	})
+
+	t.Run("ApproveChangeOrder - Requester Blocked By SoD", func(t *testing.T) {
+		service, m := newService(100)
+		m.repo.On("GetChangeOrderByID", 8).Return(pending(), nil)
+		m.poRepo.On("GetPurchaseOrderByID", 1).Return(newPO(), nil)
+		m.vendorRepo.On("GetVendorByID", 3).Return(&models.Vendor{ID: 3}, nil)
+		m.sod.On("Check", mock.Anything).Return(ErrSoDViolation)
+
+		_, err := service.ApproveChangeOrder(requesterID, 8, "")
+		assert.True(t, errors.Is(err, ErrSoDViolation))
+		m.repo.AssertNotCalled(t, "ApplyChangeOrder", mock.Anything, mock.Anything)
+	})
+
+	t.Run("ApproveChangeOrder - Order Revised Since Request", func(t *testing.T) {
+		service, m := newService(100)
+		po := newPO()
+		po.Revision = 1
+		m.repo.On("GetChangeOrderByID", 8).Return(pending(), nil)
+		m.poRepo.On("GetPurchaseOrderByID", 1).Return(po, nil)
+
+		_, err := service.ApproveChangeOrder(approverID, 8, "")
+		assert.Equal(t, ErrChangeOrderStale, err)
+	})
+
+	t.Run("RejectChangeOrder - Reason Required", func(t *testing.T) {
+		service, m := newService(100)
+		m.repo.On("GetChangeOrderByID", 8).Return(pending(), nil)
+		m.poRepo.On("GetPurchaseOrderByID", 1).Return(newPO(), nil)
+
+		_, err := service.RejectChangeOrder(approverID, 8, "  ")
+		assert.Equal(t, ErrReasonRequired, err)
+		m.repo.AssertNotCalled(t, "RejectChangeOrder", mock.Anything)
+	})
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCommentEditWindowClosed  = errors.New("comments can only be changed shortly after they are posted")
+	ErrInvalidCommentVisibility = errors.New("vendor-visible comments are only allowed on purchase orders")
+	ErrInvalidCommentParent     = errors.New("replies must answer a top-level comment in the same thread with the same visibility")
+)
+
+// DefaultCommentEditWindow is how long authors may edit or delete their comments.
+const DefaultCommentEditWindow = 15 * time.Minute
+
+// mentionPattern matches a mention: @ followed by the user's email address.
+var mentionPattern = regexp.MustCompile(`@([A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+
+// CommentService defines the interface for comment threads on entities.
+type CommentService interface {
+	GetComments(userID int, entityType string, entityID int) ([]models.Comment, error)
+	CreateComment(userID int, entityType string, entityID int, payload models.CreateCommentPayload) (*models.Comment, error)
+	UpdateComment(userID int, id int, payload models.UpdateCommentPayload) (*models.Comment, error)
+	DeleteComment(userID int, id int) error
+}
+
+type commentService struct {
+	repo          repository.CommentRepository
+	userRepo      repository.UserRepository
+	access        EntityAccessService
+	roleService   RoleService
+	notifications NotificationService
+	logService    ActivityLogService
+	editWindow    time.Duration
+	now           func() time.Time
+}
+
+// NewCommentService creates a new instance of CommentService. Authors may
+// change their comments for editWindow after posting; moderators at any time.
+func NewCommentService(repo repository.CommentRepository, userRepo repository.UserRepository, access EntityAccessService, roleService RoleService, notifications NotificationService, logService ActivityLogService, editWindow time.Duration) CommentService {
+	return &commentService{
+		repo:          repo,
+		userRepo:      userRepo,
+		access:        access,
+		roleService:   roleService,
+		notifications: notifications,
+		logService:    logService,
+		editWindow:    editWindow,
+		now:           time.Now,
+	}
+}
+
+// GetComments returns an entity's thread as the user may see it. External
+// users only see vendor-visible comments.
+func (s *commentService) GetComments(userID int, entityType string, entityID int) ([]models.Comment, error) {
+	access, err := s.access.Access(userID, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetComments(entityType, entityID, access.External)
+}
+
+// CreateComment posts a comment and notifies the users it mentions.
+func (s *commentService) CreateComment(userID int, entityType string, entityID int, payload models.CreateCommentPayload) (*models.Comment, error) {
+	access, err := s.access.Access(userID, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	visibility := payload.Visibility
+	if payload.ParentID != nil {
+		parent, err := s.repo.GetCommentByID(*payload.ParentID)
+		if err != nil {
+			if errors.Is(err, repository.ErrCommentNotFound) {
+				return nil, ErrInvalidCommentParent
+			}
+			return nil, err
+		}
+		if parent.EntityType != entityType || parent.EntityID != entityID || parent.ParentID != nil ||
+			(visibility != "" && visibility != parent.Visibility) {
+			return nil, ErrInvalidCommentParent
+		}
+		visibility = parent.Visibility
+	}
+	visibility, err = resolveVisibility(visibility, entityType, access, ErrInvalidCommentVisibility)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &models.Comment{
+		EntityType: entityType,
+		EntityID:   entityID,
+		ParentID:   payload.ParentID,
+		AuthorID:   &userID,
+		Body:       strings.TrimSpace(payload.Body),
+		Visibility: visibility,
+	}
+	c.Mentions, err = s.resolveMentions(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateComment(c); err != nil {
+		details := err.Error()
+		s.logService.Log(&userID, "CREATE_COMMENT_FAILED", Ptr("comment"), nil, "FAILED", &details)
+		return nil, err
+	}
+
+	details := fmt.Sprintf("Commented on %s %d", entityType, entityID)
+	s.logService.Log(&userID, "CREATE_COMMENT_SUCCESS", Ptr("comment"), &c.ID, "SUCCESS", &details)
+	s.notifyMentions(userID, c, c.Mentions)
+	return c, nil
+}
+
+// UpdateComment changes a comment's body. Users mentioned for the first time
+// are notified.
+func (s *commentService) UpdateComment(userID int, id int, payload models.UpdateCommentPayload) (*models.Comment, error) {
+	c, err := s.getChangeableComment(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := make(map[int]bool, len(c.Mentions))
+	for _, mentioned := range c.Mentions {
+		previous[mentioned] = true
+	}
+
+	c.Body = strings.TrimSpace(payload.Body)
+	c.Mentions, err = s.resolveMentions(c)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	c.EditedAt = &now
+
+	if err := s.repo.UpdateComment(c); err != nil {
+		details := err.Error()
+		s.logService.Log(&userID, "UPDATE_COMMENT_FAILED", Ptr("comment"), &id, "FAILED", &details)
+		return nil, err
+	}
+
+	s.logService.Log(&userID, "UPDATE_COMMENT_SUCCESS", Ptr("comment"), &id, "SUCCESS", nil)
+	var added []int
+	for _, mentioned := range c.Mentions {
+		if !previous[mentioned] {
+			added = append(added, mentioned)
+		}
+	}
+	s.notifyMentions(userID, c, added)
+	return c, nil
+}
+
+// DeleteComment removes a comment's body, leaving a placeholder so replies
+// keep their place in the thread.
+func (s *commentService) DeleteComment(userID int, id int) error {
+	if _, err := s.getChangeableComment(userID, id); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteComment(id, s.now()); err != nil {
+		details := err.Error()
+		s.logService.Log(&userID, "DELETE_COMMENT_FAILED", Ptr("comment"), &id, "FAILED", &details)
+		return err
+	}
+
+	s.logService.Log(&userID, "DELETE_COMMENT_SUCCESS", Ptr("comment"), &id, "SUCCESS", nil)
+	return nil
+}
+
+// getChangeableComment loads a comment the user may edit or delete: their own
+// within the edit window, or any comment if they moderate comments.
+func (s *commentService) getChangeableComment(userID int, id int) (*models.Comment, error) {
+	c, err := s.repo.GetCommentByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if c.DeletedAt != nil {
+		return nil, repository.ErrCommentNotFound
+	}
+
+	permissions, err := s.roleService.GetUserPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+	if permissions[models.PermCommentModerate] {
+		return c, nil
+	}
+
+	if c.AuthorID == nil || *c.AuthorID != userID {
+		return nil, ErrForbidden
+	}
+	if s.now().Sub(c.CreatedAt) > s.editWindow {
+		return nil, ErrCommentEditWindowClosed
+	}
+	return c, nil
+}
+
+// resolveMentions finds the users mentioned in a comment's body. Unknown
+// addresses are left as plain text, and users who cannot see the comment are
+// not mentioned, so a mention never discloses a thread.
+func (s *commentService) resolveMentions(c *models.Comment) ([]int, error) {
+	mentions := []int{}
+	seen := make(map[int]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(c.Body, -1) {
+		user, err := s.userRepo.GetUserByEmail(strings.ToLower(match[1]))
+		if err != nil {
+			if errors.Is(err, repository.ErrUserNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		if seen[user.ID] {
+			continue
+		}
+		seen[user.ID] = true
+
+		access, err := s.access.Access(user.ID, c.EntityType, c.EntityID)
+		if err != nil {
+			if errors.Is(err, ErrForbidden) {
+				continue
+			}
+			return nil, err
+		}
+		if access.External && c.Visibility != models.CommentVisibilityVendor {
+			continue
+		}
+		mentions = append(mentions, user.ID)
+	}
+	return mentions, nil
+}
+
+// notifyMentions tells mentioned users about a comment. The comment is already
+// saved, so failures are only logged.
+func (s *commentService) notifyMentions(authorID int, c *models.Comment, userIDs []int) {
+	authorName := "Someone"
+	if author, err := s.userRepo.GetUserByID(authorID); err == nil {
+		authorName = author.Name
+	}
+
+	message := fmt.Sprintf("%s mentioned you in a comment on %s %d", authorName, strings.ReplaceAll(c.EntityType, "_", " "), c.EntityID)
+	for _, userID := range userIDs {
+		if userID == authorID {
+			continue
+		}
+		if err := s.notifications.Notify(userID, models.NotificationMention, message, &c.EntityType, &c.EntityID); err != nil {
+			log.Printf("Failed to notify user %d of mention in comment %d: %v", userID, c.ID, err)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockCommentRepository is a mock type for the CommentRepository
+type MockCommentRepository struct {
+	mock.Mock
+}
+
+func (m *MockCommentRepository) CreateComment(c *models.Comment) error {
+	args := m.Called(c)
+	return args.Error(0)
+}
+func (m *MockCommentRepository) GetCommentByID(id int) (*models.Comment, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Comment), args.Error(1)
+}
+func (m *MockCommentRepository) GetComments(entityType string, entityID int, vendorVisibleOnly bool) ([]models.Comment, error) {
+	args := m.Called(entityType, entityID, vendorVisibleOnly)
+	return args.Get(0).([]models.Comment), args.Error(1)
+}
+func (m *MockCommentRepository) UpdateComment(c *models.Comment) error {
+	args := m.Called(c)
+	return args.Error(0)
+}
+func (m *MockCommentRepository) DeleteComment(id int, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+// MockEntityAccessService is a mock type for the EntityAccessService
+type MockEntityAccessService struct {
+	mock.Mock
+}
+
+func (m *MockEntityAccessService) RegisterPolicy(entityType string, policy EntityAccessPolicy) {}
+func (m *MockEntityAccessService) Access(userID int, entityType string, entityID int) (*EntityAccess, error) {
+	args := m.Called(userID, entityType, entityID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*EntityAccess), args.Error(1)
+}
+
+// MockNotificationService is a mock type for the NotificationService
+type MockNotificationService struct {
+	mock.Mock
+}
+
+func (m *MockNotificationService) Notify(userID int, kind string, message string, entityType *string, entityID *int) error {
+	args := m.Called(userID, kind, message, entityType, entityID)
+	return args.Error(0)
+}
+func (m *MockNotificationService) GetMyNotifications(userID int, unreadOnly bool) ([]models.Notification, error) {
+	return nil, nil
+}
+func (m *MockNotificationService) CountUnread(userID int) (int, error) { return 0, nil }
+func (m *MockNotificationService) MarkRead(userID int, id int) error   { return nil }
+func (m *MockNotificationService) MarkAllRead(userID int) error        { return nil }
+
+func TestCommentService(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	type mocks struct {
+		repo          *MockCommentRepository
+		users         *MockUserRepository
+		access        *MockEntityAccessService
+		roles         *MockRoleService
+		notifications *MockNotificationService
+	}
+	newService := func() (CommentService, mocks) {
+		m := mocks{
+			repo:          new(MockCommentRepository),
+			users:         new(MockUserRepository),
+			access:        new(MockEntityAccessService),
+			roles:         new(MockRoleService),
+			notifications: new(MockNotificationService),
+		}
+		mockLog := new(MockActivityLogService)
+		mockLog.On("Log", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+		s := NewCommentService(m.repo, m.users, m.access, m.roles, m.notifications, mockLog, DefaultCommentEditWindow)
+		s.(*commentService).now = func() time.Time { return now }
+		return s, m
+	}
+
+	t.Run("CreateComment - Notifies Mentioned Users Who Can See It", func(t *testing.T) {
+		s, m := newService()
+		m.access.On("Access", 1, models.EntityRequisition, 10).Return(&EntityAccess{}, nil)
+		m.access.On("Access", 2, models.EntityRequisition, 10).Return(&EntityAccess{}, nil)
+		m.access.On("Access", 3, models.EntityRequisition, 10).Return(nil, ErrForbidden)
+		m.users.On("GetUserByEmail", "approver@example.com").Return(&models.User{ID: 2}, nil)
+		m.users.On("GetUserByEmail", "outsider@example.com").Return(&models.User{ID: 3}, nil)
+		m.users.On("GetUserByEmail", "nobody@example.com").Return(nil, repository.ErrUserNotFound)
+		m.users.On("GetUserByID", 1).Return(&models.User{ID: 1, Name: "Alice"}, nil)
+		m.repo.On("CreateComment", mock.AnythingOfType("*models.Comment")).Return(nil)
+		m.notifications.On("Notify", 2, models.NotificationMention, "Alice mentioned you in a comment on requisition 10", mock.Anything, mock.Anything).Return(nil)
+
+		c, err := s.CreateComment(1, models.EntityRequisition, 10, models.CreateCommentPayload{
+			Body: "@Approver@example.com and @outsider@example.com, please check. cc @nobody@example.com",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, models.CommentVisibilityInternal, c.Visibility)
+		assert.Equal(t, []int{2}, c.Mentions)
+		m.notifications.AssertNumberOfCalls(t, "Notify", 1)
+	})
+
+	t.Run("CreateComment - Vendor Visibility Only On Purchase Orders", func(t *testing.T) {
+		s, m := newService()
+		m.access.On("Access", 1, models.EntityRequisition, 10).Return(&EntityAccess{}, nil)
+
+		_, err := s.CreateComment(1, models.EntityRequisition, 10, models.CreateCommentPayload{Body: "Hi", Visibility: models.CommentVisibilityVendor})
+		assert.Equal(t, ErrInvalidCommentVisibility, err)
+		m.repo.AssertNotCalled(t, "CreateComment", mock.Anything)
+	})
+
+	t.Run("CreateComment - External Users Post Vendor-Visible Comments", func(t *testing.T) {
+		s, m := newService()
+		m.access.On("Access", 7, models.EntityPurchaseOrder, 5).Return(&EntityAccess{External: true}, nil)
+		m.users.On("GetUserByID", 7).Return(&models.User{ID: 7, Name: "Vendor Rep"}, nil)
+		m.repo.On("CreateComment", mock.AnythingOfType("*models.Comment")).Return(nil)
+
+		c, err := s.CreateComment(7, models.EntityPurchaseOrder, 5, models.CreateCommentPayload{Body: "Shipping Friday"})
+		assert.NoError(t, err)
+		assert.Equal(t, models.CommentVisibilityVendor, c.Visibility)
+
+		_, err = s.CreateComment(7, models.EntityPurchaseOrder, 5, models.CreateCommentPayload{Body: "Psst", Visibility: models.CommentVisibilityInternal})
+		assert.Equal(t, ErrForbidden, err)
+	})
+
+	t.Run("CreateComment - Internal Mentions Skip External Users", func(t *testing.T) {
+		s, m := newService()
+		m.access.On("Access", 1, models.EntityPurchaseOrder, 5).Return(&EntityAccess{}, nil)
+		m.access.On("Access", 7, models.EntityPurchaseOrder, 5).Return(&EntityAccess{External: true}, nil)
+		m.users.On("GetUserByEmail", "rep@vendor.example").Return(&models.User{ID: 7}, nil)
+		m.users.On("GetUserByID", 1).Return(&models.User{ID: 1, Name: "Alice"}, nil)
+		m.repo.On("CreateComment", mock.AnythingOfType("*models.Comment")).Return(nil)
+
+		c, err := s.CreateComment(1, models.EntityPurchaseOrder, 5, models.CreateCommentPayload{Body: "Don't tell @rep@vendor.example yet"})
+		assert.NoError(t, err)
+		assert.Empty(t, c.Mentions)
+		m.notifications.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("GetComments - External Users See Vendor-Visible Only", func(t *testing.T) {
+		s, m := newService()
+		m.access.On("Access", 7, models.EntityPurchaseOrder, 5).Return(&EntityAccess{External: true}, nil)
+		m.repo.On("GetComments", models.EntityPurchaseOrder, 5, true).Return([]models.Comment{}, nil)
+
+		_, err := s.GetComments(7, models.EntityPurchaseOrder, 5)
+		assert.NoError(t, err)
+		m.repo.AssertExpectations(t)
+	})
+
+	t.Run("UpdateComment - Edit Window Closed", func(t *testing.T) {
+		s, m := newService()
+		m.repo.On("GetCommentByID", 3).Return(&models.Comment{ID: 3, AuthorID: intPtr(1), CreatedAt: now.Add(-time.Hour)}, nil)
+		m.roles.On("GetUserPermissions", 1).Return(map[string]bool{}, nil)
+
+		_, err := s.UpdateComment(1, 3, models.UpdateCommentPayload{Body: "Changed my mind"})
+		assert.Equal(t, ErrCommentEditWindowClosed, err)
+		m.repo.AssertNotCalled(t, "UpdateComment", mock.Anything)
+	})
+
+	t.Run("UpdateComment - Notifies Only New Mentions", func(t *testing.T) {
+		s, m := newService()
+		m.repo.On("GetCommentByID", 3).Return(&models.Comment{
+			ID: 3, EntityType: models.EntityRequisition, EntityID: 10, AuthorID: intPtr(1),
+			Visibility: models.CommentVisibilityInternal, Mentions: []int{2}, CreatedAt: now.Add(-time.Minute),
+		}, nil)
+		m.roles.On("GetUserPermissions", 1).Return(map[string]bool{}, nil)
+		m.access.On("Access", 2, models.EntityRequisition, 10).Return(&EntityAccess{}, nil)
+		m.access.On("Access", 4, models.EntityRequisition, 10).Return(&EntityAccess{}, nil)
+		m.users.On("GetUserByEmail", "bob@example.com").Return(&models.User{ID: 2}, nil)
+		m.users.On("GetUserByEmail", "carol@example.com").Return(&models.User{ID: 4}, nil)
+		m.users.On("GetUserByID", 1).Return(&models.User{ID: 1, Name: "Alice"}, nil)
+		m.repo.On("UpdateComment", mock.AnythingOfType("*models.Comment")).Return(nil)
+		m.notifications.On("Notify", 4, models.NotificationMention, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		c, err := s.UpdateComment(1, 3, models.UpdateCommentPayload{Body: "@bob@example.com @carol@example.com"})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 4}, c.Mentions)
+		assert.Equal(t, now, *c.EditedAt)
+		m.notifications.AssertNumberOfCalls(t, "Notify", 1)
+	})
+
+	t.Run("DeleteComment - Moderator After Window", func(t *testing.T) {
+		s, m := newService()
+		m.repo.On("GetCommentByID", 3).Return(&models.Comment{ID: 3, AuthorID: intPtr(1), CreatedAt: now.Add(-24 * time.Hour)}, nil)
+		m.roles.On("GetUserPermissions", 9).Return(map[string]bool{models.PermCommentModerate: true}, nil)
+		m.repo.On("DeleteComment", 3, now).Return(nil)
+
+		assert.NoError(t, s.DeleteComment(9, 3))
+		m.repo.AssertExpectations(t)
+	})
+
+	t.Run("DeleteComment - Not The Author", func(t *testing.T) {
+		s, m := newService()
+		m.repo.On("GetCommentByID", 3).Return(&models.Comment{ID: 3, AuthorID: intPtr(1), CreatedAt: now}, nil)
+		m.roles.On("GetUserPermissions", 2).Return(map[string]bool{}, nil)
+
+		assert.Equal(t, ErrForbidden, s.DeleteComment(2, 3))
+	})
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"strings"
+)
+
+var (
+	ErrNoLogo             = errors.New("no logo has been uploaded")
+	ErrLogoTooLarge       = errors.New("logo is too large")
+	ErrLogoTypeNotAllowed = errors.New("logo must be a PNG, JPEG or GIF image")
+)
+
+// MaxLogoSize is the largest logo accepted, in bytes.
+const MaxLogoSize = 1 << 20
+
+// CompanySettingsService manages the company profile and branding printed on
+// purchase orders. Every change is kept as a new version.
+type CompanySettingsService interface {
+	GetCompanySettings() (*models.CompanySettings, error)
+	GetSettingsHistory() ([]models.CompanySettings, error)
+	UpdateCompanySettings(actorID int, payload models.CompanySettingsPayload, expectedVersion *int) (*models.CompanySettings, error)
+	SetLogo(actorID int, content io.Reader, expectedVersion *int) (*models.CompanySettings, error)
+	RemoveLogo(actorID int, expectedVersion *int) (*models.CompanySettings, error)
+	GetLogo() (*models.CompanyLogo, error)
+}
+
+type companySettingsService struct {
+	repo       repository.CompanySettingsRepository
+	logService ActivityLogService
+}
+
+// NewCompanySettingsService creates a new instance of CompanySettingsService.
+func NewCompanySettingsService(repo repository.CompanySettingsRepository, logService ActivityLogService) CompanySettingsService {
+	return &companySettingsService{repo: repo, logService: logService}
+}
+
+// GetCompanySettings returns the settings in use.
+func (s *companySettingsService) GetCompanySettings() (*models.CompanySettings, error) {
+	return s.repo.GetCurrentSettings()
+}
+
+// GetSettingsHistory returns every version of the settings, newest first.
+func (s *companySettingsService) GetSettingsHistory() ([]models.CompanySettings, error) {
+	return s.repo.GetSettingsHistory()
+}
+
+// UpdateCompanySettings replaces the company profile, keeping the logo. An
+// empty layout or tax label gets the default.
+func (s *companySettingsService) UpdateCompanySettings(actorID int, payload models.CompanySettingsPayload, expectedVersion *int) (*models.CompanySettings, error) {
+	return s.change(actorID, expectedVersion, "UPDATE_COMPANY_SETTINGS", func(next *models.CompanySettings) error {
+		next.CompanyName = strings.TrimSpace(payload.CompanyName)
+		next.RegNo = strings.TrimSpace(payload.RegNo)
+		next.TinNo = strings.TrimSpace(payload.TinNo)
+		next.MsicCode = strings.TrimSpace(payload.MsicCode)
+		next.Address = strings.TrimSpace(payload.Address)
+		next.Phones = strings.TrimSpace(payload.Phones)
+		next.Email = strings.TrimSpace(payload.Email)
+		next.BankName = strings.TrimSpace(payload.BankName)
+		next.BankAccount = strings.TrimSpace(payload.BankAccount)
+		next.PaymentTerms = strings.TrimSpace(payload.PaymentTerms)
+		next.PDFFooter = strings.TrimSpace(payload.PDFFooter)
+		next.PDFLayout = strings.TrimSpace(payload.PDFLayout)
+		if next.PDFLayout == "" {
+			next.PDFLayout = DefaultPDFLayout
+		}
+		if err := validatePDFLayout(next.PDFLayout); err != nil {
+			return err
+		}
+		next.TaxLabel = strings.TrimSpace(payload.TaxLabel)
+		if next.TaxLabel == "" {
+			next.TaxLabel = "Tax"
+		}
+		next.TaxRate = payload.TaxRate
+		next.RoundingStep = payload.RoundingStep
+		return nil
+	})
+}
+
+// SetLogo stores a new logo. The image type is detected from the content and
+// the image must decode, so that it can be embedded in PDFs.
+func (s *companySettingsService) SetLogo(actorID int, content io.Reader, expectedVersion *int) (*models.CompanySettings, error) {
+	return s.change(actorID, expectedVersion, "SET_COMPANY_LOGO", func(next *models.CompanySettings) error {
+		data, err := io.ReadAll(io.LimitReader(content, MaxLogoSize+1))
+		if err != nil {
+			return err
+		}
+		if len(data) > MaxLogoSize {
+			return ErrLogoTooLarge
+		}
+		contentType := http.DetectContentType(data)
+		switch contentType {
+		case "image/png", "image/jpeg", "image/gif":
+		default:
+			return fmt.Errorf("%w: %s", ErrLogoTypeNotAllowed, contentType)
+		}
+		if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+			return fmt.Errorf("%w: %v", ErrLogoTypeNotAllowed, err)
+		}
+
+		sum := sha256.Sum256(data)
+		logo := &models.CompanyLogo{ContentType: contentType, Data: data, SHA256: hex.EncodeToString(sum[:])}
+		if err := s.repo.SaveLogo(logo); err != nil {
+			return err
+		}
+		next.LogoID = &logo.ID
+		return nil
+	})
+}
+
+// RemoveLogo stops printing a logo.
+func (s *companySettingsService) RemoveLogo(actorID int, expectedVersion *int) (*models.CompanySettings, error) {
+	return s.change(actorID, expectedVersion, "REMOVE_COMPANY_LOGO", func(next *models.CompanySettings) error {
+		if next.LogoID == nil {
+			return ErrNoLogo
+		}
+		next.LogoID = nil
+		return nil
+	})
+}
+
+// GetLogo returns the logo in use.
+func (s *companySettingsService) GetLogo() (*models.CompanyLogo, error) {
+	settings, err := s.repo.GetCurrentSettings()
+	if err != nil {
+		return nil, err
+	}
+	if settings.LogoID == nil {
+		return nil, ErrNoLogo
+	}
+	return s.repo.GetLogo(*settings.LogoID)
+}
+
+// change stores the next version of the settings, as edited by apply, and
+// logs the outcome under action.
+func (s *companySettingsService) change(actorID int, expectedVersion *int, action string, apply func(next *models.CompanySettings) error) (*models.CompanySettings, error) {
+	current, err := s.repo.GetCurrentSettings()
+	if err == nil {
+		err = checkVersion(expectedVersion, current.Version)
+	}
+
+	var next models.CompanySettings
+	if err == nil {
+		next = *current
+		next.Version = current.Version + 1
+		next.ChangedBy = &actorID
+		err = apply(&next)
+	}
+	if err == nil {
+		err = s.repo.CreateSettingsVersion(&next)
+	}
+	if err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, action+"_FAILED", Ptr("company_settings"), nil, "FAILED", &details)
+		return nil, err
+	}
+
+	details := fmt.Sprintf("Company settings version %d", next.Version)
+	s.logService.Log(&actorID, action+"_SUCCESS", Ptr("company_settings"), &next.Version, "SUCCESS", &details)
+	return &next, nil
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockCompanySettingsRepository is a mock type for the CompanySettingsRepository
+type MockCompanySettingsRepository struct {
+	mock.Mock
+}
+
+func (m *MockCompanySettingsRepository) GetCurrentSettings() (*models.CompanySettings, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CompanySettings), args.Error(1)
+}
+func (m *MockCompanySettingsRepository) GetSettingsHistory() ([]models.CompanySettings, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.CompanySettings), args.Error(1)
+}
+func (m *MockCompanySettingsRepository) CreateSettingsVersion(settings *models.CompanySettings) error {
+	args := m.Called(settings)
+	return args.Error(0)
+}
+func (m *MockCompanySettingsRepository) SaveLogo(logo *models.CompanyLogo) error {
+	args := m.Called(logo)
+	logo.ID = 9
+	return args.Error(0)
+}
+func (m *MockCompanySettingsRepository) GetLogo(id int) (*models.CompanyLogo, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CompanyLogo), args.Error(1)
+}
+
+// newStubCompanySettings returns a company settings service whose current
+// settings are settings.
+func newStubCompanySettings(settings *models.CompanySettings) CompanySettingsService {
+	repo := new(MockCompanySettingsRepository)
+	repo.On("GetCurrentSettings").Return(settings, nil)
+	mockLog := new(MockActivityLogService)
+	mockLog.On("Log", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	return NewCompanySettingsService(repo, mockLog)
+}
+
+func TestCompanySettingsService(t *testing.T) {
+	const adminID = 1
+	logoID := 4
+	current := func() *models.CompanySettings {
+		return &models.CompanySettings{Version: 3, CompanyName: "Procurement Corp", PaymentTerms: "NET 30", LogoID: &logoID}
+	}
+	newService := func() (CompanySettingsService, *MockCompanySettingsRepository) {
+		repo := new(MockCompanySettingsRepository)
+		mockLog := new(MockActivityLogService)
+		mockLog.On("Log", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+		repo.On("GetCurrentSettings").Return(current(), nil)
+		return NewCompanySettingsService(repo, mockLog), repo
+	}
+	intPtr := func(i int) *int { return &i }
+
+	t.Run("UpdateCompanySettings - Defaults Layout And Tax Label", func(t *testing.T) {
+		service, repo := newService()
+		repo.On("CreateSettingsVersion", mock.AnythingOfType("*models.CompanySettings")).Return(nil).Once()
+
+		settings, err := service.UpdateCompanySettings(adminID, models.CompanySettingsPayload{CompanyName: "Acme", TaxRate: 8, RoundingStep: 0.05}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultPDFLayout, settings.PDFLayout)
+		assert.Equal(t, "Tax", settings.TaxLabel)
+		assert.Equal(t, 8.0, settings.TaxRate)
+		assert.Equal(t, 0.05, settings.RoundingStep)
+	})
+
+	t.Run("UpdateCompanySettings - Unknown Layout", func(t *testing.T) {
+		service, repo := newService()
+
+		_, err := service.UpdateCompanySettings(adminID, models.CompanySettingsPayload{CompanyName: "Acme", PDFLayout: "fancy"}, nil)
+		assert.ErrorIs(t, err, ErrUnknownPDFLayout)
+		repo.AssertNotCalled(t, "CreateSettingsVersion", mock.Anything)
+	})
+
+	t.Run("UpdateCompanySettings - Saves Next Version Keeping Logo", func(t *testing.T) {
+		service, repo := newService()
+		repo.On("CreateSettingsVersion", mock.AnythingOfType("*models.CompanySettings")).Return(nil).Once()
+
+		settings, err := service.UpdateCompanySettings(adminID, models.CompanySettingsPayload{CompanyName: " Acme Sdn Bhd ", PaymentTerms: "NET 45", PDFFooter: "Thank you"}, intPtr(3))
+		assert.NoError(t, err)
+		assert.Equal(t, 4, settings.Version)
+		assert.Equal(t, "Acme Sdn Bhd", settings.CompanyName)
+		assert.Equal(t, "NET 45", settings.PaymentTerms)
+		assert.Equal(t, &logoID, settings.LogoID)
+		assert.Equal(t, adminID, *settings.ChangedBy)
+	})
+
+	t.Run("UpdateCompanySettings - Stale Version", func(t *testing.T) {
+		service, repo := newService()
+
+		_, err := service.UpdateCompanySettings(adminID, models.CompanySettingsPayload{CompanyName: "Acme"}, intPtr(2))
+		assert.Equal(t, repository.ErrVersionConflict, err)
+		repo.AssertNotCalled(t, "CreateSettingsVersion", mock.Anything)
+	})
+
+	t.Run("SetLogo - Stores PNG", func(t *testing.T) {
+		service, repo := newService()
+		repo.On("SaveLogo", mock.AnythingOfType("*models.CompanyLogo")).Return(nil).Once()
+		repo.On("CreateSettingsVersion", mock.AnythingOfType("*models.CompanySettings")).Return(nil).Once()
+		var img bytes.Buffer
+		assert.NoError(t, png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 4, 2))))
+
+		settings, err := service.SetLogo(adminID, &img, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 9, *settings.LogoID)
+		logo := repo.Calls[1].Arguments.Get(0).(*models.CompanyLogo)
+		assert.Equal(t, "image/png", logo.ContentType)
+		assert.Len(t, logo.SHA256, 64)
+	})
+
+	t.Run("SetLogo - Rejects Other Types", func(t *testing.T) {
+		service, repo := newService()
+
+		_, err := service.SetLogo(adminID, strings.NewReader("<svg xmlns='http://www.w3.org/2000/svg'></svg>"), nil)
+		assert.True(t, errors.Is(err, ErrLogoTypeNotAllowed))
+		_, err = service.SetLogo(adminID, bytes.NewReader(append([]byte("\x89PNG\r\n\x1a\n"), 0, 0)), nil)
+		assert.True(t, errors.Is(err, ErrLogoTypeNotAllowed))
+		_, err = service.SetLogo(adminID, bytes.NewReader(make([]byte, MaxLogoSize+1)), nil)
+		assert.Equal(t, ErrLogoTooLarge, err)
+		repo.AssertNotCalled(t, "SaveLogo", mock.Anything)
+	})
+
+	t.Run("RemoveLogo", func(t *testing.T) {
+		service, repo := newService()
+		repo.On("CreateSettingsVersion", mock.AnythingOfType("*models.CompanySettings")).Return(nil).Once()
+
+		settings, err := service.RemoveLogo(adminID, nil)
+		assert.NoError(t, err)
+		assert.Nil(t, settings.LogoID)
+		assert.Equal(t, 4, settings.Version)
+	})
+}
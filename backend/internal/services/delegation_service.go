@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidDelegation       = errors.New("a delegation must be to another user and end in the future")
+	ErrNotAnApprover           = errors.New("user does not hold requisition:approve")
+	ErrDelegationLimitExceeded = errors.New("requisition exceeds the amount delegated to this user")
+)
+
+// DelegationService defines the interface for approval delegations, which let
+// a substitute approve on an approver's behalf while they are away.
+type DelegationService interface {
+	CreateDelegation(actorID int, payload models.CreateDelegationPayload) (*models.Delegation, error)
+	AdminCreateDelegation(actorID int, payload models.CreateDelegationPayload) (*models.Delegation, error)
+	GetMyDelegations(userID int) ([]models.Delegation, error)
+	GetAllDelegations() ([]models.Delegation, error)
+	GetActiveDelegationsTo(delegateID int) ([]models.Delegation, error)
+	DeleteDelegation(actorID int, id int) error
+	AdminDeleteDelegation(actorID int, id int) error
+}
+
+type delegationService struct {
+	repo        repository.DelegationRepository
+	userRepo    repository.UserRepository
+	roleService RoleService
+	logService  ActivityLogService
+	now         func() time.Time
+}
+
+// NewDelegationService creates a new instance of DelegationService.
+func NewDelegationService(repo repository.DelegationRepository, userRepo repository.UserRepository, roleService RoleService, logService ActivityLogService) DelegationService {
+	return &delegationService{repo: repo, userRepo: userRepo, roleService: roleService, logService: logService, now: time.Now}
+}
+
+// CreateDelegation lets an approver hand their approval authority to another
+// user for a period.
+func (s *delegationService) CreateDelegation(actorID int, payload models.CreateDelegationPayload) (*models.Delegation, error) {
+	return s.createDelegation(actorID, actorID, payload)
+}
+
+// AdminCreateDelegation registers a delegation on behalf of any approver,
+// defaulting to the actor when no delegator is given.
+func (s *delegationService) AdminCreateDelegation(actorID int, payload models.CreateDelegationPayload) (*models.Delegation, error) {
+	delegatorID := actorID
+	if payload.DelegatorID != nil {
+		delegatorID = *payload.DelegatorID
+	}
+	return s.createDelegation(actorID, delegatorID, payload)
+}
+
+func (s *delegationService) createDelegation(actorID, delegatorID int, payload models.CreateDelegationPayload) (*models.Delegation, error) {
+	if delegatorID == payload.DelegateID || !payload.EndsAt.After(payload.StartsAt) || !payload.EndsAt.After(s.now()) {
+		return nil, ErrInvalidDelegation
+	}
+
+	permissions, err := s.roleService.GetUserPermissions(delegatorID)
+	if err != nil {
+		return nil, err
+	}
+	if !permissions[models.PermRequisitionApprove] {
+		return nil, ErrNotAnApprover
+	}
+
+	if _, err := s.userRepo.GetUserByID(payload.DelegateID); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrUnknownUser
+		}
+		return nil, err
+	}
+
+	d := &models.Delegation{
+		DelegatorID: delegatorID,
+		DelegateID:  payload.DelegateID,
+		StartsAt:    payload.StartsAt,
+		EndsAt:      payload.EndsAt,
+		MaxAmount:   payload.MaxAmount,
+		Reason:      strings.TrimSpace(payload.Reason),
+		CreatedBy:   &actorID,
+	}
+	if err := s.repo.CreateDelegation(d); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "CREATE_DELEGATION_FAILED", Ptr("delegation"), nil, "FAILED", &details)
+		return nil, err
+	}
+
+	details := fmt.Sprintf("User %d delegated approvals to user %d from %s to %s",
+		d.DelegatorID, d.DelegateID, d.StartsAt.Format(time.RFC3339), d.EndsAt.Format(time.RFC3339))
+	if d.MaxAmount != nil {
+		details += fmt.Sprintf(" up to %.2f", *d.MaxAmount)
+	}
+	s.logService.Log(&actorID, "CREATE_DELEGATION_SUCCESS", Ptr("delegation"), &d.ID, "SUCCESS", &details)
+	return d, nil
+}
+
+// GetMyDelegations returns current and upcoming delegations the user gave or received.
+func (s *delegationService) GetMyDelegations(userID int) ([]models.Delegation, error) {
+	return s.repo.GetDelegationsForUser(userID, s.now())
+}
+
+// GetAllDelegations returns every current and upcoming delegation.
+func (s *delegationService) GetAllDelegations() ([]models.Delegation, error) {
+	return s.repo.GetAllDelegations(s.now())
+}
+
+// GetActiveDelegationsTo returns the delegations the user can act on right now.
+func (s *delegationService) GetActiveDelegationsTo(delegateID int) ([]models.Delegation, error) {
+	return s.repo.GetActiveDelegationsTo(delegateID, s.now())
+}
+
+// DeleteDelegation lets the delegator withdraw a delegation.
+func (s *delegationService) DeleteDelegation(actorID int, id int) error {
+	d, err := s.repo.GetDelegationByID(id)
+	if err != nil {
+		return err
+	}
+	if d.DelegatorID != actorID {
+		return ErrForbidden
+	}
+	return s.AdminDeleteDelegation(actorID, id)
+}
+
+// AdminDeleteDelegation withdraws any delegation.
+func (s *delegationService) AdminDeleteDelegation(actorID int, id int) error {
+	if err := s.repo.DeleteDelegation(id); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "DELETE_DELEGATION_FAILED", Ptr("delegation"), &id, "FAILED", &details)
+		return err
+	}
+
+	s.logService.Log(&actorID, "DELETE_DELEGATION_SUCCESS", Ptr("delegation"), &id, "SUCCESS", nil)
+	return nil
+}
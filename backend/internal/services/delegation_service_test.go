@@ -0,0 +1,129 @@
+package services
+
+import (
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockDelegationRepository is a mock type for the DelegationRepository
+type MockDelegationRepository struct {
+	mock.Mock
+}
+
+func (m *MockDelegationRepository) CreateDelegation(d *models.Delegation) error {
+	args := m.Called(d)
+	return args.Error(0)
+}
+func (m *MockDelegationRepository) GetDelegationByID(id int) (*models.Delegation, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Delegation), args.Error(1)
+}
+func (m *MockDelegationRepository) GetDelegationsForUser(userID int, since time.Time) ([]models.Delegation, error) {
+	args := m.Called(userID, since)
+	return args.Get(0).([]models.Delegation), args.Error(1)
+}
+func (m *MockDelegationRepository) GetAllDelegations(since time.Time) ([]models.Delegation, error) {
+	args := m.Called(since)
+	return args.Get(0).([]models.Delegation), args.Error(1)
+}
+func (m *MockDelegationRepository) GetActiveDelegationsTo(delegateID int, at time.Time) ([]models.Delegation, error) {
+	args := m.Called(delegateID, at)
+	return args.Get(0).([]models.Delegation), args.Error(1)
+}
+func (m *MockDelegationRepository) DeleteDelegation(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func TestDelegationService(t *testing.T) {
+	now := time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)
+
+	newService := func() (DelegationService, *MockDelegationRepository, *MockUserRepository, *MockRoleService) {
+		mockRepo := new(MockDelegationRepository)
+		mockUserRepo := new(MockUserRepository)
+		mockRoles := new(MockRoleService)
+		mockLog := new(MockActivityLogService)
+		mockLog.On("Log", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+		s := NewDelegationService(mockRepo, mockUserRepo, mockRoles, mockLog).(*delegationService)
+		s.now = func() time.Time { return now }
+		return s, mockRepo, mockUserRepo, mockRoles
+	}
+
+	payload := models.CreateDelegationPayload{
+		DelegateID: 6,
+		StartsAt:   now,
+		EndsAt:     now.Add(14 * 24 * time.Hour),
+		Reason:     " Annual leave ",
+	}
+
+	t.Run("CreateDelegation - Success", func(t *testing.T) {
+		s, mockRepo, mockUserRepo, mockRoles := newService()
+		mockRoles.On("GetUserPermissions", 5).Return(map[string]bool{models.PermRequisitionApprove: true}, nil)
+		mockUserRepo.On("GetUserByID", 6).Return(&models.User{ID: 6}, nil)
+		mockRepo.On("CreateDelegation", mock.MatchedBy(func(d *models.Delegation) bool {
+			return d.DelegatorID == 5 && d.DelegateID == 6 && d.Reason == "Annual leave" && *d.CreatedBy == 5
+		})).Return(nil).Once()
+
+		d, err := s.CreateDelegation(5, payload)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, d.DelegatorID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("CreateDelegation - Delegator Is Not An Approver", func(t *testing.T) {
+		s, mockRepo, _, mockRoles := newService()
+		mockRoles.On("GetUserPermissions", 2).Return(map[string]bool{models.PermRequisitionCreate: true}, nil)
+
+		_, err := s.CreateDelegation(2, payload)
+		assert.Equal(t, ErrNotAnApprover, err)
+		mockRepo.AssertNotCalled(t, "CreateDelegation", mock.Anything)
+	})
+
+	t.Run("CreateDelegation - Already Ended", func(t *testing.T) {
+		s, _, _, _ := newService()
+		past := payload
+		past.StartsAt = now.Add(-48 * time.Hour)
+		past.EndsAt = now.Add(-24 * time.Hour)
+
+		_, err := s.CreateDelegation(5, past)
+		assert.Equal(t, ErrInvalidDelegation, err)
+	})
+
+	t.Run("AdminCreateDelegation - On Behalf Of Approver", func(t *testing.T) {
+		s, mockRepo, mockUserRepo, mockRoles := newService()
+		delegatorID := 5
+		adminPayload := payload
+		adminPayload.DelegatorID = &delegatorID
+		mockRoles.On("GetUserPermissions", 5).Return(map[string]bool{models.PermRequisitionApprove: true}, nil)
+		mockUserRepo.On("GetUserByID", 6).Return(&models.User{ID: 6}, nil)
+		mockRepo.On("CreateDelegation", mock.AnythingOfType("*models.Delegation")).Return(nil).Once()
+
+		d, err := s.AdminCreateDelegation(1, adminPayload)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, d.DelegatorID)
+		assert.Equal(t, 1, *d.CreatedBy)
+	})
+
+	t.Run("DeleteDelegation - Only The Delegator", func(t *testing.T) {
+		s, mockRepo, _, _ := newService()
+		mockRepo.On("GetDelegationByID", 3).Return(&models.Delegation{ID: 3, DelegatorID: 5, DelegateID: 6}, nil)
+
+		err := s.DeleteDelegation(6, 3)
+		assert.Equal(t, ErrForbidden, err)
+		mockRepo.AssertNotCalled(t, "DeleteDelegation", mock.Anything)
+
+		mockRepo.On("DeleteDelegation", 3).Return(nil).Once()
+		assert.NoError(t, s.DeleteDelegation(5, 3))
+
+		mockRepo.On("GetDelegationByID", 4).Return(nil, repository.ErrDelegationNotFound)
+		assert.Equal(t, repository.ErrDelegationNotFound, s.DeleteDelegation(5, 4))
+	})
+}
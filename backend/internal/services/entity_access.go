@@ -0,0 +1,188 @@
+package services
+
+import (
+	"errors"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"sync"
+)
+
+var (
+	ErrUnknownEntityType = errors.New("unknown entity type")
+	ErrEntityNotFound    = errors.New("entity not found")
+)
+
+// Actor is the user an entity access decision is made for.
+type Actor struct {
+	User        *models.User
+	Permissions map[string]bool
+}
+
+// IsVendorUser reports whether the actor uses the vendor portal rather than
+// working for the organisation.
+func (a *Actor) IsVendorUser() bool {
+	return a.Permissions[models.PermVendorPortal] && !a.Permissions[models.PermPOReadAll]
+}
+
+// EntityAccess describes how a user may see the records kept against an
+// entity, such as comments. External users only see what is shared with the
+// vendor.
+type EntityAccess struct {
+	External bool
+}
+
+// EntityAccessPolicy decides whether an actor may see an entity. It returns
+// ErrForbidden when they may not and ErrEntityNotFound when there is no such
+// entity.
+type EntityAccessPolicy interface {
+	Access(actor *Actor, entityID int) (*EntityAccess, error)
+}
+
+// EntityAccessPolicyFunc adapts a function to the EntityAccessPolicy interface.
+type EntityAccessPolicyFunc func(actor *Actor, entityID int) (*EntityAccess, error)
+
+// Access calls f(actor, entityID).
+func (f EntityAccessPolicyFunc) Access(actor *Actor, entityID int) (*EntityAccess, error) {
+	return f(actor, entityID)
+}
+
+// EntityAccessService decides who may see records attached to entities, using
+// the policy registered for each entity type.
+type EntityAccessService interface {
+	RegisterPolicy(entityType string, policy EntityAccessPolicy)
+	Access(userID int, entityType string, entityID int) (*EntityAccess, error)
+}
+
+type entityAccessService struct {
+	userRepo    repository.UserRepository
+	roleService RoleService
+	mu          sync.RWMutex
+	policies    map[string]EntityAccessPolicy
+}
+
+// NewEntityAccessService creates a new instance of EntityAccessService with no
+// policies registered.
+func NewEntityAccessService(userRepo repository.UserRepository, roleService RoleService) EntityAccessService {
+	return &entityAccessService{userRepo: userRepo, roleService: roleService, policies: make(map[string]EntityAccessPolicy)}
+}
+
+// RegisterPolicy makes entityType available for comments and other attached
+// records, guarded by policy.
+func (s *entityAccessService) RegisterPolicy(entityType string, policy EntityAccessPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policies[entityType] = policy
+}
+
+// Access applies the entity type's policy to the user.
+func (s *entityAccessService) Access(userID int, entityType string, entityID int) (*EntityAccess, error) {
+	s.mu.RLock()
+	policy, ok := s.policies[entityType]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownEntityType
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := s.roleService.GetUserPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+	return policy.Access(&Actor{User: user, Permissions: permissions}, entityID)
+}
+
+// resolveVisibility applies the visibility rules for records kept against an
+// entity. Records default to internal, or vendor-visible when an external
+// user creates them; vendor visibility is only allowed on purchase orders,
+// where invalid is returned otherwise; and external users may only create
+// vendor-visible records.
+func resolveVisibility(visibility string, entityType string, access *EntityAccess, invalid error) (string, error) {
+	if visibility == "" {
+		visibility = models.CommentVisibilityInternal
+		if access.External {
+			visibility = models.CommentVisibilityVendor
+		}
+	}
+	if visibility == models.CommentVisibilityVendor && entityType != models.EntityPurchaseOrder {
+		return "", invalid
+	}
+	if access.External && visibility != models.CommentVisibilityVendor {
+		return "", ErrForbidden
+	}
+	return visibility, nil
+}
+
+// RegisterDefaultEntityPolicies registers the access policies for
+// requisitions, purchase orders and vendors. Invoices are registered by the
+// invoice module once it exists.
+func RegisterDefaultEntityPolicies(s EntityAccessService, requisitionRepo repository.RequisitionRepository, poRepo repository.PurchaseOrderRepository, vendorRepo repository.VendorRepository) {
+	s.RegisterPolicy(models.EntityRequisition, RequisitionAccessPolicy(requisitionRepo))
+	s.RegisterPolicy(models.EntityPurchaseOrder, PurchaseOrderAccessPolicy(poRepo))
+	s.RegisterPolicy(models.EntityVendor, VendorAccessPolicy(vendorRepo))
+}
+
+// RequisitionAccessPolicy lets the requester, the assigned approver and
+// anyone who can see or decide every requisition see a requisition.
+func RequisitionAccessPolicy(repo repository.RequisitionRepository) EntityAccessPolicy {
+	return EntityAccessPolicyFunc(func(actor *Actor, entityID int) (*EntityAccess, error) {
+		req, err := repo.GetRequisitionByID(entityID)
+		if err != nil {
+			if errors.Is(err, repository.ErrRequisitionNotFound) {
+				return nil, ErrEntityNotFound
+			}
+			return nil, err
+		}
+
+		if req.RequesterID == actor.User.ID || (req.ApproverID != nil && *req.ApproverID == actor.User.ID) ||
+			actor.Permissions[models.PermRequisitionReadAll] || actor.Permissions[models.PermRequisitionApprove] ||
+			actor.Permissions[models.PermRequisitionManage] {
+			return &EntityAccess{}, nil
+		}
+		return nil, ErrForbidden
+	})
+}
+
+// PurchaseOrderAccessPolicy lets staff who can read purchase orders see them,
+// and vendor portal users see their own vendor's orders as external users.
+func PurchaseOrderAccessPolicy(repo repository.PurchaseOrderRepository) EntityAccessPolicy {
+	return EntityAccessPolicyFunc(func(actor *Actor, entityID int) (*EntityAccess, error) {
+		po, err := repo.GetPurchaseOrderByID(entityID)
+		if err != nil {
+			if errors.Is(err, repository.ErrPurchaseOrderNotFound) {
+				return nil, ErrEntityNotFound
+			}
+			return nil, err
+		}
+
+		if actor.IsVendorUser() {
+			if actor.User.VendorID != nil && *actor.User.VendorID == po.VendorID {
+				return &EntityAccess{External: true}, nil
+			}
+			return nil, ErrForbidden
+		}
+		if actor.Permissions[models.PermPORead] || actor.Permissions[models.PermPOReadAll] {
+			return &EntityAccess{}, nil
+		}
+		return nil, ErrForbidden
+	})
+}
+
+// VendorAccessPolicy lets staff who can read vendors see them.
+func VendorAccessPolicy(repo repository.VendorRepository) EntityAccessPolicy {
+	return EntityAccessPolicyFunc(func(actor *Actor, entityID int) (*EntityAccess, error) {
+		if _, err := repo.GetVendorByID(entityID); err != nil {
+			if errors.Is(err, repository.ErrVendorNotFound) {
+				return nil, ErrEntityNotFound
+			}
+			return nil, err
+		}
+
+		if !actor.IsVendorUser() && (actor.Permissions[models.PermVendorRead] || actor.Permissions[models.PermVendorWrite]) {
+			return &EntityAccess{}, nil
+		}
+		return nil, ErrForbidden
+	})
+}
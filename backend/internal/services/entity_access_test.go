@@ -0,0 +1,58 @@
+package services
+
+import (
+	"procurement-system/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntityAccessService(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+
+	newService := func() (EntityAccessService, *MockUserRepository, *MockRoleService, *MockPurchaseOrderRepository) {
+		mockUsers := new(MockUserRepository)
+		mockRoles := new(MockRoleService)
+		mockPOs := new(MockPurchaseOrderRepository)
+		s := NewEntityAccessService(mockUsers, mockRoles)
+		s.RegisterPolicy(models.EntityPurchaseOrder, PurchaseOrderAccessPolicy(mockPOs))
+		mockPOs.On("GetPurchaseOrderByID", 5).Return(&models.PurchaseOrder{ID: 5, VendorID: 3}, nil)
+		return s, mockUsers, mockRoles, mockPOs
+	}
+	vendorPerms := map[string]bool{models.PermPORead: true, models.PermVendorPortal: true}
+
+	t.Run("Purchase Order - Vendor User Sees Own Vendor's Orders", func(t *testing.T) {
+		s, mockUsers, mockRoles, _ := newService()
+		mockUsers.On("GetUserByID", 7).Return(&models.User{ID: 7, VendorID: intPtr(3)}, nil)
+		mockRoles.On("GetUserPermissions", 7).Return(vendorPerms, nil)
+
+		access, err := s.Access(7, models.EntityPurchaseOrder, 5)
+		assert.NoError(t, err)
+		assert.True(t, access.External)
+	})
+
+	t.Run("Purchase Order - Vendor User Cannot See Other Vendors' Orders", func(t *testing.T) {
+		s, mockUsers, mockRoles, _ := newService()
+		mockUsers.On("GetUserByID", 8).Return(&models.User{ID: 8, VendorID: intPtr(4)}, nil)
+		mockRoles.On("GetUserPermissions", 8).Return(vendorPerms, nil)
+
+		_, err := s.Access(8, models.EntityPurchaseOrder, 5)
+		assert.Equal(t, ErrForbidden, err)
+	})
+
+	t.Run("Purchase Order - Staff Access Is Internal", func(t *testing.T) {
+		s, mockUsers, mockRoles, _ := newService()
+		mockUsers.On("GetUserByID", 1).Return(&models.User{ID: 1}, nil)
+		mockRoles.On("GetUserPermissions", 1).Return(map[string]bool{models.PermPOReadAll: true, models.PermVendorPortal: true}, nil)
+
+		access, err := s.Access(1, models.EntityPurchaseOrder, 5)
+		assert.NoError(t, err)
+		assert.False(t, access.External)
+	})
+
+	t.Run("Unknown Entity Type", func(t *testing.T) {
+		s, _, _, _ := newService()
+		_, err := s.Access(1, models.EntityInvoice, 5)
+		assert.Equal(t, ErrUnknownEntityType, err)
+	})
+}
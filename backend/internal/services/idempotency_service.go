@@ -0,0 +1,88 @@
+package services
+
+import (
+	"log"
+	"net/http"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"sync"
+	"time"
+)
+
+// DefaultIdempotencyTTL is how long responses to idempotent requests are kept
+// for replay.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// idempotencyAbandonAfter is how long a request may hold its key without
+// finishing before it is assumed lost, e.g. to a server restart, and the key
+// is handed to the next retry.
+const idempotencyAbandonAfter = 5 * time.Minute
+
+// idempotencySweepInterval is how often expired records are removed.
+const idempotencySweepInterval = time.Hour
+
+// IdempotencyService defines the interface for remembering requests sent with
+// an Idempotency-Key, so that retries can be answered with the original
+// response. It satisfies middleware.IdempotencyStore.
+type IdempotencyService interface {
+	Reserve(userID int, key string, fingerprint string) (*models.IdempotencyRecord, error)
+	Complete(userID int, key string, statusCode int, header http.Header, body []byte) error
+	Release(userID int, key string) error
+}
+
+type idempotencyService struct {
+	repo      repository.IdempotencyRepository
+	ttl       time.Duration
+	now       func() time.Time
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewIdempotencyService creates a new instance of IdempotencyService that
+// keeps responses for ttl.
+func NewIdempotencyService(repo repository.IdempotencyRepository, ttl time.Duration) IdempotencyService {
+	return &idempotencyService{repo: repo, ttl: ttl, now: time.Now}
+}
+
+// Reserve claims a user's key for a request with the given fingerprint. It
+// returns nil when the request should go ahead, and otherwise the record of
+// the earlier request that used the key, which may still be in progress.
+func (s *idempotencyService) Reserve(userID int, key string, fingerprint string) (*models.IdempotencyRecord, error) {
+	now := s.now()
+	s.sweep(now)
+
+	record := &models.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+	return s.repo.Reserve(record, now.Add(-idempotencyAbandonAfter))
+}
+
+// Complete stores the response to replay for a reserved key.
+func (s *idempotencyService) Complete(userID int, key string, statusCode int, header http.Header, body []byte) error {
+	return s.repo.Complete(userID, key, statusCode, header, body)
+}
+
+// Release frees a reserved key without storing a response.
+func (s *idempotencyService) Release(userID int, key string) error {
+	return s.repo.Release(userID, key)
+}
+
+// sweep removes expired records, at most once per idempotencySweepInterval.
+// Expired records are ignored anyway, so failures are only logged.
+func (s *idempotencyService) sweep(now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < idempotencySweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	if _, err := s.repo.DeleteExpired(now); err != nil {
+		log.Printf("Failed to delete expired idempotency keys: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"net/http"
+	"procurement-system/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockIdempotencyRepository is a mock type for the IdempotencyRepository
+type MockIdempotencyRepository struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyRepository) Reserve(record *models.IdempotencyRecord, abandonedBefore time.Time) (*models.IdempotencyRecord, error) {
+	args := m.Called(record, abandonedBefore)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.IdempotencyRecord), args.Error(1)
+}
+func (m *MockIdempotencyRepository) Complete(userID int, key string, statusCode int, header http.Header, body []byte) error {
+	args := m.Called(userID, key, statusCode, header, body)
+	return args.Error(0)
+}
+func (m *MockIdempotencyRepository) Release(userID int, key string) error {
+	args := m.Called(userID, key)
+	return args.Error(0)
+}
+func (m *MockIdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	args := m.Called(now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestIdempotencyService(t *testing.T) {
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	newService := func() (*idempotencyService, *MockIdempotencyRepository) {
+		repo := new(MockIdempotencyRepository)
+		s := NewIdempotencyService(repo, DefaultIdempotencyTTL).(*idempotencyService)
+		s.now = func() time.Time { return now }
+		return s, repo
+	}
+
+	t.Run("Reserve - New Key Expires After The TTL", func(t *testing.T) {
+		s, repo := newService()
+		repo.On("DeleteExpired", now).Return(int64(0), nil)
+		repo.On("Reserve", mock.MatchedBy(func(r *models.IdempotencyRecord) bool {
+			return r.UserID == 1 && r.Key == "key-1" && r.Fingerprint == "abc" &&
+				r.CreatedAt.Equal(now) && r.ExpiresAt.Equal(now.Add(24*time.Hour))
+		}), now.Add(-5*time.Minute)).Return(nil, nil)
+
+		existing, err := s.Reserve(1, "key-1", "abc")
+		assert.NoError(t, err)
+		assert.Nil(t, existing)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Reserve - Returns The Earlier Request", func(t *testing.T) {
+		s, repo := newService()
+		earlier := &models.IdempotencyRecord{UserID: 1, Key: "key-1", Fingerprint: "abc", StatusCode: http.StatusCreated, Body: []byte(`{"id":5}`)}
+		repo.On("DeleteExpired", now).Return(int64(0), nil)
+		repo.On("Reserve", mock.Anything, mock.Anything).Return(earlier, nil)
+
+		existing, err := s.Reserve(1, "key-1", "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, earlier, existing)
+		assert.True(t, existing.Completed())
+	})
+
+	t.Run("Reserve - Sweeps Expired Keys At Most Hourly", func(t *testing.T) {
+		s, repo := newService()
+		repo.On("DeleteExpired", mock.Anything).Return(int64(3), nil)
+		repo.On("Reserve", mock.Anything, mock.Anything).Return(nil, nil)
+
+		s.Reserve(1, "key-1", "abc")
+		now = now.Add(30 * time.Minute)
+		s.Reserve(1, "key-2", "abc")
+		repo.AssertNumberOfCalls(t, "DeleteExpired", 1)
+
+		now = now.Add(31 * time.Minute)
+		s.Reserve(1, "key-3", "abc")
+		repo.AssertNumberOfCalls(t, "DeleteExpired", 2)
+	})
+}
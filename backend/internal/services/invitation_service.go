@@ -0,0 +1,143 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvitationInvalid = errors.New("invitation is invalid, expired or already used")
+)
+
+const defaultInvitationTTL = 72 * time.Hour
+
+// InvitationService defines the interface for admin-issued registration invitations.
+type InvitationService interface {
+	CreateInvitation(actorID int, payload models.CreateInvitationPayload) (*models.CreateInvitationResponse, error)
+	GetAllInvitations() ([]models.Invitation, error)
+	RevokeInvitation(actorID int, id int) error
+	AcceptInvitation(payload models.AcceptInvitationPayload) (*models.User, error)
+}
+
+type invitationService struct {
+	repo       repository.InvitationRepository
+	logService ActivityLogService
+	now        func() time.Time
+}
+
+// NewInvitationService creates a new instance of InvitationService.
+func NewInvitationService(repo repository.InvitationRepository, logService ActivityLogService) InvitationService {
+	return &invitationService{repo: repo, logService: logService, now: time.Now}
+}
+
+// CreateInvitation issues an invitation for the given email and role. The
+// returned token must be passed on to the invitee; only its hash is stored.
+func (s *invitationService) CreateInvitation(actorID int, payload models.CreateInvitationPayload) (*models.CreateInvitationResponse, error) {
+	token, err := generateInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := defaultInvitationTTL
+	if payload.ExpiresInHours > 0 {
+		ttl = time.Duration(payload.ExpiresInHours) * time.Hour
+	}
+
+	inv := &models.Invitation{
+		Email:     normalizeEmail(payload.Email),
+		Role:      payload.Role,
+		TokenHash: hashInvitationToken(token),
+		InvitedBy: &actorID,
+		ExpiresAt: s.now().Add(ttl),
+	}
+
+	if err := s.repo.CreateInvitation(inv); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "CREATE_INVITATION_FAILED", Ptr("invitation"), nil, "FAILED", &details)
+		return nil, err
+	}
+
+	details := inv.Role + " invitation for " + inv.Email
+	s.logService.Log(&actorID, "CREATE_INVITATION_SUCCESS", Ptr("invitation"), &inv.ID, "SUCCESS", &details)
+	return &models.CreateInvitationResponse{Invitation: *inv, Token: token}, nil
+}
+
+// GetAllInvitations retrieves all invitations.
+func (s *invitationService) GetAllInvitations() ([]models.Invitation, error) {
+	return s.repo.GetAllInvitations()
+}
+
+// RevokeInvitation prevents an unused invitation from being accepted.
+func (s *invitationService) RevokeInvitation(actorID int, id int) error {
+	if err := s.repo.RevokeInvitation(id); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "REVOKE_INVITATION_FAILED", Ptr("invitation"), &id, "FAILED", &details)
+		return err
+	}
+	s.logService.Log(&actorID, "REVOKE_INVITATION_SUCCESS", Ptr("invitation"), &id, "SUCCESS", nil)
+	return nil
+}
+
+// AcceptInvitation registers a new, active user with the email and role the
+// invitation was issued for.
+func (s *invitationService) AcceptInvitation(payload models.AcceptInvitationPayload) (*models.User, error) {
+	inv, err := s.repo.GetInvitationByTokenHash(hashInvitationToken(payload.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrInvitationNotFound) {
+			return nil, ErrInvitationInvalid
+		}
+		return nil, err
+	}
+	if inv.AcceptedAt != nil || inv.RevokedAt != nil || !s.now().Before(inv.ExpiresAt) {
+		details := "Invitation is expired, revoked or already used"
+		s.logService.Log(nil, "ACCEPT_INVITATION_FAILED", Ptr("invitation"), &inv.ID, "FAILED", &details)
+		return nil, ErrInvitationInvalid
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(payload.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Name:           payload.Name,
+		Email:          inv.Email,
+		HashedPassword: string(hashedPassword),
+		Role:           inv.Role,
+		Status:         models.UserStatusActive,
+	}
+
+	if err := s.repo.AcceptInvitation(inv.ID, user); err != nil {
+		details := err.Error()
+		s.logService.Log(nil, "ACCEPT_INVITATION_FAILED", Ptr("invitation"), &inv.ID, "FAILED", &details)
+		if errors.Is(err, repository.ErrInvitationUsed) {
+			return nil, ErrInvitationInvalid
+		}
+		return nil, err
+	}
+
+	s.logService.Log(&user.ID, "ACCEPT_INVITATION_SUCCESS", Ptr("invitation"), &inv.ID, "SUCCESS", nil)
+	user.HashedPassword = ""
+	return user, nil
+}
+
+func generateInvitationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockInvitationRepository is a mock type for the InvitationRepository
+type MockInvitationRepository struct {
+	mock.Mock
+}
+
+func (m *MockInvitationRepository) CreateInvitation(inv *models.Invitation) error {
+	args := m.Called(inv)
+	return args.Error(0)
+}
+func (m *MockInvitationRepository) GetAllInvitations() ([]models.Invitation, error) {
+	args := m.Called()
+	return args.Get(0).([]models.Invitation), args.Error(1)
+}
+func (m *MockInvitationRepository) GetInvitationByTokenHash(tokenHash string) (*models.Invitation, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Invitation), args.Error(1)
+}
+func (m *MockInvitationRepository) RevokeInvitation(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+func (m *MockInvitationRepository) AcceptInvitation(id int, user *models.User) error {
+	args := m.Called(id, user)
+	return args.Error(0)
+}
+
+func TestInvitationService(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	newService := func(repo *MockInvitationRepository, logService *MockActivityLogService) *invitationService {
+		s := NewInvitationService(repo, logService).(*invitationService)
+		s.now = func() time.Time { return now }
+		return s
+	}
+
+	t.Run("CreateInvitation", func(t *testing.T) {
+		mockRepo := new(MockInvitationRepository)
+		mockLogService := new(MockActivityLogService)
+		s := newService(mockRepo, mockLogService)
+
+		mockRepo.On("CreateInvitation", mock.MatchedBy(func(inv *models.Invitation) bool {
+			return inv.Email == "approver@example.com" && inv.Role == "Approver" && inv.ExpiresAt.Equal(now.Add(24*time.Hour))
+		})).Return(nil).Once()
+		mockLogService.On("Log", mock.Anything, "CREATE_INVITATION_SUCCESS", mock.Anything, mock.Anything, "SUCCESS", mock.Anything).Return()
+
+		resp, err := s.CreateInvitation(1, models.CreateInvitationPayload{Email: "Approver@Example.com", Role: "Approver", ExpiresInHours: 24})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resp.Token)
+		assert.Equal(t, hashInvitationToken(resp.Token), resp.Invitation.TokenHash)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("AcceptInvitation", func(t *testing.T) {
+		mockRepo := new(MockInvitationRepository)
+		mockLogService := new(MockActivityLogService)
+		s := newService(mockRepo, mockLogService)
+		inv := &models.Invitation{ID: 3, Email: "approver@example.com", Role: "Approver", ExpiresAt: now.Add(time.Hour)}
+
+		mockRepo.On("GetInvitationByTokenHash", hashInvitationToken("tok")).Return(inv, nil).Once()
+		mockRepo.On("AcceptInvitation", 3, mock.MatchedBy(func(u *models.User) bool {
+			return u.Email == "approver@example.com" && u.Role == "Approver" && u.Status == models.UserStatusActive
+		})).Return(nil).Once()
+		mockLogService.On("Log", mock.Anything, "ACCEPT_INVITATION_SUCCESS", mock.Anything, mock.Anything, "SUCCESS", mock.Anything).Return()
+
+		user, err := s.AcceptInvitation(models.AcceptInvitationPayload{Token: "tok", Name: "New Approver", Password: "password123"})
+		assert.NoError(t, err)
+		assert.Equal(t, "Approver", user.Role)
+		assert.Empty(t, user.HashedPassword)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("AcceptInvitation - Expired", func(t *testing.T) {
+		mockRepo := new(MockInvitationRepository)
+		mockLogService := new(MockActivityLogService)
+		s := newService(mockRepo, mockLogService)
+		inv := &models.Invitation{ID: 4, Email: "late@example.com", Role: "Admin", ExpiresAt: now.Add(-time.Minute)}
+
+		mockRepo.On("GetInvitationByTokenHash", hashInvitationToken("old")).Return(inv, nil).Once()
+		mockLogService.On("Log", mock.Anything, "ACCEPT_INVITATION_FAILED", mock.Anything, mock.Anything, "FAILED", mock.Anything).Return()
+
+		_, err := s.AcceptInvitation(models.AcceptInvitationPayload{Token: "old", Name: "Late", Password: "password123"})
+		assert.Equal(t, ErrInvitationInvalid, err)
+		mockRepo.AssertNotCalled(t, "AcceptInvitation", mock.Anything, mock.Anything)
+	})
+
+	t.Run("AcceptInvitation - Unknown Token", func(t *testing.T) {
+		mockRepo := new(MockInvitationRepository)
+		s := newService(mockRepo, new(MockActivityLogService))
+		mockRepo.On("GetInvitationByTokenHash", mock.Anything).Return(nil, repository.ErrInvitationNotFound).Once()
+
+		_, err := s.AcceptInvitation(models.AcceptInvitationPayload{Token: "nope", Name: "X", Password: "password123"})
+		assert.Equal(t, ErrInvitationInvalid, err)
+	})
+}
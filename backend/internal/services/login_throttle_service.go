@@ -0,0 +1,184 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTooManyLoginAttempts = errors.New("too many failed login attempts, please try again later")
+)
+
+// ThrottledError is returned when a login is blocked by backoff or lockout.
+// It wraps ErrTooManyLoginAttempts and carries how long the caller should wait.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return ErrTooManyLoginAttempts.Error()
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
+
+// LoginThrottlePolicy controls how failed logins are throttled.
+type LoginThrottlePolicy struct {
+	MaxAccountFailures int           // failures per email before the account is locked
+	MaxIPFailures      int           // failures per client IP before the IP is locked
+	BaseDelay          time.Duration // backoff after the first failure, doubled on each further failure
+	MaxDelay           time.Duration // upper bound for the backoff delay
+	LockoutDuration    time.Duration // how long a lock lasts once the failure limit is reached
+	FailureWindow      time.Duration // failures older than this no longer count
+}
+
+// DefaultLoginThrottlePolicy returns the policy used when nothing is configured.
+func DefaultLoginThrottlePolicy() LoginThrottlePolicy {
+	return LoginThrottlePolicy{
+		MaxAccountFailures: 5,
+		MaxIPFailures:      50,
+		BaseDelay:          time.Second,
+		MaxDelay:           5 * time.Minute,
+		LockoutDuration:    15 * time.Minute,
+		FailureWindow:      time.Hour,
+	}
+}
+
+// LoginThrottleService defines the interface for throttling login attempts.
+type LoginThrottleService interface {
+	Check(email, ipAddress string) error
+	RegisterFailure(email, ipAddress string)
+	RegisterSuccess(email string)
+	GetActiveLocks() ([]models.LoginThrottle, error)
+	ClearLock(actorID int, lockID int) error
+}
+
+type loginThrottleService struct {
+	repo       repository.LoginThrottleRepository
+	logService ActivityLogService
+	policy     LoginThrottlePolicy
+	now        func() time.Time
+}
+
+// NewLoginThrottleService creates a new instance of LoginThrottleService.
+func NewLoginThrottleService(repo repository.LoginThrottleRepository, logService ActivityLogService, policy LoginThrottlePolicy) LoginThrottleService {
+	return &loginThrottleService{repo: repo, logService: logService, policy: policy, now: time.Now}
+}
+
+type throttleKey struct {
+	scope       string
+	key         string
+	maxFailures int
+}
+
+// keys returns the throttle subjects for an attempt. The account key is the
+// normalised email so that unknown emails are throttled exactly like real ones.
+func (s *loginThrottleService) keys(email, ipAddress string) []throttleKey {
+	keys := []throttleKey{{scope: models.ThrottleScopeAccount, key: normalizeEmail(email), maxFailures: s.policy.MaxAccountFailures}}
+	if ipAddress != "" {
+		keys = append(keys, throttleKey{scope: models.ThrottleScopeIP, key: ipAddress, maxFailures: s.policy.MaxIPFailures})
+	}
+	return keys
+}
+
+// Check returns a *ThrottledError if either the account or the IP is currently blocked.
+func (s *loginThrottleService) Check(email, ipAddress string) error {
+	now := s.now()
+	var wait time.Duration
+	for _, k := range s.keys(email, ipAddress) {
+		t, err := s.repo.GetThrottle(k.scope, k.key)
+		if err != nil {
+			if errors.Is(err, repository.ErrLoginThrottleNotFound) {
+				continue
+			}
+			return err
+		}
+		if t.LockedUntil != nil && t.LockedUntil.After(now) {
+			if d := t.LockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	if wait > 0 {
+		return &ThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// RegisterFailure records a failed attempt and applies backoff or lockout.
+// Errors are logged rather than returned so they never change the login response.
+func (s *loginThrottleService) RegisterFailure(email, ipAddress string) {
+	now := s.now()
+	for _, k := range s.keys(email, ipAddress) {
+		t, err := s.repo.RecordFailure(k.scope, k.key, now.Add(-s.policy.FailureWindow))
+		if err != nil {
+			log.Printf("Failed to record login failure for %s %s: %v", k.scope, k.key, err)
+			continue
+		}
+
+		lockedOut := k.maxFailures > 0 && t.FailureCount >= k.maxFailures
+		var lockedUntil time.Time
+		if lockedOut {
+			lockedUntil = now.Add(s.policy.LockoutDuration)
+		} else {
+			lockedUntil = now.Add(s.backoff(t.FailureCount))
+		}
+
+		if err := s.repo.SetLockedUntil(t.ID, lockedUntil); err != nil {
+			log.Printf("Failed to lock login throttle %d: %v", t.ID, err)
+			continue
+		}
+
+		if lockedOut {
+			details := fmt.Sprintf("%s %s locked until %s after %d failed attempts", k.scope, k.key, lockedUntil.Format(time.RFC3339), t.FailureCount)
+			s.logService.Log(nil, "LOGIN_LOCKOUT", Ptr("login_throttle"), &t.ID, "SUCCESS", &details)
+		}
+	}
+}
+
+// RegisterSuccess clears the account throttle. The IP throttle is left to
+// expire on its own so one valid account can't be used to reset it.
+func (s *loginThrottleService) RegisterSuccess(email string) {
+	if err := s.repo.ResetThrottle(models.ThrottleScopeAccount, normalizeEmail(email)); err != nil {
+		log.Printf("Failed to reset login throttle: %v", err)
+	}
+}
+
+// GetActiveLocks retrieves all throttles that are currently blocking logins.
+func (s *loginThrottleService) GetActiveLocks() ([]models.LoginThrottle, error) {
+	return s.repo.GetActiveLocks()
+}
+
+// ClearLock removes a throttle so the account or IP can log in again immediately.
+func (s *loginThrottleService) ClearLock(actorID int, lockID int) error {
+	err := s.repo.DeleteThrottle(lockID)
+	if err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "CLEAR_LOGIN_LOCK_FAILED", Ptr("login_throttle"), &lockID, "FAILED", &details)
+		return err
+	}
+	s.logService.Log(&actorID, "CLEAR_LOGIN_LOCK_SUCCESS", Ptr("login_throttle"), &lockID, "SUCCESS", nil)
+	return nil
+}
+
+// backoff returns BaseDelay doubled for every failure after the first, capped at MaxDelay.
+func (s *loginThrottleService) backoff(failures int) time.Duration {
+	delay := s.policy.BaseDelay
+	for i := 1; i < failures && delay < s.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.policy.MaxDelay {
+		delay = s.policy.MaxDelay
+	}
+	return delay
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockLoginThrottleRepository is a mock type for the LoginThrottleRepository
+type MockLoginThrottleRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginThrottleRepository) GetThrottle(scope, key string) (*models.LoginThrottle, error) {
+	args := m.Called(scope, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginThrottle), args.Error(1)
+}
+func (m *MockLoginThrottleRepository) RecordFailure(scope, key string, resetBefore time.Time) (*models.LoginThrottle, error) {
+	args := m.Called(scope, key, resetBefore)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginThrottle), args.Error(1)
+}
+func (m *MockLoginThrottleRepository) SetLockedUntil(id int, lockedUntil time.Time) error {
+	args := m.Called(id, lockedUntil)
+	return args.Error(0)
+}
+func (m *MockLoginThrottleRepository) ResetThrottle(scope, key string) error {
+	args := m.Called(scope, key)
+	return args.Error(0)
+}
+func (m *MockLoginThrottleRepository) GetActiveLocks() ([]models.LoginThrottle, error) {
+	args := m.Called()
+	return args.Get(0).([]models.LoginThrottle), args.Error(1)
+}
+func (m *MockLoginThrottleRepository) DeleteThrottle(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func newTestThrottleService(repo *MockLoginThrottleRepository, logService *MockActivityLogService, now time.Time) *loginThrottleService {
+	s := NewLoginThrottleService(repo, logService, DefaultLoginThrottlePolicy()).(*loginThrottleService)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestLoginThrottleService(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Check - No Throttle", func(t *testing.T) {
+		mockRepo := new(MockLoginThrottleRepository)
+		s := newTestThrottleService(mockRepo, new(MockActivityLogService), now)
+		mockRepo.On("GetThrottle", models.ThrottleScopeAccount, "user@example.com").Return(nil, repository.ErrLoginThrottleNotFound).Once()
+		mockRepo.On("GetThrottle", models.ThrottleScopeIP, "10.0.0.1").Return(nil, repository.ErrLoginThrottleNotFound).Once()
+
+		err := s.Check(" User@Example.com ", "10.0.0.1")
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Check - Locked", func(t *testing.T) {
+		mockRepo := new(MockLoginThrottleRepository)
+		s := newTestThrottleService(mockRepo, new(MockActivityLogService), now)
+		lockedUntil := now.Add(10 * time.Minute)
+		mockRepo.On("GetThrottle", models.ThrottleScopeAccount, "user@example.com").Return(&models.LoginThrottle{ID: 1, LockedUntil: &lockedUntil}, nil).Once()
+
+		err := s.Check("user@example.com", "")
+		var throttled *ThrottledError
+		assert.ErrorAs(t, err, &throttled)
+		assert.ErrorIs(t, err, ErrTooManyLoginAttempts)
+		assert.Equal(t, 10*time.Minute, throttled.RetryAfter)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("RegisterFailure - Exponential Backoff", func(t *testing.T) {
+		mockRepo := new(MockLoginThrottleRepository)
+		s := newTestThrottleService(mockRepo, new(MockActivityLogService), now)
+		resetBefore := now.Add(-time.Hour)
+		mockRepo.On("RecordFailure", models.ThrottleScopeAccount, "user@example.com", resetBefore).Return(&models.LoginThrottle{ID: 1, FailureCount: 3}, nil).Once()
+		mockRepo.On("SetLockedUntil", 1, now.Add(4*time.Second)).Return(nil).Once()
+
+		s.RegisterFailure("user@example.com", "")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("RegisterFailure - Lockout", func(t *testing.T) {
+		mockRepo := new(MockLoginThrottleRepository)
+		mockLogService := new(MockActivityLogService)
+		s := newTestThrottleService(mockRepo, mockLogService, now)
+		resetBefore := now.Add(-time.Hour)
+		mockRepo.On("RecordFailure", models.ThrottleScopeAccount, "user@example.com", resetBefore).Return(&models.LoginThrottle{ID: 1, FailureCount: 5}, nil).Once()
+		mockRepo.On("RecordFailure", models.ThrottleScopeIP, "10.0.0.1", resetBefore).Return(&models.LoginThrottle{ID: 2, FailureCount: 1}, nil).Once()
+		mockRepo.On("SetLockedUntil", 1, now.Add(15*time.Minute)).Return(nil).Once()
+		mockRepo.On("SetLockedUntil", 2, now.Add(time.Second)).Return(nil).Once()
+		mockLogService.On("Log", (*int)(nil), "LOGIN_LOCKOUT", mock.Anything, mock.Anything, "SUCCESS", mock.Anything).Return().Once()
+
+		s.RegisterFailure("user@example.com", "10.0.0.1")
+		mockRepo.AssertExpectations(t)
+		mockLogService.AssertExpectations(t)
+	})
+
+	t.Run("ClearLock", func(t *testing.T) {
+		mockRepo := new(MockLoginThrottleRepository)
+		mockLogService := new(MockActivityLogService)
+		s := newTestThrottleService(mockRepo, mockLogService, now)
+		mockRepo.On("DeleteThrottle", 7).Return(nil).Once()
+		mockLogService.On("Log", mock.Anything, "CLEAR_LOGIN_LOCK_SUCCESS", mock.Anything, mock.Anything, "SUCCESS", mock.Anything).Return().Once()
+
+		err := s.ClearLock(99, 7)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockLogService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+)
+
+// Names of the built-in badge providers, as referenced by menu_items.badge.
+const (
+	BadgePendingApprovals       = "pending_approvals"
+	BadgeMyRejectedRequisitions = "my_rejected_requisitions"
+	BadgePendingChangeOrders    = "pending_change_orders"
+	BadgeBuyQueue               = "buy_queue"
+	BadgeFailedDispatches       = "failed_dispatches"
+)
+
+// RegisterDefaultBadgeProviders registers the badge providers backed by the
+// requisition service and the repositories.
+func RegisterDefaultBadgeProviders(s NavigationService, requisitions RequisitionService, requisitionRepo repository.RequisitionRepository, poRepo repository.PurchaseOrderRepository, changeOrderRepo repository.ChangeOrderRepository, dispatchRepo repository.PODispatchRepository) {
+	s.RegisterBadgeProvider(BadgePendingApprovals, PendingApprovalsBadge(requisitions))
+	s.RegisterBadgeProvider(BadgeMyRejectedRequisitions, MyRejectedRequisitionsBadge(requisitionRepo))
+	s.RegisterBadgeProvider(BadgePendingChangeOrders, PendingChangeOrdersBadge(changeOrderRepo))
+	s.RegisterBadgeProvider(BadgeBuyQueue, BuyQueueBadge(poRepo))
+	s.RegisterBadgeProvider(BadgeFailedDispatches, FailedDispatchesBadge(dispatchRepo))
+}
+
+// PendingApprovalsBadge counts the requisitions waiting for a decision that
+// the user may make: those in their approval queue.
+func PendingApprovalsBadge(requisitions RequisitionService) BadgeProvider {
+	return BadgeProviderFunc(func(userID int) (*models.NavigationBadge, error) {
+		queue, err := requisitions.GetApprovalQueue(userID)
+		return countBadge(len(queue), err)
+	})
+}
+
+// MyRejectedRequisitionsBadge counts the user's own requisitions that came
+// back from review, returned for changes or rejected.
+func MyRejectedRequisitionsBadge(repo repository.RequisitionRepository) BadgeProvider {
+	return BadgeProviderFunc(func(userID int) (*models.NavigationBadge, error) {
+		returned, err := repo.CountRequisitions(&userID, models.RequisitionStatusReturned)
+		if err != nil {
+			return nil, err
+		}
+		rejected, err := repo.CountRequisitions(&userID, models.RequisitionStatusRejected)
+		badge, err := countBadge(returned+rejected, err)
+		if badge != nil {
+			switch {
+			case rejected == 0:
+				badge.Label = "returned"
+			case returned == 0:
+				badge.Label = "rejected"
+			default:
+				badge.Label = "returned or rejected"
+			}
+		}
+		return badge, err
+	})
+}
+
+// PendingChangeOrdersBadge counts the change orders waiting for approval,
+// leaving out those the user requested, since they may not approve them.
+func PendingChangeOrdersBadge(repo repository.ChangeOrderRepository) BadgeProvider {
+	return BadgeProviderFunc(func(userID int) (*models.NavigationBadge, error) {
+		pending, err := repo.GetPendingChangeOrders()
+		count := 0
+		for _, co := range pending {
+			if co.RequestedBy == nil || *co.RequestedBy != userID {
+				count++
+			}
+		}
+		return countBadge(count, err)
+	})
+}
+
+// BuyQueueBadge counts the approved requisitions waiting to be consolidated
+// onto a purchase order.
+func BuyQueueBadge(repo repository.PurchaseOrderRepository) BadgeProvider {
+	return BadgeProviderFunc(func(userID int) (*models.NavigationBadge, error) {
+		queue, err := repo.GetBuyQueue()
+		return countBadge(len(queue), err)
+	})
+}
+
+// FailedDispatchesBadge counts the purchase orders that could not be sent to
+// their vendor and need resending.
+func FailedDispatchesBadge(repo repository.PODispatchRepository) BadgeProvider {
+	return BadgeProviderFunc(func(userID int) (*models.NavigationBadge, error) {
+		badge, err := countBadge(repo.CountFailedPurchaseOrders())
+		if badge != nil {
+			badge.Label = "failed"
+		}
+		return badge, err
+	})
+}
+
+// countBadge turns a count into a badge, leaving it out when there is nothing to show.
+func countBadge(count int, err error) (*models.NavigationBadge, error) {
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	return &models.NavigationBadge{Count: count}, nil
+}
@@ -1,98 +1,459 @@
 package services
 
 import (
+	"errors"
+	"log"
 	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
 	"strings"
+	"sync"
+	"time"
 )
 
+var (
+	ErrInvalidMenuParent    = errors.New("menu item parent does not exist or would create a cycle")
+	ErrUnknownBadgeProvider = errors.New("unknown badge provider")
+)
+
+// defaultBadgeCacheTTL is how long a user's badge counts are reused, so
+// fetching the menu on every page load stays cheap.
+const defaultBadgeCacheTTL = 30 * time.Second
+
+// maxBadgeCacheEntries is the cache size at which expired entries are swept.
+const maxBadgeCacheEntries = 1000
+
+// BadgeProvider computes the badge for a menu item for the given user. It
+// returns nil when there is nothing to show. Providers are only called for
+// items the user is allowed to see.
+type BadgeProvider interface {
+	Badge(userID int) (*models.NavigationBadge, error)
+}
+
+// BadgeProviderFunc adapts a function to the BadgeProvider interface.
+type BadgeProviderFunc func(userID int) (*models.NavigationBadge, error)
+
+// Badge calls f(userID).
+func (f BadgeProviderFunc) Badge(userID int) (*models.NavigationBadge, error) {
+	return f(userID)
+}
+
+type badgeCacheKey struct {
+	userID int
+	name   string
+}
+
+type badgeCacheEntry struct {
+	badge     *models.NavigationBadge
+	expiresAt time.Time
+}
+
 type NavigationService interface {
-	GetMenuForRole(role string) []models.NavigationItem
-	GetBreadcrumbsForPath(role, path string) []models.BreadcrumbItem
+	GetMenu(userID int) ([]models.NavigationItem, error)
+	GetBreadcrumbsForPath(userID int, path string) ([]models.BreadcrumbItem, error)
+	GetAllMenuItems() ([]models.MenuItem, error)
+	CreateMenuItem(actorID int, payload models.MenuItemPayload) (*models.MenuItem, error)
+	UpdateMenuItem(actorID int, id int, payload models.MenuItemPayload) (*models.MenuItem, error)
+	DeleteMenuItem(actorID int, id int) error
+	RegisterBadgeProvider(name string, provider BadgeProvider)
 }
 
-type navigationService struct{}
+type navigationService struct {
+	repo        repository.NavigationRepository
+	roleService RoleService
+	logService  ActivityLogService
 
-func NewNavigationService() NavigationService {
-	return &navigationService{}
+	mu         sync.Mutex
+	providers  map[string]BadgeProvider
+	badgeCache map[badgeCacheKey]badgeCacheEntry
+	badgeTTL   time.Duration
+	now        func() time.Time
 }
 
-func (s *navigationService) GetMenuForRole(role string) []models.NavigationItem {
-	switch role {
-	case "Admin":
-		return getAdminMenu()
-	case "Procurement Officer":
-		return getProcurementOfficerMenu()
-	case "Approver":
-		return getApproverMenu()
-	case "Vendor":
-		return getVendorMenu()
-	default: // Employee
-		return getEmployeeMenu()
+// NewNavigationService creates a new instance of NavigationService. Menu items
+// are read from the repository and filtered by the permissions roleService
+// resolves for the user.
+func NewNavigationService(repo repository.NavigationRepository, roleService RoleService, logService ActivityLogService) NavigationService {
+	return &navigationService{
+		repo:        repo,
+		roleService: roleService,
+		logService:  logService,
+		providers:   make(map[string]BadgeProvider),
+		badgeCache:  make(map[badgeCacheKey]badgeCacheEntry),
+		badgeTTL:    defaultBadgeCacheTTL,
+		now:         time.Now,
 	}
 }
 
-func (s *navigationService) GetBreadcrumbsForPath(role, path string) []models.BreadcrumbItem {
-	menu := s.GetMenuForRole(role)
-	var breadcrumbs []models.BreadcrumbItem
+// RegisterBadgeProvider makes provider available to menu items whose badge
+// column is set to name.
+func (s *navigationService) RegisterBadgeProvider(name string, provider BadgeProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.providers[name] = provider
+}
+
+// menuNode is a menu item together with the children the user may see.
+type menuNode struct {
+	item     models.MenuItem
+	children []*menuNode
+}
+
+// GetMenu returns the menu items the user's permissions allow, as top-level
+// items with their direct children.
+func (s *navigationService) GetMenu(userID int) ([]models.NavigationItem, error) {
+	roots, err := s.visibleTree(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	menu := []models.NavigationItem{}
+	for _, root := range roots {
+		if !root.item.ShowInMenu {
+			continue
+		}
+		nav := models.NavigationItem{
+			Title: root.item.Title,
+			Path:  root.item.Path,
+			Icon:  root.item.Icon,
+			Badge: s.badge(userID, root.item.Badge),
+		}
+		for _, child := range root.children {
+			if child.item.ShowInMenu {
+				nav.SubItems = append(nav.SubItems, models.NavigationSubItem{
+					Title: child.item.Title,
+					Path:  child.item.Path,
+					Badge: s.badge(userID, child.item.Badge),
+				})
+			}
+		}
+		menu = append(menu, nav)
+	}
+	return menu, nil
+}
+
+// GetBreadcrumbsForPath returns the chain of menu items leading to path.
+// Parameterised items such as /procurement/requisitions/:id match any value
+// in that segment, and the value is substituted into their title and path.
+// If no item matches the whole path, the deepest item matching a prefix is used.
+func (s *navigationService) GetBreadcrumbsForPath(userID int, path string) ([]models.BreadcrumbItem, error) {
+	roots, err := s.visibleTree(userID)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, item := range menu {
-		if strings.HasPrefix(path, item.Path) {
-			breadcrumbs = append(breadcrumbs, models.BreadcrumbItem{Title: item.Title, Path: item.Path})
-			for _, subItem := range item.SubItems {
-				if path == subItem.Path {
-					breadcrumbs = append(breadcrumbs, models.BreadcrumbItem{Title: subItem.Title, Path: subItem.Path})
-					return breadcrumbs
+	segments := splitPath(path)
+	var best []*menuNode
+	bestExact, bestLen, bestParams := false, 0, 0
+
+	var walk func(nodes []*menuNode, chain []*menuNode)
+	walk = func(nodes []*menuNode, chain []*menuNode) {
+		for _, node := range nodes {
+			current := append(chain[:len(chain):len(chain)], node)
+			pattern := splitPath(node.item.Path)
+			if params, ok := matchSegments(pattern, segments); ok {
+				exact := len(pattern) == len(segments)
+				better := best == nil ||
+					(exact && !bestExact) ||
+					(exact == bestExact && len(pattern) > bestLen) ||
+					(exact == bestExact && len(pattern) == bestLen && params < bestParams)
+				if better {
+					best, bestExact, bestLen, bestParams = current, exact, len(pattern), params
 				}
 			}
-			return breadcrumbs
+			walk(node.children, current)
+		}
+	}
+	walk(roots, nil)
+
+	breadcrumbs := []models.BreadcrumbItem{}
+	if best == nil {
+		return breadcrumbs, nil
+	}
+
+	values := pathParams(splitPath(best[len(best)-1].item.Path), segments)
+	for _, node := range best {
+		breadcrumbs = append(breadcrumbs, models.BreadcrumbItem{
+			Title: fillParams(node.item.Title, values),
+			Path:  fillParams(node.item.Path, values),
+		})
+	}
+	return breadcrumbs, nil
+}
+
+// GetAllMenuItems returns every stored menu item, regardless of permissions.
+func (s *navigationService) GetAllMenuItems() ([]models.MenuItem, error) {
+	return s.repo.GetAllMenuItems()
+}
+
+// CreateMenuItem adds a menu item.
+func (s *navigationService) CreateMenuItem(actorID int, payload models.MenuItemPayload) (*models.MenuItem, error) {
+	item := menuItemFromPayload(payload)
+	if err := s.validateMenuItem(item); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateMenuItem(item); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "CREATE_MENU_ITEM_FAILED", Ptr("menu_item"), nil, "FAILED", &details)
+		return nil, err
+	}
+
+	s.logService.Log(&actorID, "CREATE_MENU_ITEM_SUCCESS", Ptr("menu_item"), &item.ID, "SUCCESS", &item.Path)
+	return item, nil
+}
+
+// UpdateMenuItem replaces a menu item's fields.
+func (s *navigationService) UpdateMenuItem(actorID int, id int, payload models.MenuItemPayload) (*models.MenuItem, error) {
+	if _, err := s.repo.GetMenuItemByID(id); err != nil {
+		return nil, err
+	}
+
+	item := menuItemFromPayload(payload)
+	item.ID = id
+	if err := s.validateMenuItem(item); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateMenuItem(item); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "UPDATE_MENU_ITEM_FAILED", Ptr("menu_item"), &id, "FAILED", &details)
+		return nil, err
+	}
+
+	s.logService.Log(&actorID, "UPDATE_MENU_ITEM_SUCCESS", Ptr("menu_item"), &id, "SUCCESS", &item.Path)
+	return item, nil
+}
+
+// DeleteMenuItem removes a menu item and its children.
+func (s *navigationService) DeleteMenuItem(actorID int, id int) error {
+	if err := s.repo.DeleteMenuItem(id); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "DELETE_MENU_ITEM_FAILED", Ptr("menu_item"), &id, "FAILED", &details)
+		return err
+	}
+
+	s.logService.Log(&actorID, "DELETE_MENU_ITEM_SUCCESS", Ptr("menu_item"), &id, "SUCCESS", nil)
+	return nil
+}
+
+// badge returns the cached or freshly computed badge for a menu item. A
+// failing provider is logged and the badge left out rather than failing the
+// whole menu; failures are not cached.
+func (s *navigationService) badge(userID int, name *string) *models.NavigationBadge {
+	if name == nil {
+		return nil
+	}
+	key := badgeCacheKey{userID: userID, name: *name}
+
+	s.mu.Lock()
+	provider, ok := s.providers[*name]
+	entry, cached := s.badgeCache[key]
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	if cached && s.now().Before(entry.expiresAt) {
+		return entry.badge
+	}
+
+	badge, err := provider.Badge(userID)
+	if err != nil {
+		log.Printf("Failed to compute %s badge for user %d: %v", *name, userID, err)
+		return nil
+	}
+
+	now := s.now()
+	s.mu.Lock()
+	// Drop stale entries now and then so users who stopped browsing don't
+	// keep their counts in memory forever.
+	if len(s.badgeCache) >= maxBadgeCacheEntries {
+		for k, e := range s.badgeCache {
+			if !now.Before(e.expiresAt) {
+				delete(s.badgeCache, k)
+			}
 		}
 	}
-	return breadcrumbs
+	s.badgeCache[key] = badgeCacheEntry{badge: badge, expiresAt: now.Add(s.badgeTTL)}
+	s.mu.Unlock()
+	return badge
 }
 
-func getAdminMenu() []models.NavigationItem {
-	return []models.NavigationItem{
-		{Title: "Dashboard", Path: "/dashboard", Icon: "dashboard"},
-		{Title: "Procurement", Path: "/procurement", Icon: "shopping_cart", SubItems: []models.NavigationSubItem{
-			{Title: "Requisitions", Path: "/procurement/requisitions"},
-			{Title: "Purchase Orders", Path: "/procurement/purchase-orders"},
-			{Title: "Approvals", Path: "/procurement/approvals"},
-		}},
-		{Title: "Vendors", Path: "/vendors", Icon: "store"},
-		{Title: "Reports", Path: "/reports", Icon: "assessment"},
-		{Title: "Administration", Path: "/admin", Icon: "settings", SubItems: []models.NavigationSubItem{
-			{Title: "User Management", Path: "/admin/users"},
-			{Title: "All Requisitions", Path: "/admin/requisitions"},
-			{Title: "All Purchase Orders", Path: "/admin/purchase-orders"},
-			{Title: "System Settings", Path: "/admin/settings"},
-		}},
+// visibleTree loads the menu and keeps only the items the user may see.
+func (s *navigationService) visibleTree(userID int) ([]*menuNode, error) {
+	items, err := s.repo.GetAllMenuItems()
+	if err != nil {
+		return nil, err
 	}
+	granted, err := s.roleService.GetUserPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+	return buildMenuTree(items, granted), nil
 }
 
-func getProcurementOfficerMenu() []models.NavigationItem {
-	// Omitting for brevity in this example
-	return getAdminMenu() // For now, let's give them the same menu as admin
+// buildMenuTree arranges items (already in display order) into a tree,
+// dropping items the user lacks the permission for along with their
+// descendants. Grouping items without a permission of their own are dropped
+// too when none of their children remain.
+func buildMenuTree(items []models.MenuItem, granted map[string]bool) []*menuNode {
+	nodes := make(map[int]*menuNode, len(items))
+	for _, item := range items {
+		nodes[item.ID] = &menuNode{item: item}
+	}
+
+	var roots []*menuNode
+	for _, item := range items {
+		node := nodes[item.ID]
+		if item.ParentID == nil {
+			roots = append(roots, node)
+		} else if parent, ok := nodes[*item.ParentID]; ok {
+			parent.children = append(parent.children, node)
+		}
+	}
+
+	var filter func(nodes []*menuNode) []*menuNode
+	filter = func(nodes []*menuNode) []*menuNode {
+		var visible []*menuNode
+		for _, node := range nodes {
+			if node.item.Permission != nil && !granted[*node.item.Permission] {
+				continue
+			}
+			hadChildren := len(node.children) > 0
+			node.children = filter(node.children)
+			if node.item.Permission == nil && hadChildren && len(node.children) == 0 {
+				continue
+			}
+			visible = append(visible, node)
+		}
+		return visible
+	}
+	return filter(roots)
 }
 
-func getApproverMenu() []models.NavigationItem {
-	// Omitting for brevity
-	return []models.NavigationItem{
-		{Title: "Dashboard", Path: "/dashboard", Icon: "dashboard"},
-		{Title: "Approvals", Path: "/approvals", Icon: "check_circle"},
+// validateMenuItem checks the parent exists without creating a cycle, that the
+// badge provider is registered and that the permission is in the catalogue.
+func (s *navigationService) validateMenuItem(item *models.MenuItem) error {
+	if item.Badge != nil {
+		s.mu.Lock()
+		_, ok := s.providers[*item.Badge]
+		s.mu.Unlock()
+		if !ok {
+			return ErrUnknownBadgeProvider
+		}
 	}
+
+	if item.ParentID != nil {
+		items, err := s.repo.GetAllMenuItems()
+		if err != nil {
+			return err
+		}
+		parents := make(map[int]*int, len(items))
+		for _, it := range items {
+			parents[it.ID] = it.ParentID
+		}
+		for id := item.ParentID; id != nil; id = parents[*id] {
+			if _, ok := parents[*id]; !ok || *id == item.ID {
+				return ErrInvalidMenuParent
+			}
+		}
+	}
+
+	if item.Permission != nil {
+		catalogue, err := s.roleService.GetAllPermissions()
+		if err != nil {
+			return err
+		}
+		for _, p := range catalogue {
+			if p.Code == *item.Permission {
+				return nil
+			}
+		}
+		return ErrUnknownPermission
+	}
+	return nil
+}
+
+func menuItemFromPayload(payload models.MenuItemPayload) *models.MenuItem {
+	item := &models.MenuItem{
+		ParentID:   payload.ParentID,
+		Title:      payload.Title,
+		Path:       payload.Path,
+		Icon:       payload.Icon,
+		SortOrder:  payload.SortOrder,
+		ShowInMenu: payload.ShowInMenu == nil || *payload.ShowInMenu,
+	}
+	if payload.Permission != nil && *payload.Permission != "" {
+		item.Permission = payload.Permission
+	}
+	if payload.Badge != nil && *payload.Badge != "" {
+		item.Badge = payload.Badge
+	}
+	return item
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
 }
 
-func getVendorMenu() []models.NavigationItem {
-	// Omitting for brevity
-	return []models.NavigationItem{
-		{Title: "Purchase Orders", Path: "/purchase-orders", Icon: "list_alt"},
-		{Title: "Invoices", Path: "/invoices", Icon: "receipt"},
+// matchSegments reports whether pattern matches the start of path and how
+// many parameter segments it used.
+func matchSegments(pattern, path []string) (int, bool) {
+	if len(pattern) == 0 || len(pattern) > len(path) {
+		return 0, false
+	}
+	params := 0
+	for i, seg := range pattern {
+		if strings.HasPrefix(seg, ":") {
+			params++
+			continue
+		}
+		if seg != path[i] {
+			return 0, false
+		}
 	}
+	return params, true
 }
 
-func getEmployeeMenu() []models.NavigationItem {
-	return []models.NavigationItem{
-		{Title: "Dashboard", Path: "/dashboard", Icon: "dashboard"},
-		{Title: "My Requisitions", Path: "/requisitions/my", Icon: "description"},
+// pathParams maps each :name segment of pattern to the value in path.
+func pathParams(pattern, path []string) map[string]string {
+	values := make(map[string]string)
+	for i, seg := range pattern {
+		if strings.HasPrefix(seg, ":") && i < len(path) {
+			values[seg] = path[i]
+		}
 	}
+	return values
+}
+
+// fillParams replaces :name placeholders in s with their values. Each
+// placeholder is matched whole, so :id never eats the start of :idx, and
+// substituted values are not scanned again.
+func fillParams(s string, values map[string]string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != ':' {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		end := i + 1
+		for end < len(s) && isParamChar(s[end]) {
+			end++
+		}
+		if value, ok := values[s[i:end]]; ok && end > i+1 {
+			b.WriteString(value)
+		} else {
+			b.WriteString(s[i:end])
+		}
+		i = end
+	}
+	return b.String()
+}
+
+func isParamChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
 }
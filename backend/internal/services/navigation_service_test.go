@@ -0,0 +1,222 @@
+package services
+
+import (
+	"procurement-system/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockNavigationRepository is a mock type for the NavigationRepository
+type MockNavigationRepository struct {
+	mock.Mock
+}
+
+func (m *MockNavigationRepository) GetAllMenuItems() ([]models.MenuItem, error) {
+	args := m.Called()
+	return args.Get(0).([]models.MenuItem), args.Error(1)
+}
+func (m *MockNavigationRepository) GetMenuItemByID(id int) (*models.MenuItem, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MenuItem), args.Error(1)
+}
+func (m *MockNavigationRepository) CreateMenuItem(item *models.MenuItem) error {
+	args := m.Called(item)
+	return args.Error(0)
+}
+func (m *MockNavigationRepository) UpdateMenuItem(item *models.MenuItem) error {
+	args := m.Called(item)
+	return args.Error(0)
+}
+func (m *MockNavigationRepository) DeleteMenuItem(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// MockRoleService is a mock type for the RoleService
+type MockRoleService struct {
+	mock.Mock
+}
+
+func (m *MockRoleService) GetAllPermissions() ([]models.Permission, error) {
+	args := m.Called()
+	return args.Get(0).([]models.Permission), args.Error(1)
+}
+func (m *MockRoleService) GetAllRoles() ([]models.Role, error) { return nil, nil }
+func (m *MockRoleService) CreateRole(actorID int, payload models.CreateRolePayload) (*models.Role, error) {
+	return nil, nil
+}
+func (m *MockRoleService) UpdateRole(actorID int, id int, payload models.UpdateRolePayload) (*models.Role, error) {
+	return nil, nil
+}
+func (m *MockRoleService) DeleteRole(actorID int, id int) error               { return nil }
+func (m *MockRoleService) GetUserRoles(userID int) (*models.UserRoles, error) { return nil, nil }
+func (m *MockRoleService) SetUserRoles(actorID int, userID int, payload models.SetUserRolesPayload) (*models.UserRoles, error) {
+	return nil, nil
+}
+func (m *MockRoleService) GetUserPermissions(userID int) (map[string]bool, error) {
+	args := m.Called(userID)
+	return args.Get(0).(map[string]bool), args.Error(1)
+}
+
+func TestNavigationService(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	menuItems := []models.MenuItem{
+		{ID: 1, Title: "Dashboard", Path: "/dashboard", ShowInMenu: true},
+		{ID: 2, Title: "Procurement", Path: "/procurement", ShowInMenu: true},
+		{ID: 3, ParentID: intPtr(2), Title: "My Requisitions", Path: "/procurement/requisitions/my", Permission: Ptr(models.PermRequisitionCreate), ShowInMenu: true},
+		{ID: 4, ParentID: intPtr(2), Title: "Requisitions", Path: "/procurement/requisitions", Permission: Ptr(models.PermRequisitionReadAll), ShowInMenu: true},
+		{ID: 5, ParentID: intPtr(4), Title: "Requisition :id", Path: "/procurement/requisitions/:id", Permission: Ptr(models.PermRequisitionReadAll)},
+		{ID: 6, Title: "Administration", Path: "/admin", ShowInMenu: true},
+		{ID: 7, ParentID: intPtr(6), Title: "User Management", Path: "/admin/users", Permission: Ptr(models.PermUserRead), ShowInMenu: true},
+	}
+
+	newService := func(perms map[string]bool) (NavigationService, *MockNavigationRepository) {
+		mockRepo := new(MockNavigationRepository)
+		mockRoles := new(MockRoleService)
+		mockRepo.On("GetAllMenuItems").Return(menuItems, nil)
+		mockRoles.On("GetUserPermissions", 1).Return(perms, nil)
+		mockRoles.On("GetAllPermissions").Return([]models.Permission{{Code: models.PermUserRead}}, nil)
+		return NewNavigationService(mockRepo, mockRoles, new(MockActivityLogService)), mockRepo
+	}
+
+	t.Run("GetMenu - Filters By Permission", func(t *testing.T) {
+		s, _ := newService(map[string]bool{models.PermRequisitionCreate: true})
+
+		menu, err := s.GetMenu(1)
+		assert.NoError(t, err)
+		// Administration has no visible children, so it is dropped entirely.
+		assert.Len(t, menu, 2)
+		assert.Equal(t, "Procurement", menu[1].Title)
+		assert.Equal(t, []models.NavigationSubItem{{Title: "My Requisitions", Path: "/procurement/requisitions/my"}}, menu[1].SubItems)
+	})
+
+	t.Run("GetBreadcrumbsForPath - Parameterised", func(t *testing.T) {
+		s, _ := newService(map[string]bool{models.PermRequisitionReadAll: true, models.PermRequisitionCreate: true})
+
+		crumbs, err := s.GetBreadcrumbsForPath(1, "/procurement/requisitions/42")
+		assert.NoError(t, err)
+		assert.Equal(t, []models.BreadcrumbItem{
+			{Title: "Procurement", Path: "/procurement"},
+			{Title: "Requisitions", Path: "/procurement/requisitions"},
+			{Title: "Requisition 42", Path: "/procurement/requisitions/42"},
+		}, crumbs)
+
+		// A literal segment beats a parameter.
+		crumbs, _ = s.GetBreadcrumbsForPath(1, "/procurement/requisitions/my")
+		assert.Equal(t, "My Requisitions", crumbs[len(crumbs)-1].Title)
+	})
+
+	t.Run("fillParams - Overlapping Placeholders", func(t *testing.T) {
+		values := map[string]string{":id": "7", ":idx": "3"}
+
+		for i := 0; i < 20; i++ {
+			assert.Equal(t, "/orders/7/lines/3", fillParams("/orders/:id/lines/:idx", values))
+		}
+		assert.Equal(t, "Line 3 of 7 (:other)", fillParams("Line :idx of :id (:other)", values))
+		assert.Equal(t, "/a/:id", fillParams("/a/:x", map[string]string{":x": ":id", ":id": "9"}))
+	})
+
+	t.Run("GetBreadcrumbsForPath - Prefix Fallback", func(t *testing.T) {
+		s, _ := newService(map[string]bool{})
+
+		crumbs, err := s.GetBreadcrumbsForPath(1, "/dashboard/widgets")
+		assert.NoError(t, err)
+		assert.Equal(t, []models.BreadcrumbItem{{Title: "Dashboard", Path: "/dashboard"}}, crumbs)
+	})
+
+	t.Run("UpdateMenuItem - Cycle", func(t *testing.T) {
+		s, mockRepo := newService(map[string]bool{})
+		mockRepo.On("GetMenuItemByID", 6).Return(&menuItems[5], nil)
+
+		_, err := s.UpdateMenuItem(1, 6, models.MenuItemPayload{ParentID: intPtr(7), Title: "Administration", Path: "/admin"})
+		assert.Equal(t, ErrInvalidMenuParent, err)
+		mockRepo.AssertNotCalled(t, "UpdateMenuItem", mock.Anything)
+	})
+
+	t.Run("CreateMenuItem - Unknown Permission", func(t *testing.T) {
+		s, mockRepo := newService(map[string]bool{})
+
+		_, err := s.CreateMenuItem(1, models.MenuItemPayload{Title: "Ghost", Path: "/ghost", Permission: Ptr("ghost:read")})
+		assert.Equal(t, ErrUnknownPermission, err)
+		mockRepo.AssertNotCalled(t, "CreateMenuItem", mock.Anything)
+	})
+
+	t.Run("GetMenu - Cached Badges", func(t *testing.T) {
+		mockRepo := new(MockNavigationRepository)
+		mockRoles := new(MockRoleService)
+		mockReqRepo := new(MockRequisitionRepository)
+		mockRepo.On("GetAllMenuItems").Return([]models.MenuItem{
+			{ID: 1, Title: "Procurement", Path: "/procurement", ShowInMenu: true},
+			{ID: 2, ParentID: intPtr(1), Title: "My Requisitions", Path: "/procurement/requisitions/my", Badge: Ptr(BadgeMyRejectedRequisitions), ShowInMenu: true},
+			{ID: 3, ParentID: intPtr(1), Title: "Approvals", Path: "/procurement/approvals", Badge: Ptr(BadgePendingApprovals), ShowInMenu: true},
+		}, nil)
+		mockRoles.On("GetUserPermissions", 1).Return(map[string]bool{models.PermRequisitionApprove: true}, nil)
+		mockDelegations := new(MockDelegationService)
+		mockDelegations.On("GetActiveDelegationsTo", 1).Return([]models.Delegation{}, nil)
+		mockReqRepo.On("CountRequisitions", intPtr(1), "Returned").Return(0, nil).Once()
+		mockReqRepo.On("CountRequisitions", intPtr(1), "Rejected").Return(2, nil).Once()
+		mockReqRepo.On("GetPendingRequisitions").Return([]models.Requisition{}, nil).Once()
+
+		now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		s := NewNavigationService(mockRepo, mockRoles, new(MockActivityLogService)).(*navigationService)
+		s.now = func() time.Time { return now }
+		requisitions := NewRequisitionService(mockReqRepo, nil, new(MockActivityLogService), mockRoles, mockDelegations, nil, nil)
+		RegisterDefaultBadgeProviders(s, requisitions, mockReqRepo, nil, nil, nil)
+
+		menu, err := s.GetMenu(1)
+		assert.NoError(t, err)
+		assert.Equal(t, &models.NavigationBadge{Count: 2, Label: "rejected"}, menu[0].SubItems[0].Badge)
+		assert.Nil(t, menu[0].SubItems[1].Badge)
+
+		// Within the TTL the counts come from the cache.
+		_, err = s.GetMenu(1)
+		assert.NoError(t, err)
+		mockReqRepo.AssertExpectations(t)
+
+		// Once expired they are recomputed.
+		now = now.Add(defaultBadgeCacheTTL)
+		mockReqRepo.On("CountRequisitions", intPtr(1), "Returned").Return(1, nil).Once()
+		mockReqRepo.On("CountRequisitions", intPtr(1), "Rejected").Return(0, nil).Once()
+		// Only requisitions the user may decide are counted, not those
+		// assigned to another approver.
+		mockReqRepo.On("GetPendingRequisitions").Return([]models.Requisition{
+			{ID: 1}, {ID: 2, ApproverID: intPtr(1)}, {ID: 3, ApproverID: intPtr(5)},
+		}, nil).Once()
+		menu, _ = s.GetMenu(1)
+		assert.Equal(t, &models.NavigationBadge{Count: 1, Label: "returned"}, menu[0].SubItems[0].Badge)
+		assert.Equal(t, 2, menu[0].SubItems[1].Badge.Count)
+	})
+
+	t.Run("Badge Providers - Purchasing Queues", func(t *testing.T) {
+		mockChangeOrders := new(MockChangeOrderRepository)
+		mockPoRepo := new(MockPurchaseOrderRepository)
+		mockDispatches := new(MockPODispatchRepository)
+		mockChangeOrders.On("GetPendingChangeOrders").Return([]models.ChangeOrder{
+			{ID: 1, RequestedBy: intPtr(2)}, {ID: 2, RequestedBy: intPtr(1)}, {ID: 3},
+		}, nil)
+		mockPoRepo.On("GetBuyQueue").Return([]models.Requisition{{ID: 4}, {ID: 5}}, nil)
+		mockDispatches.On("CountFailedPurchaseOrders").Return(0, nil).Once()
+		mockDispatches.On("CountFailedPurchaseOrders").Return(3, nil).Once()
+
+		// The user cannot approve the change they requested themselves.
+		badge, err := PendingChangeOrdersBadge(mockChangeOrders).Badge(1)
+		assert.NoError(t, err)
+		assert.Equal(t, &models.NavigationBadge{Count: 2}, badge)
+
+		badge, err = BuyQueueBadge(mockPoRepo).Badge(1)
+		assert.NoError(t, err)
+		assert.Equal(t, &models.NavigationBadge{Count: 2}, badge)
+
+		badge, err = FailedDispatchesBadge(mockDispatches).Badge(1)
+		assert.NoError(t, err)
+		assert.Nil(t, badge)
+		badge, _ = FailedDispatchesBadge(mockDispatches).Badge(1)
+		assert.Equal(t, &models.NavigationBadge{Count: 3, Label: "failed"}, badge)
+	})
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+)
+
+// notificationListLimit caps how many notifications are returned at once.
+const notificationListLimit = 100
+
+// NotificationService defines the interface for in-app notifications.
+type NotificationService interface {
+	Notify(userID int, kind string, message string, entityType *string, entityID *int) error
+	GetMyNotifications(userID int, unreadOnly bool) ([]models.Notification, error)
+	CountUnread(userID int) (int, error)
+	MarkRead(userID int, id int) error
+	MarkAllRead(userID int) error
+}
+
+type notificationService struct {
+	repo repository.NotificationRepository
+}
+
+// NewNotificationService creates a new instance of NotificationService.
+func NewNotificationService(repo repository.NotificationRepository) NotificationService {
+	return &notificationService{repo: repo}
+}
+
+// Notify sends a user a notification, optionally about an entity.
+func (s *notificationService) Notify(userID int, kind string, message string, entityType *string, entityID *int) error {
+	return s.repo.CreateNotification(&models.Notification{
+		UserID:     userID,
+		Kind:       kind,
+		Message:    message,
+		EntityType: entityType,
+		EntityID:   entityID,
+	})
+}
+
+// GetMyNotifications returns the user's most recent notifications.
+func (s *notificationService) GetMyNotifications(userID int, unreadOnly bool) ([]models.Notification, error) {
+	return s.repo.GetNotifications(userID, unreadOnly, notificationListLimit)
+}
+
+// CountUnread counts the user's unread notifications.
+func (s *notificationService) CountUnread(userID int) (int, error) {
+	return s.repo.CountUnread(userID)
+}
+
+// MarkRead marks one of the user's notifications as read.
+func (s *notificationService) MarkRead(userID int, id int) error {
+	return s.repo.MarkRead(userID, id)
+}
+
+// MarkAllRead marks all of the user's notifications as read.
+func (s *notificationService) MarkAllRead(userID int) error {
+	return s.repo.MarkAllRead(userID)
+}
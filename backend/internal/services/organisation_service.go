@@ -0,0 +1,448 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownUser             = errors.New("unknown user")
+	ErrUnknownDepartment       = errors.New("unknown department")
+	ErrInvalidDepartmentParent = errors.New("department parent does not exist or would create a cycle")
+	ErrInvalidManager          = errors.New("a user cannot report to themselves or to someone who reports to them")
+	ErrInvalidOrgChart         = errors.New("org chart import contains errors")
+)
+
+// OrganisationService defines the interface for managing departments and
+// reporting lines, and for resolving who a user reports to.
+type OrganisationService interface {
+	GetAllDepartments() ([]models.Department, error)
+	CreateDepartment(actorID int, payload models.DepartmentPayload) (*models.Department, error)
+	UpdateDepartment(actorID int, id int, payload models.DepartmentPayload) (*models.Department, error)
+	DeleteDepartment(actorID int, id int) error
+	SetUserOrganisation(actorID int, userID int, payload models.UserOrganisationPayload) (*models.User, error)
+	GetUserOrganisation(userID int) (*models.UserOrganisation, error)
+	GetLineManager(userID int) (*models.User, error)
+	GetDepartmentHead(userID int) (*models.User, error)
+	ImportOrgChart(actorID int, r io.Reader) (*models.OrgChartImportResult, error)
+}
+
+type organisationService struct {
+	repo       repository.DepartmentRepository
+	userRepo   repository.UserRepository
+	logService ActivityLogService
+}
+
+// NewOrganisationService creates a new instance of OrganisationService.
+func NewOrganisationService(repo repository.DepartmentRepository, userRepo repository.UserRepository, logService ActivityLogService) OrganisationService {
+	return &organisationService{repo: repo, userRepo: userRepo, logService: logService}
+}
+
+// GetAllDepartments returns every department.
+func (s *organisationService) GetAllDepartments() ([]models.Department, error) {
+	return s.repo.GetAllDepartments()
+}
+
+// CreateDepartment adds a department.
+func (s *organisationService) CreateDepartment(actorID int, payload models.DepartmentPayload) (*models.Department, error) {
+	dept := &models.Department{
+		Name:       strings.TrimSpace(payload.Name),
+		ParentID:   payload.ParentID,
+		HeadUserID: payload.HeadUserID,
+	}
+	if err := s.validateDepartment(dept); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateDepartment(dept); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "CREATE_DEPARTMENT_FAILED", Ptr("department"), nil, "FAILED", &details)
+		return nil, err
+	}
+
+	s.logService.Log(&actorID, "CREATE_DEPARTMENT_SUCCESS", Ptr("department"), &dept.ID, "SUCCESS", &dept.Name)
+	return dept, nil
+}
+
+// UpdateDepartment renames, moves or changes the head of a department.
+func (s *organisationService) UpdateDepartment(actorID int, id int, payload models.DepartmentPayload) (*models.Department, error) {
+	dept, err := s.repo.GetDepartmentByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	dept.Name = strings.TrimSpace(payload.Name)
+	dept.ParentID = payload.ParentID
+	dept.HeadUserID = payload.HeadUserID
+	if err := s.validateDepartment(dept); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateDepartment(dept); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "UPDATE_DEPARTMENT_FAILED", Ptr("department"), &id, "FAILED", &details)
+		return nil, err
+	}
+
+	s.logService.Log(&actorID, "UPDATE_DEPARTMENT_SUCCESS", Ptr("department"), &id, "SUCCESS", &dept.Name)
+	return dept, nil
+}
+
+// DeleteDepartment removes an empty department. Its members are left
+// without a department.
+func (s *organisationService) DeleteDepartment(actorID int, id int) error {
+	if err := s.repo.DeleteDepartment(id); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "DELETE_DEPARTMENT_FAILED", Ptr("department"), &id, "FAILED", &details)
+		return err
+	}
+
+	s.logService.Log(&actorID, "DELETE_DEPARTMENT_SUCCESS", Ptr("department"), &id, "SUCCESS", nil)
+	return nil
+}
+
+// validateDepartment checks that the parent exists without creating a cycle
+// and that the head is a known user.
+func (s *organisationService) validateDepartment(dept *models.Department) error {
+	if dept.ParentID != nil {
+		departments, err := s.repo.GetAllDepartments()
+		if err != nil {
+			return err
+		}
+		parents := make(map[int]*int, len(departments))
+		for _, d := range departments {
+			parents[d.ID] = d.ParentID
+		}
+
+		// Walk up from the new parent; reaching the department itself means a cycle.
+		for current := dept.ParentID; current != nil; {
+			if dept.ID != 0 && *current == dept.ID {
+				return ErrInvalidDepartmentParent
+			}
+			next, ok := parents[*current]
+			if !ok {
+				return ErrInvalidDepartmentParent
+			}
+			current = next
+		}
+	}
+
+	if dept.HeadUserID != nil {
+		if _, err := s.lookupUser(*dept.HeadUserID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetUserOrganisation sets a user's department and line manager.
+func (s *organisationService) SetUserOrganisation(actorID int, userID int, payload models.UserOrganisationPayload) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.DepartmentID != nil {
+		if _, err := s.repo.GetDepartmentByID(*payload.DepartmentID); err != nil {
+			if errors.Is(err, repository.ErrDepartmentNotFound) {
+				return nil, ErrUnknownDepartment
+			}
+			return nil, err
+		}
+	}
+
+	if payload.ManagerID != nil {
+		if err := s.checkManager(userID, *payload.ManagerID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.SetUserOrganisation(userID, payload.DepartmentID, payload.ManagerID); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "SET_USER_ORGANISATION_FAILED", Ptr("user"), &userID, "FAILED", &details)
+		return nil, err
+	}
+
+	user.DepartmentID = payload.DepartmentID
+	user.ManagerID = payload.ManagerID
+	details := fmt.Sprintf("Department: %s, manager: %s", formatOptionalID(user.DepartmentID), formatOptionalID(user.ManagerID))
+	s.logService.Log(&actorID, "SET_USER_ORGANISATION_SUCCESS", Ptr("user"), &userID, "SUCCESS", &details)
+	return user, nil
+}
+
+// checkManager verifies that managerID exists and that making them userID's
+// manager would not create a reporting cycle.
+func (s *organisationService) checkManager(userID, managerID int) error {
+	if managerID == userID {
+		return ErrInvalidManager
+	}
+
+	visited := map[int]bool{userID: true}
+	for current := &managerID; current != nil; {
+		if visited[*current] {
+			return ErrInvalidManager
+		}
+		visited[*current] = true
+
+		manager, err := s.lookupUser(*current)
+		if err != nil {
+			return err
+		}
+		current = manager.ManagerID
+	}
+	return nil
+}
+
+// GetUserOrganisation returns a user's department, line manager and
+// department head.
+func (s *organisationService) GetUserOrganisation(userID int) (*models.UserOrganisation, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	org := &models.UserOrganisation{UserID: userID}
+	if user.DepartmentID != nil {
+		if org.Department, err = s.repo.GetDepartmentByID(*user.DepartmentID); err != nil {
+			return nil, err
+		}
+	}
+	if org.LineManager, err = s.GetLineManager(userID); err != nil {
+		return nil, err
+	}
+	if org.DepartmentHead, err = s.GetDepartmentHead(userID); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// GetLineManager returns the user's line manager, or nil if none is set.
+func (s *organisationService) GetLineManager(userID int) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.ManagerID == nil {
+		return nil, nil
+	}
+	return s.userRepo.GetUserByID(*user.ManagerID)
+}
+
+// GetDepartmentHead returns the head of the user's department. If that
+// department has no head, or the user heads it themselves, the parent
+// departments are searched in turn. It returns nil if nobody is found.
+func (s *organisationService) GetDepartmentHead(userID int) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	visited := make(map[int]bool)
+	for current := user.DepartmentID; current != nil && !visited[*current]; {
+		visited[*current] = true
+
+		dept, err := s.repo.GetDepartmentByID(*current)
+		if err != nil {
+			return nil, err
+		}
+		if dept.HeadUserID != nil && *dept.HeadUserID != userID {
+			return s.userRepo.GetUserByID(*dept.HeadUserID)
+		}
+		current = dept.ParentID
+	}
+	return nil, nil
+}
+
+// lookupUser fetches a user referenced by another record, reporting a
+// missing user as ErrUnknownUser.
+func (s *organisationService) lookupUser(id int) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(id)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrUnknownUser
+	}
+	return user, err
+}
+
+// ImportOrgChart reads an org chart CSV and applies it. The header row must
+// include an email column and may include department, manager_email and
+// department_head. Departments are written as paths such as
+// "Operations/Procurement" and are created when missing. Every listed user's
+// department and manager are replaced; an empty value clears them. If any
+// line is invalid nothing is imported and the errors are returned with
+// ErrInvalidOrgChart.
+func (s *organisationService) ImportOrgChart(actorID int, r io.Reader) (*models.OrgChartImportResult, error) {
+	users, err := s.userRepo.GetAllUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	assignments, importErrors := parseOrgChart(r, users)
+	result := &models.OrgChartImportResult{Errors: importErrors}
+	if len(importErrors) > 0 {
+		details := fmt.Sprintf("%d invalid lines", len(importErrors))
+		s.logService.Log(&actorID, "IMPORT_ORG_CHART_FAILED", Ptr("organisation"), nil, "FAILED", &details)
+		return result, ErrInvalidOrgChart
+	}
+
+	created, err := s.repo.ImportOrgChart(assignments)
+	if err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "IMPORT_ORG_CHART_FAILED", Ptr("organisation"), nil, "FAILED", &details)
+		return nil, err
+	}
+
+	result.DepartmentsCreated = created
+	result.UsersUpdated = len(assignments)
+	details := fmt.Sprintf("%d users updated, %d departments created", result.UsersUpdated, result.DepartmentsCreated)
+	s.logService.Log(&actorID, "IMPORT_ORG_CHART_SUCCESS", Ptr("organisation"), nil, "SUCCESS", &details)
+	return result, nil
+}
+
+// parseOrgChart turns CSV input into assignments, resolving email addresses
+// against users. Line numbers in errors count the header as line 1.
+func parseOrgChart(r io.Reader, users []models.User) ([]models.OrgChartAssignment, []models.OrgChartImportError) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, []models.OrgChartImportError{{Line: 1, Message: "missing or unreadable header row"}}
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, []models.OrgChartImportError{{Line: 1, Message: "header row must include an email column"}}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	byEmail := make(map[string]*models.User, len(users))
+	managers := make(map[int]*int, len(users))
+	for i := range users {
+		byEmail[strings.ToLower(users[i].Email)] = &users[i]
+		managers[users[i].ID] = users[i].ManagerID
+	}
+
+	var assignments []models.OrgChartAssignment
+	var importErrors []models.OrgChartImportError
+	lines := make(map[int]int)
+	heads := make(map[string]int)
+	fail := func(line int, format string, args ...interface{}) {
+		importErrors = append(importErrors, models.OrgChartImportError{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fail(line, "%v", err)
+			continue
+		}
+
+		email := field(record, "email")
+		user, ok := byEmail[strings.ToLower(email)]
+		if !ok {
+			fail(line, "unknown user %q", email)
+			continue
+		}
+		if previous, ok := lines[user.ID]; ok {
+			fail(line, "%s is already listed on line %d", email, previous)
+			continue
+		}
+		lines[user.ID] = line
+
+		assignment := models.OrgChartAssignment{UserID: user.ID}
+		if department := field(record, "department"); department != "" {
+			for _, name := range strings.Split(department, "/") {
+				if name = strings.TrimSpace(name); name == "" {
+					fail(line, "invalid department path %q", department)
+					assignment.DepartmentPath = nil
+					break
+				}
+				assignment.DepartmentPath = append(assignment.DepartmentPath, name)
+			}
+		}
+
+		if managerEmail := field(record, "manager_email"); managerEmail != "" {
+			manager, ok := byEmail[strings.ToLower(managerEmail)]
+			switch {
+			case !ok:
+				fail(line, "unknown manager %q", managerEmail)
+			case manager.ID == user.ID:
+				fail(line, "%s cannot be their own manager", email)
+			default:
+				assignment.ManagerID = &manager.ID
+			}
+		}
+		managers[user.ID] = assignment.ManagerID
+
+		if value := field(record, "department_head"); value != "" {
+			isHead, err := parseYesNo(value)
+			switch {
+			case err != nil:
+				fail(line, "invalid department_head value %q", value)
+			case isHead && assignment.DepartmentPath == nil:
+				fail(line, "%s cannot head a department without being in one", email)
+			case isHead:
+				key := strings.Join(assignment.DepartmentPath, "/")
+				if previous, ok := heads[key]; ok {
+					fail(line, "%s already has a head on line %d", key, previous)
+				} else {
+					heads[key] = line
+					assignment.DepartmentHead = true
+				}
+			}
+		}
+
+		assignments = append(assignments, assignment)
+	}
+
+	// Reporting lines are checked once every row is known, since a cycle can
+	// span rows or involve users who are not in the file.
+	for _, a := range assignments {
+		visited := make(map[int]bool)
+		for current := managers[a.UserID]; current != nil && !visited[*current]; current = managers[*current] {
+			if *current == a.UserID {
+				fail(lines[a.UserID], "reporting line loops back to this user")
+				break
+			}
+			visited[*current] = true
+		}
+	}
+
+	return assignments, importErrors
+}
+
+// parseYesNo parses a CSV flag, accepting yes/no as well as the forms
+// understood by strconv.ParseBool.
+func parseYesNo(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "y":
+		return true, nil
+	case "no", "n":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// formatOptionalID renders an optional ID for activity log details.
+func formatOptionalID(id *int) string {
+	if id == nil {
+		return "none"
+	}
+	return strconv.Itoa(*id)
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockDepartmentRepository is a mock type for the DepartmentRepository
+type MockDepartmentRepository struct {
+	mock.Mock
+}
+
+func (m *MockDepartmentRepository) GetAllDepartments() ([]models.Department, error) {
+	args := m.Called()
+	return args.Get(0).([]models.Department), args.Error(1)
+}
+func (m *MockDepartmentRepository) GetDepartmentByID(id int) (*models.Department, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Department), args.Error(1)
+}
+func (m *MockDepartmentRepository) CreateDepartment(dept *models.Department) error {
+	args := m.Called(dept)
+	return args.Error(0)
+}
+func (m *MockDepartmentRepository) UpdateDepartment(dept *models.Department) error {
+	args := m.Called(dept)
+	return args.Error(0)
+}
+func (m *MockDepartmentRepository) DeleteDepartment(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+func (m *MockDepartmentRepository) SetUserOrganisation(userID int, departmentID, managerID *int) error {
+	args := m.Called(userID, departmentID, managerID)
+	return args.Error(0)
+}
+func (m *MockDepartmentRepository) ImportOrgChart(assignments []models.OrgChartAssignment) (int, error) {
+	args := m.Called(assignments)
+	return args.Int(0), args.Error(1)
+}
+
+func TestOrganisationService(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+
+	newService := func() (OrganisationService, *MockDepartmentRepository, *MockUserRepository) {
+		mockRepo := new(MockDepartmentRepository)
+		mockUserRepo := new(MockUserRepository)
+		mockLog := new(MockActivityLogService)
+		mockLog.On("Log", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+		return NewOrganisationService(mockRepo, mockUserRepo, mockLog), mockRepo, mockUserRepo
+	}
+
+	t.Run("GetLineManager", func(t *testing.T) {
+		s, _, mockUserRepo := newService()
+		mockUserRepo.On("GetUserByID", 1).Return(&models.User{ID: 1, ManagerID: intPtr(2)}, nil)
+		mockUserRepo.On("GetUserByID", 2).Return(&models.User{ID: 2, Name: "Manager"}, nil)
+
+		manager, err := s.GetLineManager(1)
+		assert.NoError(t, err)
+		assert.Equal(t, "Manager", manager.Name)
+
+		// Nobody above the manager.
+		manager, err = s.GetLineManager(2)
+		assert.NoError(t, err)
+		assert.Nil(t, manager)
+	})
+
+	t.Run("GetDepartmentHead - Walks Up From Own Department", func(t *testing.T) {
+		s, mockRepo, mockUserRepo := newService()
+		// User 5 heads Procurement, so their head is the head of its parent, Operations.
+		mockUserRepo.On("GetUserByID", 5).Return(&models.User{ID: 5, DepartmentID: intPtr(20)}, nil)
+		mockUserRepo.On("GetUserByID", 9).Return(&models.User{ID: 9, Name: "COO"}, nil)
+		mockRepo.On("GetDepartmentByID", 20).Return(&models.Department{ID: 20, Name: "Procurement", ParentID: intPtr(10), HeadUserID: intPtr(5)}, nil)
+		mockRepo.On("GetDepartmentByID", 10).Return(&models.Department{ID: 10, Name: "Operations", HeadUserID: intPtr(9)}, nil)
+
+		head, err := s.GetDepartmentHead(5)
+		assert.NoError(t, err)
+		assert.Equal(t, 9, head.ID)
+	})
+
+	t.Run("SetUserOrganisation - Manager Cycle", func(t *testing.T) {
+		s, mockRepo, mockUserRepo := newService()
+		// 2 reports to 3, who reports to 1; making 1 report to 2 closes the loop.
+		mockUserRepo.On("GetUserByID", 1).Return(&models.User{ID: 1}, nil)
+		mockUserRepo.On("GetUserByID", 2).Return(&models.User{ID: 2, ManagerID: intPtr(3)}, nil)
+		mockUserRepo.On("GetUserByID", 3).Return(&models.User{ID: 3, ManagerID: intPtr(1)}, nil)
+
+		_, err := s.SetUserOrganisation(99, 1, models.UserOrganisationPayload{ManagerID: intPtr(2)})
+		assert.Equal(t, ErrInvalidManager, err)
+		mockRepo.AssertNotCalled(t, "SetUserOrganisation", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("SetUserOrganisation - Unknown Department", func(t *testing.T) {
+		s, mockRepo, mockUserRepo := newService()
+		mockUserRepo.On("GetUserByID", 1).Return(&models.User{ID: 1}, nil)
+		mockRepo.On("GetDepartmentByID", 42).Return(nil, repository.ErrDepartmentNotFound)
+
+		_, err := s.SetUserOrganisation(99, 1, models.UserOrganisationPayload{DepartmentID: intPtr(42)})
+		assert.Equal(t, ErrUnknownDepartment, err)
+	})
+
+	t.Run("UpdateDepartment - Parent Cycle", func(t *testing.T) {
+		s, mockRepo, _ := newService()
+		mockRepo.On("GetDepartmentByID", 10).Return(&models.Department{ID: 10, Name: "Operations"}, nil)
+		mockRepo.On("GetAllDepartments").Return([]models.Department{
+			{ID: 10, Name: "Operations"},
+			{ID: 20, Name: "Procurement", ParentID: intPtr(10)},
+		}, nil)
+
+		_, err := s.UpdateDepartment(99, 10, models.DepartmentPayload{Name: "Operations", ParentID: intPtr(20)})
+		assert.Equal(t, ErrInvalidDepartmentParent, err)
+		mockRepo.AssertNotCalled(t, "UpdateDepartment", mock.Anything)
+	})
+
+	users := []models.User{
+		{ID: 1, Email: "ceo@example.com"},
+		{ID: 2, Email: "ops@example.com", ManagerID: intPtr(1)},
+		{ID: 3, Email: "buyer@example.com"},
+	}
+
+	t.Run("ImportOrgChart - Success", func(t *testing.T) {
+		s, mockRepo, mockUserRepo := newService()
+		mockUserRepo.On("GetAllUsers").Return(users, nil)
+		expected := []models.OrgChartAssignment{
+			{UserID: 1, DepartmentPath: []string{"Executive"}, DepartmentHead: true},
+			{UserID: 2, DepartmentPath: []string{"Operations"}, ManagerID: intPtr(1), DepartmentHead: true},
+			{UserID: 3, DepartmentPath: []string{"Operations", "Procurement"}, ManagerID: intPtr(2)},
+		}
+		mockRepo.On("ImportOrgChart", expected).Return(3, nil)
+
+		csv := "email,department,manager_email,department_head\n" +
+			"ceo@example.com,Executive,,yes\n" +
+			"OPS@example.com,Operations,ceo@example.com,true\n" +
+			"buyer@example.com, Operations / Procurement ,ops@example.com,\n"
+		result, err := s.ImportOrgChart(99, strings.NewReader(csv))
+		assert.NoError(t, err)
+		assert.Equal(t, &models.OrgChartImportResult{DepartmentsCreated: 3, UsersUpdated: 3}, result)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("ImportOrgChart - Rejects Invalid Lines", func(t *testing.T) {
+		s, mockRepo, mockUserRepo := newService()
+		mockUserRepo.On("GetAllUsers").Return(users, nil)
+
+		csv := "email,department,manager_email\n" +
+			"ghost@example.com,Operations,\n" +
+			"ceo@example.com,Executive,ops@example.com\n" +
+			"buyer@example.com,Operations,nobody@example.com\n"
+		result, err := s.ImportOrgChart(99, strings.NewReader(csv))
+		assert.Equal(t, ErrInvalidOrgChart, err)
+		assert.Equal(t, []models.OrgChartImportError{
+			{Line: 2, Message: `unknown user "ghost@example.com"`},
+			{Line: 4, Message: `unknown manager "nobody@example.com"`},
+			// ops already reports to ceo, so ceo cannot report to ops.
+			{Line: 3, Message: "reporting line loops back to this user"},
+		}, result.Errors)
+		mockRepo.AssertNotCalled(t, "ImportOrgChart", mock.Anything)
+	})
+}
@@ -0,0 +1,321 @@
+package services
+
+import (
+	"fmt"
+	"procurement-system/internal/models"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	pdfMargin       = 15.0 // Left, top and right page margins, in mm
+	pdfBottomMargin = 25.0 // Space kept free for the footer, in mm
+)
+
+// pdfColumn is a column of the items table.
+type pdfColumn struct {
+	title string
+	width float64
+	align string
+	value func(n int, item models.PDFItem) string
+}
+
+// poDocument draws a purchase order over as many pages as its items need.
+type poDocument struct {
+	pdf     *gofpdf.Fpdf
+	data    *models.PDFData
+	layout  PDFLayout
+	tr      func(string) string
+	columns []pdfColumn
+}
+
+func newPODocument(data *models.PDFData, layout PDFLayout) *poDocument {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(false, pdfBottomMargin)
+	pdf.AliasNbPages("")
+
+	d := &poDocument{
+		pdf:    pdf,
+		data:   data,
+		layout: layout,
+		tr:     pdf.UnicodeTranslatorFromDescriptor(""),
+	}
+	d.columns = d.itemColumns()
+	pdf.SetHeaderFunc(d.header)
+	pdf.SetFooterFunc(d.footer)
+	pdf.AddPage()
+	return d
+}
+
+// itemColumns lays out the items table across the page. The description
+// takes whatever width the other columns leave.
+func (d *poDocument) itemColumns() []pdfColumn {
+	money := func(v float64) string { return fmt.Sprintf("%.2f", v) }
+	columns := []pdfColumn{
+		{title: "#", width: 8, align: "R", value: func(n int, _ models.PDFItem) string { return fmt.Sprint(n) }},
+		{title: "Description", align: "L", value: func(_ int, item models.PDFItem) string { return item.Desc }},
+	}
+	if d.layout.showDelivery {
+		columns = append(columns, pdfColumn{title: "Delivery", width: 22, align: "L", value: func(_ int, item models.PDFItem) string { return item.DeliveryDate }})
+	}
+	columns = append(columns,
+		pdfColumn{title: "Qty", width: 18, align: "R", value: func(_ int, item models.PDFItem) string {
+			return strings.TrimSpace(fmt.Sprintf("%.2f %s", item.Qty, item.Uom))
+		}},
+		pdfColumn{title: "Unit Price", width: 24, align: "R", value: func(_ int, item models.PDFItem) string { return money(item.UPrice) }},
+	)
+	columns = append(columns, pdfColumn{title: "Amount", width: 26, align: "R", value: func(_ int, item models.PDFItem) string {
+		return money(roundAmount(item.Qty * item.UPrice))
+	}})
+
+	used := 0.0
+	for _, c := range columns {
+		used += c.width
+	}
+	columns[1].width = d.contentWidth() - used
+	return columns
+}
+
+func (d *poDocument) contentWidth() float64 {
+	pageWidth, _ := d.pdf.GetPageSize()
+	return pageWidth - 2*pdfMargin
+}
+
+// ensureSpace starts a new page unless height mm still fit above the footer,
+// and reports whether it did.
+func (d *poDocument) ensureSpace(height float64) bool {
+	_, pageHeight := d.pdf.GetPageSize()
+	if d.pdf.GetY()+height <= pageHeight-pdfBottomMargin {
+		return false
+	}
+	d.pdf.AddPage()
+	return true
+}
+
+// header runs at the start of every page. Cancelled orders are watermarked
+// on every page, and pages after the first say which order they continue.
+func (d *poDocument) header() {
+	if d.data.Status == models.PurchaseOrderStatusCancelled {
+		drawWatermark(d.pdf, "CANCELLED")
+	}
+	if d.pdf.PageNo() == 1 {
+		return
+	}
+	d.pdf.SetFont("Arial", "I", 8)
+	d.pdf.CellFormat(0, 5, d.tr(fmt.Sprintf("Purchase Order %s (continued)", d.data.ReceiptNo)), "B", 1, "L", false, 0, "")
+	d.pdf.Ln(3)
+}
+
+// footer prints the company's footer text and the page number.
+func (d *poDocument) footer() {
+	if d.data.Footer != "" {
+		d.pdf.SetY(-pdfBottomMargin + 3)
+		d.pdf.SetFont("Arial", "I", 8)
+		d.pdf.MultiCell(0, 4, d.tr(d.data.Footer), "", "C", false)
+	}
+	d.pdf.SetY(-10)
+	d.pdf.SetFont("Arial", "", 8)
+	d.pdf.CellFormat(0, 5, fmt.Sprintf("Page %d of {nb}", d.pdf.PageNo()), "", 0, "R", false, 0, "")
+}
+
+// drawFirstPageHeader prints the logo, title, company and vendor details and
+// the order's number, date and revision.
+func (d *poDocument) drawFirstPageHeader() {
+	pdf, data := d.pdf, d.data
+	if len(data.Logo) > 0 {
+		drawLogo(pdf, data.Logo, data.LogoType)
+	}
+
+	pdf.SetFont("Arial", "B", 16)
+	pdf.Cell(100, 10, "Purchase Order")
+	pdf.Ln(15)
+
+	half := d.contentWidth() / 2
+	pdf.SetFont("Arial", "B", 11)
+	pdf.Cell(half, 6, d.tr(data.CompanyName))
+	pdf.Cell(half, 6, "Vendor:")
+	pdf.Ln(6)
+
+	company := []string{companyIdentifiers(data), data.CompanyAddress, data.CompanyEmail, data.CompanyPhones}
+	vendor := []string{data.CustomerName, data.CustomerAddress, data.CustomerEmail, data.CustomerPhone}
+	pdf.SetFont("Arial", "", 9)
+	top := pdf.GetY()
+	d.drawBlock(pdfMargin, half, company)
+	bottom := pdf.GetY()
+	pdf.SetY(top)
+	d.drawBlock(pdfMargin+half, half, vendor)
+	if pdf.GetY() < bottom {
+		pdf.SetY(bottom)
+	}
+	pdf.Ln(6)
+
+	details := [][2]string{{"PO Number:", data.ReceiptNo}, {"Date:", data.ReceiptDate}}
+	if data.Revision > 0 {
+		details = append(details, [2]string{"Revision:", fmt.Sprintf("Revision %d", data.Revision)})
+	}
+	if data.Currency != "" {
+		details = append(details, [2]string{"Currency:", data.Currency})
+	}
+	for _, detail := range details {
+		pdf.SetFont("Arial", "B", 10)
+		pdf.Cell(30, 6, detail[0])
+		pdf.SetFont("Arial", "", 10)
+		pdf.Cell(60, 6, d.tr(detail[1]))
+		pdf.Ln(6)
+	}
+	pdf.Ln(6)
+}
+
+// drawBlock prints the non-empty lines one under another at x, wrapping those
+// wider than width.
+func (d *poDocument) drawBlock(x, width float64, lines []string) {
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		d.pdf.SetX(x)
+		d.pdf.MultiCell(width, 5, d.tr(line), "", "L", false)
+	}
+}
+
+// drawTableHeader prints the titles of the items table.
+func (d *poDocument) drawTableHeader() {
+	pdf, layout := d.pdf, d.layout
+	pdf.SetFont("Arial", "B", layout.fontSize)
+	pdf.SetFillColor(layout.accent[0], layout.accent[1], layout.accent[2])
+	pdf.SetTextColor(layout.headerText[0], layout.headerText[1], layout.headerText[2])
+	for _, c := range d.columns {
+		pdf.CellFormat(c.width, layout.lineHeight+2, c.title, "", 0, c.align, true, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// drawItems prints the items table. Each row grows to fit its wrapped text,
+// rows are never split across pages, and the table header is repeated on
+// every page the table reaches. Lines changed in this revision are
+// highlighted.
+func (d *poDocument) drawItems() {
+	pdf, layout := d.pdf, d.layout
+	d.ensureSpace(3 * layout.lineHeight)
+	d.drawTableHeader()
+
+	var anyChanged bool
+	for i, item := range d.data.Items {
+		pdf.SetFont("Arial", "", layout.fontSize)
+		cells := make([][]string, len(d.columns))
+		lines := 1
+		for j, c := range d.columns {
+			cells[j] = pdf.SplitText(latin1(c.value(i+1, item)), c.width)
+			if len(cells[j]) > lines {
+				lines = len(cells[j])
+			}
+		}
+		height := float64(lines) * layout.lineHeight
+
+		if d.ensureSpace(height) {
+			d.drawTableHeader()
+			pdf.SetFont("Arial", "", layout.fontSize)
+		}
+
+		x, y := pdf.GetX(), pdf.GetY()
+		switch {
+		case item.Changed:
+			anyChanged = true
+			pdf.SetFillColor(255, 243, 176)
+			pdf.Rect(x, y, d.contentWidth(), height, "F")
+		case layout.zebra && i%2 == 1:
+			pdf.SetFillColor(240, 244, 248)
+			pdf.Rect(x, y, d.contentWidth(), height, "F")
+		}
+		for j, c := range d.columns {
+			for k, text := range cells[j] {
+				pdf.SetXY(x, y+float64(k)*layout.lineHeight)
+				pdf.CellFormat(c.width, layout.lineHeight, d.tr(text), "", 0, c.align, false, 0, "")
+			}
+			x += c.width
+		}
+		pdf.SetDrawColor(210, 210, 210)
+		pdf.Line(pdfMargin, y+height, pdfMargin+d.contentWidth(), y+height)
+		pdf.SetDrawColor(0, 0, 0)
+		pdf.SetXY(pdfMargin, y+height)
+	}
+
+	if anyChanged {
+		d.ensureSpace(6)
+		pdf.SetFont("Arial", "I", 8)
+		pdf.Cell(0, 6, fmt.Sprintf("Highlighted lines were changed in revision %d.", d.data.Revision))
+		pdf.Ln(6)
+	}
+}
+
+// drawTotals prints the subtotal, tax, rounding adjustment and grand total,
+// leaving out the rows that are zero, and the grand total in words.
+func (d *poDocument) drawTotals() {
+	pdf, data := d.pdf, d.data
+	rows := [][2]string{{"Subtotal", fmt.Sprintf("%.2f", data.Subtotal)}}
+	if data.TaxRate != 0 {
+		rows = append(rows, [2]string{fmt.Sprintf("%s (%g%%)", data.TaxLabel, data.TaxRate), fmt.Sprintf("%.2f", data.TaxAmount)})
+	}
+	if data.RoundingAdj != 0 {
+		rows = append(rows, [2]string{"Rounding", fmt.Sprintf("%+.2f", data.RoundingAdj)})
+	}
+
+	const labelWidth, amountWidth = 40.0, 30.0
+	left := pdfMargin + d.contentWidth() - labelWidth - amountWidth
+	d.ensureSpace(float64(len(rows)+3)*6 + 4)
+	pdf.Ln(4)
+	pdf.SetFont("Arial", "", 10)
+	for _, row := range rows {
+		pdf.SetX(left)
+		pdf.CellFormat(labelWidth, 6, d.tr(row[0]), "", 0, "L", false, 0, "")
+		pdf.CellFormat(amountWidth, 6, row[1], "", 1, "R", false, 0, "")
+	}
+
+	accent, text := d.layout.accent, d.layout.headerText
+	pdf.SetX(left)
+	pdf.SetFont("Arial", "B", 11)
+	pdf.SetFillColor(accent[0], accent[1], accent[2])
+	pdf.SetTextColor(text[0], text[1], text[2])
+	pdf.CellFormat(labelWidth, 8, d.tr(joinNonEmpty(" ", "Grand Total", data.Currency)), "", 0, "L", true, 0, "")
+	pdf.CellFormat(amountWidth, 8, fmt.Sprintf("%.2f", data.GrandTotal), "", 1, "R", true, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+
+	pdf.Ln(2)
+	pdf.SetFont("Arial", "I", 9)
+	pdf.MultiCell(0, 5, d.tr(data.AmountInWords), "", "L", false)
+}
+
+// drawPaymentDetails prints the payment terms and bank details.
+func (d *poDocument) drawPaymentDetails() {
+	pdf, data := d.pdf, d.data
+	var lines []string
+	if data.PaymentMethod != "" {
+		lines = append(lines, "Payment Terms: "+data.PaymentMethod)
+	}
+	if bank := joinNonEmpty(", ", data.BankName, data.BankAccount); bank != "" {
+		lines = append(lines, "Bank: "+bank)
+	}
+	if len(lines) == 0 {
+		return
+	}
+	d.ensureSpace(float64(len(lines))*5 + 6)
+	pdf.Ln(6)
+	pdf.SetFont("Arial", "", 10)
+	for _, line := range lines {
+		pdf.MultiCell(0, 5, d.tr(line), "", "L", false)
+	}
+}
+
+// latin1 replaces the characters the built-in PDF fonts cannot measure, so
+// that text can be wrapped before it is translated for printing.
+func latin1(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r > 0xff {
+			return '?'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownPDFLayout = errors.New("unknown PDF layout")
+
+// DefaultPDFLayout is used when neither the vendor nor the company settings
+// choose a layout.
+const DefaultPDFLayout = "standard"
+
+// PDFLayout is one of the built-in ways of drawing a purchase order PDF.
+type PDFLayout struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	fontSize     float64 // Body text, in points
+	lineHeight   float64 // Height of one line of a table row, in mm
+	accent       [3]int  // Colour of the table header and the grand total
+	headerText   [3]int  // Colour of the text on the table header
+	zebra        bool    // Shade every other item row
+	showDelivery bool    // Print a delivery date column
+}
+
+var pdfLayouts = []PDFLayout{
+	{
+		Name:         "standard",
+		Description:  "Plain black and white, with a delivery date column.",
+		fontSize:     10,
+		lineHeight:   5,
+		accent:       [3]int{230, 230, 230},
+		headerText:   [3]int{0, 0, 0},
+		showDelivery: true,
+	},
+	{
+		Name:        "compact",
+		Description: "Smaller text without the delivery date column, for long orders.",
+		fontSize:    8,
+		lineHeight:  4,
+		accent:      [3]int{230, 230, 230},
+		headerText:  [3]int{0, 0, 0},
+	},
+	{
+		Name:         "branded",
+		Description:  "Coloured table header and shaded rows.",
+		fontSize:     10,
+		lineHeight:   5,
+		accent:       [3]int{31, 78, 121},
+		headerText:   [3]int{255, 255, 255},
+		zebra:        true,
+		showDelivery: true,
+	},
+}
+
+// PDFLayouts returns the layouts that can be chosen.
+func PDFLayouts() []PDFLayout {
+	return append([]PDFLayout(nil), pdfLayouts...)
+}
+
+// validatePDFLayout fails with ErrUnknownPDFLayout unless name is a layout.
+func validatePDFLayout(name string) error {
+	if _, ok := findPDFLayout(name); !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownPDFLayout, name)
+	}
+	return nil
+}
+
+// resolvePDFLayout returns the layout called name. An unknown or empty name
+// gets the default layout, so that a layout removed from the backend does not
+// stop PDFs from being generated.
+func resolvePDFLayout(name string) PDFLayout {
+	if layout, ok := findPDFLayout(name); ok {
+		return layout
+	}
+	layout, _ := findPDFLayout(DefaultPDFLayout)
+	return layout
+}
+
+func findPDFLayout(name string) (PDFLayout, bool) {
+	for _, layout := range pdfLayouts {
+		if layout.Name == name {
+			return layout, true
+		}
+	}
+	return PDFLayout{}, false
+}
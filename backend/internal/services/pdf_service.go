@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"procurement-system/internal/models"
+	"procurement-system/pkg/amountwords"
+	"strings"
 
 	"github.com/jung-kurt/gofpdf"
 )
@@ -12,80 +14,142 @@ type PDFService interface {
 	GeneratePurchaseOrderPDF(data *models.PDFData) (*bytes.Buffer, error)
 }
 
-type pdfService struct{}
+type pdfService struct {
+	settings CompanySettingsService
+}
 
-func NewPDFService() PDFService {
-	return &pdfService{}
+// NewPDFService creates a PDFService that prints the company profile and
+// branding from settings.
+func NewPDFService(settings CompanySettingsService) PDFService {
+	return &pdfService{settings: settings}
 }
 
+// GeneratePurchaseOrderPDF draws a purchase order in the layout chosen for
+// its vendor, or else the company's default layout. The items table runs over
+// as many pages as needed, repeating its header on each, and the totals and
+// AmountInWords are worked out from the items.
 func (s *pdfService) GeneratePurchaseOrderPDF(data *models.PDFData) (*bytes.Buffer, error) {
-	pdf := gofpdf.New("P", "mm", "A4", "")
-	pdf.AddPage()
-	pdf.SetFont("Arial", "B", 16)
+	if err := s.applyCompanySettings(data); err != nil {
+		return nil, err
+	}
+	computePDFTotals(data)
 
-	// Header
-	pdf.Cell(40, 10, "Purchase Order")
-	pdf.Ln(20)
+	doc := newPODocument(data, resolvePDFLayout(data.Layout))
+	doc.drawFirstPageHeader()
+	doc.drawItems()
+	doc.drawTotals()
+	doc.drawPaymentDetails()
 
-	// Company and Vendor Info
-	pdf.SetFont("Arial", "B", 12)
-	pdf.Cell(100, 10, data.CompanyName)
-	pdf.Cell(40, 10, "Vendor:")
-	pdf.Ln(5)
-	pdf.SetFont("Arial", "", 10)
-	pdf.Cell(100, 10, data.CompanyAddress)
-	pdf.Cell(40, 10, data.CustomerName)
-	pdf.Ln(5)
-	pdf.Cell(100, 10, data.CompanyEmail)
-	pdf.Cell(40, 10, data.CustomerAddress)
-	pdf.Ln(5)
-	pdf.Cell(100, 10, data.CompanyPhones)
-	pdf.Cell(40, 10, data.CustomerEmail)
-	pdf.Ln(20)
+	var buf bytes.Buffer
+	if err := doc.pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
 
-	// PO Details
-	pdf.SetFont("Arial", "B", 12)
-	pdf.Cell(40, 10, "PO Number:")
-	pdf.SetFont("Arial", "", 12)
-	pdf.Cell(40, 10, data.ReceiptNo)
-	pdf.Ln(5)
-	pdf.SetFont("Arial", "B", 12)
-	pdf.Cell(40, 10, "Date:")
-	pdf.SetFont("Arial", "", 12)
-	pdf.Cell(40, 10, data.ReceiptDate)
-	pdf.Ln(20)
+// computePDFTotals works out the subtotal, tax, rounding adjustment and grand
+// total of data's items at the order's tax rate and rounding step, as
+// models.PurchaseOrder.Totals does, and spells out the grand total.
+func computePDFTotals(data *models.PDFData) {
+	var subtotal float64
+	for _, item := range data.Items {
+		subtotal += roundAmount(item.Qty * item.UPrice)
+	}
+	totals := models.ComputeOrderTotals(subtotal, data.TaxRate, data.RoundingStep)
+	data.Subtotal = totals.Subtotal
+	data.TaxAmount = totals.Tax
+	data.RoundingAdj = totals.Rounding
+	data.GrandTotal = totals.Grand
+	data.AmountInWords = amountwords.Format(data.GrandTotal, data.Currency)
+}
 
-	// Items Table Header
-	pdf.SetFont("Arial", "B", 10)
-	pdf.Cell(100, 10, "Description")
-	pdf.Cell(30, 10, "Quantity")
-	pdf.Cell(30, 10, "Unit Price")
-	pdf.Cell(30, 10, "Total")
-	pdf.Ln(10)
+// applyCompanySettings fills in the company profile and branding, and the
+// company's layout unless the vendor has its own. Tax and rounding come with
+// the order, as they were when it was issued.
+func (s *pdfService) applyCompanySettings(data *models.PDFData) error {
+	settings, err := s.settings.GetCompanySettings()
+	if err != nil {
+		return fmt.Errorf("loading company settings: %w", err)
+	}
+	data.CompanyName = settings.CompanyName
+	data.RegNo = settings.RegNo
+	data.TinNo = settings.TinNo
+	data.MsicCode = settings.MsicCode
+	data.CompanyAddress = settings.Address
+	data.CompanyPhones = settings.Phones
+	data.CompanyEmail = settings.Email
+	data.BankName = settings.BankName
+	data.BankAccount = settings.BankAccount
+	data.PaymentMethod = settings.PaymentTerms
+	data.Footer = settings.PDFFooter
+	if data.Layout == "" {
+		data.Layout = settings.PDFLayout
+	}
+	if settings.LogoID != nil {
+		logo, err := s.settings.GetLogo()
+		if err != nil {
+			return fmt.Errorf("loading company logo: %w", err)
+		}
+		data.Logo = logo.Data
+		data.LogoType = logo.ContentType
+	}
+	return nil
+}
 
-	// Items Table Body
-	pdf.SetFont("Arial", "", 10)
-	var total float64
-	for _, item := range data.Items {
-		itemTotal := item.Qty * item.UPrice
-		total += itemTotal
-		pdf.Cell(100, 10, item.Desc)
-		pdf.Cell(30, 10, fmt.Sprintf("%.2f", item.Qty))
-		pdf.Cell(30, 10, fmt.Sprintf("%.2f", item.UPrice))
-		pdf.Cell(30, 10, fmt.Sprintf("%.2f", itemTotal))
-		pdf.Ln(5)
+// companyIdentifiers formats the company's registration, tax and MSIC
+// numbers on one line, leaving out those not set.
+func companyIdentifiers(data *models.PDFData) string {
+	var parts []string
+	if data.RegNo != "" {
+		parts = append(parts, "Reg. No: "+data.RegNo)
+	}
+	if data.TinNo != "" {
+		parts = append(parts, "TIN: "+data.TinNo)
+	}
+	if data.MsicCode != "" {
+		parts = append(parts, "MSIC: "+data.MsicCode)
 	}
+	return joinNonEmpty("  |  ", parts...)
+}
 
-	// Total
-	pdf.Ln(10)
-	pdf.SetFont("Arial", "B", 12)
-	pdf.Cell(160, 10, "Total:")
-	pdf.Cell(30, 10, fmt.Sprintf("%.2f", total))
+// joinNonEmpty joins the values that are not empty.
+func joinNonEmpty(sep string, values ...string) string {
+	var parts []string
+	for _, v := range values {
+		if v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, sep)
+}
 
-	var buf bytes.Buffer
-	err := pdf.Output(&buf)
-	if err != nil {
-		return nil, err
+// drawLogo places the logo in the top right corner, 40 mm wide at most and
+// 20 mm high at most, keeping its proportions. An image that cannot be read
+// makes the PDF fail to generate.
+func drawLogo(pdf *gofpdf.Fpdf, logo []byte, contentType string) {
+	options := gofpdf.ImageOptions{ImageType: strings.ToUpper(strings.TrimPrefix(contentType, "image/"))}
+	info := pdf.RegisterImageOptionsReader("logo", options, bytes.NewReader(logo))
+	if info == nil {
+		return
 	}
-	return &buf, nil
+	width, height := 40.0, 40.0*info.Height()/info.Width()
+	if height > 20 {
+		width, height = 20*info.Width()/info.Height(), 20
+	}
+	pageWidth, _ := pdf.GetPageSize()
+	_, _, right, _ := pdf.GetMargins()
+	pdf.ImageOptions("logo", pageWidth-right-width, 10, width, height, false, options, 0, "")
+}
+
+// drawWatermark writes text diagonally across the current page, behind what
+// is drawn afterwards.
+func drawWatermark(pdf *gofpdf.Fpdf, text string) {
+	pageWidth, pageHeight := pdf.GetPageSize()
+	pdf.SetFont("Arial", "B", 80)
+	pdf.SetTextColor(235, 180, 180)
+	pdf.TransformBegin()
+	pdf.TransformRotate(45, pageWidth/2, pageHeight/2)
+	pdf.Text((pageWidth-pdf.GetStringWidth(text))/2, pageHeight/2, text)
+	pdf.TransformEnd()
+	pdf.SetTextColor(0, 0, 0)
 }
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"procurement-system/internal/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputePDFTotals(t *testing.T) {
+	items := func() []models.PDFItem {
+		return []models.PDFItem{
+			{Desc: "Bolts", Qty: 3, UPrice: 10},
+			{Desc: "Nuts", Qty: 2, UPrice: 7.49},
+		}
+	}
+
+	t.Run("Tax And Rounding", func(t *testing.T) {
+		data := &models.PDFData{Items: items(), Currency: "MYR", TaxRate: 6, RoundingStep: 0.05}
+		computePDFTotals(data)
+		assert.Equal(t, 44.98, data.Subtotal)
+		assert.Equal(t, 2.7, data.TaxAmount)
+		assert.Equal(t, 0.02, data.RoundingAdj)
+		assert.Equal(t, 47.7, data.GrandTotal)
+		assert.Equal(t, "MYR Forty-Seven and Cents Seventy Only", data.AmountInWords)
+	})
+
+	t.Run("No Tax Or Rounding", func(t *testing.T) {
+		data := &models.PDFData{Items: items(), Currency: "USD"}
+		computePDFTotals(data)
+		assert.Equal(t, 0.0, data.TaxAmount)
+		assert.Equal(t, 0.0, data.RoundingAdj)
+		assert.Equal(t, 44.98, data.GrandTotal)
+		assert.Equal(t, "USD Forty-Four and Cents Ninety-Eight Only", data.AmountInWords)
+	})
+}
+
+func TestResolvePDFLayout(t *testing.T) {
+	assert.Equal(t, "compact", resolvePDFLayout("compact").Name)
+	assert.Equal(t, DefaultPDFLayout, resolvePDFLayout("").Name)
+	assert.Equal(t, DefaultPDFLayout, resolvePDFLayout("retired").Name)
+	assert.ErrorIs(t, validatePDFLayout("retired"), ErrUnknownPDFLayout)
+	assert.NoError(t, validatePDFLayout("branded"))
+}
+
+func TestPDFService_GeneratePurchaseOrderPDF(t *testing.T) {
+	newService := func(settings *models.CompanySettings) PDFService {
+		return NewPDFService(newStubCompanySettings(settings))
+	}
+	pageCount := func(pdf *bytes.Buffer) int {
+		return strings.Count(pdf.String(), "/Type /Page\n")
+	}
+
+	t.Run("Vendor Layout Overrides Company Default, Tax From The Order", func(t *testing.T) {
+		service := newService(&models.CompanySettings{CompanyName: "Acme", PDFLayout: "branded", TaxLabel: "VAT", TaxRate: 20})
+		data := &models.PDFData{ReceiptNo: "PO-1", Currency: "MYR", Layout: "compact", TaxLabel: "SST", TaxRate: 8, Items: []models.PDFItem{{Desc: "Bolts", Qty: 1, UPrice: 100}}}
+
+		pdf, err := service.GeneratePurchaseOrderPDF(data)
+		assert.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(pdf.Bytes(), []byte("%PDF")))
+		assert.Equal(t, "compact", data.Layout)
+		assert.Equal(t, "SST", data.TaxLabel)
+		assert.Equal(t, 108.0, data.GrandTotal)
+		assert.Equal(t, 1, pageCount(pdf))
+	})
+
+	t.Run("Long Orders Run Over Several Pages", func(t *testing.T) {
+		service := newService(&models.CompanySettings{CompanyName: "Acme", PDFLayout: "branded", PDFFooter: "Thank you"})
+		data := &models.PDFData{ReceiptNo: "PO-2", Currency: "MYR", Status: models.PurchaseOrderStatusCancelled}
+		for i := 0; i < 60; i++ {
+			data.Items = append(data.Items, models.PDFItem{
+				Desc:   fmt.Sprintf("Item %d: stainless steel hex bolts, grade A2-70, M8 x 40 mm, fully threaded, packed in boxes of one hundred", i),
+				Qty:    2,
+				UPrice: 12.5,
+			})
+		}
+
+		pdf, err := service.GeneratePurchaseOrderPDF(data)
+		assert.NoError(t, err)
+		assert.Greater(t, pageCount(pdf), 1)
+		assert.Equal(t, 1500.0, data.GrandTotal)
+	})
+}
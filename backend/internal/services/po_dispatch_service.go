@@ -0,0 +1,225 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"procurement-system/pkg/mailer"
+	"strings"
+	"time"
+)
+
+var ErrVendorNoEmail = errors.New("vendor has no email address")
+
+// DispatchPolicy controls how purchase orders are emailed to vendors.
+type DispatchPolicy struct {
+	From        string        // sender address, e.g. "Procurement <po@example.com>"
+	MaxAttempts int           // attempts before a dispatch is marked Failed
+	RetryDelay  time.Duration // wait before the first retry; doubled for each further one
+}
+
+// DefaultDispatchPolicy returns the policy used when nothing is configured:
+// five attempts, retried after 1, 2, 4 and 8 minutes.
+func DefaultDispatchPolicy() DispatchPolicy {
+	return DispatchPolicy{From: "Procurement <procurement@localhost>", MaxAttempts: 5, RetryDelay: time.Minute}
+}
+
+// dispatchLease is how long a dispatch being sent is kept from other workers.
+const dispatchLease = 10 * time.Minute
+
+// PODispatchService emails purchase order PDFs to vendors and keeps a log of
+// every send. A send that fails is retried by RetryDue.
+type PODispatchService interface {
+	SendToVendor(actorID int, poID int, payload models.SendToVendorPayload) (*models.PODispatch, error)
+	GetDispatches(poID int) ([]models.PODispatch, error)
+	RetryDue() (int, error)
+}
+
+type poDispatchService struct {
+	repo       repository.PODispatchRepository
+	poService  PurchaseOrderService
+	documents  PODocumentService
+	vendorRepo repository.VendorRepository
+	sender     mailer.Sender
+	logService ActivityLogService
+	policy     DispatchPolicy
+	now        func() time.Time
+}
+
+// NewPODispatchService creates a new instance of PODispatchService that sends
+// through sender.
+func NewPODispatchService(repo repository.PODispatchRepository, poService PurchaseOrderService, documents PODocumentService, vendorRepo repository.VendorRepository, sender mailer.Sender, logService ActivityLogService, policy DispatchPolicy) PODispatchService {
+	return &poDispatchService{repo: repo, poService: poService, documents: documents, vendorRepo: vendorRepo, sender: sender, logService: logService, policy: policy, now: time.Now}
+}
+
+// SendToVendor records a dispatch of a purchase order to its vendor's email
+// address, copying in payload.Cc, and makes the first attempt straight away.
+// If that attempt fails the dispatch is returned still Pending, to be retried.
+func (s *poDispatchService) SendToVendor(actorID int, poID int, payload models.SendToVendorPayload) (*models.PODispatch, error) {
+	po, err := s.poService.GetPurchaseOrderByID(poID)
+	if err != nil {
+		return nil, err
+	}
+	vendor, err := s.vendorRepo.GetVendorByID(po.VendorID)
+	if err != nil {
+		return nil, err
+	}
+	if vendor.Email == nil || strings.TrimSpace(*vendor.Email) == "" {
+		details := ErrVendorNoEmail.Error()
+		s.logService.Log(&actorID, "SEND_PO_TO_VENDOR_FAILED", Ptr("purchase_order"), &poID, "FAILED", &details)
+		return nil, ErrVendorNoEmail
+	}
+
+	to := strings.TrimSpace(*vendor.Email)
+	cc := []string{}
+	seen := map[string]bool{strings.ToLower(to): true}
+	for _, addr := range payload.Cc {
+		addr = strings.TrimSpace(addr)
+		if !seen[strings.ToLower(addr)] {
+			seen[strings.ToLower(addr)] = true
+			cc = append(cc, addr)
+		}
+	}
+
+	// The first attempt is made here, so keep the worker away from it.
+	lease := s.now().Add(dispatchLease)
+	d := &models.PODispatch{
+		PurchaseOrderID: po.ID,
+		Revision:        po.Revision,
+		To:              to,
+		Cc:              cc,
+		Subject:         dispatchSubject(po),
+		Body:            dispatchBody(po, vendor, strings.TrimSpace(payload.Message)),
+		Status:          models.DispatchStatusPending,
+		NextAttemptAt:   &lease,
+		RequestedBy:     &actorID,
+	}
+	if err := s.repo.CreateDispatch(d); err != nil {
+		return nil, err
+	}
+	if err := s.attempt(d, &actorID); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// GetDispatches lists a purchase order's dispatches, newest first.
+func (s *poDispatchService) GetDispatches(poID int) ([]models.PODispatch, error) {
+	if _, err := s.poService.GetPurchaseOrderByID(poID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDispatchesByPurchaseOrder(poID)
+}
+
+// RetryDue makes the next attempt for every dispatch whose retry is due and
+// returns how many were sent.
+func (s *poDispatchService) RetryDue() (int, error) {
+	due, err := s.repo.ClaimDueDispatches(s.now(), dispatchLease, 50)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for i := range due {
+		if err := s.attempt(&due[i], nil); err != nil {
+			return sent, err
+		}
+		if due[i].Status == models.DispatchStatusSent {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// attempt sends a dispatch once and records the outcome. After a failure the
+// next attempt is scheduled, doubling the delay each time, until the policy's
+// attempts run out and the dispatch is marked Failed. actorID is nil for
+// retries. Only errors recording the outcome are returned.
+func (s *poDispatchService) attempt(d *models.PODispatch, actorID *int) error {
+	d.Attempts++
+	sendErr := s.send(d)
+	now := s.now()
+
+	var details string
+	if sendErr == nil {
+		d.Status = models.DispatchStatusSent
+		d.SentAt = &now
+		d.NextAttemptAt = nil
+		d.LastError = nil
+		details = fmt.Sprintf("Dispatch %d sent to %s (attempt %d)", d.ID, strings.Join(append([]string{d.To}, d.Cc...), ", "), d.Attempts)
+	} else {
+		message := sendErr.Error()
+		d.LastError = &message
+		if d.Attempts >= s.policy.MaxAttempts {
+			d.Status = models.DispatchStatusFailed
+			d.NextAttemptAt = nil
+			details = fmt.Sprintf("Dispatch %d failed on attempt %d, giving up: %s", d.ID, d.Attempts, message)
+		} else {
+			next := now.Add(s.policy.RetryDelay << (d.Attempts - 1))
+			d.NextAttemptAt = &next
+			details = fmt.Sprintf("Dispatch %d failed on attempt %d, retrying at %s: %s", d.ID, d.Attempts, next.Format(time.RFC3339), message)
+		}
+	}
+
+	if sendErr == nil {
+		s.logService.Log(actorID, "SEND_PO_TO_VENDOR_SUCCESS", Ptr("purchase_order"), &d.PurchaseOrderID, "SUCCESS", &details)
+	} else {
+		s.logService.Log(actorID, "SEND_PO_TO_VENDOR_FAILED", Ptr("purchase_order"), &d.PurchaseOrderID, "FAILED", &details)
+	}
+	return s.repo.UpdateDispatch(d)
+}
+
+// send emails the archived PDF of the revision the dispatch was requested
+// for, so that the vendor receives the genuine document.
+func (s *poDispatchService) send(d *models.PODispatch) error {
+	po, err := s.poService.GetPurchaseOrderByID(d.PurchaseOrderID)
+	if err != nil {
+		return err
+	}
+	_, content, err := s.documents.OpenDocument(d.PurchaseOrderID, &d.Revision)
+	if err != nil {
+		return fmt.Errorf("opening PDF: %w", err)
+	}
+	defer content.Close()
+	pdf, err := io.ReadAll(content)
+	if err != nil {
+		return fmt.Errorf("reading PDF: %w", err)
+	}
+	return s.sender.Send(&mailer.Message{
+		From:    s.policy.From,
+		To:      []string{d.To},
+		Cc:      d.Cc,
+		Subject: d.Subject,
+		Body:    d.Body,
+		Attachments: []mailer.Attachment{
+			{Filename: po.PONumber + ".pdf", ContentType: "application/pdf", Data: pdf},
+		},
+	})
+}
+
+func dispatchSubject(po *models.PurchaseOrder) string {
+	subject := "Purchase Order " + po.PONumber
+	if po.Revision > 0 {
+		subject += fmt.Sprintf(" (Revision %d)", po.Revision)
+	}
+	if po.Status == models.PurchaseOrderStatusCancelled {
+		subject += " - CANCELLED"
+	}
+	return subject
+}
+
+func dispatchBody(po *models.PurchaseOrder, vendor *models.Vendor, message string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Dear %s,\n\n", vendor.Name)
+	if po.POType == models.POTypeBlanket && po.CeilingAmount != nil {
+		fmt.Fprintf(&b, "Please find attached blanket purchase order %s for up to %.2f %s.\n", po.PONumber, *po.CeilingAmount, po.Currency)
+	} else {
+		fmt.Fprintf(&b, "Please find attached purchase order %s for %.2f %s.\n", po.PONumber, po.TotalAmount, po.Currency)
+	}
+	if message != "" {
+		fmt.Fprintf(&b, "\n%s\n", message)
+	}
+	b.WriteString("\nThis email was sent by the procurement system.\n")
+	return b.String()
+}
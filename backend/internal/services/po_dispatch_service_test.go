@@ -0,0 +1,169 @@
+package services
+
+import (
+	"errors"
+	"procurement-system/internal/models"
+	"procurement-system/pkg/mailer"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPODispatchRepository is a mock type for the PODispatchRepository
+type MockPODispatchRepository struct {
+	mock.Mock
+}
+
+func (m *MockPODispatchRepository) CreateDispatch(d *models.PODispatch) error {
+	args := m.Called(d)
+	d.ID = 1
+	return args.Error(0)
+}
+func (m *MockPODispatchRepository) UpdateDispatch(d *models.PODispatch) error {
+	args := m.Called(d)
+	return args.Error(0)
+}
+func (m *MockPODispatchRepository) ClaimDueDispatches(now time.Time, lease time.Duration, limit int) ([]models.PODispatch, error) {
+	args := m.Called(now, lease, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PODispatch), args.Error(1)
+}
+func (m *MockPODispatchRepository) GetDispatchesByPurchaseOrder(poID int) ([]models.PODispatch, error) {
+	args := m.Called(poID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PODispatch), args.Error(1)
+}
+func (m *MockPODispatchRepository) CountFailedPurchaseOrders() (int, error) {
+	args := m.Called()
+	return args.Int(0), args.Error(1)
+}
+
+// MockMailSender is a mock type for mailer.Sender
+type MockMailSender struct {
+	mock.Mock
+}
+
+func (m *MockMailSender) Send(msg *mailer.Message) error {
+	args := m.Called(msg)
+	return args.Error(0)
+}
+
+func TestPODispatchService(t *testing.T) {
+	const actorID = 4
+	now := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	vendorEmail := "sales@vendor.example"
+	newPO := func() *models.PurchaseOrder {
+		return &models.PurchaseOrder{ID: 1, PONumber: "PO-2026-0001", VendorID: 3, Status: models.PurchaseOrderStatusIssued, Currency: "MYR", TotalAmount: 500, Revision: 2}
+	}
+
+	type mocks struct {
+		repo      *MockPODispatchRepository
+		poService *MockPurchaseOrderService
+		documents *MockPODocumentService
+		vendors   *MockVendorRepository
+		sender    *MockMailSender
+	}
+	newService := func() (PODispatchService, *mocks) {
+		m := &mocks{
+			repo:      new(MockPODispatchRepository),
+			poService: new(MockPurchaseOrderService),
+			documents: new(MockPODocumentService),
+			vendors:   new(MockVendorRepository),
+			sender:    new(MockMailSender),
+		}
+		mockLog := new(MockActivityLogService)
+		mockLog.On("Log", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+		m.poService.On("GetPurchaseOrderByID", 1).Return(newPO(), nil)
+		m.documents.On("OpenDocument", 1, mock.AnythingOfType("*int")).Return(&models.PODocument{ID: 7}, []byte("%PDF"), nil)
+		m.vendors.On("GetVendorByID", 3).Return(&models.Vendor{ID: 3, Name: "Acme", Email: &vendorEmail}, nil)
+		m.repo.On("CreateDispatch", mock.AnythingOfType("*models.PODispatch")).Return(nil)
+		m.repo.On("UpdateDispatch", mock.AnythingOfType("*models.PODispatch")).Return(nil)
+		s := NewPODispatchService(m.repo, m.poService, m.documents, m.vendors, m.sender, mockLog, DispatchPolicy{From: "po@example.com", MaxAttempts: 3, RetryDelay: time.Minute})
+		s.(*poDispatchService).now = func() time.Time { return now }
+		return s, m
+	}
+
+	t.Run("SendToVendor - Emails The PDF", func(t *testing.T) {
+		service, m := newService()
+		m.sender.On("Send", mock.AnythingOfType("*mailer.Message")).Return(nil).Once()
+
+		d, err := service.SendToVendor(actorID, 1, models.SendToVendorPayload{Cc: []string{"buyer@example.com", "SALES@vendor.example"}, Message: "Please confirm."})
+		assert.NoError(t, err)
+		assert.Equal(t, models.DispatchStatusSent, d.Status)
+		assert.Equal(t, 1, d.Attempts)
+		assert.Equal(t, 2, d.Revision)
+		assert.Equal(t, []string{"buyer@example.com"}, d.Cc)
+		assert.Nil(t, d.NextAttemptAt)
+
+		msg := m.sender.Calls[0].Arguments.Get(0).(*mailer.Message)
+		assert.Equal(t, []string{vendorEmail}, msg.To)
+		assert.Equal(t, "Purchase Order PO-2026-0001 (Revision 2)", msg.Subject)
+		assert.Contains(t, msg.Body, "Please confirm.")
+		if assert.Len(t, msg.Attachments, 1) {
+			assert.Equal(t, "PO-2026-0001.pdf", msg.Attachments[0].Filename)
+		}
+	})
+
+	t.Run("SendToVendor - Failure Is Scheduled For Retry", func(t *testing.T) {
+		service, m := newService()
+		m.sender.On("Send", mock.Anything).Return(errors.New("connection refused")).Once()
+
+		d, err := service.SendToVendor(actorID, 1, models.SendToVendorPayload{})
+		assert.NoError(t, err)
+		assert.Equal(t, models.DispatchStatusPending, d.Status)
+		assert.Equal(t, "connection refused", *d.LastError)
+		assert.Equal(t, now.Add(time.Minute), *d.NextAttemptAt)
+	})
+
+	t.Run("SendToVendor - Vendor Without Email", func(t *testing.T) {
+		service, m := newService()
+		m.vendors.ExpectedCalls = nil
+		m.vendors.On("GetVendorByID", 3).Return(&models.Vendor{ID: 3, Name: "Acme"}, nil)
+
+		_, err := service.SendToVendor(actorID, 1, models.SendToVendorPayload{})
+		assert.Equal(t, ErrVendorNoEmail, err)
+		m.repo.AssertNotCalled(t, "CreateDispatch", mock.Anything)
+	})
+
+	t.Run("RetryDue - Backs Off Then Gives Up", func(t *testing.T) {
+		service, m := newService()
+		lastError := "timeout"
+		m.repo.On("ClaimDueDispatches", now, dispatchLease, mock.Anything).Return([]models.PODispatch{
+			{ID: 7, PurchaseOrderID: 1, To: vendorEmail, Status: models.DispatchStatusPending, Attempts: 1, LastError: &lastError},
+			{ID: 8, PurchaseOrderID: 1, To: vendorEmail, Status: models.DispatchStatusPending, Attempts: 2, LastError: &lastError},
+		}, nil)
+		m.sender.On("Send", mock.Anything).Return(errors.New("timeout"))
+
+		sent, err := service.RetryDue()
+		assert.NoError(t, err)
+		assert.Equal(t, 0, sent)
+
+		second := m.repo.Calls[1].Arguments.Get(0).(*models.PODispatch)
+		assert.Equal(t, models.DispatchStatusPending, second.Status)
+		assert.Equal(t, now.Add(2*time.Minute), *second.NextAttemptAt)
+		third := m.repo.Calls[2].Arguments.Get(0).(*models.PODispatch)
+		assert.Equal(t, models.DispatchStatusFailed, third.Status)
+		assert.Nil(t, third.NextAttemptAt)
+	})
+
+	t.Run("RetryDue - Sends Due Dispatches", func(t *testing.T) {
+		service, m := newService()
+		m.repo.On("ClaimDueDispatches", now, dispatchLease, mock.Anything).Return([]models.PODispatch{
+			{ID: 7, PurchaseOrderID: 1, To: vendorEmail, Status: models.DispatchStatusPending, Attempts: 1},
+		}, nil)
+		m.sender.On("Send", mock.Anything).Return(nil)
+
+		sent, err := service.RetryDue()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, sent)
+		m.repo.AssertCalled(t, "UpdateDispatch", mock.MatchedBy(func(d *models.PODispatch) bool {
+			return d.ID == 7 && d.Status == models.DispatchStatusSent && d.Attempts == 2
+		}))
+	})
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"procurement-system/pkg/blobstore"
+)
+
+// PODocumentService keeps the PDF of every purchase order as it was issued,
+// revised and cancelled, so that what was sent to the vendor can be shown
+// again unchanged and told apart from a forgery.
+type PODocumentService interface {
+	ArchiveDocument(actorID *int, poID int, reason string) (*models.PODocument, error)
+	GetDocuments(poID int) ([]models.PODocument, error)
+	OpenDocument(poID int, revision *int) (*models.PODocument, io.ReadCloser, error)
+	VerifyDocument(content io.Reader) (*models.PODocumentVerification, error)
+}
+
+type poDocumentService struct {
+	repo       repository.PODocumentRepository
+	poRepo     repository.PurchaseOrderRepository
+	pdfService PDFService
+	store      blobstore.Store
+	logService ActivityLogService
+}
+
+// NewPODocumentService creates a new instance of PODocumentService that keeps
+// documents in store.
+func NewPODocumentService(repo repository.PODocumentRepository, poRepo repository.PurchaseOrderRepository, pdfService PDFService, store blobstore.Store, logService ActivityLogService) PODocumentService {
+	return &poDocumentService{repo: repo, poRepo: poRepo, pdfService: pdfService, store: store, logService: logService}
+}
+
+// ArchiveDocument renders a purchase order's PDF as it stands now and keeps
+// it under a new key, with its SHA-256 digest, as the order's latest
+// document. reason says what happened to the order.
+func (s *poDocumentService) ArchiveDocument(actorID *int, poID int, reason string) (*models.PODocument, error) {
+	doc, err := s.archive(actorID, poID, reason)
+	if err != nil {
+		details := err.Error()
+		s.logService.Log(actorID, "ARCHIVE_PO_DOCUMENT_FAILED", Ptr("purchase_order"), &poID, "FAILED", &details)
+		return nil, err
+	}
+
+	details := fmt.Sprintf("Archived %s document of revision %d (%d bytes, sha256 %s)", reason, doc.Revision, doc.SizeBytes, doc.SHA256)
+	s.logService.Log(actorID, "ARCHIVE_PO_DOCUMENT_SUCCESS", Ptr("purchase_order"), &poID, "SUCCESS", &details)
+	return doc, nil
+}
+
+func (s *poDocumentService) archive(actorID *int, poID int, reason string) (*models.PODocument, error) {
+	data, err := s.poRepo.GetPDFData(poID)
+	if err != nil {
+		return nil, err
+	}
+	pdf, err := s.pdfService.GeneratePurchaseOrderPDF(data)
+	if err != nil {
+		return nil, fmt.Errorf("rendering PDF: %w", err)
+	}
+
+	key, err := blobstore.NewKey()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(pdf.Bytes())
+	doc := &models.PODocument{
+		PurchaseOrderID: poID,
+		Revision:        data.Revision,
+		Reason:          reason,
+		SizeBytes:       int64(pdf.Len()),
+		SHA256:          hex.EncodeToString(sum[:]),
+		StorageKey:      key,
+		CreatedBy:       actorID,
+	}
+	if err := s.store.Put(key, bytes.NewReader(pdf.Bytes())); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateDocument(doc); err != nil {
+		if err := s.store.Delete(key); err != nil {
+			log.Printf("Failed to delete purchase order document blob %s: %v", key, err)
+		}
+		return nil, err
+	}
+	return doc, nil
+}
+
+// GetDocuments lists a purchase order's archived documents, newest first.
+func (s *poDocumentService) GetDocuments(poID int) ([]models.PODocument, error) {
+	return s.repo.GetDocuments(poID)
+}
+
+// OpenDocument returns the latest archived document of a purchase order, or
+// of one of its revisions, and its content. The caller must close the
+// content. An order whose current revision was never archived, such as one
+// issued before documents were kept, is archived now as backfilled, which
+// marks the document as not the original.
+func (s *poDocumentService) OpenDocument(poID int, revision *int) (*models.PODocument, io.ReadCloser, error) {
+	po, err := s.poRepo.GetPurchaseOrderByID(poID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if revision == nil {
+		revision = &po.Revision
+	}
+
+	doc, err := s.repo.GetLatestDocument(poID, revision)
+	if errors.Is(err, repository.ErrPODocumentNotFound) && *revision == po.Revision {
+		doc, err = s.ArchiveDocument(nil, poID, models.PODocumentBackfilled)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := s.store.Get(doc.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc, content, nil
+}
+
+// VerifyDocument checks whether content is a purchase order PDF exactly as
+// archived, by its SHA-256 digest.
+func (s *poDocumentService) VerifyDocument(content io.Reader) (*models.PODocumentVerification, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return nil, err
+	}
+	result := &models.PODocumentVerification{SHA256: hex.EncodeToString(hash.Sum(nil))}
+
+	doc, err := s.repo.GetDocumentBySHA256(result.SHA256)
+	if errors.Is(err, repository.ErrPODocumentNotFound) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	po, err := s.poRepo.GetPurchaseOrderByID(doc.PurchaseOrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Genuine = true
+	result.PONumber = po.PONumber
+	result.Latest = po.DocumentSHA256 != nil && *po.DocumentSHA256 == result.SHA256
+	result.Original = doc.Original()
+	result.Document = doc
+	return result, nil
+}
+
+// archivePODocument archives a purchase order's PDF after the order was
+// issued, revised or cancelled. The order has already changed, so a failure
+// is only logged; an order left without a document for its current revision
+// is backfilled when its PDF is next opened, and that document is reported
+// as not the original.
+func archivePODocument(documents PODocumentService, actorID int, poID int, reason string) {
+	if _, err := documents.ArchiveDocument(&actorID, poID, reason); err != nil {
+		log.Printf("Failed to archive purchase order %d document: %v", poID, err)
+	}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"procurement-system/pkg/blobstore"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPODocumentRepository is a mock type for the PODocumentRepository
+type MockPODocumentRepository struct {
+	mock.Mock
+}
+
+func (m *MockPODocumentRepository) CreateDocument(doc *models.PODocument) error {
+	args := m.Called(doc)
+	doc.ID = 30
+	return args.Error(0)
+}
+func (m *MockPODocumentRepository) GetDocuments(poID int) ([]models.PODocument, error) {
+	args := m.Called(poID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PODocument), args.Error(1)
+}
+func (m *MockPODocumentRepository) GetLatestDocument(poID int, revision *int) (*models.PODocument, error) {
+	args := m.Called(poID, revision)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PODocument), args.Error(1)
+}
+func (m *MockPODocumentRepository) GetDocumentBySHA256(sha256 string) (*models.PODocument, error) {
+	args := m.Called(sha256)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PODocument), args.Error(1)
+}
+
+// MockPODocumentService is a mock type for the PODocumentService
+type MockPODocumentService struct {
+	mock.Mock
+}
+
+func (m *MockPODocumentService) ArchiveDocument(actorID *int, poID int, reason string) (*models.PODocument, error) {
+	args := m.Called(actorID, poID, reason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PODocument), args.Error(1)
+}
+func (m *MockPODocumentService) GetDocuments(poID int) ([]models.PODocument, error) {
+	args := m.Called(poID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PODocument), args.Error(1)
+}
+func (m *MockPODocumentService) OpenDocument(poID int, revision *int) (*models.PODocument, io.ReadCloser, error) {
+	args := m.Called(poID, revision)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	// The content is given as bytes, so that every call can read it afresh.
+	return args.Get(0).(*models.PODocument), io.NopCloser(bytes.NewReader(args.Get(1).([]byte))), args.Error(2)
+}
+func (m *MockPODocumentService) VerifyDocument(content io.Reader) (*models.PODocumentVerification, error) {
+	args := m.Called(content)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PODocumentVerification), args.Error(1)
+}
+
+func TestPODocumentService(t *testing.T) {
+	actorID := 5
+	const pdf = "%PDF-1.3 purchase order"
+	sum := sha256.Sum256([]byte(pdf))
+	digest := hex.EncodeToString(sum[:])
+	intPtr := func(i int) *int { return &i }
+
+	type mocks struct {
+		repo   *MockPODocumentRepository
+		poRepo *MockPurchaseOrderRepository
+		pdf    *MockPDFService
+		store  *blobstore.LocalStore
+		log    *MockActivityLogService
+	}
+	newService := func(t *testing.T) (PODocumentService, *mocks) {
+		m := &mocks{
+			repo:   new(MockPODocumentRepository),
+			poRepo: new(MockPurchaseOrderRepository),
+			pdf:    new(MockPDFService),
+			log:    new(MockActivityLogService),
+		}
+		m.store, _ = blobstore.NewLocalStore(t.TempDir())
+		m.log.On("Log", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+		m.poRepo.On("GetPDFData", 1).Return(&models.PDFData{ReceiptNo: "PO-2026-0001", Revision: 2}, nil)
+		m.pdf.On("GeneratePurchaseOrderPDF", mock.AnythingOfType("*models.PDFData")).Return(bytes.NewBufferString(pdf), nil)
+		return NewPODocumentService(m.repo, m.poRepo, m.pdf, m.store, m.log), m
+	}
+	read := func(t *testing.T, store blobstore.Store, key string) string {
+		content, err := store.Get(key)
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		defer content.Close()
+		data, _ := io.ReadAll(content)
+		return string(data)
+	}
+
+	t.Run("ArchiveDocument - Stores PDF With Digest", func(t *testing.T) {
+		service, m := newService(t)
+		m.repo.On("CreateDocument", mock.AnythingOfType("*models.PODocument")).Return(nil).Once()
+
+		doc, err := service.ArchiveDocument(&actorID, 1, models.PODocumentIssued)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, doc.Revision)
+		assert.Equal(t, digest, doc.SHA256)
+		assert.Equal(t, int64(len(pdf)), doc.SizeBytes)
+		assert.Equal(t, &actorID, doc.CreatedBy)
+		assert.Equal(t, pdf, read(t, m.store, doc.StorageKey))
+		m.log.AssertCalled(t, "Log", &actorID, "ARCHIVE_PO_DOCUMENT_SUCCESS", mock.Anything, mock.Anything, "SUCCESS", mock.Anything)
+	})
+
+	t.Run("ArchiveDocument - Removes Blob When Not Recorded", func(t *testing.T) {
+		service, m := newService(t)
+		var stored *models.PODocument
+		m.repo.On("CreateDocument", mock.AnythingOfType("*models.PODocument")).Run(func(args mock.Arguments) {
+			stored = args.Get(0).(*models.PODocument)
+		}).Return(errors.New("db down")).Once()
+
+		_, err := service.ArchiveDocument(&actorID, 1, models.PODocumentIssued)
+		assert.Error(t, err)
+		_, err = m.store.Get(stored.StorageKey)
+		assert.ErrorIs(t, err, blobstore.ErrNotFound)
+		m.log.AssertCalled(t, "Log", &actorID, "ARCHIVE_PO_DOCUMENT_FAILED", mock.Anything, mock.Anything, "FAILED", mock.Anything)
+	})
+
+	t.Run("OpenDocument - Serves Archived Copy", func(t *testing.T) {
+		service, m := newService(t)
+		m.store.Put("ab/archived", strings.NewReader("archived pdf"))
+		m.poRepo.On("GetPurchaseOrderByID", 1).Return(&models.PurchaseOrder{ID: 1, Revision: 2}, nil)
+		m.repo.On("GetLatestDocument", 1, intPtr(2)).Return(&models.PODocument{ID: 7, Revision: 2, StorageKey: "ab/archived"}, nil)
+
+		doc, content, err := service.OpenDocument(1, nil)
+		if assert.NoError(t, err) {
+			defer content.Close()
+			data, _ := io.ReadAll(content)
+			assert.Equal(t, 7, doc.ID)
+			assert.Equal(t, "archived pdf", string(data))
+		}
+		m.pdf.AssertNotCalled(t, "GeneratePurchaseOrderPDF", mock.Anything)
+	})
+
+	t.Run("OpenDocument - Backfills Current Revision", func(t *testing.T) {
+		service, m := newService(t)
+		m.poRepo.On("GetPurchaseOrderByID", 1).Return(&models.PurchaseOrder{ID: 1, Revision: 2}, nil)
+		m.repo.On("GetLatestDocument", 1, intPtr(2)).Return(nil, repository.ErrPODocumentNotFound)
+		m.repo.On("CreateDocument", mock.MatchedBy(func(d *models.PODocument) bool {
+			return d.Reason == models.PODocumentBackfilled && d.CreatedBy == nil
+		})).Return(nil).Once()
+
+		doc, content, err := service.OpenDocument(1, nil)
+		if assert.NoError(t, err) {
+			defer content.Close()
+			assert.Equal(t, digest, doc.SHA256)
+			assert.False(t, doc.Original())
+		}
+		m.repo.AssertExpectations(t)
+	})
+
+	t.Run("OpenDocument - Earlier Revision Never Archived", func(t *testing.T) {
+		service, m := newService(t)
+		m.poRepo.On("GetPurchaseOrderByID", 1).Return(&models.PurchaseOrder{ID: 1, Revision: 2}, nil)
+		m.repo.On("GetLatestDocument", 1, intPtr(1)).Return(nil, repository.ErrPODocumentNotFound)
+
+		_, _, err := service.OpenDocument(1, intPtr(1))
+		assert.ErrorIs(t, err, repository.ErrPODocumentNotFound)
+		m.repo.AssertNotCalled(t, "CreateDocument", mock.Anything)
+	})
+
+	t.Run("VerifyDocument - Genuine But Superseded", func(t *testing.T) {
+		service, m := newService(t)
+		newer := "0000000000000000000000000000000000000000000000000000000000000000"
+		m.repo.On("GetDocumentBySHA256", digest).Return(&models.PODocument{ID: 7, PurchaseOrderID: 1, Revision: 1, SHA256: digest}, nil)
+		m.poRepo.On("GetPurchaseOrderByID", 1).Return(&models.PurchaseOrder{ID: 1, PONumber: "PO-2026-0001", DocumentSHA256: &newer}, nil)
+
+		result, err := service.VerifyDocument(strings.NewReader(pdf))
+		assert.NoError(t, err)
+		assert.True(t, result.Genuine)
+		assert.False(t, result.Latest)
+		assert.True(t, result.Original)
+		assert.Equal(t, "PO-2026-0001", result.PONumber)
+		assert.Equal(t, 1, result.Document.Revision)
+	})
+
+	t.Run("VerifyDocument - Backfilled Is Not The Original", func(t *testing.T) {
+		service, m := newService(t)
+		m.repo.On("GetDocumentBySHA256", digest).Return(&models.PODocument{ID: 8, PurchaseOrderID: 1, Revision: 2, Reason: models.PODocumentBackfilled, SHA256: digest}, nil)
+		m.poRepo.On("GetPurchaseOrderByID", 1).Return(&models.PurchaseOrder{ID: 1, PONumber: "PO-2026-0001", DocumentSHA256: &digest}, nil)
+
+		result, err := service.VerifyDocument(strings.NewReader(pdf))
+		assert.NoError(t, err)
+		assert.True(t, result.Genuine)
+		assert.True(t, result.Latest)
+		assert.False(t, result.Original)
+	})
+
+	t.Run("VerifyDocument - Unknown Document", func(t *testing.T) {
+		service, m := newService(t)
+		m.repo.On("GetDocumentBySHA256", mock.Anything).Return(nil, repository.ErrPODocumentNotFound)
+
+		result, err := service.VerifyDocument(strings.NewReader(pdf + " tampered"))
+		assert.NoError(t, err)
+		assert.False(t, result.Genuine)
+		assert.Nil(t, result.Document)
+		assert.Len(t, result.SHA256, 64)
+	})
+}
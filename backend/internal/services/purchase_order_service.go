@@ -3,53 +3,95 @@ package services
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"procurement-system/internal/models"
 	"procurement-system/internal/repository"
+	"strings"
 	"time"
 )
 
+var (
+	ErrNotInBuyQueue              = errors.New("requisition is not waiting in the buy queue")
+	ErrIncompatibleRequisitions   = errors.New("requisitions must share a vendor, currency and ship-to to be consolidated")
+	ErrPurchaseOrderNotOpen       = errors.New("only issued purchase orders can be cancelled or closed")
+	errPurchaseOrderWithoutVendor = errors.New("cannot create purchase order without a vendor")
+)
+
+// POIssueMode controls what happens to a requisition when it is approved.
+type POIssueMode string
+
+const (
+	POIssueImmediate   POIssueMode = "immediate"   // Each approved requisition gets its own purchase order
+	POIssueConsolidate POIssueMode = "consolidate" // Approved requisitions wait in the buy queue to be consolidated
+)
+
+// ParsePOIssueMode converts a config value into a POIssueMode, defaulting to
+// POIssueImmediate for empty or unknown values.
+func ParsePOIssueMode(value string) POIssueMode {
+	if POIssueMode(value) == POIssueConsolidate {
+		return POIssueConsolidate
+	}
+	return POIssueImmediate
+}
+
 type PurchaseOrderService interface {
-	CreatePurchaseOrderFromRequisition(requisition *models.Requisition) (*models.PurchaseOrder, error)
+	CreatePurchaseOrderFromRequisition(requisition *models.Requisition, actorID int, store func(po *models.PurchaseOrder) error) (*models.PurchaseOrder, error)
 	GetPurchaseOrderByID(id int) (*models.PurchaseOrder, error)
 	GetAllPurchaseOrders() ([]models.PurchaseOrder, error)
 	GeneratePurchaseOrderPDF(poID int) (*bytes.Buffer, error)
+	GetBuyQueue() ([]models.BuyQueueGroup, error)
+	ConsolidateRequisitions(actorID int, requisitionIDs []int) (*models.PurchaseOrder, error)
+	CancelPurchaseOrder(actorID int, id int, payload models.CancelPurchaseOrderPayload, expectedVersion *int) (*models.PurchaseOrder, error)
+	ClosePurchaseOrder(actorID int, id int, payload models.ClosePurchaseOrderPayload, expectedVersion *int) (*models.PurchaseOrder, error)
 }
 
 type purchaseOrderService struct {
 	poRepo     repository.PurchaseOrderRepository
 	vendRepo   repository.VendorRepository
 	pdfService PDFService
+	settings   CompanySettingsService
+	documents  PODocumentService
+	logService ActivityLogService
+	mode       POIssueMode
 }
 
-func NewPurchaseOrderService(poRepo repository.PurchaseOrderRepository, vendRepo repository.VendorRepository, pdfService PDFService) PurchaseOrderService {
+// NewPurchaseOrderService creates a new instance of PurchaseOrderService.
+// Orders are issued with the tax and rounding in settings at the time.
+func NewPurchaseOrderService(poRepo repository.PurchaseOrderRepository, vendRepo repository.VendorRepository, pdfService PDFService, settings CompanySettingsService, documents PODocumentService, logService ActivityLogService, mode POIssueMode) PurchaseOrderService {
 	return &purchaseOrderService{
 		poRepo:     poRepo,
 		vendRepo:   vendRepo,
 		pdfService: pdfService,
+		settings:   settings,
+		documents:  documents,
+		logService: logService,
+		mode:       mode,
 	}
 }
 
-func (s *purchaseOrderService) CreatePurchaseOrderFromRequisition(requisition *models.Requisition) (*models.PurchaseOrder, error) {
+// CreatePurchaseOrderFromRequisition issues a purchase order for a
+// requisition being approved. actorID is the user whose approval issued it.
+// The order is handed to store, which saves it in the same transaction as the
+// approval. In POIssueConsolidate mode no order is issued: store is called
+// with nil, the requisition waits in the buy queue and nil is returned.
+func (s *purchaseOrderService) CreatePurchaseOrderFromRequisition(requisition *models.Requisition, actorID int, store func(po *models.PurchaseOrder) error) (*models.PurchaseOrder, error) {
 	if requisition.VendorID == nil {
-		return nil, errors.New("cannot create purchase order without a vendor")
+		return nil, errPurchaseOrderWithoutVendor
+	}
+	if s.mode == POIssueConsolidate {
+		return nil, store(nil)
 	}
 
-	poNumber, err := s.poRepo.GetNextPONumber()
+	po, err := s.issue([]models.Requisition{*requisition})
 	if err != nil {
 		return nil, err
 	}
+	po.RequisitionID = &requisition.ID
 
-	po := &models.PurchaseOrder{
-		PONumber:      poNumber,
-		RequisitionID: requisition.ID,
-		VendorID:      *requisition.VendorID,
-		OrderDate:     time.Now(),
-	}
-
-	err = s.poRepo.CreatePurchaseOrder(po)
-	if err != nil {
+	if err := store(po); err != nil {
 		return nil, err
 	}
+	archivePODocument(s.documents, actorID, po.ID, models.PODocumentIssued)
 
 	return po, nil
 }
@@ -62,6 +104,8 @@ func (s *purchaseOrderService) GetAllPurchaseOrders() ([]models.PurchaseOrder, e
 	return s.poRepo.GetAllPurchaseOrders()
 }
 
+// GeneratePurchaseOrderPDF draws a purchase order's PDF from its current
+// data. The PDF is not archived; PODocumentService keeps the issued ones.
 func (s *purchaseOrderService) GeneratePurchaseOrderPDF(poID int) (*bytes.Buffer, error) {
 	pdfData, err := s.poRepo.GetPDFData(poID)
 	if err != nil {
@@ -70,3 +114,214 @@ func (s *purchaseOrderService) GeneratePurchaseOrderPDF(poID int) (*bytes.Buffer
 
 	return s.pdfService.GeneratePurchaseOrderPDF(pdfData)
 }
+
+// GetBuyQueue returns the approved requisitions waiting for a purchase order,
+// grouped by what can be consolidated into one order.
+func (s *purchaseOrderService) GetBuyQueue() ([]models.BuyQueueGroup, error) {
+	queue, err := s.poRepo.GetBuyQueue()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []models.BuyQueueGroup{}
+	index := make(map[string]int)
+	for _, req := range queue {
+		if req.VendorID == nil {
+			continue
+		}
+		key := consolidationKey(&req)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, models.BuyQueueGroup{VendorID: *req.VendorID, Currency: req.Currency, ShipTo: req.ShipTo, Requisitions: []models.Requisition{}})
+		}
+		groups[i].Requisitions = append(groups[i].Requisitions, req)
+		groups[i].TotalAmount += req.TotalPrice
+	}
+	return groups, nil
+}
+
+// ConsolidateRequisitions issues one purchase order for several requisitions
+// from the buy queue, with a line for each in the order given. They must all
+// be waiting in the queue and share a vendor, currency and ship-to.
+func (s *purchaseOrderService) ConsolidateRequisitions(actorID int, requisitionIDs []int) (*models.PurchaseOrder, error) {
+	po, err := s.consolidate(actorID, requisitionIDs)
+	if err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "CONSOLIDATE_REQUISITIONS_FAILED", Ptr("purchase_order"), nil, "FAILED", &details)
+		return nil, err
+	}
+
+	details := fmt.Sprintf("Issued %s for requisitions %s", po.PONumber, joinIDs(requisitionIDs))
+	s.logService.Log(&actorID, "CONSOLIDATE_REQUISITIONS_SUCCESS", Ptr("purchase_order"), &po.ID, "SUCCESS", &details)
+	archivePODocument(s.documents, actorID, po.ID, models.PODocumentIssued)
+	return po, nil
+}
+
+func (s *purchaseOrderService) consolidate(actorID int, requisitionIDs []int) (*models.PurchaseOrder, error) {
+	queue, err := s.poRepo.GetBuyQueue()
+	if err != nil {
+		return nil, err
+	}
+	queued := make(map[int]models.Requisition, len(queue))
+	for _, req := range queue {
+		queued[req.ID] = req
+	}
+
+	var selected []models.Requisition
+	seen := make(map[int]bool, len(requisitionIDs))
+	for _, id := range requisitionIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		req, ok := queued[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d", ErrNotInBuyQueue, id)
+		}
+		if req.VendorID == nil {
+			return nil, fmt.Errorf("%w: requisition %d", errPurchaseOrderWithoutVendor, id)
+		}
+		if len(selected) > 0 && consolidationKey(&req) != consolidationKey(&selected[0]) {
+			return nil, ErrIncompatibleRequisitions
+		}
+		selected = append(selected, req)
+	}
+
+	po, err := s.issue(selected)
+	if err != nil {
+		return nil, err
+	}
+	if len(selected) == 1 {
+		po.RequisitionID = &selected[0].ID
+	}
+	if err := s.poRepo.CreatePurchaseOrder(po, &actorID); err != nil {
+		return nil, err
+	}
+	return po, nil
+}
+
+// CancelPurchaseOrder cancels an issued purchase order before anything has
+// been received, releasing its commitment.
+func (s *purchaseOrderService) CancelPurchaseOrder(actorID int, id int, payload models.CancelPurchaseOrderPayload, expectedVersion *int) (*models.PurchaseOrder, error) {
+	return s.end(actorID, id, models.PurchaseOrderStatusCancelled, payload.ReasonCode, payload.Note, expectedVersion, s.poRepo.CancelPurchaseOrder, "CANCEL_PURCHASE_ORDER")
+}
+
+// ClosePurchaseOrder closes an issued purchase order out short, giving up
+// whatever has not been received and releasing the rest of its commitment.
+func (s *purchaseOrderService) ClosePurchaseOrder(actorID int, id int, payload models.ClosePurchaseOrderPayload, expectedVersion *int) (*models.PurchaseOrder, error) {
+	return s.end(actorID, id, models.PurchaseOrderStatusClosed, payload.ReasonCode, payload.Note, expectedVersion, s.poRepo.ClosePurchaseOrder, "CLOSE_PURCHASE_ORDER")
+}
+
+// end moves an issued order to status using endOrder, logging the outcome
+// under action. The "other" reason code needs a note.
+func (s *purchaseOrderService) end(actorID int, id int, status string, reasonCode string, note string, expectedVersion *int, endOrder func(id int, change models.StatusChange, reasonCode string, note string) error, action string) (*models.PurchaseOrder, error) {
+	note = strings.TrimSpace(note)
+	po, err := s.poRepo.GetPurchaseOrderByID(id)
+	if err == nil {
+		err = checkVersion(expectedVersion, po.Version)
+	}
+	if err == nil && po.Status != models.PurchaseOrderStatusIssued {
+		err = ErrPurchaseOrderNotOpen
+	}
+	if err == nil && reasonCode == models.POReasonOther && note == "" {
+		err = ErrReasonRequired
+	}
+
+	var ended *models.PurchaseOrder
+	if err == nil {
+		reason := reasonCode
+		if note != "" {
+			reason += ": " + note
+		}
+		err = endOrder(id, models.StatusChange{From: po.Status, To: status, Version: po.Version, ActorID: &actorID, Reason: reason}, reasonCode, note)
+	}
+	if err == nil {
+		ended, err = s.poRepo.GetPurchaseOrderByID(id)
+	}
+	if err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, action+"_FAILED", Ptr("purchase_order"), &id, "FAILED", &details)
+		return nil, err
+	}
+
+	details := fmt.Sprintf("%s %s (%s); released %.2f %s of commitment", status, ended.PONumber, reasonCode, po.CommittedAmount-ended.CommittedAmount, ended.Currency)
+	s.logService.Log(&actorID, action+"_SUCCESS", Ptr("purchase_order"), &id, "SUCCESS", &details)
+	if status == models.PurchaseOrderStatusCancelled {
+		// The cancelled order's PDF carries a watermark.
+		archivePODocument(s.documents, actorID, id, models.PODocumentCancelled)
+	}
+	return ended, nil
+}
+
+// issue builds a new purchase order with one line per requisition, priced
+// with the current tax and rounding. The requisitions must share a vendor,
+// currency and ship-to.
+func (s *purchaseOrderService) issue(requisitions []models.Requisition) (*models.PurchaseOrder, error) {
+	poNumber, err := s.poRepo.GetNextPONumber()
+	if err != nil {
+		return nil, err
+	}
+
+	first := requisitions[0]
+	po := &models.PurchaseOrder{
+		PONumber:  poNumber,
+		VendorID:  *first.VendorID,
+		OrderDate: time.Now(),
+		Status:    models.PurchaseOrderStatusIssued,
+		POType:    models.POTypeStandard,
+		Currency:  first.Currency,
+		ShipTo:    first.ShipTo,
+		Lines:     make([]models.PurchaseOrderLine, len(requisitions)),
+	}
+	for i := range requisitions {
+		req := &requisitions[i]
+		po.Lines[i] = models.PurchaseOrderLine{
+			LineNo:        i + 1,
+			RequisitionID: &req.ID,
+			Description:   req.ItemDescription,
+			Quantity:      req.Quantity,
+			UnitPrice:     req.EstimatedPrice,
+			TotalPrice:    req.TotalPrice,
+		}
+	}
+	if err := priceOrder(s.settings, po); err != nil {
+		return nil, err
+	}
+	return po, nil
+}
+
+// priceOrder fixes the company's current tax and rounding on a new order and
+// works out its total from its lines.
+func priceOrder(settings CompanySettingsService, po *models.PurchaseOrder) error {
+	current, err := settings.GetCompanySettings()
+	if err != nil {
+		return fmt.Errorf("loading company settings: %w", err)
+	}
+	po.TaxLabel = current.TaxLabel
+	po.TaxRate = current.TaxRate
+	po.RoundingStep = current.RoundingStep
+	po.TotalAmount = po.Totals().Grand
+	return nil
+}
+
+// consolidationKey identifies the requisitions that may share a purchase
+// order. Ship-to addresses are compared ignoring case and spacing.
+func consolidationKey(req *models.Requisition) string {
+	vendorID := 0
+	if req.VendorID != nil {
+		vendorID = *req.VendorID
+	}
+	shipTo := strings.ToLower(strings.Join(strings.Fields(req.ShipTo), " "))
+	return fmt.Sprintf("%d|%s|%s", vendorID, strings.ToUpper(req.Currency), shipTo)
+}
+
+// joinIDs formats IDs as a comma-separated list.
+func joinIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+	return strings.Join(parts, ", ")
+}
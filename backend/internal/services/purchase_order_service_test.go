@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,8 +16,8 @@ type MockPurchaseOrderRepository struct {
 	mock.Mock
 }
 
-func (m *MockPurchaseOrderRepository) CreatePurchaseOrder(po *models.PurchaseOrder) error {
-	args := m.Called(po)
+func (m *MockPurchaseOrderRepository) CreatePurchaseOrder(po *models.PurchaseOrder, actorID *int) error {
+	args := m.Called(po, actorID)
 	return args.Error(0)
 }
 func (m *MockPurchaseOrderRepository) GetPurchaseOrderByID(id int) (*models.PurchaseOrder, error) {
@@ -46,6 +47,33 @@ func (m *MockPurchaseOrderRepository) GetPDFData(poID int) (*models.PDFData, err
 	return args.Get(0).(*models.PDFData), args.Error(1)
 }
 
+func (m *MockPurchaseOrderRepository) GetBuyQueue() ([]models.Requisition, error) {
+	args := m.Called()
+	return args.Get(0).([]models.Requisition), args.Error(1)
+}
+func (m *MockPurchaseOrderRepository) CancelPurchaseOrder(id int, change models.StatusChange, reasonCode string, note string) error {
+	args := m.Called(id, change, reasonCode, note)
+	return args.Error(0)
+}
+func (m *MockPurchaseOrderRepository) ClosePurchaseOrder(id int, change models.StatusChange, reasonCode string, note string) error {
+	args := m.Called(id, change, reasonCode, note)
+	return args.Error(0)
+}
+func (m *MockPurchaseOrderRepository) GetBlanketConsumption() ([]models.BlanketConsumption, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.BlanketConsumption), args.Error(1)
+}
+func (m *MockPurchaseOrderRepository) GetBlanketConsumptionByID(blanketID int) (*models.BlanketConsumption, error) {
+	args := m.Called(blanketID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BlanketConsumption), args.Error(1)
+}
+
 // MockPDFService is a mock type for the PDFService
 type MockPDFService struct {
 	mock.Mock
@@ -60,29 +88,66 @@ func (m *MockPDFService) GeneratePurchaseOrderPDF(data *models.PDFData) (*bytes.
 }
 
 func TestPurchaseOrderService(t *testing.T) {
+	settings := newStubCompanySettings(&models.CompanySettings{TaxLabel: "SST", TaxRate: 8, RoundingStep: 0.05})
+
 	t.Run("CreatePurchaseOrderFromRequisition", func(t *testing.T) {
 		mockPoRepo := new(MockPurchaseOrderRepository)
-		poService := NewPurchaseOrderService(mockPoRepo, nil, nil)
+		mockDocuments := new(MockPODocumentService)
+		poService := NewPurchaseOrderService(mockPoRepo, nil, nil, settings, mockDocuments, nil, POIssueImmediate)
 		vendorID := 1
 		requisition := &models.Requisition{
-			ID:       1,
-			VendorID: &vendorID,
+			ID:             1,
+			VendorID:       &vendorID,
+			Quantity:       3,
+			EstimatedPrice: 14.99,
 		}
 		mockPoRepo.On("GetNextPONumber").Return("PO-2023-0001", nil).Once()
-		mockPoRepo.On("CreatePurchaseOrder", mock.AnythingOfType("*models.PurchaseOrder")).Return(nil).Once()
+		approverID := 99
+		mockDocuments.On("ArchiveDocument", &approverID, 7, models.PODocumentIssued).Return(&models.PODocument{}, nil).Once()
 
-		po, err := poService.CreatePurchaseOrderFromRequisition(requisition)
+		var stored *models.PurchaseOrder
+		po, err := poService.CreatePurchaseOrderFromRequisition(requisition, approverID, func(po *models.PurchaseOrder) error {
+			stored = po
+			po.ID = 7
+			return nil
+		})
 		assert.NoError(t, err)
 		assert.NotNil(t, po)
+		assert.Same(t, stored, po)
 		assert.Equal(t, "PO-2023-0001", po.PONumber)
+		assert.Equal(t, models.PurchaseOrderStatusIssued, po.Status)
+		assert.Equal(t, &requisition.ID, po.RequisitionID)
+		// 44.97 plus 3.60 tax, rounded to the nearest 0.05.
+		assert.Equal(t, "SST", po.TaxLabel)
+		assert.Equal(t, 8.0, po.TaxRate)
+		assert.Equal(t, 48.55, po.TotalAmount)
 		mockPoRepo.AssertExpectations(t)
+		mockDocuments.AssertExpectations(t)
+	})
+
+	t.Run("CreatePurchaseOrderFromRequisition - Store Fails", func(t *testing.T) {
+		mockPoRepo := new(MockPurchaseOrderRepository)
+		mockDocuments := new(MockPODocumentService)
+		poService := NewPurchaseOrderService(mockPoRepo, nil, nil, settings, mockDocuments, nil, POIssueImmediate)
+		vendorID := 1
+		mockPoRepo.On("GetNextPONumber").Return("PO-2023-0002", nil).Once()
+
+		po, err := poService.CreatePurchaseOrderFromRequisition(&models.Requisition{ID: 4, VendorID: &vendorID}, 99, func(po *models.PurchaseOrder) error {
+			return repository.ErrRequisitionAlreadyOrdered
+		})
+		assert.Equal(t, repository.ErrRequisitionAlreadyOrdered, err)
+		assert.Nil(t, po)
+		mockDocuments.AssertNotCalled(t, "ArchiveDocument", mock.Anything, mock.Anything, mock.Anything)
 	})
 
 	t.Run("CreatePurchaseOrderFromRequisition - No Vendor", func(t *testing.T) {
 		mockPoRepo := new(MockPurchaseOrderRepository)
-		poService := NewPurchaseOrderService(mockPoRepo, nil, nil)
+		poService := NewPurchaseOrderService(mockPoRepo, nil, nil, settings, nil, nil, POIssueImmediate)
 		requisition := &models.Requisition{ID: 2} // No VendorID
-		po, err := poService.CreatePurchaseOrderFromRequisition(requisition)
+		po, err := poService.CreatePurchaseOrderFromRequisition(requisition, 99, func(po *models.PurchaseOrder) error {
+			t.Fatal("an order without a vendor must not be stored")
+			return nil
+		})
 		assert.Error(t, err)
 		assert.Nil(t, po)
 		assert.Equal(t, "cannot create purchase order without a vendor", err.Error())
@@ -91,7 +156,7 @@ func TestPurchaseOrderService(t *testing.T) {
 	t.Run("GeneratePurchaseOrderPDF", func(t *testing.T) {
 		mockPoRepo := new(MockPurchaseOrderRepository)
 		mockPdfService := new(MockPDFService)
-		poService := NewPurchaseOrderService(mockPoRepo, nil, mockPdfService)
+		poService := NewPurchaseOrderService(mockPoRepo, nil, mockPdfService, settings, nil, nil, POIssueImmediate)
 		poID := 1
 		pdfData := &models.PDFData{CompanyName: "Test Corp"}
 		pdfBuffer := new(bytes.Buffer)
@@ -111,7 +176,7 @@ func TestPurchaseOrderService(t *testing.T) {
 	t.Run("GeneratePurchaseOrderPDF - Repo Fails", func(t *testing.T) {
 		mockPoRepo := new(MockPurchaseOrderRepository)
 		mockPdfService := new(MockPDFService)
-		poService := NewPurchaseOrderService(mockPoRepo, nil, mockPdfService)
+		poService := NewPurchaseOrderService(mockPoRepo, nil, mockPdfService, settings, nil, nil, POIssueImmediate)
 		poID := 2
 		expectedErr := errors.New("db error")
 
@@ -123,4 +188,163 @@ func TestPurchaseOrderService(t *testing.T) {
 		mockPoRepo.AssertExpectations(t)
 		mockPdfService.AssertNotCalled(t, "GeneratePurchaseOrderPDF", mock.Anything)
 	})
+	t.Run("CreatePurchaseOrderFromRequisition - Held For Consolidation", func(t *testing.T) {
+		mockPoRepo := new(MockPurchaseOrderRepository)
+		poService := NewPurchaseOrderService(mockPoRepo, nil, nil, settings, nil, nil, POIssueConsolidate)
+		vendorID := 1
+
+		stored := false
+		po, err := poService.CreatePurchaseOrderFromRequisition(&models.Requisition{ID: 3, VendorID: &vendorID}, 99, func(po *models.PurchaseOrder) error {
+			stored = po == nil
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Nil(t, po)
+		assert.True(t, stored, "the approval is stored without an order")
+		mockPoRepo.AssertNotCalled(t, "GetNextPONumber")
+	})
+}
+
+func TestPurchaseOrderConsolidation(t *testing.T) {
+	settings := newStubCompanySettings(&models.CompanySettings{TaxLabel: "Tax"})
+	stationer, furniture := 1, 2
+	queue := []models.Requisition{
+		{ID: 10, VendorID: &stationer, ItemDescription: "Pens", Quantity: 10, EstimatedPrice: 2, TotalPrice: 20, Currency: "MYR", ShipTo: "Level 3, KL Office"},
+		{ID: 11, VendorID: &stationer, ItemDescription: "Paper", Quantity: 5, EstimatedPrice: 15, TotalPrice: 75, Currency: "MYR", ShipTo: "level 3,  kl office"},
+		{ID: 12, VendorID: &stationer, ItemDescription: "Toner", Quantity: 1, EstimatedPrice: 300, TotalPrice: 300, Currency: "USD", ShipTo: "Level 3, KL Office"},
+		{ID: 13, VendorID: &furniture, ItemDescription: "Chair", Quantity: 1, EstimatedPrice: 350, TotalPrice: 350, Currency: "MYR", ShipTo: ""},
+	}
+
+	newService := func() (PurchaseOrderService, *MockPurchaseOrderRepository, *MockActivityLogService) {
+		mockPoRepo := new(MockPurchaseOrderRepository)
+		mockPoRepo.On("GetBuyQueue").Return(queue, nil)
+		mockLog := new(MockActivityLogService)
+		mockLog.On("Log", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+		mockDocuments := new(MockPODocumentService)
+		mockDocuments.On("ArchiveDocument", mock.Anything, mock.Anything, models.PODocumentIssued).Return(&models.PODocument{}, nil)
+		return NewPurchaseOrderService(mockPoRepo, nil, nil, settings, mockDocuments, mockLog, POIssueConsolidate), mockPoRepo, mockLog
+	}
+
+	t.Run("GetBuyQueue - Groups By Vendor, Currency And Ship-To", func(t *testing.T) {
+		poService, _, _ := newService()
+
+		groups, err := poService.GetBuyQueue()
+		assert.NoError(t, err)
+		assert.Len(t, groups, 3)
+		assert.Len(t, groups[0].Requisitions, 2)
+		assert.Equal(t, 95.0, groups[0].TotalAmount)
+		assert.Equal(t, "USD", groups[1].Currency)
+		assert.Equal(t, furniture, groups[2].VendorID)
+	})
+
+	t.Run("ConsolidateRequisitions - One Line Per Requisition", func(t *testing.T) {
+		poService, mockPoRepo, _ := newService()
+		actorID := 5
+		mockPoRepo.On("GetNextPONumber").Return("PO-2026-0007", nil).Once()
+		mockPoRepo.On("CreatePurchaseOrder", mock.AnythingOfType("*models.PurchaseOrder"), &actorID).Return(nil).Once()
+
+		po, err := poService.ConsolidateRequisitions(actorID, []int{11, 10, 11})
+		assert.NoError(t, err)
+		assert.Nil(t, po.RequisitionID)
+		assert.Equal(t, stationer, po.VendorID)
+		assert.Equal(t, 95.0, po.TotalAmount)
+		if assert.Len(t, po.Lines, 2) {
+			assert.Equal(t, 1, po.Lines[0].LineNo)
+			assert.Equal(t, 11, *po.Lines[0].RequisitionID)
+			assert.Equal(t, "Paper", po.Lines[0].Description)
+			assert.Equal(t, 10, *po.Lines[1].RequisitionID)
+		}
+		mockPoRepo.AssertExpectations(t)
+	})
+
+	t.Run("ConsolidateRequisitions - Different Currency", func(t *testing.T) {
+		poService, mockPoRepo, mockLog := newService()
+
+		_, err := poService.ConsolidateRequisitions(5, []int{10, 12})
+		assert.Equal(t, ErrIncompatibleRequisitions, err)
+		mockPoRepo.AssertNotCalled(t, "CreatePurchaseOrder", mock.Anything, mock.Anything)
+		mockLog.AssertCalled(t, "Log", mock.Anything, "CONSOLIDATE_REQUISITIONS_FAILED", mock.Anything, mock.Anything, "FAILED", mock.Anything)
+	})
+
+	t.Run("ConsolidateRequisitions - Not In Queue", func(t *testing.T) {
+		poService, mockPoRepo, _ := newService()
+
+		_, err := poService.ConsolidateRequisitions(5, []int{10, 99})
+		assert.ErrorIs(t, err, ErrNotInBuyQueue)
+		mockPoRepo.AssertNotCalled(t, "CreatePurchaseOrder", mock.Anything, mock.Anything)
+	})
+}
+
+func TestPurchaseOrderCancelAndClose(t *testing.T) {
+	settings := newStubCompanySettings(&models.CompanySettings{TaxLabel: "Tax"})
+	actorID := 5
+	issued := &models.PurchaseOrder{ID: 1, PONumber: "PO-2026-0001", Status: models.PurchaseOrderStatusIssued, Currency: "MYR", TotalAmount: 500, CommittedAmount: 500, Version: 2}
+
+	var mockDocuments *MockPODocumentService
+	newService := func() (PurchaseOrderService, *MockPurchaseOrderRepository, *MockActivityLogService) {
+		mockPoRepo := new(MockPurchaseOrderRepository)
+		mockLog := new(MockActivityLogService)
+		mockLog.On("Log", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+		mockDocuments = new(MockPODocumentService)
+		mockDocuments.On("ArchiveDocument", mock.Anything, mock.Anything, mock.Anything).Return(&models.PODocument{}, nil)
+		return NewPurchaseOrderService(mockPoRepo, nil, nil, settings, mockDocuments, mockLog, POIssueImmediate), mockPoRepo, mockLog
+	}
+
+	t.Run("CancelPurchaseOrder - Releases Commitment", func(t *testing.T) {
+		poService, mockPoRepo, mockLog := newService()
+		cancelled := *issued
+		cancelled.Status = models.PurchaseOrderStatusCancelled
+		cancelled.CommittedAmount = 0
+		mockPoRepo.On("GetPurchaseOrderByID", 1).Return(issued, nil).Once()
+		mockPoRepo.On("GetPurchaseOrderByID", 1).Return(&cancelled, nil).Once()
+		change := models.StatusChange{From: models.PurchaseOrderStatusIssued, To: models.PurchaseOrderStatusCancelled, Version: issued.Version, ActorID: &actorID, Reason: "duplicate: Raised twice"}
+		mockPoRepo.On("CancelPurchaseOrder", 1, change, models.POCancelDuplicate, "Raised twice").Return(nil).Once()
+
+		po, err := poService.CancelPurchaseOrder(actorID, 1, models.CancelPurchaseOrderPayload{ReasonCode: models.POCancelDuplicate, Note: " Raised twice "}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, models.PurchaseOrderStatusCancelled, po.Status)
+		details := "Cancelled PO-2026-0001 (duplicate); released 500.00 MYR of commitment"
+		mockLog.AssertCalled(t, "Log", &actorID, "CANCEL_PURCHASE_ORDER_SUCCESS", mock.Anything, mock.Anything, "SUCCESS", &details)
+		mockDocuments.AssertCalled(t, "ArchiveDocument", &actorID, 1, models.PODocumentCancelled)
+		mockPoRepo.AssertExpectations(t)
+	})
+
+	t.Run("CancelPurchaseOrder - Goods Received", func(t *testing.T) {
+		poService, mockPoRepo, mockLog := newService()
+		mockPoRepo.On("GetPurchaseOrderByID", 1).Return(issued, nil)
+		mockPoRepo.On("CancelPurchaseOrder", 1, mock.Anything, models.POCancelVendorUnable, "").Return(repository.ErrPurchaseOrderReceived)
+
+		_, err := poService.CancelPurchaseOrder(actorID, 1, models.CancelPurchaseOrderPayload{ReasonCode: models.POCancelVendorUnable}, nil)
+		assert.Equal(t, repository.ErrPurchaseOrderReceived, err)
+		mockLog.AssertCalled(t, "Log", &actorID, "CANCEL_PURCHASE_ORDER_FAILED", mock.Anything, mock.Anything, "FAILED", mock.Anything)
+	})
+
+	t.Run("CancelPurchaseOrder - Already Closed", func(t *testing.T) {
+		poService, mockPoRepo, _ := newService()
+		closed := *issued
+		closed.Status = models.PurchaseOrderStatusClosed
+		mockPoRepo.On("GetPurchaseOrderByID", 1).Return(&closed, nil)
+
+		_, err := poService.CancelPurchaseOrder(actorID, 1, models.CancelPurchaseOrderPayload{ReasonCode: models.POCancelDuplicate}, nil)
+		assert.Equal(t, ErrPurchaseOrderNotOpen, err)
+		mockPoRepo.AssertNotCalled(t, "CancelPurchaseOrder", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("ClosePurchaseOrder - Other Needs A Note", func(t *testing.T) {
+		poService, mockPoRepo, _ := newService()
+		mockPoRepo.On("GetPurchaseOrderByID", 1).Return(issued, nil)
+
+		_, err := poService.ClosePurchaseOrder(actorID, 1, models.ClosePurchaseOrderPayload{ReasonCode: models.POReasonOther, Note: "  "}, nil)
+		assert.Equal(t, ErrReasonRequired, err)
+		mockPoRepo.AssertNotCalled(t, "ClosePurchaseOrder", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("ClosePurchaseOrder - Stale If-Match", func(t *testing.T) {
+		poService, mockPoRepo, _ := newService()
+		mockPoRepo.On("GetPurchaseOrderByID", 1).Return(issued, nil)
+		staleVersion := 1
+
+		_, err := poService.ClosePurchaseOrder(actorID, 1, models.ClosePurchaseOrderPayload{ReasonCode: models.POCloseVendorShortShipped}, &staleVersion)
+		assert.Equal(t, repository.ErrVersionConflict, err)
+	})
 }
@@ -2,36 +2,70 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"procurement-system/internal/models"
 	"procurement-system/internal/repository"
+	"strings"
 )
 
 var (
 	ErrForbidden    = errors.New("user does not have permission to perform this action")
 	ErrCannotModify = errors.New("requisition cannot be modified in its current state")
+
+	ErrIncompleteRequisition = errors.New("requisition is incomplete")
+	ErrReasonRequired        = errors.New("a reason is required")
+	ErrDuplicateBulkItem     = errors.New("requisition is listed more than once")
 )
 
 type RequisitionService interface {
 	CreateRequisition(payload models.CreateRequisitionPayload, requesterID int) (*models.Requisition, error)
 	GetMyRequisitions(requesterID int) ([]models.Requisition, error)
+	SubmitRequisition(requisitionID int, requesterID int) (*models.Requisition, error)
+	WithdrawRequisition(requisitionID int, requesterID int) (*models.Requisition, error)
 	GetPendingRequisitions() ([]models.Requisition, error)
 	GetAllRequisitions() ([]models.Requisition, error)
-	ApproveRequisition(requisitionID int, adminID int) error
-	RejectRequisition(requisitionID int, adminID int) error
-	UpdateRequisition(requisitionID int, requesterID int, payload models.CreateRequisitionPayload) (*models.Requisition, error)
-	DeleteRequisition(requisitionID int, requesterID int) error
-	AdminUpdateRequisition(requisitionID int, adminID int, payload models.CreateRequisitionPayload) (*models.Requisition, error)
-	AdminDeleteRequisition(requisitionID int, adminID int) error
+	GetApprovalQueue(userID int) ([]models.Requisition, error)
+	ApproveRequisition(requisitionID int, adminID int, expectedVersion *int) error
+	RejectRequisition(requisitionID int, adminID int, reason string, expectedVersion *int) error
+	ReturnRequisition(requisitionID int, adminID int, reason string, expectedVersion *int) error
+	BulkApproveRequisitions(requisitionIDs []int, adminID int, reason string) (*BulkDecision, error)
+	BulkRejectRequisitions(requisitionIDs []int, adminID int, reason string) (*BulkDecision, error)
+	GetRequisition(requisitionID int, userID int) (*models.Requisition, error)
+	GetRequisitionHistory(requisitionID int, userID int) ([]models.RequisitionRound, error)
+	ReassignApprover(requisitionID int, adminID int, payload models.ReassignApproverPayload) (*models.Requisition, error)
+	UpdateRequisition(requisitionID int, requesterID int, payload models.CreateRequisitionPayload, expectedVersion *int) (*models.Requisition, error)
+	DeleteRequisition(requisitionID int, requesterID int, expectedVersion *int) error
+	AdminUpdateRequisition(requisitionID int, adminID int, payload models.CreateRequisitionPayload, expectedVersion *int) (*models.Requisition, error)
+	AdminDeleteRequisition(requisitionID int, adminID int, expectedVersion *int) error
+}
+
+// BulkDecision is the outcome of approving or rejecting several requisitions
+// at once. Outcomes are in request order, one per requested ID; repeats of an
+// ID fail with ErrDuplicateBulkItem.
+type BulkDecision struct {
+	BatchID  string
+	Outcomes []BulkDecisionOutcome
+}
+
+// BulkDecisionOutcome is the result for one requisition of a bulk decision;
+// Err is nil if it was decided.
+type BulkDecisionOutcome struct {
+	RequisitionID int
+	Err           error
 }
 
 type requisitionService struct {
-	repo       repository.RequisitionRepository
-	poService  PurchaseOrderService
-	logService ActivityLogService
+	repo        repository.RequisitionRepository
+	poService   PurchaseOrderService
+	logService  ActivityLogService
+	roleService RoleService
+	delegations DelegationService
+	sod         SoDService
+	vendorRepo  repository.VendorRepository
 }
 
-func NewRequisitionService(repo repository.RequisitionRepository, poService PurchaseOrderService, logService ActivityLogService) RequisitionService {
-	return &requisitionService{repo: repo, poService: poService, logService: logService}
+func NewRequisitionService(repo repository.RequisitionRepository, poService PurchaseOrderService, logService ActivityLogService, roleService RoleService, delegations DelegationService, sod SoDService, vendorRepo repository.VendorRepository) RequisitionService {
+	return &requisitionService{repo: repo, poService: poService, logService: logService, roleService: roleService, delegations: delegations, sod: sod, vendorRepo: vendorRepo}
 }
 
 func (s *requisitionService) CreateRequisition(payload models.CreateRequisitionPayload, requesterID int) (*models.Requisition, error) {
@@ -43,7 +77,9 @@ func (s *requisitionService) CreateRequisition(payload models.CreateRequisitionP
 		EstimatedPrice:  payload.EstimatedPrice,
 		TotalPrice:      payload.EstimatedPrice * float64(payload.Quantity),
 		Justification:   payload.Justification,
-		Status:          "Pending",
+		Currency:        currencyOrDefault(payload.Currency),
+		ShipTo:          strings.TrimSpace(payload.ShipTo),
+		Status:          models.RequisitionStatusDraft,
 	}
 
 	createdReq, err := s.repo.CreateRequisition(requisition)
@@ -61,6 +97,96 @@ func (s *requisitionService) GetMyRequisitions(requesterID int) ([]models.Requis
 	return s.repo.GetRequisitionsByRequesterID(requesterID)
 }
 
+// SubmitRequisition checks that a draft, or a requisition returned for
+// changes, is complete and sends it for approval as a new round.
+func (s *requisitionService) SubmitRequisition(requisitionID int, requesterID int) (*models.Requisition, error) {
+	req, err := s.repo.GetRequisitionByID(requisitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.RequesterID != requesterID {
+		return nil, ErrForbidden
+	}
+
+	if !req.EditableByRequester() {
+		return nil, ErrCannotModify
+	}
+
+	if err := s.validateForSubmit(req); err != nil {
+		return nil, err
+	}
+
+	submitted, err := s.repo.SubmitRequisition(requisitionID, requesterID)
+	if err != nil {
+		details := err.Error()
+		s.logService.Log(&requesterID, "SUBMIT_REQUISITION_FAILED", Ptr("requisition"), &requisitionID, "FAILED", &details)
+		return nil, err
+	}
+
+	details := fmt.Sprintf("Round %d", submitted.Round)
+	s.logService.Log(&requesterID, "SUBMIT_REQUISITION_SUCCESS", Ptr("requisition"), &requisitionID, "SUCCESS", &details)
+	return submitted, nil
+}
+
+// WithdrawRequisition pulls a pending requisition out of the approval queue
+// and back to draft so its requester can change it.
+func (s *requisitionService) WithdrawRequisition(requisitionID int, requesterID int) (*models.Requisition, error) {
+	req, err := s.repo.GetRequisitionByID(requisitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.RequesterID != requesterID {
+		return nil, ErrForbidden
+	}
+
+	if req.Status != models.RequisitionStatusPending {
+		return nil, ErrCannotModify
+	}
+
+	change := models.StatusChange{From: models.RequisitionStatusPending, To: models.RequisitionStatusDraft, Version: req.Version, ActorID: &requesterID}
+	if err := s.repo.CloseRequisitionRound(change, closedRound(req, models.RoundOutcomeWithdrawn, "", requesterID, nil), nil); err != nil {
+		details := err.Error()
+		s.logService.Log(&requesterID, "WITHDRAW_REQUISITION_FAILED", Ptr("requisition"), &requisitionID, "FAILED", &details)
+		return nil, err
+	}
+
+	req.Status = models.RequisitionStatusDraft
+	req.Version++
+	s.logService.Log(&requesterID, "WITHDRAW_REQUISITION_SUCCESS", Ptr("requisition"), &requisitionID, "SUCCESS", nil)
+	return req, nil
+}
+
+// validateForSubmit reports every field a draft is missing before it can be
+// sent for approval. A purchase order is raised on approval, so the vendor
+// must exist.
+func (s *requisitionService) validateForSubmit(req *models.Requisition) error {
+	var problems []string
+	if strings.TrimSpace(req.ItemDescription) == "" {
+		problems = append(problems, "item description is required")
+	}
+	if req.Quantity <= 0 {
+		problems = append(problems, "quantity must be greater than 0")
+	}
+	if req.EstimatedPrice <= 0 {
+		problems = append(problems, "estimated price must be greater than 0")
+	}
+	if req.VendorID == nil {
+		problems = append(problems, "vendor is required")
+	} else if _, err := s.vendorRepo.GetVendorByID(*req.VendorID); err != nil {
+		if !errors.Is(err, repository.ErrVendorNotFound) {
+			return err
+		}
+		problems = append(problems, "vendor does not exist")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrIncompleteRequisition, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
 func (s *requisitionService) GetPendingRequisitions() ([]models.Requisition, error) {
 	return s.repo.GetPendingRequisitions()
 }
@@ -69,45 +195,398 @@ func (s *requisitionService) GetAllRequisitions() ([]models.Requisition, error)
 	return s.repo.GetAllRequisitions()
 }
 
-func (s *requisitionService) ApproveRequisition(requisitionID int, adminID int) error {
-	// In a real app, we would wrap this in a transaction
-	err := s.repo.UpdateRequisitionStatus(requisitionID, "Approved")
+// GetApprovalQueue returns the pending requisitions the user may decide,
+// either directly or through an active delegation.
+func (s *requisitionService) GetApprovalQueue(userID int) ([]models.Requisition, error) {
+	authority, err := s.loadApprovalAuthority(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.repo.GetPendingRequisitions()
+	if err != nil {
+		return nil, err
+	}
+
+	queue := []models.Requisition{}
+	for _, req := range pending {
+		if _, err := authority.decide(&req); err == nil {
+			queue = append(queue, req)
+		}
+	}
+	return queue, nil
+}
+
+// ApproveRequisition approves a pending requisition and issues its purchase
+// order, unless orders are consolidated, in the same transaction.
+func (s *requisitionService) ApproveRequisition(requisitionID int, adminID int, expectedVersion *int) error {
+	req, delegation, err := s.authorizeDecision(requisitionID, adminID, expectedVersion)
+	if err == nil {
+		err = s.checkApprovalDuties(req, adminID, delegation)
+	}
 	if err != nil {
 		details := err.Error()
 		s.logService.Log(&adminID, "APPROVE_REQUISITION_FAILED", Ptr("requisition"), &requisitionID, "FAILED", &details)
 		return err
 	}
 
-	req, err := s.repo.GetRequisitionByID(requisitionID)
+	change := models.StatusChange{From: models.RequisitionStatusPending, To: models.RequisitionStatusApproved, Version: req.Version, ActorID: &adminID}
+	if d := onBehalfOf("Approved", delegation); d != nil {
+		change.Reason = *d
+	}
+	round := closedRound(req, models.RequisitionStatusApproved, "", adminID, delegation)
+	_, err = s.poService.CreatePurchaseOrderFromRequisition(req, adminID, func(po *models.PurchaseOrder) error {
+		return s.repo.CloseRequisitionRound(change, round, po)
+	})
 	if err != nil {
-		// Here we might want to roll back the status update
+		details := err.Error()
+		s.logService.Log(&adminID, "APPROVE_REQUISITION_FAILED", Ptr("requisition"), &requisitionID, "FAILED", &details)
 		return err
 	}
 
-	_, err = s.poService.CreatePurchaseOrderFromRequisition(req)
+	s.logService.Log(&adminID, "APPROVE_REQUISITION_SUCCESS", Ptr("requisition"), &requisitionID, "SUCCESS", onBehalfOf("Approved", delegation))
+	return nil
+}
+
+// RejectRequisition closes a pending requisition for good, with a reason the
+// requester can see.
+func (s *requisitionService) RejectRequisition(requisitionID int, adminID int, reason string, expectedVersion *int) error {
+	return s.decideWithReason(requisitionID, adminID, reason, expectedVersion, models.RequisitionStatusRejected, "REJECT_REQUISITION")
+}
+
+// ReturnRequisition sends a pending requisition back to its requester to be
+// changed and resubmitted.
+func (s *requisitionService) ReturnRequisition(requisitionID int, adminID int, reason string, expectedVersion *int) error {
+	return s.decideWithReason(requisitionID, adminID, reason, expectedVersion, models.RequisitionStatusReturned, "RETURN_REQUISITION")
+}
+
+func (s *requisitionService) decideWithReason(requisitionID int, adminID int, reason string, expectedVersion *int, status string, action string) error {
+	reason = strings.TrimSpace(reason)
+	req, delegation, err := s.authorizeDecision(requisitionID, adminID, expectedVersion)
+	if err == nil && reason == "" {
+		err = ErrReasonRequired
+	}
 	if err != nil {
-		// Here we might want to roll back the status update
 		details := err.Error()
-		s.logService.Log(&adminID, "APPROVE_REQUISITION_FAILED_PO_CREATION", Ptr("requisition"), &requisitionID, "FAILED", &details)
+		s.logService.Log(&adminID, action+"_FAILED", Ptr("requisition"), &requisitionID, "FAILED", &details)
 		return err
 	}
 
-	s.logService.Log(&adminID, "APPROVE_REQUISITION_SUCCESS", Ptr("requisition"), &requisitionID, "SUCCESS", nil)
+	change := models.StatusChange{From: models.RequisitionStatusPending, To: status, Version: req.Version, ActorID: &adminID, Reason: reason}
+	if err := s.repo.CloseRequisitionRound(change, closedRound(req, status, reason, adminID, delegation), nil); err != nil {
+		details := err.Error()
+		s.logService.Log(&adminID, action+"_FAILED", Ptr("requisition"), &requisitionID, "FAILED", &details)
+		return err
+	}
+
+	details := status + ": " + reason
+	if d := onBehalfOf(status, delegation); d != nil {
+		details = *d + ": " + reason
+	}
+	s.logService.Log(&adminID, action+"_SUCCESS", Ptr("requisition"), &requisitionID, "SUCCESS", &details)
 	return nil
 }
 
-func (s *requisitionService) RejectRequisition(requisitionID int, adminID int) error {
-	err := s.repo.UpdateRequisitionStatus(requisitionID, "Rejected")
+// BulkApproveRequisitions approves each requisition as ApproveRequisition
+// would. The optional reason is recorded against the batch.
+func (s *requisitionService) BulkApproveRequisitions(requisitionIDs []int, adminID int, reason string) (*BulkDecision, error) {
+	return s.decideBulk(requisitionIDs, adminID, reason, "BULK_APPROVE_REQUISITIONS", func(item *requisitionService, requisitionID int) error {
+		return item.ApproveRequisition(requisitionID, adminID, nil)
+	})
+}
+
+// BulkRejectRequisitions rejects each requisition as RejectRequisition would,
+// all with the same reason.
+func (s *requisitionService) BulkRejectRequisitions(requisitionIDs []int, adminID int, reason string) (*BulkDecision, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+	return s.decideBulk(requisitionIDs, adminID, reason, "BULK_REJECT_REQUISITIONS", func(item *requisitionService, requisitionID int) error {
+		return item.RejectRequisition(requisitionID, adminID, reason, nil)
+	})
+}
+
+// decideBulk decides requisitions one by one through the same path as a
+// single decision, so each is checked and committed on its own and a failure
+// neither stops nor undoes the others. Everything it logs, including a
+// summary entry, carries a new batch ID.
+func (s *requisitionService) decideBulk(requisitionIDs []int, adminID int, reason string, action string, decide func(item *requisitionService, requisitionID int) error) (*BulkDecision, error) {
+	batchID, err := newBatchID()
+	if err != nil {
+		return nil, err
+	}
+	item := *s
+	item.logService = batchLog{ActivityLogService: s.logService, batchID: batchID}
+
+	decision := &BulkDecision{BatchID: batchID, Outcomes: []BulkDecisionOutcome{}}
+	seen := make(map[int]bool, len(requisitionIDs))
+	failed := 0
+	for _, requisitionID := range requisitionIDs {
+		// A repeat is reported rather than dropped, so the results line up
+		// with the request one for one.
+		err := ErrDuplicateBulkItem
+		if !seen[requisitionID] {
+			seen[requisitionID] = true
+			err = decide(&item, requisitionID)
+		}
+		if err != nil {
+			failed++
+		}
+		decision.Outcomes = append(decision.Outcomes, BulkDecisionOutcome{RequisitionID: requisitionID, Err: err})
+	}
+
+	status := "SUCCESS"
+	if failed > 0 {
+		status = "FAILED"
+	}
+	details := fmt.Sprintf("%d of %d succeeded", len(decision.Outcomes)-failed, len(decision.Outcomes))
+	if reason = strings.TrimSpace(reason); reason != "" {
+		details += ": " + reason
+	}
+	item.logService.Log(&adminID, action, Ptr("requisition"), nil, status, &details)
+	return decision, nil
+}
+
+// closedRound describes how the requisition's current review round ended.
+func closedRound(req *models.Requisition, outcome string, reason string, decidedBy int, delegation *models.Delegation) *models.RequisitionRound {
+	round := &models.RequisitionRound{
+		RequisitionID: req.ID,
+		Round:         req.Round,
+		SubmittedAt:   req.SubmittedAt,
+		Outcome:       outcome,
+		Reason:        reason,
+		DecidedBy:     &decidedBy,
+	}
+	if delegation != nil {
+		round.OnBehalfOfID = &delegation.DelegatorID
+	}
+	return round
+}
+
+// GetRequisition returns a requisition to a user who may see it: its
+// requester, its assigned approver and holders of requisition:read:all,
+// requisition:approve or requisition:manage.
+func (s *requisitionService) GetRequisition(requisitionID int, userID int) (*models.Requisition, error) {
+	req, err := s.repo.GetRequisitionByID(requisitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.RequesterID != userID && (req.ApproverID == nil || *req.ApproverID != userID) {
+		permissions, err := s.roleService.GetUserPermissions(userID)
+		if err != nil {
+			return nil, err
+		}
+		if !permissions[models.PermRequisitionReadAll] && !permissions[models.PermRequisitionApprove] &&
+			!permissions[models.PermRequisitionManage] {
+			return nil, ErrForbidden
+		}
+	}
+
+	return req, nil
+}
+
+// GetRequisitionHistory returns the review rounds of a requisition to the
+// users who may see it.
+func (s *requisitionService) GetRequisitionHistory(requisitionID int, userID int) ([]models.RequisitionRound, error) {
+	if _, err := s.GetRequisition(requisitionID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetRequisitionRounds(requisitionID)
+}
+
+// ReassignApprover moves a pending requisition to another approver, for
+// example when the assigned one is unavailable and set no delegation.
+func (s *requisitionService) ReassignApprover(requisitionID int, adminID int, payload models.ReassignApproverPayload) (*models.Requisition, error) {
+	req, err := s.repo.GetRequisitionByID(requisitionID)
 	if err != nil {
+		return nil, err
+	}
+
+	if req.Status != models.RequisitionStatusPending {
+		return nil, ErrCannotModify
+	}
+
+	if payload.ApproverID != nil {
+		permissions, err := s.roleService.GetUserPermissions(*payload.ApproverID)
+		if err != nil {
+			return nil, err
+		}
+		if !permissions[models.PermRequisitionApprove] {
+			return nil, ErrNotAnApprover
+		}
+	}
+
+	if err := s.repo.UpdateRequisitionApprover(requisitionID, payload.ApproverID, req.Version); err != nil {
 		details := err.Error()
-		s.logService.Log(&adminID, "REJECT_REQUISITION_FAILED", Ptr("requisition"), &requisitionID, "FAILED", &details)
-		return err
+		s.logService.Log(&adminID, "REASSIGN_REQUISITION_FAILED", Ptr("requisition"), &requisitionID, "FAILED", &details)
+		return nil, err
 	}
-	s.logService.Log(&adminID, "REJECT_REQUISITION_SUCCESS", Ptr("requisition"), &requisitionID, "SUCCESS", nil)
-	return nil
+
+	details := fmt.Sprintf("Approver changed from %s to %s", approverName(req.ApproverID), approverName(payload.ApproverID))
+	if reason := strings.TrimSpace(payload.Reason); reason != "" {
+		details += ": " + reason
+	}
+	req.ApproverID = payload.ApproverID
+	req.Version++
+	s.logService.Log(&adminID, "REASSIGN_REQUISITION_SUCCESS", Ptr("requisition"), &requisitionID, "SUCCESS", &details)
+	return req, nil
+}
+
+// authorizeDecision loads a pending requisition, at expectedVersion if one is
+// given, and checks that the user may approve or reject it. The returned
+// delegation is non-nil when the user acts on someone else's behalf.
+func (s *requisitionService) authorizeDecision(requisitionID int, userID int, expectedVersion *int) (*models.Requisition, *models.Delegation, error) {
+	req, err := s.repo.GetRequisitionByID(requisitionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkVersion(expectedVersion, req.Version); err != nil {
+		return nil, nil, err
+	}
+
+	if req.Status != models.RequisitionStatusPending {
+		return nil, nil, ErrCannotModify
+	}
+
+	authority, err := s.loadApprovalAuthority(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	delegation, err := authority.decide(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, delegation, nil
+}
+
+// checkApprovalDuties runs the segregation of duties rules for an approval.
+// Approving a requisition also issues its purchase order, so it counts as
+// approving both.
+func (s *requisitionService) checkApprovalDuties(req *models.Requisition, approverID int, delegation *models.Delegation) error {
+	check := models.SoDCheck{
+		Duties:     []string{models.DutyRequisitionApprove, models.DutyPOApprove},
+		ActorID:    approverID,
+		EntityType: "requisition",
+		EntityID:   req.ID,
+		Performers: map[string]int{models.DutyRequisitionCreate: req.RequesterID},
+	}
+	if delegation != nil {
+		check.OnBehalfOfID = &delegation.DelegatorID
+	}
+
+	if req.VendorID != nil {
+		vendor, err := s.vendorRepo.GetVendorByID(*req.VendorID)
+		if err != nil {
+			return err
+		}
+		if vendor.CreatedBy != nil {
+			check.Performers[models.DutyVendorCreate] = *vendor.CreatedBy
+		}
+	}
+
+	return s.sod.Check(check)
 }
 
-func (s *requisitionService) UpdateRequisition(requisitionID int, requesterID int, payload models.CreateRequisitionPayload) (*models.Requisition, error) {
+// approvalAuthority is what a user may decide: requisitions assigned to them,
+// unassigned ones if they hold requisition:approve, and whatever their active
+// delegations cover.
+type approvalAuthority struct {
+	userID      int
+	canApprove  bool
+	delegations []models.Delegation
+	// delegatorCanApprove records which delegators hold requisition:approve,
+	// and so can pass on authority over unassigned requisitions.
+	delegatorCanApprove map[int]bool
+}
+
+func (s *requisitionService) loadApprovalAuthority(userID int) (*approvalAuthority, error) {
+	permissions, err := s.roleService.GetUserPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	delegations, err := s.delegations.GetActiveDelegationsTo(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	authority := &approvalAuthority{
+		userID:              userID,
+		canApprove:          permissions[models.PermRequisitionApprove],
+		delegations:         delegations,
+		delegatorCanApprove: make(map[int]bool),
+	}
+	for _, d := range delegations {
+		if _, ok := authority.delegatorCanApprove[d.DelegatorID]; ok {
+			continue
+		}
+		delegatorPermissions, err := s.roleService.GetUserPermissions(d.DelegatorID)
+		if err != nil {
+			return nil, err
+		}
+		authority.delegatorCanApprove[d.DelegatorID] = delegatorPermissions[models.PermRequisitionApprove]
+	}
+	return authority, nil
+}
+
+// decide reports whether the user may decide req, and through which
+// delegation if they are not entitled to it themselves.
+func (a *approvalAuthority) decide(req *models.Requisition) (*models.Delegation, error) {
+	if req.ApproverID != nil && *req.ApproverID == a.userID {
+		return nil, nil
+	}
+	if req.ApproverID == nil && a.canApprove {
+		return nil, nil
+	}
+
+	overLimit := false
+	for i := range a.delegations {
+		d := &a.delegations[i]
+		applies := a.delegatorCanApprove[d.DelegatorID]
+		if req.ApproverID != nil {
+			applies = d.DelegatorID == *req.ApproverID
+		}
+		if !applies {
+			continue
+		}
+		if !d.Covers(req.TotalPrice) {
+			overLimit = true
+			continue
+		}
+		return d, nil
+	}
+
+	if overLimit {
+		return nil, ErrDelegationLimitExceeded
+	}
+	return nil, ErrForbidden
+}
+
+// onBehalfOf describes a decision made through a delegation for the activity log.
+func onBehalfOf(decision string, d *models.Delegation) *string {
+	if d == nil {
+		return nil
+	}
+	details := fmt.Sprintf("%s by %s on behalf of %s", decision, d.DelegateName, d.DelegatorName)
+	return &details
+}
+
+// approverName describes an approver assignment for the activity log.
+func approverName(approverID *int) string {
+	if approverID == nil {
+		return "the approval queue"
+	}
+	return fmt.Sprintf("user %d", *approverID)
+}
+
+// UpdateRequisition lets a requester edit their own draft or returned
+// requisition. Pending requisitions must be withdrawn first. If
+// expectedVersion is set, the requisition must still be at that version.
+func (s *requisitionService) UpdateRequisition(requisitionID int, requesterID int, payload models.CreateRequisitionPayload, expectedVersion *int) (*models.Requisition, error) {
 	req, err := s.repo.GetRequisitionByID(requisitionID)
 	if err != nil {
 		return nil, err // Handles not found
@@ -117,7 +596,11 @@ func (s *requisitionService) UpdateRequisition(requisitionID int, requesterID in
 		return nil, ErrForbidden
 	}
 
-	if req.Status != "Pending" {
+	if err := checkVersion(expectedVersion, req.Version); err != nil {
+		return nil, err
+	}
+
+	if !req.EditableByRequester() {
 		return nil, ErrCannotModify
 	}
 
@@ -127,6 +610,8 @@ func (s *requisitionService) UpdateRequisition(requisitionID int, requesterID in
 	req.EstimatedPrice = payload.EstimatedPrice
 	req.TotalPrice = payload.EstimatedPrice * float64(payload.Quantity)
 	req.Justification = payload.Justification
+	req.Currency = currencyOrDefault(payload.Currency)
+	req.ShipTo = strings.TrimSpace(payload.ShipTo)
 
 	err = s.repo.UpdateRequisition(req)
 	if err != nil {
@@ -139,7 +624,10 @@ func (s *requisitionService) UpdateRequisition(requisitionID int, requesterID in
 	return req, nil
 }
 
-func (s *requisitionService) DeleteRequisition(requisitionID int, requesterID int) error {
+// DeleteRequisition lets a requester discard their own draft or returned
+// requisition. If expectedVersion is set, the requisition must still be at
+// that version.
+func (s *requisitionService) DeleteRequisition(requisitionID int, requesterID int, expectedVersion *int) error {
 	req, err := s.repo.GetRequisitionByID(requisitionID)
 	if err != nil {
 		return err // Handles not found
@@ -149,11 +637,15 @@ func (s *requisitionService) DeleteRequisition(requisitionID int, requesterID in
 		return ErrForbidden
 	}
 
-	if req.Status != "Pending" {
+	if err := checkVersion(expectedVersion, req.Version); err != nil {
+		return err
+	}
+
+	if !req.EditableByRequester() {
 		return ErrCannotModify
 	}
 
-	err = s.repo.DeleteRequisition(requisitionID)
+	err = s.repo.DeleteRequisition(requisitionID, req.Version)
 	if err != nil {
 		details := err.Error()
 		s.logService.Log(&requesterID, "DELETE_REQUISITION_FAILED", Ptr("requisition"), &requisitionID, "FAILED", &details)
@@ -164,21 +656,33 @@ func (s *requisitionService) DeleteRequisition(requisitionID int, requesterID in
 	return nil
 }
 
-func (s *requisitionService) AdminUpdateRequisition(requisitionID int, adminID int, payload models.CreateRequisitionPayload) (*models.Requisition, error) {
+func (s *requisitionService) AdminUpdateRequisition(requisitionID int, adminID int, payload models.CreateRequisitionPayload, expectedVersion *int) (*models.Requisition, error) {
 	req, err := s.repo.GetRequisitionByID(requisitionID)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := checkVersion(expectedVersion, req.Version); err != nil {
+		return nil, err
+	}
+
 	// Admin can update any requisition, so no owner/status checks are needed.
+	// Once submitted it must stay complete enough to be approved, though.
 	req.VendorID = payload.VendorID
 	req.ItemDescription = payload.ItemDescription
 	req.Quantity = payload.Quantity
 	req.EstimatedPrice = payload.EstimatedPrice
 	req.TotalPrice = payload.EstimatedPrice * float64(payload.Quantity)
 	req.Justification = payload.Justification
+	req.Currency = currencyOrDefault(payload.Currency)
+	req.ShipTo = strings.TrimSpace(payload.ShipTo)
 
-	err = s.repo.UpdateRequisition(req)
+	if req.Status != models.RequisitionStatusDraft {
+		err = s.validateForSubmit(req)
+	}
+	if err == nil {
+		err = s.repo.UpdateRequisition(req)
+	}
 	if err != nil {
 		details := err.Error()
 		s.logService.Log(&adminID, "ADMIN_UPDATE_REQUISITION_FAILED", Ptr("requisition"), &requisitionID, "FAILED", &details)
@@ -189,9 +693,17 @@ func (s *requisitionService) AdminUpdateRequisition(requisitionID int, adminID i
 	return req, nil
 }
 
-func (s *requisitionService) AdminDeleteRequisition(requisitionID int, adminID int) error {
-	// We might want to get the requisition first to log its details, but for now, this is fine.
-	err := s.repo.DeleteRequisition(requisitionID)
+func (s *requisitionService) AdminDeleteRequisition(requisitionID int, adminID int, expectedVersion *int) error {
+	req, err := s.repo.GetRequisitionByID(requisitionID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkVersion(expectedVersion, req.Version); err != nil {
+		return err
+	}
+
+	err = s.repo.DeleteRequisition(requisitionID, req.Version)
 	if err != nil {
 		details := err.Error()
 		s.logService.Log(&adminID, "ADMIN_DELETE_REQUISITION_FAILED", Ptr("requisition"), &requisitionID, "FAILED", &details)
@@ -200,3 +712,12 @@ func (s *requisitionService) AdminDeleteRequisition(requisitionID int, adminID i
 	s.logService.Log(&adminID, "ADMIN_DELETE_REQUISITION_SUCCESS", Ptr("requisition"), &requisitionID, "SUCCESS", nil)
 	return nil
 }
+
+// currencyOrDefault normalises a currency code, falling back to
+// models.DefaultCurrency when none is given.
+func currencyOrDefault(currency string) string {
+	if currency = strings.ToUpper(strings.TrimSpace(currency)); currency != "" {
+		return currency
+	}
+	return models.DefaultCurrency
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -41,18 +42,41 @@ func (m *MockRequisitionRepository) GetRequisitionByID(id int) (*models.Requisit
 	}
 	return args.Get(0).(*models.Requisition), args.Error(1)
 }
-func (m *MockRequisitionRepository) UpdateRequisitionStatus(id int, status string) error {
-	args := m.Called(id, status)
-	return args.Error(0)
-}
 func (m *MockRequisitionRepository) UpdateRequisition(req *models.Requisition) error {
 	args := m.Called(req)
 	return args.Error(0)
 }
-func (m *MockRequisitionRepository) DeleteRequisition(id int) error {
-	args := m.Called(id)
+func (m *MockRequisitionRepository) DeleteRequisition(id int, version int) error {
+	args := m.Called(id, version)
+	return args.Error(0)
+}
+func (m *MockRequisitionRepository) CountRequisitions(requesterID *int, status string) (int, error) {
+	args := m.Called(requesterID, status)
+	return args.Int(0), args.Error(1)
+}
+func (m *MockRequisitionRepository) UpdateRequisitionApprover(id int, approverID *int, version int) error {
+	args := m.Called(id, approverID, version)
+	return args.Error(0)
+}
+func (m *MockRequisitionRepository) SubmitRequisition(id int, actorID int) (*models.Requisition, error) {
+	args := m.Called(id, actorID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Requisition), args.Error(1)
+}
+func (m *MockRequisitionRepository) CreateRequisitionRound(round *models.RequisitionRound) error {
+	args := m.Called(round)
+	return args.Error(0)
+}
+func (m *MockRequisitionRepository) CloseRequisitionRound(change models.StatusChange, round *models.RequisitionRound, po *models.PurchaseOrder) error {
+	args := m.Called(change, round, po)
 	return args.Error(0)
 }
+func (m *MockRequisitionRepository) GetRequisitionRounds(requisitionID int) ([]models.RequisitionRound, error) {
+	args := m.Called(requisitionID)
+	return args.Get(0).([]models.RequisitionRound), args.Error(1)
+}
 
 
 // MockPurchaseOrderService is a mock type for the PurchaseOrderService
@@ -60,12 +84,21 @@ type MockPurchaseOrderService struct {
 	mock.Mock
 }
 
-func (m *MockPurchaseOrderService) CreatePurchaseOrderFromRequisition(requisition *models.Requisition) (*models.PurchaseOrder, error) {
-	args := m.Called(requisition)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
+// CreatePurchaseOrderFromRequisition hands the order it is set up to return
+// to store, as the real service does, unless it is set up to fail.
+func (m *MockPurchaseOrderService) CreatePurchaseOrderFromRequisition(requisition *models.Requisition, actorID int, store func(po *models.PurchaseOrder) error) (*models.PurchaseOrder, error) {
+	args := m.Called(requisition, actorID)
+	if err := args.Error(1); err != nil {
+		return nil, err
 	}
-	return args.Get(0).(*models.PurchaseOrder), args.Error(1)
+	var po *models.PurchaseOrder
+	if args.Get(0) != nil {
+		po = args.Get(0).(*models.PurchaseOrder)
+	}
+	if err := store(po); err != nil {
+		return nil, err
+	}
+	return po, nil
 }
 
 func (m *MockPurchaseOrderService) GetPurchaseOrderByID(id int) (*models.PurchaseOrder, error) {
@@ -96,21 +129,100 @@ func (m *MockPurchaseOrderService) AdminUpdateRequisition(requisitionID int, adm
 func (m *MockPurchaseOrderService) AdminDeleteRequisition(requisitionID int, adminID int) error {
 	return nil
 }
+func (m *MockPurchaseOrderService) GetBuyQueue() ([]models.BuyQueueGroup, error) { return nil, nil }
+func (m *MockPurchaseOrderService) ConsolidateRequisitions(actorID int, requisitionIDs []int) (*models.PurchaseOrder, error) {
+	return nil, nil
+}
+func (m *MockPurchaseOrderService) CancelPurchaseOrder(actorID int, id int, payload models.CancelPurchaseOrderPayload, expectedVersion *int) (*models.PurchaseOrder, error) {
+	return nil, nil
+}
+func (m *MockPurchaseOrderService) ClosePurchaseOrder(actorID int, id int, payload models.ClosePurchaseOrderPayload, expectedVersion *int) (*models.PurchaseOrder, error) {
+	return nil, nil
+}
 
 
+// MockDelegationService is a mock type for the DelegationService
+type MockDelegationService struct {
+	mock.Mock
+}
+
+func (m *MockDelegationService) CreateDelegation(actorID int, payload models.CreateDelegationPayload) (*models.Delegation, error) {
+	return nil, nil
+}
+func (m *MockDelegationService) AdminCreateDelegation(actorID int, payload models.CreateDelegationPayload) (*models.Delegation, error) {
+	return nil, nil
+}
+func (m *MockDelegationService) GetMyDelegations(userID int) ([]models.Delegation, error) {
+	return nil, nil
+}
+func (m *MockDelegationService) GetAllDelegations() ([]models.Delegation, error) { return nil, nil }
+func (m *MockDelegationService) GetActiveDelegationsTo(delegateID int) ([]models.Delegation, error) {
+	args := m.Called(delegateID)
+	return args.Get(0).([]models.Delegation), args.Error(1)
+}
+func (m *MockDelegationService) DeleteDelegation(actorID int, id int) error      { return nil }
+func (m *MockDelegationService) AdminDeleteDelegation(actorID int, id int) error { return nil }
+
+// MockSoDService is a mock type for the SoDService
+type MockSoDService struct {
+	mock.Mock
+}
+
+func (m *MockSoDService) Check(check models.SoDCheck) error {
+	args := m.Called(check)
+	return args.Error(0)
+}
+func (m *MockSoDService) GetAllRules() ([]models.SoDRule, error) { return nil, nil }
+func (m *MockSoDService) CreateRule(actorID int, payload models.SoDRulePayload) (*models.SoDRule, error) {
+	return nil, nil
+}
+func (m *MockSoDService) UpdateRule(actorID int, id int, payload models.SoDRulePayload) (*models.SoDRule, error) {
+	return nil, nil
+}
+func (m *MockSoDService) DeleteRule(actorID int, id int) error          { return nil }
+func (m *MockSoDService) GetViolations() ([]models.SoDViolation, error) { return nil, nil }
+
+// newPassingSoD returns a SoDService mock that allows every duty.
+func newPassingSoD() *MockSoDService {
+	mockSoD := new(MockSoDService)
+	mockSoD.On("Check", mock.Anything).Return(nil)
+	return mockSoD
+}
+
+// newVendorLookup returns a VendorRepository mock that finds any vendor, with
+// no recorded creator.
+func newVendorLookup() *MockVendorRepository {
+	mockVendorRepo := new(MockVendorRepository)
+	mockVendorRepo.On("GetVendorByID", mock.Anything).Return(&models.Vendor{}, nil)
+	return mockVendorRepo
+}
+
+// newApproverMocks returns role and delegation mocks for a user who holds
+// requisition:approve and has no delegations.
+func newApproverMocks(userID int) (*MockRoleService, *MockDelegationService) {
+	mockRoles := new(MockRoleService)
+	mockDelegations := new(MockDelegationService)
+	mockRoles.On("GetUserPermissions", userID).Return(map[string]bool{models.PermRequisitionApprove: true}, nil)
+	mockDelegations.On("GetActiveDelegationsTo", userID).Return([]models.Delegation{}, nil)
+	return mockRoles, mockDelegations
+}
+
 func TestRequisitionService(t *testing.T) {
 	t.Run("CreateRequisition", func(t *testing.T) {
 		mockReqRepo := new(MockRequisitionRepository)
 		mockPoService := new(MockPurchaseOrderService)
 		mockLogService := new(MockActivityLogService)
-		requisitionService := NewRequisitionService(mockReqRepo, mockPoService, mockLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, mockPoService, mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
 		payload := models.CreateRequisitionPayload{
 			ItemDescription: "Test Item",
 			Quantity:        10,
 			EstimatedPrice:  100,
 		}
 
-		mockReqRepo.On("CreateRequisition", mock.AnythingOfType("*models.Requisition")).Return(&models.Requisition{ID: 1, Status: "Pending", TotalPrice: 1000}, nil).Once()
+		mockReqRepo.On("CreateRequisition", mock.MatchedBy(func(r *models.Requisition) bool {
+			return r.Status == models.RequisitionStatusDraft
+		})).Return(&models.Requisition{ID: 1, Status: models.RequisitionStatusDraft, TotalPrice: 1000}, nil).Once()
 		mockLogService.On("Log", mock.Anything, "CREATE_REQUISITION_SUCCESS", mock.Anything, mock.Anything, "SUCCESS", mock.Anything).Return()
 
 		req, err := requisitionService.CreateRequisition(payload, 1)
@@ -124,54 +236,545 @@ func TestRequisitionService(t *testing.T) {
 		mockReqRepo := new(MockRequisitionRepository)
 		mockPoService := new(MockPurchaseOrderService)
 		mockLogService := new(MockActivityLogService)
-		requisitionService := NewRequisitionService(mockReqRepo, mockPoService, mockLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, mockPoService, mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
 		reqID := 1
 		adminID := 99
 		vendorID := 123
-		mockRequisition := &models.Requisition{ID: reqID, VendorID: &vendorID}
+		mockRequisition := &models.Requisition{ID: reqID, VendorID: &vendorID, Status: "Pending", Version: 3}
+		po := &models.PurchaseOrder{PONumber: "PO-2026-0001"}
 
-		mockReqRepo.On("UpdateRequisitionStatus", reqID, "Approved").Return(nil).Once()
 		mockReqRepo.On("GetRequisitionByID", reqID).Return(mockRequisition, nil).Once()
-		mockPoService.On("CreatePurchaseOrderFromRequisition", mockRequisition).Return(&models.PurchaseOrder{}, nil).Once()
+		mockPoService.On("CreatePurchaseOrderFromRequisition", mockRequisition, adminID).Return(po, nil).Once()
+		// The change is guarded by the version read, so a requisition withdrawn
+		// and resubmitted in between is not approved by mistake.
+		mockReqRepo.On("CloseRequisitionRound", models.StatusChange{From: models.RequisitionStatusPending, To: models.RequisitionStatusApproved, Version: 3, ActorID: &adminID}, mock.MatchedBy(func(r *models.RequisitionRound) bool {
+			return r.RequisitionID == reqID && r.Outcome == models.RequisitionStatusApproved && *r.DecidedBy == adminID
+		}), po).Return(nil).Once()
 		mockLogService.On("Log", &adminID, "APPROVE_REQUISITION_SUCCESS", mock.Anything, &reqID, "SUCCESS", mock.Anything).Return()
 
-		err := requisitionService.ApproveRequisition(reqID, adminID)
+		err := requisitionService.ApproveRequisition(reqID, adminID, nil)
 		assert.NoError(t, err)
 		mockReqRepo.AssertExpectations(t)
 		mockPoService.AssertExpectations(t)
 		mockLogService.AssertExpectations(t)
 	})
 
+	t.Run("ApproveRequisition - Stale Version", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockPoService := new(MockPurchaseOrderService)
+		mockLogService := new(MockActivityLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, mockPoService, mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 2
+		adminID := 99
+		staleVersion := 3
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, Status: "Pending", Version: 5}, nil).Once()
+		mockLogService.On("Log", &adminID, "APPROVE_REQUISITION_FAILED", mock.Anything, &reqID, "FAILED", mock.Anything).Return()
+
+		err := requisitionService.ApproveRequisition(reqID, adminID, &staleVersion)
+		assert.ErrorIs(t, err, repository.ErrVersionConflict)
+		mockPoService.AssertNotCalled(t, "CreatePurchaseOrderFromRequisition", mock.Anything, mock.Anything)
+		mockReqRepo.AssertNotCalled(t, "CloseRequisitionRound", mock.Anything, mock.Anything, mock.Anything)
+	})
+
 	t.Run("ApproveRequisition - UpdateStatus Fails", func(t *testing.T) {
 		mockReqRepo := new(MockRequisitionRepository)
 		mockPoService := new(MockPurchaseOrderService)
 		mockLogService := new(MockActivityLogService)
-		requisitionService := NewRequisitionService(mockReqRepo, mockPoService, mockLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, mockPoService, mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
 		reqID := 2
 		adminID := 99
 		expectedErr := errors.New("update failed")
-		mockReqRepo.On("UpdateRequisitionStatus", reqID, "Approved").Return(expectedErr).Once()
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, Status: "Pending"}, nil).Once()
+		mockPoService.On("CreatePurchaseOrderFromRequisition", mock.Anything, adminID).Return(&models.PurchaseOrder{}, nil).Once()
+		mockReqRepo.On("CloseRequisitionRound", models.StatusChange{From: models.RequisitionStatusPending, To: models.RequisitionStatusApproved, ActorID: &adminID}, mock.Anything, mock.Anything).Return(expectedErr).Once()
 		mockLogService.On("Log", &adminID, "APPROVE_REQUISITION_FAILED", mock.Anything, &reqID, "FAILED", mock.Anything).Return()
 
-		err := requisitionService.ApproveRequisition(reqID, adminID)
+		err := requisitionService.ApproveRequisition(reqID, adminID, nil)
 
 		assert.Error(t, err)
 		assert.Equal(t, expectedErr, err)
 		mockReqRepo.AssertExpectations(t)
 	})
 
+	t.Run("ApproveRequisition - Purchase Order Not Created", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockPoService := new(MockPurchaseOrderService)
+		mockLogService := new(MockActivityLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, mockPoService, mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 2
+		adminID := 99
+		vendorID := 123
+		po := &models.PurchaseOrder{PONumber: "PO-2026-0002"}
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, VendorID: &vendorID, Status: "Pending"}, nil).Once()
+		mockPoService.On("CreatePurchaseOrderFromRequisition", mock.Anything, adminID).Return(po, nil).Once()
+		// The order is stored in the same transaction as the approval, so a
+		// failure leaves the requisition Pending and it can be approved again.
+		mockReqRepo.On("CloseRequisitionRound", mock.Anything, mock.Anything, po).Return(repository.ErrRequisitionAlreadyOrdered).Once()
+		mockLogService.On("Log", &adminID, "APPROVE_REQUISITION_FAILED", mock.Anything, &reqID, "FAILED", mock.Anything).Return()
+
+		err := requisitionService.ApproveRequisition(reqID, adminID, nil)
+		assert.Equal(t, repository.ErrRequisitionAlreadyOrdered, err)
+		mockReqRepo.AssertExpectations(t)
+		mockLogService.AssertExpectations(t)
+		mockLogService.AssertNotCalled(t, "Log", mock.Anything, "APPROVE_REQUISITION_SUCCESS", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("ApproveRequisition - Purchase Order Cannot Be Built", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockPoService := new(MockPurchaseOrderService)
+		mockLogService := new(MockActivityLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, mockPoService, mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 2
+		adminID := 99
+		expectedErr := errors.New("numbering failed")
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, Status: "Pending"}, nil).Once()
+		mockPoService.On("CreatePurchaseOrderFromRequisition", mock.Anything, adminID).Return(nil, expectedErr).Once()
+		mockLogService.On("Log", &adminID, "APPROVE_REQUISITION_FAILED", mock.Anything, &reqID, "FAILED", mock.Anything).Return()
+
+		err := requisitionService.ApproveRequisition(reqID, adminID, nil)
+		assert.Equal(t, expectedErr, err)
+		mockReqRepo.AssertNotCalled(t, "CloseRequisitionRound", mock.Anything, mock.Anything, mock.Anything)
+	})
+
 	t.Run("RejectRequisition", func(t *testing.T) {
 		mockReqRepo := new(MockRequisitionRepository)
 		mockPoService := new(MockPurchaseOrderService)
 		mockLogService := new(MockActivityLogService)
-		requisitionService := NewRequisitionService(mockReqRepo, mockPoService, mockLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, mockPoService, mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
 		reqID := 3
 		adminID := 99
-		mockReqRepo.On("UpdateRequisitionStatus", reqID, "Rejected").Return(nil).Once()
-		mockLogService.On("Log", &adminID, "REJECT_REQUISITION_SUCCESS", mock.Anything, &reqID, "SUCCESS", mock.Anything).Return()
-		err := requisitionService.RejectRequisition(reqID, adminID)
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, Status: "Pending", Round: 2}, nil).Once()
+		mockReqRepo.On("CloseRequisitionRound", models.StatusChange{From: models.RequisitionStatusPending, To: models.RequisitionStatusRejected, ActorID: &adminID, Reason: "Over budget"}, mock.MatchedBy(func(r *models.RequisitionRound) bool {
+			return r.Round == 2 && r.Outcome == models.RequisitionStatusRejected && r.Reason == "Over budget"
+		}), (*models.PurchaseOrder)(nil)).Return(nil).Once()
+		details := "Rejected: Over budget"
+		mockLogService.On("Log", &adminID, "REJECT_REQUISITION_SUCCESS", mock.Anything, &reqID, "SUCCESS", &details).Return()
+		err := requisitionService.RejectRequisition(reqID, adminID, " Over budget ", nil)
+		assert.NoError(t, err)
+		mockReqRepo.AssertExpectations(t)
+		mockLogService.AssertExpectations(t)
+	})
+
+	t.Run("RejectRequisition - Round Not Recorded", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockLogService := new(MockActivityLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, new(MockPurchaseOrderService), mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 3
+		adminID := 99
+		expectedErr := errors.New("insert failed")
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, Status: "Pending", Round: 2}, nil).Once()
+		// The status change and the round are one repository call, so the
+		// requisition stays Pending when the round cannot be written.
+		mockReqRepo.On("CloseRequisitionRound", mock.Anything, mock.Anything, mock.Anything).Return(expectedErr).Once()
+		mockLogService.On("Log", &adminID, "REJECT_REQUISITION_FAILED", mock.Anything, &reqID, "FAILED", mock.Anything).Return()
+
+		err := requisitionService.RejectRequisition(reqID, adminID, "Over budget", nil)
+		assert.Equal(t, expectedErr, err)
+		mockLogService.AssertExpectations(t)
+	})
+
+	t.Run("ApproveRequisition - On Behalf Of Delegator", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockPoService := new(MockPurchaseOrderService)
+		mockLogService := new(MockActivityLogService)
+		mockRoles := new(MockRoleService)
+		mockDelegations := new(MockDelegationService)
+		requisitionService := NewRequisitionService(mockReqRepo, mockPoService, mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 4
+		approverID := 5
+		delegateID := 6
+		mockRequisition := &models.Requisition{ID: reqID, Status: "Pending", TotalPrice: 400, ApproverID: &approverID}
+
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(mockRequisition, nil).Once()
+		mockRoles.On("GetUserPermissions", delegateID).Return(map[string]bool{}, nil)
+		mockRoles.On("GetUserPermissions", approverID).Return(map[string]bool{models.PermRequisitionApprove: true}, nil)
+		mockDelegations.On("GetActiveDelegationsTo", delegateID).Return([]models.Delegation{
+			{DelegatorID: approverID, DelegatorName: "Approver One", DelegateID: delegateID, DelegateName: "Deputy"},
+		}, nil)
+		mockReqRepo.On("CloseRequisitionRound", models.StatusChange{
+			From: models.RequisitionStatusPending, To: models.RequisitionStatusApproved, ActorID: &delegateID,
+			Reason: "Approved by Deputy on behalf of Approver One",
+		}, mock.MatchedBy(func(r *models.RequisitionRound) bool {
+			return *r.DecidedBy == delegateID && *r.OnBehalfOfID == approverID
+		}), mock.Anything).Return(nil).Once()
+		mockPoService.On("CreatePurchaseOrderFromRequisition", mockRequisition, delegateID).Return(&models.PurchaseOrder{}, nil).Once()
+		details := "Approved by Deputy on behalf of Approver One"
+		mockLogService.On("Log", &delegateID, "APPROVE_REQUISITION_SUCCESS", mock.Anything, &reqID, "SUCCESS", &details).Return()
+
+		err := requisitionService.ApproveRequisition(reqID, delegateID, nil)
+		assert.NoError(t, err)
+		mockReqRepo.AssertExpectations(t)
+		mockLogService.AssertExpectations(t)
+	})
+
+	t.Run("ApproveRequisition - Delegation Limit", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockPoService := new(MockPurchaseOrderService)
+		mockLogService := new(MockActivityLogService)
+		mockRoles := new(MockRoleService)
+		mockDelegations := new(MockDelegationService)
+		requisitionService := NewRequisitionService(mockReqRepo, mockPoService, mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 5
+		delegateID := 6
+		limit := 500.0
+
+		// Unassigned requisitions follow delegations from anyone holding requisition:approve.
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, Status: "Pending", TotalPrice: 1200}, nil).Once()
+		mockRoles.On("GetUserPermissions", delegateID).Return(map[string]bool{}, nil)
+		mockRoles.On("GetUserPermissions", 5).Return(map[string]bool{models.PermRequisitionApprove: true}, nil)
+		mockDelegations.On("GetActiveDelegationsTo", delegateID).Return([]models.Delegation{{DelegatorID: 5, DelegateID: delegateID, MaxAmount: &limit}}, nil)
+		mockLogService.On("Log", &delegateID, "APPROVE_REQUISITION_FAILED", mock.Anything, &reqID, "FAILED", mock.Anything).Return()
+
+		err := requisitionService.ApproveRequisition(reqID, delegateID, nil)
+		assert.Equal(t, ErrDelegationLimitExceeded, err)
+		mockReqRepo.AssertNotCalled(t, "CloseRequisitionRound", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("RejectRequisition - Assigned To Someone Else", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockPoService := new(MockPurchaseOrderService)
+		mockLogService := new(MockActivityLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, mockPoService, mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 6
+		adminID := 99
+		approverID := 5
+
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, Status: "Pending", ApproverID: &approverID}, nil).Once()
+		mockLogService.On("Log", &adminID, "REJECT_REQUISITION_FAILED", mock.Anything, &reqID, "FAILED", mock.Anything).Return()
+
+		err := requisitionService.RejectRequisition(reqID, adminID, "Over budget", nil)
+		assert.Equal(t, ErrForbidden, err)
+		mockReqRepo.AssertNotCalled(t, "CloseRequisitionRound", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("ReassignApprover", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockPoService := new(MockPurchaseOrderService)
+		mockLogService := new(MockActivityLogService)
+		mockRoles, mockDelegations := newApproverMocks(7)
+		requisitionService := NewRequisitionService(mockReqRepo, mockPoService, mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 7
+		adminID := 99
+		oldApproverID := 5
+		newApproverID := 7
+
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, Status: "Pending", ApproverID: &oldApproverID, Version: 4}, nil).Once()
+		mockReqRepo.On("UpdateRequisitionApprover", reqID, &newApproverID, 4).Return(nil).Once()
+		details := "Approver changed from user 5 to user 7: on leave"
+		mockLogService.On("Log", &adminID, "REASSIGN_REQUISITION_SUCCESS", mock.Anything, &reqID, "SUCCESS", &details).Return()
+
+		req, err := requisitionService.ReassignApprover(reqID, adminID, models.ReassignApproverPayload{ApproverID: &newApproverID, Reason: "on leave"})
+		assert.NoError(t, err)
+		assert.Equal(t, &newApproverID, req.ApproverID)
+		assert.Equal(t, 5, req.Version)
+		mockReqRepo.AssertExpectations(t)
+		mockLogService.AssertExpectations(t)
+	})
+
+	t.Run("ApproveRequisition - Requester Cannot Approve", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockPoService := new(MockPurchaseOrderService)
+		mockLogService := new(MockActivityLogService)
+		mockVendorRepo := new(MockVendorRepository)
+		mockSoDRepo := new(MockSoDRepository)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		sodService := NewSoDService(mockSoDRepo, mockLogService)
+		requisitionService := NewRequisitionService(mockReqRepo, mockPoService, mockLogService, mockRoles, mockDelegations, sodService, mockVendorRepo)
+		reqID := 8
+		adminID := 99
+		vendorID := 3
+
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, RequesterID: adminID, VendorID: &vendorID, Status: "Pending"}, nil).Once()
+		mockVendorRepo.On("GetVendorByID", vendorID).Return(&models.Vendor{ID: vendorID}, nil)
+		mockSoDRepo.On("GetEnabledRules").Return([]models.SoDRule{
+			{ID: 1, Code: "requester_not_approver", Description: "The requester of a requisition cannot approve it", FirstDuty: models.DutyRequisitionCreate, SecondDuty: models.DutyRequisitionApprove},
+		}, nil)
+		mockSoDRepo.On("CreateViolation", mock.MatchedBy(func(v *models.SoDViolation) bool {
+			return v.RuleCode == "requester_not_approver" && *v.UserID == adminID && v.EntityID == reqID
+		})).Return(nil).Once()
+		mockLogService.On("Log", &adminID, "SOD_VIOLATION", mock.Anything, &reqID, "FAILED", mock.Anything).Return()
+		mockLogService.On("Log", &adminID, "APPROVE_REQUISITION_FAILED", mock.Anything, &reqID, "FAILED", mock.Anything).Return()
+
+		err := requisitionService.ApproveRequisition(reqID, adminID, nil)
+		assert.ErrorIs(t, err, ErrSoDViolation)
+		assert.Contains(t, err.Error(), "The requester of a requisition cannot approve it")
+		mockReqRepo.AssertNotCalled(t, "CloseRequisitionRound", mock.Anything, mock.Anything, mock.Anything)
+		mockSoDRepo.AssertExpectations(t)
+	})
+
+	t.Run("SubmitRequisition", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockLogService := new(MockActivityLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, new(MockPurchaseOrderService), mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 11
+		requesterID := 4
+		vendorID := 3
+
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, RequesterID: requesterID, VendorID: &vendorID, ItemDescription: "Monitors", Quantity: 2, EstimatedPrice: 150, Status: models.RequisitionStatusDraft}, nil).Once()
+		mockReqRepo.On("SubmitRequisition", reqID, requesterID).Return(&models.Requisition{ID: reqID, RequesterID: requesterID, Status: models.RequisitionStatusPending, Round: 1}, nil).Once()
+		mockLogService.On("Log", &requesterID, "SUBMIT_REQUISITION_SUCCESS", mock.Anything, &reqID, "SUCCESS", mock.Anything).Return()
+
+		req, err := requisitionService.SubmitRequisition(reqID, requesterID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.RequisitionStatusPending, req.Status)
+		mockReqRepo.AssertExpectations(t)
+	})
+
+	t.Run("SubmitRequisition - Incomplete Draft", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, new(MockPurchaseOrderService), new(MockActivityLogService), mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 12
+		requesterID := 4
+
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, RequesterID: requesterID, ItemDescription: "Catering", Status: models.RequisitionStatusDraft}, nil).Once()
+
+		_, err := requisitionService.SubmitRequisition(reqID, requesterID)
+		assert.ErrorIs(t, err, ErrIncompleteRequisition)
+		assert.Equal(t, "requisition is incomplete: quantity must be greater than 0; estimated price must be greater than 0; vendor is required", err.Error())
+		mockReqRepo.AssertNotCalled(t, "SubmitRequisition", mock.Anything, mock.Anything)
+	})
+
+	t.Run("WithdrawRequisition", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockLogService := new(MockActivityLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, new(MockPurchaseOrderService), mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 13
+		requesterID := 4
+
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, RequesterID: requesterID, Status: models.RequisitionStatusPending}, nil).Once()
+		mockReqRepo.On("CloseRequisitionRound", models.StatusChange{From: models.RequisitionStatusPending, To: models.RequisitionStatusDraft, ActorID: &requesterID}, mock.MatchedBy(func(r *models.RequisitionRound) bool {
+			return r.Outcome == models.RoundOutcomeWithdrawn && *r.DecidedBy == requesterID
+		}), (*models.PurchaseOrder)(nil)).Return(nil).Once()
+		mockLogService.On("Log", &requesterID, "WITHDRAW_REQUISITION_SUCCESS", mock.Anything, &reqID, "SUCCESS", mock.Anything).Return()
+
+		req, err := requisitionService.WithdrawRequisition(reqID, requesterID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.RequisitionStatusDraft, req.Status)
+		mockReqRepo.AssertExpectations(t)
+	})
+
+	t.Run("UpdateRequisition - Pending Must Be Withdrawn First", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, new(MockPurchaseOrderService), new(MockActivityLogService), mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 14
+		requesterID := 4
+
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, RequesterID: requesterID, Status: models.RequisitionStatusPending}, nil).Once()
+
+		_, err := requisitionService.UpdateRequisition(reqID, requesterID, models.CreateRequisitionPayload{ItemDescription: "Monitors"}, nil)
+		assert.Equal(t, ErrCannotModify, err)
+		mockReqRepo.AssertNotCalled(t, "UpdateRequisition", mock.Anything)
+	})
+
+	t.Run("UpdateRequisition - Stale Version", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, new(MockPurchaseOrderService), new(MockActivityLogService), mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 14
+		requesterID := 4
+		staleVersion := 2
+
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, RequesterID: requesterID, Status: models.RequisitionStatusDraft, Version: 3}, nil).Once()
+
+		_, err := requisitionService.UpdateRequisition(reqID, requesterID, models.CreateRequisitionPayload{ItemDescription: "Monitors"}, &staleVersion)
+		assert.ErrorIs(t, err, repository.ErrVersionConflict)
+		mockReqRepo.AssertNotCalled(t, "UpdateRequisition", mock.Anything)
+	})
+
+	t.Run("AdminUpdateRequisition - Writes Against The Version Read", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockLogService := new(MockActivityLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, new(MockPurchaseOrderService), mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 14
+		adminID := 1
+		vendorID := 3
+
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, RequesterID: 4, Status: models.RequisitionStatusPending, Version: 5}, nil).Once()
+		// Another admin saved in between, so the repository finds a newer version.
+		mockReqRepo.On("UpdateRequisition", mock.MatchedBy(func(r *models.Requisition) bool {
+			return r.Version == 5 && r.ItemDescription == "Monitors"
+		})).Return(repository.ErrVersionConflict).Once()
+		mockLogService.On("Log", &adminID, "ADMIN_UPDATE_REQUISITION_FAILED", mock.Anything, &reqID, "FAILED", mock.Anything).Return()
+
+		_, err := requisitionService.AdminUpdateRequisition(reqID, adminID, models.CreateRequisitionPayload{VendorID: &vendorID, ItemDescription: "Monitors", Quantity: 2, EstimatedPrice: 150}, nil)
+		assert.ErrorIs(t, err, repository.ErrVersionConflict)
+		mockReqRepo.AssertExpectations(t)
+	})
+
+	t.Run("AdminUpdateRequisition - Submitted Requisition Stays Complete", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockLogService := new(MockActivityLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, new(MockPurchaseOrderService), mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 14
+		adminID := 1
+
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, RequesterID: 4, Status: models.RequisitionStatusPending, Version: 5}, nil).Once()
+		mockLogService.On("Log", &adminID, "ADMIN_UPDATE_REQUISITION_FAILED", mock.Anything, &reqID, "FAILED", mock.Anything).Return()
+
+		// Clearing the vendor would leave nothing to raise a purchase order against.
+		_, err := requisitionService.AdminUpdateRequisition(reqID, adminID, models.CreateRequisitionPayload{ItemDescription: "Monitors", Quantity: 2, EstimatedPrice: 150}, nil)
+		assert.ErrorIs(t, err, ErrIncompleteRequisition)
+		mockReqRepo.AssertNotCalled(t, "UpdateRequisition", mock.Anything)
+	})
+
+	t.Run("ReturnRequisition", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockLogService := new(MockActivityLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, new(MockPurchaseOrderService), mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 15
+		adminID := 99
+
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, RequesterID: 4, Status: models.RequisitionStatusPending, Round: 1}, nil).Once()
+		mockReqRepo.On("CloseRequisitionRound", models.StatusChange{From: models.RequisitionStatusPending, To: models.RequisitionStatusReturned, ActorID: &adminID, Reason: "Attach a quote"}, mock.MatchedBy(func(r *models.RequisitionRound) bool {
+			return r.Round == 1 && r.Outcome == models.RequisitionStatusReturned && r.Reason == "Attach a quote"
+		}), (*models.PurchaseOrder)(nil)).Return(nil).Once()
+		mockLogService.On("Log", &adminID, "RETURN_REQUISITION_SUCCESS", mock.Anything, &reqID, "SUCCESS", mock.Anything).Return()
+
+		err := requisitionService.ReturnRequisition(reqID, adminID, "Attach a quote", nil)
 		assert.NoError(t, err)
 		mockReqRepo.AssertExpectations(t)
+	})
+
+	t.Run("RejectRequisition - Reason Required", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockLogService := new(MockActivityLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, new(MockPurchaseOrderService), mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 16
+		adminID := 99
+
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, Status: models.RequisitionStatusPending}, nil).Once()
+		mockLogService.On("Log", &adminID, "REJECT_REQUISITION_FAILED", mock.Anything, &reqID, "FAILED", mock.Anything).Return()
+
+		err := requisitionService.RejectRequisition(reqID, adminID, "   ", nil)
+		assert.Equal(t, ErrReasonRequired, err)
+		mockReqRepo.AssertNotCalled(t, "CloseRequisitionRound", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("SubmitRequisition - Resubmit After Return", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockLogService := new(MockActivityLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, new(MockPurchaseOrderService), mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		reqID := 17
+		requesterID := 4
+		vendorID := 3
+
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, RequesterID: requesterID, VendorID: &vendorID, ItemDescription: "Monitors", Quantity: 2, EstimatedPrice: 150, Status: models.RequisitionStatusReturned, Round: 1}, nil).Once()
+		mockReqRepo.On("SubmitRequisition", reqID, requesterID).Return(&models.Requisition{ID: reqID, Status: models.RequisitionStatusPending, Round: 2}, nil).Once()
+		details := "Round 2"
+		mockLogService.On("Log", &requesterID, "SUBMIT_REQUISITION_SUCCESS", mock.Anything, &reqID, "SUCCESS", &details).Return()
+
+		req, err := requisitionService.SubmitRequisition(reqID, requesterID)
+		assert.NoError(t, err)
+		assert.Equal(t, reqID, req.ID)
+		assert.Equal(t, 2, req.Round)
 		mockLogService.AssertExpectations(t)
 	})
+
+	t.Run("GetRequisitionHistory - Other Requester", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockRoles := new(MockRoleService)
+		requisitionService := NewRequisitionService(mockReqRepo, new(MockPurchaseOrderService), new(MockActivityLogService), mockRoles, new(MockDelegationService), newPassingSoD(), newVendorLookup())
+		reqID := 18
+
+		mockReqRepo.On("GetRequisitionByID", reqID).Return(&models.Requisition{ID: reqID, RequesterID: 4}, nil)
+		mockRoles.On("GetUserPermissions", 7).Return(map[string]bool{models.PermRequisitionCreate: true}, nil)
+
+		_, err := requisitionService.GetRequisitionHistory(reqID, 7)
+		assert.Equal(t, ErrForbidden, err)
+		mockReqRepo.AssertNotCalled(t, "GetRequisitionRounds", mock.Anything)
+	})
+}
+
+func TestBulkRequisitionDecisions(t *testing.T) {
+	t.Run("BulkApproveRequisitions - Reports Each Item And Links Logs", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockPoService := new(MockPurchaseOrderService)
+		mockLogService := new(MockActivityLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, mockPoService, mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		adminID := 99
+
+		mockReqRepo.On("GetRequisitionByID", 1).Return(&models.Requisition{ID: 1, Status: models.RequisitionStatusPending}, nil)
+		mockReqRepo.On("GetRequisitionByID", 2).Return(&models.Requisition{ID: 2, Status: models.RequisitionStatusApproved}, nil)
+		mockReqRepo.On("GetRequisitionByID", 3).Return(nil, repository.ErrRequisitionNotFound)
+		mockReqRepo.On("CloseRequisitionRound", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+		mockPoService.On("CreatePurchaseOrderFromRequisition", mock.Anything, adminID).Return(&models.PurchaseOrder{}, nil).Once()
+
+		var batchIDs []string
+		mockLogService.On("LogBatch", mock.Anything, &adminID, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { batchIDs = append(batchIDs, args.String(0)) }).Return()
+
+		decision, err := requisitionService.BulkApproveRequisitions([]int{1, 2, 3, 1}, adminID, "Monday queue")
+		assert.NoError(t, err)
+		assert.Len(t, decision.Outcomes, 4)
+		assert.NoError(t, decision.Outcomes[0].Err)
+		assert.Equal(t, ErrCannotModify, decision.Outcomes[1].Err)
+		assert.Equal(t, repository.ErrRequisitionNotFound, decision.Outcomes[2].Err)
+		assert.Equal(t, 1, decision.Outcomes[3].RequisitionID)
+		assert.Equal(t, ErrDuplicateBulkItem, decision.Outcomes[3].Err)
+
+		// One entry per item plus the summary, all under the same batch.
+		assert.Len(t, batchIDs, 4)
+		for _, id := range batchIDs {
+			assert.Equal(t, decision.BatchID, id)
+		}
+		details := "1 of 4 succeeded: Monday queue"
+		mockLogService.AssertCalled(t, "LogBatch", decision.BatchID, &adminID, "BULK_APPROVE_REQUISITIONS", mock.Anything, (*int)(nil), "FAILED", &details)
+		mockLogService.AssertNotCalled(t, "Log", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockPoService.AssertExpectations(t)
+	})
+
+	t.Run("BulkRejectRequisitions - Reason Required", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockLogService := new(MockActivityLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, new(MockPurchaseOrderService), mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+
+		_, err := requisitionService.BulkRejectRequisitions([]int{1, 2}, 99, "  ")
+		assert.Equal(t, ErrReasonRequired, err)
+		mockReqRepo.AssertNotCalled(t, "GetRequisitionByID", mock.Anything)
+	})
+
+	t.Run("BulkRejectRequisitions - Rejects Each With The Reason", func(t *testing.T) {
+		mockReqRepo := new(MockRequisitionRepository)
+		mockLogService := new(MockActivityLogService)
+		mockRoles, mockDelegations := newApproverMocks(99)
+		requisitionService := NewRequisitionService(mockReqRepo, new(MockPurchaseOrderService), mockLogService, mockRoles, mockDelegations, newPassingSoD(), newVendorLookup())
+		adminID := 99
+
+		for _, id := range []int{4, 5} {
+			mockReqRepo.On("GetRequisitionByID", id).Return(&models.Requisition{ID: id, Status: models.RequisitionStatusPending}, nil).Once()
+			mockReqRepo.On("CloseRequisitionRound", models.StatusChange{From: models.RequisitionStatusPending, To: models.RequisitionStatusRejected, ActorID: &adminID, Reason: "Duplicate"}, mock.MatchedBy(func(r *models.RequisitionRound) bool {
+				return r.RequisitionID == id && r.Reason == "Duplicate"
+			}), (*models.PurchaseOrder)(nil)).Return(nil).Once()
+		}
+		mockLogService.On("LogBatch", mock.Anything, &adminID, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+
+		decision, err := requisitionService.BulkRejectRequisitions([]int{4, 5}, adminID, " Duplicate ")
+		assert.NoError(t, err)
+		assert.Len(t, decision.Outcomes, 2)
+		for _, outcome := range decision.Outcomes {
+			assert.NoError(t, outcome.Err)
+		}
+		details := "2 of 2 succeeded: Duplicate"
+		mockLogService.AssertCalled(t, "LogBatch", decision.BatchID, &adminID, "BULK_REJECT_REQUISITIONS", mock.Anything, (*int)(nil), "SUCCESS", &details)
+		mockReqRepo.AssertExpectations(t)
+	})
 }
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"errors"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"strings"
+)
+
+var (
+	ErrUnknownPermission   = errors.New("unknown permission")
+	ErrSystemRoleProtected = errors.New("system roles cannot be deleted and the Admin role must keep role:manage")
+)
+
+// RoleService defines the interface for managing roles, permissions and
+// user role assignments.
+type RoleService interface {
+	GetAllPermissions() ([]models.Permission, error)
+	GetAllRoles() ([]models.Role, error)
+	CreateRole(actorID int, payload models.CreateRolePayload) (*models.Role, error)
+	UpdateRole(actorID int, id int, payload models.UpdateRolePayload) (*models.Role, error)
+	DeleteRole(actorID int, id int) error
+	GetUserRoles(userID int) (*models.UserRoles, error)
+	SetUserRoles(actorID int, userID int, payload models.SetUserRolesPayload) (*models.UserRoles, error)
+	GetUserPermissions(userID int) (map[string]bool, error)
+}
+
+type roleService struct {
+	repo       repository.RoleRepository
+	logService ActivityLogService
+}
+
+// NewRoleService creates a new instance of RoleService.
+func NewRoleService(repo repository.RoleRepository, logService ActivityLogService) RoleService {
+	return &roleService{repo: repo, logService: logService}
+}
+
+// GetAllPermissions returns the permission catalogue.
+func (s *roleService) GetAllPermissions() ([]models.Permission, error) {
+	return s.repo.GetAllPermissions()
+}
+
+// GetAllRoles returns every role with its permissions.
+func (s *roleService) GetAllRoles() ([]models.Role, error) {
+	return s.repo.GetAllRoles()
+}
+
+// CreateRole defines a new role with the given permissions.
+func (s *roleService) CreateRole(actorID int, payload models.CreateRolePayload) (*models.Role, error) {
+	if err := s.checkPermissions(payload.Permissions); err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{
+		Name:        strings.TrimSpace(payload.Name),
+		Description: payload.Description,
+		Permissions: payload.Permissions,
+	}
+	if err := s.repo.CreateRole(role); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "CREATE_ROLE_FAILED", Ptr("role"), nil, "FAILED", &details)
+		return nil, err
+	}
+
+	details := "Role " + role.Name + ": " + strings.Join(role.Permissions, ", ")
+	s.logService.Log(&actorID, "CREATE_ROLE_SUCCESS", Ptr("role"), &role.ID, "SUCCESS", &details)
+	return role, nil
+}
+
+// UpdateRole replaces a role's description and permissions.
+func (s *roleService) UpdateRole(actorID int, id int, payload models.UpdateRolePayload) (*models.Role, error) {
+	role, err := s.repo.GetRoleByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPermissions(payload.Permissions); err != nil {
+		return nil, err
+	}
+	// Removing role:manage from Admin would leave nobody able to undo it.
+	if role.Name == models.AdminRole && !contains(payload.Permissions, models.PermRoleManage) {
+		return nil, ErrSystemRoleProtected
+	}
+
+	role.Description = payload.Description
+	role.Permissions = payload.Permissions
+	if err := s.repo.UpdateRole(role); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "UPDATE_ROLE_FAILED", Ptr("role"), &id, "FAILED", &details)
+		return nil, err
+	}
+
+	details := "Role " + role.Name + ": " + strings.Join(role.Permissions, ", ")
+	s.logService.Log(&actorID, "UPDATE_ROLE_SUCCESS", Ptr("role"), &id, "SUCCESS", &details)
+	return s.repo.GetRoleByID(id)
+}
+
+// DeleteRole removes a custom role that is not assigned to anyone.
+func (s *roleService) DeleteRole(actorID int, id int) error {
+	role, err := s.repo.GetRoleByID(id)
+	if err != nil {
+		return err
+	}
+	if role.IsSystem {
+		return ErrSystemRoleProtected
+	}
+
+	if err := s.repo.DeleteRole(id); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "DELETE_ROLE_FAILED", Ptr("role"), &id, "FAILED", &details)
+		return err
+	}
+
+	s.logService.Log(&actorID, "DELETE_ROLE_SUCCESS", Ptr("role"), &id, "SUCCESS", &role.Name)
+	return nil
+}
+
+// GetUserRoles returns the user's primary and additional roles.
+func (s *roleService) GetUserRoles(userID int) (*models.UserRoles, error) {
+	return s.repo.GetUserRoles(userID)
+}
+
+// SetUserRoles replaces the roles a user holds in addition to their primary role.
+func (s *roleService) SetUserRoles(actorID int, userID int, payload models.SetUserRolesPayload) (*models.UserRoles, error) {
+	if err := s.repo.SetUserAdditionalRoles(userID, payload.AdditionalRoles); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "SET_USER_ROLES_FAILED", Ptr("user"), &userID, "FAILED", &details)
+		return nil, err
+	}
+
+	details := "Additional roles: " + strings.Join(payload.AdditionalRoles, ", ")
+	s.logService.Log(&actorID, "SET_USER_ROLES_SUCCESS", Ptr("user"), &userID, "SUCCESS", &details)
+	return s.repo.GetUserRoles(userID)
+}
+
+// GetUserPermissions returns the set of permissions granted to the user by
+// all of their roles.
+func (s *roleService) GetUserPermissions(userID int) (map[string]bool, error) {
+	permissions, err := s.repo.GetUserPermissions(userID)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(permissions))
+	for _, p := range permissions {
+		set[p] = true
+	}
+	return set, nil
+}
+
+// checkPermissions ensures every code exists in the permission catalogue.
+func (s *roleService) checkPermissions(codes []string) error {
+	catalogue, err := s.repo.GetAllPermissions()
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(catalogue))
+	for _, p := range catalogue {
+		known[p.Code] = true
+	}
+	for _, code := range codes {
+		if !known[code] {
+			return ErrUnknownPermission
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRoleRepository is a mock type for the RoleRepository
+type MockRoleRepository struct {
+	mock.Mock
+}
+
+func (m *MockRoleRepository) GetAllPermissions() ([]models.Permission, error) {
+	args := m.Called()
+	return args.Get(0).([]models.Permission), args.Error(1)
+}
+func (m *MockRoleRepository) GetAllRoles() ([]models.Role, error) {
+	args := m.Called()
+	return args.Get(0).([]models.Role), args.Error(1)
+}
+func (m *MockRoleRepository) GetRoleByID(id int) (*models.Role, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Role), args.Error(1)
+}
+func (m *MockRoleRepository) CreateRole(role *models.Role) error {
+	args := m.Called(role)
+	return args.Error(0)
+}
+func (m *MockRoleRepository) UpdateRole(role *models.Role) error {
+	args := m.Called(role)
+	return args.Error(0)
+}
+func (m *MockRoleRepository) DeleteRole(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+func (m *MockRoleRepository) GetUserRoles(userID int) (*models.UserRoles, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserRoles), args.Error(1)
+}
+func (m *MockRoleRepository) SetUserAdditionalRoles(userID int, roleNames []string) error {
+	args := m.Called(userID, roleNames)
+	return args.Error(0)
+}
+func (m *MockRoleRepository) GetUserPermissions(userID int) ([]string, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func TestRoleService(t *testing.T) {
+	catalogue := []models.Permission{
+		{Code: models.PermVendorRead}, {Code: models.PermVendorWrite}, {Code: models.PermRoleManage},
+	}
+
+	t.Run("CreateRole", func(t *testing.T) {
+		mockRepo := new(MockRoleRepository)
+		mockLogService := new(MockActivityLogService)
+		s := NewRoleService(mockRepo, mockLogService)
+
+		mockRepo.On("GetAllPermissions").Return(catalogue, nil).Once()
+		mockRepo.On("CreateRole", mock.MatchedBy(func(r *models.Role) bool {
+			return r.Name == "Buyer" && len(r.Permissions) == 2
+		})).Return(nil).Once()
+		mockLogService.On("Log", mock.Anything, "CREATE_ROLE_SUCCESS", mock.Anything, mock.Anything, "SUCCESS", mock.Anything).Return()
+
+		role, err := s.CreateRole(1, models.CreateRolePayload{Name: " Buyer ", Permissions: []string{models.PermVendorRead, models.PermVendorWrite}})
+		assert.NoError(t, err)
+		assert.Equal(t, "Buyer", role.Name)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("CreateRole - Unknown Permission", func(t *testing.T) {
+		mockRepo := new(MockRoleRepository)
+		s := NewRoleService(mockRepo, new(MockActivityLogService))
+
+		mockRepo.On("GetAllPermissions").Return(catalogue, nil).Once()
+
+		_, err := s.CreateRole(1, models.CreateRolePayload{Name: "Buyer", Permissions: []string{"vendor:fly"}})
+		assert.Equal(t, ErrUnknownPermission, err)
+		mockRepo.AssertNotCalled(t, "CreateRole", mock.Anything)
+	})
+
+	t.Run("UpdateRole - Admin Keeps Role Manage", func(t *testing.T) {
+		mockRepo := new(MockRoleRepository)
+		s := NewRoleService(mockRepo, new(MockActivityLogService))
+
+		mockRepo.On("GetRoleByID", 1).Return(&models.Role{ID: 1, Name: models.AdminRole, IsSystem: true}, nil).Once()
+		mockRepo.On("GetAllPermissions").Return(catalogue, nil).Once()
+
+		_, err := s.UpdateRole(1, 1, models.UpdateRolePayload{Permissions: []string{models.PermVendorRead}})
+		assert.Equal(t, ErrSystemRoleProtected, err)
+		mockRepo.AssertNotCalled(t, "UpdateRole", mock.Anything)
+	})
+
+	t.Run("DeleteRole - System Role", func(t *testing.T) {
+		mockRepo := new(MockRoleRepository)
+		s := NewRoleService(mockRepo, new(MockActivityLogService))
+
+		mockRepo.On("GetRoleByID", 2).Return(&models.Role{ID: 2, Name: "Employee", IsSystem: true}, nil).Once()
+
+		err := s.DeleteRole(1, 2)
+		assert.Equal(t, ErrSystemRoleProtected, err)
+		mockRepo.AssertNotCalled(t, "DeleteRole", mock.Anything)
+	})
+
+	t.Run("SetUserRoles - Unknown Role", func(t *testing.T) {
+		mockRepo := new(MockRoleRepository)
+		mockLogService := new(MockActivityLogService)
+		s := NewRoleService(mockRepo, mockLogService)
+
+		mockRepo.On("SetUserAdditionalRoles", 5, []string{"Ghost"}).Return(repository.ErrRoleNotFound).Once()
+		mockLogService.On("Log", mock.Anything, "SET_USER_ROLES_FAILED", mock.Anything, mock.Anything, "FAILED", mock.Anything).Return()
+
+		_, err := s.SetUserRoles(1, 5, models.SetUserRolesPayload{AdditionalRoles: []string{"Ghost"}})
+		assert.Equal(t, repository.ErrRoleNotFound, err)
+	})
+
+	t.Run("GetUserPermissions", func(t *testing.T) {
+		mockRepo := new(MockRoleRepository)
+		s := NewRoleService(mockRepo, new(MockActivityLogService))
+
+		mockRepo.On("GetUserPermissions", 3).Return([]string{models.PermVendorRead, models.PermPORead}, nil).Once()
+
+		granted, err := s.GetUserPermissions(3)
+		assert.NoError(t, err)
+		assert.True(t, granted[models.PermPORead])
+		assert.False(t, granted[models.PermUserWrite])
+	})
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"strings"
+)
+
+var (
+	ErrSoDViolation   = errors.New("segregation of duties violation")
+	ErrUnknownSoDDuty = errors.New("unknown segregation of duties duty")
+)
+
+// defaultSoDViolationLimit caps how many violations are returned for review.
+const defaultSoDViolationLimit = 200
+
+// SoDService defines the interface for the segregation of duties rule engine.
+// Other services call Check before performing a duty.
+type SoDService interface {
+	Check(check models.SoDCheck) error
+	GetAllRules() ([]models.SoDRule, error)
+	CreateRule(actorID int, payload models.SoDRulePayload) (*models.SoDRule, error)
+	UpdateRule(actorID int, id int, payload models.SoDRulePayload) (*models.SoDRule, error)
+	DeleteRule(actorID int, id int) error
+	GetViolations() ([]models.SoDViolation, error)
+}
+
+type sodService struct {
+	repo       repository.SoDRepository
+	logService ActivityLogService
+}
+
+// NewSoDService creates a new instance of SoDService.
+func NewSoDService(repo repository.SoDRepository, logService ActivityLogService) SoDService {
+	return &sodService{repo: repo, logService: logService}
+}
+
+// Check evaluates the enabled rules against a duty about to be performed. If
+// the actor, or the user they act on behalf of, already performed a
+// conflicting duty on the same transaction, the attempt is recorded and an
+// error wrapping ErrSoDViolation is returned.
+func (s *sodService) Check(check models.SoDCheck) error {
+	rules, err := s.repo.GetEnabledRules()
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		for _, duty := range check.Duties {
+			var other string
+			switch duty {
+			case rule.FirstDuty:
+				other = rule.SecondDuty
+			case rule.SecondDuty:
+				other = rule.FirstDuty
+			default:
+				continue
+			}
+
+			performer, ok := check.Performers[other]
+			if !ok {
+				continue
+			}
+			if performer != check.ActorID && (check.OnBehalfOfID == nil || performer != *check.OnBehalfOfID) {
+				continue
+			}
+
+			s.recordViolation(rule, duty, check)
+			return fmt.Errorf("%w: %s", ErrSoDViolation, rule.Description)
+		}
+	}
+	return nil
+}
+
+// recordViolation stores a blocked attempt. Failing to store it must not let
+// the attempt through, so errors are only logged.
+func (s *sodService) recordViolation(rule models.SoDRule, duty string, check models.SoDCheck) {
+	ruleID := rule.ID
+	actorID := check.ActorID
+	details := fmt.Sprintf("%s blocked on %s %d by rule %s", duty, check.EntityType, check.EntityID, rule.Code)
+	violation := &models.SoDViolation{
+		RuleID:       &ruleID,
+		RuleCode:     rule.Code,
+		UserID:       &actorID,
+		OnBehalfOfID: check.OnBehalfOfID,
+		Duty:         duty,
+		EntityType:   check.EntityType,
+		EntityID:     check.EntityID,
+		Details:      details,
+	}
+	if err := s.repo.CreateViolation(violation); err != nil {
+		log.Printf("Failed to record segregation of duties violation: %v", err)
+	}
+
+	entityID := check.EntityID
+	s.logService.Log(&actorID, "SOD_VIOLATION", Ptr(check.EntityType), &entityID, "FAILED", &details)
+}
+
+// GetAllRules returns every rule, enabled or not.
+func (s *sodService) GetAllRules() ([]models.SoDRule, error) {
+	return s.repo.GetAllRules()
+}
+
+// CreateRule adds a rule.
+func (s *sodService) CreateRule(actorID int, payload models.SoDRulePayload) (*models.SoDRule, error) {
+	rule, err := sodRuleFromPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateRule(rule); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "CREATE_SOD_RULE_FAILED", Ptr("sod_rule"), nil, "FAILED", &details)
+		return nil, err
+	}
+
+	s.logService.Log(&actorID, "CREATE_SOD_RULE_SUCCESS", Ptr("sod_rule"), &rule.ID, "SUCCESS", &rule.Code)
+	return rule, nil
+}
+
+// UpdateRule replaces a rule, including enabling or disabling it.
+func (s *sodService) UpdateRule(actorID int, id int, payload models.SoDRulePayload) (*models.SoDRule, error) {
+	existing, err := s.repo.GetRuleByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := sodRuleFromPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	rule.ID = id
+	rule.CreatedAt = existing.CreatedAt
+
+	if err := s.repo.UpdateRule(rule); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "UPDATE_SOD_RULE_FAILED", Ptr("sod_rule"), &id, "FAILED", &details)
+		return nil, err
+	}
+
+	details := fmt.Sprintf("%s (enabled: %t)", rule.Code, rule.Enabled)
+	s.logService.Log(&actorID, "UPDATE_SOD_RULE_SUCCESS", Ptr("sod_rule"), &id, "SUCCESS", &details)
+	return rule, nil
+}
+
+// DeleteRule removes a rule.
+func (s *sodService) DeleteRule(actorID int, id int) error {
+	if err := s.repo.DeleteRule(id); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "DELETE_SOD_RULE_FAILED", Ptr("sod_rule"), &id, "FAILED", &details)
+		return err
+	}
+
+	s.logService.Log(&actorID, "DELETE_SOD_RULE_SUCCESS", Ptr("sod_rule"), &id, "SUCCESS", nil)
+	return nil
+}
+
+// GetViolations returns the most recent blocked attempts.
+func (s *sodService) GetViolations() ([]models.SoDViolation, error) {
+	return s.repo.GetViolations(defaultSoDViolationLimit)
+}
+
+// sodRuleFromPayload builds a rule from a payload, checking both duties are known.
+func sodRuleFromPayload(payload models.SoDRulePayload) (*models.SoDRule, error) {
+	if !contains(models.Duties, payload.FirstDuty) || !contains(models.Duties, payload.SecondDuty) {
+		return nil, ErrUnknownSoDDuty
+	}
+
+	rule := &models.SoDRule{
+		Code:        strings.TrimSpace(payload.Code),
+		Description: strings.TrimSpace(payload.Description),
+		FirstDuty:   payload.FirstDuty,
+		SecondDuty:  payload.SecondDuty,
+		Enabled:     true,
+	}
+	if payload.Enabled != nil {
+		rule.Enabled = *payload.Enabled
+	}
+	return rule, nil
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"procurement-system/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSoDRepository is a mock type for the SoDRepository
+type MockSoDRepository struct {
+	mock.Mock
+}
+
+func (m *MockSoDRepository) GetAllRules() ([]models.SoDRule, error) {
+	args := m.Called()
+	return args.Get(0).([]models.SoDRule), args.Error(1)
+}
+func (m *MockSoDRepository) GetEnabledRules() ([]models.SoDRule, error) {
+	args := m.Called()
+	return args.Get(0).([]models.SoDRule), args.Error(1)
+}
+func (m *MockSoDRepository) GetRuleByID(id int) (*models.SoDRule, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SoDRule), args.Error(1)
+}
+func (m *MockSoDRepository) CreateRule(rule *models.SoDRule) error {
+	args := m.Called(rule)
+	return args.Error(0)
+}
+func (m *MockSoDRepository) UpdateRule(rule *models.SoDRule) error {
+	args := m.Called(rule)
+	return args.Error(0)
+}
+func (m *MockSoDRepository) DeleteRule(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+func (m *MockSoDRepository) CreateViolation(v *models.SoDViolation) error {
+	args := m.Called(v)
+	return args.Error(0)
+}
+func (m *MockSoDRepository) GetViolations(limit int) ([]models.SoDViolation, error) {
+	args := m.Called(limit)
+	return args.Get(0).([]models.SoDViolation), args.Error(1)
+}
+
+func TestSoDService(t *testing.T) {
+	rules := []models.SoDRule{
+		{ID: 1, Code: "requester_not_approver", Description: "The requester of a requisition cannot approve it", FirstDuty: models.DutyRequisitionCreate, SecondDuty: models.DutyRequisitionApprove},
+		{ID: 2, Code: "vendor_creator_not_po_approver", Description: "The creator of a vendor cannot approve purchase orders to that vendor", FirstDuty: models.DutyVendorCreate, SecondDuty: models.DutyPOApprove},
+	}
+
+	newService := func() (SoDService, *MockSoDRepository) {
+		mockRepo := new(MockSoDRepository)
+		mockLog := new(MockActivityLogService)
+		mockLog.On("Log", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+		mockRepo.On("GetEnabledRules").Return(rules, nil)
+		return NewSoDService(mockRepo, mockLog), mockRepo
+	}
+
+	approval := func(actorID int, performers map[string]int) models.SoDCheck {
+		return models.SoDCheck{
+			Duties:     []string{models.DutyRequisitionApprove, models.DutyPOApprove},
+			ActorID:    actorID,
+			EntityType: "requisition",
+			EntityID:   10,
+			Performers: performers,
+		}
+	}
+
+	t.Run("Check - No Conflict", func(t *testing.T) {
+		s, mockRepo := newService()
+
+		err := s.Check(approval(5, map[string]int{models.DutyRequisitionCreate: 2, models.DutyVendorCreate: 3}))
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "CreateViolation", mock.Anything)
+	})
+
+	t.Run("Check - Vendor Creator Approving PO", func(t *testing.T) {
+		s, mockRepo := newService()
+		mockRepo.On("CreateViolation", mock.MatchedBy(func(v *models.SoDViolation) bool {
+			return v.RuleCode == "vendor_creator_not_po_approver" && v.Duty == models.DutyPOApprove
+		})).Return(nil).Once()
+
+		err := s.Check(approval(3, map[string]int{models.DutyRequisitionCreate: 2, models.DutyVendorCreate: 3}))
+		assert.ErrorIs(t, err, ErrSoDViolation)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Check - Acting On Behalf Of The Requester", func(t *testing.T) {
+		s, mockRepo := newService()
+		mockRepo.On("CreateViolation", mock.AnythingOfType("*models.SoDViolation")).Return(nil).Once()
+		check := approval(6, map[string]int{models.DutyRequisitionCreate: 2})
+		delegatorID := 2
+		check.OnBehalfOfID = &delegatorID
+
+		err := s.Check(check)
+		assert.ErrorIs(t, err, ErrSoDViolation)
+	})
+
+	t.Run("CreateRule - Unknown Duty", func(t *testing.T) {
+		s, mockRepo := newService()
+
+		_, err := s.CreateRule(1, models.SoDRulePayload{Code: "x", Description: "x", FirstDuty: "requisition.create", SecondDuty: "payment.release"})
+		assert.Equal(t, ErrUnknownSoDDuty, err)
+		mockRepo.AssertNotCalled(t, "CreateRule", mock.Anything)
+	})
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"time"
+)
+
+var (
+	ErrInvalidPeriod = errors.New("the end of the period must be after its start")
+)
+
+// defaultCycleTimePeriod is how far back cycle time summaries look when no
+// period is given.
+const defaultCycleTimePeriod = 30 * 24 * time.Hour
+
+// StatusHistoryService defines the interface for status histories and the
+// cycle times computed from them.
+type StatusHistoryService interface {
+	GetStatusHistory(userID int, entityType string, entityID int) (*models.StatusHistory, error)
+	GetCycleTimeSummary(from, to time.Time) (*models.CycleTimeSummary, error)
+}
+
+type statusHistoryService struct {
+	repo   repository.StatusHistoryRepository
+	access EntityAccessService
+	now    func() time.Time
+}
+
+// NewStatusHistoryService creates a new instance of StatusHistoryService.
+func NewStatusHistoryService(repo repository.StatusHistoryRepository, access EntityAccessService) StatusHistoryService {
+	return &statusHistoryService{repo: repo, access: access, now: time.Now}
+}
+
+// GetStatusHistory returns an entity's status transitions and cycle times to
+// a user who may see the entity. External users do not see who made each
+// change or why.
+func (s *statusHistoryService) GetStatusHistory(userID int, entityType string, entityID int) (*models.StatusHistory, error) {
+	access, err := s.access.Access(userID, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	transitions, err := s.repo.GetTransitions(entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	var poIssuedAt *time.Time
+	if entityType == models.EntityRequisition {
+		if poIssuedAt, err = s.repo.GetPOIssuedAt(entityID); err != nil {
+			return nil, err
+		}
+	}
+
+	history := &models.StatusHistory{
+		Transitions: transitions,
+		CycleTimes:  computeCycleTimes(transitions, poIssuedAt, s.now()),
+	}
+	if access.External {
+		for i := range history.Transitions {
+			history.Transitions[i].ActorID = nil
+			history.Transitions[i].ActorName = ""
+			history.Transitions[i].Reason = ""
+		}
+	}
+	return history, nil
+}
+
+// GetCycleTimeSummary summarises the cycle times of requisitions approved in
+// [from, to). A zero to means now and a zero from means 30 days before to.
+func (s *statusHistoryService) GetCycleTimeSummary(from, to time.Time) (*models.CycleTimeSummary, error) {
+	if to.IsZero() {
+		to = s.now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultCycleTimePeriod)
+	}
+	if !to.After(from) {
+		return nil, ErrInvalidPeriod
+	}
+	return s.repo.GetCycleTimeSummary(from, to)
+}
+
+// computeCycleTimes derives cycle times from transitions sorted oldest first.
+// The current status counts up to now. Submit to approve runs from the
+// submission that was approved; first submit to approve includes any rounds
+// that were returned or withdrawn before it.
+func computeCycleTimes(transitions []models.StatusTransition, poIssuedAt *time.Time, now time.Time) models.CycleTimes {
+	times := models.CycleTimes{TimeInStatus: make(map[string]float64)}
+
+	var firstSubmit, lastSubmit, approved *time.Time
+	for i, t := range transitions {
+		end := now
+		if i+1 < len(transitions) {
+			end = transitions[i+1].CreatedAt
+		}
+		times.TimeInStatus[t.ToStatus] += end.Sub(t.CreatedAt).Seconds()
+
+		at := t.CreatedAt
+		switch {
+		case approved != nil:
+		case t.ToStatus == models.RequisitionStatusPending:
+			if firstSubmit == nil {
+				firstSubmit = &at
+			}
+			lastSubmit = &at
+		case t.ToStatus == models.RequisitionStatusApproved:
+			approved = &at
+		}
+	}
+
+	if approved != nil {
+		if lastSubmit != nil {
+			submitToApprove := approved.Sub(*lastSubmit).Seconds()
+			firstSubmitToApprove := approved.Sub(*firstSubmit).Seconds()
+			times.SubmitToApprove = &submitToApprove
+			times.FirstSubmitToApprove = &firstSubmitToApprove
+		}
+		if poIssuedAt != nil {
+			approveToPOIssued := poIssuedAt.Sub(*approved).Seconds()
+			times.ApproveToPOIssued = &approveToPOIssued
+		}
+	}
+	return times
+}
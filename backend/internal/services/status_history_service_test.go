@@ -0,0 +1,150 @@
+package services
+
+import (
+	"procurement-system/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockStatusHistoryRepository is a mock type for the StatusHistoryRepository
+type MockStatusHistoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockStatusHistoryRepository) GetTransitions(entityType string, entityID int) ([]models.StatusTransition, error) {
+	args := m.Called(entityType, entityID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.StatusTransition), args.Error(1)
+}
+func (m *MockStatusHistoryRepository) GetPOIssuedAt(requisitionID int) (*time.Time, error) {
+	args := m.Called(requisitionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*time.Time), args.Error(1)
+}
+func (m *MockStatusHistoryRepository) GetCycleTimeSummary(from, to time.Time) (*models.CycleTimeSummary, error) {
+	args := m.Called(from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CycleTimeSummary), args.Error(1)
+}
+
+func TestStatusHistoryService(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	strPtr := func(s string) *string { return &s }
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return start.Add(time.Duration(hours) * time.Hour) }
+	now := at(100)
+
+	newService := func() (StatusHistoryService, *MockStatusHistoryRepository, *MockEntityAccessService) {
+		repo := new(MockStatusHistoryRepository)
+		access := new(MockEntityAccessService)
+		s := NewStatusHistoryService(repo, access)
+		s.(*statusHistoryService).now = func() time.Time { return now }
+		return s, repo, access
+	}
+
+	// Drafted, submitted, returned, resubmitted and approved.
+	transitions := []models.StatusTransition{
+		{ToStatus: models.RequisitionStatusDraft, ActorID: intPtr(1), CreatedAt: at(0)},
+		{FromStatus: strPtr(models.RequisitionStatusDraft), ToStatus: models.RequisitionStatusPending, ActorID: intPtr(1), CreatedAt: at(2)},
+		{FromStatus: strPtr(models.RequisitionStatusPending), ToStatus: models.RequisitionStatusReturned, ActorID: intPtr(2), Reason: "Missing quote", CreatedAt: at(10)},
+		{FromStatus: strPtr(models.RequisitionStatusReturned), ToStatus: models.RequisitionStatusPending, ActorID: intPtr(1), CreatedAt: at(12)},
+		{FromStatus: strPtr(models.RequisitionStatusPending), ToStatus: models.RequisitionStatusApproved, ActorID: intPtr(2), CreatedAt: at(20)},
+	}
+
+	t.Run("GetStatusHistory - Computes Cycle Times", func(t *testing.T) {
+		s, repo, access := newService()
+		issued := at(21)
+		access.On("Access", 1, models.EntityRequisition, 7).Return(&EntityAccess{}, nil).Once()
+		repo.On("GetTransitions", models.EntityRequisition, 7).Return(append([]models.StatusTransition(nil), transitions...), nil).Once()
+		repo.On("GetPOIssuedAt", 7).Return(&issued, nil).Once()
+
+		history, err := s.GetStatusHistory(1, models.EntityRequisition, 7)
+
+		assert.NoError(t, err)
+		assert.Len(t, history.Transitions, 5)
+		times := history.CycleTimes
+		assert.Equal(t, map[string]float64{
+			models.RequisitionStatusDraft:    (2 * time.Hour).Seconds(),
+			models.RequisitionStatusPending:  (16 * time.Hour).Seconds(),
+			models.RequisitionStatusReturned: (2 * time.Hour).Seconds(),
+			models.RequisitionStatusApproved: (80 * time.Hour).Seconds(),
+		}, times.TimeInStatus)
+		assert.Equal(t, (8 * time.Hour).Seconds(), *times.SubmitToApprove)
+		assert.Equal(t, (18 * time.Hour).Seconds(), *times.FirstSubmitToApprove)
+		assert.Equal(t, time.Hour.Seconds(), *times.ApproveToPOIssued)
+		assert.Equal(t, "Missing quote", history.Transitions[2].Reason)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("GetStatusHistory - Omits Metrics That Do Not Apply Yet", func(t *testing.T) {
+		s, repo, access := newService()
+		access.On("Access", 1, models.EntityRequisition, 7).Return(&EntityAccess{}, nil).Once()
+		repo.On("GetTransitions", models.EntityRequisition, 7).Return(transitions[:2], nil).Once()
+		repo.On("GetPOIssuedAt", 7).Return(nil, nil).Once()
+
+		history, err := s.GetStatusHistory(1, models.EntityRequisition, 7)
+
+		assert.NoError(t, err)
+		assert.Equal(t, (98 * time.Hour).Seconds(), history.CycleTimes.TimeInStatus[models.RequisitionStatusPending])
+		assert.Nil(t, history.CycleTimes.SubmitToApprove)
+		assert.Nil(t, history.CycleTimes.FirstSubmitToApprove)
+		assert.Nil(t, history.CycleTimes.ApproveToPOIssued)
+	})
+
+	t.Run("GetStatusHistory - Hides Actors And Reasons From External Users", func(t *testing.T) {
+		s, repo, access := newService()
+		poTransitions := []models.StatusTransition{
+			{EntityType: models.EntityPurchaseOrder, ToStatus: models.PurchaseOrderStatusIssued, ActorID: intPtr(2), ActorName: "Admin", Reason: "Internal note", CreatedAt: at(0)},
+		}
+		access.On("Access", 5, models.EntityPurchaseOrder, 3).Return(&EntityAccess{External: true}, nil).Once()
+		repo.On("GetTransitions", models.EntityPurchaseOrder, 3).Return(poTransitions, nil).Once()
+
+		history, err := s.GetStatusHistory(5, models.EntityPurchaseOrder, 3)
+
+		assert.NoError(t, err)
+		assert.Nil(t, history.Transitions[0].ActorID)
+		assert.Empty(t, history.Transitions[0].ActorName)
+		assert.Empty(t, history.Transitions[0].Reason)
+		repo.AssertNotCalled(t, "GetPOIssuedAt", mock.Anything)
+	})
+
+	t.Run("GetStatusHistory - Forbidden", func(t *testing.T) {
+		s, repo, access := newService()
+		access.On("Access", 9, models.EntityRequisition, 7).Return(nil, ErrForbidden).Once()
+
+		_, err := s.GetStatusHistory(9, models.EntityRequisition, 7)
+
+		assert.ErrorIs(t, err, ErrForbidden)
+		repo.AssertNotCalled(t, "GetTransitions", mock.Anything, mock.Anything)
+	})
+
+	t.Run("GetCycleTimeSummary - Defaults To The Last 30 Days", func(t *testing.T) {
+		s, repo, _ := newService()
+		summary := &models.CycleTimeSummary{}
+		repo.On("GetCycleTimeSummary", now.Add(-30*24*time.Hour), now).Return(summary, nil).Once()
+
+		result, err := s.GetCycleTimeSummary(time.Time{}, time.Time{})
+
+		assert.NoError(t, err)
+		assert.Same(t, summary, result)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("GetCycleTimeSummary - Rejects An Empty Period", func(t *testing.T) {
+		s, repo, _ := newService()
+
+		_, err := s.GetCycleTimeSummary(at(5), at(5))
+
+		assert.ErrorIs(t, err, ErrInvalidPeriod)
+		repo.AssertNotCalled(t, "GetCycleTimeSummary", mock.Anything, mock.Anything)
+	})
+}
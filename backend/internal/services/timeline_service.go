@@ -0,0 +1,120 @@
+package services
+
+import (
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"sort"
+	"sync"
+)
+
+// TimelineSource contributes entries to an entity's timeline. access says how
+// the requesting user may see the entity, so sources can hide internal records
+// from external users.
+type TimelineSource interface {
+	Entries(entityType string, entityID int, access *EntityAccess) ([]models.TimelineEntry, error)
+}
+
+// TimelineSourceFunc adapts a function to the TimelineSource interface.
+type TimelineSourceFunc func(entityType string, entityID int, access *EntityAccess) ([]models.TimelineEntry, error)
+
+// Entries calls f(entityType, entityID, access).
+func (f TimelineSourceFunc) Entries(entityType string, entityID int, access *EntityAccess) ([]models.TimelineEntry, error) {
+	return f(entityType, entityID, access)
+}
+
+// TimelineService defines the interface for entity timelines, which merge
+// everything recorded against an entity into one chronological list.
+type TimelineService interface {
+	RegisterSource(name string, source TimelineSource)
+	GetTimeline(userID int, entityType string, entityID int) ([]models.TimelineEntry, error)
+}
+
+type timelineService struct {
+	access  EntityAccessService
+	mu      sync.RWMutex
+	sources map[string]TimelineSource
+}
+
+// NewTimelineService creates a new instance of TimelineService with no sources registered.
+func NewTimelineService(access EntityAccessService) TimelineService {
+	return &timelineService{access: access, sources: make(map[string]TimelineSource)}
+}
+
+// RegisterSource adds a source of timeline entries, replacing any registered under the same name.
+func (s *timelineService) RegisterSource(name string, source TimelineSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sources[name] = source
+}
+
+// GetTimeline returns the entries of every source, oldest first.
+func (s *timelineService) GetTimeline(userID int, entityType string, entityID int) ([]models.TimelineEntry, error) {
+	access, err := s.access.Access(userID, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Visit sources in name order so entries at the same instant keep a stable order.
+	names := make([]string, 0, len(s.sources))
+	for name := range s.sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	timeline := []models.TimelineEntry{}
+	for _, name := range names {
+		entries, err := s.sources[name].Entries(entityType, entityID, access)
+		if err != nil {
+			return nil, err
+		}
+		timeline = append(timeline, entries...)
+	}
+	sort.SliceStable(timeline, func(i, j int) bool { return timeline[i].At.Before(timeline[j].At) })
+	return timeline, nil
+}
+
+// RegisterDefaultTimelineSources registers the activity log, comments and
+// attachments as timeline sources.
+func RegisterDefaultTimelineSources(s TimelineService, logService ActivityLogService, commentRepo repository.CommentRepository, attachmentRepo repository.AttachmentRepository) {
+	s.RegisterSource(models.TimelineActivity, TimelineSourceFunc(func(entityType string, entityID int, access *EntityAccess) ([]models.TimelineEntry, error) {
+		// The activity log is an internal audit trail.
+		if access.External {
+			return nil, nil
+		}
+		activities, err := logService.GetByTarget(entityType, entityID)
+		if err != nil {
+			return nil, err
+		}
+		entries := make([]models.TimelineEntry, len(activities))
+		for i := range activities {
+			entries[i] = models.TimelineEntry{Kind: models.TimelineActivity, At: activities[i].CreatedAt, Activity: &activities[i]}
+		}
+		return entries, nil
+	}))
+
+	s.RegisterSource(models.TimelineComment, TimelineSourceFunc(func(entityType string, entityID int, access *EntityAccess) ([]models.TimelineEntry, error) {
+		comments, err := commentRepo.GetComments(entityType, entityID, access.External)
+		if err != nil {
+			return nil, err
+		}
+		entries := make([]models.TimelineEntry, len(comments))
+		for i := range comments {
+			entries[i] = models.TimelineEntry{Kind: models.TimelineComment, At: comments[i].CreatedAt, Comment: &comments[i]}
+		}
+		return entries, nil
+	}))
+	s.RegisterSource(models.TimelineAttachment, TimelineSourceFunc(func(entityType string, entityID int, access *EntityAccess) ([]models.TimelineEntry, error) {
+		attachments, err := attachmentRepo.GetAttachments(entityType, entityID, access.External)
+		if err != nil {
+			return nil, err
+		}
+		entries := make([]models.TimelineEntry, len(attachments))
+		for i := range attachments {
+			entries[i] = models.TimelineEntry{Kind: models.TimelineAttachment, At: attachments[i].CreatedAt, Attachment: &attachments[i]}
+		}
+		return entries, nil
+	}))
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"procurement-system/pkg/totp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTwoFactorNotEnrolled     = errors.New("two-factor authentication is not enabled for this user")
+	ErrTwoFactorAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrInvalidTwoFactorCode     = errors.New("invalid two-factor code")
+	ErrTwoFactorRequiredForRole = errors.New("two-factor authentication is required for this role")
+)
+
+const recoveryCodeCount = 10
+
+// TwoFactorService defines the interface for TOTP enrolment and verification.
+type TwoFactorService interface {
+	IsRequiredForUser(userID int) (bool, error)
+	IsEnabled(userID int) (bool, error)
+	BeginEnrolment(userID int) (*models.TwoFactorEnrolment, error)
+	ConfirmEnrolment(userID int, code string) ([]string, error)
+	VerifyCode(userID int, code string) error
+	RegenerateRecoveryCodes(userID int, code string) ([]string, error)
+	Disable(userID int, code string) error
+	Reset(actorID int, targetUserID int) error
+}
+
+type twoFactorService struct {
+	repo          repository.TwoFactorRepository
+	userRepo      repository.UserRepository
+	roleRepo      repository.RoleRepository
+	logService    ActivityLogService
+	issuer        string
+	requiredRoles map[string]bool
+	now           func() time.Time
+}
+
+// NewTwoFactorService creates a new instance of TwoFactorService.
+// issuer is shown in authenticator apps; requiredRoles lists the roles that
+// cannot log in without a second factor, whether held as the primary or an
+// additional role.
+func NewTwoFactorService(repo repository.TwoFactorRepository, userRepo repository.UserRepository, roleRepo repository.RoleRepository, logService ActivityLogService, issuer string, requiredRoles []string) TwoFactorService {
+	roles := make(map[string]bool, len(requiredRoles))
+	for _, role := range requiredRoles {
+		roles[role] = true
+	}
+	return &twoFactorService{
+		repo:          repo,
+		userRepo:      userRepo,
+		roleRepo:      roleRepo,
+		logService:    logService,
+		issuer:        issuer,
+		requiredRoles: roles,
+		now:           time.Now,
+	}
+}
+
+// IsRequiredForUser reports whether any of the user's roles requires 2FA.
+func (s *twoFactorService) IsRequiredForUser(userID int) (bool, error) {
+	if len(s.requiredRoles) == 0 {
+		return false, nil
+	}
+	roles, err := s.roleRepo.GetUserRoles(userID)
+	if err != nil {
+		return false, err
+	}
+	if s.requiredRoles[roles.Role] {
+		return true, nil
+	}
+	for _, role := range roles.AdditionalRoles {
+		if s.requiredRoles[role] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// IsEnabled reports whether the user has a confirmed 2FA enrolment.
+func (s *twoFactorService) IsEnabled(userID int) (bool, error) {
+	tf, err := s.repo.GetByUserID(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTwoFactorNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return tf.Enabled, nil
+}
+
+// BeginEnrolment generates a new secret for the user. The enrolment stays
+// inactive until ConfirmEnrolment is called with a valid code.
+func (s *twoFactorService) BeginEnrolment(userID int) (*models.TwoFactorEnrolment, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.IsEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveSecret(userID, secret); err != nil {
+		details := err.Error()
+		s.logService.Log(&userID, "TWO_FACTOR_ENROL_FAILED", Ptr("user"), &userID, "FAILED", &details)
+		return nil, err
+	}
+
+	s.logService.Log(&userID, "TWO_FACTOR_ENROL_STARTED", Ptr("user"), &userID, "SUCCESS", nil)
+	return &models.TwoFactorEnrolment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(secret, s.issuer, user.Email),
+	}, nil
+}
+
+// ConfirmEnrolment activates 2FA once the user proves their authenticator
+// works, and returns a fresh set of recovery codes.
+func (s *twoFactorService) ConfirmEnrolment(userID int, code string) ([]string, error) {
+	tf, err := s.repo.GetByUserID(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTwoFactorNotFound) {
+			return nil, ErrTwoFactorNotEnrolled
+		}
+		return nil, err
+	}
+	if tf.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(tf.Secret, code, s.now(), 1)
+	if !ok {
+		details := "Invalid code during enrolment confirmation"
+		s.logService.Log(&userID, "TWO_FACTOR_ENABLE_FAILED", Ptr("user"), &userID, "FAILED", &details)
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	if err := s.repo.Enable(userID, step); err != nil {
+		details := err.Error()
+		s.logService.Log(&userID, "TWO_FACTOR_ENABLE_FAILED", Ptr("user"), &userID, "FAILED", &details)
+		return nil, err
+	}
+
+	codes, err := s.issueRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logService.Log(&userID, "TWO_FACTOR_ENABLE_SUCCESS", Ptr("user"), &userID, "SUCCESS", nil)
+	return codes, nil
+}
+
+// VerifyCode accepts either a current TOTP code or an unused recovery code.
+// Each TOTP step and each recovery code can only be used once.
+func (s *twoFactorService) VerifyCode(userID int, code string) error {
+	tf, err := s.repo.GetByUserID(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTwoFactorNotFound) {
+			return ErrTwoFactorNotEnrolled
+		}
+		return err
+	}
+	if !tf.Enabled {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	if step, ok := totp.Validate(tf.Secret, code, s.now(), 1); ok {
+		if err := s.repo.UseStep(userID, step); err != nil {
+			if errors.Is(err, repository.ErrTOTPStepReplayed) {
+				return ErrInvalidTwoFactorCode
+			}
+			return err
+		}
+		return nil
+	}
+
+	codes, err := s.repo.GetUnusedRecoveryCodes(userID)
+	if err != nil {
+		return err
+	}
+	hash := hashRecoveryCode(code)
+	for _, c := range codes {
+		if subtle.ConstantTimeCompare([]byte(c.CodeHash), []byte(hash)) == 1 {
+			if err := s.repo.UseRecoveryCode(c.ID); err != nil {
+				if errors.Is(err, repository.ErrRecoveryCodeConsumed) {
+					return ErrInvalidTwoFactorCode
+				}
+				return err
+			}
+			details := "Recovery code used"
+			s.logService.Log(&userID, "TWO_FACTOR_RECOVERY_CODE_USED", Ptr("user"), &userID, "SUCCESS", &details)
+			return nil
+		}
+	}
+
+	return ErrInvalidTwoFactorCode
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after verifying a code.
+func (s *twoFactorService) RegenerateRecoveryCodes(userID int, code string) ([]string, error) {
+	if err := s.VerifyCode(userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.issueRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logService.Log(&userID, "TWO_FACTOR_RECOVERY_CODES_REGENERATED", Ptr("user"), &userID, "SUCCESS", nil)
+	return codes, nil
+}
+
+// Disable turns off 2FA for the user, unless their role requires it.
+func (s *twoFactorService) Disable(userID int, code string) error {
+	required, err := s.IsRequiredForUser(userID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorRequiredForRole
+	}
+
+	if err := s.VerifyCode(userID, code); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(userID); err != nil {
+		details := err.Error()
+		s.logService.Log(&userID, "TWO_FACTOR_DISABLE_FAILED", Ptr("user"), &userID, "FAILED", &details)
+		return err
+	}
+
+	s.logService.Log(&userID, "TWO_FACTOR_DISABLE_SUCCESS", Ptr("user"), &userID, "SUCCESS", nil)
+	return nil
+}
+
+// Reset removes another user's enrolment, e.g. after they lose their device.
+// If their role requires 2FA they will be asked to enrol again at next login.
+func (s *twoFactorService) Reset(actorID int, targetUserID int) error {
+	if err := s.repo.Delete(targetUserID); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "ADMIN_RESET_TWO_FACTOR_FAILED", Ptr("user"), &targetUserID, "FAILED", &details)
+		return err
+	}
+
+	s.logService.Log(&actorID, "ADMIN_RESET_TWO_FACTOR_SUCCESS", Ptr("user"), &targetUserID, "SUCCESS", nil)
+	return nil
+}
+
+// issueRecoveryCodes generates and stores a new set of recovery codes,
+// returning the plain codes. Only their hashes are persisted.
+func (s *twoFactorService) issueRecoveryCodes(userID int) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = hashRecoveryCode(code)
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a random code formatted as XXXXX-XXXXX.
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	s := base32.StdEncoding.EncodeToString(b)[:10]
+	return s[:5] + "-" + s[5:], nil
+}
+
+// hashRecoveryCode normalises a code (case, dashes and spaces) and returns its SHA-256 hex digest.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
+	"procurement-system/pkg/totp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTwoFactorRepository is a mock type for the TwoFactorRepository
+type MockTwoFactorRepository struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorRepository) GetByUserID(userID int) (*models.UserTwoFactor, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserTwoFactor), args.Error(1)
+}
+func (m *MockTwoFactorRepository) SaveSecret(userID int, secret string) error {
+	args := m.Called(userID, secret)
+	return args.Error(0)
+}
+func (m *MockTwoFactorRepository) Enable(userID int, step int64) error {
+	args := m.Called(userID, step)
+	return args.Error(0)
+}
+func (m *MockTwoFactorRepository) UseStep(userID int, step int64) error {
+	args := m.Called(userID, step)
+	return args.Error(0)
+}
+func (m *MockTwoFactorRepository) Delete(userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+func (m *MockTwoFactorRepository) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	args := m.Called(userID, codeHashes)
+	return args.Error(0)
+}
+func (m *MockTwoFactorRepository) GetUnusedRecoveryCodes(userID int) ([]models.RecoveryCode, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.RecoveryCode), args.Error(1)
+}
+func (m *MockTwoFactorRepository) UseRecoveryCode(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func TestTwoFactorService(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	secret := "JBSWY3DPEHPK3PXP"
+	code, _ := totp.Code(secret, now)
+
+	newService := func(repo *MockTwoFactorRepository, roleRepo *MockRoleRepository, logService *MockActivityLogService) *twoFactorService {
+		s := NewTwoFactorService(repo, nil, roleRepo, logService, "Procurement System", []string{"Admin", "Approver"}).(*twoFactorService)
+		s.now = func() time.Time { return now }
+		return s
+	}
+
+	t.Run("ConfirmEnrolment", func(t *testing.T) {
+		mockRepo := new(MockTwoFactorRepository)
+		mockLogService := new(MockActivityLogService)
+		s := newService(mockRepo, nil, mockLogService)
+
+		mockRepo.On("GetByUserID", 1).Return(&models.UserTwoFactor{UserID: 1, Secret: secret}, nil).Once()
+		mockRepo.On("Enable", 1, totp.Step(now)).Return(nil).Once()
+		mockRepo.On("ReplaceRecoveryCodes", 1, mock.AnythingOfType("[]string")).Return(nil).Once()
+		mockLogService.On("Log", mock.Anything, "TWO_FACTOR_ENABLE_SUCCESS", mock.Anything, mock.Anything, "SUCCESS", mock.Anything).Return()
+
+		codes, err := s.ConfirmEnrolment(1, code)
+		assert.NoError(t, err)
+		assert.Len(t, codes, recoveryCodeCount)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("VerifyCode - Replayed TOTP", func(t *testing.T) {
+		mockRepo := new(MockTwoFactorRepository)
+		s := newService(mockRepo, nil, new(MockActivityLogService))
+
+		mockRepo.On("GetByUserID", 1).Return(&models.UserTwoFactor{UserID: 1, Secret: secret, Enabled: true}, nil).Once()
+		mockRepo.On("UseStep", 1, totp.Step(now)).Return(repository.ErrTOTPStepReplayed).Once()
+
+		err := s.VerifyCode(1, code)
+		assert.Equal(t, ErrInvalidTwoFactorCode, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("VerifyCode - Recovery Code", func(t *testing.T) {
+		mockRepo := new(MockTwoFactorRepository)
+		mockLogService := new(MockActivityLogService)
+		s := newService(mockRepo, nil, mockLogService)
+
+		mockRepo.On("GetByUserID", 1).Return(&models.UserTwoFactor{UserID: 1, Secret: secret, Enabled: true}, nil).Once()
+		mockRepo.On("GetUnusedRecoveryCodes", 1).Return([]models.RecoveryCode{
+			{ID: 4, UserID: 1, CodeHash: hashRecoveryCode("ABCDE-FGHIJ")},
+		}, nil).Once()
+		mockRepo.On("UseRecoveryCode", 4).Return(nil).Once()
+		mockLogService.On("Log", mock.Anything, "TWO_FACTOR_RECOVERY_CODE_USED", mock.Anything, mock.Anything, "SUCCESS", mock.Anything).Return()
+
+		err := s.VerifyCode(1, "abcde fghij")
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Disable - Required For Role", func(t *testing.T) {
+		mockRepo := new(MockTwoFactorRepository)
+		mockRoleRepo := new(MockRoleRepository)
+		s := newService(mockRepo, mockRoleRepo, new(MockActivityLogService))
+
+		mockRoleRepo.On("GetUserRoles", 1).Return(&models.UserRoles{UserID: 1, Role: "Approver"}, nil).Once()
+
+		err := s.Disable(1, code)
+		assert.Equal(t, ErrTwoFactorRequiredForRole, err)
+		mockRepo.AssertNotCalled(t, "Delete", mock.Anything)
+	})
+
+	t.Run("IsRequiredForUser - Additional Role", func(t *testing.T) {
+		mockRoleRepo := new(MockRoleRepository)
+		s := newService(new(MockTwoFactorRepository), mockRoleRepo, new(MockActivityLogService))
+
+		mockRoleRepo.On("GetUserRoles", 2).Return(&models.UserRoles{UserID: 2, Role: "Employee", AdditionalRoles: []string{"Admin"}}, nil).Once()
+
+		required, err := s.IsRequiredForUser(2)
+		assert.NoError(t, err)
+		assert.True(t, required)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		mockRepo := new(MockTwoFactorRepository)
+		mockLogService := new(MockActivityLogService)
+		s := newService(mockRepo, nil, mockLogService)
+
+		mockRepo.On("Delete", 5).Return(nil).Once()
+		mockLogService.On("Log", mock.Anything, "ADMIN_RESET_TWO_FACTOR_SUCCESS", mock.Anything, mock.Anything, "SUCCESS", mock.Anything).Return()
+
+		err := s.Reset(99, 5)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockLogService.AssertExpectations(t)
+	})
+}
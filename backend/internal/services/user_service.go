@@ -2,6 +2,7 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"procurement-system/internal/models"
 	"procurement-system/internal/repository"
 
@@ -10,16 +11,22 @@ import (
 
 var (
 	ErrIncorrectPassword = errors.New("incorrect old password")
+	ErrUserNotPending    = errors.New("user is not awaiting approval")
 )
 
 // UserService defines the interface for user management operations.
 type UserService interface {
+	CreateUser(actorID int, payload models.CreateUserPayload) (*models.User, error)
 	GetAllUsers() ([]models.User, error)
 	GetUserByID(id int) (*models.User, error)
-	UpdateUser(actorID int, targetUserID int, payload models.UpdateUserPayload) (*models.User, error)
-	DeleteUser(actorID int, targetUserID int) error
+	UpdateUser(actorID int, targetUserID int, payload models.UpdateUserPayload, expectedVersion *int) (*models.User, error)
+	DeleteUser(actorID int, targetUserID int, expectedVersion *int) error
+	SetUserVendor(actorID int, targetUserID int, payload models.SetUserVendorPayload) (*models.User, error)
 	UpdateMyProfile(userID int, payload models.UpdateProfilePayload) (*models.User, error)
 	ChangeMyPassword(userID int, payload models.ChangePasswordPayload) error
+	GetPendingUsers() ([]models.User, error)
+	ApproveUser(actorID int, targetUserID int) (*models.User, error)
+	RejectUser(actorID int, targetUserID int) (*models.User, error)
 }
 
 type userService struct {
@@ -32,6 +39,31 @@ func NewUserService(userRepo repository.UserRepository, logService ActivityLogSe
 	return &userService{userRepo: userRepo, logService: logService}
 }
 
+// CreateUser creates an active account with any role on behalf of an admin.
+func (s *userService) CreateUser(actorID int, payload models.CreateUserPayload) (*models.User, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(payload.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.CreateUser(&models.User{
+		Name:           payload.Name,
+		Email:          payload.Email,
+		HashedPassword: string(hashedPassword),
+		Role:           payload.Role,
+		Status:         models.UserStatusActive,
+	})
+	if err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "CREATE_USER_FAILED", Ptr("user"), nil, "FAILED", &details)
+		return nil, err
+	}
+
+	s.logService.Log(&actorID, "CREATE_USER_SUCCESS", Ptr("user"), &user.ID, "SUCCESS", nil)
+	user.HashedPassword = ""
+	return user, nil
+}
+
 // GetAllUsers retrieves all users.
 func (s *userService) GetAllUsers() ([]models.User, error) {
 	return s.userRepo.GetAllUsers()
@@ -48,13 +80,17 @@ func (s *userService) GetUserByID(id int) (*models.User, error) {
 	return user, nil
 }
 
-// UpdateUser updates a user's details based on the provided payload.
-func (s *userService) UpdateUser(actorID int, targetUserID int, payload models.UpdateUserPayload) (*models.User, error) {
+// UpdateUser updates a user's details based on the provided payload. If
+// expectedVersion is set, the user must still be at that version.
+func (s *userService) UpdateUser(actorID int, targetUserID int, payload models.UpdateUserPayload, expectedVersion *int) (*models.User, error) {
 	// Get the existing user to ensure they exist before updating.
 	user, err := s.userRepo.GetUserByID(targetUserID)
 	if err != nil {
 		return nil, err // Handles repository.ErrUserNotFound
 	}
+	if err := checkVersion(expectedVersion, user.Version); err != nil {
+		return nil, err
+	}
 
 	// Update fields from payload.
 	user.Name = payload.Name
@@ -75,9 +111,18 @@ func (s *userService) UpdateUser(actorID int, targetUserID int, payload models.U
 	return user, nil
 }
 
-// DeleteUser deletes a user by their ID.
-func (s *userService) DeleteUser(actorID int, targetUserID int) error {
-	err := s.userRepo.DeleteUser(targetUserID)
+// DeleteUser deletes a user by their ID. If expectedVersion is set, the user
+// must still be at that version.
+func (s *userService) DeleteUser(actorID int, targetUserID int, expectedVersion *int) error {
+	user, err := s.userRepo.GetUserByID(targetUserID)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(expectedVersion, user.Version); err != nil {
+		return err
+	}
+
+	err = s.userRepo.DeleteUser(targetUserID, user.Version)
 	if err != nil {
 		details := err.Error()
 		s.logService.Log(&actorID, "DELETE_USER_FAILED", Ptr("user"), &targetUserID, "FAILED", &details)
@@ -87,6 +132,29 @@ func (s *userService) DeleteUser(actorID int, targetUserID int) error {
 	return nil
 }
 
+// SetUserVendor links a vendor portal user to the vendor they work for.
+func (s *userService) SetUserVendor(actorID int, targetUserID int, payload models.SetUserVendorPayload) (*models.User, error) {
+	if err := s.userRepo.SetUserVendor(targetUserID, payload.VendorID); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, "SET_USER_VENDOR_FAILED", Ptr("user"), &targetUserID, "FAILED", &details)
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(targetUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := "Vendor link removed"
+	if payload.VendorID != nil {
+		details = fmt.Sprintf("Linked to vendor %d", *payload.VendorID)
+	}
+	s.logService.Log(&actorID, "SET_USER_VENDOR_SUCCESS", Ptr("user"), &targetUserID, "SUCCESS", &details)
+
+	user.HashedPassword = ""
+	return user, nil
+}
+
 func (s *userService) UpdateMyProfile(userID int, payload models.UpdateProfilePayload) (*models.User, error) {
 	user, err := s.userRepo.GetUserByID(userID)
 	if err != nil {
@@ -140,3 +208,41 @@ func (s *userService) ChangeMyPassword(userID int, payload models.ChangePassword
 	s.logService.Log(&userID, "CHANGE_PASSWORD_SUCCESS", Ptr("user"), &userID, "SUCCESS", nil)
 	return nil
 }
+
+// GetPendingUsers retrieves self-registered users awaiting approval.
+func (s *userService) GetPendingUsers() ([]models.User, error) {
+	return s.userRepo.GetUsersByStatus(models.UserStatusPendingApproval)
+}
+
+// ApproveUser activates a self-registered account.
+func (s *userService) ApproveUser(actorID int, targetUserID int) (*models.User, error) {
+	return s.decidePendingUser(actorID, targetUserID, models.UserStatusActive, "APPROVE_USER")
+}
+
+// RejectUser refuses a self-registered account. The account is kept so the
+// email can't simply be registered again.
+func (s *userService) RejectUser(actorID int, targetUserID int) (*models.User, error) {
+	return s.decidePendingUser(actorID, targetUserID, models.UserStatusRejected, "REJECT_USER")
+}
+
+func (s *userService) decidePendingUser(actorID int, targetUserID int, status string, action string) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(targetUserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != models.UserStatusPendingApproval {
+		return nil, ErrUserNotPending
+	}
+
+	if err := s.userRepo.UpdateUserStatus(targetUserID, status); err != nil {
+		details := err.Error()
+		s.logService.Log(&actorID, action+"_FAILED", Ptr("user"), &targetUserID, "FAILED", &details)
+		return nil, err
+	}
+
+	s.logService.Log(&actorID, action+"_SUCCESS", Ptr("user"), &targetUserID, "SUCCESS", nil)
+	user.Status = status
+	user.Version++
+	user.HashedPassword = ""
+	return user, nil
+}
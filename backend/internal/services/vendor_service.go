@@ -9,8 +9,8 @@ type VendorService interface {
 	CreateVendor(actorID int, vendor *models.Vendor) error
 	GetAllVendors() ([]models.Vendor, error)
 	GetVendorByID(id int) (*models.Vendor, error)
-	UpdateVendor(actorID int, vendor *models.Vendor) error
-	DeleteVendor(actorID int, id int) error
+	UpdateVendor(actorID int, vendor *models.Vendor, expectedVersion *int) error
+	DeleteVendor(actorID int, id int, expectedVersion *int) error
 }
 
 type vendorService struct {
@@ -23,6 +23,10 @@ func NewVendorService(repo repository.VendorRepository, logService ActivityLogSe
 }
 
 func (s *vendorService) CreateVendor(actorID int, vendor *models.Vendor) error {
+	if err := validateVendorLayout(vendor); err != nil {
+		return err
+	}
+	vendor.CreatedBy = &actorID
 	err := s.repo.CreateVendor(vendor)
 	if err != nil {
 		details := err.Error()
@@ -41,8 +45,23 @@ func (s *vendorService) GetVendorByID(id int) (*models.Vendor, error) {
 	return s.repo.GetVendorByID(id)
 }
 
-func (s *vendorService) UpdateVendor(actorID int, vendor *models.Vendor) error {
-	err := s.repo.UpdateVendor(vendor)
+// UpdateVendor replaces a vendor's details. If expectedVersion is set, the
+// vendor must still be at that version.
+func (s *vendorService) UpdateVendor(actorID int, vendor *models.Vendor, expectedVersion *int) error {
+	if err := validateVendorLayout(vendor); err != nil {
+		return err
+	}
+	existing, err := s.repo.GetVendorByID(vendor.ID)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(expectedVersion, existing.Version); err != nil {
+		return err
+	}
+	vendor.CreatedBy = existing.CreatedBy
+	vendor.Version = existing.Version
+
+	err = s.repo.UpdateVendor(vendor)
 	if err != nil {
 		details := err.Error()
 		s.logService.Log(&actorID, "UPDATE_VENDOR_FAILED", Ptr("vendor"), &vendor.ID, "FAILED", &details)
@@ -52,8 +71,18 @@ func (s *vendorService) UpdateVendor(actorID int, vendor *models.Vendor) error {
 	return nil
 }
 
-func (s *vendorService) DeleteVendor(actorID int, id int) error {
-	err := s.repo.DeleteVendor(id)
+// DeleteVendor deletes a vendor. If expectedVersion is set, the vendor must
+// still be at that version.
+func (s *vendorService) DeleteVendor(actorID int, id int, expectedVersion *int) error {
+	existing, err := s.repo.GetVendorByID(id)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(expectedVersion, existing.Version); err != nil {
+		return err
+	}
+
+	err = s.repo.DeleteVendor(id, existing.Version)
 	if err != nil {
 		details := err.Error()
 		s.logService.Log(&actorID, "DELETE_VENDOR_FAILED", Ptr("vendor"), &id, "FAILED", &details)
@@ -62,3 +91,16 @@ func (s *vendorService) DeleteVendor(actorID int, id int) error {
 	s.logService.Log(&actorID, "DELETE_VENDOR_SUCCESS", Ptr("vendor"), &id, "SUCCESS", nil)
 	return nil
 }
+
+// validateVendorLayout checks the vendor's PDF layout, if it has its own. An
+// empty layout is stored as none, leaving the company's default.
+func validateVendorLayout(vendor *models.Vendor) error {
+	if vendor.PDFLayout == nil {
+		return nil
+	}
+	if *vendor.PDFLayout == "" {
+		vendor.PDFLayout = nil
+		return nil
+	}
+	return validatePDFLayout(*vendor.PDFLayout)
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"procurement-system/internal/models"
+	"procurement-system/internal/repository"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -36,8 +37,8 @@ func (m *MockVendorRepository) UpdateVendor(vendor *models.Vendor) error {
 	return args.Error(0)
 }
 
-func (m *MockVendorRepository) DeleteVendor(id int) error {
-	args := m.Called(id)
+func (m *MockVendorRepository) DeleteVendor(id int, version int) error {
+	args := m.Called(id, version)
 	return args.Error(0)
 }
 
@@ -46,7 +47,8 @@ func TestVendorService(t *testing.T) {
 	mockLogService := new(MockActivityLogService)
 	vendorService := NewVendorService(mockRepo, mockLogService)
 
-	vendor := &models.Vendor{ID: 1, Name: "Test Vendor"}
+	vendor := &models.Vendor{ID: 1, Name: "Test Vendor", Version: 3}
+	intPtr := func(i int) *int { return &i }
 
 	t.Run("CreateVendor", func(t *testing.T) {
 		mockRepo.On("CreateVendor", vendor).Return(nil).Once()
@@ -56,6 +58,14 @@ func TestVendorService(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("CreateVendor - Unknown PDF Layout", func(t *testing.T) {
+		layoutRepo := new(MockVendorRepository)
+		layout := "fancy"
+		err := NewVendorService(layoutRepo, mockLogService).CreateVendor(99, &models.Vendor{Name: "Layout Vendor", PDFLayout: &layout})
+		assert.ErrorIs(t, err, ErrUnknownPDFLayout)
+		layoutRepo.AssertNotCalled(t, "CreateVendor", mock.Anything)
+	})
+
 	t.Run("GetAllVendors", func(t *testing.T) {
 		vendors := []models.Vendor{*vendor}
 		mockRepo.On("GetAllVendors").Return(vendors, nil).Once()
@@ -74,17 +84,30 @@ func TestVendorService(t *testing.T) {
 	})
 
 	t.Run("UpdateVendor", func(t *testing.T) {
-		mockRepo.On("UpdateVendor", vendor).Return(nil).Once()
+		update := &models.Vendor{ID: 1, Name: "Renamed Vendor"}
+		mockRepo.On("GetVendorByID", 1).Return(vendor, nil).Once()
+		mockRepo.On("UpdateVendor", mock.MatchedBy(func(v *models.Vendor) bool {
+			return v.Name == "Renamed Vendor" && v.Version == 3
+		})).Return(nil).Once()
 		mockLogService.On("Log", mock.Anything, "UPDATE_VENDOR_SUCCESS", mock.Anything, mock.Anything, "SUCCESS", mock.Anything).Return()
-		err := vendorService.UpdateVendor(99, vendor)
+		err := vendorService.UpdateVendor(99, update, intPtr(3))
 		assert.NoError(t, err)
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("UpdateVendor - Stale Version", func(t *testing.T) {
+		staleRepo := new(MockVendorRepository)
+		staleRepo.On("GetVendorByID", 1).Return(vendor, nil).Once()
+		err := NewVendorService(staleRepo, mockLogService).UpdateVendor(99, &models.Vendor{ID: 1, Name: "Renamed Vendor"}, intPtr(2))
+		assert.ErrorIs(t, err, repository.ErrVersionConflict)
+		staleRepo.AssertNotCalled(t, "UpdateVendor", mock.Anything)
+	})
+
 	t.Run("DeleteVendor", func(t *testing.T) {
-		mockRepo.On("DeleteVendor", 1).Return(nil).Once()
+		mockRepo.On("GetVendorByID", 1).Return(vendor, nil).Once()
+		mockRepo.On("DeleteVendor", 1, 3).Return(nil).Once()
 		mockLogService.On("Log", mock.Anything, "DELETE_VENDOR_SUCCESS", mock.Anything, mock.Anything, "SUCCESS", mock.Anything).Return()
-		err := vendorService.DeleteVendor(99, 1)
+		err := vendorService.DeleteVendor(99, 1, nil)
 		assert.NoError(t, err)
 		mockRepo.AssertExpectations(t)
 	})
@@ -0,0 +1,14 @@
+package services
+
+import "procurement-system/internal/repository"
+
+// checkVersion fails with repository.ErrVersionConflict when the client said
+// which version of a record it last saw (via If-Match) and the record has
+// changed since. A nil expected version skips the check; the repository still
+// refuses to overwrite a change made after the record was read.
+func checkVersion(expected *int, current int) error {
+	if expected != nil && *expected != current {
+		return repository.ErrVersionConflict
+	}
+	return nil
+}
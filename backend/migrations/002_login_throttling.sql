@@ -0,0 +1,18 @@
+-- 002_login_throttling.sql
+
+-- Login Throttles Table
+-- One row per throttled subject. scope is either 'account' (key is the
+-- normalised email, whether or not a user exists for it) or 'ip' (key is
+-- the client address).
+CREATE TABLE IF NOT EXISTS login_throttles (
+    id SERIAL PRIMARY KEY,
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('account', 'ip')),
+    key VARCHAR(255) NOT NULL,
+    failure_count INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_throttles_locked_until ON login_throttles (locked_until);
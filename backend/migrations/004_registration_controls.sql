@@ -0,0 +1,23 @@
+-- 004_registration_controls.sql
+
+-- Account status, used for the self-registration approval queue.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status VARCHAR(30) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'pending_approval', 'rejected'));
+
+-- Invitations Table
+-- An admin-issued invitation to register with a specific email and role.
+-- Only the SHA-256 hash of the token is stored.
+CREATE TABLE IF NOT EXISTS invitations (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(50) NOT NULL CHECK (role IN ('Employee', 'Admin', 'Procurement Officer', 'Approver', 'Vendor')),
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    accepted_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_invitations_email ON invitations (email);
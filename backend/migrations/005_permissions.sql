@@ -0,0 +1,93 @@
+-- 005_permissions.sql
+
+-- Roles Table
+-- Role definitions are editable by admins. System roles are the built-in ones
+-- the application relies on and cannot be deleted.
+CREATE TABLE IF NOT EXISTS roles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) UNIQUE NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    is_system BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Permissions Table
+-- The catalogue of permissions the application checks. Codes are
+-- resource:action, with an optional scope suffix (e.g. po:read:all).
+CREATE TABLE IF NOT EXISTS permissions (
+    code VARCHAR(100) PRIMARY KEY,
+    description TEXT NOT NULL
+);
+
+-- Role Permissions Table
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission VARCHAR(100) NOT NULL REFERENCES permissions(code) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission)
+);
+
+-- User Roles Table
+-- Roles held in addition to the user's primary role (users.role).
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+
+INSERT INTO roles (name, description, is_system) VALUES
+    ('Admin', 'Full access to the system', TRUE),
+    ('Employee', 'Raises and tracks their own requisitions', TRUE),
+    ('Procurement Officer', 'Manages vendors and purchase orders', TRUE),
+    ('Approver', 'Reviews and approves requisitions', TRUE),
+    ('Vendor', 'External supplier with access to their purchase orders', TRUE)
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (code, description) VALUES
+    ('user:read', 'View user accounts'),
+    ('user:write', 'Create, update, approve and delete user accounts'),
+    ('role:manage', 'Edit role definitions and user role assignments'),
+    ('invitation:manage', 'Issue and revoke registration invitations'),
+    ('security:manage', 'Clear login lockouts and reset two-factor authentication'),
+    ('vendor:read', 'View vendors'),
+    ('vendor:write', 'Create, update and delete vendors'),
+    ('requisition:create', 'Raise and manage own requisitions'),
+    ('requisition:read:all', 'View all requisitions'),
+    ('requisition:approve', 'Approve or reject requisitions'),
+    ('requisition:manage', 'Edit or delete any requisition'),
+    ('po:read', 'View individual purchase orders'),
+    ('po:read:all', 'List all purchase orders')
+ON CONFLICT (code) DO NOTHING;
+
+-- Admin holds every permission.
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.code FROM roles r CROSS JOIN permissions p WHERE r.name = 'Admin'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, m.permission
+FROM (VALUES
+    ('Employee', 'requisition:create'),
+    ('Employee', 'po:read'),
+    ('Procurement Officer', 'requisition:create'),
+    ('Procurement Officer', 'requisition:read:all'),
+    ('Procurement Officer', 'vendor:read'),
+    ('Procurement Officer', 'vendor:write'),
+    ('Procurement Officer', 'po:read'),
+    ('Procurement Officer', 'po:read:all'),
+    ('Approver', 'requisition:create'),
+    ('Approver', 'requisition:read:all'),
+    ('Approver', 'requisition:approve'),
+    ('Approver', 'po:read'),
+    ('Vendor', 'po:read')
+) AS m(role_name, permission)
+JOIN roles r ON r.name = m.role_name
+ON CONFLICT DO NOTHING;
+
+-- Primary roles now reference the roles table instead of a fixed list.
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_fkey;
+ALTER TABLE users ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles(name);
+
+ALTER TABLE invitations DROP CONSTRAINT IF EXISTS invitations_role_check;
+ALTER TABLE invitations DROP CONSTRAINT IF EXISTS invitations_role_fkey;
+ALTER TABLE invitations ADD CONSTRAINT invitations_role_fkey FOREIGN KEY (role) REFERENCES roles(name);
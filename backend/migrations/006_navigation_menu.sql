@@ -0,0 +1,95 @@
+-- 006_navigation_menu.sql
+
+INSERT INTO permissions (code, description) VALUES
+    ('navigation:manage', 'Edit navigation menu items'),
+    ('report:view', 'View reports'),
+    ('settings:manage', 'Change system settings'),
+    ('vendor_portal:access', 'Use the vendor portal')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, m.permission
+FROM (VALUES
+    ('Admin', 'navigation:manage'),
+    ('Admin', 'report:view'),
+    ('Admin', 'settings:manage'),
+    ('Procurement Officer', 'report:view'),
+    ('Vendor', 'vendor_portal:access')
+) AS m(role_name, permission)
+JOIN roles r ON r.name = m.role_name
+ON CONFLICT DO NOTHING;
+
+-- Menu Items Table
+-- A tree of navigation entries. Items without a permission are shown to every
+-- authenticated user. Items with show_in_menu = FALSE are not listed in the
+-- menu but still name their path in breadcrumbs. Paths may contain
+-- parameters such as :id.
+CREATE TABLE IF NOT EXISTS menu_items (
+    id SERIAL PRIMARY KEY,
+    parent_id INTEGER REFERENCES menu_items(id) ON DELETE CASCADE,
+    title VARCHAR(100) NOT NULL,
+    path VARCHAR(255) NOT NULL,
+    icon VARCHAR(50) NOT NULL DEFAULT '',
+    permission VARCHAR(100) REFERENCES permissions(code) ON DELETE SET NULL,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    show_in_menu BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_menu_items_parent ON menu_items (parent_id);
+
+-- Default menu, only seeded into an empty table.
+DO $$
+DECLARE
+    procurement_id INTEGER;
+    my_reqs_id INTEGER;
+    reqs_id INTEGER;
+    pos_id INTEGER;
+    vendors_id INTEGER;
+    admin_id INTEGER;
+    users_id INTEGER;
+BEGIN
+    IF EXISTS (SELECT 1 FROM menu_items) THEN
+        RETURN;
+    END IF;
+
+    INSERT INTO menu_items (title, path, icon, permission, sort_order) VALUES
+        ('Dashboard', '/dashboard', 'dashboard', NULL, 10);
+
+    INSERT INTO menu_items (title, path, icon, permission, sort_order)
+        VALUES ('Procurement', '/procurement', 'shopping_cart', NULL, 20) RETURNING id INTO procurement_id;
+    INSERT INTO menu_items (parent_id, title, path, permission, sort_order)
+        VALUES (procurement_id, 'My Requisitions', '/procurement/requisitions/my', 'requisition:create', 10) RETURNING id INTO my_reqs_id;
+    INSERT INTO menu_items (parent_id, title, path, permission, sort_order)
+        VALUES (procurement_id, 'Requisitions', '/procurement/requisitions', 'requisition:read:all', 20) RETURNING id INTO reqs_id;
+    INSERT INTO menu_items (parent_id, title, path, permission, sort_order)
+        VALUES (procurement_id, 'Purchase Orders', '/procurement/purchase-orders', 'po:read:all', 30) RETURNING id INTO pos_id;
+    INSERT INTO menu_items (parent_id, title, path, permission, sort_order) VALUES
+        (procurement_id, 'Approvals', '/procurement/approvals', 'requisition:approve', 40);
+    INSERT INTO menu_items (parent_id, title, path, permission, sort_order, show_in_menu) VALUES
+        (my_reqs_id, 'New Requisition', '/procurement/requisitions/create', 'requisition:create', 10, FALSE),
+        (reqs_id, 'Requisition :id', '/procurement/requisitions/:id', 'requisition:read:all', 10, FALSE),
+        (pos_id, 'Purchase Order :id', '/procurement/purchase-orders/:id', 'po:read:all', 10, FALSE);
+
+    INSERT INTO menu_items (title, path, icon, permission, sort_order)
+        VALUES ('Vendors', '/vendors', 'store', 'vendor:read', 30) RETURNING id INTO vendors_id;
+    INSERT INTO menu_items (parent_id, title, path, permission, sort_order, show_in_menu) VALUES
+        (vendors_id, 'New Vendor', '/vendors/create', 'vendor:write', 10, FALSE),
+        (vendors_id, 'Vendor :id', '/vendors/:id', 'vendor:read', 20, FALSE);
+
+    INSERT INTO menu_items (title, path, icon, permission, sort_order) VALUES
+        ('Reports', '/reports', 'assessment', 'report:view', 40),
+        ('Purchase Orders', '/purchase-orders', 'list_alt', 'vendor_portal:access', 50),
+        ('Invoices', '/invoices', 'receipt', 'vendor_portal:access', 60);
+
+    INSERT INTO menu_items (title, path, icon, permission, sort_order)
+        VALUES ('Administration', '/admin', 'settings', NULL, 90) RETURNING id INTO admin_id;
+    INSERT INTO menu_items (parent_id, title, path, permission, sort_order)
+        VALUES (admin_id, 'User Management', '/admin/users', 'user:read', 10) RETURNING id INTO users_id;
+    INSERT INTO menu_items (parent_id, title, path, permission, sort_order) VALUES
+        (admin_id, 'All Requisitions', '/admin/requisitions', 'requisition:manage', 20),
+        (admin_id, 'All Purchase Orders', '/admin/purchase-orders', 'po:read:all', 30),
+        (admin_id, 'System Settings', '/admin/settings', 'settings:manage', 40);
+    INSERT INTO menu_items (parent_id, title, path, permission, sort_order, show_in_menu) VALUES
+        (users_id, 'Add User', '/admin/users/create', 'user:write', 10, FALSE);
+END $$;
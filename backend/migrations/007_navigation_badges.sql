@@ -0,0 +1,11 @@
+-- 007_navigation_badges.sql
+
+-- Name of the badge provider whose count is shown next to the item.
+-- Providers are registered in code; unknown names are ignored.
+ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS badge VARCHAR(50);
+
+UPDATE menu_items SET badge = 'pending_approvals'
+WHERE path = '/procurement/approvals' AND badge IS NULL;
+
+UPDATE menu_items SET badge = 'my_rejected_requisitions'
+WHERE path = '/procurement/requisitions/my' AND badge IS NULL;
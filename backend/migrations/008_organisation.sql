@@ -0,0 +1,40 @@
+-- 008_organisation.sql
+
+-- Departments Table
+-- Departments can be nested. head_user_id is the department head, used for
+-- approvals and reporting.
+CREATE TABLE IF NOT EXISTS departments (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    parent_id INTEGER REFERENCES departments(id) ON DELETE RESTRICT,
+    head_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (parent_id IS NULL OR parent_id <> id)
+);
+
+-- Department names are unique among siblings.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_departments_parent_name ON departments (COALESCE(parent_id, 0), name);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS department_id INTEGER REFERENCES departments(id) ON DELETE SET NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS manager_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_manager_not_self;
+ALTER TABLE users ADD CONSTRAINT users_manager_not_self CHECK (manager_id IS NULL OR manager_id <> id);
+
+CREATE INDEX IF NOT EXISTS idx_users_department ON users (department_id);
+CREATE INDEX IF NOT EXISTS idx_users_manager ON users (manager_id);
+
+INSERT INTO permissions (code, description) VALUES
+    ('org:read', 'View departments and reporting lines'),
+    ('org:manage', 'Edit departments, reporting lines and import the org chart')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, m.permission
+FROM (VALUES
+    ('Admin', 'org:read'),
+    ('Admin', 'org:manage'),
+    ('Procurement Officer', 'org:read'),
+    ('Approver', 'org:read')
+) AS m(role_name, permission)
+JOIN roles r ON r.name = m.role_name
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,28 @@
+-- 009_approval_delegation.sql
+
+-- A requisition may be assigned to a specific approver. Unassigned pending
+-- requisitions can be decided by anyone holding requisition:approve.
+ALTER TABLE requisitions ADD COLUMN IF NOT EXISTS approver_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_requisitions_approver ON requisitions (approver_id);
+
+-- Approval Delegations Table
+-- While a delegation is active the delegate may approve and reject on the
+-- delegator's behalf, up to max_amount per requisition when it is set.
+CREATE TABLE IF NOT EXISTS approval_delegations (
+    id SERIAL PRIMARY KEY,
+    delegator_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    delegate_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    max_amount NUMERIC(10, 2),
+    reason TEXT NOT NULL DEFAULT '',
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (delegator_id <> delegate_id),
+    CHECK (ends_at > starts_at),
+    CHECK (max_amount IS NULL OR max_amount > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_approval_delegations_delegate ON approval_delegations (delegate_id, ends_at);
+CREATE INDEX IF NOT EXISTS idx_approval_delegations_delegator ON approval_delegations (delegator_id, ends_at);
@@ -0,0 +1,49 @@
+-- 010_segregation_of_duties.sql
+
+-- Who created each vendor, so vendor creation can be kept apart from PO approval.
+ALTER TABLE vendors ADD COLUMN IF NOT EXISTS created_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+-- Segregation of Duties Rules Table
+-- Each rule names two duties that one person must not both perform on the
+-- same transaction. Duty codes are defined in models/sod.go.
+CREATE TABLE IF NOT EXISTS sod_rules (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT NOT NULL,
+    first_duty VARCHAR(50) NOT NULL,
+    second_duty VARCHAR(50) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (first_duty <> second_duty)
+);
+
+-- Segregation of Duties Violations Table
+-- Every blocked attempt, for review by administrators.
+CREATE TABLE IF NOT EXISTS sod_violations (
+    id SERIAL PRIMARY KEY,
+    rule_id INTEGER REFERENCES sod_rules(id) ON DELETE SET NULL,
+    rule_code VARCHAR(100) NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    on_behalf_of_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    duty VARCHAR(50) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sod_violations_created ON sod_violations (created_at DESC);
+
+INSERT INTO sod_rules (code, description, first_duty, second_duty) VALUES
+    ('requester_not_approver', 'The requester of a requisition cannot approve it', 'requisition.create', 'requisition.approve'),
+    ('vendor_creator_not_po_approver', 'The creator of a vendor cannot approve purchase orders to that vendor', 'vendor.create', 'po.approve'),
+    ('invoice_approver_not_receiver', 'The person who received the goods cannot approve the invoice', 'goods.receive', 'invoice.approve')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO permissions (code, description) VALUES
+    ('sod:manage', 'Configure segregation of duties rules and review violations')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, 'sod:manage' FROM roles r WHERE r.name = 'Admin'
+ON CONFLICT DO NOTHING;
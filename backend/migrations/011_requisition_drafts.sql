@@ -0,0 +1,10 @@
+-- 011_requisition_drafts.sql
+
+-- Requisitions are created as drafts and enter the approval queue only once
+-- their requester submits them. A pending requisition can be withdrawn back
+-- to draft.
+ALTER TABLE requisitions DROP CONSTRAINT IF EXISTS requisitions_status_check;
+ALTER TABLE requisitions ADD CONSTRAINT requisitions_status_check
+    CHECK (status IN ('Draft', 'Pending', 'Approved', 'Rejected'));
+
+ALTER TABLE requisitions ALTER COLUMN status SET DEFAULT 'Draft';
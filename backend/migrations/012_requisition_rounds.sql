@@ -0,0 +1,29 @@
+-- 012_requisition_rounds.sql
+
+-- An approver can now return a requisition to its requester for changes
+-- instead of rejecting it outright. The requester edits and resubmits the
+-- same requisition, starting a new review round.
+ALTER TABLE requisitions DROP CONSTRAINT IF EXISTS requisitions_status_check;
+ALTER TABLE requisitions ADD CONSTRAINT requisitions_status_check
+    CHECK (status IN ('Draft', 'Pending', 'Returned', 'Approved', 'Rejected'));
+
+-- round counts submissions; submitted_at is when the current round began.
+ALTER TABLE requisitions ADD COLUMN IF NOT EXISTS round INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE requisitions ADD COLUMN IF NOT EXISTS submitted_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE requisitions SET round = 1, submitted_at = created_at WHERE status <> 'Draft' AND round = 0;
+
+-- Requisition Rounds Table
+-- One row per finished review round: how it ended, why and by whom.
+CREATE TABLE IF NOT EXISTS requisition_rounds (
+    id SERIAL PRIMARY KEY,
+    requisition_id INTEGER NOT NULL REFERENCES requisitions(id) ON DELETE CASCADE,
+    round INTEGER NOT NULL,
+    submitted_at TIMESTAMP WITH TIME ZONE,
+    outcome VARCHAR(50) NOT NULL CHECK (outcome IN ('Approved', 'Rejected', 'Returned', 'Withdrawn')),
+    reason TEXT NOT NULL DEFAULT '',
+    decided_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    on_behalf_of_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (requisition_id, round)
+);
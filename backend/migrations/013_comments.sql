@@ -0,0 +1,53 @@
+-- 013_comments.sql
+
+-- Vendor portal users belong to a vendor, and only see that vendor's
+-- purchase orders and vendor-visible comments on them.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS vendor_id INTEGER REFERENCES vendors(id) ON DELETE SET NULL;
+
+-- Comments Table
+-- Comments attach to any entity by type and ID. A reply points at the
+-- top-level comment it answers. Deleted comments keep their place in the
+-- thread with the body removed.
+CREATE TABLE IF NOT EXISTS comments (
+    id SERIAL PRIMARY KEY,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    parent_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    visibility VARCHAR(20) NOT NULL DEFAULT 'internal' CHECK (visibility IN ('internal', 'vendor')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    edited_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_entity ON comments (entity_type, entity_id, created_at);
+
+CREATE TABLE IF NOT EXISTS comment_mentions (
+    comment_id INTEGER NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (comment_id, user_id)
+);
+
+-- Notifications Table
+-- In-app notifications, e.g. for @mentions.
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL,
+    entity_type VARCHAR(50),
+    entity_id INTEGER,
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications (user_id, created_at DESC);
+
+INSERT INTO permissions (code, description) VALUES
+    ('comment:moderate', 'Edit or delete anyone''s comments at any time')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, 'comment:moderate' FROM roles r WHERE r.name = 'Admin'
+ON CONFLICT DO NOTHING;
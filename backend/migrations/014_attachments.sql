@@ -0,0 +1,30 @@
+-- 014_attachments.sql
+
+-- Attachments Table
+-- Files attach to any entity by type and ID. The content lives in the blob
+-- store under storage_key; sha256 is the hex digest of the content.
+-- Vendor-visible attachments are only allowed on purchase orders, like
+-- comments.
+CREATE TABLE IF NOT EXISTS attachments (
+    id SERIAL PRIMARY KEY,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    visibility VARCHAR(20) NOT NULL DEFAULT 'internal' CHECK (visibility IN ('internal', 'vendor')),
+    uploaded_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_entity ON attachments (entity_type, entity_id, created_at);
+
+INSERT INTO permissions (code, description) VALUES
+    ('attachment:manage', 'Delete anyone''s attachments')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, 'attachment:manage' FROM roles r WHERE r.name = 'Admin'
+ON CONFLICT DO NOTHING;
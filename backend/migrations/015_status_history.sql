@@ -0,0 +1,59 @@
+-- 015_status_history.sql
+
+-- Purchase orders get a status so their lifecycle can be tracked. Every PO
+-- is issued when it is created.
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS status VARCHAR(50) NOT NULL DEFAULT 'Issued';
+ALTER TABLE purchase_orders DROP CONSTRAINT IF EXISTS purchase_orders_status_check;
+ALTER TABLE purchase_orders ADD CONSTRAINT purchase_orders_status_check CHECK (status IN ('Issued'));
+
+-- Status Transitions Table
+-- Append-only: one row per status change of a requisition or purchase order,
+-- written in the same transaction as the change itself. from_status is NULL
+-- for the status an entity was created with.
+CREATE TABLE IF NOT EXISTS status_transitions (
+    id BIGSERIAL PRIMARY KEY,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    from_status VARCHAR(50),
+    to_status VARCHAR(50) NOT NULL,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_status_transitions_entity ON status_transitions (entity_type, entity_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_status_transitions_to ON status_transitions (entity_type, to_status, created_at);
+
+-- Backfill a best-effort history for existing data from what was recorded
+-- before: creation, the finished review rounds and the current open round.
+-- Decisions made before review rounds were recorded have no timestamp and
+-- are left out.
+INSERT INTO status_transitions (entity_type, entity_id, from_status, to_status, actor_id, created_at)
+SELECT 'requisition', r.id, NULL, 'Draft', r.requester_id, r.created_at
+FROM requisitions r;
+
+INSERT INTO status_transitions (entity_type, entity_id, from_status, to_status, actor_id, created_at)
+SELECT 'requisition', rr.requisition_id,
+       COALESCE((SELECT CASE prev.outcome WHEN 'Withdrawn' THEN 'Draft' ELSE prev.outcome END
+                 FROM requisition_rounds prev
+                 WHERE prev.requisition_id = rr.requisition_id AND prev.round = rr.round - 1), 'Draft'),
+       'Pending', r.requester_id, rr.submitted_at
+FROM requisition_rounds rr
+JOIN requisitions r ON r.id = rr.requisition_id
+WHERE rr.submitted_at IS NOT NULL;
+
+INSERT INTO status_transitions (entity_type, entity_id, from_status, to_status, actor_id, reason, created_at)
+SELECT 'requisition', rr.requisition_id, 'Pending',
+       CASE rr.outcome WHEN 'Withdrawn' THEN 'Draft' ELSE rr.outcome END,
+       rr.decided_by, rr.reason, rr.decided_at
+FROM requisition_rounds rr;
+
+INSERT INTO status_transitions (entity_type, entity_id, from_status, to_status, actor_id, created_at)
+SELECT 'requisition', r.id, 'Draft', 'Pending', r.requester_id, r.submitted_at
+FROM requisitions r
+WHERE r.submitted_at IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM requisition_rounds rr WHERE rr.requisition_id = r.id AND rr.round = r.round);
+
+INSERT INTO status_transitions (entity_type, entity_id, from_status, to_status, created_at)
+SELECT 'purchase_order', po.id, NULL, 'Issued', po.created_at
+FROM purchase_orders po;
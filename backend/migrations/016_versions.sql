@@ -0,0 +1,9 @@
+-- 016_versions.sql
+
+-- Optimistic concurrency control. Every update of a row increments its
+-- version, and updates that were prepared against an older version are
+-- rejected. The API exposes the version as the ETag of the record.
+ALTER TABLE requisitions ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE vendors ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,20 @@
+-- 017_idempotency_keys.sql
+
+-- Idempotency Keys Table
+-- Remembers POST requests sent with an Idempotency-Key header so retries get
+-- the original response instead of repeating the action. Keys are scoped to
+-- the user who sent them. status_code is NULL while the original request is
+-- still being handled.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status_code INTEGER,
+    response_headers JSONB NOT NULL DEFAULT '{}',
+    response_body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,7 @@
+-- 018_activity_log_batches.sql
+
+-- Links the activity logged by one bulk operation, e.g. approving a batch of
+-- requisitions, so its entries can be found together.
+ALTER TABLE activity_logs ADD COLUMN IF NOT EXISTS batch_id VARCHAR(36);
+
+CREATE INDEX IF NOT EXISTS idx_activity_logs_batch_id ON activity_logs(batch_id) WHERE batch_id IS NOT NULL;
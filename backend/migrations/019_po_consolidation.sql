@@ -0,0 +1,50 @@
+-- 019_po_consolidation.sql
+
+-- Requisitions record the currency they are priced in and where the goods
+-- should be delivered. An empty ship_to means the company's own address.
+ALTER TABLE requisitions ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'MYR';
+ALTER TABLE requisitions ADD COLUMN IF NOT EXISTS ship_to TEXT NOT NULL DEFAULT '';
+
+-- A purchase order may now be consolidated from several requisitions, so it
+-- carries its own currency, ship-to and total, and its items are lines.
+-- requisition_id is only set on orders raised from a single requisition.
+ALTER TABLE purchase_orders ALTER COLUMN requisition_id DROP NOT NULL;
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'MYR';
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS ship_to TEXT NOT NULL DEFAULT '';
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS total_amount NUMERIC(12, 2) NOT NULL DEFAULT 0;
+
+-- Purchase Order Lines Table
+-- One line per item ordered. requisition_id traces a line back to the
+-- requisition it was raised from; a requisition is ordered at most once.
+CREATE TABLE IF NOT EXISTS purchase_order_lines (
+    id SERIAL PRIMARY KEY,
+    purchase_order_id INTEGER NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    line_no INTEGER NOT NULL,
+    requisition_id INTEGER REFERENCES requisitions(id),
+    description TEXT NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    unit_price NUMERIC(10, 2) NOT NULL,
+    total_price NUMERIC(12, 2) NOT NULL,
+    UNIQUE (purchase_order_id, line_no)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_purchase_order_lines_requisition ON purchase_order_lines(requisition_id) WHERE requisition_id IS NOT NULL;
+
+-- Existing orders each had exactly one requisition; it becomes their only line.
+INSERT INTO purchase_order_lines (purchase_order_id, line_no, requisition_id, description, quantity, unit_price, total_price)
+SELECT po.id, 1, r.id, r.item_description, r.quantity, r.estimated_price, r.total_price
+FROM purchase_orders po
+JOIN requisitions r ON r.id = po.requisition_id
+WHERE NOT EXISTS (SELECT 1 FROM purchase_order_lines l WHERE l.purchase_order_id = po.id);
+
+UPDATE purchase_orders po
+SET total_amount = (SELECT COALESCE(SUM(l.total_price), 0) FROM purchase_order_lines l WHERE l.purchase_order_id = po.id);
+
+-- Issuing and changing purchase orders, including consolidating the buy queue.
+INSERT INTO permissions (code, description) VALUES
+    ('po:write', 'Issue and change purchase orders')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, 'po:write' FROM roles r WHERE r.name IN ('Admin', 'Procurement Officer')
+ON CONFLICT DO NOTHING;
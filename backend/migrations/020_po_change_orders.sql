@@ -0,0 +1,41 @@
+-- 020_po_change_orders.sql
+
+-- Revision 0 is the order as issued; each applied change order adds one.
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS revision INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE purchase_order_lines ADD COLUMN IF NOT EXISTS delivery_date DATE;
+
+-- Purchase Order Change Orders Table
+-- A change to the quantities, prices or delivery dates of an order's lines.
+-- changes holds the difference against base_revision, line by line.
+CREATE TABLE IF NOT EXISTS purchase_order_change_orders (
+    id SERIAL PRIMARY KEY,
+    purchase_order_id INTEGER NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    revision INTEGER,
+    base_revision INTEGER NOT NULL,
+    status VARCHAR(50) NOT NULL CHECK (status IN ('Pending', 'Applied', 'Rejected')),
+    reason TEXT NOT NULL,
+    changes JSONB NOT NULL,
+    previous_total NUMERIC(12, 2) NOT NULL,
+    new_total NUMERIC(12, 2) NOT NULL,
+    requested_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    requested_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    decided_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMP WITH TIME ZONE,
+    decision_reason TEXT NOT NULL DEFAULT '',
+    UNIQUE (purchase_order_id, revision)
+);
+
+-- At most one change order per purchase order may wait for approval.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_po_change_orders_pending ON purchase_order_change_orders(purchase_order_id) WHERE status = 'Pending';
+
+INSERT INTO sod_rules (code, description, first_duty, second_duty) VALUES
+    ('po_changer_not_approver', 'The requester of a purchase order change cannot approve it', 'po.change', 'po.approve')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO permissions (code, description) VALUES
+    ('po:approve', 'Approve purchase order changes that raise the order value beyond the threshold')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, 'po:approve' FROM roles r WHERE r.name IN ('Admin', 'Approver')
+ON CONFLICT DO NOTHING;
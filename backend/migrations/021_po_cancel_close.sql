@@ -0,0 +1,28 @@
+-- 021_po_cancel_close.sql
+
+-- Purchase orders can be cancelled before anything is received, or closed
+-- out short, giving up whatever is still to be delivered.
+ALTER TABLE purchase_orders DROP CONSTRAINT IF EXISTS purchase_orders_status_check;
+ALTER TABLE purchase_orders ADD CONSTRAINT purchase_orders_status_check CHECK (status IN ('Issued', 'Cancelled', 'Closed'));
+
+-- committed_amount is the value still committed against budget: the total
+-- while the order is open, nothing once cancelled, and what was received once
+-- closed.
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS committed_amount NUMERIC(12, 2) NOT NULL DEFAULT 0;
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS closure_code VARCHAR(50);
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS closure_note TEXT NOT NULL DEFAULT '';
+UPDATE purchase_orders SET committed_amount = total_amount WHERE status = 'Issued';
+
+-- received_quantity is kept by goods receipt once it is recorded;
+-- short_closed_quantity is what a close-out gave up.
+ALTER TABLE purchase_order_lines ADD COLUMN IF NOT EXISTS received_quantity INTEGER NOT NULL DEFAULT 0 CHECK (received_quantity >= 0);
+ALTER TABLE purchase_order_lines ADD COLUMN IF NOT EXISTS short_closed_quantity INTEGER NOT NULL DEFAULT 0 CHECK (short_closed_quantity >= 0);
+
+-- A requisition on a cancelled order may be deleted; its order and lines keep
+-- their description but lose the link.
+ALTER TABLE purchase_orders DROP CONSTRAINT IF EXISTS purchase_orders_requisition_id_fkey;
+ALTER TABLE purchase_orders ADD CONSTRAINT purchase_orders_requisition_id_fkey
+    FOREIGN KEY (requisition_id) REFERENCES requisitions(id) ON DELETE SET NULL;
+ALTER TABLE purchase_order_lines DROP CONSTRAINT IF EXISTS purchase_order_lines_requisition_id_fkey;
+ALTER TABLE purchase_order_lines ADD CONSTRAINT purchase_order_lines_requisition_id_fkey
+    FOREIGN KEY (requisition_id) REFERENCES requisitions(id) ON DELETE SET NULL;
@@ -0,0 +1,33 @@
+-- 022_blanket_orders.sql
+
+-- A blanket purchase order is an agreement with a vendor for a period, up to
+-- a ceiling value, at agreed prices. Call-offs are purchase orders released
+-- against it; together they may not commit more than the ceiling.
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS po_type VARCHAR(20) NOT NULL DEFAULT 'standard'
+    CHECK (po_type IN ('standard', 'blanket', 'call_off'));
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS blanket_id INTEGER REFERENCES purchase_orders(id);
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS valid_from DATE;
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS valid_to DATE;
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS ceiling_amount NUMERIC(12, 2);
+
+ALTER TABLE purchase_orders DROP CONSTRAINT IF EXISTS purchase_orders_blanket_check;
+ALTER TABLE purchase_orders ADD CONSTRAINT purchase_orders_blanket_check CHECK (
+    (po_type = 'blanket' AND valid_from IS NOT NULL AND valid_to >= valid_from AND ceiling_amount > 0 AND blanket_id IS NULL)
+    OR (po_type = 'call_off' AND blanket_id IS NOT NULL)
+    OR (po_type = 'standard' AND blanket_id IS NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_blanket ON purchase_orders(blanket_id) WHERE blanket_id IS NOT NULL;
+
+-- Blanket Price Items Table
+-- The agreed price list of a blanket order. Call-off lines are priced from it.
+CREATE TABLE IF NOT EXISTS blanket_price_items (
+    id SERIAL PRIMARY KEY,
+    blanket_id INTEGER NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    line_no INTEGER NOT NULL,
+    description TEXT NOT NULL,
+    unit_price NUMERIC(10, 2) NOT NULL CHECK (unit_price > 0),
+    UNIQUE (blanket_id, line_no)
+);
+
+ALTER TABLE purchase_order_lines ADD COLUMN IF NOT EXISTS price_item_id INTEGER REFERENCES blanket_price_items(id);
@@ -0,0 +1,25 @@
+-- 023_po_dispatches.sql
+
+-- Purchase Order Dispatches Table
+-- Every time a purchase order PDF is emailed to its vendor. A dispatch that
+-- fails stays Pending and is retried at next_attempt_at until it is Sent or
+-- runs out of attempts and is marked Failed.
+CREATE TABLE IF NOT EXISTS purchase_order_dispatches (
+    id SERIAL PRIMARY KEY,
+    purchase_order_id INTEGER NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    to_address TEXT NOT NULL,
+    cc_addresses TEXT[] NOT NULL DEFAULT '{}',
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    status VARCHAR(50) NOT NULL CHECK (status IN ('Pending', 'Sent', 'Failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    requested_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_po_dispatches_po ON purchase_order_dispatches(purchase_order_id);
+CREATE INDEX IF NOT EXISTS idx_po_dispatches_due ON purchase_order_dispatches(next_attempt_at) WHERE status = 'Pending';
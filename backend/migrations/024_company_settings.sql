@@ -0,0 +1,39 @@
+-- 024_company_settings.sql
+
+-- Company Logos Table
+-- Logo images, shared by every settings version that uses them.
+CREATE TABLE IF NOT EXISTS company_logos (
+    id SERIAL PRIMARY KEY,
+    content_type VARCHAR(100) NOT NULL,
+    data BYTEA NOT NULL,
+    sha256 CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Company Settings Table
+-- The company profile and branding printed on purchase orders. Settings are
+-- never updated in place: each change adds a row with the next version, and
+-- the highest version is the one in use.
+CREATE TABLE IF NOT EXISTS company_settings (
+    version INTEGER PRIMARY KEY,
+    company_name VARCHAR(255) NOT NULL,
+    reg_no VARCHAR(50) NOT NULL DEFAULT '',
+    tin_no VARCHAR(50) NOT NULL DEFAULT '',
+    msic_code VARCHAR(20) NOT NULL DEFAULT '',
+    address TEXT NOT NULL DEFAULT '',
+    phones VARCHAR(255) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    bank_name VARCHAR(255) NOT NULL DEFAULT '',
+    bank_account VARCHAR(100) NOT NULL DEFAULT '',
+    payment_terms VARCHAR(255) NOT NULL DEFAULT '',
+    pdf_footer TEXT NOT NULL DEFAULT '',
+    logo_id INTEGER REFERENCES company_logos(id),
+    changed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Version 1 is what used to be hard-coded, so existing PDFs look the same.
+INSERT INTO company_settings (version, company_name, reg_no, tin_no, msic_code, address, phones, email, bank_name, bank_account, payment_terms)
+VALUES (1, 'Procurement Corp', '202301000001', 'TIN12345678', '62010', '123 Tech Avenue, Silicon Valley, CA 94043',
+        '1-800-555-PROC', 'contact@procurementcorp.com', 'Global Tech Bank', '123-456-7890', 'NET 30')
+ON CONFLICT (version) DO NOTHING;
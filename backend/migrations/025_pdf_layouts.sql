@@ -0,0 +1,13 @@
+-- 025_pdf_layouts.sql
+
+-- Purchase order PDFs are drawn with one of the layouts built into the
+-- backend. The company settings choose the default; a vendor may override it.
+ALTER TABLE company_settings ADD COLUMN IF NOT EXISTS pdf_layout VARCHAR(50) NOT NULL DEFAULT 'standard';
+ALTER TABLE vendors ADD COLUMN IF NOT EXISTS pdf_layout VARCHAR(50);
+
+-- Tax is added to the order's subtotal on the PDF, and the grand total is
+-- rounded to rounding_increment (e.g. 0.05), printing the difference as the
+-- rounding adjustment. Both are off by default.
+ALTER TABLE company_settings ADD COLUMN IF NOT EXISTS tax_label VARCHAR(50) NOT NULL DEFAULT 'Tax';
+ALTER TABLE company_settings ADD COLUMN IF NOT EXISTS tax_rate NUMERIC(5, 2) NOT NULL DEFAULT 0 CHECK (tax_rate >= 0 AND tax_rate <= 100);
+ALTER TABLE company_settings ADD COLUMN IF NOT EXISTS rounding_increment NUMERIC(4, 2) NOT NULL DEFAULT 0 CHECK (rounding_increment >= 0 AND rounding_increment <= 1);
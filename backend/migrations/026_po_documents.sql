@@ -0,0 +1,25 @@
+-- 026_po_documents.sql
+
+-- Purchase Order Documents Table
+-- The PDF of a purchase order as it was issued, revised or cancelled, kept so
+-- that later changes to the vendor, company settings or layouts do not alter
+-- what was sent. The content lives in the blob store under storage_key and
+-- is never replaced; sha256 is the hex digest of the content. Rows are never
+-- updated.
+CREATE TABLE IF NOT EXISTS purchase_order_documents (
+    id SERIAL PRIMARY KEY,
+    purchase_order_id INTEGER NOT NULL REFERENCES purchase_orders(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    reason VARCHAR(20) NOT NULL CHECK (reason IN ('issued', 'revised', 'cancelled', 'backfilled')),
+    size_bytes BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_po_documents_po ON purchase_order_documents(purchase_order_id, revision);
+CREATE INDEX IF NOT EXISTS idx_po_documents_sha256 ON purchase_order_documents(sha256);
+
+-- The digest of the order's latest archived document.
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS document_sha256 CHAR(64);
@@ -0,0 +1,9 @@
+-- 027_po_tax.sql
+
+-- A purchase order keeps the tax and rounding in force when it was issued,
+-- so changing the company settings later does not change what it is worth.
+-- total_amount and committed_amount include both. Orders issued before this
+-- were committed at their line total, so they keep no tax.
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS tax_label VARCHAR(50) NOT NULL DEFAULT 'Tax';
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS tax_rate NUMERIC(5, 2) NOT NULL DEFAULT 0 CHECK (tax_rate >= 0 AND tax_rate <= 100);
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS rounding_increment NUMERIC(4, 2) NOT NULL DEFAULT 0 CHECK (rounding_increment >= 0 AND rounding_increment <= 1);
@@ -0,0 +1,89 @@
+// Package amountwords spells out money amounts in English, as printed on
+// purchase orders and cheques: "MYR One Hundred Twenty-Three and Cents
+// Forty-Five Only".
+package amountwords
+
+import (
+	"math"
+	"strings"
+)
+
+var (
+	ones = []string{"", "One", "Two", "Three", "Four", "Five", "Six", "Seven", "Eight", "Nine",
+		"Ten", "Eleven", "Twelve", "Thirteen", "Fourteen", "Fifteen", "Sixteen", "Seventeen", "Eighteen", "Nineteen"}
+	tens = []string{"", "", "Twenty", "Thirty", "Forty", "Fifty", "Sixty", "Seventy", "Eighty", "Ninety"}
+	// scales name every group of three digits an int64 can have.
+	scales = []string{"", "Thousand", "Million", "Billion", "Trillion", "Quadrillion", "Quintillion"}
+)
+
+// Format spells out amount, rounded to the cent, prefixed by currency.
+// Negative amounts are spelled as their absolute value with "Minus". Amounts
+// too large to count in cents as an int64, and NaN, give "".
+func Format(amount float64, currency string) string {
+	rounded := math.Round(math.Abs(amount) * 100)
+	if math.IsNaN(rounded) || rounded >= math.MaxInt64 {
+		return ""
+	}
+	cents := int64(rounded)
+	whole, fraction := cents/100, cents%100
+
+	var b strings.Builder
+	if currency != "" {
+		b.WriteString(currency + " ")
+	}
+	if amount < 0 && cents > 0 {
+		b.WriteString("Minus ")
+	}
+	b.WriteString(Number(whole))
+	if fraction > 0 {
+		b.WriteString(" and Cents " + Number(fraction))
+	}
+	b.WriteString(" Only")
+	return b.String()
+}
+
+// Number spells out a whole number, e.g. "One Thousand Two Hundred Five".
+func Number(n int64) string {
+	if n < 0 {
+		// -n would overflow for math.MinInt64.
+		return "Minus " + number(uint64(-(n+1))+1)
+	}
+	return number(uint64(n))
+}
+
+func number(n uint64) string {
+	if n == 0 {
+		return "Zero"
+	}
+
+	var groups []string
+	for scale := 0; n > 0; scale++ {
+		if group := n % 1000; group > 0 {
+			words := hundreds(int(group))
+			if scales[scale] != "" {
+				words += " " + scales[scale]
+			}
+			groups = append([]string{words}, groups...)
+		}
+		n /= 1000
+	}
+	return strings.Join(groups, " ")
+}
+
+// hundreds spells out a number from 1 to 999.
+func hundreds(n int) string {
+	var parts []string
+	if n >= 100 {
+		parts = append(parts, ones[n/100]+" Hundred")
+		n %= 100
+	}
+	switch {
+	case n >= 20 && n%10 != 0:
+		parts = append(parts, tens[n/10]+"-"+ones[n%10])
+	case n >= 20:
+		parts = append(parts, tens[n/10])
+	case n > 0:
+		parts = append(parts, ones[n])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,45 @@
+package amountwords
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumber(t *testing.T) {
+	cases := map[int64]string{
+		0:                   "Zero",
+		7:                   "Seven",
+		15:                  "Fifteen",
+		40:                  "Forty",
+		99:                  "Ninety-Nine",
+		100:                 "One Hundred",
+		1005:                "One Thousand Five",
+		21340:               "Twenty-One Thousand Three Hundred Forty",
+		1000000:             "One Million",
+		2003004005:          "Two Billion Three Million Four Thousand Five",
+		-12:                 "Minus Twelve",
+		1000000000000:       "One Trillion",
+		1000000000000000:    "One Quadrillion",
+		2000000000000000001: "Two Quintillion One",
+		math.MaxInt64: "Nine Quintillion Two Hundred Twenty-Three Quadrillion Three Hundred Seventy-Two Trillion " +
+			"Thirty-Six Billion Eight Hundred Fifty-Four Million Seven Hundred Seventy-Five Thousand Eight Hundred Seven",
+		math.MinInt64: "Minus Nine Quintillion Two Hundred Twenty-Three Quadrillion Three Hundred Seventy-Two Trillion " +
+			"Thirty-Six Billion Eight Hundred Fifty-Four Million Seven Hundred Seventy-Five Thousand Eight Hundred Eight",
+	}
+	for n, want := range cases {
+		assert.Equal(t, want, Number(n), n)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "MYR One Hundred Twenty-Three and Cents Forty-Five Only", Format(123.45, "MYR"))
+	assert.Equal(t, "USD Ten Only", Format(10, "USD"))
+	assert.Equal(t, "Zero and Cents Five Only", Format(0.049999, ""))
+	assert.Equal(t, "MYR Minus Two and Cents Fifty Only", Format(-2.5, "MYR"))
+	assert.Equal(t, "MYR One Quadrillion Only", Format(1e15, "MYR"))
+	assert.Equal(t, "", Format(1e17, "MYR"))
+	assert.Equal(t, "", Format(math.Inf(1), "MYR"))
+	assert.Equal(t, "", Format(math.NaN(), "MYR"))
+}
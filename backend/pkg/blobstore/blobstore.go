@@ -0,0 +1,39 @@
+// Package blobstore stores opaque blobs of content under string keys. The
+// local filesystem implementation is the default; other backends, such as
+// S3-compatible object storage, implement the same Store interface.
+package blobstore
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Store is a place to keep blobs.
+type Store interface {
+	// Put stores the content read from r under key, replacing any blob
+	// already stored there.
+	Put(key string, r io.Reader) error
+	// Get opens the blob stored under key. The caller must close it.
+	Get(key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key. Deleting a missing blob is
+	// not an error.
+	Delete(key string) error
+}
+
+// NewKey returns a new random key. Keys are spread across 256 prefixes so no
+// single directory or prefix grows too large.
+func NewKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+	return id[:2] + "/" + id, nil
+}
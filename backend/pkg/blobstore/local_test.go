@@ -0,0 +1,38 @@
+package blobstore
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	s, err := NewLocalStore(t.TempDir())
+	assert.NoError(t, err)
+
+	key, err := NewKey()
+	assert.NoError(t, err)
+	assert.NoError(t, s.Put(key, strings.NewReader("quote")))
+
+	r, err := s.Get(key)
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "quote", string(content))
+
+	assert.NoError(t, s.Delete(key))
+	_, err = s.Get(key)
+	assert.Equal(t, ErrNotFound, err)
+	assert.NoError(t, s.Delete(key))
+}
+
+func TestLocalStore_RejectsEscapingKeys(t *testing.T) {
+	s, err := NewLocalStore(t.TempDir())
+	assert.NoError(t, err)
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "ab/../../outside", `ab\cd`} {
+		assert.Equal(t, ErrInvalidKey, s.Put(key, strings.NewReader("x")), key)
+	}
+}
@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileSender writes each message to an .eml file in a directory instead of
+// sending it. The files open in any mail client.
+type FileSender struct {
+	dir string
+}
+
+// NewFileSender returns a sender writing to dir, creating the directory if
+// needed.
+func NewFileSender(dir string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &FileSender{dir: dir}, nil
+}
+
+func (s *FileSender) Send(msg *Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), hex.EncodeToString(b))
+	return os.WriteFile(filepath.Join(s.dir, name), data, 0o640)
+}
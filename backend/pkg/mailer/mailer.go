@@ -0,0 +1,140 @@
+// Package mailer sends email. SMTPSender delivers through any SMTP server,
+// including local catchers such as MailHog; FileSender writes messages to a
+// directory instead, for development without a mail server.
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+var ErrNoRecipients = errors.New("message has no recipients")
+
+// Message is an email with optional attachments. Body is plain text.
+type Message struct {
+	From        string
+	To          []string
+	Cc          []string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Attachment is a file sent with a message.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Sender delivers messages.
+type Sender interface {
+	Send(msg *Message) error
+}
+
+// Recipients returns everyone the message is delivered to.
+func (m *Message) Recipients() []string {
+	return append(append([]string{}, m.To...), m.Cc...)
+}
+
+// Bytes encodes the message as MIME, ready to hand to an SMTP server or write
+// to an .eml file.
+func (m *Message) Bytes() ([]byte, error) {
+	if len(m.To) == 0 {
+		return nil, ErrNoRecipients
+	}
+	for _, addr := range append([]string{m.From}, m.Recipients()...) {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", addr, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	header("From", m.From)
+	header("To", strings.Join(m.To, ", "))
+	if len(m.Cc) > 0 {
+		header("Cc", strings.Join(m.Cc, ", "))
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(m.From))
+	header("MIME-Version", "1.0")
+
+	w := multipart.NewWriter(&buf)
+	header("Content-Type", fmt.Sprintf("multipart/mixed; boundary=%q", w.Boundary()))
+	buf.WriteString("\r\n")
+
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBase64(part, []byte(m.Body)); err != nil {
+		return nil, err
+	}
+
+	for _, a := range m.Attachments {
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64(part, a.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64 writes data base64-encoded in lines of 76 characters.
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := fmt.Fprintf(w, "%s\r\n", encoded[:76]); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := fmt.Fprintf(w, "%s\r\n", encoded)
+	return err
+}
+
+// messageID returns a unique Message-ID in the sender's domain.
+func messageID(from string) string {
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if at := strings.LastIndex(addr.Address, "@"); at >= 0 {
+			domain = addr.Address[at+1:]
+		}
+	}
+	b := make([]byte, 12)
+	rand.Read(b)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
@@ -0,0 +1,79 @@
+package mailer
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newMessage() *Message {
+	return &Message{
+		From:    "Procurement <po@example.com>",
+		To:      []string{"sales@vendor.example"},
+		Cc:      []string{"buyer@example.com"},
+		Subject: "Purchase Order PO-2026-0001",
+		Body:    "Please find our purchase order attached.",
+		Attachments: []Attachment{
+			{Filename: "PO-2026-0001.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.3 test")},
+		},
+	}
+}
+
+func TestMessageBytes(t *testing.T) {
+	data, err := newMessage().Bytes()
+	assert.NoError(t, err)
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, "sales@vendor.example", parsed.Header.Get("To"))
+	assert.Equal(t, "buyer@example.com", parsed.Header.Get("Cc"))
+	assert.Equal(t, "Purchase Order PO-2026-0001", parsed.Header.Get("Subject"))
+	assert.Contains(t, parsed.Header.Get("Message-ID"), "@example.com>")
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	r := multipart.NewReader(parsed.Body, params["boundary"])
+	body, err := r.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "text/plain; charset=utf-8", body.Header.Get("Content-Type"))
+
+	attachment, err := r.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "PO-2026-0001.pdf", attachment.FileName())
+	encoded, _ := io.ReadAll(attachment)
+	assert.Contains(t, string(encoded), "JVBERi0xLjMgdGVzdA==")
+}
+
+func TestMessageBytes_RejectsBadAddresses(t *testing.T) {
+	msg := newMessage()
+	msg.To = nil
+	_, err := msg.Bytes()
+	assert.Equal(t, ErrNoRecipients, err)
+
+	msg = newMessage()
+	msg.Cc = []string{"not an address"}
+	_, err = msg.Bytes()
+	assert.Error(t, err)
+}
+
+func TestFileSender(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileSender(dir)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Send(newMessage()))
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if assert.Len(t, files, 1) {
+		data, _ := os.ReadFile(files[0])
+		assert.Contains(t, string(data), "Subject: Purchase Order PO-2026-0001")
+	}
+}
@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+)
+
+// SMTPSender delivers messages through an SMTP server. The connection is
+// upgraded with STARTTLS when the server offers it. Credentials are optional,
+// so a local catcher such as MailHog (port 1025) works as is.
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+}
+
+// NewSMTPSender returns a sender for the server at host:port. Without a
+// username, messages are sent unauthenticated.
+func NewSMTPSender(host string, port int, username, password string) *SMTPSender {
+	s := &SMTPSender{addr: net.JoinHostPort(host, strconv.Itoa(port))}
+	if username != "" {
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+func (s *SMTPSender) Send(msg *Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return err
+	}
+	recipients := make([]string, 0, len(msg.Recipients()))
+	for _, r := range msg.Recipients() {
+		addr, err := mail.ParseAddress(r)
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, addr.Address)
+	}
+	return smtp.SendMail(s.addr, s.auth, from.Address, recipients, data)
+}
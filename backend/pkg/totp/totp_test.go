@@ -0,0 +1,53 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test vectors from RFC 6238 Appendix B (SHA1), truncated to 6 digits.
+func TestCode_RFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		got, err := Code(secret, time.Unix(unix, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, "time %d", unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+
+	code, _ := Code(secret, now.Add(-Period))
+	step, ok := Validate(secret, code, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, Step(now)-1, step)
+
+	code, _ = Code(secret, now.Add(-3*Period))
+	_, ok = Validate(secret, code, now, 1)
+	assert.False(t, ok)
+
+	_, ok = Validate(secret, "12345", now, 1)
+	assert.False(t, ok)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("JBSWY3DPEHPK3PXP", "Procurement System", "admin@example.com")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Procurement%20System:admin@example.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=Procurement+System")
+}
@@ -7,8 +7,9 @@ class PurchaseOrder {
   final int id;
   @JsonKey(name: 'po_number')
   final String poNumber;
+  // Null for orders consolidated from several requisitions.
   @JsonKey(name: 'requisition_id')
-  final int requisitionId;
+  final int? requisitionId;
   @JsonKey(name: 'vendor_id')
   final int vendorId;
   @JsonKey(name: 'order_date')
@@ -19,7 +20,7 @@ class PurchaseOrder {
   PurchaseOrder({
     required this.id,
     required this.poNumber,
-    required this.requisitionId,
+    this.requisitionId,
     required this.vendorId,
     required this.orderDate,
     required this.createdAt,
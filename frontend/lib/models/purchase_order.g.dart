@@ -10,7 +10,7 @@ PurchaseOrder _$PurchaseOrderFromJson(Map<String, dynamic> json) =>
     PurchaseOrder(
       id: (json['id'] as num).toInt(),
       poNumber: json['po_number'] as String,
-      requisitionId: (json['requisition_id'] as num).toInt(),
+      requisitionId: (json['requisition_id'] as num?)?.toInt(),
       vendorId: (json['vendor_id'] as num).toInt(),
       orderDate: DateTime.parse(json['order_date'] as String),
       createdAt: DateTime.parse(json['created_at'] as String),
@@ -74,7 +74,7 @@ class _AllPurchaseOrdersScreenState extends State<AllPurchaseOrdersScreen> {
                   return DataRow(cells: [
                     DataCell(Text(po.id.toString())),
                     DataCell(Text(po.poNumber)),
-                    DataCell(Text(po.requisitionId.toString())),
+                    DataCell(Text(po.requisitionId?.toString() ?? 'Consolidated')),
                     DataCell(Text(po.vendorId.toString())),
                     DataCell(Text(po.orderDate.toLocal().toString().split(' ')[0])),
                   ]);
@@ -40,18 +40,48 @@ class _ApprovalsScreenState extends State<ApprovalsScreen> {
     }
   }
 
-  void _reject(int id) async {
-    try {
-      await _apiService.rejectRequisition(id);
-      ScaffoldMessenger.of(context).showSnackBar(
-        const SnackBar(content: Text('Requisition Rejected'), backgroundColor: Colors.orange),
-      );
-      _refreshPendingRequisitions();
-    } catch (e) {
-      ScaffoldMessenger.of(context).showSnackBar(
-        SnackBar(content: Text('Failed to reject: $e'), backgroundColor: Colors.red),
-      );
-    }
+  // Rejecting and returning both need a reason the requester will see.
+  void _decideWithReason(Requisition req, String title, Future<void> Function(int id, String reason) decide, String done) {
+    final reasonController = TextEditingController();
+    showDialog(
+      context: context,
+      builder: (context) {
+        return AlertDialog(
+          title: Text('$title "${req.itemDescription}"'),
+          content: TextField(
+            controller: reasonController,
+            decoration: const InputDecoration(labelText: 'Reason', border: OutlineInputBorder()),
+            maxLines: 3,
+            autofocus: true,
+          ),
+          actions: [
+            TextButton(
+              onPressed: () => Navigator.of(context).pop(),
+              child: const Text('Cancel'),
+            ),
+            TextButton(
+              onPressed: () async {
+                final reason = reasonController.text.trim();
+                if (reason.isEmpty) return;
+                Navigator.of(context).pop();
+                try {
+                  await decide(req.id, reason);
+                  ScaffoldMessenger.of(this.context).showSnackBar(
+                    SnackBar(content: Text(done), backgroundColor: Colors.orange),
+                  );
+                  _refreshPendingRequisitions();
+                } catch (e) {
+                  ScaffoldMessenger.of(this.context).showSnackBar(
+                    SnackBar(content: Text('$e'), backgroundColor: Colors.red),
+                  );
+                }
+              },
+              child: Text(title),
+            ),
+          ],
+        );
+      },
+    );
   }
 
   @override
@@ -113,7 +143,12 @@ class _ApprovalsScreenState extends State<ApprovalsScreen> {
                         ),
                         const SizedBox(width: 8),
                         ElevatedButton(
-                          onPressed: () => _reject(req.id),
+                          onPressed: () => _decideWithReason(req, 'Return', _apiService.returnRequisition, 'Requisition Returned for Changes'),
+                          child: const Text('Return'),
+                        ),
+                        const SizedBox(width: 8),
+                        ElevatedButton(
+                          onPressed: () => _decideWithReason(req, 'Reject', _apiService.rejectRequisition, 'Requisition Rejected'),
                           child: const Text('Reject'),
                           style: ElevatedButton.styleFrom(backgroundColor: Colors.orange),
                         ),
@@ -53,7 +53,9 @@ class _CreateRequisitionScreenState extends State<CreateRequisitionScreen> {
           'estimated_price': double.tryParse(_estimatedPriceController.text) ?? 0.0,
           'justification': _justificationController.text,
         };
-        await _apiService.createRequisition(requisitionData);
+        // Requisitions are created as drafts; the form is complete, so send it straight for approval.
+        final draft = await _apiService.createRequisition(requisitionData);
+        await _apiService.submitRequisition(draft.id);
 
         if (mounted) {
           ScaffoldMessenger.of(context).showSnackBar(
@@ -57,6 +57,71 @@ class _MyRequisitionsScreenState extends State<MyRequisitionsScreen> {
     );
   }
 
+  Future<void> _changeStatus(Future<Requisition> Function(int id) change, Requisition requisition, String action) async {
+    try {
+      await change(requisition.id);
+      ScaffoldMessenger.of(context).showSnackBar(
+        SnackBar(
+          content: Text('Requisition $action successfully'),
+          backgroundColor: Colors.green,
+        ),
+      );
+      _refreshRequisitions();
+    } catch (e) {
+      ScaffoldMessenger.of(context).showSnackBar(
+        SnackBar(
+          content: Text('$e'),
+          backgroundColor: Colors.red,
+        ),
+      );
+    }
+  }
+
+  void _showHistoryDialog(Requisition requisition) {
+    showDialog(
+      context: context,
+      builder: (context) {
+        return AlertDialog(
+          title: Text('History of "${requisition.itemDescription}"'),
+          content: SizedBox(
+            width: 400,
+            child: FutureBuilder<List<Map<String, dynamic>>>(
+              future: _apiService.getRequisitionHistory(requisition.id),
+              builder: (context, snapshot) {
+                if (snapshot.connectionState == ConnectionState.waiting) {
+                  return const SizedBox(height: 80, child: Center(child: CircularProgressIndicator()));
+                } else if (snapshot.hasError) {
+                  return Text('${snapshot.error}');
+                } else if (snapshot.data!.isEmpty) {
+                  return const Text('No decisions yet.');
+                }
+                return ListView(
+                  shrinkWrap: true,
+                  children: snapshot.data!.map((round) {
+                    final reason = round['reason'] as String?;
+                    return ListTile(
+                      title: Text('Round ${round['round']}: ${round['outcome']}'),
+                      subtitle: Text([
+                        if (reason != null && reason.isNotEmpty) reason,
+                        'by ${round['decided_by_name'] ?? 'unknown'} on ${round['decided_at']}',
+                      ].join('\n')),
+                    );
+                  }).toList(),
+                );
+              },
+            ),
+          ),
+          actions: [
+            TextButton(
+              onPressed: () => Navigator.of(context).pop(),
+              child: const Text('Close'),
+            ),
+          ],
+        );
+      },
+    );
+  }
+
   void _showDeleteConfirmationDialog(Requisition requisition) {
     showDialog(
       context: context,
@@ -149,6 +214,7 @@ class _MyRequisitionsScreenState extends State<MyRequisitionsScreen> {
                   DataColumn(label: Text('Actions')),
                 ],
                 rows: requisitions.map((req) {
+                  final bool isDraft = req.status == 'Draft' || req.status == 'Returned';
                   final bool isPending = req.status == 'Pending';
                   return DataRow(cells: [
                     DataCell(Text(req.id.toString())),
@@ -157,16 +223,33 @@ class _MyRequisitionsScreenState extends State<MyRequisitionsScreen> {
                     DataCell(Text('\$${req.totalPrice.toStringAsFixed(2)}')),
                     DataCell(Row(
                       children: [
-                        if (isPending)
+                        if (isDraft)
                           IconButton(
                             icon: const Icon(Icons.edit),
                             onPressed: () => _showEditRequisitionDialog(req),
                           ),
-                        if (isPending)
+                        if (isDraft)
+                          IconButton(
+                            icon: const Icon(Icons.send),
+                            tooltip: 'Submit for approval',
+                            onPressed: () => _changeStatus(_apiService.submitRequisition, req, 'submitted'),
+                          ),
+                        if (isDraft)
                           IconButton(
                             icon: const Icon(Icons.delete, color: Colors.red),
                             onPressed: () => _showDeleteConfirmationDialog(req),
                           ),
+                        IconButton(
+                          icon: const Icon(Icons.history),
+                          tooltip: 'Review history',
+                          onPressed: () => _showHistoryDialog(req),
+                        ),
+                        if (isPending)
+                          IconButton(
+                            icon: const Icon(Icons.undo),
+                            tooltip: 'Withdraw to draft',
+                            onPressed: () => _changeStatus(_apiService.withdrawRequisition, req, 'withdrawn'),
+                          ),
                       ],
                     )),
                   ]);
@@ -95,7 +95,8 @@ class ApiService {
 
   Future<User> registerUser(Map<String, dynamic> data) async {
     try {
-      final response = await _dio.post('/register', data: data);
+      // Self-registration only creates Employees, so admins use /users.
+      final response = await _dio.post('/users', data: data);
       if (response.statusCode == 201) {
         return User.fromJson(response.data);
       } else {
@@ -157,6 +158,32 @@ class ApiService {
     }
   }
 
+  Future<Requisition> submitRequisition(int id) async {
+    try {
+      final response = await _dio.post('/requisitions/$id/submit');
+      if (response.statusCode == 200) {
+        return Requisition.fromJson(response.data);
+      } else {
+        throw Exception('Failed to submit requisition');
+      }
+    } catch (e) {
+      throw Exception('Failed to submit requisition: $e');
+    }
+  }
+
+  Future<Requisition> withdrawRequisition(int id) async {
+    try {
+      final response = await _dio.post('/requisitions/$id/withdraw');
+      if (response.statusCode == 200) {
+        return Requisition.fromJson(response.data);
+      } else {
+        throw Exception('Failed to withdraw requisition');
+      }
+    } catch (e) {
+      throw Exception('Failed to withdraw requisition: $e');
+    }
+  }
+
   // Approval methods
   Future<List<Requisition>> getPendingRequisitions() async {
     try {
@@ -183,9 +210,9 @@ class ApiService {
     }
   }
 
-  Future<void> rejectRequisition(int id) async {
+  Future<void> rejectRequisition(int id, String reason) async {
     try {
-      final response = await _dio.post('/requisitions/$id/reject');
+      final response = await _dio.post('/requisitions/$id/reject', data: {'reason': reason});
       if (response.statusCode != 200) {
         throw Exception('Failed to reject requisition');
       }
@@ -194,6 +221,31 @@ class ApiService {
     }
   }
 
+  Future<void> returnRequisition(int id, String reason) async {
+    try {
+      final response = await _dio.post('/requisitions/$id/return', data: {'reason': reason});
+      if (response.statusCode != 200) {
+        throw Exception('Failed to return requisition');
+      }
+    } catch (e) {
+      throw Exception('Failed to return requisition: $e');
+    }
+  }
+
+  Future<List<Map<String, dynamic>>> getRequisitionHistory(int id) async {
+    try {
+      final response = await _dio.get('/requisitions/$id/history');
+      if (response.statusCode == 200) {
+        final List<dynamic> data = response.data;
+        return data.cast<Map<String, dynamic>>();
+      } else {
+        throw Exception('Failed to load requisition history');
+      }
+    } catch (e) {
+      throw Exception('Failed to load requisition history: $e');
+    }
+  }
+
   // Admin listing methods
   Future<List<Requisition>> getAllRequisitions() async {
     try {